@@ -113,6 +113,69 @@ std::vector<Callback> dispatchers = {
     unpackSyscall<::gvisor::syscall::InotifyAddWatch>,
     unpackSyscall<::gvisor::syscall::InotifyRmWatch>,
     unpackSyscall<::gvisor::syscall::SocketPair>,
+    unpackSyscall<::gvisor::syscall::Semget>,
+    unpackSyscall<::gvisor::syscall::Semop>,
+    unpackSyscall<::gvisor::syscall::Msgget>,
+    unpackSyscall<::gvisor::syscall::Msgsnd>,
+    unpackSyscall<::gvisor::syscall::Msgrcv>,
+    unpackSyscall<::gvisor::syscall::MqOpen>,
+    unpackSyscall<::gvisor::syscall::MqSend>,
+    unpackSyscall<::gvisor::syscall::MqReceive>,
+    unpackSyscall<::gvisor::syscall::Shutdown>,
+    unpackSyscall<::gvisor::syscall::Getsockname>,
+    unpackSyscall<::gvisor::syscall::Getpeername>,
+    unpackSyscall<::gvisor::syscall::Setsockopt>,
+    unpackSyscall<::gvisor::syscall::Getsockopt>,
+    unpackSyscall<::gvisor::syscall::Flock>,
+    unpackSyscall<::gvisor::syscall::Utimes>,
+    unpackSyscall<::gvisor::syscall::Fallocate>,
+    unpackSyscall<::gvisor::syscall::Fsync>,
+    unpackSyscall<::gvisor::syscall::Sync>,
+    unpackSyscall<::gvisor::syscall::Mknod>,
+    unpackSyscall<::gvisor::syscall::Acct>,
+    unpackSyscall<::gvisor::syscall::Personality>,
+    unpackSyscall<::gvisor::syscall::Uname>,
+    unpackSyscall<::gvisor::syscall::Sysinfo>,
+    unpack<::gvisor::sentry::SignalDeliverInfo>,
+    unpack<::gvisor::sentry::OOMInfo>,
+    unpack<::gvisor::container::Stop>,
+    unpack<::gvisor::container::Pause>,
+    unpack<::gvisor::container::Resume>,
+    unpack<::gvisor::container::Exec>,
+    unpack<::gvisor::sentry::CheckpointInfo>,
+    unpack<::gvisor::sentry::RestoreInfo>,
+    unpack<::gvisor::sentry::CoreDumpInfo>,
+    unpack<::gvisor::sentry::SeccompInfo>,
+    unpack<::gvisor::sentry::CapabilityDeniedInfo>,
+    unpack<::gvisor::sentry::NamespaceCreateInfo>,
+    unpack<::gvisor::sentry::ExecMapInfo>,
+    unpack<::gvisor::sentry::SyntheticFileWriteInfo>,
+    unpack<::gvisor::sentry::TCPEstablishedInfo>,
+    unpack<::gvisor::sentry::DNSQueryInfo>,
+    unpack<::gvisor::sentry::ListenInfo>,
+    unpack<::gvisor::sentry::GoferOpInfo>,
+    unpack<::gvisor::sentry::FileOpenInfo>,
+    unpack<::gvisor::sentry::TTYDataInfo>,
+    unpack<::gvisor::sentry::MajorFaultInfo>,
+    unpack<::gvisor::sentry::RLimitBreachInfo>,
+    unpack<::gvisor::sentry::CPUThrottleInfo>,
+    unpack<::gvisor::common::DropStats>,
+    unpack<::gvisor::common::SessionClosed>,
+    unpackSyscall<::gvisor::syscall::Unlink>,
+    unpackSyscall<::gvisor::syscall::Rename>,
+    unpackSyscall<::gvisor::syscall::Sendto>,
+    unpack<::gvisor::sentry::PolicyViolationInfo>,
+    unpackSyscall<::gvisor::syscall::Ptrace>,
+    unpack<::gvisor::sentry::FilelessExecInfo>,
+    unpackSyscall<::gvisor::syscall::Mount>,
+    unpackSyscall<::gvisor::syscall::Umount>,
+    unpackSyscall<::gvisor::syscall::PivotRoot>,
+    unpack<::gvisor::sentry::AnomalyAlertInfo>,
+    unpackSyscall<::gvisor::syscall::Mmap>,
+    unpackSyscall<::gvisor::syscall::Mprotect>,
+    unpack<::gvisor::sentry::DriftInfo>,
+    unpackSyscall<::gvisor::syscall::Write>,
+    unpackSyscall<::gvisor::syscall::Sendmsg>,
 };
 
 void unpack(absl::string_view buf) {
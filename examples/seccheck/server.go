@@ -0,0 +1,71 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Binary server_go is an example of a server that receives trace events from
+// the remote sink, written with the consumer package. It's the Go counterpart
+// of server.cc, and works with pod_init.json.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/consumer"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+var (
+	endpoint = flag.String("endpoint", "/tmp/gvisor_events.sock", "path to the socket that the remote sink connects to")
+	quiet    = flag.Bool("quiet", false, "only print the number of events received per type on exit")
+)
+
+func main() {
+	flag.Parse()
+
+	// Handlers are called concurrently for different clients.
+	var mu sync.Mutex
+	counts := make(map[pb.MessageType]uint64)
+	c := consumer.New(*endpoint)
+	c.HandleAll(func(e *consumer.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		counts[e.Type]++
+		if !*quiet {
+			fmt.Printf("[%d] %v: %v\n", e.Client, e.Type, e.Msg)
+		}
+		return nil
+	})
+
+	_ = os.Remove(*endpoint)
+	if err := c.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "starting server: %v\n", err)
+		os.Exit(1)
+	}
+	defer c.Close()
+	fmt.Printf("Listening on %q. Press ctrl-C to exit...\n", *endpoint)
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, unix.SIGINT, unix.SIGTERM)
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	for t, count := range counts {
+		fmt.Printf("%v: %d\n", t, count)
+	}
+}
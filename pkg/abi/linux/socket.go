@@ -15,6 +15,7 @@
 package linux
 
 import (
+	"gvisor.dev/gvisor/pkg/abi"
 	"gvisor.dev/gvisor/pkg/marshal"
 )
 
@@ -600,3 +601,192 @@ const SO_ACCEPTCON = 1 << 16
 type ICMP6Filter struct {
 	Filter [8]uint32
 }
+
+// SockOptLevels are the possible socket option levels, used for
+// setsockopt(2) and getsockopt(2).
+var SockOptLevels = abi.ValueSet{
+	SOL_IP:      "SOL_IP",
+	SOL_SOCKET:  "SOL_SOCKET",
+	SOL_TCP:     "SOL_TCP",
+	SOL_UDP:     "SOL_UDP",
+	SOL_IPV6:    "SOL_IPV6",
+	SOL_ICMPV6:  "SOL_ICMPV6",
+	SOL_RAW:     "SOL_RAW",
+	SOL_PACKET:  "SOL_PACKET",
+	SOL_NETLINK: "SOL_NETLINK",
+}
+
+// SockOptNames are the possible socket option names for each level, used for
+// setsockopt(2) and getsockopt(2).
+var SockOptNames = map[uint64]abi.ValueSet{
+	SOL_IP: {
+		IP_TTL:                    "IP_TTL",
+		IP_MULTICAST_TTL:          "IP_MULTICAST_TTL",
+		IP_MULTICAST_IF:           "IP_MULTICAST_IF",
+		IP_MULTICAST_LOOP:         "IP_MULTICAST_LOOP",
+		IP_TOS:                    "IP_TOS",
+		IP_RECVTOS:                "IP_RECVTOS",
+		IPT_SO_GET_INFO:           "IPT_SO_GET_INFO",
+		IPT_SO_GET_ENTRIES:        "IPT_SO_GET_ENTRIES",
+		IP_ADD_MEMBERSHIP:         "IP_ADD_MEMBERSHIP",
+		IP_DROP_MEMBERSHIP:        "IP_DROP_MEMBERSHIP",
+		MCAST_JOIN_GROUP:          "MCAST_JOIN_GROUP",
+		IP_ADD_SOURCE_MEMBERSHIP:  "IP_ADD_SOURCE_MEMBERSHIP",
+		IP_BIND_ADDRESS_NO_PORT:   "IP_BIND_ADDRESS_NO_PORT",
+		IP_BLOCK_SOURCE:           "IP_BLOCK_SOURCE",
+		IP_CHECKSUM:               "IP_CHECKSUM",
+		IP_DROP_SOURCE_MEMBERSHIP: "IP_DROP_SOURCE_MEMBERSHIP",
+		IP_FREEBIND:               "IP_FREEBIND",
+		IP_HDRINCL:                "IP_HDRINCL",
+		IP_IPSEC_POLICY:           "IP_IPSEC_POLICY",
+		IP_MINTTL:                 "IP_MINTTL",
+		IP_MSFILTER:               "IP_MSFILTER",
+		IP_MTU_DISCOVER:           "IP_MTU_DISCOVER",
+		IP_MULTICAST_ALL:          "IP_MULTICAST_ALL",
+		IP_NODEFRAG:               "IP_NODEFRAG",
+		IP_OPTIONS:                "IP_OPTIONS",
+		IP_PASSSEC:                "IP_PASSSEC",
+		IP_PKTINFO:                "IP_PKTINFO",
+		IP_RECVERR:                "IP_RECVERR",
+		IP_RECVFRAGSIZE:           "IP_RECVFRAGSIZE",
+		IP_RECVOPTS:               "IP_RECVOPTS",
+		IP_RECVORIGDSTADDR:        "IP_RECVORIGDSTADDR",
+		IP_RECVTTL:                "IP_RECVTTL",
+		IP_RETOPTS:                "IP_RETOPTS",
+		IP_TRANSPARENT:            "IP_TRANSPARENT",
+		IP_UNBLOCK_SOURCE:         "IP_UNBLOCK_SOURCE",
+		IP_UNICAST_IF:             "IP_UNICAST_IF",
+		IP_XFRM_POLICY:            "IP_XFRM_POLICY",
+		MCAST_BLOCK_SOURCE:        "MCAST_BLOCK_SOURCE",
+		MCAST_JOIN_SOURCE_GROUP:   "MCAST_JOIN_SOURCE_GROUP",
+		MCAST_LEAVE_GROUP:         "MCAST_LEAVE_GROUP",
+		MCAST_LEAVE_SOURCE_GROUP:  "MCAST_LEAVE_SOURCE_GROUP",
+		MCAST_MSFILTER:            "MCAST_MSFILTER",
+		MCAST_UNBLOCK_SOURCE:      "MCAST_UNBLOCK_SOURCE",
+		IP_ROUTER_ALERT:           "IP_ROUTER_ALERT",
+		IP_PKTOPTIONS:             "IP_PKTOPTIONS",
+		IP_MTU:                    "IP_MTU",
+		SO_ORIGINAL_DST:           "SO_ORIGINAL_DST",
+	},
+	SOL_SOCKET: {
+		SO_ERROR:        "SO_ERROR",
+		SO_PEERCRED:     "SO_PEERCRED",
+		SO_PASSCRED:     "SO_PASSCRED",
+		SO_SNDBUF:       "SO_SNDBUF",
+		SO_RCVBUF:       "SO_RCVBUF",
+		SO_REUSEADDR:    "SO_REUSEADDR",
+		SO_REUSEPORT:    "SO_REUSEPORT",
+		SO_BINDTODEVICE: "SO_BINDTODEVICE",
+		SO_BROADCAST:    "SO_BROADCAST",
+		SO_KEEPALIVE:    "SO_KEEPALIVE",
+		SO_LINGER:       "SO_LINGER",
+		SO_SNDTIMEO:     "SO_SNDTIMEO",
+		SO_RCVTIMEO:     "SO_RCVTIMEO",
+		SO_OOBINLINE:    "SO_OOBINLINE",
+		SO_TIMESTAMP:    "SO_TIMESTAMP",
+	},
+	SOL_TCP: {
+		TCP_NODELAY:              "TCP_NODELAY",
+		TCP_CORK:                 "TCP_CORK",
+		TCP_QUICKACK:             "TCP_QUICKACK",
+		TCP_MAXSEG:               "TCP_MAXSEG",
+		TCP_KEEPIDLE:             "TCP_KEEPIDLE",
+		TCP_KEEPINTVL:            "TCP_KEEPINTVL",
+		TCP_USER_TIMEOUT:         "TCP_USER_TIMEOUT",
+		TCP_INFO:                 "TCP_INFO",
+		TCP_CC_INFO:              "TCP_CC_INFO",
+		TCP_NOTSENT_LOWAT:        "TCP_NOTSENT_LOWAT",
+		TCP_ZEROCOPY_RECEIVE:     "TCP_ZEROCOPY_RECEIVE",
+		TCP_CONGESTION:           "TCP_CONGESTION",
+		TCP_LINGER2:              "TCP_LINGER2",
+		TCP_DEFER_ACCEPT:         "TCP_DEFER_ACCEPT",
+		TCP_REPAIR_OPTIONS:       "TCP_REPAIR_OPTIONS",
+		TCP_INQ:                  "TCP_INQ",
+		TCP_FASTOPEN:             "TCP_FASTOPEN",
+		TCP_FASTOPEN_CONNECT:     "TCP_FASTOPEN_CONNECT",
+		TCP_FASTOPEN_KEY:         "TCP_FASTOPEN_KEY",
+		TCP_FASTOPEN_NO_COOKIE:   "TCP_FASTOPEN_NO_COOKIE",
+		TCP_KEEPCNT:              "TCP_KEEPCNT",
+		TCP_QUEUE_SEQ:            "TCP_QUEUE_SEQ",
+		TCP_REPAIR:               "TCP_REPAIR",
+		TCP_REPAIR_QUEUE:         "TCP_REPAIR_QUEUE",
+		TCP_REPAIR_WINDOW:        "TCP_REPAIR_WINDOW",
+		TCP_SAVED_SYN:            "TCP_SAVED_SYN",
+		TCP_SAVE_SYN:             "TCP_SAVE_SYN",
+		TCP_SYNCNT:               "TCP_SYNCNT",
+		TCP_THIN_DUPACK:          "TCP_THIN_DUPACK",
+		TCP_THIN_LINEAR_TIMEOUTS: "TCP_THIN_LINEAR_TIMEOUTS",
+		TCP_TIMESTAMP:            "TCP_TIMESTAMP",
+		TCP_ULP:                  "TCP_ULP",
+		TCP_WINDOW_CLAMP:         "TCP_WINDOW_CLAMP",
+	},
+	SOL_IPV6: {
+		IPV6_V6ONLY:              "IPV6_V6ONLY",
+		IPV6_PATHMTU:             "IPV6_PATHMTU",
+		IPV6_TCLASS:              "IPV6_TCLASS",
+		IPV6_ADD_MEMBERSHIP:      "IPV6_ADD_MEMBERSHIP",
+		IPV6_DROP_MEMBERSHIP:     "IPV6_DROP_MEMBERSHIP",
+		IPV6_IPSEC_POLICY:        "IPV6_IPSEC_POLICY",
+		IPV6_JOIN_ANYCAST:        "IPV6_JOIN_ANYCAST",
+		IPV6_LEAVE_ANYCAST:       "IPV6_LEAVE_ANYCAST",
+		IPV6_PKTINFO:             "IPV6_PKTINFO",
+		IPV6_ROUTER_ALERT:        "IPV6_ROUTER_ALERT",
+		IPV6_XFRM_POLICY:         "IPV6_XFRM_POLICY",
+		MCAST_BLOCK_SOURCE:       "MCAST_BLOCK_SOURCE",
+		MCAST_JOIN_GROUP:         "MCAST_JOIN_GROUP",
+		MCAST_JOIN_SOURCE_GROUP:  "MCAST_JOIN_SOURCE_GROUP",
+		MCAST_LEAVE_GROUP:        "MCAST_LEAVE_GROUP",
+		MCAST_LEAVE_SOURCE_GROUP: "MCAST_LEAVE_SOURCE_GROUP",
+		MCAST_UNBLOCK_SOURCE:     "MCAST_UNBLOCK_SOURCE",
+		IPV6_2292DSTOPTS:         "IPV6_2292DSTOPTS",
+		IPV6_2292HOPLIMIT:        "IPV6_2292HOPLIMIT",
+		IPV6_2292HOPOPTS:         "IPV6_2292HOPOPTS",
+		IPV6_2292PKTINFO:         "IPV6_2292PKTINFO",
+		IPV6_2292PKTOPTIONS:      "IPV6_2292PKTOPTIONS",
+		IPV6_2292RTHDR:           "IPV6_2292RTHDR",
+		IPV6_ADDR_PREFERENCES:    "IPV6_ADDR_PREFERENCES",
+		IPV6_AUTOFLOWLABEL:       "IPV6_AUTOFLOWLABEL",
+		IPV6_DONTFRAG:            "IPV6_DONTFRAG",
+		IPV6_DSTOPTS:             "IPV6_DSTOPTS",
+		IPV6_FLOWINFO:            "IPV6_FLOWINFO",
+		IPV6_FLOWINFO_SEND:       "IPV6_FLOWINFO_SEND",
+		IPV6_FLOWLABEL_MGR:       "IPV6_FLOWLABEL_MGR",
+		IPV6_FREEBIND:            "IPV6_FREEBIND",
+		IPV6_HOPOPTS:             "IPV6_HOPOPTS",
+		IPV6_MINHOPCOUNT:         "IPV6_MINHOPCOUNT",
+		IPV6_MTU:                 "IPV6_MTU",
+		IPV6_MTU_DISCOVER:        "IPV6_MTU_DISCOVER",
+		IPV6_MULTICAST_ALL:       "IPV6_MULTICAST_ALL",
+		IPV6_MULTICAST_HOPS:      "IPV6_MULTICAST_HOPS",
+		IPV6_MULTICAST_IF:        "IPV6_MULTICAST_IF",
+		IPV6_MULTICAST_LOOP:      "IPV6_MULTICAST_LOOP",
+		IPV6_RECVDSTOPTS:         "IPV6_RECVDSTOPTS",
+		IPV6_RECVERR:             "IPV6_RECVERR",
+		IPV6_RECVFRAGSIZE:        "IPV6_RECVFRAGSIZE",
+		IPV6_RECVHOPLIMIT:        "IPV6_RECVHOPLIMIT",
+		IPV6_RECVHOPOPTS:         "IPV6_RECVHOPOPTS",
+		IPV6_RECVORIGDSTADDR:     "IPV6_RECVORIGDSTADDR",
+		IPV6_RECVPATHMTU:         "IPV6_RECVPATHMTU",
+		IPV6_RECVPKTINFO:         "IPV6_RECVPKTINFO",
+		IPV6_RECVRTHDR:           "IPV6_RECVRTHDR",
+		IPV6_RECVTCLASS:          "IPV6_RECVTCLASS",
+		IPV6_RTHDR:               "IPV6_RTHDR",
+		IPV6_RTHDRDSTOPTS:        "IPV6_RTHDRDSTOPTS",
+		IPV6_TRANSPARENT:         "IPV6_TRANSPARENT",
+		IPV6_UNICAST_HOPS:        "IPV6_UNICAST_HOPS",
+		IPV6_UNICAST_IF:          "IPV6_UNICAST_IF",
+		MCAST_MSFILTER:           "MCAST_MSFILTER",
+		IPV6_ADDRFORM:            "IPV6_ADDRFORM",
+		IP6T_SO_GET_INFO:         "IP6T_SO_GET_INFO",
+		IP6T_SO_GET_ENTRIES:      "IP6T_SO_GET_ENTRIES",
+	},
+	SOL_NETLINK: {
+		NETLINK_BROADCAST_ERROR:  "NETLINK_BROADCAST_ERROR",
+		NETLINK_CAP_ACK:          "NETLINK_CAP_ACK",
+		NETLINK_DUMP_STRICT_CHK:  "NETLINK_DUMP_STRICT_CHK",
+		NETLINK_EXT_ACK:          "NETLINK_EXT_ACK",
+		NETLINK_LIST_MEMBERSHIPS: "NETLINK_LIST_MEMBERSHIPS",
+		NETLINK_NO_ENOBUFS:       "NETLINK_NO_ENOBUFS",
+		NETLINK_PKTINFO:          "NETLINK_PKTINFO",
+	},
+}
@@ -16,15 +16,20 @@ package control
 
 import (
 	"fmt"
+	"os"
+	"sort"
 	"strings"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/fd"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/fs/user"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/limits"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/urpc"
@@ -190,18 +195,103 @@ func (l *Lifecycle) StartContainer(args *StartContainerArgs, _ *uint32) error {
 	return nil
 }
 
+// Initiator identifies the host process that calls into the control server,
+// to attribute lifecycle points to it.
+type Initiator struct {
+	PID  int32
+	UID  uint32
+	GID  uint32
+	Args []string
+}
+
+// CurrentInitiator returns the Initiator for the calling process.
+func CurrentInitiator() Initiator {
+	return Initiator{
+		PID:  int32(os.Getpid()),
+		UID:  uint32(os.Getuid()),
+		GID:  uint32(os.Getgid()),
+		Args: os.Args,
+	}
+}
+
+// PauseResumeArgs is the set of arguments to pause and resume the sandbox.
+type PauseResumeArgs struct {
+	// Initiator is the process that requests the operation. It's trusted,
+	// since the control socket is only reachable from the host.
+	Initiator Initiator
+}
+
+// Proto returns the initiator as a seccheck point field. It returns nil if the
+// initiator is unset.
+func (i *Initiator) Proto() *pb.Initiator {
+	if i == nil || i.PID == 0 {
+		return nil
+	}
+	return &pb.Initiator{
+		Pid:  i.PID,
+		Uid:  i.UID,
+		Gid:  i.GID,
+		Args: i.Args,
+	}
+}
+
 // Pause pauses all tasks, blocking until they are stopped.
-func (l *Lifecycle) Pause(_, _ *struct{}) error {
+func (l *Lifecycle) Pause(args *PauseResumeArgs, _ *struct{}) error {
 	l.Kernel.Pause()
+	if seccheck.Global.Enabled(seccheck.PointContainerPause) {
+		fields := seccheck.Global.GetFieldSet(seccheck.PointContainerPause)
+		var initiator *pb.Initiator
+		if args != nil {
+			initiator = args.Initiator.Proto()
+		}
+		for _, id := range l.containerIDs() {
+			evt := pb.Pause{Id: id, Workload: seccheck.Workload(id), Initiator: initiator}
+			evt.TimeNs, evt.MonotonicTimeNs = l.Kernel.SeccheckTime()
+			_ = seccheck.Global.SendToCheckers(id, seccheck.PointContainerPause, func(c seccheck.Checker) error {
+				return c.ContainerPause(context.Background(), fields, &evt)
+			})
+		}
+	}
 	return nil
 }
 
 // Resume resumes all tasks.
-func (l *Lifecycle) Resume(_, _ *struct{}) error {
+func (l *Lifecycle) Resume(args *PauseResumeArgs, _ *struct{}) error {
 	l.Kernel.Unpause()
+	if seccheck.Global.Enabled(seccheck.PointContainerResume) {
+		fields := seccheck.Global.GetFieldSet(seccheck.PointContainerResume)
+		var initiator *pb.Initiator
+		if args != nil {
+			initiator = args.Initiator.Proto()
+		}
+		for _, id := range l.containerIDs() {
+			evt := pb.Resume{Id: id, Workload: seccheck.Workload(id), Initiator: initiator}
+			evt.TimeNs, evt.MonotonicTimeNs = l.Kernel.SeccheckTime()
+			_ = seccheck.Global.SendToCheckers(id, seccheck.PointContainerResume, func(c seccheck.Checker) error {
+				return c.ContainerResume(context.Background(), fields, &evt)
+			})
+		}
+	}
 	return nil
 }
 
+// containerIDs returns the sorted IDs of all containers that have tasks
+// in the kernel.
+func (l *Lifecycle) containerIDs() []string {
+	set := make(map[string]struct{})
+	for _, tg := range l.Kernel.RootPIDNamespace().ThreadGroups() {
+		if leader := tg.Leader(); leader != nil {
+			set[leader.ContainerID()] = struct{}{}
+		}
+	}
+	ids := make([]string, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // Shutdown sends signal to destroy the sentry/sandbox.
 func (l *Lifecycle) Shutdown(_, _ *struct{}) error {
 	close(l.ShutdownCh)
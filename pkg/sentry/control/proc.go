@@ -102,6 +102,10 @@ type ExecArgs struct {
 
 	// Limits is the limit set for the process being executed.
 	Limits *limits.LimitSet
+
+	// Initiator is the host process that requests the execution. It's only
+	// used to report seccheck.PointContainerExec.
+	Initiator Initiator
 }
 
 // String prints the arguments as a string.
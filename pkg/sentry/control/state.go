@@ -18,8 +18,11 @@ import (
 	"errors"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 	"gvisor.dev/gvisor/pkg/sentry/state"
 	"gvisor.dev/gvisor/pkg/sentry/watchdog"
 	"gvisor.dev/gvisor/pkg/urpc"
@@ -55,6 +58,12 @@ func (s *State) Save(o *SaveOpts, _ *struct{}) error {
 	}
 	defer o.FilePayload.Files[0].Close()
 
+	// Allocate the metadata map here, so that metadata added while saving can be
+	// reported below.
+	if o.Metadata == nil {
+		o.Metadata = make(map[string]string)
+	}
+
 	// Save to the first provided stream.
 	saveOpts := state.SaveOpts{
 		Destination: o.FilePayload.Files[0],
@@ -68,6 +77,17 @@ func (s *State) Save(o *SaveOpts, _ *struct{}) error {
 				log.Warningf("Save failed: exiting...")
 				s.Kernel.SetSaveError(err)
 			}
+			if seccheck.Global.Enabled(seccheck.PointCheckpoint) {
+				info := pb.CheckpointInfo{Metadata: o.Metadata, Workload: seccheck.Workload("" /* cid */)}
+				info.TimeNs, info.MonotonicTimeNs = s.Kernel.SeccheckTime()
+				if err != nil {
+					info.Error = err.Error()
+				}
+				fields := seccheck.Global.GetFieldSet(seccheck.PointCheckpoint)
+				_ = seccheck.Global.SendToCheckers("" /* cid */, seccheck.PointCheckpoint, func(c seccheck.Checker) error {
+					return c.Checkpoint(context.Background(), fields, &info)
+				})
+			}
 			s.Kernel.Kill(linux.WaitStatusExit(0))
 		},
 	}
@@ -348,7 +348,7 @@ func (f *fileInodeOperations) read(ctx context.Context, file *fs.File, dst userm
 		return 0, io.EOF
 	}
 
-	n, err := dst.CopyOutFrom(ctx, &fileReadWriter{f, offset})
+	n, err := dst.CopyOutFrom(ctx, &fileReadWriter{f: f, offset: offset})
 	if !file.Dirent.Inode.MountSource.Flags.NoAtime {
 		// Compare Linux's mm/filemap.c:do_generic_file_read() => file_accessed().
 		f.attrMu.Lock()
@@ -370,7 +370,11 @@ func (f *fileInodeOperations) write(ctx context.Context, src usermem.IOSequence,
 	now := ktime.NowFromContext(ctx)
 	f.attr.ModificationTime = now
 	f.attr.StatusChangeTime = now
-	nwritten, err := src.CopyInTo(ctx, &fileReadWriter{f, offset})
+	rw := &fileReadWriter{f: f, offset: offset}
+	nwritten, err := src.CopyInTo(ctx, rw)
+	if rw.oomLength != 0 {
+		pgalloc.NotifyOOM(ctx, rw.oomLength)
+	}
 
 	// Writing clears privilege bits.
 	if nwritten > 0 {
@@ -383,6 +387,11 @@ func (f *fileInodeOperations) write(ctx context.Context, src usermem.IOSequence,
 type fileReadWriter struct {
 	f      *fileInodeOperations
 	offset int64
+
+	// oomLength is the length of the allocation that failed in
+	// WriteFromBlocks, if any. It's reported by the caller, which doesn't
+	// hold dataMu, see pgalloc.NotifyOOM.
+	oomLength uint64
 }
 
 // ReadToBlocks implements safemem.Reader.ReadToBlocks.
@@ -531,6 +540,7 @@ func (rw *fileReadWriter) WriteFromBlocks(srcs safemem.BlockSeq) (uint64, error)
 			gapMR := gap.Range().Intersect(pgMR)
 			fr, err := mf.Allocate(gapMR.Length(), pgalloc.AllocOpts{Kind: rw.f.memUsage})
 			if err != nil {
+				rw.oomLength = gapMR.Length()
 				return done, err
 			}
 
@@ -23,6 +23,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/usermem"
 	"gvisor.dev/gvisor/pkg/waiter"
@@ -134,12 +135,20 @@ func (rfd *replicaFileDescription) Epollable() bool {
 
 // Read implements vfs.FileDescriptionImpl.Read.
 func (rfd *replicaFileDescription) Read(ctx context.Context, dst usermem.IOSequence, _ vfs.ReadOptions) (int64, error) {
-	return rfd.inode.t.ld.inputQueueRead(ctx, dst)
+	n, err := rfd.inode.t.ld.inputQueueRead(ctx, dst)
+	if n > 0 && seccheck.Global.Enabled(seccheck.PointTTYData) {
+		kernel.TTYDataSeccheck(ctx, rfd.inode.t.seccheckName(), false /* output */, dst, n)
+	}
+	return n, err
 }
 
 // Write implements vfs.FileDescriptionImpl.Write.
 func (rfd *replicaFileDescription) Write(ctx context.Context, src usermem.IOSequence, _ vfs.WriteOptions) (int64, error) {
-	return rfd.inode.t.ld.outputQueueWrite(ctx, src)
+	n, err := rfd.inode.t.ld.outputQueueWrite(ctx, src)
+	if n > 0 && seccheck.Global.Enabled(seccheck.PointTTYData) {
+		kernel.TTYDataSeccheck(ctx, rfd.inode.t.seccheckName(), true /* output */, src, n)
+	}
+	return n, err
 }
 
 // Ioctl implements vfs.FileDescriptionImpl.Ioctl.
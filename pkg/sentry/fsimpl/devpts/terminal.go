@@ -15,6 +15,8 @@
 package devpts
 
 import (
+	"fmt"
+
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/marshal/primitive"
@@ -52,6 +54,11 @@ func newTerminal(n uint32) *Terminal {
 	return &t
 }
 
+// seccheckName returns the name used to identify tm in seccheck points.
+func (tm *Terminal) seccheckName() string {
+	return fmt.Sprintf("pts/%d", tm.n)
+}
+
 // setControllingTTY makes tm the controlling terminal of the calling thread
 // group.
 func (tm *Terminal) setControllingTTY(ctx context.Context, steal bool, isMaster, isReadable bool) error {
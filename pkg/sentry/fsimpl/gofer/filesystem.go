@@ -33,6 +33,8 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/pipe"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 	"gvisor.dev/gvisor/pkg/sentry/socket/unix/transport"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 )
@@ -296,6 +298,9 @@ func (fs *filesystem) getChildAndWalkPathLocked(ctx context.Context, parent *den
 		names = append(names, name)
 	}
 	status, inodes, err := parent.controlFDLisa.WalkMultiple(ctx, names)
+	if seccheck.Global.Enabled(seccheck.PointGoferOp) {
+		parent.goferOpSeccheckLocked(ctx, pb.GoferOpInfo_OP_WALK, err, names...)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -386,6 +391,9 @@ func (fs *filesystem) getChildLocked(ctx context.Context, parent *dentry, name s
 	var child *dentry
 	if fs.opts.lisaEnabled {
 		childInode, err := parent.controlFDLisa.Walk(ctx, name)
+		if seccheck.Global.Enabled(seccheck.PointGoferOp) {
+			parent.goferOpSeccheckLocked(ctx, pb.GoferOpInfo_OP_WALK, err, name)
+		}
 		if err != nil {
 			if linuxerr.Equals(linuxerr.ENOENT, err) {
 				parent.cacheNegativeLookupLocked(name)
@@ -400,6 +408,9 @@ func (fs *filesystem) getChildLocked(ctx context.Context, parent *dentry, name s
 		}
 	} else {
 		qid, file, attrMask, attr, err := parent.file.walkGetAttrOne(ctx, name)
+		if seccheck.Global.Enabled(seccheck.PointGoferOp) {
+			parent.goferOpSeccheckLocked(ctx, pb.GoferOpInfo_OP_WALK, err, name)
+		}
 		if err != nil {
 			if linuxerr.Equals(linuxerr.ENOENT, err) {
 				parent.cacheNegativeLookupLocked(name)
@@ -579,7 +590,11 @@ func (fs *filesystem) doCreateAt(ctx context.Context, rp *vfs.ResolvingPath, dir
 	// No cached dentry exists; however, in InteropModeShared there might still be
 	// an existing file at name. Just attempt the file creation RPC anyways. If a
 	// file does exist, the RPC will fail with EEXIST like we would have.
-	if err := createInRemoteDir(parent, name, &ds); err != nil {
+	err = createInRemoteDir(parent, name, &ds)
+	if seccheck.Global.Enabled(seccheck.PointGoferOp) {
+		parent.goferOpSeccheckLocked(ctx, pb.GoferOpInfo_OP_CREATE, err, name)
+	}
+	if err != nil {
 		return err
 	}
 	if fs.opts.interop != InteropModeShared {
@@ -740,6 +755,9 @@ func (fs *filesystem) unlinkAt(ctx context.Context, rp *vfs.ResolvingPath, dir b
 		} else {
 			err = parent.file.unlinkAt(ctx, name, flags)
 		}
+		if seccheck.Global.Enabled(seccheck.PointGoferOp) {
+			parent.goferOpSeccheckLocked(ctx, pb.GoferOpInfo_OP_UNLINK, err, name)
+		}
 		if err != nil {
 			if child != nil {
 				vfsObj.AbortDeleteDentry(&child.vfsd) // +checklocksforce: see above.
@@ -1122,12 +1140,20 @@ afterTrailingSymlink:
 
 // Preconditions: The caller must hold no locks (since opening pipes may block
 // indefinitely).
-func (d *dentry) open(ctx context.Context, rp *vfs.ResolvingPath, opts *vfs.OpenOptions) (*vfs.FileDescription, error) {
+func (d *dentry) open(ctx context.Context, rp *vfs.ResolvingPath, opts *vfs.OpenOptions) (_ *vfs.FileDescription, retErr error) {
 	ats := vfs.AccessTypesForOpenFlags(opts)
 	if err := d.checkPermissions(rp.Credentials(), ats); err != nil {
 		return nil, err
 	}
 
+	if seccheck.Global.Enabled(seccheck.PointGoferOp) && !d.isSynthetic() {
+		// Deferred before any lock is taken below, since reporting requires
+		// d.fs.renameMu.
+		defer func() {
+			d.goferOpSeccheck(ctx, pb.GoferOpInfo_OP_OPEN, retErr)
+		}()
+	}
+
 	trunc := opts.Flags&linux.O_TRUNC != 0 && d.fileType() == linux.S_IFREG
 	if trunc {
 		// Lock metadataMu *while* we open a regular file with O_TRUNC because
@@ -1333,6 +1359,9 @@ func (d *dentry) createAndOpenChildLocked(ctx context.Context, rp *vfs.Resolving
 	openHostFD := int32(-1)
 	if d.fs.opts.lisaEnabled {
 		ino, openFD, hostFD, err := d.controlFDLisa.OpenCreateAt(ctx, name, opts.Flags&linux.O_ACCMODE, opts.Mode, lisafs.UID(creds.EffectiveKUID), lisafs.GID(kgid))
+		if seccheck.Global.Enabled(seccheck.PointGoferOp) {
+			d.goferOpSeccheckLocked(ctx, pb.GoferOpInfo_OP_CREATE, err, name)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -1360,6 +1389,9 @@ func (d *dentry) createAndOpenChildLocked(ctx context.Context, rp *vfs.Resolving
 		createFlags := p9.OpenFlags(opts.Flags) & p9.OpenFlagsModeMask
 
 		fdobj, openFile, createQID, _, err := dirfile.create(ctx, name, createFlags, p9.FileMode(opts.Mode), (p9.UID)(creds.EffectiveKUID), p9.GID(kgid))
+		if seccheck.Global.Enabled(seccheck.PointGoferOp) {
+			d.goferOpSeccheckLocked(ctx, pb.GoferOpInfo_OP_CREATE, err, name)
+		}
 		if err != nil {
 			dirfile.close(ctx)
 			return nil, err
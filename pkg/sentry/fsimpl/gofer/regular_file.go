@@ -826,6 +826,10 @@ func (d *dentry) Translate(ctx context.Context, required, optional memmap.Mappab
 
 	mf := d.fs.mfp.MemoryFile()
 	h := d.readHandleLocked()
+	if d.cache.SpanRange(required) != required.Length() {
+		// Some of the required range must be read from the remote file.
+		memmap.RecordMajorFault(ctx)
+	}
 	cerr := d.cache.Fill(ctx, required, maxFillRange(required, optional), d.size.Load(), mf, usage.PageCache, h.readToBlocksAt)
 
 	var ts []memmap.Translation
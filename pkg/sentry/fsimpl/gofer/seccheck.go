@@ -0,0 +1,55 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gofer
+
+import (
+	"path"
+
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// goferOpSeccheck reports an operation performed by the gofer client on d, or
+// on the file reached by walking names from d, to seccheck.
+//
+// Preconditions: d.fs.renameMu must be unlocked.
+func (d *dentry) goferOpSeccheck(ctx context.Context, op pb.GoferOpInfo_Op, err error, names ...string) {
+	d.fs.renameMu.RLock()
+	defer d.fs.renameMu.RUnlock()
+	d.goferOpSeccheckLocked(ctx, op, err, names...)
+}
+
+// goferOpSeccheckLocked is equivalent to goferOpSeccheck, but requires
+// d.fs.renameMu to be locked.
+//
+// Preconditions: d.fs.renameMu must be locked.
+func (d *dentry) goferOpSeccheckLocked(ctx context.Context, op pb.GoferOpInfo_Op, err error, names ...string) {
+	info := &pb.GoferOpInfo{
+		Op:    op,
+		Aname: d.fs.opts.aname,
+		Path:  path.Join(append([]string{genericDebugPathname(d)}, names...)...),
+		Errno: int32(kernel.ExtractErrno(err, -1)),
+	}
+	fields := seccheck.Global.GetFieldSet(seccheck.PointGoferOp)
+	if t := kernel.TaskFromContext(ctx); t != nil && !fields.Context.Empty() {
+		info.ContextData = &pb.ContextData{}
+		kernel.LoadSeccheckData(t, fields.Context, info.ContextData)
+	}
+	seccheck.Global.SendToCheckers(seccheck.ContainerID(ctx), seccheck.PointGoferOp, func(c seccheck.Checker) error {
+		return c.GoferOp(ctx, fields, info)
+	})
+}
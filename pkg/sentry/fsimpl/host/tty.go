@@ -21,6 +21,7 @@ import (
 	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	"gvisor.dev/gvisor/pkg/sentry/unimpl"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sync"
@@ -92,7 +93,11 @@ func (t *TTYFileDescription) PRead(ctx context.Context, dst usermem.IOSequence,
 	}
 
 	// Do the read.
-	return t.fileDescription.PRead(ctx, dst, offset, opts)
+	n, err := t.fileDescription.PRead(ctx, dst, offset, opts)
+	if n > 0 && seccheck.Global.Enabled(seccheck.PointTTYData) {
+		kernel.TTYDataSeccheck(ctx, "host", false /* output */, dst, n)
+	}
+	return n, err
 }
 
 // Read implements vfs.FileDescriptionImpl.Read.
@@ -110,7 +115,11 @@ func (t *TTYFileDescription) Read(ctx context.Context, dst usermem.IOSequence, o
 	}
 
 	// Do the read.
-	return t.fileDescription.Read(ctx, dst, opts)
+	n, err := t.fileDescription.Read(ctx, dst, opts)
+	if n > 0 && seccheck.Global.Enabled(seccheck.PointTTYData) {
+		kernel.TTYDataSeccheck(ctx, "host", false /* output */, dst, n)
+	}
+	return n, err
 }
 
 // PWrite implements vfs.FileDescriptionImpl.PWrite.
@@ -125,7 +134,11 @@ func (t *TTYFileDescription) PWrite(ctx context.Context, src usermem.IOSequence,
 			return 0, err
 		}
 	}
-	return t.fileDescription.PWrite(ctx, src, offset, opts)
+	n, err := t.fileDescription.PWrite(ctx, src, offset, opts)
+	if n > 0 && seccheck.Global.Enabled(seccheck.PointTTYData) {
+		kernel.TTYDataSeccheck(ctx, "host", true /* output */, src, n)
+	}
+	return n, err
 }
 
 // Write implements vfs.FileDescriptionImpl.Write.
@@ -140,7 +153,11 @@ func (t *TTYFileDescription) Write(ctx context.Context, src usermem.IOSequence,
 			return 0, err
 		}
 	}
-	return t.fileDescription.Write(ctx, src, opts)
+	n, err := t.fileDescription.Write(ctx, src, opts)
+	if n > 0 && seccheck.Global.Enabled(seccheck.PointTTYData) {
+		kernel.TTYDataSeccheck(ctx, "host", true /* output */, src, n)
+	}
+	return n, err
 }
 
 // Ioctl implements vfs.FileDescriptionImpl.Ioctl.
@@ -50,6 +50,10 @@ type regularFile struct {
 	// this regularFile's contents are accounted.
 	memoryUsageKind usage.MemoryKind
 
+	// memfd is true if the file was created by memfd_create(2). It is
+	// immutable.
+	memfd bool
+
 	// mapsMu protects mappings.
 	mapsMu sync.Mutex `state:"nosave"`
 
@@ -162,12 +166,21 @@ func NewMemfd(ctx context.Context, creds *auth.Credentials, mount *vfs.Mount, al
 	if err != nil {
 		return nil, err
 	}
+	rf := fd.inode().impl.(*regularFile)
+	rf.memfd = true
 	if allowSeals {
-		fd.inode().impl.(*regularFile).seals = 0
+		rf.seals = 0
 	}
 	return &fd.vfsfd, nil
 }
 
+// IsMemfd returns true if fd represents a file created by NewMemfd, including
+// if it was reopened, e.g. through /proc/[pid]/fd.
+func IsMemfd(fd *vfs.FileDescription) bool {
+	rfd, ok := fd.Impl().(*regularFileFD)
+	return ok && rfd.inode().impl.(*regularFile).memfd
+}
+
 // truncate grows or shrinks the file to the given size. It returns true if the
 // file size was updated.
 func (rf *regularFile) truncate(newSize uint64) (bool, error) {
@@ -469,6 +482,9 @@ func (fd *regularFileFD) pwrite(ctx context.Context, src usermem.IOSequence, off
 	// Perform the write.
 	rw := getRegularFileReadWriter(f, offset)
 	n, err := src.CopyInTo(ctx, rw)
+	if rw.oomLength != 0 {
+		pgalloc.NotifyOOM(ctx, rw.oomLength)
+	}
 
 	// Correct page accounting if this was a partial write.
 	if maybeSizeInc && srclen-n != 0 {
@@ -533,6 +549,11 @@ type regularFileReadWriter struct {
 	// Offset into the file to read/write at. Note that this may be
 	// different from the FD offset if PRead/PWrite is used.
 	off uint64
+
+	// oomLength is the length of the allocation that failed in
+	// WriteFromBlocks, if any. It's reported by the caller, which doesn't
+	// hold dataMu, see pgalloc.NotifyOOM.
+	oomLength uint64
 }
 
 var regularFileReadWriterPool = sync.Pool{
@@ -550,6 +571,7 @@ func getRegularFileReadWriter(file *regularFile, offset int64) *regularFileReadW
 
 func putRegularFileReadWriter(rw *regularFileReadWriter) {
 	rw.file = nil
+	rw.oomLength = 0
 	regularFileReadWriterPool.Put(rw)
 }
 
@@ -693,6 +715,7 @@ func (rw *regularFileReadWriter) WriteFromBlocks(srcs safemem.BlockSeq) (uint64,
 			gapMR := gap.Range().Intersect(pgMR)
 			fr, err := rw.file.memFile.Allocate(gapMR.Length(), pgalloc.AllocOpts{Kind: rw.file.memoryUsageKind})
 			if err != nil {
+				rw.oomLength = gapMR.Length()
 				retErr = err
 				goto exitLoop
 			}
@@ -24,6 +24,7 @@ import (
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/contexttest"
 	"gvisor.dev/gvisor/pkg/sentry/fs/lock"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/usermem"
 )
@@ -347,3 +348,24 @@ func TestTruncate(t *testing.T) {
 		t.Errorf("fd.Stat got Ctime %v, want %v", got, statAfterTruncateUp.Ctime)
 	}
 }
+
+func TestIsMemfd(t *testing.T) {
+	ctx := contexttest.Context(t)
+	fd, cleanup, err := newFileFD(ctx, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	if IsMemfd(fd) {
+		t.Errorf("IsMemfd() got true for a regular file, want false")
+	}
+
+	memfd, err := NewMemfd(ctx, auth.CredentialsFromContext(ctx), fd.Mount(), false /* allowSeals */, "memfd:test")
+	if err != nil {
+		t.Fatalf("NewMemfd failed: %v", err)
+	}
+	defer memfd.DecRef(ctx)
+	if !IsMemfd(memfd) {
+		t.Errorf("IsMemfd() got false for a memfd, want true")
+	}
+}
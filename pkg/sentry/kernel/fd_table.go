@@ -27,7 +27,9 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/fs"
 	"gvisor.dev/gvisor/pkg/sentry/fs/lock"
 	"gvisor.dev/gvisor/pkg/sentry/limits"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
 )
 
 // FDFlags define flags for an individual descriptor.
@@ -91,6 +93,26 @@ type FDTable struct {
 
 	// descriptorTable holds descriptors.
 	descriptorTable `state:".(map[int32]descriptor)"`
+
+	// pathsMu protects paths. It's acquired after mu.
+	pathsMu sync.Mutex `state:"nosave"`
+
+	// paths caches the paths of files resolved for trace points, so that
+	// points of hot read and write loops don't walk the dentry tree every
+	// time. Entries are invalidated when their FD is closed or replaced, see
+	// setAll.
+	//
+	// +checklocks:pathsMu
+	paths map[int32]fdPath `state:"nosave"`
+}
+
+// fdPath is the path of a file, resolved relative to root.
+type fdPath struct {
+	// file and root are only compared, to check that the FD still refers to
+	// the same file in case the entry was added after it was invalidated.
+	file *vfs.FileDescription
+	root vfs.VirtualDentry
+	path string
 }
 
 func (f *FDTable) saveDescriptorTable() map[int32]descriptor {
@@ -305,6 +327,9 @@ func (f *FDTable) NewFDs(ctx context.Context, minFD int32, files []*fs.File, fla
 			end = int32(lim.Cur)
 		}
 		if minFD+int32(len(files)) > end {
+			if seccheck.Global.Enabled(seccheck.PointRLimitBreach) {
+				RLimitSeccheck(ctx, limits.NumberOfFiles)
+			}
 			return nil, unix.EMFILE
 		}
 	}
@@ -356,6 +381,9 @@ func (f *FDTable) NewFDs(ctx context.Context, minFD int32, files []*fs.File, fla
 		for _, file := range files[:len(fds)] {
 			file.DecRef(ctx)
 		}
+		if seccheck.Global.Enabled(seccheck.PointRLimitBreach) {
+			RLimitSeccheck(ctx, limits.NumberOfFiles)
+		}
 		return nil, unix.EMFILE
 	}
 
@@ -382,6 +410,9 @@ func (f *FDTable) NewFDsVFS2(ctx context.Context, minFD int32, files []*vfs.File
 			end = int32(lim.Cur)
 		}
 		if minFD >= end {
+			if seccheck.Global.Enabled(seccheck.PointRLimitBreach) {
+				RLimitSeccheck(ctx, limits.NumberOfFiles)
+			}
 			return nil, unix.EMFILE
 		}
 	}
@@ -432,6 +463,9 @@ func (f *FDTable) NewFDsVFS2(ctx context.Context, minFD int32, files []*vfs.File
 		for _, file := range files[:len(fds)] {
 			file.DecRef(ctx)
 		}
+		if seccheck.Global.Enabled(seccheck.PointRLimitBreach) {
+			RLimitSeccheck(ctx, limits.NumberOfFiles)
+		}
 		return nil, unix.EMFILE
 	}
 
@@ -487,6 +521,9 @@ func (f *FDTable) newFDAt(ctx context.Context, fd int32, file *fs.File, fileVFS2
 	// Check the limit for the provided file.
 	if limitSet := limits.FromContext(ctx); limitSet != nil {
 		if lim := limitSet.Get(limits.NumberOfFiles); lim.Cur != limits.Infinity && uint64(fd) >= lim.Cur {
+			if seccheck.Global.Enabled(seccheck.PointRLimitBreach) {
+				RLimitSeccheck(ctx, limits.NumberOfFiles)
+			}
 			return nil, nil, unix.EMFILE
 		}
 	}
@@ -638,6 +675,35 @@ func (f *FDTable) GetVFS2(fd int32) (*vfs.FileDescription, FDFlags) {
 	}
 }
 
+// CachedPath returns the path of file at fd, resolved relative to root, if it
+// was cached with CachePath. Paths are not updated when files are renamed.
+func (f *FDTable) CachedPath(fd int32, file *vfs.FileDescription, root vfs.VirtualDentry) (string, bool) {
+	f.pathsMu.Lock()
+	defer f.pathsMu.Unlock()
+	p, ok := f.paths[fd]
+	if !ok || p.file != file || p.root != root {
+		return "", false
+	}
+	return p.path, true
+}
+
+// CachePath caches path as the path of file at fd, resolved relative to root.
+func (f *FDTable) CachePath(fd int32, file *vfs.FileDescription, root vfs.VirtualDentry, path string) {
+	f.pathsMu.Lock()
+	defer f.pathsMu.Unlock()
+	if f.paths == nil {
+		f.paths = make(map[int32]fdPath)
+	}
+	f.paths[fd] = fdPath{file: file, root: root, path: path}
+}
+
+// invalidatePath removes the cached path of fd, if any.
+func (f *FDTable) invalidatePath(fd int32) {
+	f.pathsMu.Lock()
+	defer f.pathsMu.Unlock()
+	delete(f.paths, fd)
+}
+
 // GetFDs returns a sorted list of valid fds.
 //
 // Precondition: The caller must be running on the task goroutine, or Task.mu
@@ -23,6 +23,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/fs"
 	"gvisor.dev/gvisor/pkg/sentry/fs/filetest"
 	"gvisor.dev/gvisor/pkg/sentry/limits"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sync"
 )
 
@@ -162,6 +163,42 @@ func TestFDTable(t *testing.T) {
 	})
 }
 
+func TestCachedPath(t *testing.T) {
+	runTest(t, func(ctx context.Context, fdTable *FDTable, file *fs.File, _ *limits.LimitSet) {
+		// The cache only compares files and roots, so they don't need to be
+		// real.
+		cached, other := &vfs.FileDescription{}, &vfs.FileDescription{}
+		root := vfs.VirtualDentry{}
+		for _, fd := range []int32{0, 1} {
+			if err := fdTable.NewFDAt(ctx, fd, file, FDFlags{}); err != nil {
+				t.Fatalf("fdTable.NewFDAt(%d): %v", fd, err)
+			}
+			fdTable.CachePath(fd, cached, root, "/foo")
+		}
+
+		if path, ok := fdTable.CachedPath(0, cached, root); !ok || path != "/foo" {
+			t.Errorf("fdTable.CachedPath(0): got (%q, %t), want (%q, true)", path, ok, "/foo")
+		}
+		if path, ok := fdTable.CachedPath(0, other, root); ok {
+			t.Errorf("fdTable.CachedPath(0) for another file: got %q, want no path", path)
+		}
+
+		// Closing or replacing an FD invalidates its path.
+		if ref, _ := fdTable.Remove(ctx, 0); ref != nil {
+			ref.DecRef(ctx)
+		}
+		if path, ok := fdTable.CachedPath(0, cached, root); ok {
+			t.Errorf("fdTable.CachedPath(0) after close: got %q, want no path", path)
+		}
+		if err := fdTable.NewFDAt(ctx, 1, file, FDFlags{}); err != nil {
+			t.Fatalf("fdTable.NewFDAt(1): %v", err)
+		}
+		if path, ok := fdTable.CachedPath(1, cached, root); ok {
+			t.Errorf("fdTable.CachedPath(1) after dup: got %q, want no path", path)
+		}
+	})
+}
+
 func TestDescriptorFlags(t *testing.T) {
 	runTest(t, func(ctx context.Context, fdTable *FDTable, file *fs.File, _ *limits.LimitSet) {
 		if err := fdTable.NewFDAt(ctx, 2, file, FDFlags{CloseOnExec: true}); err != nil {
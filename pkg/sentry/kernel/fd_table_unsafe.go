@@ -150,6 +150,9 @@ func (f *FDTable) setAll(ctx context.Context, fd int32, file *fs.File, fileVFS2
 
 	// Update the single element.
 	orig := (*descriptor)(atomic.SwapPointer(&slice[fd], unsafe.Pointer(desc)))
+	if orig != nil {
+		f.invalidatePath(fd)
+	}
 
 	// Acquire a table reference.
 	if desc != nil {
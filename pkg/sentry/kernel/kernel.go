@@ -67,6 +67,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/mm"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/port"
 	sentrytime "gvisor.dev/gvisor/pkg/sentry/time"
 	"gvisor.dev/gvisor/pkg/sentry/unimpl"
@@ -113,6 +114,9 @@ func (uc *userCounters) incRLimitNProc(ctx context.Context) error {
 		!creds.HasCapability(linux.CAP_SYS_ADMIN) &&
 		!creds.HasCapability(linux.CAP_SYS_RESOURCE) {
 		uc.rlimitNProc.Add(^uint64(0))
+		if seccheck.Global.Enabled(seccheck.PointRLimitBreach) {
+			RLimitSeccheck(ctx, limits.ProcessCount)
+		}
 		return linuxerr.EAGAIN
 	}
 	return nil
@@ -180,6 +184,16 @@ type Kernel struct {
 	// to CreateProcess, and is protected by extMu.
 	globalInit *ThreadGroup
 
+	// containerStartsMu protects containerStarts.
+	containerStartsMu sync.Mutex `state:"nosave"`
+
+	// containerStarts maps the IDs of containers to the time that their
+	// first process was created by CreateProcess, i.e. the time that they
+	// started. See Task.driftSeccheck.
+	//
+	// containerStarts is protected by containerStartsMu.
+	containerStarts map[string]ktime.Time
+
 	// syslog is the kernel log.
 	syslog syslog
 
@@ -1097,9 +1111,28 @@ func (k *Kernel) CreateProcess(args CreateProcessArgs) (*ThreadGroup, ThreadID,
 	if k.globalInit == nil {
 		k.globalInit = tg
 	}
+	if args.ContainerID != "" {
+		k.containerStartsMu.Lock()
+		if _, ok := k.containerStarts[args.ContainerID]; !ok {
+			if k.containerStarts == nil {
+				k.containerStarts = make(map[string]ktime.Time)
+			}
+			k.containerStarts[args.ContainerID] = k.RealtimeClock().Now()
+		}
+		k.containerStartsMu.Unlock()
+	}
 	return tg, tgid, nil
 }
 
+// containerStartTime returns the time that container cid started, or false if
+// it's unknown.
+func (k *Kernel) containerStartTime(cid string) (ktime.Time, bool) {
+	k.containerStartsMu.Lock()
+	defer k.containerStartsMu.Unlock()
+	start, ok := k.containerStarts[cid]
+	return start, ok
+}
+
 // StartProcess starts running a process that was created with CreateProcess.
 func (k *Kernel) StartProcess(tg *ThreadGroup) {
 	t := tg.Leader()
@@ -1383,6 +1416,12 @@ func (k *Kernel) SendExternalSignalThreadGroup(tg *ThreadGroup, info *linux.Sign
 func (k *Kernel) SendContainerSignal(cid string, info *linux.SignalInfo) error {
 	k.extMu.Lock()
 	defer k.extMu.Unlock()
+	if linux.Signal(info.Signo) == linux.SIGKILL {
+		// Tasks in a paused container can't act on SIGKILL until they
+		// resume. This runs after the signal is sent, once tasks.mu is
+		// released.
+		defer k.tasks.ResumeContainer(cid)
+	}
 	k.tasks.mu.RLock()
 	defer k.tasks.mu.RUnlock()
 
@@ -269,7 +269,9 @@ func (t *Task) canTraceYAMALocked(target *Task) bool {
 	if target.hasYAMAExceptionForLocked(t) {
 		return true
 	}
-	if t.HasCapabilityIn(linux.CAP_SYS_PTRACE, target.UserNamespace()) {
+	// Check the credentials directly, since Task.HasCapabilityIn cannot be
+	// called with the TaskSet mutex locked.
+	if t.Credentials().HasCapabilityIn(linux.CAP_SYS_PTRACE, target.UserNamespace()) {
 		return true
 	}
 	return false
@@ -15,8 +15,21 @@
 package kernel
 
 import (
+	"time"
+
+	"golang.org/x/time/rate"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/fsbridge"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/tmpfs"
+	"gvisor.dev/gvisor/pkg/sentry/limits"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
 )
 
 // LoadSeccheckData sets info from the task based on mask.
@@ -31,7 +44,7 @@ func LoadSeccheckData(t *Task, mask seccheck.FieldMask, info *pb.ContextData) {
 // Preconditions: The TaskSet mutex must be locked.
 func LoadSeccheckDataLocked(t *Task, mask seccheck.FieldMask, info *pb.ContextData) {
 	if mask.Contains(seccheck.FieldCtxtTime) {
-		info.TimeNs = t.k.RealtimeClock().Now().Nanoseconds()
+		info.TimeNs, info.MonotonicTimeNs = t.k.SeccheckTime()
 	}
 	if mask.Contains(seccheck.FieldCtxtThreadID) {
 		info.ThreadId = int32(t.k.tasks.Root.tids[t])
@@ -45,13 +58,21 @@ func LoadSeccheckDataLocked(t *Task, mask seccheck.FieldMask, info *pb.ContextDa
 	if mask.Contains(seccheck.FieldCtxtThreadGroupStartTime) {
 		info.ThreadGroupStartTimeNs = t.tg.leader.startTime.Nanoseconds()
 	}
+	if mask.Contains(seccheck.FieldCtxtNamespaceThreadID) {
+		info.NsThreadId = int32(t.tg.pidns.tids[t])
+	}
+	if mask.Contains(seccheck.FieldCtxtNamespaceThreadGroupID) {
+		info.NsThreadGroupId = int32(t.tg.pidns.tgids[t.tg])
+	}
 	if mask.Contains(seccheck.FieldCtxtContainerID) {
 		info.ContainerId = t.tg.leader.ContainerID()
 	}
-	if mask.Contains(seccheck.FieldCtxtCwd) {
-		if root := t.FSContext().RootDirectoryVFS2(); root.Ok() {
+	// FSContext is nil if t has already exited, which may happen when t is the
+	// owner of an object that outlives it, e.g. a socket.
+	if fsc := t.FSContext(); fsc != nil && mask.Contains(seccheck.FieldCtxtCwd) {
+		if root := fsc.RootDirectoryVFS2(); root.Ok() {
 			defer root.DecRef(t)
-			if wd := t.FSContext().WorkingDirectoryVFS2(); wd.Ok() {
+			if wd := fsc.WorkingDirectoryVFS2(); wd.Ok() {
 				defer wd.DecRef(t)
 				vfsObj := root.Mount().Filesystem().VirtualFilesystem()
 				info.Cwd, _ = vfsObj.PathnameWithDeleted(t, root, wd)
@@ -61,5 +82,248 @@ func LoadSeccheckDataLocked(t *Task, mask seccheck.FieldMask, info *pb.ContextDa
 	if mask.Contains(seccheck.FieldCtxtProcessName) {
 		info.ProcessName = t.Name()
 	}
+	if mask.Contains(seccheck.FieldCtxtWorkload) {
+		info.Workload = seccheck.Workload(t.tg.leader.ContainerID())
+	}
 	t.Credentials().LoadSeccheckData(mask, info)
 }
+
+// SeccheckTime returns the current time of the sandbox's CLOCK_REALTIME and
+// CLOCK_MONOTONIC, in nanoseconds, to stamp points when they're captured.
+func (k *Kernel) SeccheckTime() (realtimeNs, monotonicNs int64) {
+	return k.RealtimeClock().Now().Nanoseconds(), k.MonotonicClock().Now().Nanoseconds()
+}
+
+// taskOOMNotifier implements pgalloc.OOMNotifier for a Task.
+type taskOOMNotifier Task
+
+// NotifyOOM implements pgalloc.OOMNotifier.NotifyOOM.
+func (n *taskOOMNotifier) NotifyOOM(length uint64) {
+	if seccheck.Global.Enabled(seccheck.PointOOM) {
+		(*Task)(n).oomSeccheck(&pb.OOMInfo{
+			Source:         pb.OOMInfo_SOURCE_ALLOCATION,
+			AllocationSize: length,
+		})
+	}
+}
+
+// oomSeccheck fills in memory usage in info and sends it to the checkers
+// registered for seccheck.PointOOM.
+func (t *Task) oomSeccheck(info *pb.OOMInfo) {
+	if mm := t.MemoryManager(); mm != nil {
+		info.VirtualSize = mm.VirtualMemorySize()
+		info.ResidentSize = mm.ResidentSetSize()
+	}
+	info.TotalUsage = usage.MemoryAccounting.Total()
+
+	fields := seccheck.Global.GetFieldSet(seccheck.PointOOM)
+	if !fields.Context.Empty() {
+		info.ContextData = &pb.ContextData{}
+		LoadSeccheckData(t, fields.Context, info.ContextData)
+	}
+	seccheck.Global.SendToCheckers(t.ContainerID(), seccheck.PointOOM, func(c seccheck.Checker) error {
+		return c.OOM(t, fields, info)
+	})
+}
+
+const (
+	// ttyDataMaxSize is the maximum number of bytes reported for a single
+	// terminal read or write.
+	ttyDataMaxSize = 4096
+
+	// ttyDataRate is the sustained rate, in bytes per second, at which terminal
+	// data is reported across the sandbox. Data beyond this rate is dropped.
+	ttyDataRate = 64 << 10
+)
+
+// ttyDataLimiter rate limits the terminal data reported to seccheck.
+var ttyDataLimiter = rate.NewLimiter(ttyDataRate, ttyDataMaxSize)
+
+// ttyDataDropped is the number of bytes of terminal data dropped due to rate
+// limiting since the last report.
+var ttyDataDropped atomicbitops.Uint64
+
+// TTYDataSeccheck reports the first n bytes of data, which were read from or
+// written to terminal, to the checkers registered for seccheck.PointTTYData.
+// output is true if the data was written to the terminal.
+func TTYDataSeccheck(ctx context.Context, terminal string, output bool, data usermem.IOSequence, n int64) {
+	size := n
+	if size > ttyDataMaxSize {
+		size = ttyDataMaxSize
+	}
+	if !ttyDataLimiter.AllowN(time.Now(), int(size)) {
+		ttyDataDropped.Add(uint64(n))
+		return
+	}
+
+	buf := make([]byte, size)
+	c, _ := data.CopyIn(ctx, buf)
+	info := &pb.TTYDataInfo{
+		Terminal:     terminal,
+		Direction:    pb.TTYDataInfo_DIRECTION_INPUT,
+		Data:         buf[:c],
+		Truncated:    int64(c) < n,
+		DroppedBytes: ttyDataDropped.Swap(0),
+	}
+	if output {
+		info.Direction = pb.TTYDataInfo_DIRECTION_OUTPUT
+	}
+	fields := seccheck.Global.GetFieldSet(seccheck.PointTTYData)
+	if t := TaskFromContext(ctx); t != nil && !fields.Context.Empty() {
+		info.ContextData = &pb.ContextData{}
+		LoadSeccheckData(t, fields.Context, info.ContextData)
+	}
+	seccheck.Global.SendToCheckers(seccheck.ContainerID(ctx), seccheck.PointTTYData, func(c seccheck.Checker) error {
+		return c.TTYData(ctx, fields, info)
+	})
+}
+
+// RLimitSeccheck reports to the checkers registered for
+// seccheck.PointRLimitBreach that an operation performed by ctx failed because
+// it would have exceeded the limit on resource.
+func RLimitSeccheck(ctx context.Context, resource limits.LimitType) {
+	info := &pb.RLimitBreachInfo{}
+	if linuxResource, ok := limits.ToLinuxResource(resource); ok {
+		info.Resource = int32(linuxResource)
+	}
+	if ls := limits.FromContext(ctx); ls != nil {
+		lim := ls.Get(resource)
+		info.Cur = limits.ToLinux(lim.Cur)
+		info.Max = limits.ToLinux(lim.Max)
+	}
+	fields := seccheck.Global.GetFieldSet(seccheck.PointRLimitBreach)
+	if t := TaskFromContext(ctx); t != nil && !fields.Context.Empty() {
+		info.ContextData = &pb.ContextData{}
+		LoadSeccheckData(t, fields.Context, info.ContextData)
+	}
+	seccheck.Global.SendToCheckers(seccheck.ContainerID(ctx), seccheck.PointRLimitBreach, func(c seccheck.Checker) error {
+		return c.RLimitBreach(ctx, fields, info)
+	})
+}
+
+// filelessExecSeccheck sends info to the checkers registered for
+// seccheck.PointFilelessExec, and returns the error that the execution fails
+// with if they deny it.
+func (t *Task) filelessExecSeccheck(info *pb.FilelessExecInfo) error {
+	fields := seccheck.Global.GetFieldSet(seccheck.PointFilelessExec)
+	if !fields.Context.Empty() {
+		info.ContextData = &pb.ContextData{}
+		LoadSeccheckData(t, fields.Context, info.ContextData)
+	}
+	if err := seccheck.Global.SendToCheckers(t.ContainerID(), seccheck.PointFilelessExec, func(c seccheck.Checker) error {
+		return c.FilelessExec(t, fields, info)
+	}); err != nil {
+		return t.seccheckDenied(err)
+	}
+	return nil
+}
+
+// FilelessMMapSeccheck reports to the checkers registered for
+// seccheck.PointFilelessExec that t maps file with execute permission, if file
+// was created by memfd_create(2). It returns the error that the mapping fails
+// with if they deny it.
+func (t *Task) FilelessMMapSeccheck(file *vfs.FileDescription, offset, length uint64) error {
+	if !seccheck.Global.Enabled(seccheck.PointFilelessExec) || !tmpfs.IsMemfd(file) {
+		return nil
+	}
+	return t.filelessExecSeccheck(&pb.FilelessExecInfo{
+		Path:   file.MappedName(t),
+		Mmap:   true,
+		Offset: offset,
+		Length: length,
+	})
+}
+
+// driftSeccheck reports to the checkers registered for seccheck.PointDrift
+// that t executes executable, if it was created or changed after the container
+// of t started. It returns the error that the execution fails with if they
+// deny it.
+//
+// Changes are detected by the status change time of executable, which
+// applications can't set, unlike its modification time. Executables of
+// containers that weren't started by CreateProcess, e.g. processes created
+// without a container ID, aren't checked.
+func (t *Task) driftSeccheck(executable fsbridge.File, pathname string, argv []string) error {
+	f, ok := executable.(*fsbridge.VFSFile)
+	if !ok {
+		return nil
+	}
+	start, ok := t.k.containerStartTime(t.ContainerID())
+	if !ok {
+		return nil
+	}
+	stat, err := f.FileDescription().Stat(t, vfs.StatOptions{Mask: linux.STATX_CTIME})
+	if err != nil || stat.Mask&linux.STATX_CTIME == 0 {
+		return nil
+	}
+	changed := stat.Ctime.ToNsec()
+	if changed <= start.Nanoseconds() {
+		return nil
+	}
+
+	fields := seccheck.Global.GetFieldSet(seccheck.PointDrift)
+	info := &pb.DriftInfo{
+		BinaryPath:           pathname,
+		Argv:                 argv,
+		ChangeTimeNs:         changed,
+		ContainerStartTimeNs: start.Nanoseconds(),
+	}
+	if !fields.Context.Empty() {
+		info.ContextData = &pb.ContextData{}
+		LoadSeccheckData(t, fields.Context, info.ContextData)
+	}
+	if err := seccheck.Global.SendToCheckers(t.ContainerID(), seccheck.PointDrift, func(c seccheck.Checker) error {
+		return c.Drift(t, fields, info)
+	}); err != nil {
+		return t.seccheckDenied(err)
+	}
+	return nil
+}
+
+// seccheckDenied returns the error that an operation performed by t fails with
+// after checkers returned err for it, or nil if the operation is allowed. If
+// err is a *seccheck.Violation, it also reports the violation to the checkers
+// registered for seccheck.PointPolicyViolation and then takes its action, e.g.
+// kills the container of t.
+func (t *Task) seccheckDenied(err error) error {
+	v, ok := err.(*seccheck.Violation)
+	if !ok {
+		return err
+	}
+	if seccheck.Global.Enabled(seccheck.PointPolicyViolation) {
+		info := &pb.PolicyViolationInfo{
+			Rule:   v.Rule,
+			Reason: v.Reason,
+			Action: pb.PolicyViolationInfo_Action(v.Action),
+			Audit:  v.Audit,
+		}
+		fields := seccheck.Global.GetFieldSet(seccheck.PointPolicyViolation)
+		if !fields.Context.Empty() {
+			info.ContextData = &pb.ContextData{}
+			LoadSeccheckData(t, fields.Context, info.ContextData)
+		}
+		seccheck.Global.SendToCheckers(t.ContainerID(), seccheck.PointPolicyViolation, func(c seccheck.Checker) error {
+			return c.PolicyViolation(t, fields, info)
+		})
+	}
+	if v.Audit {
+		return nil
+	}
+	switch v.Action {
+	case seccheck.ViolationKillSandbox:
+		log.Warningf("Killing sandbox after %v", v)
+		t.k.Kill(linux.WaitStatusTerminationSignal(linux.SIGKILL))
+	case seccheck.ViolationKillContainer:
+		log.Warningf("Killing container %q after %v", t.ContainerID(), v)
+		if err := t.k.SendContainerSignal(t.ContainerID(), SignalInfoPriv(linux.SIGKILL)); err != nil {
+			log.Warningf("Failed to kill container %q: %v", t.ContainerID(), err)
+		}
+	case seccheck.ViolationPauseContainer:
+		log.Warningf("Pausing container %q after %v", t.ContainerID(), v)
+		t.k.tasks.PauseContainer(t.ContainerID())
+	}
+	if v.Err != nil {
+		return v.Err
+	}
+	return seccheck.ErrDenied
+}
@@ -21,6 +21,8 @@ import (
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 )
 
 const maxSyscallFilterInstructions = 1 << 15
@@ -59,6 +61,9 @@ func seccompSiginfo(t *Task, errno, sysno int32, ip hostarch.Addr) *linux.Signal
 func (t *Task) checkSeccompSyscall(sysno int32, args arch.SyscallArguments, ip hostarch.Addr) linux.BPFAction {
 	result := linux.BPFAction(t.evaluateSyscallFilters(sysno, args, ip))
 	action := result & linux.SECCOMP_RET_ACTION
+	if action != linux.SECCOMP_RET_ALLOW && seccheck.Global.Enabled(seccheck.PointSeccomp) {
+		t.seccompSeccheck(sysno, ip, result)
+	}
 	switch action {
 	case linux.SECCOMP_RET_TRAP:
 		// "Results in the kernel sending a SIGSYS signal to the triggering
@@ -102,6 +107,26 @@ func (t *Task) checkSeccompSyscall(sysno int32, args arch.SyscallArguments, ip h
 	return action
 }
 
+// seccompSeccheck sends the outcome of a seccomp filter that didn't allow the
+// syscall to the checkers registered for seccheck.PointSeccomp.
+func (t *Task) seccompSeccheck(sysno int32, ip hostarch.Addr, result linux.BPFAction) {
+	info := &pb.SeccompInfo{
+		Sysno:              sysno,
+		Arch:               t.SyscallTable().AuditNumber,
+		InstructionPointer: uint64(ip),
+		Action:             uint32(result & linux.SECCOMP_RET_ACTION_FULL),
+		Data:               uint32(result.Data()),
+	}
+	fields := seccheck.Global.GetFieldSet(seccheck.PointSeccomp)
+	if !fields.Context.Empty() {
+		info.ContextData = &pb.ContextData{}
+		LoadSeccheckData(t, fields.Context, info.ContextData)
+	}
+	seccheck.Global.SendToCheckers(t.ContainerID(), seccheck.PointSeccomp, func(c seccheck.Checker) error {
+		return c.Seccomp(t, fields, info)
+	})
+}
+
 func (t *Task) evaluateSyscallFilters(sysno int32, args arch.SyscallArguments, ip hostarch.Addr) uint32 {
 	data := linux.SeccompData{
 		Nr:                 sysno,
@@ -209,6 +209,7 @@ func (r *Registry) newShmLocked(ctx context.Context, pid int32, key ipc.Key, cre
 	effectiveSize := uint64(hostarch.Addr(size).MustRoundUp())
 	fr, err := mfp.MemoryFile().Allocate(effectiveSize, pgalloc.AllocOpts{Kind: usage.Anonymous})
 	if err != nil {
+		pgalloc.NotifyOOM(ctx, effectiveSize)
 		return nil, err
 	}
 
@@ -83,7 +83,9 @@ type Syscall struct {
 	// URLs is set of URLs to any relevant bugs or issues.
 	URLs []string
 	// PointCallback is an optional callback that converts syscall arguments
-	// to a proto that can be used with seccheck.Checker.
+	// to a proto that can be used with seccheck.Checker. It's only invoked
+	// when the corresponding syscall point is enabled, so it may build the
+	// proto unconditionally.
 	// Callback functions must follow this naming convention:
 	//   PointSyscallNameInCamelCase, e.g. PointReadat, PointRtSigaction.
 	PointCallback SyscallToProto
@@ -398,7 +400,8 @@ func (s *SyscallTable) LookupSyscallToProto(sysno uintptr) SyscallToProto {
 }
 
 // SyscallToProto is a callback function that converts generic syscall data to
-// schematized protobuf for the corresponding syscall.
+// schematized protobuf for the corresponding syscall. It may return a nil
+// message for enter points that it sends itself, asynchronously.
 type SyscallToProto func(*Task, seccheck.FieldSet, *pb.ContextData, SyscallInfo) (proto.Message, pb.MessageType)
 
 // SyscallInfo provides generic information about the syscall.
@@ -408,4 +411,12 @@ type SyscallInfo struct {
 	Args  arch.SyscallArguments
 	Rval  uintptr
 	Errno int
+
+	// Enter is the message built for the enter point of the syscall, if it
+	// was enabled with at least the local fields of the exit point. It's
+	// only set on exit, once the enter point is done with it. Callbacks may
+	// update and return it instead of copying the arguments in again, which
+	// is cheaper and reports what the syscall actually used, even if
+	// userspace modified its buffers in the meantime.
+	Enter proto.Message
 }
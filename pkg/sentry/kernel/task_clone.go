@@ -74,6 +74,8 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 	// user_namespaces(7)
 	creds := t.Credentials()
 	userns := creds.UserNamespace
+	// newNS has the CLONE_NEW* flags of the namespaces that are created.
+	var newNS uint64
 	if args.Flags&linux.CLONE_NEWUSER != 0 {
 		var err error
 		// "EPERM (since Linux 3.9): CLONE_NEWUSER was specified in flags and
@@ -88,6 +90,7 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 		if err != nil {
 			return 0, nil, err
 		}
+		newNS |= linux.CLONE_NEWUSER
 	}
 	if args.Flags&(linux.CLONE_NEWPID|linux.CLONE_NEWNET|linux.CLONE_NEWUTS|linux.CLONE_NEWIPC) != 0 && !creds.HasCapabilityIn(linux.CAP_SYS_ADMIN, userns) {
 		return 0, nil, linuxerr.EPERM
@@ -98,6 +101,7 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 		// Note that this must happen after NewUserNamespace so we get
 		// the new userns if there is one.
 		utsns = t.UTSNamespace().Clone(userns)
+		newNS |= linux.CLONE_NEWUTS
 	}
 
 	ipcns := t.IPCNamespace()
@@ -106,6 +110,7 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 		if VFS2Enabled {
 			ipcns.InitPosixQueues(t, t.k.VFS(), creds)
 		}
+		newNS |= linux.CLONE_NEWIPC
 	} else {
 		ipcns.IncRef()
 	}
@@ -117,6 +122,7 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 	netns := t.NetworkNamespace()
 	if args.Flags&linux.CLONE_NEWNET != 0 {
 		netns = inet.NewNamespace(netns)
+		newNS |= linux.CLONE_NEWNET
 	} else {
 		netns.IncRef()
 	}
@@ -172,6 +178,7 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 		pidns = t.childPIDNamespace
 	} else if args.Flags&linux.CLONE_NEWPID != 0 {
 		pidns = pidns.NewChild(userns)
+		newNS |= linux.CLONE_NEWPID
 	}
 
 	tg := t.tg
@@ -253,20 +260,23 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 
 	if seccheck.Global.Enabled(seccheck.PointClone) {
 		mask, info := getCloneSeccheckInfo(t, nt, args.Flags)
-		if err := seccheck.Global.SendToCheckers(func(c seccheck.Checker) error {
+		if err := seccheck.Global.SendToCheckers(t.ContainerID(), seccheck.PointClone, func(c seccheck.Checker) error {
 			return c.Clone(t, mask, info)
 		}); err != nil {
-			// nt has been visible to the rest of the system since NewTask, so
-			// it may be blocking execve or a group stop, have been notified
-			// for group signal delivery, had children reparented to it, etc.
-			// Thus we can't just drop it on the floor. Instead, instruct the
-			// task goroutine to exit immediately, as quietly as possible.
-			nt.exitTracerNotified = true
-			nt.exitTracerAcked = true
-			nt.exitParentNotified = true
-			nt.exitParentAcked = true
-			nt.runState = (*runExitMain)(nil)
-			return 0, nil, err
+			if err := t.seccheckDenied(err); err != nil {
+				// nt has been visible to the rest of the system since NewTask,
+				// so it may be blocking execve or a group stop, have been
+				// notified for group signal delivery, had children reparented
+				// to it, etc. Thus we can't just drop it on the floor.
+				// Instead, instruct the task goroutine to exit immediately, as
+				// quietly as possible.
+				nt.exitTracerNotified = true
+				nt.exitTracerAcked = true
+				nt.exitParentNotified = true
+				nt.exitParentAcked = true
+				nt.runState = (*runExitMain)(nil)
+				return 0, nil, err
+			}
 		}
 	}
 
@@ -293,6 +303,10 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 		ntid.CopyOut(t, hostarch.Addr(args.ParentTID))
 	}
 
+	if newNS != 0 && seccheck.Global.Enabled(seccheck.PointNamespaceCreate) {
+		t.namespaceCreateSeccheck(newNS, false /* unshare */)
+	}
+
 	t.traceCloneEvent(tid)
 	kind := ptraceCloneKindClone
 	if args.Flags&linux.CLONE_VFORK != 0 {
@@ -425,6 +439,8 @@ func (t *Task) Unshare(flags int32) error {
 		return linuxerr.EINVAL
 	}
 	creds := t.Credentials()
+	// newNS has the CLONE_NEW* flags of the namespaces that are created.
+	var newNS uint64
 	if flags&linux.CLONE_THREAD != 0 {
 		t.tg.signalHandlers.mu.Lock()
 		if t.tg.tasksCount != 1 {
@@ -449,13 +465,18 @@ func (t *Task) Unshare(flags int32) error {
 		}
 		// Need to reload creds, becaue t.SetUserNamespace() changed task credentials.
 		creds = t.Credentials()
+		newNS |= linux.CLONE_NEWUSER
 	}
-	haveCapSysAdmin := t.HasCapability(linux.CAP_SYS_ADMIN)
+	// Only check CAP_SYS_ADMIN if a namespace that requires it is being
+	// created, so that unrelated unshares aren't reported as denials.
+	haveCapSysAdmin := flags&(linux.CLONE_NEWPID|linux.CLONE_NEWNET|linux.CLONE_NEWUTS|linux.CLONE_NEWIPC) != 0 &&
+		t.HasCapability(linux.CAP_SYS_ADMIN)
 	if flags&linux.CLONE_NEWPID != 0 {
 		if !haveCapSysAdmin {
 			return linuxerr.EPERM
 		}
 		t.childPIDNamespace = t.tg.pidns.NewChild(t.UserNamespace())
+		newNS |= linux.CLONE_NEWPID
 	}
 	t.mu.Lock()
 	// Can't defer unlock: DecRefs must occur without holding t.mu.
@@ -467,6 +488,7 @@ func (t *Task) Unshare(flags int32) error {
 		}
 		oldNETNS = t.netns.Load()
 		t.netns.Store(inet.NewNamespace(t.netns.Load()))
+		newNS |= linux.CLONE_NEWNET
 	}
 	if flags&linux.CLONE_NEWUTS != 0 {
 		if !haveCapSysAdmin {
@@ -476,6 +498,7 @@ func (t *Task) Unshare(flags int32) error {
 		// Note that this must happen after NewUserNamespace, so the
 		// new user namespace is used if there is one.
 		t.utsns = t.utsns.Clone(creds.UserNamespace)
+		newNS |= linux.CLONE_NEWUTS
 	}
 	var oldIPCNS *IPCNamespace
 	if flags&linux.CLONE_NEWIPC != 0 {
@@ -490,6 +513,7 @@ func (t *Task) Unshare(flags int32) error {
 		if VFS2Enabled {
 			t.ipcns.InitPosixQueues(t, t.k.VFS(), creds)
 		}
+		newNS |= linux.CLONE_NEWIPC
 	}
 	var oldFDTable *FDTable
 	if flags&linux.CLONE_FILES != 0 {
@@ -514,9 +538,51 @@ func (t *Task) Unshare(flags int32) error {
 	if oldFSContext != nil {
 		oldFSContext.DecRef(t)
 	}
+	if newNS != 0 && seccheck.Global.Enabled(seccheck.PointNamespaceCreate) {
+		t.namespaceCreateSeccheck(newNS, true /* unshare */)
+	}
 	return nil
 }
 
+// namespaceTypes maps CLONE_NEW* flags to the namespace type names used in
+// /proc/[pid]/ns.
+var namespaceTypes = []struct {
+	flag uint64
+	name string
+}{
+	{linux.CLONE_NEWUSER, "user"},
+	{linux.CLONE_NEWNET, "net"},
+	{linux.CLONE_NEWPID, "pid"},
+	{linux.CLONE_NEWUTS, "uts"},
+	{linux.CLONE_NEWIPC, "ipc"},
+}
+
+// namespaceCreateSeccheck sends one point for each namespace in newNS, given as
+// CLONE_NEW* flags, to the checkers registered for
+// seccheck.PointNamespaceCreate.
+func (t *Task) namespaceCreateSeccheck(newNS uint64, unshare bool) {
+	fields := seccheck.Global.GetFieldSet(seccheck.PointNamespaceCreate)
+	var ctxData *pb.ContextData
+	if !fields.Context.Empty() {
+		ctxData = &pb.ContextData{}
+		LoadSeccheckData(t, fields.Context, ctxData)
+	}
+	for _, ns := range namespaceTypes {
+		if newNS&ns.flag == 0 {
+			continue
+		}
+		info := &pb.NamespaceCreateInfo{
+			ContextData: ctxData,
+			Type:        ns.name,
+			Flag:        ns.flag,
+			Unshare:     unshare,
+		}
+		seccheck.Global.SendToCheckers(t.ContainerID(), seccheck.PointNamespaceCreate, func(c seccheck.Checker) error {
+			return c.NamespaceCreate(t, fields, info)
+		})
+	}
+}
+
 // UnshareFdTable unshares the FdTable that task t shares with other tasks, upto
 // the maxFd.
 //
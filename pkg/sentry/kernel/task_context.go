@@ -28,6 +28,8 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/limits"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 	"gvisor.dev/gvisor/pkg/sentry/unimpl"
 	"gvisor.dev/gvisor/pkg/sentry/uniqueid"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
@@ -123,8 +125,16 @@ func (t *Task) contextValue(key interface{}, isTaskGoroutine bool) interface{} {
 		return t.k.mf
 	case pgalloc.CtxMemoryFileProvider:
 		return t.k
+	case pgalloc.CtxOOMNotifier:
+		return (*taskOOMNotifier)(t)
 	case platform.CtxPlatform:
 		return t.k
+	case seccheck.CtxContainerID:
+		return t.ContainerID()
+	case seccheck.CtxLoadContextDataFunc:
+		return func(mask seccheck.FieldMask, info *pb.ContextData) {
+			LoadSeccheckData(t, mask, info)
+		}
 	case uniqueid.CtxGlobalUniqueID:
 		return t.k.UniqueID()
 	case uniqueid.CtxGlobalUniqueIDProvider:
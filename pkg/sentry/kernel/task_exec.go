@@ -65,14 +65,21 @@ package kernel
 // """
 
 import (
+	"crypto/sha256"
+	"io"
+
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/fs"
 	"gvisor.dev/gvisor/pkg/sentry/fsbridge"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/tmpfs"
 	"gvisor.dev/gvisor/pkg/sentry/mm"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
 )
 
 // execStop is a TaskStop that a task sets on itself when it wants to execve
@@ -97,9 +104,29 @@ func (t *Task) Execve(newImage *TaskImage, argv, env []string, executable fsbrid
 	// We can't clearly hold kernel package locks while stat'ing executable.
 	if seccheck.Global.Enabled(seccheck.PointExecve) {
 		mask, info := getExecveSeccheckInfo(t, argv, env, executable, pathname)
-		if err := seccheck.Global.SendToCheckers(func(c seccheck.Checker) error {
+		if err := seccheck.Global.SendToCheckers(t.ContainerID(), seccheck.PointExecve, func(c seccheck.Checker) error {
 			return c.Execve(t, mask, info)
 		}); err != nil {
+			if err := t.seccheckDenied(err); err != nil {
+				newImage.release()
+				return nil, err
+			}
+		}
+	}
+	if seccheck.Global.Enabled(seccheck.PointFilelessExec) {
+		if f, ok := executable.(*fsbridge.VFSFile); ok && tmpfs.IsMemfd(f.FileDescription()) {
+			info := &pb.FilelessExecInfo{
+				Path: f.FileDescription().MappedName(t),
+				Argv: argv,
+			}
+			if err := t.filelessExecSeccheck(info); err != nil {
+				newImage.release()
+				return nil, err
+			}
+		}
+	}
+	if seccheck.Global.Enabled(seccheck.PointDrift) {
+		if err := t.driftSeccheck(executable, pathname, argv); err != nil {
 			newImage.release()
 			return nil, err
 		}
@@ -306,10 +333,10 @@ func (t *Task) promoteLocked() {
 
 func getExecveSeccheckInfo(t *Task, argv, env []string, executable fsbridge.File, pathname string) (seccheck.FieldSet, *pb.ExecveInfo) {
 	fields := seccheck.Global.GetFieldSet(seccheck.PointExecve)
-	info := &pb.ExecveInfo{
-		Argv: argv,
-		Env:  env,
-	}
+	info := &pb.ExecveInfo{}
+	execArgs := seccheck.Global.GetExecArgs()
+	info.Argv, info.ArgvTruncated = execArgs.Argv(argv)
+	info.Env, info.EnvTruncated = execArgs.Env(env)
 	if executable != nil {
 		info.BinaryPath = pathname
 		if vfs2bridgeFile, ok := executable.(*fsbridge.VFSFile); ok {
@@ -329,8 +356,13 @@ func getExecveSeccheckInfo(t *Task, argv, env []string, executable fsbridge.File
 					}
 				}
 			}
-			// TODO(b/202293325): Decide if we actually want to offer binary
-			// SHA256, which is very expensive.
+			if fields.Local.Contains(seccheck.FieldSentryExecveBinarySHA256) {
+				if sum, err := fileSHA256(t, vfs2bridgeFile.FileDescription()); err == nil {
+					info.BinarySha256 = sum
+				} else {
+					log.Warningf("Failed to hash executable %q: %v", pathname, err)
+				}
+			}
 		}
 	}
 
@@ -340,3 +372,24 @@ func getExecveSeccheckInfo(t *Task, argv, env []string, executable fsbridge.File
 	}
 	return fields, info
 }
+
+// fileSHA256 returns the SHA-256 hash of the contents of fd.
+func fileSHA256(t *Task, fd *vfs.FileDescription) ([]byte, error) {
+	h := sha256.New()
+	buf := make([]byte, hostarch.PageSize*16)
+	var off int64
+	for {
+		n, err := fd.PRead(t, usermem.BytesIOSequence(buf), off, vfs.ReadOptions{})
+		h.Write(buf[:n])
+		off += n
+		if err == io.EOF {
+			return h.Sum(nil), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return h.Sum(nil), nil
+		}
+	}
+}
@@ -205,22 +205,15 @@ type runExitMain struct{}
 func (*runExitMain) execute(t *Task) taskRunState {
 	t.traceExitEvent()
 
+	lastExiter := t.exitThreadGroup()
+
 	if seccheck.Global.Enabled(seccheck.PointTaskExit) {
-		info := &pb.TaskExit{
-			ExitStatus: int32(t.tg.exitStatus),
-		}
-		fields := seccheck.Global.GetFieldSet(seccheck.PointTaskExit)
-		if !fields.Context.Empty() {
-			info.ContextData = &pb.ContextData{}
-			LoadSeccheckData(t, fields.Context, info.ContextData)
-		}
-		seccheck.Global.SendToCheckers(func(c seccheck.Checker) error {
+		fields, info := getTaskExitSeccheckInfo(t, lastExiter)
+		seccheck.Global.SendToCheckers(t.ContainerID(), seccheck.PointTaskExit, func(c seccheck.Checker) error {
 			return c.TaskExit(t, fields, info)
 		})
 	}
 
-	lastExiter := t.exitThreadGroup()
-
 	t.ResetKcov()
 
 	// If the task has a cleartid, and the thread group wasn't killed by a
@@ -668,7 +661,7 @@ func (t *Task) exitNotifyLocked(fromPtraceDetach bool) {
 			// Clone or Exec events for the initial process.
 			if t.tg != t.k.globalInit && seccheck.Global.Enabled(seccheck.PointExitNotifyParent) {
 				mask, info := getExitNotifyParentSeccheckInfo(t)
-				if err := seccheck.Global.SendToCheckers(func(c seccheck.Checker) error {
+				if err := seccheck.Global.SendToCheckers(t.ContainerID(), seccheck.PointExitNotifyParent, func(c seccheck.Checker) error {
 					return c.ExitNotifyParent(t, mask, info)
 				}); err != nil {
 					log.Infof("Ignoring error from ExitNotifyParent point: %v", err)
@@ -723,6 +716,43 @@ func (t *Task) exitNotificationSignal(sig linux.Signal, receiver *Task) *linux.S
 	return info
 }
 
+// Preconditions: The caller must be running on the task goroutine.
+func getTaskExitSeccheckInfo(t *Task, lastExiter bool) (seccheck.FieldSet, *pb.TaskExit) {
+	fields := seccheck.Global.GetFieldSet(seccheck.PointTaskExit)
+
+	t.tg.signalHandlers.mu.Lock()
+	tgStatus := t.tg.exitStatus
+	ws := t.exitStatus
+	if t.tg.exiting {
+		ws = tgStatus
+	}
+	t.tg.signalHandlers.mu.Unlock()
+
+	info := &pb.TaskExit{
+		ExitStatus:      int32(tgStatus),
+		ThreadGroupExit: lastExiter,
+	}
+	if ws.Signaled() {
+		info.Signal = int32(ws.TerminationSignal())
+		info.CoreDumped = ws.CoreDumped()
+	} else {
+		info.ExitCode = int32(ws.ExitStatus())
+	}
+	if lastExiter {
+		cs := t.tg.CPUStats()
+		info.UserTimeNs = cs.UserTime.Nanoseconds()
+		info.SysTimeNs = cs.SysTime.Nanoseconds()
+		info.VoluntarySwitches = cs.VoluntarySwitches
+		info.MaxRss = t.MaxRSS(linux.RUSAGE_SELF)
+	}
+	if !fields.Context.Empty() {
+		info.ContextData = &pb.ContextData{}
+		LoadSeccheckData(t, fields.Context, info.ContextData)
+	}
+
+	return fields, info
+}
+
 // Preconditions: The TaskSet mutex must be locked.
 func getExitNotifyParentSeccheckInfo(t *Task) (seccheck.FieldSet, *pb.ExitNotifyParentInfo) {
 	fields := seccheck.Global.GetFieldSet(seccheck.PointExitNotifyParent)
@@ -19,6 +19,8 @@ import (
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/mm"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 )
 
 // Credentials returns t's credentials.
@@ -34,13 +36,51 @@ func (t *Task) UserNamespace() *auth.UserNamespace {
 }
 
 // HasCapabilityIn checks if the task has capability cp in user namespace ns.
+// Failed checks are reported to seccheck.PointCapabilityDenied.
+//
+// Preconditions: The TaskSet mutex must not be locked.
 func (t *Task) HasCapabilityIn(cp linux.Capability, ns *auth.UserNamespace) bool {
+	if t.Credentials().HasCapabilityIn(cp, ns) {
+		return true
+	}
+	if seccheck.Global.Enabled(seccheck.PointCapabilityDenied) {
+		t.capabilityDeniedSeccheck(cp)
+	}
+	return false
+}
+
+// HasCapabilityInNoAudit checks if the task has capability cp in user
+// namespace ns without reporting failed checks. It is the equivalent of
+// Linux's ns_capable_noaudit, and is meant for speculative checks whose
+// failure does not cause the operation to fail.
+func (t *Task) HasCapabilityInNoAudit(cp linux.Capability, ns *auth.UserNamespace) bool {
 	return t.Credentials().HasCapabilityIn(cp, ns)
 }
 
 // HasCapability checks if the task has capability cp in its user namespace.
+// Failed checks are reported to seccheck.PointCapabilityDenied.
+//
+// Preconditions: The TaskSet mutex must not be locked.
 func (t *Task) HasCapability(cp linux.Capability) bool {
-	return t.Credentials().HasCapability(cp)
+	return t.HasCapabilityIn(cp, t.UserNamespace())
+}
+
+// capabilityDeniedSeccheck sends the failed check for capability cp to the
+// checkers registered for seccheck.PointCapabilityDenied.
+func (t *Task) capabilityDeniedSeccheck(cp linux.Capability) {
+	info := &pb.CapabilityDeniedInfo{
+		Capability:     int32(cp),
+		CapabilityName: cp.String(),
+		Sysno:          uint64(t.Arch().SyscallNo()),
+	}
+	fields := seccheck.Global.GetFieldSet(seccheck.PointCapabilityDenied)
+	if !fields.Context.Empty() {
+		info.ContextData = &pb.ContextData{}
+		LoadSeccheckData(t, fields.Context, info.ContextData)
+	}
+	seccheck.Global.SendToCheckers(t.ContainerID(), seccheck.PointCapabilityDenied, func(c seccheck.Checker) error {
+		return c.CapabilityDenied(t, fields, info)
+	})
 }
 
 // SetUID implements the semantics of setuid(2).
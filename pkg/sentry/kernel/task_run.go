@@ -27,6 +27,8 @@ import (
 	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
 	"gvisor.dev/gvisor/pkg/sentry/memmap"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 )
 
 // A taskRunState is a reified state in the task state machine. See README.md
@@ -304,6 +306,14 @@ func (app *runApp) execute(t *Task) taskRunState {
 				sig = linux.SIGBUS
 				info.Signo = int32(linux.SIGBUS)
 			}
+
+			if seccheck.Global.Enabled(seccheck.PointOOM) && isOOMError(err) {
+				t.oomSeccheck(&pb.OOMInfo{
+					Source:    pb.OOMInfo_SOURCE_FAULT,
+					FaultAddr: uint64(addr),
+					Signal:    int32(sig),
+				})
+			}
 		}
 
 		switch sig {
@@ -377,3 +387,12 @@ func (t *Task) Yield() {
 	t.yieldCount.Add(1)
 	runtime.Gosched()
 }
+
+// isOOMError returns true if err indicates that memory could not be allocated
+// while handling a fault.
+func isOOMError(err error) bool {
+	if busErr, ok := err.(*memmap.BusError); ok {
+		err = busErr.Err
+	}
+	return linuxerr.Equals(linuxerr.ENOMEM, err)
+}
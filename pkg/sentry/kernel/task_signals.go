@@ -27,6 +27,9 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	ucspb "gvisor.dev/gvisor/pkg/sentry/kernel/uncaught_signal_go_proto"
+	"gvisor.dev/gvisor/pkg/sentry/limits"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 	"gvisor.dev/gvisor/pkg/waiter"
 )
 
@@ -158,6 +161,10 @@ func (t *Task) deliverSignal(info *linux.SignalInfo, act linux.SigAction) taskRu
 	sig := linux.Signal(info.Signo)
 	sigact := computeAction(sig, act)
 
+	if seccheck.Global.Enabled(seccheck.PointSignalDeliver) {
+		t.signalDeliverSeccheck(info, sigact)
+	}
+
 	if t.haveSyscallReturn {
 		if sre, ok := linuxerr.SyscallRestartErrorFromReturn(t.Arch().Return()); ok {
 			// Signals that are ignored, cause a thread group stop, or
@@ -204,6 +211,10 @@ func (t *Task) deliverSignal(info *linux.SignalInfo, act linux.SigAction) taskRu
 
 		eventchannel.Emit(ucs)
 
+		if sigact == SignalActionCore && seccheck.Global.Enabled(seccheck.PointCoreDump) {
+			t.coreDumpSeccheck(info)
+		}
+
 		t.PrepareGroupExit(linux.WaitStatusTerminationSignal(sig))
 		return (*runExit)(nil)
 
@@ -234,6 +245,74 @@ func (t *Task) deliverSignal(info *linux.SignalInfo, act linux.SigAction) taskRu
 	return (*runInterrupt)(nil)
 }
 
+// seccheckActions maps SignalActions to their seccheck representation.
+var seccheckActions = map[SignalAction]pb.SignalDeliverInfo_Action{
+	SignalActionTerm:    pb.SignalDeliverInfo_ACTION_TERMINATE,
+	SignalActionCore:    pb.SignalDeliverInfo_ACTION_CORE,
+	SignalActionStop:    pb.SignalDeliverInfo_ACTION_STOP,
+	SignalActionIgnore:  pb.SignalDeliverInfo_ACTION_IGNORE,
+	SignalActionHandler: pb.SignalDeliverInfo_ACTION_HANDLER,
+}
+
+// signalDeliverSeccheck sends info about the signal being delivered to t to
+// the checkers registered for seccheck.PointSignalDeliver.
+func (t *Task) signalDeliverSeccheck(info *linux.SignalInfo, sigact SignalAction) {
+	p := &pb.SignalDeliverInfo{
+		Signo:  info.Signo,
+		Code:   info.Code,
+		Action: seccheckActions[sigact],
+	}
+	if info.Code <= linux.SI_USER {
+		// Signal was sent by a task, e.g. kill(2), tkill(2), sigqueue(3).
+		p.SenderPid = info.PID()
+		p.SenderUid = uint32(info.UID())
+	} else {
+		switch linux.Signal(info.Signo) {
+		case linux.SIGSEGV, linux.SIGFPE, linux.SIGILL, linux.SIGTRAP, linux.SIGBUS:
+			p.FaultAddr = info.Addr()
+		case linux.SIGSYS:
+			p.Syscall = info.Syscall()
+		}
+	}
+
+	fields := seccheck.Global.GetFieldSet(seccheck.PointSignalDeliver)
+	if !fields.Context.Empty() {
+		p.ContextData = &pb.ContextData{}
+		LoadSeccheckData(t, fields.Context, p.ContextData)
+	}
+	seccheck.Global.SendToCheckers(t.ContainerID(), seccheck.PointSignalDeliver, func(c seccheck.Checker) error {
+		return c.SignalDeliver(t, fields, p)
+	})
+}
+
+// coreDumpSeccheck sends info about the core dump (not) generated for the
+// fatal signal to the checkers registered for seccheck.PointCoreDump.
+func (t *Task) coreDumpSeccheck(info *linux.SignalInfo) {
+	p := &pb.CoreDumpInfo{
+		Signo:     info.Signo,
+		CoreLimit: t.Limits().Get(limits.Core).Cur,
+	}
+	switch linux.Signal(info.Signo) {
+	case linux.SIGSEGV, linux.SIGFPE, linux.SIGILL, linux.SIGTRAP, linux.SIGBUS:
+		p.FaultAddr = info.Addr()
+	}
+	if mm := t.MemoryManager(); mm != nil {
+		if exec := mm.Executable(); exec != nil {
+			p.ExecutablePath = exec.PathnameWithDeleted(t)
+			exec.DecRef(t)
+		}
+	}
+
+	fields := seccheck.Global.GetFieldSet(seccheck.PointCoreDump)
+	if !fields.Context.Empty() {
+		p.ContextData = &pb.ContextData{}
+		LoadSeccheckData(t, fields.Context, p.ContextData)
+	}
+	seccheck.Global.SendToCheckers(t.ContainerID(), seccheck.PointCoreDump, func(c seccheck.Checker) error {
+		return c.CoreDump(t, fields, p)
+	})
+}
+
 // deliverSignalToHandler changes the task's userspace state to enter the given
 // user-configured handler for the given signal.
 func (t *Task) deliverSignalToHandler(info *linux.SignalInfo, act linux.SigAction) error {
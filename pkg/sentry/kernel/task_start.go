@@ -251,7 +251,11 @@ func (ts *TaskSet) newTask(ctx context.Context, cfg *TaskConfig) (*Task, error)
 	tg.activeTasks++
 
 	// Propagate external TaskSet stops to the new task.
-	t.stopCount = atomicbitops.FromInt32(ts.stopCount)
+	stopCount := ts.stopCount
+	if _, ok := ts.pausedContainers[t.containerID]; ok {
+		stopCount++
+	}
+	t.stopCount = atomicbitops.FromInt32(stopCount)
 
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -241,3 +241,56 @@ func (ts *TaskSet) EndExternalStop() {
 		t.tg.signalHandlers.mu.Unlock()
 	}
 }
+
+// PauseContainer starts an external stop that applies to all current and
+// future tasks in the container with ID cid, until a call to
+// TaskSet.ResumeContainer. PauseContainer does not wait for task goroutines to
+// stop. Pausing a container that is already paused has no effect.
+func (ts *TaskSet) PauseContainer(cid string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if _, ok := ts.pausedContainers[cid]; ok {
+		return
+	}
+	if ts.pausedContainers == nil {
+		ts.pausedContainers = make(map[string]struct{})
+	}
+	ts.pausedContainers[cid] = struct{}{}
+	if ts.Root == nil {
+		return
+	}
+	for t := range ts.Root.tids {
+		if t.ContainerID() != cid {
+			continue
+		}
+		t.tg.signalHandlers.mu.Lock()
+		t.beginStopLocked()
+		t.tg.signalHandlers.mu.Unlock()
+		t.interrupt()
+	}
+}
+
+// ResumeContainer ends the external stop started by a previous call to
+// TaskSet.PauseContainer for the container with ID cid. It returns false if
+// the container isn't paused. ResumeContainer does not wait for task
+// goroutines to resume.
+func (ts *TaskSet) ResumeContainer(cid string) bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if _, ok := ts.pausedContainers[cid]; !ok {
+		return false
+	}
+	delete(ts.pausedContainers, cid)
+	if ts.Root == nil {
+		return true
+	}
+	for t := range ts.Root.tids {
+		if t.ContainerID() != cid {
+			continue
+		}
+		t.tg.signalHandlers.mu.Lock()
+		t.endStopLocked()
+		t.tg.signalHandlers.mu.Unlock()
+	}
+	return true
+}
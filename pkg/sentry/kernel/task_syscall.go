@@ -20,6 +20,7 @@ import (
 	"runtime/trace"
 
 	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/proto"
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/bits"
 	"gvisor.dev/gvisor/pkg/errors"
@@ -90,7 +91,21 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 		straceContext = s.Stracer.SyscallEnter(t, sysno, args, fe)
 	}
 
-	if seccheck.Global.SyscallEnabled(seccheck.SyscallRawEnter, sysno) {
+	// Syscall points are usually all disabled, in which case checking
+	// SyscallsEnabled is all it takes to skip them. Points must not build
+	// their info before they are known to be enabled.
+	//
+	// denied is set when checkers deny the syscall at enter points, in which
+	// case it fails with denied without being invoked. See seccheck.IsDenied.
+	var denied error
+	// enterMsg and enterFields are the message and fields of the enter point,
+	// if it's enabled, to be reused at exit, see SyscallInfo.Enter.
+	var (
+		enterMsg    proto.Message
+		enterFields seccheck.FieldSet
+	)
+	tracing := seccheck.Global.SyscallsEnabled()
+	if tracing && seccheck.Global.SyscallEnabled(seccheck.SyscallRawEnter, sysno) {
 		info := pb.Syscall{
 			Sysno: uint64(sysno),
 			Arg1:  args[0].Uint64(),
@@ -100,17 +115,21 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 			Arg5:  args[4].Uint64(),
 			Arg6:  args[5].Uint64(),
 		}
-		fields := seccheck.Global.GetFieldSet(seccheck.GetPointForSyscall(seccheck.SyscallRawEnter, sysno))
+		pt := seccheck.GetPointForSyscall(seccheck.SyscallRawEnter, sysno)
+		fields := seccheck.Global.GetFieldSet(pt)
 		if !fields.Context.Empty() {
 			info.ContextData = &pb.ContextData{}
 			LoadSeccheckData(t, fields.Context, info.ContextData)
 		}
-		seccheck.Global.SendToCheckers(func(c seccheck.Checker) error {
-			return c.RawSyscall(t, fields, &info)
-		})
+		if err := seccheck.Global.SendRawSyscallToCheckers(t, t.ContainerID(), pt, fields, &info); err != nil {
+			if err := t.seccheckDenied(err); seccheck.IsDenied(err) {
+				denied = err
+			}
+		}
 	}
-	if seccheck.Global.SyscallEnabled(seccheck.SyscallEnter, sysno) {
-		fields := seccheck.Global.GetFieldSet(seccheck.GetPointForSyscall(seccheck.SyscallEnter, sysno))
+	if tracing && denied == nil && seccheck.Global.SyscallEnabled(seccheck.SyscallEnter, sysno) {
+		pt := seccheck.GetPointForSyscall(seccheck.SyscallEnter, sysno)
+		fields := seccheck.Global.GetFieldSet(pt)
 		var ctxData *pb.ContextData
 		if !fields.Context.Empty() {
 			ctxData = &pb.ContextData{}
@@ -121,13 +140,21 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 			Args:  args,
 		}
 		cb := t.SyscallTable().LookupSyscallToProto(sysno)
-		msg, msgType := cb(t, fields, ctxData, info)
-		seccheck.Global.SendToCheckers(func(c seccheck.Checker) error {
-			return c.Syscall(t, fields, ctxData, msgType, msg)
-		})
+		// Callbacks return no message if they send the point asynchronously,
+		// see seccheck.FieldSyscallExecveAsync.
+		if msg, msgType := cb(t, fields, ctxData, info); msg != nil {
+			enterMsg, enterFields = msg, fields
+			if err := seccheck.Global.SendSyscallToCheckers(t, t.ContainerID(), pt, fields, ctxData, msgType, msg); err != nil {
+				if err := t.seccheckDenied(err); seccheck.IsDenied(err) {
+					denied = err
+				}
+			}
+		}
 	}
 
-	if bits.IsOn32(fe, ExternalBeforeEnable) && (s.ExternalFilterBefore == nil || s.ExternalFilterBefore(t, sysno, args)) {
+	if denied != nil {
+		err = denied
+	} else if bits.IsOn32(fe, ExternalBeforeEnable) && (s.ExternalFilterBefore == nil || s.ExternalFilterBefore(t, sysno, args)) {
 		t.invokeExternal()
 		// Ensure we check for stops, then invoke the syscall again.
 		ctrl = ctrlStopAndReinvokeSyscall
@@ -158,7 +185,9 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 		s.Stracer.SyscallExit(straceContext, t, sysno, rval, err)
 	}
 
-	if seccheck.Global.SyscallEnabled(seccheck.SyscallRawExit, sysno) {
+	// Sessions may have been created while the syscall ran.
+	tracing = seccheck.Global.SyscallsEnabled()
+	if tracing && seccheck.Global.SyscallEnabled(seccheck.SyscallRawExit, sysno) {
 		info := pb.Syscall{
 			Sysno: uint64(sysno),
 			Arg1:  args[0].Uint64(),
@@ -172,17 +201,19 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 				Errorno: int64(ExtractErrno(err, int(sysno))),
 			},
 		}
-		fields := seccheck.Global.GetFieldSet(seccheck.GetPointForSyscall(seccheck.SyscallRawEnter, sysno))
+		pt := seccheck.GetPointForSyscall(seccheck.SyscallRawExit, sysno)
+		fields := seccheck.Global.GetFieldSet(pt)
 		if !fields.Context.Empty() {
 			info.ContextData = &pb.ContextData{}
 			LoadSeccheckData(t, fields.Context, info.ContextData)
 		}
-		seccheck.Global.SendToCheckers(func(c seccheck.Checker) error {
-			return c.RawSyscall(t, fields, &info)
-		})
+		seccheck.Global.SendRawSyscallToCheckers(t, t.ContainerID(), pt, fields, &info)
 	}
-	if seccheck.Global.SyscallEnabled(seccheck.SyscallExit, sysno) {
-		fields := seccheck.Global.GetFieldSet(seccheck.GetPointForSyscall(seccheck.SyscallExit, sysno))
+	// exitMsg is the message of the exit point, which may be enterMsg.
+	var exitMsg proto.Message
+	if tracing && seccheck.Global.SyscallEnabled(seccheck.SyscallExit, sysno) {
+		pt := seccheck.GetPointForSyscall(seccheck.SyscallExit, sysno)
+		fields := seccheck.Global.GetFieldSet(pt)
 		var ctxData *pb.ContextData
 		if !fields.Context.Empty() {
 			ctxData = &pb.ContextData{}
@@ -195,12 +226,16 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 			Rval:  rval,
 			Errno: ExtractErrno(err, int(sysno)),
 		}
+		if enterMsg != nil && enterFields.Local.ContainsAll(fields.Local) {
+			info.Enter = enterMsg
+		}
 		cb := t.SyscallTable().LookupSyscallToProto(sysno)
-		msg, msgType := cb(t, fields, ctxData, info)
-		seccheck.Global.SendToCheckers(func(c seccheck.Checker) error {
-			return c.Syscall(t, fields, ctxData, msgType, msg)
-		})
+		var msgType pb.MessageType
+		exitMsg, msgType = cb(t, fields, ctxData, info)
+		seccheck.Global.SendSyscallToCheckers(t, t.ContainerID(), pt, fields, ctxData, msgType, exitMsg)
 	}
+	// Checkers don't retain messages, so both points are done with them.
+	seccheck.ReleaseSyscallMessages(enterMsg, exitMsg)
 
 	return
 }
@@ -76,6 +76,13 @@ type TaskSet struct {
 	// always reset to zero after restore.
 	stopCount int32 `state:"nosave"`
 
+	// pausedContainers is the set of containers whose tasks, current and
+	// future, are in an external stop started by TaskSet.PauseContainer.
+	// pausedContainers is protected by mu.
+	//
+	// pausedContainers is not saved for the same reason as stopCount.
+	pausedContainers map[string]struct{} `state:"nosave"`
+
 	// liveGoroutines is the number of non-exited task goroutines in the
 	// TaskSet.
 	//
@@ -40,6 +40,16 @@ var FromLinuxResource = map[int]LimitType{
 	linux.RLIMIT_RTTIME:     Rttime,
 }
 
+// ToLinuxResource maps a sentry LimitType to the equivalent linux resource.
+func ToLinuxResource(t LimitType) (int, bool) {
+	for resource, lt := range FromLinuxResource {
+		if lt == t {
+			return resource, true
+		}
+	}
+	return 0, false
+}
+
 // FromLinux maps linux rlimit values to sentry Limits, being careful to handle
 // infinities.
 func FromLinux(rl uint64) uint64 {
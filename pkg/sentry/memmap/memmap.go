@@ -432,3 +432,27 @@ type File interface {
 func (fr FileRange) String() string {
 	return fmt.Sprintf("[%#x, %#x)", fr.Start, fr.End)
 }
+
+// FaultInfo records information about the handling of a page fault.
+type FaultInfo struct {
+	// Major is set if a Mappable had to read data from its backing store to
+	// satisfy the fault, analogous to Linux's VM_FAULT_MAJOR.
+	Major bool
+}
+
+// contextID is the memmap package's type for context.Context.Value keys.
+type contextID int
+
+const (
+	// CtxFaultInfo is a Context.Value key for the *FaultInfo of the page fault
+	// being handled, if any.
+	CtxFaultInfo contextID = iota
+)
+
+// RecordMajorFault marks the page fault being handled with ctx, if any, as
+// major. It's called by implementations of Mappable.Translate.
+func RecordMajorFault(ctx context.Context) {
+	if fi, ok := ctx.Value(CtxFaultInfo).(*FaultInfo); ok {
+		fi.Major = true
+	}
+}
@@ -26,6 +26,8 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/kernel/futex"
 	"gvisor.dev/gvisor/pkg/sentry/limits"
 	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 )
 
 // HandleUserFault handles an application page fault. sp is the faulting
@@ -51,6 +53,23 @@ func (mm *MemoryManager) HandleUserFault(ctx context.Context, addr hostarch.Addr
 		return err
 	}
 
+	// If major faults are being reported, ask the Mappable to record whether
+	// it had to read from its backing store. The report is sent after all mm
+	// locks are released.
+	if vma := vseg.ValuePtr(); vma.mappable != nil && vma.id != nil && seccheck.Global.Enabled(seccheck.PointMajorFault) {
+		fault := &memmap.FaultInfo{}
+		ctx = context.WithValue(ctx, memmap.CtxFaultInfo, fault)
+		id := vma.id
+		id.IncRef()
+		off := vseg.mappableOffsetAt(addr)
+		defer func() {
+			if fault.Major {
+				majorFaultSeccheck(ctx, addr, at, id, off)
+			}
+			id.DecRef(ctx)
+		}()
+	}
+
 	// Ensure that we have a usable pma.
 	mm.activeMu.Lock()
 	pseg, _, err := mm.getPMAsLocked(ctx, vseg, ar, at)
@@ -152,9 +171,53 @@ func (mm *MemoryManager) MMap(ctx context.Context, opts memmap.MMapOpts) (hostar
 		id.DecRef(ctx)
 	}
 
+	if opts.Perms.Execute && opts.Mappable != nil && opts.MappingIdentity != nil && seccheck.Global.Enabled(seccheck.PointExecMap) {
+		execMapSeccheck(ctx, ar, &opts)
+	}
+
 	return ar.Start, nil
 }
 
+// execMapSeccheck reports the creation of an executable file-backed mapping
+// to seccheck.
+func execMapSeccheck(ctx context.Context, ar hostarch.AddrRange, opts *memmap.MMapOpts) {
+	info := &pb.ExecMapInfo{
+		Address: uint64(ar.Start),
+		Length:  uint64(ar.Length()),
+		Offset:  opts.Offset,
+		Path:    opts.MappingIdentity.MappedName(ctx),
+	}
+	fields := seccheck.Global.GetFieldSet(seccheck.PointExecMap)
+	if !fields.Context.Empty() {
+		info.ContextData = &pb.ContextData{}
+		seccheck.LoadContextData(ctx, fields.Context, info.ContextData)
+	}
+	seccheck.Global.SendToCheckers(seccheck.ContainerID(ctx), seccheck.PointExecMap, func(c seccheck.Checker) error {
+		return c.ExecMap(ctx, fields, info)
+	})
+}
+
+// majorFaultSeccheck reports a page fault at addr that required the Mappable
+// identified by id to read from its backing store.
+func majorFaultSeccheck(ctx context.Context, addr hostarch.Addr, at hostarch.AccessType, id memmap.MappingIdentity, off uint64) {
+	info := &pb.MajorFaultInfo{
+		Address: uint64(addr),
+		Offset:  off,
+		Path:    id.MappedName(ctx),
+		Read:    at.Read,
+		Write:   at.Write,
+		Execute: at.Execute,
+	}
+	fields := seccheck.Global.GetFieldSet(seccheck.PointMajorFault)
+	if !fields.Context.Empty() {
+		info.ContextData = &pb.ContextData{}
+		seccheck.LoadContextData(ctx, fields.Context, info.ContextData)
+	}
+	seccheck.Global.SendToCheckers(seccheck.ContainerID(ctx), seccheck.PointMajorFault, func(c seccheck.Checker) error {
+		return c.MajorFault(ctx, fields, info)
+	})
+}
+
 // populateVMA obtains pmas for addresses in ar in the given vma, and maps them
 // into mm.as if it is active.
 //
@@ -715,6 +778,19 @@ func (mm *MemoryManager) MProtect(addr hostarch.Addr, length uint64, realPerms h
 	}
 }
 
+// Writable returns true if any page mapped in ar is writable, as set by
+// mmap(2) or mprotect(2).
+func (mm *MemoryManager) Writable(ar hostarch.AddrRange) bool {
+	mm.mappingMu.RLock()
+	defer mm.mappingMu.RUnlock()
+	for vseg := mm.vmas.LowerBoundSegment(ar.Start); vseg.Ok() && vseg.Start() < ar.End; vseg = vseg.NextSegment() {
+		if vseg.ValuePtr().realPerms.Write {
+			return true
+		}
+	}
+	return false
+}
+
 // BrkSetup sets mm's brk address to addr and its brk size to 0.
 func (mm *MemoryManager) BrkSetup(ctx context.Context, addr hostarch.Addr) {
 	var droppedIDs []memmap.MappingIdentity
@@ -27,8 +27,26 @@ const (
 
 	// CtxMemoryFileProvider is a Context.Value key for a MemoryFileProvider.
 	CtxMemoryFileProvider
+
+	// CtxOOMNotifier is a Context.Value key for an OOMNotifier.
+	CtxOOMNotifier
 )
 
+// OOMNotifier is notified when memory can't be allocated on behalf of a
+// context.
+type OOMNotifier interface {
+	// NotifyOOM is called when an allocation of length bytes fails.
+	NotifyOOM(length uint64)
+}
+
+// NotifyOOM notifies the OOMNotifier of ctx, if it has one, that a MemoryFile
+// allocation of length bytes on its behalf failed.
+func NotifyOOM(ctx context.Context, length uint64) {
+	if n, ok := ctx.Value(CtxOOMNotifier).(OOMNotifier); ok {
+		n.NotifyOOM(length)
+	}
+}
+
 // MemoryFileFromContext returns the MemoryFile used by ctx, or nil if no such
 // MemoryFile exists.
 func MemoryFileFromContext(ctx context.Context) *MemoryFile {
@@ -0,0 +1,138 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/context"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// benchSysno is an arbitrary syscall number used by syscall points in
+// benchmarks.
+const benchSysno = 257
+
+// benchPoint is a point of each type, dispatched the same way that the
+// callers of the point do it.
+type benchPoint struct {
+	name string
+	pt   Point
+	// enabled is the check made by the caller before doing any work.
+	enabled func(s *State) bool
+	// send builds the point and sends it to the checkers.
+	send func(s *State, fields FieldSet) error
+}
+
+var benchPoints = []benchPoint{
+	{
+		name:    "sentry",
+		pt:      PointClone,
+		enabled: func(s *State) bool { return s.Enabled(PointClone) },
+		send: func(s *State, fields FieldSet) error {
+			info := &pb.CloneInfo{}
+			return s.SendToCheckers("" /* cid */, PointClone, func(c Checker) error {
+				return c.Clone(context.Background(), fields, info)
+			})
+		},
+	},
+	{
+		name: "syscall",
+		pt:   GetPointForSyscall(SyscallEnter, benchSysno),
+		enabled: func(s *State) bool {
+			return s.SyscallsEnabled() && s.SyscallEnabled(SyscallEnter, benchSysno)
+		},
+		send: func(s *State, fields FieldSet) error {
+			msg := NewOpen()
+			defer ReleaseMessage(msg)
+			msg.Sysno = benchSysno
+			return s.SendSyscallToCheckers(context.Background(), "" /* cid */, GetPointForSyscall(SyscallEnter, benchSysno), fields, nil, pb.MessageType_MESSAGE_SYSCALL_OPEN, msg)
+		},
+	},
+	{
+		name: "raw_syscall",
+		pt:   GetPointForSyscall(SyscallRawEnter, benchSysno),
+		enabled: func(s *State) bool {
+			return s.SyscallsEnabled() && s.SyscallEnabled(SyscallRawEnter, benchSysno)
+		},
+		send: func(s *State, fields FieldSet) error {
+			info := &pb.Syscall{Sysno: benchSysno}
+			return s.SendRawSyscallToCheckers(context.Background(), "" /* cid */, GetPointForSyscall(SyscallRawEnter, benchSysno), fields, info)
+		},
+	},
+}
+
+// run dispatches p, if it's enabled in s.
+func (p *benchPoint) run(s *State) error {
+	if !p.enabled(s) {
+		return nil
+	}
+	return p.send(s, s.GetFieldSet(p.pt))
+}
+
+// TestDisabledPointAllocs checks that points that are not enabled cost nothing
+// more than the check, even when other points are.
+func TestDisabledPointAllocs(t *testing.T) {
+	var other State
+	other.AppendChecker(&testChecker{}, []PointReq{{Pt: PointExecve}, {Pt: GetPointForSyscall(SyscallExit, benchSysno+1)}})
+	for _, s := range []*State{{}, &other} {
+		for i := range benchPoints {
+			p := &benchPoints[i]
+			allocs := testing.AllocsPerRun(100, func() {
+				if err := p.run(s); err != nil {
+					t.Fatalf("run(): %v", err)
+				}
+			})
+			if allocs != 0 {
+				t.Errorf("%s point allocated while disabled: %v allocs per run", p.name, allocs)
+			}
+			if p.enabled(s) {
+				t.Errorf("%s point is enabled", p.name)
+			}
+		}
+	}
+}
+
+func BenchmarkPointDisabled(b *testing.B) {
+	var s State
+	for i := range benchPoints {
+		p := &benchPoints[i]
+		b.Run(p.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := p.run(&s); err != nil {
+					b.Fatalf("run(): %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkPointNullSink(b *testing.B) {
+	for i := range benchPoints {
+		p := &benchPoints[i]
+		b.Run(p.name, func(b *testing.B) {
+			var s State
+			s.AppendChecker(&testChecker{}, []PointReq{{Pt: p.pt}})
+			b.ResetTimer()
+			b.RunParallel(func(sub *testing.PB) {
+				for sub.Next() {
+					if err := p.run(&s); err != nil {
+						b.Fatalf("run(): %v", err)
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package anomaly defines a seccheck.Checker that aggregates points and sends
+// alerts to sentry/anomaly_alert when they cross thresholds, for consumers
+// that want pre-digested signals rather than every point.
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const name = "anomaly"
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     name,
+		New:      new,
+		Validate: validate,
+	})
+}
+
+// Metrics counted by thresholds, see anomaly.
+const (
+	metricFailedOpens          = "failed_opens"
+	metricFailedSyscalls       = "failed_syscalls"
+	metricDistinctDestinations = "distinct_destinations"
+	metricExecs                = "execs"
+)
+
+// maxLimit is the maximum limit of a threshold, which bounds the memory used
+// to count its events.
+const maxLimit = 100000
+
+// threshold is the number of events of a metric allowed within a window.
+type threshold struct {
+	name   string
+	metric string
+	limit  int
+	window time.Duration
+}
+
+// counter counts the events of a threshold within a sliding window.
+type counter struct {
+	// times are the times of the events in the window, oldest first.
+	times []time.Time
+	// seen maps the distinct values of events to the last time that they
+	// were seen, for metrics that count distinct values.
+	seen map[string]time.Time
+}
+
+// add records an event with value at now, and returns the number of events
+// counted within the window of t.
+func (c *counter) add(t *threshold, value string, now time.Time) int {
+	start := now.Add(-t.window)
+	if t.metric == metricDistinctDestinations {
+		if c.seen == nil {
+			c.seen = make(map[string]time.Time)
+		}
+		c.seen[value] = now
+		if len(c.seen) > t.limit {
+			for v, last := range c.seen {
+				if !last.After(start) {
+					delete(c.seen, v)
+				}
+			}
+		}
+		return len(c.seen)
+	}
+	i := 0
+	for i < len(c.times) && !c.times[i].After(start) {
+		i++
+	}
+	n := copy(c.times, c.times[i:])
+	c.times = append(c.times[:n], now)
+	return len(c.times)
+}
+
+// reset forgets all events, so that the threshold must be crossed again to
+// send another alert.
+func (c *counter) reset() {
+	c.times = c.times[:0]
+	c.seen = nil
+}
+
+// anomaly counts the events of metrics per container, and sends an alert to
+// sentry/anomaly_alert when a threshold is crossed, configured as:
+//
+//	thresholds: list of {"name": "failed-opens", "metric": "failed_opens",
+//	            "limit": 100, "window": "10s"}, which alerts when more than
+//	            limit events of metric happen within window.
+//
+// Metrics are:
+//   - "failed_opens": open(2), openat(2) and creat(2) that fail, counted at
+//     their syscall exit points.
+//   - "failed_syscalls": syscalls that fail, counted at their syscall exit
+//     points, including raw ones. Syscalls that are enabled at both raw and
+//     schematized points are counted twice.
+//   - "distinct_destinations": distinct IP addresses that connect(2) and
+//     sendto(2) send to, counted at their syscall enter points.
+//   - "execs": executions, counted at sentry/execve.
+//
+// Events are counted per container, so points must include the container_id
+// context field, see the anomaly preset. Once an alert is sent, the events of
+// the threshold are forgotten, so that another alert is only sent when it's
+// crossed again.
+type anomaly struct {
+	seccheck.CheckerDefaults
+
+	thresholds []threshold
+
+	// now returns the current time.
+	now func() time.Time
+	// send sends an alert for an event of ctx.
+	send func(ctx context.Context, info *pb.AnomalyAlertInfo)
+
+	mu sync.Mutex
+	// counters maps container IDs to the counters of each threshold.
+	// +checklocks:mu
+	counters map[string][]counter
+
+	alertCount atomicbitops.Uint64
+}
+
+var _ seccheck.Checker = (*anomaly)(nil)
+
+func parseThreshold(opaque interface{}) (threshold, error) {
+	obj, ok := opaque.(map[string]interface{})
+	if !ok {
+		return threshold{}, fmt.Errorf("threshold %v is not an object", opaque)
+	}
+	var t threshold
+	t.name, _ = obj["name"].(string)
+	if len(t.name) == 0 {
+		return threshold{}, fmt.Errorf("threshold name %v is invalid", obj["name"])
+	}
+	t.metric, _ = obj["metric"].(string)
+	switch t.metric {
+	case metricFailedOpens, metricFailedSyscalls, metricDistinctDestinations, metricExecs:
+	default:
+		return threshold{}, fmt.Errorf("invalid metric %v for threshold %q, must be %q, %q, %q or %q", obj["metric"], t.name, metricFailedOpens, metricFailedSyscalls, metricDistinctDestinations, metricExecs)
+	}
+	limit, ok := obj["limit"].(float64)
+	if !ok || limit < 1 || limit > maxLimit || limit != math.Trunc(limit) {
+		return threshold{}, fmt.Errorf("limit %v for threshold %q must be an integer between 1 and %d", obj["limit"], t.name, maxLimit)
+	}
+	t.limit = int(limit)
+	window, _ := obj["window"].(string)
+	var err error
+	if t.window, err = time.ParseDuration(window); err != nil || t.window <= 0 {
+		return threshold{}, fmt.Errorf("window %v for threshold %q is not a positive duration", obj["window"], t.name)
+	}
+	return t, nil
+}
+
+func parse(config map[string]interface{}) (*anomaly, error) {
+	list, ok := config["thresholds"].([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("thresholds %v is not a non-empty list", config["thresholds"])
+	}
+	a := &anomaly{
+		now:      time.Now,
+		send:     sendAlert,
+		counters: make(map[string][]counter),
+	}
+	names := make(map[string]struct{}, len(list))
+	for _, opaque := range list {
+		t, err := parseThreshold(opaque)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := names[t.name]; ok {
+			return nil, fmt.Errorf("duplicate threshold %q", t.name)
+		}
+		names[t.name] = struct{}{}
+		a.thresholds = append(a.thresholds, t)
+	}
+	return a, nil
+}
+
+func validate(config map[string]interface{}) error {
+	_, err := parse(config)
+	return err
+}
+
+func new(config map[string]interface{}, _ *fd.FD) (seccheck.Checker, error) {
+	return parse(config)
+}
+
+// Name implements seccheck.Checker.
+func (*anomaly) Name() string {
+	return name
+}
+
+// Status implements seccheck.Checker.
+func (a *anomaly) Status() seccheck.CheckerStatus {
+	return seccheck.CheckerStatus{
+		Metrics: []seccheck.MetricSample{
+			{
+				Family: "runsc_trace_anomaly_alerts_total",
+				Type:   "counter",
+				Name:   "runsc_trace_anomaly_alerts_total",
+				Value:  a.alertCount.Load(),
+			},
+		},
+	}
+}
+
+// record counts an event of metric with value, which is only used by metrics
+// that count distinct values, and sends alerts for the thresholds that it
+// crosses.
+func (a *anomaly) record(ctx context.Context, ctxData *pb.ContextData, metric, value string) {
+	var alerts []*pb.AnomalyAlertInfo
+	now := a.now()
+	cid := ctxData.GetContainerId()
+
+	a.mu.Lock()
+	counters, ok := a.counters[cid]
+	if !ok {
+		counters = make([]counter, len(a.thresholds))
+		a.counters[cid] = counters
+	}
+	for i := range a.thresholds {
+		t := &a.thresholds[i]
+		if t.metric != metric {
+			continue
+		}
+		c := &counters[i]
+		if n := c.add(t, value, now); n > t.limit {
+			c.reset()
+			alerts = append(alerts, &pb.AnomalyAlertInfo{
+				Name:     t.name,
+				Metric:   t.metric,
+				Count:    uint64(n),
+				Limit:    uint64(t.limit),
+				WindowNs: uint64(t.window.Nanoseconds()),
+			})
+		}
+	}
+	a.mu.Unlock()
+
+	// Alerts are sent without holding mu, since they're sent to checkers
+	// that may include this one.
+	for _, info := range alerts {
+		a.alertCount.Add(1)
+		a.send(ctx, info)
+	}
+}
+
+// sendAlert sends info to the checkers registered for
+// seccheck.PointAnomalyAlert.
+func sendAlert(ctx context.Context, info *pb.AnomalyAlertInfo) {
+	if !seccheck.Global.Enabled(seccheck.PointAnomalyAlert) {
+		return
+	}
+	fields := seccheck.Global.GetFieldSet(seccheck.PointAnomalyAlert)
+	if t := kernel.TaskFromContext(ctx); t != nil && !fields.Context.Empty() {
+		info.ContextData = &pb.ContextData{}
+		kernel.LoadSeccheckData(t, fields.Context, info.ContextData)
+	}
+	// Alerts can't be denied, so errors from checkers are ignored.
+	_ = seccheck.Global.SendToCheckers(seccheck.ContainerID(ctx), seccheck.PointAnomalyAlert, func(c seccheck.Checker) error {
+		return c.AnomalyAlert(ctx, fields, info)
+	})
+}
+
+// destinationIP returns the IP address of a sockaddr_in or sockaddr_in6, or
+// false if addr isn't one of them.
+func destinationIP(addr []byte) (net.IP, bool) {
+	if len(addr) < 2 {
+		return nil, false
+	}
+	switch hostarch.ByteOrder.Uint16(addr) {
+	case linux.AF_INET:
+		if len(addr) < 8 {
+			return nil, false
+		}
+		return net.IP(addr[4:8]), true
+	case linux.AF_INET6:
+		if len(addr) < 24 {
+			return nil, false
+		}
+		return net.IP(addr[8:24]), true
+	default:
+		return nil, false
+	}
+}
+
+// Syscall implements seccheck.Checker.
+func (a *anomaly) Syscall(ctx context.Context, _ seccheck.FieldSet, ctxData *pb.ContextData, _ pb.MessageType, msg proto.Message) error {
+	var addr []byte
+	switch m := msg.(type) {
+	case *pb.Connect:
+		addr = m.Address
+	case *pb.Sendto:
+		addr = m.Address
+	}
+	withExit, ok := msg.(interface{ GetExit() *pb.Exit })
+	if !ok {
+		return nil
+	}
+	exit := withExit.GetExit()
+	if exit == nil {
+		if ip, ok := destinationIP(addr); ok {
+			a.record(ctx, ctxData, metricDistinctDestinations, ip.String())
+		}
+		return nil
+	}
+	if exit.Errorno == 0 {
+		return nil
+	}
+	if _, ok := msg.(*pb.Open); ok {
+		a.record(ctx, ctxData, metricFailedOpens, "")
+	}
+	a.record(ctx, ctxData, metricFailedSyscalls, "")
+	return nil
+}
+
+// RawSyscall implements seccheck.Checker.
+func (a *anomaly) RawSyscall(ctx context.Context, _ seccheck.FieldSet, info *pb.Syscall) error {
+	if exit := info.GetExit(); exit != nil && exit.Errorno != 0 {
+		a.record(ctx, info.ContextData, metricFailedSyscalls, "")
+	}
+	return nil
+}
+
+// Execve implements seccheck.Checker.
+func (a *anomaly) Execve(ctx context.Context, _ seccheck.FieldSet, info *pb.ExecveInfo) error {
+	a.record(ctx, info.ContextData, metricExecs, "")
+	return nil
+}
@@ -0,0 +1,212 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anomaly
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func parseConfig(t *testing.T, text string) map[string]interface{} {
+	t.Helper()
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &config); err != nil {
+		t.Fatalf("json.Unmarshal(%q): %v", text, err)
+	}
+	return config
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "valid",
+			config: `{"thresholds": [{"name": "a", "metric": "failed_opens", "limit": 100, "window": "10s"}, {"name": "b", "metric": "distinct_destinations", "limit": 50, "window": "1m"}]}`,
+		},
+		{
+			name:   "no thresholds",
+			config: `{}`,
+			err:    "not a non-empty list",
+		},
+		{
+			name:   "no name",
+			config: `{"thresholds": [{"metric": "execs", "limit": 1, "window": "1s"}]}`,
+			err:    "name",
+		},
+		{
+			name:   "invalid metric",
+			config: `{"thresholds": [{"name": "a", "metric": "foo", "limit": 1, "window": "1s"}]}`,
+			err:    "invalid metric",
+		},
+		{
+			name:   "zero limit",
+			config: `{"thresholds": [{"name": "a", "metric": "execs", "limit": 0, "window": "1s"}]}`,
+			err:    "limit",
+		},
+		{
+			name:   "fractional limit",
+			config: `{"thresholds": [{"name": "a", "metric": "execs", "limit": 1.5, "window": "1s"}]}`,
+			err:    "limit",
+		},
+		{
+			name:   "invalid window",
+			config: `{"thresholds": [{"name": "a", "metric": "execs", "limit": 1, "window": "-1s"}]}`,
+			err:    "window",
+		},
+		{
+			name:   "duplicate name",
+			config: `{"thresholds": [{"name": "a", "metric": "execs", "limit": 1, "window": "1s"}, {"name": "a", "metric": "failed_opens", "limit": 1, "window": "1s"}]}`,
+			err:    "duplicate",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validate(parseConfig(t, tc.config))
+			if len(tc.err) == 0 {
+				if err != nil {
+					t.Fatalf("validate(): %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("validate(): got %v, want error containing %q", err, tc.err)
+			}
+		})
+	}
+}
+
+// newTestAnomaly returns an anomaly checker with a fake clock, and the alerts
+// that it sends.
+func newTestAnomaly(t *testing.T, config string) (*anomaly, *time.Time, *[]*pb.AnomalyAlertInfo) {
+	t.Helper()
+	a, err := parse(parseConfig(t, config))
+	if err != nil {
+		t.Fatalf("parse(): %v", err)
+	}
+	now := time.Unix(0, 0)
+	a.now = func() time.Time { return now }
+	var alerts []*pb.AnomalyAlertInfo
+	a.send = func(_ context.Context, info *pb.AnomalyAlertInfo) {
+		alerts = append(alerts, info)
+	}
+	return a, &now, &alerts
+}
+
+func failedOpen(cid string) (*pb.ContextData, *pb.Open) {
+	return &pb.ContextData{ContainerId: cid}, &pb.Open{Exit: &pb.Exit{Errorno: int64(linux.ENOENT)}}
+}
+
+func TestFailedOpens(t *testing.T) {
+	a, now, alerts := newTestAnomaly(t, `{"thresholds": [{"name": "opens", "metric": "failed_opens", "limit": 3, "window": "10s"}]}`)
+	send := func(cid string) {
+		ctxData, msg := failedOpen(cid)
+		if err := a.Syscall(nil, seccheck.FieldSet{}, ctxData, pb.MessageType_MESSAGE_SYSCALL_OPEN, msg); err != nil {
+			t.Fatalf("Syscall(): %v", err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		send("c1")
+		*now = now.Add(time.Second)
+	}
+	// Successful opens and other containers aren't counted.
+	if err := a.Syscall(nil, seccheck.FieldSet{}, &pb.ContextData{ContainerId: "c1"}, pb.MessageType_MESSAGE_SYSCALL_OPEN, &pb.Open{Exit: &pb.Exit{}}); err != nil {
+		t.Fatalf("Syscall(): %v", err)
+	}
+	send("c2")
+	if len(*alerts) != 0 {
+		t.Fatalf("got alerts %v before the threshold is crossed", *alerts)
+	}
+
+	send("c1")
+	if len(*alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(*alerts))
+	}
+	if got := (*alerts)[0]; got.Name != "opens" || got.Count != 4 || got.Limit != 3 || got.WindowNs != uint64(10*time.Second) {
+		t.Errorf("got alert %v, want opens with count 4, limit 3 and 10s window", got)
+	}
+
+	// Events are forgotten after an alert, and once they fall out of the
+	// window.
+	*alerts = nil
+	for i := 0; i < 3; i++ {
+		send("c1")
+		*now = now.Add(5 * time.Second)
+	}
+	send("c1")
+	if len(*alerts) != 0 {
+		t.Fatalf("got alerts %v for events outside of the window", *alerts)
+	}
+	if got := a.Status().Metrics[0].Value; got != 1 {
+		t.Errorf("alert count: got %d, want 1", got)
+	}
+}
+
+func sockaddrIPv4(ip [4]byte) []byte {
+	addr := make([]byte, 16)
+	hostarch.ByteOrder.PutUint16(addr, linux.AF_INET)
+	copy(addr[4:], ip[:])
+	return addr
+}
+
+func TestDistinctDestinations(t *testing.T) {
+	a, now, alerts := newTestAnomaly(t, `{"thresholds": [{"name": "scan", "metric": "distinct_destinations", "limit": 2, "window": "1m"}]}`)
+	connect := func(ip [4]byte) {
+		msg := &pb.Connect{Address: sockaddrIPv4(ip)}
+		if err := a.Syscall(nil, seccheck.FieldSet{}, &pb.ContextData{}, pb.MessageType_MESSAGE_SYSCALL_CONNECT, msg); err != nil {
+			t.Fatalf("Syscall(): %v", err)
+		}
+	}
+
+	connect([4]byte{10, 0, 0, 1})
+	connect([4]byte{10, 0, 0, 1})
+	connect([4]byte{10, 0, 0, 2})
+	if len(*alerts) != 0 {
+		t.Fatalf("got alerts %v for 2 distinct destinations", *alerts)
+	}
+	// Destinations outside of the window aren't counted.
+	*now = now.Add(2 * time.Minute)
+	connect([4]byte{10, 0, 0, 3})
+	if len(*alerts) != 0 {
+		t.Fatalf("got alerts %v for destinations outside of the window", *alerts)
+	}
+	connect([4]byte{10, 0, 0, 4})
+	connect([4]byte{10, 0, 0, 5})
+	if len(*alerts) != 1 || (*alerts)[0].Count != 3 {
+		t.Fatalf("got alerts %v, want 1 with count 3", *alerts)
+	}
+}
+
+func TestExecs(t *testing.T) {
+	a, _, alerts := newTestAnomaly(t, `{"thresholds": [{"name": "execs", "metric": "execs", "limit": 1, "window": "1s"}, {"name": "failures", "metric": "failed_syscalls", "limit": 1, "window": "1s"}]}`)
+	for i := 0; i < 2; i++ {
+		if err := a.Execve(nil, seccheck.FieldSet{}, &pb.ExecveInfo{}); err != nil {
+			t.Fatalf("Execve(): %v", err)
+		}
+	}
+	if len(*alerts) != 1 || (*alerts)[0].Name != "execs" {
+		t.Fatalf("got alerts %v, want 1 for execs", *alerts)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+)
+
+// Errors that denied connections fail with, see egress.
+var egressErrors = map[string]error{
+	"EPERM":       seccheck.ErrDenied,
+	"ENETUNREACH": seccheck.ErrDeniedUnreachable,
+}
+
+// egressRule sets the access to the destinations in network and port.
+type egressRule struct {
+	network *net.IPNet
+	// port is the destination port, or 0 for any port.
+	port   uint16
+	access string
+	// enforcement applies when the rule denies a destination.
+	enforcement enforcement
+}
+
+// String returns a description of the rule.
+func (r *egressRule) String() string {
+	if r.port == 0 {
+		return fmt.Sprintf("%s %s", r.access, r.network)
+	}
+	return fmt.Sprintf("%s %s port %d", r.access, r.network, r.port)
+}
+
+// contains returns true if the destination ip:port is in r.
+func (r *egressRule) contains(ip net.IP, port uint16) bool {
+	return (r.port == 0 || r.port == port) && r.network.Contains(ip)
+}
+
+// egress denies connections and datagrams to IP destinations, configured as:
+//
+//	egress_rules: list of {"cidr": "10.0.0.0/8", "port": 443, "access": "deny"},
+//	              where port is optional and access is "allow" or "deny".
+//	              Like path rules, they may set "action" and "mode".
+//	egress_default: access to destinations that no rule contains, "allow"
+//	                (default) or "deny" to only allow destinations in rules.
+//	egress_errno: error that denied syscalls fail with, "EPERM" (default) or
+//	              "ENETUNREACH".
+//
+// The rule with the longest prefix that contains a destination applies to it,
+// and rules with a port take precedence over rules without one. Destinations
+// are checked when connect(2) and sendto(2) are entered. sendto(2) on
+// connected sockets and sendmsg(2) are not checked, nor are addresses of
+// families other than AF_INET and AF_INET6, e.g. AF_UNIX.
+type egress struct {
+	// rules are sorted by decreasing prefix length, so that the first rule
+	// that contains a destination applies to it.
+	rules         []egressRule
+	defaultAccess string
+	// defaultEnforcement applies when defaultAccess denies a destination.
+	defaultEnforcement enforcement
+	err                error
+}
+
+// parseEgress returns the egress configuration in config, or nil if config
+// doesn't restrict egress. Rules take the settings of def that they don't
+// override.
+func parseEgress(config map[string]interface{}, def enforcement) (*egress, error) {
+	e := &egress{
+		defaultAccess:      accessAllow,
+		defaultEnforcement: def,
+		err:                seccheck.ErrDenied,
+	}
+	present := false
+	if opaque, ok := config["egress_default"]; ok {
+		present = true
+		access, _ := opaque.(string)
+		if access != accessAllow && access != accessDeny {
+			return nil, fmt.Errorf("invalid egress_default %v, must be %q or %q", opaque, accessAllow, accessDeny)
+		}
+		e.defaultAccess = access
+	}
+	if opaque, ok := config["egress_errno"]; ok {
+		errno, _ := opaque.(string)
+		err, ok := egressErrors[errno]
+		if !ok {
+			return nil, fmt.Errorf("invalid egress_errno %v, must be \"EPERM\" or \"ENETUNREACH\"", opaque)
+		}
+		e.err = err
+	}
+	if opaque, ok := config["egress_rules"]; ok {
+		present = true
+		list, ok := opaque.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("egress_rules %v is not a list", opaque)
+		}
+		type key struct {
+			network string
+			port    uint16
+		}
+		seen := make(map[key]struct{}, len(list))
+		for _, opaque := range list {
+			r, err := parseEgressRule(opaque, def)
+			if err != nil {
+				return nil, err
+			}
+			k := key{network: r.network.String(), port: r.port}
+			if _, ok := seen[k]; ok {
+				return nil, fmt.Errorf("duplicate egress rule for %q port %d", k.network, k.port)
+			}
+			seen[k] = struct{}{}
+			e.rules = append(e.rules, r)
+		}
+	}
+	if !present {
+		return nil, nil
+	}
+	sort.SliceStable(e.rules, func(i, j int) bool {
+		li, _ := e.rules[i].network.Mask.Size()
+		lj, _ := e.rules[j].network.Mask.Size()
+		if li != lj {
+			return li > lj
+		}
+		return e.rules[i].port != 0 && e.rules[j].port == 0
+	})
+	return e, nil
+}
+
+func parseEgressRule(opaque interface{}, def enforcement) (egressRule, error) {
+	obj, ok := opaque.(map[string]interface{})
+	if !ok {
+		return egressRule{}, fmt.Errorf("egress rule %v is not an object", opaque)
+	}
+	cidr, _ := obj["cidr"].(string)
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return egressRule{}, fmt.Errorf("egress rule cidr %v is invalid: %w", obj["cidr"], err)
+	}
+	r := egressRule{network: network}
+	if opaque, ok := obj["port"]; ok {
+		port, ok := opaque.(float64)
+		if !ok || port < 1 || port > math.MaxUint16 || port != math.Trunc(port) {
+			return egressRule{}, fmt.Errorf("egress rule port %v for %q is invalid", opaque, cidr)
+		}
+		r.port = uint16(port)
+	}
+	r.access, _ = obj["access"].(string)
+	if r.access != accessAllow && r.access != accessDeny {
+		return egressRule{}, fmt.Errorf("invalid access %v for %q, must be %q or %q", obj["access"], cidr, accessAllow, accessDeny)
+	}
+	if r.enforcement, err = parseEnforcement(obj, def); err != nil {
+		return egressRule{}, fmt.Errorf("egress rule for %q: %w", cidr, err)
+	}
+	return r, nil
+}
+
+// match returns the rule that applies to the destination ip:port, or nil if
+// none does.
+func (e *egress) match(ip net.IP, port uint16) *egressRule {
+	for i := range e.rules {
+		if e.rules[i].contains(ip, port) {
+			return &e.rules[i]
+		}
+	}
+	return nil
+}
+
+// parseAddress returns the IP address and port of a sockaddr_in or
+// sockaddr_in6, or false if addr isn't one of them.
+func parseAddress(addr []byte) (net.IP, uint16, bool) {
+	if len(addr) < 2 {
+		return nil, 0, false
+	}
+	switch hostarch.ByteOrder.Uint16(addr) {
+	case linux.AF_INET:
+		if len(addr) < 8 {
+			return nil, 0, false
+		}
+		return net.IP(addr[4:8]), binary.BigEndian.Uint16(addr[2:4]), true
+	case linux.AF_INET6:
+		if len(addr) < 24 {
+			return nil, 0, false
+		}
+		return net.IP(addr[8:24]), binary.BigEndian.Uint16(addr[2:4]), true
+	default:
+		return nil, 0, false
+	}
+}
+
+// checkEgress returns an error that denies the syscall sysno if it can't send
+// to the sockaddr addr.
+func (p *policy) checkEgress(sysno uint64, addr []byte) error {
+	if p.egress == nil {
+		return nil
+	}
+	ip, port, ok := parseAddress(addr)
+	if !ok {
+		// Not an IP destination, or the syscall fails on its own.
+		return nil
+	}
+	rule := "egress_default " + p.egress.defaultAccess
+	access, e := p.egress.defaultAccess, p.egress.defaultEnforcement
+	if r := p.egress.match(ip, port); r != nil {
+		rule, access, e = r.String(), r.access, r.enforcement
+	}
+	if access == accessAllow {
+		return nil
+	}
+	dest := net.JoinHostPort(ip.String(), strconv.Itoa(int(port)))
+	return p.violation(e, rule, fmt.Sprintf("syscall %d to %s", sysno, dest), p.egress.err)
+}
@@ -0,0 +1,203 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func newEgressRule(cidr string, port int, access string) map[string]interface{} {
+	r := map[string]interface{}{"cidr": cidr, "access": access}
+	if port != 0 {
+		r["port"] = float64(port)
+	}
+	return r
+}
+
+// sockaddr returns the sockaddr for ip:port.
+func sockaddr(ip string, port uint16) []byte {
+	addr := net.ParseIP(ip)
+	if v4 := addr.To4(); v4 != nil {
+		buf := make([]byte, 16)
+		hostarch.ByteOrder.PutUint16(buf, linux.AF_INET)
+		binary.BigEndian.PutUint16(buf[2:], port)
+		copy(buf[4:], v4)
+		return buf
+	}
+	buf := make([]byte, 28)
+	hostarch.ByteOrder.PutUint16(buf, linux.AF_INET6)
+	binary.BigEndian.PutUint16(buf[2:], port)
+	copy(buf[8:], addr)
+	return buf
+}
+
+func TestEgressConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config map[string]interface{}
+		err    string
+	}{
+		{
+			name: "valid",
+			config: map[string]interface{}{
+				"egress_rules": []interface{}{newEgressRule("10.0.0.0/8", 0, "deny"), newEgressRule("10.0.0.1/32", 443, "allow")},
+				"egress_errno": "ENETUNREACH",
+			},
+		},
+		{
+			name:   "allowlist",
+			config: map[string]interface{}{"egress_default": "deny"},
+		},
+		{
+			name:   "default",
+			config: map[string]interface{}{"egress_default": "read-only"},
+			err:    "invalid egress_default",
+		},
+		{
+			name:   "errno",
+			config: map[string]interface{}{"egress_default": "deny", "egress_errno": "EACCES"},
+			err:    "invalid egress_errno",
+		},
+		{
+			name:   "cidr",
+			config: map[string]interface{}{"egress_rules": []interface{}{newEgressRule("10.0.0.0", 0, "deny")}},
+			err:    "cidr",
+		},
+		{
+			name:   "port",
+			config: map[string]interface{}{"egress_rules": []interface{}{newEgressRule("10.0.0.0/8", 70000, "deny")}},
+			err:    "port",
+		},
+		{
+			name:   "access",
+			config: map[string]interface{}{"egress_rules": []interface{}{newEgressRule("10.0.0.0/8", 0, "read-only")}},
+			err:    "invalid access",
+		},
+		{
+			name:   "duplicate",
+			config: map[string]interface{}{"egress_rules": []interface{}{newEgressRule("10.1.0.0/8", 0, "deny"), newEgressRule("10.0.0.0/8", 0, "allow")}},
+			err:    "duplicate egress rule",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validate(tc.config)
+			if len(tc.err) == 0 {
+				if err != nil {
+					t.Errorf("validate(): %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("validate(): got: %v, want: %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestEgress(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		config  map[string]interface{}
+		msg     proto.Message
+		wantErr error
+	}{
+		{
+			name:    "deny",
+			config:  map[string]interface{}{"egress_rules": []interface{}{newEgressRule("169.254.0.0/16", 0, "deny")}},
+			msg:     &pb.Connect{Address: sockaddr("169.254.169.254", 80)},
+			wantErr: seccheck.ErrDenied,
+		},
+		{
+			name:   "unmatched",
+			config: map[string]interface{}{"egress_rules": []interface{}{newEgressRule("169.254.0.0/16", 0, "deny")}},
+			msg:    &pb.Connect{Address: sockaddr("8.8.8.8", 53)},
+		},
+		{
+			name: "port",
+			config: map[string]interface{}{"egress_rules": []interface{}{
+				newEgressRule("10.0.0.0/8", 0, "deny"),
+				newEgressRule("10.0.0.0/8", 443, "allow"),
+			}},
+			msg: &pb.Connect{Address: sockaddr("10.1.2.3", 443)},
+		},
+		{
+			name: "longest-prefix",
+			config: map[string]interface{}{"egress_rules": []interface{}{
+				newEgressRule("10.0.0.0/8", 0, "allow"),
+				newEgressRule("10.1.0.0/16", 0, "deny"),
+			}},
+			msg:     &pb.Sendto{Address: sockaddr("10.1.2.3", 53)},
+			wantErr: seccheck.ErrDenied,
+		},
+		{
+			name: "allowlist",
+			config: map[string]interface{}{
+				"egress_default": "deny",
+				"egress_rules":   []interface{}{newEgressRule("10.0.0.0/8", 0, "allow")},
+				"egress_errno":   "ENETUNREACH",
+			},
+			msg:     &pb.Connect{Address: sockaddr("8.8.8.8", 53)},
+			wantErr: seccheck.ErrDeniedUnreachable,
+		},
+		{
+			name:    "ipv6",
+			config:  map[string]interface{}{"egress_rules": []interface{}{newEgressRule("fd00::/8", 0, "deny")}},
+			msg:     &pb.Connect{Address: sockaddr("fd00::1", 80)},
+			wantErr: seccheck.ErrDenied,
+		},
+		{
+			name:    "ipv4-mapped",
+			config:  map[string]interface{}{"egress_rules": []interface{}{newEgressRule("10.0.0.0/8", 0, "deny")}},
+			msg:     &pb.Connect{Address: sockaddr("::ffff:10.0.0.1", 80)},
+			wantErr: seccheck.ErrDenied,
+		},
+		{
+			name:   "connected",
+			config: map[string]interface{}{"egress_default": "deny"},
+			msg:    &pb.Sendto{},
+		},
+		{
+			name:   "unix",
+			config: map[string]interface{}{"egress_default": "deny"},
+			msg:    &pb.Connect{Address: []byte{linux.AF_UNIX, 0, '/', 0}},
+		},
+		{
+			name:   "exit",
+			config: map[string]interface{}{"egress_default": "deny"},
+			msg:    &pb.Connect{Address: sockaddr("8.8.8.8", 53), Exit: &pb.Exit{}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := new(tc.config, nil)
+			if err != nil {
+				t.Fatalf("new(): %v", err)
+			}
+			err = c.Syscall(nil, seccheck.FieldSet{}, nil, pb.MessageType_MESSAGE_UNKNOWN, tc.msg)
+			if v, ok := err.(*seccheck.Violation); ok {
+				err = v.Err
+			}
+			if err != tc.wantErr {
+				t.Errorf("Syscall(%v): got: %v, want: %v", tc.msg, err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// execAllowlist only allows the execution of binaries that it lists by path or
+// by SHA-256 digest, configured as:
+//
+//	exec_allowlist: {"paths": ["/usr/bin", ...], "sha256": ["<hex>", ...]},
+//	                where paths allow the binaries at or under them.
+//
+// Binaries are checked at the sentry/execve point, which must include the
+// binary_sha256 field for digests to match, see the exec-policy preset.
+// Binaries are identified by the first file that execve(2) opens, e.g. a
+// script rather than its interpreter, and deleted binaries don't match paths.
+type execAllowlist struct {
+	paths   []string
+	digests map[string]struct{}
+}
+
+// parseExecAllowlist returns the allowlist in config, or nil if config doesn't
+// restrict execution.
+func parseExecAllowlist(config map[string]interface{}) (*execAllowlist, error) {
+	opaque, ok := config["exec_allowlist"]
+	if !ok {
+		return nil, nil
+	}
+	obj, ok := opaque.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("exec_allowlist %v is not an object", opaque)
+	}
+	e := &execAllowlist{digests: make(map[string]struct{})}
+	if opaque, ok := obj["paths"]; ok {
+		list, ok := opaque.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("exec_allowlist paths %v is not a list", opaque)
+		}
+		for _, opaque := range list {
+			pathname, ok := opaque.(string)
+			if !ok || !path.IsAbs(pathname) {
+				return nil, fmt.Errorf("exec_allowlist path %v is not an absolute path", opaque)
+			}
+			e.paths = append(e.paths, path.Clean(pathname))
+		}
+	}
+	if opaque, ok := obj["sha256"]; ok {
+		list, ok := opaque.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("exec_allowlist sha256 %v is not a list", opaque)
+		}
+		for _, opaque := range list {
+			digest, _ := opaque.(string)
+			digest = strings.ToLower(digest)
+			if b, err := hex.DecodeString(digest); err != nil || len(b) != 32 {
+				return nil, fmt.Errorf("exec_allowlist sha256 %v is not a SHA-256 digest", opaque)
+			}
+			e.digests[digest] = struct{}{}
+		}
+	}
+	return e, nil
+}
+
+// allows returns true if the binary executed by info is in the allowlist.
+func (e *execAllowlist) allows(info *pb.ExecveInfo) bool {
+	if len(info.BinarySha256) > 0 {
+		if _, ok := e.digests[hex.EncodeToString(info.BinarySha256)]; ok {
+			return true
+		}
+	}
+	if !path.IsAbs(info.BinaryPath) || strings.HasSuffix(info.BinaryPath, " (deleted)") {
+		// The binary is unknown, or was replaced since it was opened.
+		return false
+	}
+	for _, prefix := range e.paths {
+		r := rule{prefix: prefix}
+		if r.contains(info.BinaryPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// Execve implements seccheck.Checker.
+func (p *policy) Execve(_ context.Context, _ seccheck.FieldSet, info *pb.ExecveInfo) error {
+	if p.exec == nil || p.exec.allows(info) {
+		return nil
+	}
+	return p.violation(p.enforcement, "exec_allowlist", fmt.Sprintf("execve of %q", info.BinaryPath), seccheck.ErrDenied)
+}
+
+// parseFilelessExec returns whether config denies fileless execution,
+// configured as:
+//
+//	deny_fileless_exec: true to deny the execution of files created by
+//	       memfd_create(2), by execve(2), execveat(2) or mmap(2) with
+//	       PROT_EXEC, which are used to run payloads that never touch the
+//	       disk.
+//
+// Executions are checked at the sentry/fileless_exec point, see the
+// exec-policy preset. Mappings that mprotect(2) makes executable later are
+// not checked.
+func parseFilelessExec(config map[string]interface{}) (bool, error) {
+	return parseFlag(config, "deny_fileless_exec")
+}
+
+// FilelessExec implements seccheck.Checker.
+func (p *policy) FilelessExec(_ context.Context, _ seccheck.FieldSet, info *pb.FilelessExecInfo) error {
+	if !p.denyFilelessExec {
+		return nil
+	}
+	reason := fmt.Sprintf("execve of %q", info.Path)
+	if info.Mmap {
+		reason = fmt.Sprintf("executable mapping of %q", info.Path)
+	}
+	return p.violation(p.enforcement, "deny_fileless_exec", reason, seccheck.ErrDenied)
+}
+
+// parseDrift returns whether config denies drift, configured as:
+//
+//	deny_drift: true to deny the execution of binaries that were created or
+//	       changed after their container started, so that containers can
+//	       only run the binaries of their image.
+//
+// Executions are checked at the sentry/drift point, see the exec-policy
+// preset.
+func parseDrift(config map[string]interface{}) (bool, error) {
+	return parseFlag(config, "deny_drift")
+}
+
+// Drift implements seccheck.Checker.
+func (p *policy) Drift(_ context.Context, _ seccheck.FieldSet, info *pb.DriftInfo) error {
+	if !p.denyDrift {
+		return nil
+	}
+	reason := fmt.Sprintf("execve of %q changed after the container started", info.BinaryPath)
+	return p.violation(p.enforcement, "deny_drift", reason, seccheck.ErrDenied)
+}
@@ -0,0 +1,189 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestExecAllowlistConfig(t *testing.T) {
+	digest := sha256.Sum256([]byte("binary"))
+	for _, tc := range []struct {
+		name      string
+		allowlist interface{}
+		err       string
+	}{
+		{
+			name:      "valid",
+			allowlist: map[string]interface{}{"paths": []interface{}{"/usr/bin"}, "sha256": []interface{}{strings.ToUpper(hex.EncodeToString(digest[:]))}},
+		},
+		{
+			name:      "empty",
+			allowlist: map[string]interface{}{},
+		},
+		{
+			name:      "object",
+			allowlist: []interface{}{"/usr/bin"},
+			err:       "not an object",
+		},
+		{
+			name:      "relative",
+			allowlist: map[string]interface{}{"paths": []interface{}{"bin"}},
+			err:       "not an absolute path",
+		},
+		{
+			name:      "digest",
+			allowlist: map[string]interface{}{"sha256": []interface{}{"abcd"}},
+			err:       "not a SHA-256 digest",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validate(map[string]interface{}{"exec_allowlist": tc.allowlist})
+			if len(tc.err) == 0 {
+				if err != nil {
+					t.Errorf("validate(): %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("validate(): got: %v, want: %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestExecAllowlist(t *testing.T) {
+	allowed := sha256.Sum256([]byte("allowed"))
+	other := sha256.Sum256([]byte("other"))
+	c, err := new(map[string]interface{}{
+		"exec_allowlist": map[string]interface{}{
+			"paths":  []interface{}{"/usr/bin", "/app/server"},
+			"sha256": []interface{}{hex.EncodeToString(allowed[:])},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	for _, tc := range []struct {
+		name   string
+		info   *pb.ExecveInfo
+		denied bool
+	}{
+		{
+			name: "dir",
+			info: &pb.ExecveInfo{BinaryPath: "/usr/bin/ls"},
+		},
+		{
+			name: "file",
+			info: &pb.ExecveInfo{BinaryPath: "/app/server"},
+		},
+		{
+			name:   "sibling",
+			info:   &pb.ExecveInfo{BinaryPath: "/app/server2"},
+			denied: true,
+		},
+		{
+			name:   "tmp",
+			info:   &pb.ExecveInfo{BinaryPath: "/tmp/payload"},
+			denied: true,
+		},
+		{
+			name:   "deleted",
+			info:   &pb.ExecveInfo{BinaryPath: "/app/server (deleted)"},
+			denied: true,
+		},
+		{
+			name:   "deleted-in-dir",
+			info:   &pb.ExecveInfo{BinaryPath: "/usr/bin/ls (deleted)"},
+			denied: true,
+		},
+		{
+			name: "digest",
+			info: &pb.ExecveInfo{BinaryPath: "/tmp/tool", BinarySha256: allowed[:]},
+		},
+		{
+			name:   "other-digest",
+			info:   &pb.ExecveInfo{BinaryPath: "/tmp/tool", BinarySha256: other[:]},
+			denied: true,
+		},
+		{
+			name:   "unknown",
+			info:   &pb.ExecveInfo{},
+			denied: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := c.Execve(nil, seccheck.FieldSet{}, tc.info)
+			if got := seccheck.IsDenied(err); got != tc.denied {
+				t.Errorf("Execve(%v): got: %v, want denied: %t", tc.info, err, tc.denied)
+			}
+		})
+	}
+}
+
+func TestFilelessExec(t *testing.T) {
+	info := &pb.FilelessExecInfo{Path: "/memfd:payload (deleted)"}
+	mmap := &pb.FilelessExecInfo{Path: "/memfd:payload (deleted)", Mmap: true}
+
+	allow, err := new(map[string]interface{}{"exec_allowlist": map[string]interface{}{}}, nil)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	if err := allow.FilelessExec(nil, seccheck.FieldSet{}, info); err != nil {
+		t.Errorf("FilelessExec(%v) without deny_fileless_exec: %v", info, err)
+	}
+
+	deny, err := new(map[string]interface{}{"deny_fileless_exec": true}, nil)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	for _, info := range []*pb.FilelessExecInfo{info, mmap} {
+		if err := deny.FilelessExec(nil, seccheck.FieldSet{}, info); !seccheck.IsDenied(err) {
+			t.Errorf("FilelessExec(%v): got: %v, want denied", info, err)
+		}
+	}
+
+	if err := validate(map[string]interface{}{"deny_fileless_exec": "yes"}); err == nil || !strings.Contains(err.Error(), "not a boolean") {
+		t.Errorf("validate(): got: %v, want: %q", err, "not a boolean")
+	}
+}
+
+func TestDrift(t *testing.T) {
+	info := &pb.DriftInfo{BinaryPath: "/tmp/miner", ChangeTimeNs: 2, ContainerStartTimeNs: 1}
+
+	allow, err := new(map[string]interface{}{"deny_fileless_exec": true}, nil)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	if err := allow.Drift(nil, seccheck.FieldSet{}, info); err != nil {
+		t.Errorf("Drift(%v) without deny_drift: %v", info, err)
+	}
+
+	deny, err := new(map[string]interface{}{"deny_drift": true}, nil)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	if err := deny.Drift(nil, seccheck.FieldSet{}, info); !seccheck.IsDenied(err) {
+		t.Errorf("Drift(%v): got: %v, want denied", info, err)
+	}
+
+	if err := validate(map[string]interface{}{"deny_drift": 1}); err == nil || !strings.Contains(err.Error(), "not a boolean") {
+		t.Errorf("validate(): got: %v, want: %q", err, "not a boolean")
+	}
+}
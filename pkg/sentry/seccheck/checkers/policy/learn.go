@@ -0,0 +1,350 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"runtime"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const learnName = "learn"
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     learnName,
+		Setup:    learnSetup,
+		New:      newLearn,
+		Validate: validateLearn,
+	})
+}
+
+const (
+	// maxLearnedPaths is the maximum number of paths recorded, to bound the
+	// memory used by workloads that operate on many files.
+	maxLearnedPaths = 10000
+
+	// maxLearnedDestinations is the maximum number of destinations recorded.
+	maxLearnedDestinations = 1000
+)
+
+// seccompArchs maps GOARCH to the architecture of seccomp profiles.
+var seccompArchs = map[string]string{
+	"amd64": "SCMP_ARCH_X86_64",
+	"arm64": "SCMP_ARCH_AARCH64",
+}
+
+// destination is an IP destination of the workload.
+type destination struct {
+	ip   string
+	port uint16
+}
+
+// learn records the syscalls, paths and destinations that the workload uses
+// during a profiling window, and then writes a profile that allows them,
+// configured as:
+//
+//	output: path of the file that the profile is written to, which is
+//	        created outside the sandbox.
+//	duration: length of the profiling window, e.g. "10m". By default, the
+//	          window ends when the session is deleted.
+//
+// The profile is a JSON object with a seccomp profile, in the format used by
+// container runtimes, under "seccomp", and the configuration of a policy sink
+// under "policy". The policy only allows the destinations that were used and
+// the directories of the paths that were used, read-only unless a path in them
+// was modified. Syscalls are recorded from raw syscall enter points, and paths
+// and destinations from the points checked by the policy sink, see the learn
+// preset. Profiles should be reviewed before being enforced, since workloads
+// may not exercise all their code paths during the window.
+type learn struct {
+	seccheck.CheckerDefaults
+
+	endpoint *fd.FD
+
+	mu sync.Mutex
+	// done is set once the profile has been written.
+	// +checklocks:mu
+	done bool
+	// syscalls are the names of the syscalls used.
+	// +checklocks:mu
+	syscalls map[string]struct{}
+	// paths maps paths used to whether they were modified.
+	// +checklocks:mu
+	paths map[string]bool
+	// +checklocks:mu
+	destinations map[destination]struct{}
+	// +checklocks:mu
+	timer *time.Timer
+
+	// dropped is the number of paths and destinations that weren't recorded
+	// because of maxLearnedPaths and maxLearnedDestinations.
+	dropped atomicbitops.Uint64
+}
+
+var _ seccheck.Checker = (*learn)(nil)
+
+func validateLearn(config map[string]interface{}) error {
+	if _, ok := config["output"].(string); !ok {
+		return fmt.Errorf("output %v is not a path", config["output"])
+	}
+	_, err := parseDuration(config)
+	return err
+}
+
+// parseDuration returns the length of the profiling window in config, or 0 if
+// it's not set.
+func parseDuration(config map[string]interface{}) (time.Duration, error) {
+	opaque, ok := config["duration"]
+	if !ok {
+		return 0, nil
+	}
+	str, ok := opaque.(string)
+	if !ok {
+		return 0, fmt.Errorf("duration %v is not a string", opaque)
+	}
+	duration, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", str, err)
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("duration %q must be positive", str)
+	}
+	return duration, nil
+}
+
+func learnSetup(config map[string]interface{}) (*os.File, error) {
+	if err := validateLearn(config); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(config["output"].(string), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+func newLearn(config map[string]interface{}, endpoint *fd.FD) (seccheck.Checker, error) {
+	if endpoint == nil || endpoint.FD() < 0 {
+		return nil, fmt.Errorf("learn sink requires an output file")
+	}
+	duration, err := parseDuration(config)
+	if err != nil {
+		return nil, err
+	}
+	l := &learn{
+		endpoint:     endpoint,
+		syscalls:     make(map[string]struct{}),
+		paths:        make(map[string]bool),
+		destinations: make(map[destination]struct{}),
+	}
+	if duration > 0 {
+		l.mu.Lock()
+		l.timer = time.AfterFunc(duration, l.finish)
+		l.mu.Unlock()
+	}
+	return l, nil
+}
+
+// Name implements seccheck.Checker.
+func (*learn) Name() string {
+	return learnName
+}
+
+// Status implements seccheck.Checker.
+func (l *learn) Status() seccheck.CheckerStatus {
+	return seccheck.CheckerStatus{
+		Metrics: []seccheck.MetricSample{
+			{
+				Family: "runsc_trace_learn_dropped_total",
+				Type:   "counter",
+				Name:   "runsc_trace_learn_dropped_total",
+				Value:  l.dropped.Load(),
+			},
+		},
+	}
+}
+
+// Stop implements seccheck.Checker.
+func (l *learn) Stop() {
+	l.mu.Lock()
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	l.mu.Unlock()
+	l.finish()
+}
+
+// RawSyscall implements seccheck.Checker.
+func (l *learn) RawSyscall(ctx context.Context, _ seccheck.FieldSet, info *pb.Syscall) error {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		return nil
+	}
+	name := t.SyscallTable().LookupName(uintptr(info.Sysno))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.done {
+		l.syscalls[name] = struct{}{}
+	}
+	return nil
+}
+
+// Syscall implements seccheck.Checker.
+func (l *learn) Syscall(_ context.Context, _ seccheck.FieldSet, ctxData *pb.ContextData, _ pb.MessageType, msg proto.Message) error {
+	o, ok := opOf(msg)
+	if !ok {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done {
+		return nil
+	}
+	for _, a := range o.paths {
+		if a.pathname == "" {
+			continue
+		}
+		resolved, ok := a.resolve(ctxData.GetCwd())
+		if !ok {
+			continue
+		}
+		if _, ok := l.paths[resolved]; !ok && len(l.paths) >= maxLearnedPaths {
+			l.dropped.Add(1)
+			continue
+		}
+		l.paths[resolved] = l.paths[resolved] || o.write
+	}
+	if ip, port, ok := parseAddress(o.addr); ok {
+		dest := destination{ip: ip.String(), port: port}
+		if _, ok := l.destinations[dest]; !ok && len(l.destinations) >= maxLearnedDestinations {
+			l.dropped.Add(1)
+		} else {
+			l.destinations[dest] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// finish ends the profiling window and writes the profile, unless it has
+// already been written.
+func (l *learn) finish() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done {
+		return
+	}
+	l.done = true
+
+	data, err := json.MarshalIndent(l.profileLocked(), "", "  ")
+	if err != nil {
+		log.Warningf("Learn sink failed to marshal profile: %v", err)
+		return
+	}
+	if _, err := l.endpoint.Write(append(data, '\n')); err != nil {
+		log.Warningf("Learn sink failed to write profile: %v", err)
+	}
+	_ = l.endpoint.Close()
+}
+
+// profileLocked returns the profile generated from what was recorded.
+//
+// +checklocks:mu
+func (l *learn) profileLocked() map[string]interface{} {
+	syscalls := make([]string, 0, len(l.syscalls))
+	for name := range l.syscalls {
+		syscalls = append(syscalls, name)
+	}
+	sort.Strings(syscalls)
+
+	// Allow the directories of the paths used, which are often read or
+	// written to under different names, e.g. temporary files.
+	dirs := map[string]string{"/": accessDeny}
+	for pathname, write := range l.paths {
+		dir := path.Dir(pathname)
+		switch {
+		case write:
+			dirs[dir] = accessAllow
+		case dirs[dir] != accessAllow:
+			dirs[dir] = accessReadOnly
+		}
+	}
+	prefixes := make([]string, 0, len(dirs))
+	for prefix := range dirs {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	rules := make([]interface{}, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		rules = append(rules, map[string]interface{}{"prefix": prefix, "access": dirs[prefix]})
+	}
+
+	dests := make([]destination, 0, len(l.destinations))
+	for dest := range l.destinations {
+		dests = append(dests, dest)
+	}
+	sort.Slice(dests, func(i, j int) bool {
+		if dests[i].ip != dests[j].ip {
+			return dests[i].ip < dests[j].ip
+		}
+		return dests[i].port < dests[j].port
+	})
+	egressRules := make([]interface{}, 0, len(dests))
+	for _, dest := range dests {
+		bits := 128
+		if net.ParseIP(dest.ip).To4() != nil {
+			bits = 32
+		}
+		egressRules = append(egressRules, map[string]interface{}{
+			"cidr":   fmt.Sprintf("%s/%d", dest.ip, bits),
+			"port":   dest.port,
+			"access": accessAllow,
+		})
+	}
+
+	return map[string]interface{}{
+		"seccomp": map[string]interface{}{
+			"defaultAction": "SCMP_ACT_ERRNO",
+			"architectures": []string{seccompArchs[runtime.GOARCH]},
+			"syscalls": []interface{}{
+				map[string]interface{}{
+					"names":  syscalls,
+					"action": "SCMP_ACT_ALLOW",
+				},
+			},
+		},
+		"policy": map[string]interface{}{
+			"name": name,
+			"config": map[string]interface{}{
+				"rules":          rules,
+				"egress_default": accessDeny,
+				"egress_rules":   egressRules,
+			},
+		},
+	}
+}
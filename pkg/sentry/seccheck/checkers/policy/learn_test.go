@@ -0,0 +1,160 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestLearnConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config map[string]interface{}
+		err    string
+	}{
+		{
+			name:   "valid",
+			config: map[string]interface{}{"output": "/tmp/profile.json", "duration": "10m"},
+		},
+		{
+			name:   "no-duration",
+			config: map[string]interface{}{"output": "/tmp/profile.json"},
+		},
+		{
+			name:   "no-output",
+			config: map[string]interface{}{},
+			err:    "not a path",
+		},
+		{
+			name:   "bad-duration",
+			config: map[string]interface{}{"output": "/tmp/profile.json", "duration": "forever"},
+			err:    "invalid duration",
+		},
+		{
+			name:   "negative-duration",
+			config: map[string]interface{}{"output": "/tmp/profile.json", "duration": "-1s"},
+			err:    "must be positive",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateLearn(tc.config)
+			if len(tc.err) == 0 {
+				if err != nil {
+					t.Errorf("validateLearn(): %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("validateLearn(): got: %v, want: %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestLearn(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "profile.json")
+	config := map[string]interface{}{"output": output}
+	f, err := learnSetup(config)
+	if err != nil {
+		t.Fatalf("learnSetup(): %v", err)
+	}
+	endpoint, err := fd.NewFromFile(f)
+	if err != nil {
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = f.Close()
+	c, err := newLearn(config, endpoint)
+	if err != nil {
+		t.Fatalf("newLearn(): %v", err)
+	}
+
+	cwd := &pb.ContextData{Cwd: "/app"}
+	for _, msg := range []proto.Message{
+		&pb.Open{Fd: linux.AT_FDCWD, Pathname: "/etc/hosts", Flags: linux.O_RDONLY},
+		&pb.Open{Fd: linux.AT_FDCWD, Pathname: "data/db", Flags: linux.O_RDWR},
+		&pb.Unlink{Fd: linux.AT_FDCWD, Pathname: "/tmp/lock"},
+		&pb.Open{Fd: linux.AT_FDCWD, Pathname: "/ignored", Exit: &pb.Exit{}},
+		&pb.Connect{Address: sockaddr("10.0.0.1", 443)},
+		&pb.Sendto{Address: sockaddr("fd00::53", 53)},
+	} {
+		if err := c.Syscall(nil, seccheck.FieldSet{}, cwd, pb.MessageType_MESSAGE_UNKNOWN, msg); err != nil {
+			t.Fatalf("Syscall(%v): %v", msg, err)
+		}
+	}
+	c.Stop()
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("ReadFile(): %v", err)
+	}
+	var profile struct {
+		Policy seccheck.SinkConfig `json:"policy"`
+	}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		t.Fatalf("Unmarshal(%q): %v", data, err)
+	}
+	if profile.Policy.Name != name {
+		t.Errorf("wrong policy sink, got: %q, want: %q", profile.Policy.Name, name)
+	}
+	if err := validate(profile.Policy.Config); err != nil {
+		t.Fatalf("generated policy is invalid: %v", err)
+	}
+	wantRules := []interface{}{
+		map[string]interface{}{"prefix": "/", "access": "deny"},
+		map[string]interface{}{"prefix": "/app/data", "access": "allow"},
+		map[string]interface{}{"prefix": "/etc", "access": "read-only"},
+		map[string]interface{}{"prefix": "/tmp", "access": "allow"},
+	}
+	if got := profile.Policy.Config["rules"]; !reflect.DeepEqual(got, wantRules) {
+		t.Errorf("wrong rules, got: %v, want: %v", got, wantRules)
+	}
+	wantEgress := []interface{}{
+		map[string]interface{}{"cidr": "10.0.0.1/32", "port": float64(443), "access": "allow"},
+		map[string]interface{}{"cidr": "fd00::53/128", "port": float64(53), "access": "allow"},
+	}
+	if got := profile.Policy.Config["egress_rules"]; !reflect.DeepEqual(got, wantEgress) {
+		t.Errorf("wrong egress rules, got: %v, want: %v", got, wantEgress)
+	}
+
+	// The policy allows what was recorded and denies the rest.
+	p, err := parse(profile.Policy.Config)
+	if err != nil {
+		t.Fatalf("parse(): %v", err)
+	}
+	for _, tc := range []struct {
+		msg    proto.Message
+		denied bool
+	}{
+		{msg: &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/etc/resolv.conf", Flags: linux.O_RDONLY}},
+		{msg: &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/etc/passwd", Flags: linux.O_WRONLY}, denied: true},
+		{msg: &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/root/.ssh/id_rsa", Flags: linux.O_RDONLY}, denied: true},
+		{msg: &pb.Connect{Address: sockaddr("10.0.0.1", 443)}},
+		{msg: &pb.Connect{Address: sockaddr("10.0.0.2", 443)}, denied: true},
+	} {
+		err := p.Syscall(nil, seccheck.FieldSet{}, cwd, pb.MessageType_MESSAGE_UNKNOWN, tc.msg)
+		if denied := err != nil; denied != tc.denied {
+			t.Errorf("Syscall(%v): got: %v, want denied: %t", tc.msg, err, tc.denied)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// memAccessRule is the rule reported for cross-process memory access.
+const memAccessRule = "deny_cross_process_memory"
+
+// parseMemAccess returns whether config denies cross-process memory access,
+// configured as:
+//
+//	deny_cross_process_memory: true to deny tasks access to the memory of
+//	       other thread groups in the sandbox, e.g. to scrape credentials from
+//	       or inject code into other processes of the container.
+//
+// Access is checked at the ptrace and open syscall points, see the
+// process-memory-policy preset:
+//   - ptrace(2) is denied for all requests, since every request either starts
+//     tracing another thread group or operates on a tracee, which Linux
+//     requires to be in another thread group. This includes PTRACE_TRACEME,
+//     which grants the parent access to the caller.
+//   - /proc/[pid]/mem and /proc/[pid]/task/[tid]/mem can't be opened for
+//     tasks of other thread groups. IDs are looked up in the PID namespace of
+//     the caller, and files reached through symlinks aren't checked, like
+//     other path rules.
+//
+// process_vm_readv(2) and process_vm_writev(2) are not implemented by the
+// sentry, so they always fail.
+func parseMemAccess(config map[string]interface{}) (bool, error) {
+	return parseFlag(config, "deny_cross_process_memory")
+}
+
+// procMemID returns the task directory of pathname in /proc if it's the mem
+// file of a task, or false if it isn't. Tasks in [pid]/task/[tid] are always
+// in the thread group of [pid].
+func procMemID(pathname string) (string, bool) {
+	parts := strings.Split(strings.TrimPrefix(pathname, "/"), "/")
+	if parts[0] != "proc" || parts[len(parts)-1] != "mem" {
+		return "", false
+	}
+	if len(parts) == 3 || (len(parts) == 5 && parts[2] == "task") {
+		return parts[1], true
+	}
+	return "", false
+}
+
+// sameThreadGroup returns true if the task directory id in /proc belongs to
+// the thread group of t, which is nil if it's unknown.
+func sameThreadGroup(t *kernel.Task, id string) bool {
+	if id == "self" || id == "thread-self" {
+		return true
+	}
+	tid, err := strconv.ParseInt(id, 10, 32)
+	if err != nil {
+		// Not a task directory, the syscall fails on its own.
+		return true
+	}
+	if t == nil {
+		return false
+	}
+	target := t.PIDNamespace().TaskWithID(kernel.ThreadID(tid))
+	return target != nil && target.ThreadGroup() == t.ThreadGroup()
+}
+
+// checkMemAccess returns an error that denies the syscall point msg if it
+// accesses the memory of another thread group than the task in ctx.
+func (p *policy) checkMemAccess(ctx context.Context, ctxData *pb.ContextData, msg proto.Message) error {
+	if !p.denyMemAccess {
+		return nil
+	}
+	switch m := msg.(type) {
+	case *pb.Ptrace:
+		if m.Exit != nil {
+			return nil
+		}
+		reason := fmt.Sprintf("ptrace request %d on pid %d", m.Request, m.Pid)
+		return p.violation(p.enforcement, memAccessRule, reason, seccheck.ErrDenied)
+	case *pb.Open:
+		if m.Exit != nil {
+			return nil
+		}
+		a := pathArg{fd: m.Fd, fdPath: m.FdPath, pathname: m.Pathname}
+		resolved, ok := a.resolve(ctxData.GetCwd())
+		if !ok {
+			if path.Base(a.pathname) != "mem" {
+				return nil
+			}
+			// The file may be in any task directory.
+			reason := fmt.Sprintf("syscall %d on %q", m.Sysno, a.pathname)
+			return p.violation(p.enforcement, memAccessRule, reason, seccheck.ErrDenied)
+		}
+		id, ok := procMemID(resolved)
+		if !ok || sameThreadGroup(kernel.TaskFromContext(ctx), id) {
+			return nil
+		}
+		reason := fmt.Sprintf("syscall %d on %q", m.Sysno, resolved)
+		return p.violation(p.enforcement, memAccessRule, reason, seccheck.ErrDenied)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestMemAccessConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		value interface{}
+		err   string
+	}{
+		{
+			name:  "enabled",
+			value: true,
+		},
+		{
+			name:  "disabled",
+			value: false,
+			err:   "no rules",
+		},
+		{
+			name:  "string",
+			value: "true",
+			err:   "not a boolean",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validate(map[string]interface{}{"deny_cross_process_memory": tc.value})
+			if len(tc.err) == 0 {
+				if err != nil {
+					t.Errorf("validate(): %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("validate(): got: %v, want: %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestMemAccess(t *testing.T) {
+	c, err := new(map[string]interface{}{"deny_cross_process_memory": true}, nil)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	// Without a task in the context, task IDs can't be looked up and are
+	// assumed to be in other thread groups.
+	for _, tc := range []struct {
+		name    string
+		ctxData *pb.ContextData
+		msg     proto.Message
+		denied  bool
+	}{
+		{
+			name:   "ptrace-attach",
+			msg:    &pb.Ptrace{Request: linux.PTRACE_ATTACH, Pid: 1},
+			denied: true,
+		},
+		{
+			name:   "ptrace-traceme",
+			msg:    &pb.Ptrace{Request: linux.PTRACE_TRACEME},
+			denied: true,
+		},
+		{
+			name: "ptrace-exit",
+			msg:  &pb.Ptrace{Request: linux.PTRACE_ATTACH, Pid: 1, Exit: &pb.Exit{}},
+		},
+		{
+			name: "self",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/proc/self/mem", Flags: linux.O_RDWR},
+		},
+		{
+			name: "thread-self",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/proc/thread-self/mem"},
+		},
+		{
+			name: "self-task",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/proc/self/task/2/mem"},
+		},
+		{
+			name:   "pid",
+			msg:    &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/proc/1/mem"},
+			denied: true,
+		},
+		{
+			name:   "task",
+			msg:    &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/proc/self/../1/task/1/mem"},
+			denied: true,
+		},
+		{
+			name:    "cwd",
+			ctxData: &pb.ContextData{Cwd: "/proc/1"},
+			msg:     &pb.Open{Fd: linux.AT_FDCWD, Pathname: "mem"},
+			denied:  true,
+		},
+		{
+			name:   "fd",
+			msg:    &pb.Open{Fd: 3, FdPath: "/proc", Pathname: "1/mem"},
+			denied: true,
+		},
+		{
+			name:   "unresolved",
+			msg:    &pb.Open{Fd: 3, Pathname: "mem"},
+			denied: true,
+		},
+		{
+			name: "maps",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/proc/1/maps"},
+		},
+		{
+			name: "other",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/tmp/1/mem"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := c.Syscall(context.Background(), seccheck.FieldSet{}, tc.ctxData, pb.MessageType_MESSAGE_UNKNOWN, tc.msg)
+			if got := seccheck.IsDenied(err); got != tc.denied {
+				t.Errorf("Syscall(%v): got: %v, want denied: %t", tc.msg, err, tc.denied)
+			}
+		})
+	}
+}
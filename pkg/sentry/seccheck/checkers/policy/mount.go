@@ -0,0 +1,218 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// Operations checked by mount rules, see parseMountRules.
+const (
+	mountOpMount     = "mount"
+	mountOpUmount    = "umount"
+	mountOpPivotRoot = "pivot_root"
+	mountOpChroot    = "chroot"
+)
+
+// mountRule sets the access to an operation on the mount table.
+type mountRule struct {
+	op string
+	// fstype is the filesystem type of mounts that the rule applies to, or
+	// empty for all types.
+	fstype string
+	// target contains the targets that the rule applies to.
+	target rule
+	access string
+	// enforcement applies when the rule denies an operation.
+	enforcement enforcement
+}
+
+// String returns a description of the rule.
+func (r *mountRule) String() string {
+	op := r.op
+	if len(r.fstype) > 0 {
+		op += " " + r.fstype
+	}
+	return fmt.Sprintf("%s %s on %s", r.access, op, r.target.prefix)
+}
+
+// applies returns true if r applies to op of fstype on target.
+func (r *mountRule) applies(op, fstype, target string) bool {
+	return r.op == op && (len(r.fstype) == 0 || r.fstype == fstype) && r.target.contains(target)
+}
+
+// parseMountRules returns the rules that restrict changes to the mount table
+// in config, configured as:
+//
+//	mount_rules: list of {"op": "mount", "fstype": "tmpfs", "target": "/tmp",
+//	             "access": "allow"}, where op is "mount" (default), "umount",
+//	             "pivot_root" or "chroot", fstype only applies to mounts,
+//	             target is a path prefix, "/" by default, and access is
+//	             "allow" or "deny". Like path rules, they may set "action" and
+//	             "mode".
+//
+// For example, [{"access": "deny"}] denies all new mounts, and adding
+// {"fstype": "tmpfs", "access": "allow"} only allows tmpfs mounts.
+//
+// Of the rules for an operation, the rule with the longest target that
+// contains the path operated on applies, and rules with a fstype take
+// precedence over rules without one. Operations that no rule applies to are
+// allowed. Paths are checked when mount(2), umount2(2), pivot_root(2) and
+// chroot(2) are entered, using the new root for pivot_root(2), and are made
+// absolute like the paths of path rules, see policy.
+func parseMountRules(config map[string]interface{}, def enforcement) ([]mountRule, error) {
+	opaque, ok := config["mount_rules"]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := opaque.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("mount_rules %v is not a list", opaque)
+	}
+	rules := make([]mountRule, 0, len(list))
+	seen := make(map[string]struct{}, len(list))
+	for _, opaque := range list {
+		r, err := parseMountRule(opaque, def)
+		if err != nil {
+			return nil, err
+		}
+		k := fmt.Sprintf("%s %s on %s", r.op, r.fstype, r.target.prefix)
+		if _, ok := seen[k]; ok {
+			return nil, fmt.Errorf("duplicate mount rule for %s", r.String())
+		}
+		seen[k] = struct{}{}
+		rules = append(rules, r)
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		li, lj := len(rules[i].target.prefix), len(rules[j].target.prefix)
+		if li != lj {
+			return li > lj
+		}
+		return len(rules[i].fstype) > 0 && len(rules[j].fstype) == 0
+	})
+	return rules, nil
+}
+
+func parseMountRule(opaque interface{}, def enforcement) (mountRule, error) {
+	obj, ok := opaque.(map[string]interface{})
+	if !ok {
+		return mountRule{}, fmt.Errorf("mount rule %v is not an object", opaque)
+	}
+	r := mountRule{op: mountOpMount, target: rule{prefix: "/"}}
+	if opaque, ok := obj["op"]; ok {
+		r.op, _ = opaque.(string)
+		switch r.op {
+		case mountOpMount, mountOpUmount, mountOpPivotRoot, mountOpChroot:
+		default:
+			return mountRule{}, fmt.Errorf("invalid mount rule op %v, must be %q, %q, %q or %q", opaque, mountOpMount, mountOpUmount, mountOpPivotRoot, mountOpChroot)
+		}
+	}
+	if opaque, ok := obj["fstype"]; ok {
+		r.fstype, _ = opaque.(string)
+		if len(r.fstype) == 0 || r.op != mountOpMount {
+			return mountRule{}, fmt.Errorf("mount rule fstype %v is invalid for op %q", opaque, r.op)
+		}
+	}
+	if opaque, ok := obj["target"]; ok {
+		target, ok := opaque.(string)
+		if !ok || !path.IsAbs(target) {
+			return mountRule{}, fmt.Errorf("mount rule target %v is not an absolute path", opaque)
+		}
+		r.target.prefix = path.Clean(target)
+	}
+	r.access, _ = obj["access"].(string)
+	if r.access != accessAllow && r.access != accessDeny {
+		return mountRule{}, fmt.Errorf("invalid access %v for mount rule, must be %q or %q", obj["access"], accessAllow, accessDeny)
+	}
+	var err error
+	if r.enforcement, err = parseEnforcement(obj, def); err != nil {
+		return mountRule{}, fmt.Errorf("mount rule %s: %w", r.String(), err)
+	}
+	return r, nil
+}
+
+// mountOp is an operation on the mount table.
+type mountOp struct {
+	sysno uint64
+	op    string
+	// fstype is the filesystem type of mounts.
+	fstype string
+	target string
+}
+
+// mountOpOf returns the operation on the mount table of the syscall point msg,
+// or false if it isn't one.
+func mountOpOf(msg proto.Message) (mountOp, bool) {
+	switch m := msg.(type) {
+	case *pb.Mount:
+		if m.Exit != nil {
+			return mountOp{}, false
+		}
+		return mountOp{sysno: m.Sysno, op: mountOpMount, fstype: m.Fstype, target: m.Target}, true
+	case *pb.Umount:
+		if m.Exit != nil {
+			return mountOp{}, false
+		}
+		return mountOp{sysno: m.Sysno, op: mountOpUmount, target: m.Target}, true
+	case *pb.PivotRoot:
+		if m.Exit != nil {
+			return mountOp{}, false
+		}
+		return mountOp{sysno: m.Sysno, op: mountOpPivotRoot, target: m.NewRoot}, true
+	case *pb.Chroot:
+		if m.Exit != nil {
+			return mountOp{}, false
+		}
+		return mountOp{sysno: m.Sysno, op: mountOpChroot, target: m.Pathname}, true
+	}
+	return mountOp{}, false
+}
+
+// checkMount returns an error that denies the syscall point msg if mount
+// rules deny it.
+func (p *policy) checkMount(ctxData *pb.ContextData, msg proto.Message) error {
+	if len(p.mountRules) == 0 {
+		return nil
+	}
+	o, ok := mountOpOf(msg)
+	if !ok || len(o.target) == 0 {
+		// Not checked, or the syscall fails on its own.
+		return nil
+	}
+	a := pathArg{fd: linux.AT_FDCWD, pathname: o.target}
+	resolved, ok := a.resolve(ctxData.GetCwd())
+	if !ok {
+		return p.deny(o.sysno, o.target, nil)
+	}
+	for i := range p.mountRules {
+		r := &p.mountRules[i]
+		if !r.applies(o.op, o.fstype, resolved) {
+			continue
+		}
+		if r.access == accessDeny {
+			reason := fmt.Sprintf("syscall %d on %q", o.sysno, resolved)
+			return p.violation(r.enforcement, r.String(), reason, seccheck.ErrDenied)
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestMountConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		rule interface{}
+		err  string
+	}{
+		{
+			name: "deny",
+			rule: map[string]interface{}{"access": "deny"},
+		},
+		{
+			name: "allow",
+			rule: map[string]interface{}{"op": "mount", "fstype": "tmpfs", "target": "/tmp", "access": "allow", "mode": "audit"},
+		},
+		{
+			name: "object",
+			rule: "deny",
+			err:  "not an object",
+		},
+		{
+			name: "op",
+			rule: map[string]interface{}{"op": "remount", "access": "deny"},
+			err:  "invalid mount rule op",
+		},
+		{
+			name: "fstype",
+			rule: map[string]interface{}{"op": "umount", "fstype": "tmpfs", "access": "deny"},
+			err:  "invalid for op",
+		},
+		{
+			name: "target",
+			rule: map[string]interface{}{"target": "mnt", "access": "deny"},
+			err:  "not an absolute path",
+		},
+		{
+			name: "access",
+			rule: map[string]interface{}{"access": "read-only"},
+			err:  "invalid access",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validate(map[string]interface{}{"mount_rules": []interface{}{tc.rule}})
+			if len(tc.err) == 0 {
+				if err != nil {
+					t.Errorf("validate(): %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("validate(): got: %v, want: %q", err, tc.err)
+			}
+		})
+	}
+
+	rule := map[string]interface{}{"target": "/mnt", "access": "deny"}
+	if err := validate(map[string]interface{}{"mount_rules": []interface{}{rule, rule}}); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("validate(): got: %v, want: %q", err, "duplicate")
+	}
+}
+
+func TestMount(t *testing.T) {
+	c, err := new(map[string]interface{}{
+		"mount_rules": []interface{}{
+			map[string]interface{}{"access": "deny"},
+			map[string]interface{}{"fstype": "tmpfs", "access": "allow"},
+			map[string]interface{}{"target": "/mnt/data", "access": "allow"},
+			map[string]interface{}{"op": "chroot", "target": "/", "access": "deny"},
+			map[string]interface{}{"op": "chroot", "target": "/jail", "access": "allow"},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	cwd := &pb.ContextData{Cwd: "/mnt"}
+	for _, tc := range []struct {
+		name    string
+		ctxData *pb.ContextData
+		msg     proto.Message
+		denied  bool
+	}{
+		{
+			name:   "proc",
+			msg:    &pb.Mount{Source: "proc", Target: "/proc2", Fstype: "proc"},
+			denied: true,
+		},
+		{
+			name: "tmpfs",
+			msg:  &pb.Mount{Source: "none", Target: "/tmp", Fstype: "tmpfs"},
+		},
+		{
+			name: "allowed-target",
+			msg:  &pb.Mount{Source: "proc", Target: "/mnt/data/proc", Fstype: "proc"},
+		},
+		{
+			name:    "relative-target",
+			ctxData: cwd,
+			msg:     &pb.Mount{Source: "proc", Target: "data", Fstype: "proc"},
+		},
+		{
+			name:   "unresolved",
+			msg:    &pb.Mount{Source: "proc", Target: "data", Fstype: "proc"},
+			denied: true,
+		},
+		{
+			name: "exit",
+			msg:  &pb.Mount{Source: "proc", Target: "/proc2", Fstype: "proc", Exit: &pb.Exit{}},
+		},
+		{
+			name: "umount",
+			msg:  &pb.Umount{Target: "/proc"},
+		},
+		{
+			name: "pivot-root",
+			msg:  &pb.PivotRoot{NewRoot: "/new", PutOld: "/new/old"},
+		},
+		{
+			name:   "chroot",
+			msg:    &pb.Chroot{Pathname: "/tmp"},
+			denied: true,
+		},
+		{
+			name: "chroot-jail",
+			msg:  &pb.Chroot{Pathname: "/jail/app"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := c.Syscall(nil, seccheck.FieldSet{}, tc.ctxData, pb.MessageType_MESSAGE_UNKNOWN, tc.msg)
+			if got := seccheck.IsDenied(err); got != tc.denied {
+				t.Errorf("Syscall(%v): got: %v, want denied: %t", tc.msg, err, tc.denied)
+			}
+		})
+	}
+}
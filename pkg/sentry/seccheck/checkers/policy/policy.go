@@ -0,0 +1,603 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policy defines a seccheck.Checker that enforces simple rules on the
+// paths that the application operates on, the network destinations that it
+// sends to, the binaries that it executes, the IDs that it switches to, the
+// mounts that it changes, the memory of other processes that it accesses and
+// the memory that it makes writable and executable, for users who want to deny
+// operations without running a remote process that sends verdicts. It also
+// defines a sink that generates policies from the operations that workloads
+// perform, see learn.
+package policy
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const name = "policy"
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     name,
+		New:      new,
+		Validate: validate,
+	})
+}
+
+// Access levels of rules, see policy.
+const (
+	accessAllow    = "allow"
+	accessReadOnly = "read-only"
+	accessDeny     = "deny"
+)
+
+// Actions taken when rules deny operations, see policy.
+const (
+	actionDeny           = "deny"
+	actionKillContainer  = "kill-container"
+	actionKillSandbox    = "kill-sandbox"
+	actionPauseContainer = "pause-container"
+)
+
+// Modes of rules, see policy.
+const (
+	modeEnforce = "enforce"
+	modeAudit   = "audit"
+)
+
+// enforcement is what happens when a rule denies an operation.
+type enforcement struct {
+	action string
+	mode   string
+}
+
+// defaultEnforcement applies to rules unless the configuration overrides it.
+var defaultEnforcement = enforcement{action: actionDeny, mode: modeEnforce}
+
+// parseEnforcement returns the enforcement set in obj, with the settings that
+// aren't present taken from def.
+func parseEnforcement(obj map[string]interface{}, def enforcement) (enforcement, error) {
+	e := def
+	if opaque, ok := obj["action"]; ok {
+		e.action, _ = opaque.(string)
+		switch e.action {
+		case actionDeny, actionKillContainer, actionKillSandbox, actionPauseContainer:
+		default:
+			return enforcement{}, fmt.Errorf("invalid action %v, must be %q, %q, %q or %q", opaque, actionDeny, actionKillContainer, actionKillSandbox, actionPauseContainer)
+		}
+	}
+	if opaque, ok := obj["mode"]; ok {
+		e.mode, _ = opaque.(string)
+		if e.mode != modeEnforce && e.mode != modeAudit {
+			return enforcement{}, fmt.Errorf("invalid mode %v, must be %q or %q", opaque, modeEnforce, modeAudit)
+		}
+	}
+	return e, nil
+}
+
+// rule sets the access to the paths under prefix.
+type rule struct {
+	prefix string
+	access string
+	// enforcement applies when the rule denies an operation.
+	enforcement enforcement
+}
+
+// String returns a description of the rule.
+func (r *rule) String() string {
+	return fmt.Sprintf("%s %s", r.access, r.prefix)
+}
+
+// contains returns true if pathname is prefix or under it.
+func (r *rule) contains(pathname string) bool {
+	return pathname == r.prefix || r.prefix == "/" || strings.HasPrefix(pathname, r.prefix+"/")
+}
+
+// deniedLog logs denied operations, which may happen for every point while a
+// rule blocks a workload.
+var deniedLog = log.BasicRateLimitedLogger(time.Minute)
+
+// policy denies operations on paths according to rules, and connections
+// according to egress, configured as:
+//
+//	rules: list of {"prefix": "/path", "access": "deny"}, where access is:
+//	       - "deny": the path can't be opened, unlinked or renamed.
+//	       - "read-only": the path can only be opened for reading.
+//	       - "allow": the path is exempt from rules with shorter prefixes.
+//	       Rules may set "action" and "mode", which override the defaults.
+//	action: what happens when a rule denies an operation:
+//	       - "deny" (default): the operation fails with EPERM.
+//	       - "kill-container": the operation fails, and the container that
+//	         performed it is killed, see seccheck.Violation.
+//	       - "kill-sandbox": the operation fails, and the whole sandbox is
+//	         killed.
+//	       - "pause-container": the operation fails, and all tasks in the
+//	         container that performed it are paused, preserving their memory
+//	         and files for inspection. See "runsc resume --quarantined".
+//	mode: "enforce" (default) to take the action, or "audit" to allow the
+//	       operation, e.g. to stage new rules before enforcing them.
+//
+// Operations that rules deny are reported to sentry/policy_violation, with the
+// mode of the rule.
+//
+// The rule with the longest prefix that contains a path applies to it, and
+// paths that no rule contains are allowed. Paths are checked as passed by the
+// application, made absolute with the working directory or the directory FD
+// that they are relative to, without following symlinks. So points must
+// include the cwd context field and the fd_path field, see the path-policy
+// preset, and relative paths that can't be resolved are denied. Open points
+// must also include the pathname field, or opens are denied.
+//
+// Points are checked when syscalls are entered: open(2), openat(2), creat(2),
+// unlink(2), unlinkat(2), rmdir(2), rename(2) and renameat(2). Other syscalls
+// that modify files, e.g. truncate(2) or mkdir(2), are not checked. Like
+// other syscall enter points, paths are read before the syscall reads them
+// again, so tasks that change them concurrently can race with the policy.
+//
+// The policy can be replaced while the sandbox runs, see checker.
+//
+// At least one of rules, egress rules, setid rules, mount rules, an exec
+// allowlist, deny_cross_process_memory, deny_fileless_exec, deny_drift or
+// deny_writable_executable must be present, see egress, parseSetidRules,
+// parseMountRules, execAllowlist, parseMemAccess, parseFilelessExec,
+// parseDrift and parseWX.
+type policy struct {
+	seccheck.CheckerDefaults
+
+	// rules are sorted by decreasing prefix length, so that the first rule
+	// that contains a path applies to it.
+	rules []rule
+
+	// enforcement applies to rules that don't override it, and to paths
+	// that can't be resolved.
+	enforcement enforcement
+
+	// egress is nil if connections aren't checked.
+	egress *egress
+
+	// exec is nil if execution isn't checked.
+	exec *execAllowlist
+
+	// denyMemAccess is true if cross-process memory access is denied.
+	denyMemAccess bool
+
+	// denyFilelessExec is true if the execution of memfds is denied.
+	denyFilelessExec bool
+
+	// denyDrift is true if the execution of binaries changed after their
+	// container started is denied.
+	denyDrift bool
+
+	// setidRules restrict the IDs that tasks can switch to.
+	setidRules []setidRule
+
+	// mountRules are sorted by decreasing target length, so that the first
+	// rule that applies to an operation is the one that takes effect.
+	mountRules []mountRule
+
+	// wx is nil if writable and executable mappings aren't checked.
+	wx *wxPolicy
+
+	// stats is shared with the policies that replace this one, see checker.
+	stats *stats
+}
+
+var _ seccheck.Checker = (*policy)(nil)
+
+// stats counts the operations that a policy denied and audited.
+type stats struct {
+	deniedCount  atomicbitops.Uint64
+	auditedCount atomicbitops.Uint64
+
+	mu sync.Mutex
+	// hits maps the rules that denied or audited operations to the number of
+	// operations they did, so that operators can find noisy rules.
+	// +checklocks:mu
+	hits map[string]uint64
+}
+
+// record counts an operation that rule denied, or audited if audit is true.
+func (s *stats) record(rule string, audit bool) {
+	if audit {
+		s.auditedCount.Add(1)
+	} else {
+		s.deniedCount.Add(1)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hits == nil {
+		s.hits = make(map[string]uint64)
+	}
+	s.hits[rule]++
+}
+
+// ruleHits returns samples of the hits of each rule, sorted by rule.
+func (s *stats) ruleHits() []seccheck.MetricSample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples := make([]seccheck.MetricSample, 0, len(s.hits))
+	for rule, hits := range s.hits {
+		samples = append(samples, seccheck.MetricSample{
+			Family: "runsc_trace_policy_rule_hits_total",
+			Type:   "counter",
+			Name:   "runsc_trace_policy_rule_hits_total",
+			Labels: map[string]string{"rule": rule},
+			Value:  hits,
+		})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Labels["rule"] < samples[j].Labels["rule"] })
+	return samples
+}
+
+// parseRules returns the rules in config, sorted by decreasing prefix length.
+// Rules take the settings of def that they don't override.
+func parseRules(config map[string]interface{}, def enforcement) ([]rule, error) {
+	opaque, ok := config["rules"]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := opaque.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("rules %v is not a list", opaque)
+	}
+	rules := make([]rule, 0, len(list))
+	prefixes := make(map[string]struct{}, len(list))
+	for _, opaque := range list {
+		obj, ok := opaque.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("rule %v is not an object", opaque)
+		}
+		prefix, ok := obj["prefix"].(string)
+		if !ok || !path.IsAbs(prefix) {
+			return nil, fmt.Errorf("rule prefix %v is not an absolute path", obj["prefix"])
+		}
+		prefix = path.Clean(prefix)
+		if _, ok := prefixes[prefix]; ok {
+			return nil, fmt.Errorf("duplicate rule for prefix %q", prefix)
+		}
+		prefixes[prefix] = struct{}{}
+		access, _ := obj["access"].(string)
+		switch access {
+		case accessAllow, accessReadOnly, accessDeny:
+		default:
+			return nil, fmt.Errorf("invalid access %v for prefix %q, must be %q, %q or %q", obj["access"], prefix, accessAllow, accessReadOnly, accessDeny)
+		}
+		e, err := parseEnforcement(obj, def)
+		if err != nil {
+			return nil, fmt.Errorf("rule for prefix %q: %w", prefix, err)
+		}
+		rules = append(rules, rule{prefix: prefix, access: access, enforcement: e})
+	}
+	sort.Slice(rules, func(i, j int) bool { return len(rules[i].prefix) > len(rules[j].prefix) })
+	return rules, nil
+}
+
+// parseFlag returns the boolean option name in config, or false if it isn't
+// present.
+func parseFlag(config map[string]interface{}, name string) (bool, error) {
+	opaque, ok := config[name]
+	if !ok {
+		return false, nil
+	}
+	flag, ok := opaque.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s %v is not a boolean", name, opaque)
+	}
+	return flag, nil
+}
+
+// parse returns the policy configured by config.
+func parse(config map[string]interface{}) (*policy, error) {
+	def, err := parseEnforcement(config, defaultEnforcement)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := parseRules(config, def)
+	if err != nil {
+		return nil, err
+	}
+	egress, err := parseEgress(config, def)
+	if err != nil {
+		return nil, err
+	}
+	exec, err := parseExecAllowlist(config)
+	if err != nil {
+		return nil, err
+	}
+	denyMemAccess, err := parseMemAccess(config)
+	if err != nil {
+		return nil, err
+	}
+	denyFilelessExec, err := parseFilelessExec(config)
+	if err != nil {
+		return nil, err
+	}
+	denyDrift, err := parseDrift(config)
+	if err != nil {
+		return nil, err
+	}
+	setidRules, err := parseSetidRules(config, def)
+	if err != nil {
+		return nil, err
+	}
+	mountRules, err := parseMountRules(config, def)
+	if err != nil {
+		return nil, err
+	}
+	wx, err := parseWX(config)
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil && egress == nil && exec == nil && !denyMemAccess && !denyFilelessExec && !denyDrift && setidRules == nil && mountRules == nil && wx == nil {
+		return nil, fmt.Errorf("no rules, egress rules, setid rules, mount rules, exec allowlist or deny options present in configuration")
+	}
+	return &policy{
+		enforcement:      def,
+		rules:            rules,
+		egress:           egress,
+		exec:             exec,
+		denyMemAccess:    denyMemAccess,
+		denyFilelessExec: denyFilelessExec,
+		denyDrift:        denyDrift,
+		setidRules:       setidRules,
+		mountRules:       mountRules,
+		wx:               wx,
+		stats:            &stats{},
+	}, nil
+}
+
+func validate(config map[string]interface{}) error {
+	_, err := parse(config)
+	return err
+}
+
+func new(config map[string]interface{}, _ *fd.FD) (seccheck.Checker, error) {
+	p, err := parse(config)
+	if err != nil {
+		return nil, err
+	}
+	return newChecker(p), nil
+}
+
+// Name implements seccheck.Checker.
+func (*policy) Name() string {
+	return name
+}
+
+// Status implements seccheck.Checker.
+func (p *policy) Status() seccheck.CheckerStatus {
+	status := seccheck.CheckerStatus{
+		Metrics: []seccheck.MetricSample{
+			{
+				Family: "runsc_trace_policy_denied_total",
+				Type:   "counter",
+				Name:   "runsc_trace_policy_denied_total",
+				Value:  p.stats.deniedCount.Load(),
+			},
+			{
+				Family: "runsc_trace_policy_audited_total",
+				Type:   "counter",
+				Name:   "runsc_trace_policy_audited_total",
+				Value:  p.stats.auditedCount.Load(),
+			},
+		},
+	}
+	status.Metrics = append(status.Metrics, p.stats.ruleHits()...)
+	return status
+}
+
+// match returns the rule that applies to pathname, or nil if none does.
+func (p *policy) match(pathname string) *rule {
+	for i := range p.rules {
+		if p.rules[i].contains(pathname) {
+			return &p.rules[i]
+		}
+	}
+	return nil
+}
+
+// pathArg is a path passed to a syscall.
+type pathArg struct {
+	// fd is the directory FD that pathname is relative to, or AT_FDCWD.
+	fd int64
+	// fdPath is the path of fd, if the fd_path field was requested.
+	fdPath   string
+	pathname string
+}
+
+// resolve returns the absolute path of a, or false if it can't be resolved
+// because the directory that it's relative to is unknown.
+func (a pathArg) resolve(cwd string) (string, bool) {
+	if path.IsAbs(a.pathname) {
+		return path.Clean(a.pathname), true
+	}
+	dir := a.fdPath
+	if a.fd == linux.AT_FDCWD {
+		dir = cwd
+	}
+	// fdPath describes errors in brackets, e.g. for bad FDs.
+	if !path.IsAbs(dir) {
+		return "", false
+	}
+	return path.Join(dir, a.pathname), true
+}
+
+// check returns an error that denies the syscall sysno if it can't operate on
+// args, which it modifies if write is true.
+func (p *policy) check(ctxData *pb.ContextData, sysno uint64, write bool, args ...pathArg) error {
+	for _, a := range args {
+		if a.pathname == "" {
+			// The syscall fails on its own.
+			continue
+		}
+		resolved, ok := a.resolve(ctxData.GetCwd())
+		if !ok {
+			return p.deny(sysno, a.pathname, nil)
+		}
+		r := p.match(resolved)
+		if r == nil {
+			continue
+		}
+		switch r.access {
+		case accessDeny:
+			return p.deny(sysno, resolved, r)
+		case accessReadOnly:
+			if write {
+				return p.deny(sysno, resolved, r)
+			}
+		}
+	}
+	return nil
+}
+
+// deny returns the error that denies the syscall sysno on pathname because of
+// r, which is nil if pathname can't be resolved.
+func (p *policy) deny(sysno uint64, pathname string, r *rule) error {
+	reason := fmt.Sprintf("syscall %d on %q", sysno, pathname)
+	if r == nil {
+		// Only the mode applies, since the path may not violate any rule.
+		e := enforcement{action: actionDeny, mode: p.enforcement.mode}
+		return p.violation(e, "unresolved path", reason, seccheck.ErrDenied)
+	}
+	return p.violation(r.enforcement, r.String(), reason, seccheck.ErrDenied)
+}
+
+// violation returns the seccheck.Violation of rule by the operation described
+// by reason, which fails with err unless e is audit-only.
+func (p *policy) violation(e enforcement, rule, reason string, err error) error {
+	v := &seccheck.Violation{
+		Rule:   rule,
+		Reason: reason,
+		Audit:  e.mode == modeAudit,
+		Err:    err,
+	}
+	switch e.action {
+	case actionKillContainer:
+		v.Action = seccheck.ViolationKillContainer
+	case actionKillSandbox:
+		v.Action = seccheck.ViolationKillSandbox
+	case actionPauseContainer:
+		v.Action = seccheck.ViolationPauseContainer
+	}
+	p.stats.record(rule, v.Audit)
+	if v.Audit {
+		deniedLog.Infof("Policy audited %s: %s", reason, rule)
+	} else {
+		deniedLog.Infof("Policy denied %s: %s", reason, rule)
+	}
+	return v
+}
+
+// op is an operation checked by rules.
+type op struct {
+	sysno uint64
+	// paths are the paths that the operation works on, which it modifies if
+	// write is true.
+	paths []pathArg
+	write bool
+	// addr is the sockaddr that the operation sends to, if paths is empty.
+	addr []byte
+}
+
+// opOf returns the operation of the syscall point msg, or false if it isn't
+// checked by rules.
+func opOf(msg proto.Message) (op, bool) {
+	switch m := msg.(type) {
+	case *pb.Open:
+		if m.Exit != nil {
+			return op{}, false
+		}
+		return op{
+			sysno: m.Sysno,
+			paths: []pathArg{{fd: m.Fd, fdPath: m.FdPath, pathname: m.Pathname}},
+			write: m.Flags&linux.O_ACCMODE != linux.O_RDONLY || m.Flags&(linux.O_CREAT|linux.O_TRUNC) != 0,
+		}, true
+	case *pb.Unlink:
+		if m.Exit != nil {
+			return op{}, false
+		}
+		return op{
+			sysno: m.Sysno,
+			paths: []pathArg{{fd: m.Fd, fdPath: m.FdPath, pathname: m.Pathname}},
+			write: true,
+		}, true
+	case *pb.Rename:
+		if m.Exit != nil {
+			return op{}, false
+		}
+		return op{
+			sysno: m.Sysno,
+			paths: []pathArg{
+				{fd: m.OldFd, fdPath: m.OldFdPath, pathname: m.OldPathname},
+				{fd: m.NewFd, fdPath: m.NewFdPath, pathname: m.NewPathname},
+			},
+			write: true,
+		}, true
+	case *pb.Connect:
+		if m.Exit != nil {
+			return op{}, false
+		}
+		return op{sysno: m.Sysno, addr: m.Address}, true
+	case *pb.Sendto:
+		if m.Exit != nil {
+			return op{}, false
+		}
+		return op{sysno: m.Sysno, addr: m.Address}, true
+	}
+	return op{}, false
+}
+
+// Syscall implements seccheck.Checker.
+func (p *policy) Syscall(ctx context.Context, fields seccheck.FieldSet, ctxData *pb.ContextData, _ pb.MessageType, msg proto.Message) error {
+	if err := p.checkMemAccess(ctx, ctxData, msg); err != nil {
+		return err
+	}
+	if err := p.checkSetid(ctxData, msg); err != nil {
+		return err
+	}
+	if err := p.checkMount(ctxData, msg); err != nil {
+		return err
+	}
+	if err := p.checkWX(ctx, ctxData, msg); err != nil {
+		return err
+	}
+	if m, ok := msg.(*pb.Open); ok && m.Exit == nil && m.Pathname == "" && !fields.Local.Contains(seccheck.FieldSyscallOpenPathname) {
+		// The pathname is only collected when requested, so the open can't be
+		// checked without it.
+		return p.deny(m.Sysno, "", nil)
+	}
+	o, ok := opOf(msg)
+	if !ok {
+		return nil
+	}
+	if len(o.paths) > 0 {
+		return p.check(ctxData, o.sysno, o.write, o.paths...)
+	}
+	return p.checkEgress(o.sysno, o.addr)
+}
@@ -0,0 +1,317 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func newPolicy(t *testing.T, rules ...interface{}) *policy {
+	t.Helper()
+	c, err := new(map[string]interface{}{"rules": rules}, nil)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	return c.(*checker).current()
+}
+
+func newRule(prefix, access string) map[string]interface{} {
+	return map[string]interface{}{"prefix": prefix, "access": access}
+}
+
+func TestConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config map[string]interface{}
+		err    string
+	}{
+		{
+			name:   "valid",
+			config: map[string]interface{}{"rules": []interface{}{newRule("/etc", "read-only"), newRule("/etc/app/", "allow")}},
+		},
+		{
+			name:   "empty",
+			config: map[string]interface{}{"rules": []interface{}{}},
+		},
+		{
+			name:   "missing",
+			config: map[string]interface{}{},
+			err:    "no rules",
+		},
+		{
+			name:   "relative",
+			config: map[string]interface{}{"rules": []interface{}{newRule("etc", "deny")}},
+			err:    "not an absolute path",
+		},
+		{
+			name:   "access",
+			config: map[string]interface{}{"rules": []interface{}{newRule("/etc", "write")}},
+			err:    "invalid access",
+		},
+		{
+			name:   "duplicate",
+			config: map[string]interface{}{"rules": []interface{}{newRule("/etc", "deny"), newRule("/etc/", "allow")}},
+			err:    "duplicate rule",
+		},
+		{
+			name:   "action",
+			config: map[string]interface{}{"rules": []interface{}{}, "action": "kill"},
+			err:    "invalid action",
+		},
+		{
+			name:   "mode",
+			config: map[string]interface{}{"rules": []interface{}{}, "mode": "log"},
+			err:    "invalid mode",
+		},
+		{
+			name:   "rule-action",
+			config: map[string]interface{}{"rules": []interface{}{map[string]interface{}{"prefix": "/etc", "access": "deny", "action": "kill"}}},
+			err:    "invalid action",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validate(tc.config)
+			if len(tc.err) == 0 {
+				if err != nil {
+					t.Errorf("validate(): %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("validate(): got: %v, want: %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestSyscall(t *testing.T) {
+	p := newPolicy(t,
+		newRule("/etc", "read-only"),
+		newRule("/etc/shadow", "deny"),
+		newRule("/etc/app", "allow"),
+		newRule("/secret", "deny"),
+	)
+	cwd := &pb.ContextData{Cwd: "/etc"}
+	for _, tc := range []struct {
+		name    string
+		ctxData *pb.ContextData
+		msg     proto.Message
+		denied  bool
+	}{
+		{
+			name: "read",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/etc/hosts", Flags: linux.O_RDONLY},
+		},
+		{
+			name:   "write",
+			msg:    &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/etc/hosts", Flags: linux.O_RDWR},
+			denied: true,
+		},
+		{
+			name:   "truncate",
+			msg:    &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/etc/hosts", Flags: linux.O_RDONLY | linux.O_TRUNC},
+			denied: true,
+		},
+		{
+			name:   "deny-read",
+			msg:    &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/etc/shadow", Flags: linux.O_RDONLY},
+			denied: true,
+		},
+		{
+			name: "allow-write",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/etc/app/config", Flags: linux.O_WRONLY | linux.O_CREAT},
+		},
+		{
+			name: "unmatched",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/etcetera", Flags: linux.O_WRONLY},
+		},
+		{
+			name:   "dot-dot",
+			msg:    &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/tmp/../secret/key", Flags: linux.O_RDONLY},
+			denied: true,
+		},
+		{
+			name:    "cwd",
+			ctxData: cwd,
+			msg:     &pb.Open{Fd: linux.AT_FDCWD, Pathname: "shadow", Flags: linux.O_RDONLY},
+			denied:  true,
+		},
+		{
+			name:   "no-cwd",
+			msg:    &pb.Open{Fd: linux.AT_FDCWD, Pathname: "hosts", Flags: linux.O_RDONLY},
+			denied: true,
+		},
+		{
+			name:   "fd-path",
+			msg:    &pb.Open{Fd: 3, FdPath: "/", Pathname: "secret", Flags: linux.O_RDONLY},
+			denied: true,
+		},
+		{
+			name:   "bad-fd-path",
+			msg:    &pb.Open{Fd: 3, FdPath: "[err: FD not found]", Pathname: "tmp", Flags: linux.O_RDONLY},
+			denied: true,
+		},
+		{
+			name: "exit",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/secret", Exit: &pb.Exit{}},
+		},
+		{
+			name:   "unlink",
+			msg:    &pb.Unlink{Fd: linux.AT_FDCWD, Pathname: "/etc/hosts"},
+			denied: true,
+		},
+		{
+			name: "unlink-allowed",
+			msg:  &pb.Unlink{Fd: linux.AT_FDCWD, Pathname: "/tmp/file"},
+		},
+		{
+			name:   "rename-from",
+			msg:    &pb.Rename{OldFd: linux.AT_FDCWD, OldPathname: "/etc/hosts", NewFd: linux.AT_FDCWD, NewPathname: "/tmp/hosts"},
+			denied: true,
+		},
+		{
+			name:   "rename-to",
+			msg:    &pb.Rename{OldFd: linux.AT_FDCWD, OldPathname: "/tmp/hosts", NewFd: linux.AT_FDCWD, NewPathname: "/etc/hosts"},
+			denied: true,
+		},
+		{
+			name: "rename-allowed",
+			msg:  &pb.Rename{OldFd: linux.AT_FDCWD, OldPathname: "/tmp/a", NewFd: linux.AT_FDCWD, NewPathname: "/tmp/b"},
+		},
+		{
+			name: "other",
+			msg:  &pb.Chdir{Pathname: "/secret"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := p.Syscall(nil, seccheck.FieldSet{}, tc.ctxData, pb.MessageType_MESSAGE_UNKNOWN, tc.msg)
+			if tc.denied {
+				if !seccheck.IsDenied(err) {
+					t.Errorf("Syscall(%v): got: %v, want denied", tc.msg, err)
+				}
+			} else if err != nil {
+				t.Errorf("Syscall(%v): %v", tc.msg, err)
+			}
+		})
+	}
+}
+
+func TestActions(t *testing.T) {
+	c, err := new(map[string]interface{}{
+		"rules": []interface{}{
+			newRule("/etc", "read-only"),
+			map[string]interface{}{"prefix": "/secret", "access": "deny", "action": "kill-sandbox"},
+			map[string]interface{}{"prefix": "/tmp", "access": "deny", "action": "deny"},
+			map[string]interface{}{"prefix": "/var", "access": "deny", "mode": "audit"},
+			map[string]interface{}{"prefix": "/home", "access": "deny", "action": "pause-container"},
+		},
+		"egress_rules": []interface{}{newEgressRule("10.0.0.0/8", 22, "deny")},
+		"action":       "kill-container",
+	}, nil)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	for _, tc := range []struct {
+		name string
+		msg  proto.Message
+		want *seccheck.Violation
+	}{
+		{
+			name: "default",
+			msg:  &pb.Unlink{Fd: linux.AT_FDCWD, Pathname: "/etc/hosts"},
+			want: &seccheck.Violation{Rule: "read-only /etc", Reason: `syscall 0 on "/etc/hosts"`, Action: seccheck.ViolationKillContainer, Err: seccheck.ErrDenied},
+		},
+		{
+			name: "sandbox",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/secret/key"},
+			want: &seccheck.Violation{Rule: "deny /secret", Reason: `syscall 0 on "/secret/key"`, Action: seccheck.ViolationKillSandbox, Err: seccheck.ErrDenied},
+		},
+		{
+			name: "pause",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/home/user"},
+			want: &seccheck.Violation{Rule: "deny /home", Reason: `syscall 0 on "/home/user"`, Action: seccheck.ViolationPauseContainer, Err: seccheck.ErrDenied},
+		},
+		{
+			name: "deny",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/tmp/file"},
+			want: &seccheck.Violation{Rule: "deny /tmp", Reason: `syscall 0 on "/tmp/file"`, Err: seccheck.ErrDenied},
+		},
+		{
+			name: "audit",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/var/log"},
+			want: &seccheck.Violation{Rule: "deny /var", Reason: `syscall 0 on "/var/log"`, Action: seccheck.ViolationKillContainer, Audit: true, Err: seccheck.ErrDenied},
+		},
+		{
+			name: "unresolved",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD, Pathname: "file"},
+			want: &seccheck.Violation{Rule: "unresolved path", Reason: `syscall 0 on "file"`, Err: seccheck.ErrDenied},
+		},
+		{
+			name: "no pathname",
+			msg:  &pb.Open{Fd: linux.AT_FDCWD},
+			want: &seccheck.Violation{Rule: "unresolved path", Reason: `syscall 0 on ""`, Err: seccheck.ErrDenied},
+		},
+		{
+			name: "egress",
+			msg:  &pb.Connect{Address: sockaddr("10.0.0.1", 22)},
+			want: &seccheck.Violation{Rule: "deny 10.0.0.0/8 port 22", Reason: "syscall 0 to 10.0.0.1:22", Action: seccheck.ViolationKillContainer, Err: seccheck.ErrDenied},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := c.Syscall(nil, seccheck.FieldSet{}, nil, pb.MessageType_MESSAGE_UNKNOWN, tc.msg)
+			if !reflect.DeepEqual(err, tc.want) {
+				t.Errorf("Syscall(%v): got: %v, want: %v", tc.msg, err, tc.want)
+			}
+			if got, want := seccheck.IsDenied(err), !tc.want.Audit; got != want {
+				t.Errorf("IsDenied(%v): got: %t, want: %t", err, got, want)
+			}
+		})
+	}
+}
+
+func TestAuditMode(t *testing.T) {
+	c, err := new(map[string]interface{}{
+		"rules": []interface{}{
+			newRule("/etc", "deny"),
+			map[string]interface{}{"prefix": "/secret", "access": "deny", "mode": "enforce"},
+		},
+		"mode": "audit",
+	}, nil)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	for _, tc := range []struct {
+		pathname string
+		denied   bool
+	}{
+		{pathname: "/etc/hosts"},
+		{pathname: "relative"},
+		{pathname: "/secret/key", denied: true},
+	} {
+		msg := &pb.Open{Fd: linux.AT_FDCWD, Pathname: tc.pathname}
+		err := c.Syscall(nil, seccheck.FieldSet{}, nil, pb.MessageType_MESSAGE_UNKNOWN, msg)
+		if _, ok := err.(*seccheck.Violation); !ok {
+			t.Errorf("Syscall(%v): got: %v, want: violation", msg, err)
+		}
+		if got := seccheck.IsDenied(err); got != tc.denied {
+			t.Errorf("IsDenied(%v): got: %t, want: %t", err, got, tc.denied)
+		}
+	}
+}
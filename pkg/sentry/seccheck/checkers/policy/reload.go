@@ -0,0 +1,93 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"sync/atomic"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// checker is the seccheck.Checker created by the policy sink. It checks points
+// with its current policy, which Reload replaces atomically while points are
+// being checked, so that operators can tune rules without restarting the
+// sandbox, see seccheck.Update. The counters of the policy, including the hits
+// of each rule, are kept across reloads.
+type checker struct {
+	seccheck.CheckerDefaults
+
+	// policy is the current *policy. Points are checked with the policy
+	// that is current when they are received, even if it's replaced before
+	// they're done.
+	policy atomic.Value
+}
+
+var _ seccheck.Checker = (*checker)(nil)
+var _ seccheck.Reloader = (*checker)(nil)
+
+func newChecker(p *policy) *checker {
+	c := &checker{}
+	c.policy.Store(p)
+	return c
+}
+
+// current returns the current policy of c.
+func (c *checker) current() *policy {
+	return c.policy.Load().(*policy)
+}
+
+// Reload implements seccheck.Reloader.
+func (c *checker) Reload(config map[string]interface{}) error {
+	p, err := parse(config)
+	if err != nil {
+		return err
+	}
+	p.stats = c.current().stats
+	c.policy.Store(p)
+	return nil
+}
+
+// Name implements seccheck.Checker.
+func (*checker) Name() string {
+	return name
+}
+
+// Status implements seccheck.Checker.
+func (c *checker) Status() seccheck.CheckerStatus {
+	return c.current().Status()
+}
+
+// Execve implements seccheck.Checker.
+func (c *checker) Execve(ctx context.Context, fields seccheck.FieldSet, info *pb.ExecveInfo) error {
+	return c.current().Execve(ctx, fields, info)
+}
+
+// FilelessExec implements seccheck.Checker.
+func (c *checker) FilelessExec(ctx context.Context, fields seccheck.FieldSet, info *pb.FilelessExecInfo) error {
+	return c.current().FilelessExec(ctx, fields, info)
+}
+
+// Drift implements seccheck.Checker.
+func (c *checker) Drift(ctx context.Context, fields seccheck.FieldSet, info *pb.DriftInfo) error {
+	return c.current().Drift(ctx, fields, info)
+}
+
+// Syscall implements seccheck.Checker.
+func (c *checker) Syscall(ctx context.Context, fields seccheck.FieldSet, ctxData *pb.ContextData, msgType pb.MessageType, msg proto.Message) error {
+	return c.current().Syscall(ctx, fields, ctxData, msgType, msg)
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// metric returns the value of the sample of family with the given rule label,
+// which is empty for samples without labels.
+func metric(status seccheck.CheckerStatus, family, rule string) uint64 {
+	for _, m := range status.Metrics {
+		if m.Family == family && m.Labels["rule"] == rule {
+			return m.Value
+		}
+	}
+	return 0
+}
+
+func TestReload(t *testing.T) {
+	c, err := new(map[string]interface{}{"rules": []interface{}{newRule("/secret", "deny")}}, nil)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	secret := &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/secret/key"}
+	etc := &pb.Open{Fd: linux.AT_FDCWD, Pathname: "/etc/passwd"}
+	check := func(msg *pb.Open, want bool) {
+		t.Helper()
+		err := c.Syscall(nil, seccheck.FieldSet{}, nil, pb.MessageType_MESSAGE_UNKNOWN, msg)
+		if got := seccheck.IsDenied(err); got != want {
+			t.Errorf("Syscall(%v): got: %v, want denied: %t", msg, err, want)
+		}
+	}
+	check(secret, true)
+	check(etc, false)
+
+	r := c.(seccheck.Reloader)
+	if err := r.Reload(map[string]interface{}{"rules": "foo"}); err == nil {
+		t.Errorf("Reload() with invalid config succeeded")
+	}
+	check(secret, true)
+
+	if err := r.Reload(map[string]interface{}{"rules": []interface{}{newRule("/etc", "deny")}}); err != nil {
+		t.Fatalf("Reload(): %v", err)
+	}
+	check(secret, false)
+	check(etc, true)
+	check(etc, true)
+
+	// Counters are kept across reloads.
+	status := c.Status()
+	if got := metric(status, "runsc_trace_policy_denied_total", ""); got != 4 {
+		t.Errorf("denied count: got: %d, want: 4", got)
+	}
+	if got := metric(status, "runsc_trace_policy_rule_hits_total", "deny /secret"); got != 2 {
+		t.Errorf("hits of deny /secret: got: %d, want: 2", got)
+	}
+	if got := metric(status, "runsc_trace_policy_rule_hits_total", "deny /etc"); got != 2 {
+		t.Errorf("hits of deny /etc: got: %d, want: 2", got)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// Types of IDs that setid rules apply to.
+const (
+	setidUID = "uid"
+	setidGID = "gid"
+)
+
+// setidRule restricts the IDs that tasks can switch to.
+type setidRule struct {
+	idType string
+	// containerID is the container that the rule applies to, or empty for
+	// all containers.
+	containerID string
+	// allow is true if ids are the only IDs allowed, otherwise they're
+	// denied.
+	allow bool
+	ids   map[uint32]struct{}
+	// enforcement applies when the rule denies a transition.
+	enforcement enforcement
+}
+
+// String returns a description of the rule.
+func (r *setidRule) String() string {
+	ids := make([]string, 0, len(r.ids))
+	for id := range r.ids {
+		ids = append(ids, fmt.Sprint(id))
+	}
+	sort.Strings(ids)
+	access := accessDeny
+	if r.allow {
+		access = accessAllow
+	}
+	s := fmt.Sprintf("%s %s %s", access, r.idType, strings.Join(ids, ","))
+	if len(r.containerID) > 0 {
+		s += fmt.Sprintf(" in container %q", r.containerID)
+	}
+	return s
+}
+
+// denies returns true if r denies switching to id.
+func (r *setidRule) denies(id uint32) bool {
+	_, ok := r.ids[id]
+	return ok != r.allow
+}
+
+// parseSetidRules returns the rules that restrict ID transitions in config,
+// configured as:
+//
+//	setid_rules: list of {"type": "uid", "deny": [0]} or
+//	             {"type": "uid", "allow": [1000], "container_id": "<id>"},
+//	             where type is "uid" or "gid", and container_id is optional.
+//	             Like path rules, they may set "action" and "mode".
+//
+// All rules that apply to the container of a task are checked: "deny" rules
+// deny switching to the IDs they list, and "allow" rules deny switching to
+// any other ID. IDs are checked when setuid(2), setreuid(2), setresuid(2) and
+// their GID counterparts are entered. IDs that the task already has as its
+// real, effective or saved ID aren't transitions and are allowed, so points
+// must include the credentials context field, and the container_id field for
+// rules with a container, see the security-essentials preset. Privileges
+// gained by executing set-user-ID binaries are not checked.
+func parseSetidRules(config map[string]interface{}, def enforcement) ([]setidRule, error) {
+	opaque, ok := config["setid_rules"]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := opaque.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("setid_rules %v is not a list", opaque)
+	}
+	rules := make([]setidRule, 0, len(list))
+	for _, opaque := range list {
+		r, err := parseSetidRule(opaque, def)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func parseSetidRule(opaque interface{}, def enforcement) (setidRule, error) {
+	obj, ok := opaque.(map[string]interface{})
+	if !ok {
+		return setidRule{}, fmt.Errorf("setid rule %v is not an object", opaque)
+	}
+	r := setidRule{ids: make(map[uint32]struct{})}
+	r.idType, _ = obj["type"].(string)
+	if r.idType != setidUID && r.idType != setidGID {
+		return setidRule{}, fmt.Errorf("invalid setid rule type %v, must be %q or %q", obj["type"], setidUID, setidGID)
+	}
+	if opaque, ok := obj["container_id"]; ok {
+		r.containerID, _ = opaque.(string)
+		if len(r.containerID) == 0 {
+			return setidRule{}, fmt.Errorf("setid rule container_id %v is invalid", opaque)
+		}
+	}
+	allowed, hasAllow := obj["allow"]
+	denied, hasDeny := obj["deny"]
+	if hasAllow == hasDeny {
+		return setidRule{}, fmt.Errorf("setid rule %v must have one of \"allow\" or \"deny\"", opaque)
+	}
+	ids := denied
+	if hasAllow {
+		r.allow = true
+		ids = allowed
+	}
+	idList, ok := ids.([]interface{})
+	if !ok {
+		return setidRule{}, fmt.Errorf("setid rule IDs %v is not a list", ids)
+	}
+	for _, opaque := range idList {
+		id, ok := opaque.(float64)
+		// -1 means that the ID is unchanged.
+		if !ok || id < 0 || id >= math.MaxUint32 || id != math.Trunc(id) {
+			return setidRule{}, fmt.Errorf("setid rule ID %v is invalid", opaque)
+		}
+		r.ids[uint32(id)] = struct{}{}
+	}
+	var err error
+	if r.enforcement, err = parseEnforcement(obj, def); err != nil {
+		return setidRule{}, fmt.Errorf("setid rule %v: %w", opaque, err)
+	}
+	return r, nil
+}
+
+// setidType returns the type of IDs set by the syscall sysno, or false if it
+// doesn't set IDs.
+func setidType(sysno uint64) (string, bool) {
+	switch sysno {
+	case unix.SYS_SETUID, unix.SYS_SETREUID, unix.SYS_SETRESUID:
+		return setidUID, true
+	case unix.SYS_SETGID, unix.SYS_SETREGID, unix.SYS_SETRESGID:
+		return setidGID, true
+	}
+	return "", false
+}
+
+// currentIDs returns the IDs of type idType that creds already has.
+func currentIDs(creds *pb.Credentials, idType string) []uint32 {
+	if creds == nil {
+		return nil
+	}
+	if idType == setidGID {
+		return []uint32{creds.RealGid, creds.EffectiveGid, creds.SavedGid}
+	}
+	return []uint32{creds.RealUid, creds.EffectiveUid, creds.SavedUid}
+}
+
+// checkSetid returns an error that denies the syscall point msg if it
+// switches to IDs that setid rules deny.
+func (p *policy) checkSetid(ctxData *pb.ContextData, msg proto.Message) error {
+	if len(p.setidRules) == 0 {
+		return nil
+	}
+	var (
+		sysno uint64
+		ids   []uint32
+	)
+	switch m := msg.(type) {
+	case *pb.Setid:
+		if m.Exit != nil {
+			return nil
+		}
+		sysno, ids = m.Sysno, []uint32{m.Id}
+	case *pb.Setresid:
+		if m.Exit != nil {
+			return nil
+		}
+		sysno, ids = m.Sysno, []uint32{m.Rgid, m.Egid, m.Sgid}
+	default:
+		return nil
+	}
+	idType, ok := setidType(sysno)
+	if !ok {
+		// E.g. setsid(2).
+		return nil
+	}
+	current := currentIDs(ctxData.GetCredentials(), idType)
+	for _, id := range ids {
+		if id == math.MaxUint32 || containsID(current, id) {
+			continue
+		}
+		for i := range p.setidRules {
+			r := &p.setidRules[i]
+			if r.idType != idType || (len(r.containerID) > 0 && r.containerID != ctxData.GetContainerId()) {
+				continue
+			}
+			if r.denies(id) {
+				reason := fmt.Sprintf("syscall %d to %s %d", sysno, idType, id)
+				return p.violation(r.enforcement, r.String(), reason, seccheck.ErrDenied)
+			}
+		}
+	}
+	return nil
+}
+
+// containsID returns true if id is in ids.
+func containsID(ids []uint32, id uint32) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestSetidConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		rule interface{}
+		err  string
+	}{
+		{
+			name: "deny",
+			rule: map[string]interface{}{"type": "uid", "deny": []interface{}{0.0}},
+		},
+		{
+			name: "allow",
+			rule: map[string]interface{}{"type": "gid", "allow": []interface{}{1000.0}, "container_id": "web", "action": "kill-container"},
+		},
+		{
+			name: "object",
+			rule: "uid",
+			err:  "not an object",
+		},
+		{
+			name: "type",
+			rule: map[string]interface{}{"type": "pid", "deny": []interface{}{0.0}},
+			err:  "invalid setid rule type",
+		},
+		{
+			name: "both",
+			rule: map[string]interface{}{"type": "uid", "allow": []interface{}{1000.0}, "deny": []interface{}{0.0}},
+			err:  "must have one of",
+		},
+		{
+			name: "neither",
+			rule: map[string]interface{}{"type": "uid"},
+			err:  "must have one of",
+		},
+		{
+			name: "id",
+			rule: map[string]interface{}{"type": "uid", "deny": []interface{}{-1.0}},
+			err:  "ID -1 is invalid",
+		},
+		{
+			name: "container",
+			rule: map[string]interface{}{"type": "uid", "deny": []interface{}{0.0}, "container_id": ""},
+			err:  "container_id",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validate(map[string]interface{}{"setid_rules": []interface{}{tc.rule}})
+			if len(tc.err) == 0 {
+				if err != nil {
+					t.Errorf("validate(): %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("validate(): got: %v, want: %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestSetid(t *testing.T) {
+	c, err := new(map[string]interface{}{
+		"setid_rules": []interface{}{
+			map[string]interface{}{"type": "uid", "deny": []interface{}{0.0}},
+			map[string]interface{}{"type": "uid", "allow": []interface{}{1000.0, 1001.0}, "container_id": "web"},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	const unchanged = ^uint32(0)
+	user := &pb.ContextData{Credentials: &pb.Credentials{RealUid: 1000, EffectiveUid: 1000, SavedUid: 1000}}
+	root := &pb.ContextData{Credentials: &pb.Credentials{}}
+	web := &pb.ContextData{ContainerId: "web", Credentials: &pb.Credentials{RealUid: 1000, EffectiveUid: 1000, SavedUid: 1000}}
+	for _, tc := range []struct {
+		name    string
+		ctxData *pb.ContextData
+		msg     proto.Message
+		denied  bool
+	}{
+		{
+			name:    "setuid-root",
+			ctxData: user,
+			msg:     &pb.Setid{Sysno: unix.SYS_SETUID, Id: 0},
+			denied:  true,
+		},
+		{
+			name:    "setuid-user",
+			ctxData: user,
+			msg:     &pb.Setid{Sysno: unix.SYS_SETUID, Id: 2000},
+		},
+		{
+			name:    "setuid-exit",
+			ctxData: user,
+			msg:     &pb.Setid{Sysno: unix.SYS_SETUID, Id: 0, Exit: &pb.Exit{}},
+		},
+		{
+			name:    "setgid-root",
+			ctxData: user,
+			msg:     &pb.Setid{Sysno: unix.SYS_SETGID, Id: 0},
+		},
+		{
+			name:    "setsid",
+			ctxData: user,
+			msg:     &pb.Setid{Sysno: unix.SYS_SETSID},
+		},
+		{
+			name:    "setresuid-saved",
+			ctxData: user,
+			msg:     &pb.Setresid{Sysno: unix.SYS_SETRESUID, Rgid: unchanged, Egid: unchanged, Sgid: 0},
+			denied:  true,
+		},
+		{
+			name:    "setreuid-effective",
+			ctxData: user,
+			msg:     &pb.Setresid{Sysno: unix.SYS_SETREUID, Rgid: unchanged, Egid: 0, Sgid: unchanged},
+			denied:  true,
+		},
+		{
+			name:    "already-root",
+			ctxData: root,
+			msg:     &pb.Setresid{Sysno: unix.SYS_SETRESUID, Rgid: 0, Egid: 0, Sgid: 0},
+		},
+		{
+			name:    "no-credentials",
+			ctxData: &pb.ContextData{},
+			msg:     &pb.Setid{Sysno: unix.SYS_SETUID, Id: 0},
+			denied:  true,
+		},
+		{
+			name:    "container-allowed",
+			ctxData: web,
+			msg:     &pb.Setid{Sysno: unix.SYS_SETUID, Id: 1001},
+		},
+		{
+			name:    "container-not-allowed",
+			ctxData: web,
+			msg:     &pb.Setid{Sysno: unix.SYS_SETUID, Id: 2000},
+			denied:  true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := c.Syscall(nil, seccheck.FieldSet{}, tc.ctxData, pb.MessageType_MESSAGE_UNKNOWN, tc.msg)
+			if got := seccheck.IsDenied(err); got != tc.denied {
+				t.Errorf("Syscall(%v): got: %v, want denied: %t", tc.msg, err, tc.denied)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// wxRule is the rule reported for writable and executable mappings.
+const wxRule = "deny_writable_executable"
+
+// wxPolicy denies mappings that are writable and executable.
+type wxPolicy struct {
+	// exempt are the IDs of containers that may create writable and
+	// executable mappings, e.g. to run JITs.
+	exempt map[string]struct{}
+}
+
+// parseWX returns the W^X policy in config, or nil if mappings aren't
+// checked, configured as:
+//
+//	deny_writable_executable: true to deny mappings that are writable and
+//	       executable at the same time, and making writable pages
+//	       executable, which are used to run injected code.
+//	writable_executable_exempt: list of IDs of containers that are allowed
+//	       to create such mappings, e.g. to run JITs.
+//
+// Mappings are checked when mmap(2) and mprotect(2) are entered, so points
+// must include the container_id context field if containers are exempt, see
+// the wx-policy preset. mprotect(2) with PROT_EXEC is denied if any page in
+// its range is writable, so pages that are made read-only before they're made
+// executable are allowed.
+func parseWX(config map[string]interface{}) (*wxPolicy, error) {
+	deny, err := parseFlag(config, wxRule)
+	if err != nil {
+		return nil, err
+	}
+	opaque, ok := config["writable_executable_exempt"]
+	if !ok {
+		if !deny {
+			return nil, nil
+		}
+		return &wxPolicy{}, nil
+	}
+	if !deny {
+		return nil, fmt.Errorf("writable_executable_exempt requires %s", wxRule)
+	}
+	list, ok := opaque.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("writable_executable_exempt %v is not a list", opaque)
+	}
+	wx := &wxPolicy{exempt: make(map[string]struct{}, len(list))}
+	for _, opaque := range list {
+		id, _ := opaque.(string)
+		if len(id) == 0 {
+			return nil, fmt.Errorf("writable_executable_exempt container ID %v is invalid", opaque)
+		}
+		wx.exempt[id] = struct{}{}
+	}
+	return wx, nil
+}
+
+// writable returns true if any page in the range of length bytes at address
+// is writable in the memory of the task in ctx. It's false outside of the
+// sentry, where there is no task.
+func writable(ctx context.Context, address, length uint64) bool {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		return false
+	}
+	ar, ok := hostarch.Addr(address).ToRange(length)
+	if !ok {
+		// The syscall fails on its own.
+		return false
+	}
+	return t.MemoryManager().Writable(ar)
+}
+
+// checkWX returns an error that denies the syscall point msg if it creates a
+// writable and executable mapping, or makes writable pages executable.
+func (p *policy) checkWX(ctx context.Context, ctxData *pb.ContextData, msg proto.Message) error {
+	if p.wx == nil {
+		return nil
+	}
+	if _, ok := p.wx.exempt[ctxData.GetContainerId()]; ok {
+		return nil
+	}
+	const wx = linux.PROT_WRITE | linux.PROT_EXEC
+	switch m := msg.(type) {
+	case *pb.Mmap:
+		if m.Exit != nil || m.Prot&wx != wx {
+			return nil
+		}
+		reason := fmt.Sprintf("writable and executable mapping of %d bytes", m.Length)
+		return p.violation(p.enforcement, wxRule, reason, seccheck.ErrDenied)
+	case *pb.Mprotect:
+		if m.Exit != nil || m.Prot&linux.PROT_EXEC == 0 {
+			return nil
+		}
+		if m.Prot&wx == wx {
+			reason := fmt.Sprintf("writable and executable protection of %d bytes at %#x", m.Length, m.Address)
+			return p.violation(p.enforcement, wxRule, reason, seccheck.ErrDenied)
+		}
+		if writable(ctx, m.Address, m.Length) {
+			reason := fmt.Sprintf("executable protection of writable pages at %#x", m.Address)
+			return p.violation(p.enforcement, wxRule, reason, seccheck.ErrDenied)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestWXConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config map[string]interface{}
+		err    string
+	}{
+		{
+			name:   "enabled",
+			config: map[string]interface{}{"deny_writable_executable": true},
+		},
+		{
+			name: "exempt",
+			config: map[string]interface{}{
+				"deny_writable_executable":   true,
+				"writable_executable_exempt": []interface{}{"jit"},
+			},
+		},
+		{
+			name:   "disabled",
+			config: map[string]interface{}{"deny_writable_executable": false},
+			err:    "no rules",
+		},
+		{
+			name:   "exempt without deny",
+			config: map[string]interface{}{"writable_executable_exempt": []interface{}{"jit"}},
+			err:    "requires deny_writable_executable",
+		},
+		{
+			name: "exempt not a list",
+			config: map[string]interface{}{
+				"deny_writable_executable":   true,
+				"writable_executable_exempt": "jit",
+			},
+			err: "not a list",
+		},
+		{
+			name: "empty container ID",
+			config: map[string]interface{}{
+				"deny_writable_executable":   true,
+				"writable_executable_exempt": []interface{}{""},
+			},
+			err: "container ID",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validate(tc.config)
+			if len(tc.err) == 0 {
+				if err != nil {
+					t.Errorf("validate(): %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("validate(): got: %v, want: %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestWX(t *testing.T) {
+	c, err := new(map[string]interface{}{
+		"deny_writable_executable":   true,
+		"writable_executable_exempt": []interface{}{"jit"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("new(): %v", err)
+	}
+	// Without a task in the context, the current protection of pages is
+	// unknown and they're assumed not to be writable.
+	for _, tc := range []struct {
+		name    string
+		ctxData *pb.ContextData
+		msg     proto.Message
+		denied  bool
+	}{
+		{
+			name:   "mmap-wx",
+			msg:    &pb.Mmap{Length: 4096, Prot: linux.PROT_READ | linux.PROT_WRITE | linux.PROT_EXEC, Flags: linux.MAP_PRIVATE | linux.MAP_ANONYMOUS},
+			denied: true,
+		},
+		{
+			name: "mmap-rx",
+			msg:  &pb.Mmap{Length: 4096, Prot: linux.PROT_READ | linux.PROT_EXEC, Flags: linux.MAP_PRIVATE, Fd: 3},
+		},
+		{
+			name: "mmap-rw",
+			msg:  &pb.Mmap{Length: 4096, Prot: linux.PROT_READ | linux.PROT_WRITE, Flags: linux.MAP_PRIVATE | linux.MAP_ANONYMOUS},
+		},
+		{
+			name: "mmap-exit",
+			msg:  &pb.Mmap{Length: 4096, Prot: linux.PROT_WRITE | linux.PROT_EXEC, Exit: &pb.Exit{}},
+		},
+		{
+			name:    "mmap-exempt",
+			ctxData: &pb.ContextData{ContainerId: "jit"},
+			msg:     &pb.Mmap{Length: 4096, Prot: linux.PROT_WRITE | linux.PROT_EXEC},
+		},
+		{
+			name:    "mmap-other-container",
+			ctxData: &pb.ContextData{ContainerId: "app"},
+			msg:     &pb.Mmap{Length: 4096, Prot: linux.PROT_WRITE | linux.PROT_EXEC},
+			denied:  true,
+		},
+		{
+			name:   "mprotect-wx",
+			msg:    &pb.Mprotect{Address: 0x10000, Length: 4096, Prot: linux.PROT_WRITE | linux.PROT_EXEC},
+			denied: true,
+		},
+		{
+			name: "mprotect-rx",
+			msg:  &pb.Mprotect{Address: 0x10000, Length: 4096, Prot: linux.PROT_READ | linux.PROT_EXEC},
+		},
+		{
+			name: "mprotect-rw",
+			msg:  &pb.Mprotect{Address: 0x10000, Length: 4096, Prot: linux.PROT_READ | linux.PROT_WRITE},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := c.Syscall(context.Background(), seccheck.FieldSet{}, tc.ctxData, pb.MessageType_MESSAGE_UNKNOWN, tc.msg)
+			if got := seccheck.IsDenied(err); got != tc.denied {
+				t.Errorf("Syscall(%v): got: %v, want denied: %t", tc.msg, err, tc.denied)
+			}
+		})
+	}
+}
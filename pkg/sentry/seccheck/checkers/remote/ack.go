@@ -0,0 +1,274 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// defaultRetransmitTimeout is how long points can go unacknowledged before
+// they are retransmitted, unless "retransmit_timeout" is set.
+const defaultRetransmitTimeout = time.Second
+
+// maxAckSize bounds the size of Ack messages read from the remote process.
+const maxAckSize = 64
+
+// parseReplaySize returns the "replay_size" configuration, or def if it's not
+// set.
+func parseReplaySize(config map[string]interface{}, def int) (int, error) {
+	opaque, ok := config["replay_size"]
+	if !ok {
+		return def, nil
+	}
+	size, ok := opaque.(float64)
+	if !ok || size != float64(int(size)) || size <= 0 {
+		return 0, fmt.Errorf("replay_size %v is not a positive int", opaque)
+	}
+	return int(size), nil
+}
+
+// replayBuffer holds points that were written to the remote process, but not
+// acknowledged yet, so that they can be retransmitted. It's bounded: once
+// full, the flusher waits for acknowledgments before writing more points, and
+// new points pile up in the ring until they are dropped.
+type replayBuffer struct {
+	mu   sync.Mutex
+	cond sync.Cond
+
+	// size is the maximum number of entries.
+	size int
+
+	// entries are in sequence order.
+	//
+	// +checklocks:mu
+	entries []ringEntry
+
+	// progress is the last time that entries were acknowledged or
+	// retransmitted, or that an entry was added to an empty buffer.
+	//
+	// +checklocks:mu
+	progress time.Time
+
+	// retransmitted is the total number of entries retransmitted.
+	//
+	// +checklocks:mu
+	retransmitted uint64
+
+	// +checklocks:mu
+	closed bool
+}
+
+func newReplayBuffer(size int) *replayBuffer {
+	b := &replayBuffer{size: size}
+	b.cond.L = &b.mu
+	return b
+}
+
+// add appends entries to the buffer, waiting for older entries to be
+// acknowledged if there is no room for them. It returns false if the buffer
+// is closed, in which case entries are not added.
+func (b *replayBuffer) add(entries []ringEntry) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for !b.closed && len(b.entries)+len(entries) > b.size {
+		b.cond.Wait()
+	}
+	if b.closed {
+		return false
+	}
+	if len(b.entries) == 0 {
+		b.progress = time.Now()
+	}
+	b.entries = append(b.entries, entries...)
+	return true
+}
+
+// ack removes entries up to and including sequence.
+func (b *replayBuffer) ack(sequence uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := sort.Search(len(b.entries), func(i int) bool {
+		return b.entries[i].sequence > sequence
+	})
+	if i == 0 {
+		return
+	}
+	n := copy(b.entries, b.entries[i:])
+	for j := n; j < len(b.entries); j++ {
+		b.entries[j] = ringEntry{}
+	}
+	b.entries = b.entries[:n]
+	b.progress = time.Now()
+	b.cond.Broadcast()
+}
+
+// expired returns a copy of all entries if none were acknowledged within
+// timeout, and restarts the timeout. It returns nil otherwise.
+func (b *replayBuffer) expired(timeout time.Duration) []ringEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.entries) == 0 || time.Since(b.progress) < timeout {
+		return nil
+	}
+	b.progress = time.Now()
+	b.retransmitted += uint64(len(b.entries))
+	return append([]ringEntry(nil), b.entries...)
+}
+
+// empty returns true if all entries were acknowledged.
+func (b *replayBuffer) empty() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries) == 0
+}
+
+// close closes the buffer and returns the number of entries that were never
+// acknowledged.
+func (b *replayBuffer) close() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.cond.Broadcast()
+	return len(b.entries)
+}
+
+// status reports the state of the buffer as metrics.
+func (b *replayBuffer) status(status *seccheck.CheckerStatus) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status.Metrics = append(status.Metrics,
+		seccheck.MetricSample{
+			Family: "runsc_trace_unacked_points",
+			Type:   "gauge",
+			Name:   "runsc_trace_unacked_points",
+			Value:  uint64(len(b.entries)),
+		},
+		seccheck.MetricSample{
+			Family: "runsc_trace_retransmitted_points_total",
+			Type:   "counter",
+			Name:   "runsc_trace_retransmitted_points_total",
+			Value:  b.retransmitted,
+		},
+	)
+}
+
+// receiveAcks reads acknowledgments from the remote process and retransmits
+// points that go unacknowledged for r.retransmitTimeout, until the ring is
+// closed. See pb.Ack for the protocol.
+func (r *remote) receiveAcks() {
+	defer close(r.acksDone)
+	buf := make([]byte, 4096)
+	// pending holds partial frames read from stream endpoints.
+	var pending []byte
+	timeout := unix.NsecToTimespec(flushPollTimeout.Nanoseconds())
+	for !r.ring.isClosed() {
+		fds := []unix.PollFd{{Fd: int32(r.endpoint.FD()), Events: unix.POLLIN}}
+		if _, err := unix.Ppoll(fds, &timeout, nil); err != nil && !errors.Is(err, unix.EINTR) {
+			log.Warningf("Remote sink stopped receiving acknowledgments: %v", err)
+			return
+		}
+		if fds[0].Revents != 0 {
+			n, err := unix.Read(r.endpoint.FD(), buf)
+			if err != nil && !errors.Is(err, unix.EAGAIN) && !errors.Is(err, unix.EINTR) {
+				log.Warningf("Remote sink stopped receiving acknowledgments: %v", err)
+				return
+			}
+			if err == nil && n == 0 {
+				// The remote process went away, which is detected when points
+				// are written.
+				return
+			}
+			if n > 0 {
+				if r.stream {
+					pending = append(pending, buf[:n]...)
+					if pending, err = receiveFrames(pending, maxAckSize, r.receiveAck); err != nil {
+						log.Warningf("Remote sink stopped receiving acknowledgments: %v", err)
+						return
+					}
+				} else {
+					r.receiveAck(buf[:n])
+				}
+			}
+		}
+		if r.disconnected.Load() != 0 {
+			continue
+		}
+		if entries := r.replay.expired(r.retransmitTimeout); entries != nil {
+			r.retransmit(entries)
+		}
+	}
+}
+
+// receiveFrames calls receive with every complete frame read from a stream
+// endpoint in buf, and returns what's left of it. Frames can't be larger than
+// maxSize.
+func receiveFrames(buf []byte, maxSize uint32, receive func([]byte)) ([]byte, error) {
+	for len(buf) >= wire.FrameLengthSize {
+		length := binary.LittleEndian.Uint32(buf)
+		if length > maxSize {
+			return nil, fmt.Errorf("message too big: %d bytes", length)
+		}
+		if len(buf) < wire.FrameLengthSize+int(length) {
+			break
+		}
+		receive(buf[wire.FrameLengthSize : wire.FrameLengthSize+length])
+		buf = buf[wire.FrameLengthSize+length:]
+	}
+	return buf, nil
+}
+
+func (r *remote) receiveAck(buf []byte) {
+	ack := pb.Ack{}
+	if err := proto.Unmarshal(buf, &ack); err != nil {
+		log.Debugf("Unmarshal(Ack): %v", err)
+		return
+	}
+	r.replay.ack(ack.Sequence)
+}
+
+// retransmit writes entries again, together if possible, see writeEntries.
+// The remote process discards the ones it already has.
+func (r *remote) retransmit(entries []ringEntry) {
+	log.Debugf("Retransmitting %d unacknowledged point(s)", len(entries))
+	maxBytes, maxCount := r.batchBytesLimit(), r.writeCount()
+	for len(entries) > 0 {
+		n, size := 1, entries[0].batchedSize()
+		for n < len(entries) && n < maxCount {
+			if size += entries[n].batchedSize(); size > maxBytes {
+				break
+			}
+			n++
+		}
+		if err := r.writeEntries(entries[:n]); err != nil {
+			log.Debugf("Retransmission failed: %v", err)
+			r.setError(err)
+			r.checkDisconnected(err)
+			return
+		}
+		entries = entries[n:]
+	}
+}
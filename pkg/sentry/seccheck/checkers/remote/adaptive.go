@@ -0,0 +1,254 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"fmt"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+const (
+	defaultAdaptiveHighWatermark = 0.5
+	defaultAdaptiveLowWatermark  = 0.1
+	defaultAdaptiveMaxInterval   = 1024
+	defaultAdaptiveCheckInterval = 100 * time.Millisecond
+)
+
+// adaptiveSampler keeps a fraction of the points of high-rate message types
+// that depends on load. When the queue fills up past a high watermark, or
+// points are dropped, the fraction is halved every check interval, down to 1
+// in maxInterval. Once the queue drains below a low watermark, the fraction
+// is doubled back, up to all points. Points of other types are always kept.
+type adaptiveSampler struct {
+	// types are the message types that can be sampled.
+	types map[pb.MessageType]struct{}
+
+	// high and low are the fractions of the queue above which sampling
+	// increases, and below which it decreases.
+	high float64
+	low  float64
+
+	// maxInterval bounds interval.
+	maxInterval uint64
+
+	// checkInterval is how often load is checked.
+	checkInterval time.Duration
+
+	// interval is the number of points of types that are seen for each point
+	// that is kept. 1 keeps all points.
+	interval atomicbitops.Uint64
+
+	// seen is the number of points of types seen.
+	seen atomicbitops.Uint64
+
+	// sampledOut is the number of points that were not kept.
+	sampledOut atomicbitops.Uint64
+
+	// lastDropped is the number of points dropped by the sink as of the last
+	// check. It's only accessed by adapt.
+	lastDropped uint32
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// parseAdaptiveSampling returns an adaptiveSampler for the "adaptive_sampling"
+// configuration, or nil if it's not set, e.g.:
+//
+//	"adaptive_sampling": {
+//	  "message_types": ["MESSAGE_SYSCALL_READ", "MESSAGE_SYSCALL_WRITE"],
+//	  "high_watermark": 0.5,
+//	  "low_watermark": 0.1,
+//	  "max_interval": 1024,
+//	  "check_interval": "100ms"
+//	}
+//
+// Only message_types is required.
+func parseAdaptiveSampling(config map[string]interface{}) (*adaptiveSampler, error) {
+	opaque, ok := config["adaptive_sampling"]
+	if !ok {
+		return nil, nil
+	}
+	opts, ok := opaque.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("adaptive_sampling %v is not an object", opaque)
+	}
+	a := &adaptiveSampler{
+		types:         make(map[pb.MessageType]struct{}),
+		high:          defaultAdaptiveHighWatermark,
+		low:           defaultAdaptiveLowWatermark,
+		maxInterval:   defaultAdaptiveMaxInterval,
+		checkInterval: defaultAdaptiveCheckInterval,
+	}
+	a.interval.Store(1)
+	for name, opaque := range opts {
+		switch name {
+		case "message_types":
+			names, ok := opaque.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("adaptive_sampling message_types %v is not a list", opaque)
+			}
+			for _, opaque := range names {
+				name, ok := opaque.(string)
+				if !ok {
+					return nil, fmt.Errorf("message type %v is not a string", opaque)
+				}
+				t, ok := pb.MessageType_value[name]
+				if !ok {
+					return nil, fmt.Errorf("invalid message type %q in adaptive_sampling", name)
+				}
+				msgType := pb.MessageType(t)
+				if msgType == pb.MessageType_MESSAGE_DROP_STATS || msgType == pb.MessageType_MESSAGE_SESSION_CLOSED {
+					return nil, fmt.Errorf("%v messages cannot be sampled", msgType)
+				}
+				a.types[msgType] = struct{}{}
+			}
+		case "high_watermark", "low_watermark":
+			fraction, ok := opaque.(float64)
+			if !ok || fraction < 0 || fraction > 1 {
+				return nil, fmt.Errorf("adaptive_sampling %s %v must be a number between 0 and 1", name, opaque)
+			}
+			if name == "high_watermark" {
+				a.high = fraction
+			} else {
+				a.low = fraction
+			}
+		case "max_interval":
+			interval, ok := opaque.(float64)
+			if !ok || interval != float64(uint64(interval)) || interval < 1 {
+				return nil, fmt.Errorf("adaptive_sampling max_interval %v is not a positive int", opaque)
+			}
+			a.maxInterval = uint64(interval)
+		case "check_interval":
+			_, interval, err := parseDuration(opts, name)
+			if err != nil {
+				return nil, fmt.Errorf("adaptive_sampling: %w", err)
+			}
+			if interval <= 0 {
+				return nil, fmt.Errorf("adaptive_sampling check_interval %v must be positive", interval)
+			}
+			a.checkInterval = interval
+		default:
+			return nil, fmt.Errorf("invalid adaptive_sampling option %q", name)
+		}
+	}
+	if len(a.types) == 0 {
+		return nil, fmt.Errorf("adaptive_sampling requires message_types")
+	}
+	if a.low >= a.high {
+		return nil, fmt.Errorf("adaptive_sampling low_watermark (%v) must be lower than high_watermark (%v)", a.low, a.high)
+	}
+	return a, nil
+}
+
+// keep returns true if a point of type msgType is kept. Like sampler, points
+// are kept at regular intervals. A nil *adaptiveSampler keeps all points.
+func (a *adaptiveSampler) keep(msgType pb.MessageType) bool {
+	if a == nil {
+		return true
+	}
+	if _, ok := a.types[msgType]; !ok {
+		return true
+	}
+	interval := a.interval.Load()
+	if interval == 1 || a.seen.Add(1)%interval == 0 {
+		return true
+	}
+	a.sampledOut.Add(1)
+	return false
+}
+
+// startAdaptiveSampling starts adjusting r.adaptive to the load of r.ring. It
+// must be called once r is ready to send points.
+func (r *remote) startAdaptiveSampling(queueSize int) {
+	r.adaptive.stop = make(chan struct{})
+	r.adaptive.done = make(chan struct{})
+	go r.adaptSampling(queueSize) // S/R-SAFE: sinks are not saved.
+}
+
+// adaptSampling checks the load every check interval, until r.adaptive.stop is
+// closed.
+func (r *remote) adaptSampling(queueSize int) {
+	a := r.adaptive
+	defer close(a.done)
+	ticker := time.NewTicker(a.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+			a.adapt(float64(r.ring.len())/float64(queueSize), r.droppedCount.Load())
+		}
+	}
+}
+
+// adapt adjusts the sampling interval given the fraction of the queue that is
+// in use, and the number of points dropped so far.
+func (a *adaptiveSampler) adapt(fill float64, dropped uint32) {
+	dropping := dropped != a.lastDropped
+	a.lastDropped = dropped
+
+	old := a.interval.Load()
+	interval := old
+	switch {
+	case dropping || fill >= a.high:
+		interval *= 2
+		if interval > a.maxInterval {
+			interval = a.maxInterval
+		}
+	case fill <= a.low && interval > 1:
+		interval /= 2
+	}
+	if interval == old {
+		return
+	}
+	a.interval.Store(interval)
+	if interval == 1 {
+		log.Infof("Trace load subsided, keeping all points")
+	} else {
+		log.Infof("Trace queue is %.0f%% full (dropping: %t), keeping 1 in %d high-rate points", fill*100, dropping, interval)
+	}
+}
+
+// stopAdapting stops adaptSampling and waits for it to return.
+func (a *adaptiveSampler) stopAdapting() {
+	close(a.stop)
+	<-a.done
+}
+
+// status reports the current interval and the number of points that were not
+// kept as metrics.
+func (a *adaptiveSampler) status(status *seccheck.CheckerStatus) {
+	status.Metrics = append(status.Metrics,
+		seccheck.MetricSample{
+			Family: "runsc_trace_adaptive_sampling_interval",
+			Type:   "gauge",
+			Name:   "runsc_trace_adaptive_sampling_interval",
+			Value:  a.interval.Load(),
+		},
+		seccheck.MetricSample{
+			Family: "runsc_trace_adaptive_sampled_out_points_total",
+			Type:   "counter",
+			Name:   "runsc_trace_adaptive_sampled_out_points_total",
+			Value:  a.sampledOut.Load(),
+		},
+	)
+}
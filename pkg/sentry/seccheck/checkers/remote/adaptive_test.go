@@ -0,0 +1,72 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"testing"
+
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestAdaptiveSampling(t *testing.T) {
+	a, err := parseAdaptiveSampling(map[string]interface{}{
+		"adaptive_sampling": map[string]interface{}{
+			"message_types": []interface{}{"MESSAGE_SYSCALL_READ"},
+			"max_interval":  float64(4),
+		},
+	})
+	if err != nil {
+		t.Fatalf("parseAdaptiveSampling(): %v", err)
+	}
+
+	// keep returns the number of points kept out of 8 of each type.
+	keep := func() (read, exec int) {
+		for i := 0; i < 8; i++ {
+			if a.keep(pb.MessageType_MESSAGE_SYSCALL_READ) {
+				read++
+			}
+			if a.keep(pb.MessageType_MESSAGE_SENTRY_EXEC) {
+				exec++
+			}
+		}
+		return read, exec
+	}
+	for _, tc := range []struct {
+		name     string
+		fill     float64
+		dropped  uint32
+		interval uint64
+	}{
+		{name: "idle", fill: 0, interval: 1},
+		{name: "full", fill: 0.6, interval: 2},
+		{name: "drops", fill: 0.2, dropped: 1, interval: 4},
+		{name: "max", fill: 0.9, dropped: 2, interval: 4},
+		{name: "steady", fill: 0.2, dropped: 2, interval: 4},
+		{name: "subsiding", fill: 0.05, dropped: 2, interval: 2},
+		{name: "subsided", fill: 0, dropped: 2, interval: 1},
+	} {
+		a.adapt(tc.fill, tc.dropped)
+		if got := a.interval.Load(); got != tc.interval {
+			t.Errorf("%s: wrong interval, want: %d, got: %d", tc.name, tc.interval, got)
+		}
+		read, exec := keep()
+		if want := 8 / int(tc.interval); read != want {
+			t.Errorf("%s: wrong number of sampled points kept, want: %d, got: %d", tc.name, want, read)
+		}
+		if exec != 8 {
+			t.Errorf("%s: points of other types must all be kept, got: %d", tc.name, exec)
+		}
+	}
+}
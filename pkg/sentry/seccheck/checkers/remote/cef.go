@@ -0,0 +1,169 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+const cefName = "cef"
+
+// cefDefaultSeverity is the CEF severity of points that are not listed in
+// cefSeverities.
+const cefDefaultSeverity = 3
+
+// cefSeverities holds the CEF severity, from 0 to 10, of points that are more
+// relevant to security monitoring than most.
+var cefSeverities = map[pb.MessageType]int{
+	pb.MessageType_MESSAGE_SENTRY_CAPABILITY_DENIED: 6,
+	pb.MessageType_MESSAGE_SENTRY_CORE_DUMP:         6,
+	pb.MessageType_MESSAGE_SENTRY_OOM:               6,
+	pb.MessageType_MESSAGE_SENTRY_RLIMIT_BREACH:     5,
+	pb.MessageType_MESSAGE_SENTRY_SECCOMP:           7,
+}
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     cefName,
+		Setup:    setupFileSink,
+		New:      newCEF,
+		Validate: validateFilters,
+	})
+}
+
+// newCEF creates a new checker that writes points in ArcSight Common Event
+// Format, one event per line, for ingestion by SIEMs.
+func newCEF(config map[string]interface{}, endpoint *fd.FD) (seccheck.Checker, error) {
+	if endpoint == nil {
+		return nil, fmt.Errorf("cef sink requires an endpoint")
+	}
+	sampler, err := parseSampleRates(config)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFilters(config)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("CEF sink created, endpoint FD: %d", endpoint.FD())
+	return &remote{fields: fields, sampler: sampler, sender: &lineWriter{
+		sinkName: cefName,
+		encode:   encodeCEF,
+		endpoint: endpoint,
+	}}, nil
+}
+
+// encodeCEF renders a point as a CEF event, e.g.:
+//
+//	CEF:0|gVisor|runsc|1|MESSAGE_SENTRY_CLONE|CloneInfo|3|rt=... msg={...}
+//
+// Context data is mapped to standard extension keys when present, and the
+// complete point is included as JSON in msg.
+func encodeCEF(msg proto.Message, msgType pb.MessageType, droppedCount uint32) ([]byte, error) {
+	point, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	severity, ok := cefSeverities[msgType]
+	if !ok {
+		severity = cefDefaultSeverity
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|gVisor|runsc|%d|%s|%s|%d|",
+		wire.CurrentVersion,
+		cefEscapeHeader(msgType.String()),
+		cefEscapeHeader(string(msg.ProtoReflect().Descriptor().Name())),
+		severity)
+
+	ext := cefExtension{b: &b}
+	if ctx := contextData(msg); ctx != nil {
+		if ctx.TimeNs != 0 {
+			ext.add("rt", strconv.FormatInt(ctx.TimeNs/1e6, 10))
+		}
+		if ctx.ThreadGroupId != 0 {
+			ext.add("dpid", strconv.FormatInt(int64(ctx.ThreadGroupId), 10))
+		}
+		if ctx.ProcessName != "" {
+			ext.add("dproc", ctx.ProcessName)
+		}
+		if creds := ctx.Credentials; creds != nil {
+			ext.add("duid", strconv.FormatUint(uint64(creds.EffectiveUid), 10))
+		}
+		if ctx.ContainerId != "" {
+			ext.add("cs1Label", "containerId")
+			ext.add("cs1", ctx.ContainerId)
+		}
+		if ctx.Cwd != "" {
+			ext.add("cs2Label", "cwd")
+			ext.add("cs2", ctx.Cwd)
+		}
+	}
+	ext.add("cn1Label", "droppedCount")
+	ext.add("cn1", strconv.FormatUint(uint64(droppedCount), 10))
+	ext.add("msg", string(point))
+	b.WriteByte('\n')
+	return []byte(b.String()), nil
+}
+
+// contextData returns the context_data field of msg, or nil if msg doesn't
+// have one.
+func contextData(msg proto.Message) *pb.ContextData {
+	m := msg.ProtoReflect()
+	field := m.Descriptor().Fields().ByName("context_data")
+	if field == nil || !m.Has(field) {
+		return nil
+	}
+	ctx, _ := m.Get(field).Message().Interface().(*pb.ContextData)
+	return ctx
+}
+
+// cefHeaderEscaper escapes CEF header fields.
+var cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+
+func cefEscapeHeader(s string) string {
+	return cefHeaderEscaper.Replace(s)
+}
+
+// cefExtensionEscaper escapes CEF extension values.
+var cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+
+// cefExtension appends key=value pairs to a CEF event.
+type cefExtension struct {
+	b *strings.Builder
+
+	// started is true once a pair has been added.
+	started bool
+}
+
+func (e *cefExtension) add(key, value string) {
+	if e.started {
+		e.b.WriteByte(' ')
+	}
+	e.started = true
+	e.b.WriteString(key)
+	e.b.WriteByte('=')
+	e.b.WriteString(cefExtensionEscaper.Replace(value))
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestCEF(t *testing.T) {
+	info := &pb.ExitNotifyParentInfo{
+		ContextData: &pb.ContextData{
+			TimeNs:        5_000_000,
+			ThreadGroupId: 7,
+			ProcessName:   "a=b|c",
+			ContainerId:   "abc",
+		},
+		ExitStatus: 123,
+	}
+	line, err := encodeCEF(info, pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT, 2)
+	if err != nil {
+		t.Fatalf("encodeCEF(): %v", err)
+	}
+	got := string(line)
+	if want := fmt.Sprintf("CEF:0|gVisor|runsc|%d|MESSAGE_SENTRY_EXIT_NOTIFY_PARENT|ExitNotifyParentInfo|3|", wire.CurrentVersion); !strings.HasPrefix(got, want) {
+		t.Errorf("wrong header, want prefix: %q, got: %q", want, got)
+	}
+	if !strings.HasSuffix(got, "\n") || strings.Count(got, "\n") != 1 {
+		t.Errorf("event must be a single line: %q", got)
+	}
+	for _, want := range []string{
+		"rt=5 ",
+		"dpid=7 ",
+		`dproc=a\=b|c `,
+		"cs1Label=containerId cs1=abc ",
+		"cn1Label=droppedCount cn1=2 ",
+		"msg={",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("event doesn't contain %q: %q", want, got)
+		}
+	}
+}
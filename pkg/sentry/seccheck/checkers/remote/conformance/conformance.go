@@ -0,0 +1,286 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package conformance checks that writers and readers of the remote wire
+// protocol agree on how points are encoded, so that changes to the protocol
+// can't silently break one side.
+//
+// It provides golden messages, one for every message type. Each has a point
+// with every field set, and the complete frame, i.e. header and payload, that
+// writers must produce for it. Writers are checked with CheckFrame, and readers
+// with CheckRead, against every golden message. The sink and the reference
+// readers in Go are checked by the tests of their packages, and the C++ example
+// server in examples/seccheck by the tests of the sink.
+//
+// Golden messages are stored in Dir, as <name>.json with the point in protojson,
+// and <name>.bin with the frame. A point must be added for new message types,
+// and fields added to points. Frames are then regenerated with:
+//
+//	bazel run //pkg/sentry/seccheck/checkers/remote/conformance:conformance_test -- -update
+//
+// Frames must never change otherwise, since it means that the encoding of
+// existing points changed, which breaks readers that were built before.
+package conformance
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/consumer"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/test/testutil"
+)
+
+// Dir is the directory of the golden messages, relative to the root of the
+// repository.
+const Dir = "pkg/sentry/seccheck/checkers/remote/conformance/testdata"
+
+const (
+	// Sequence is the Header.Sequence of golden frames.
+	Sequence = 1
+
+	// TimeNs is the Header.TimeNs of golden frames.
+	TimeNs = 1700000000000000000
+)
+
+// Golden is a golden message.
+type Golden struct {
+	// Name is the base name of the files of the message, see Name.
+	Name string
+
+	// Type is the type of the message.
+	Type pb.MessageType
+
+	// Msg is the point.
+	Msg proto.Message
+
+	// Frame is the message as written by the reference writer, see Encode.
+	Frame []byte
+}
+
+// Payload returns the payload of the frame.
+func (g *Golden) Payload() []byte {
+	return g.Frame[wire.HeaderStructSize:]
+}
+
+// Name returns the base name of the files of the golden message of type t,
+// e.g. "syscall_open".
+func Name(t pb.MessageType) string {
+	return strings.ToLower(strings.TrimPrefix(t.String(), "MESSAGE_"))
+}
+
+// Types returns all message types, in the order of their numbers.
+func Types() []pb.MessageType {
+	var types []pb.MessageType
+	for num := range pb.MessageType_name {
+		if t := pb.MessageType(num); t != pb.MessageType_MESSAGE_UNKNOWN {
+			types = append(types, t)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// Load loads the golden messages from Dir.
+func Load() ([]*Golden, error) {
+	dir, err := testutil.FindFile(Dir)
+	if err != nil {
+		return nil, err
+	}
+	return LoadDir(dir)
+}
+
+// LoadDir loads the golden messages from dir. There must be one for every
+// message type. Frames that are missing are left empty, for them to be
+// generated.
+func LoadDir(dir string) ([]*Golden, error) {
+	var goldens []*Golden
+	for _, t := range Types() {
+		g := &Golden{Name: Name(t), Type: t}
+		path := filepath.Join(dir, g.Name+".json")
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("golden point for %v missing: %w", t, err)
+		}
+		if g.Msg, err = consumer.NewMessage(t); err != nil {
+			return nil, err
+		}
+		if err := protojson.Unmarshal(data, g.Msg); err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", path, err)
+		}
+		g.Frame, err = ioutil.ReadFile(filepath.Join(dir, g.Name+".bin"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		goldens = append(goldens, g)
+	}
+	return goldens, nil
+}
+
+// Encode returns the frame of msg, of type t, as written by the reference
+// writer: a header of the current version, with the checksum of the payload,
+// followed by the point encoded in protobuf.
+func Encode(t pb.MessageType, msg proto.Message) ([]byte, error) {
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	hdr := wire.Header{
+		HeaderSize:  wire.HeaderStructSize,
+		MessageType: uint16(t),
+		Sequence:    Sequence,
+		TimeNs:      TimeNs,
+		Version:     wire.CurrentVersion,
+	}
+	hdr.SetChecksum(payload)
+	frame := make([]byte, wire.HeaderStructSize, wire.HeaderStructSize+len(payload))
+	hdr.MarshalUnsafe(frame)
+	return append(frame, payload...), nil
+}
+
+// Unset returns the names of the fields of msg, and of the messages it
+// contains, that aren't set. Golden points must set all of them, so that the
+// encoding of every field is checked.
+func Unset(msg proto.Message) []string {
+	var unset []string
+	unsetFields(msg.ProtoReflect(), "", &unset)
+	return unset
+}
+
+func unsetFields(m protoreflect.Message, prefix string, unset *[]string) {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name := prefix + string(fd.Name())
+		// Interned strings are defined by writers, see wire.Dictionary.
+		if fd.Name() == "interned_strings" {
+			continue
+		}
+		if !m.Has(fd) {
+			*unset = append(*unset, name)
+			continue
+		}
+		if fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() {
+			unsetFields(m.Get(fd).Message(), name+".", unset)
+		}
+	}
+}
+
+// CheckFrame checks that frame, written by a writer for the point of g,
+// matches the golden frame. The writer must have negotiated version. Fields of
+// the header that are assigned by writers, e.g. Sequence and TimeNs, only need
+// to be set, and the checksum is optional.
+func CheckFrame(g *Golden, frame []byte, version uint32) error {
+	hdr, payload, err := wire.ParseHeader(frame)
+	if err != nil {
+		return err
+	}
+	if hdr.HeaderSize != wire.HeaderStructSize {
+		return fmt.Errorf("header size: got %d, want %d", hdr.HeaderSize, wire.HeaderStructSize)
+	}
+	if got := pb.MessageType(hdr.MessageType); got != g.Type {
+		return fmt.Errorf("message type: got %v, want %v", got, g.Type)
+	}
+	if err := hdr.CheckVersion(version); err != nil {
+		return err
+	}
+	if hdr.Sequence == 0 {
+		return fmt.Errorf("sequence not set")
+	}
+	if hdr.TimeNs == 0 {
+		return fmt.Errorf("time not set")
+	}
+	if !hdr.VerifyChecksum(payload) {
+		return fmt.Errorf("checksum mismatch")
+	}
+	if want := g.Payload(); !bytes.Equal(payload, want) {
+		return fmt.Errorf("payload mismatch:\ngot:  %x\nwant: %x", payload, want)
+	}
+	return nil
+}
+
+// Point is a point decoded by a reader.
+type Point struct {
+	Header wire.Header
+	Type   pb.MessageType
+	Msg    proto.Message
+}
+
+// CheckRead checks that points, decoded by a reader from the frames of
+// goldens, match the golden points, in order.
+func CheckRead(goldens []*Golden, points []Point) error {
+	if len(points) != len(goldens) {
+		return fmt.Errorf("got %d points, want %d", len(points), len(goldens))
+	}
+	for i, g := range goldens {
+		p := points[i]
+		if p.Type != g.Type {
+			return fmt.Errorf("point %d: type: got %v, want %v", i, p.Type, g.Type)
+		}
+		if p.Header.Sequence != Sequence || p.Header.TimeNs != TimeNs || p.Header.ProtocolVersion() != wire.CurrentVersion {
+			return fmt.Errorf("point %d (%v): header: got %+v, want sequence %d, time %d and version %d", i, g.Type, p.Header, Sequence, TimeNs, wire.CurrentVersion)
+		}
+		if !proto.Equal(p.Msg, g.Msg) {
+			return fmt.Errorf("point %d (%v):\ngot:  %v\nwant: %v", i, g.Type, p.Msg, g.Msg)
+		}
+	}
+	return nil
+}
+
+// Stream returns the frames of goldens as written to stream transports, i.e.
+// each preceded by its length.
+func Stream(goldens []*Golden) []byte {
+	var out []byte
+	for _, g := range goldens {
+		out = appendLength(out, len(g.Frame))
+		out = append(out, g.Frame...)
+	}
+	return out
+}
+
+// Batch returns a batch message with the frames of goldens, see
+// wire.BatchMessageType.
+func Batch(goldens []*Golden) []byte {
+	hdr := wire.Header{
+		HeaderSize:  wire.HeaderStructSize,
+		MessageType: wire.BatchMessageType,
+		Version:     wire.CurrentVersion,
+	}
+	out := make([]byte, wire.HeaderStructSize)
+	for _, g := range goldens {
+		out = appendLength(out, len(g.Frame))
+		out = append(out, g.Frame...)
+	}
+	hdr.SetChecksum(out[wire.HeaderStructSize:])
+	hdr.MarshalUnsafe(out)
+	return out
+}
+
+// appendLength appends length as it precedes messages in streams and batches,
+// which use the same encoding.
+func appendLength(buf []byte, length int) []byte {
+	var b [wire.BatchLengthSize]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(length))
+	return append(buf, b[:]...)
+}
@@ -0,0 +1,174 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conformance
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/consumer"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/server"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/test/testutil"
+)
+
+var update = flag.Bool("update", false, "regenerate the golden frames from the golden points")
+
+// updateDir returns the directory where golden frames are written with
+// -update, which is the source tree when run with bazel run.
+func updateDir() (string, error) {
+	if ws := os.Getenv("BUILD_WORKSPACE_DIRECTORY"); len(ws) > 0 {
+		return filepath.Join(ws, Dir), nil
+	}
+	return testutil.FindFile(Dir)
+}
+
+func load(t *testing.T) []*Golden {
+	t.Helper()
+	goldens, err := Load()
+	if err != nil {
+		t.Fatalf("Load(): %v", err)
+	}
+	return goldens
+}
+
+// TestGolden checks that the golden frames are the encoding of the golden
+// points, and that all message types and fields are covered.
+func TestGolden(t *testing.T) {
+	goldens := load(t)
+	if want := len(pb.MessageType_name) - 1; len(goldens) != want {
+		t.Errorf("got %d golden messages, want one for each of the %d message types", len(goldens), want)
+	}
+	for _, g := range goldens {
+		t.Run(g.Name, func(t *testing.T) {
+			if unset := Unset(g.Msg); len(unset) > 0 {
+				t.Errorf("fields not set in %s.json: %v", g.Name, unset)
+			}
+			frame, err := Encode(g.Type, g.Msg)
+			if err != nil {
+				t.Fatalf("Encode(): %v", err)
+			}
+			if *update {
+				dir, err := updateDir()
+				if err != nil {
+					t.Fatalf("updateDir(): %v", err)
+				}
+				if err := ioutil.WriteFile(filepath.Join(dir, g.Name+".bin"), frame, 0644); err != nil {
+					t.Fatalf("WriteFile(): %v", err)
+				}
+				return
+			}
+			if !bytes.Equal(frame, g.Frame) {
+				t.Errorf("golden frame doesn't match the point, run with -update if the change is intended:\ngot:  %x\nwant: %x", frame, g.Frame)
+			}
+		})
+	}
+}
+
+// readMessages reads the messages in data, which are complete messages as
+// sent over a socket, with the reference reader.
+func readMessages(t *testing.T, data ...[]byte) []Point {
+	t.Helper()
+	var points []Point
+	c := consumer.New("unused")
+	c.HandleAll(func(e *consumer.Event) error {
+		points = append(points, Point{Header: e.Header, Type: e.Type, Msg: e.Msg})
+		return nil
+	})
+	handler, err := c.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient(): %v", err)
+	}
+	for _, msg := range data {
+		if err := server.HandleMessage(handler, pb.Compression_COMPRESSION_NONE, wire.CurrentVersion, msg); err != nil {
+			t.Fatalf("HandleMessage(): %v", err)
+		}
+	}
+	return points
+}
+
+func TestReadMessage(t *testing.T) {
+	for _, g := range load(t) {
+		t.Run(g.Name, func(t *testing.T) {
+			if err := CheckRead([]*Golden{g}, readMessages(t, g.Frame)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestReadBatch(t *testing.T) {
+	goldens := load(t)
+	if err := CheckRead(goldens, readMessages(t, Batch(goldens))); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReadStream(t *testing.T) {
+	goldens := load(t)
+	r := wire.NewFrameReader(bytes.NewReader(Stream(goldens)), 1<<20)
+	var points []Point
+	for {
+		hdr, payload, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next(): %v", err)
+		}
+		typ := pb.MessageType(hdr.MessageType)
+		msg, err := consumer.Decode(typ, payload)
+		if err != nil {
+			t.Fatalf("Decode(%v): %v", typ, err)
+		}
+		points = append(points, Point{Header: hdr, Type: typ, Msg: msg})
+	}
+	if r.Skipped != 0 {
+		t.Errorf("%d bytes skipped", r.Skipped)
+	}
+	if err := CheckRead(goldens, points); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestCheckFrame checks that CheckFrame detects frames that don't match.
+func TestCheckFrame(t *testing.T) {
+	g := load(t)[0]
+	if err := CheckFrame(g, g.Frame, wire.CurrentVersion); err != nil {
+		t.Fatalf("CheckFrame(golden): %v", err)
+	}
+	if err := CheckFrame(g, g.Frame, wire.CurrentVersion-1); err == nil {
+		t.Errorf("CheckFrame() with another version succeeded")
+	}
+	changed := append([]byte(nil), g.Frame...)
+	changed[len(changed)-1]++
+	if err := CheckFrame(g, changed, wire.CurrentVersion); err == nil {
+		t.Errorf("CheckFrame() with a corrupted payload succeeded")
+	}
+	var hdr wire.Header
+	hdr.UnmarshalUnsafe(g.Frame)
+	hdr.MessageType++
+	changed = append([]byte(nil), g.Frame...)
+	hdr.MarshalUnsafe(changed)
+	if err := CheckFrame(g, changed, wire.CurrentVersion); err == nil {
+		t.Errorf("CheckFrame() with another message type succeeded")
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package consumer is a library to consume the events that the remote sink
+// sends, see remote.Remote. It listens on the sink's endpoint, parses the
+// framing with server.CommonServer, decodes the messages, and invokes the
+// handlers registered for their types.
+//
+// For example:
+//
+//	c := consumer.New("/tmp/gvisor_events.sock")
+//	c.Handle(pb.MessageType_MESSAGE_SYSCALL_OPEN, func(e *consumer.Event) error {
+//		fmt.Println(e.Msg.(*pb.Open).Pathname)
+//		return nil
+//	})
+//	if err := c.Start(); err != nil { ... }
+package consumer
+
+import (
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/server"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// Event is a decoded message received from a sandbox.
+type Event struct {
+	// Header is the header of the message.
+	Header wire.Header
+
+	// Type is the type of Msg.
+	Type pb.MessageType
+
+	// Msg is the decoded message, e.g. *pb.Open for
+	// MessageType_MESSAGE_SYSCALL_OPEN.
+	Msg proto.Message
+
+	// Client identifies the connection that the event was received from,
+	// starting at 1. Each sandbox connects once per session.
+	Client uint64
+}
+
+// HandlerFunc processes events. Errors are logged, and don't affect the
+// connection.
+type HandlerFunc func(e *Event) error
+
+// Consumer is a server that the remote sink connects to. Handlers must be
+// registered before Start is called.
+type Consumer struct {
+	server.CommonServer
+
+	// handlers are the handlers per message type.
+	handlers map[pb.MessageType][]HandlerFunc
+
+	// all are the handlers of all message types.
+	all []HandlerFunc
+
+	mu sync.Mutex
+
+	// +checklocks:mu
+	clients uint64
+}
+
+var _ server.ClientHandler = (*Consumer)(nil)
+
+// New returns a Consumer that listens on endpoint, see
+// server.CommonServer.Endpoint.
+func New(endpoint string) *Consumer {
+	c := &Consumer{handlers: make(map[pb.MessageType][]HandlerFunc)}
+	c.CommonServer.Init(endpoint, c)
+	return c
+}
+
+// Handle registers fn to be called for events of type t. Only the types with
+// handlers are requested from the sink, unless HandleAll is used.
+func (c *Consumer) Handle(t pb.MessageType, fn HandlerFunc) {
+	c.handlers[t] = append(c.handlers[t], fn)
+}
+
+// HandleAll registers fn to be called for events of all types.
+func (c *Consumer) HandleAll(fn HandlerFunc) {
+	c.all = append(c.all, fn)
+}
+
+// NewClient implements server.ClientHandler.
+func (c *Consumer) NewClient() (server.MessageHandler, error) {
+	c.mu.Lock()
+	c.clients++
+	id := c.clients
+	c.mu.Unlock()
+	return &client{consumer: c, id: id}, nil
+}
+
+// requestedTypes returns the message types that have handlers, or nil if all
+// types are handled.
+func (c *Consumer) requestedTypes() []pb.MessageType {
+	if len(c.all) > 0 {
+		return nil
+	}
+	types := make([]pb.MessageType, 0, len(c.handlers))
+	for t := range c.handlers {
+		types = append(types, t)
+	}
+	return types
+}
+
+func (c *Consumer) dispatch(e *Event) {
+	for _, fn := range c.handlers[e.Type] {
+		if err := fn(e); err != nil {
+			log.Warningf("Handler for %v failed: %v", e.Type, err)
+		}
+	}
+	for _, fn := range c.all {
+		if err := fn(e); err != nil {
+			log.Warningf("Handler for %v failed: %v", e.Type, err)
+		}
+	}
+}
+
+// client handles the messages of a connection to the Consumer.
+type client struct {
+	consumer *Consumer
+	id       uint64
+}
+
+var _ server.MessageHandler = (*client)(nil)
+var _ server.Negotiator = (*client)(nil)
+
+// Version implements server.MessageHandler.
+func (*client) Version() uint32 {
+	return wire.CurrentVersion
+}
+
+// Negotiate implements server.Negotiator.
+func (c *client) Negotiate(*pb.Handshake) ([]pb.MessageType, error) {
+	return c.consumer.requestedTypes(), nil
+}
+
+// Message implements server.MessageHandler. Messages that can't be decoded,
+// e.g. of types added after this package, are logged and skipped.
+func (c *client) Message(_ []byte, hdr wire.Header, payload []byte) error {
+	t := pb.MessageType(hdr.MessageType)
+	msg, err := Decode(t, payload)
+	if err != nil {
+		log.Warningf("Skipping message: %v", err)
+		return nil
+	}
+	c.consumer.dispatch(&Event{
+		Header: hdr,
+		Type:   t,
+		Msg:    msg,
+		Client: c.id,
+	})
+	return nil
+}
+
+// Close implements server.MessageHandler.
+func (*client) Close() {}
@@ -0,0 +1,98 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consumer
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestAllMessageTypes(t *testing.T) {
+	for num, name := range pb.MessageType_name {
+		typ := pb.MessageType(num)
+		if typ == pb.MessageType_MESSAGE_UNKNOWN {
+			continue
+		}
+		if _, err := NewMessage(typ); err != nil {
+			t.Errorf("NewMessage(%s): %v", name, err)
+		}
+	}
+	if _, err := NewMessage(pb.MessageType_MESSAGE_UNKNOWN); err == nil {
+		t.Errorf("NewMessage(MESSAGE_UNKNOWN) succeeded")
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	c := New("unused")
+	var opens, all []*Event
+	c.Handle(pb.MessageType_MESSAGE_SYSCALL_OPEN, func(e *Event) error {
+		opens = append(opens, e)
+		return nil
+	})
+	if got := c.requestedTypes(); len(got) != 1 || got[0] != pb.MessageType_MESSAGE_SYSCALL_OPEN {
+		t.Errorf("requestedTypes(): got %v, want [MESSAGE_SYSCALL_OPEN]", got)
+	}
+	c.HandleAll(func(e *Event) error {
+		all = append(all, e)
+		return nil
+	})
+	if got := c.requestedTypes(); got != nil {
+		t.Errorf("requestedTypes() with HandleAll: got %v, want nil", got)
+	}
+
+	handler, err := c.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient(): %v", err)
+	}
+	for _, msg := range []struct {
+		typ pb.MessageType
+		msg proto.Message
+	}{
+		{typ: pb.MessageType_MESSAGE_SYSCALL_OPEN, msg: &pb.Open{Pathname: "/foo"}},
+		{typ: pb.MessageType_MESSAGE_SYSCALL_CLOSE, msg: &pb.Close{Fd: 3}},
+	} {
+		payload, err := proto.Marshal(msg.msg)
+		if err != nil {
+			t.Fatalf("proto.Marshal(%v): %v", msg.msg, err)
+		}
+		hdr := wire.Header{MessageType: uint16(msg.typ)}
+		if err := handler.Message(nil, hdr, payload); err != nil {
+			t.Fatalf("Message(%v): %v", msg.typ, err)
+		}
+	}
+	// Unknown types are skipped.
+	if err := handler.Message(nil, wire.Header{MessageType: 0xffff}, nil); err != nil {
+		t.Fatalf("Message(unknown): %v", err)
+	}
+
+	if len(opens) != 1 {
+		t.Fatalf("got %d open events, want 1", len(opens))
+	}
+	if want := (&pb.Open{Pathname: "/foo"}); !proto.Equal(opens[0].Msg, want) {
+		t.Errorf("open event: got %v, want %v", opens[0].Msg, want)
+	}
+	if opens[0].Client != 1 {
+		t.Errorf("open event client: got %d, want 1", opens[0].Client)
+	}
+	if len(all) != 2 {
+		t.Fatalf("got %d events, want 2", len(all))
+	}
+	if all[1].Type != pb.MessageType_MESSAGE_SYSCALL_CLOSE {
+		t.Errorf("second event type: got %v, want MESSAGE_SYSCALL_CLOSE", all[1].Type)
+	}
+}
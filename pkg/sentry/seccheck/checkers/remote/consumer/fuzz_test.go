@@ -0,0 +1,225 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consumer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/server"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// maxFuzzFrameSize is the maximum frame size used to read fuzzed streams.
+const maxFuzzFrameSize = 64 << 10
+
+// fuzzMessage returns a message of type typ with payload, as written by the
+// remote sink. Messages without checksum are more useful to mutate, since
+// changes to their payload aren't rejected upfront.
+func fuzzMessage(typ uint16, payload []byte, checksum bool) []byte {
+	hdr := wire.Header{
+		HeaderSize:  wire.HeaderStructSize,
+		MessageType: typ,
+		Sequence:    1,
+		Version:     wire.CurrentVersion,
+	}
+	if checksum {
+		hdr.SetChecksum(payload)
+	}
+	out := make([]byte, wire.HeaderStructSize, wire.HeaderStructSize+len(payload))
+	hdr.MarshalUnsafe(out)
+	return append(out, payload...)
+}
+
+// fuzzBatch returns a batch with msgs.
+func fuzzBatch(msgs ...[]byte) []byte {
+	var payload []byte
+	for _, msg := range msgs {
+		payload = append(appendLength(payload, len(msg)), msg...)
+	}
+	return fuzzMessage(wire.BatchMessageType, payload, false)
+}
+
+// appendLength appends length as a little-endian uint32 to buf.
+func appendLength(buf []byte, length int) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(length))
+	return append(buf, b[:]...)
+}
+
+// fuzzFrame prefixes msg with its length, as sent on stream transports.
+func fuzzFrame(msg []byte) []byte {
+	return append(appendLength(nil, len(msg)), msg...)
+}
+
+// fuzzSeeds returns valid and malformed streams to start fuzzing from.
+func fuzzSeeds(t testing.TB) [][]byte {
+	payload, err := proto.Marshal(&pb.Open{
+		ContextData: &pb.ContextData{ThreadId: 1, ContainerId: "abc"},
+		Pathname:    "/etc/hosts",
+	})
+	if err != nil {
+		t.Fatalf("proto.Marshal(): %v", err)
+	}
+	compressed, err := wire.Compress(pb.Compression_COMPRESSION_GZIP, payload)
+	if err != nil {
+		t.Fatalf("Compress(): %v", err)
+	}
+	open := fuzzMessage(uint16(pb.MessageType_MESSAGE_SYSCALL_OPEN), payload, false)
+
+	// Header that claims to be larger than the message.
+	oversizedHeader := append([]byte(nil), open...)
+	binary.LittleEndian.PutUint16(oversizedHeader, 0xffff)
+
+	// Batch whose message length is larger than the batch.
+	oversizedBatch := fuzzMessage(wire.BatchMessageType, append(appendLength(nil, 0xffffffff), open...), false)
+
+	// Frame whose length is larger than the maximum.
+	oversizedFrame := fuzzFrame(open)
+	binary.LittleEndian.PutUint32(oversizedFrame, 0xffffffff)
+
+	return [][]byte{
+		open,
+		fuzzMessage(uint16(pb.MessageType_MESSAGE_SYSCALL_OPEN), payload, true),
+		fuzzMessage(uint16(pb.MessageType_MESSAGE_SYSCALL_OPEN), compressed, false),
+		fuzzMessage(0x1234, payload, false),
+		fuzzMessage(uint16(pb.MessageType_MESSAGE_SYSCALL_CLOSE), payload, false),
+		fuzzBatch(open, open),
+		fuzzBatch(fuzzBatch(open)),
+		fuzzFrame(open),
+		append(fuzzFrame(open), fuzzFrame(fuzzBatch(open))...),
+		open[:wire.HeaderMinSize],
+		open[:len(open)-1],
+		fuzzFrame(open)[:wire.FrameLengthSize+wire.HeaderStructSize],
+		oversizedHeader,
+		oversizedBatch,
+		oversizedFrame,
+		nil,
+	}
+}
+
+// checkStream feeds data to the consumer in every way that a sandbox can send
+// it, i.e. as a single message, with and without compression, and as a stream
+// of frames. Malformed data must be rejected with an error, and never crash
+// the consumer or produce events that don't match their type.
+func checkStream(t testing.TB, data []byte) {
+	c := New("unused")
+	c.HandleAll(func(e *Event) error {
+		want, err := NewMessage(e.Type)
+		if err != nil {
+			t.Errorf("event with unknown type %v", e.Type)
+			return nil
+		}
+		if got, want := e.Msg.ProtoReflect().Descriptor().FullName(), want.ProtoReflect().Descriptor().FullName(); got != want {
+			t.Errorf("event of type %v has message %s, want: %s", e.Type, got, want)
+		}
+		return nil
+	})
+	handler, err := c.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient(): %v", err)
+	}
+
+	for _, compression := range []pb.Compression{pb.Compression_COMPRESSION_NONE, pb.Compression_COMPRESSION_GZIP} {
+		// Errors are expected, the message is dropped.
+		_ = server.HandleMessage(handler, compression, wire.CurrentVersion, data)
+	}
+
+	r := wire.NewFrameReader(bytes.NewReader(data), maxFuzzFrameSize)
+	for {
+		hdr, payload, err := r.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			// Frames with unsupported versions are consumed, keep reading.
+			continue
+		}
+		if hdr.HeaderSize < wire.HeaderMinSize || int(hdr.HeaderSize)+len(payload) > maxFuzzFrameSize {
+			t.Errorf("invalid frame returned, header: %+v, payload size: %d", hdr, len(payload))
+		}
+		if err := handler.Message(nil, hdr, payload); err != nil {
+			t.Errorf("Message(): %v", err)
+		}
+	}
+	if r.Skipped > uint64(len(data)) {
+		t.Errorf("skipped %d bytes, more than the %d bytes in the stream", r.Skipped, len(data))
+	}
+}
+
+func TestStreamSeeds(t *testing.T) {
+	for _, seed := range fuzzSeeds(t) {
+		checkStream(t, seed)
+	}
+}
+
+func TestNestedBatch(t *testing.T) {
+	handler, err := New("unused").NewClient()
+	if err != nil {
+		t.Fatalf("NewClient(): %v", err)
+	}
+	open := fuzzMessage(uint16(pb.MessageType_MESSAGE_SYSCALL_OPEN), nil, false)
+	if err := server.HandleMessage(handler, pb.Compression_COMPRESSION_NONE, wire.CurrentVersion, fuzzBatch(fuzzBatch(open))); err == nil {
+		t.Errorf("HandleMessage() accepted a nested batch")
+	}
+}
+
+// TestStreamMutations runs checkStream with random mutations of the seeds. It
+// covers the same ground as FuzzStream with toolchains that don't support
+// fuzzing, using a fixed seed to be reproducible.
+func TestStreamMutations(t *testing.T) {
+	seeds := fuzzSeeds(t)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		data := append([]byte(nil), seeds[rng.Intn(len(seeds))]...)
+		for n := rng.Intn(4) + 1; n > 0; n-- {
+			data = mutate(rng, data)
+		}
+		checkStream(t, data)
+		if t.Failed() {
+			t.Fatalf("failed with input %x", data)
+		}
+	}
+}
+
+// mutate returns data with a random change: a flipped byte, a truncation, a
+// large length written at a random offset, or a repeated chunk.
+func mutate(rng *rand.Rand, data []byte) []byte {
+	if len(data) == 0 {
+		return append(data, byte(rng.Intn(256)))
+	}
+	switch rng.Intn(4) {
+	case 0:
+		data[rng.Intn(len(data))] ^= byte(1 << uint(rng.Intn(8)))
+	case 1:
+		data = data[:rng.Intn(len(data))]
+	case 2:
+		if len(data) >= 4 {
+			off := rng.Intn(len(data) - 3)
+			binary.LittleEndian.PutUint32(data[off:], uint32(rng.Int63n(1<<32)))
+		}
+	case 3:
+		start := rng.Intn(len(data))
+		end := start + rng.Intn(len(data)-start) + 1
+		data = append(data[:end:end], data[start:]...)
+	}
+	return data
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consumer
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// messages maps message types to constructors of their messages. It must be
+// kept in sync with MessageType in common.proto.
+var messages = map[pb.MessageType]func() proto.Message{
+	pb.MessageType_MESSAGE_CONTAINER_START:             func() proto.Message { return &pb.Start{} },
+	pb.MessageType_MESSAGE_SENTRY_CLONE:                func() proto.Message { return &pb.CloneInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_EXEC:                 func() proto.Message { return &pb.ExecveInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT:   func() proto.Message { return &pb.ExitNotifyParentInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_TASK_EXIT:            func() proto.Message { return &pb.TaskExit{} },
+	pb.MessageType_MESSAGE_SYSCALL_RAW:                 func() proto.Message { return &pb.Syscall{} },
+	pb.MessageType_MESSAGE_SYSCALL_OPEN:                func() proto.Message { return &pb.Open{} },
+	pb.MessageType_MESSAGE_SYSCALL_CLOSE:               func() proto.Message { return &pb.Close{} },
+	pb.MessageType_MESSAGE_SYSCALL_READ:                func() proto.Message { return &pb.Read{} },
+	pb.MessageType_MESSAGE_SYSCALL_CONNECT:             func() proto.Message { return &pb.Connect{} },
+	pb.MessageType_MESSAGE_SYSCALL_EXECVE:              func() proto.Message { return &pb.Execve{} },
+	pb.MessageType_MESSAGE_SYSCALL_SOCKET:              func() proto.Message { return &pb.Socket{} },
+	pb.MessageType_MESSAGE_SYSCALL_CHDIR:               func() proto.Message { return &pb.Chdir{} },
+	pb.MessageType_MESSAGE_SYSCALL_SETID:               func() proto.Message { return &pb.Setid{} },
+	pb.MessageType_MESSAGE_SYSCALL_SETRESID:            func() proto.Message { return &pb.Setresid{} },
+	pb.MessageType_MESSAGE_SYSCALL_PRLIMIT64:           func() proto.Message { return &pb.Prlimit{} },
+	pb.MessageType_MESSAGE_SYSCALL_PIPE:                func() proto.Message { return &pb.Pipe{} },
+	pb.MessageType_MESSAGE_SYSCALL_FCNTL:               func() proto.Message { return &pb.Fcntl{} },
+	pb.MessageType_MESSAGE_SYSCALL_DUP:                 func() proto.Message { return &pb.Dup{} },
+	pb.MessageType_MESSAGE_SYSCALL_SIGNALFD:            func() proto.Message { return &pb.Signalfd{} },
+	pb.MessageType_MESSAGE_SYSCALL_CHROOT:              func() proto.Message { return &pb.Chroot{} },
+	pb.MessageType_MESSAGE_SYSCALL_EVENTFD:             func() proto.Message { return &pb.Eventfd{} },
+	pb.MessageType_MESSAGE_SYSCALL_CLONE:               func() proto.Message { return &pb.Clone{} },
+	pb.MessageType_MESSAGE_SYSCALL_BIND:                func() proto.Message { return &pb.Bind{} },
+	pb.MessageType_MESSAGE_SYSCALL_ACCEPT:              func() proto.Message { return &pb.Accept{} },
+	pb.MessageType_MESSAGE_SYSCALL_TIMERFD_CREATE:      func() proto.Message { return &pb.TimerfdCreate{} },
+	pb.MessageType_MESSAGE_SYSCALL_TIMERFD_SETTIME:     func() proto.Message { return &pb.TimerfdSetTime{} },
+	pb.MessageType_MESSAGE_SYSCALL_TIMERFD_GETTIME:     func() proto.Message { return &pb.TimerfdGetTime{} },
+	pb.MessageType_MESSAGE_SYSCALL_FORK:                func() proto.Message { return &pb.Fork{} },
+	pb.MessageType_MESSAGE_SYSCALL_INOTIFY_INIT:        func() proto.Message { return &pb.InotifyInit{} },
+	pb.MessageType_MESSAGE_SYSCALL_INOTIFY_ADD_WATCH:   func() proto.Message { return &pb.InotifyAddWatch{} },
+	pb.MessageType_MESSAGE_SYSCALL_INOTIFY_RM_WATCH:    func() proto.Message { return &pb.InotifyRmWatch{} },
+	pb.MessageType_MESSAGE_SYSCALL_SOCKETPAIR:          func() proto.Message { return &pb.SocketPair{} },
+	pb.MessageType_MESSAGE_SYSCALL_SEMGET:              func() proto.Message { return &pb.Semget{} },
+	pb.MessageType_MESSAGE_SYSCALL_SEMOP:               func() proto.Message { return &pb.Semop{} },
+	pb.MessageType_MESSAGE_SYSCALL_MSGGET:              func() proto.Message { return &pb.Msgget{} },
+	pb.MessageType_MESSAGE_SYSCALL_MSGSND:              func() proto.Message { return &pb.Msgsnd{} },
+	pb.MessageType_MESSAGE_SYSCALL_MSGRCV:              func() proto.Message { return &pb.Msgrcv{} },
+	pb.MessageType_MESSAGE_SYSCALL_MQ_OPEN:             func() proto.Message { return &pb.MqOpen{} },
+	pb.MessageType_MESSAGE_SYSCALL_MQ_SEND:             func() proto.Message { return &pb.MqSend{} },
+	pb.MessageType_MESSAGE_SYSCALL_MQ_RECEIVE:          func() proto.Message { return &pb.MqReceive{} },
+	pb.MessageType_MESSAGE_SYSCALL_SHUTDOWN:            func() proto.Message { return &pb.Shutdown{} },
+	pb.MessageType_MESSAGE_SYSCALL_GETSOCKNAME:         func() proto.Message { return &pb.Getsockname{} },
+	pb.MessageType_MESSAGE_SYSCALL_GETPEERNAME:         func() proto.Message { return &pb.Getpeername{} },
+	pb.MessageType_MESSAGE_SYSCALL_SETSOCKOPT:          func() proto.Message { return &pb.Setsockopt{} },
+	pb.MessageType_MESSAGE_SYSCALL_GETSOCKOPT:          func() proto.Message { return &pb.Getsockopt{} },
+	pb.MessageType_MESSAGE_SYSCALL_FLOCK:               func() proto.Message { return &pb.Flock{} },
+	pb.MessageType_MESSAGE_SYSCALL_UTIMES:              func() proto.Message { return &pb.Utimes{} },
+	pb.MessageType_MESSAGE_SYSCALL_FALLOCATE:           func() proto.Message { return &pb.Fallocate{} },
+	pb.MessageType_MESSAGE_SYSCALL_FSYNC:               func() proto.Message { return &pb.Fsync{} },
+	pb.MessageType_MESSAGE_SYSCALL_SYNC:                func() proto.Message { return &pb.Sync{} },
+	pb.MessageType_MESSAGE_SYSCALL_MKNOD:               func() proto.Message { return &pb.Mknod{} },
+	pb.MessageType_MESSAGE_SYSCALL_ACCT:                func() proto.Message { return &pb.Acct{} },
+	pb.MessageType_MESSAGE_SYSCALL_PERSONALITY:         func() proto.Message { return &pb.Personality{} },
+	pb.MessageType_MESSAGE_SYSCALL_UNAME:               func() proto.Message { return &pb.Uname{} },
+	pb.MessageType_MESSAGE_SYSCALL_SYSINFO:             func() proto.Message { return &pb.Sysinfo{} },
+	pb.MessageType_MESSAGE_SENTRY_SIGNAL_DELIVER:       func() proto.Message { return &pb.SignalDeliverInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_OOM:                  func() proto.Message { return &pb.OOMInfo{} },
+	pb.MessageType_MESSAGE_CONTAINER_STOP:              func() proto.Message { return &pb.Stop{} },
+	pb.MessageType_MESSAGE_CONTAINER_PAUSE:             func() proto.Message { return &pb.Pause{} },
+	pb.MessageType_MESSAGE_CONTAINER_RESUME:            func() proto.Message { return &pb.Resume{} },
+	pb.MessageType_MESSAGE_CONTAINER_EXEC:              func() proto.Message { return &pb.Exec{} },
+	pb.MessageType_MESSAGE_SENTRY_CHECKPOINT:           func() proto.Message { return &pb.CheckpointInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_RESTORE:              func() proto.Message { return &pb.RestoreInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_CORE_DUMP:            func() proto.Message { return &pb.CoreDumpInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_SECCOMP:              func() proto.Message { return &pb.SeccompInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_CAPABILITY_DENIED:    func() proto.Message { return &pb.CapabilityDeniedInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_NAMESPACE_CREATE:     func() proto.Message { return &pb.NamespaceCreateInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_EXEC_MAP:             func() proto.Message { return &pb.ExecMapInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_SYNTHETIC_FILE_WRITE: func() proto.Message { return &pb.SyntheticFileWriteInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_TCP_ESTABLISHED:      func() proto.Message { return &pb.TCPEstablishedInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_DNS_QUERY:            func() proto.Message { return &pb.DNSQueryInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_LISTEN:               func() proto.Message { return &pb.ListenInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_GOFER_OP:             func() proto.Message { return &pb.GoferOpInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_FILE_OPEN:            func() proto.Message { return &pb.FileOpenInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_TTY_DATA:             func() proto.Message { return &pb.TTYDataInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_MAJOR_FAULT:          func() proto.Message { return &pb.MajorFaultInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_RLIMIT_BREACH:        func() proto.Message { return &pb.RLimitBreachInfo{} },
+	pb.MessageType_MESSAGE_SENTRY_CPU_THROTTLE:         func() proto.Message { return &pb.CPUThrottleInfo{} },
+	pb.MessageType_MESSAGE_DROP_STATS:                  func() proto.Message { return &pb.DropStats{} },
+	pb.MessageType_MESSAGE_SESSION_CLOSED:              func() proto.Message { return &pb.SessionClosed{} },
+	pb.MessageType_MESSAGE_SYSCALL_UNLINK:              func() proto.Message { return &pb.Unlink{} },
+	pb.MessageType_MESSAGE_SYSCALL_RENAME:              func() proto.Message { return &pb.Rename{} },
+	pb.MessageType_MESSAGE_SYSCALL_SENDTO:              func() proto.Message { return &pb.Sendto{} },
+	pb.MessageType_MESSAGE_SENTRY_POLICY_VIOLATION:     func() proto.Message { return &pb.PolicyViolationInfo{} },
+	pb.MessageType_MESSAGE_SYSCALL_PTRACE:              func() proto.Message { return &pb.Ptrace{} },
+	pb.MessageType_MESSAGE_SENTRY_FILELESS_EXEC:        func() proto.Message { return &pb.FilelessExecInfo{} },
+	pb.MessageType_MESSAGE_SYSCALL_MOUNT:               func() proto.Message { return &pb.Mount{} },
+	pb.MessageType_MESSAGE_SYSCALL_UMOUNT:              func() proto.Message { return &pb.Umount{} },
+	pb.MessageType_MESSAGE_SYSCALL_PIVOT_ROOT:          func() proto.Message { return &pb.PivotRoot{} },
+	pb.MessageType_MESSAGE_SENTRY_ANOMALY_ALERT:        func() proto.Message { return &pb.AnomalyAlertInfo{} },
+	pb.MessageType_MESSAGE_SYSCALL_MMAP:                func() proto.Message { return &pb.Mmap{} },
+	pb.MessageType_MESSAGE_SYSCALL_MPROTECT:            func() proto.Message { return &pb.Mprotect{} },
+	pb.MessageType_MESSAGE_SENTRY_DRIFT:                func() proto.Message { return &pb.DriftInfo{} },
+	pb.MessageType_MESSAGE_SYSCALL_WRITE:               func() proto.Message { return &pb.Write{} },
+	pb.MessageType_MESSAGE_SYSCALL_SENDMSG:             func() proto.Message { return &pb.Sendmsg{} },
+}
+
+// NewMessage returns an empty message of type t, to unmarshal payloads into.
+func NewMessage(t pb.MessageType) (proto.Message, error) {
+	newMsg, ok := messages[t]
+	if !ok {
+		return nil, fmt.Errorf("unknown message type %v", t)
+	}
+	return newMsg(), nil
+}
+
+// Decode unmarshals payload, which is encoded with protobuf, into a message of
+// type t.
+func Decode(t pb.MessageType, payload []byte) (proto.Message, error) {
+	msg, err := NewMessage(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("unmarshalling %v: %w", t, err)
+	}
+	return msg, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+const countName = "count"
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     countName,
+		New:      newCount,
+		Validate: validateFilters,
+	})
+}
+
+// newCount creates a new checker that only counts points and their serialized
+// size, without serializing or sending them. It's used to measure the overhead
+// of collecting points, independently of the sink.
+func newCount(config map[string]interface{}, _ *fd.FD) (seccheck.Checker, error) {
+	sampler, err := parseSampleRates(config)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFilters(config)
+	if err != nil {
+		return nil, err
+	}
+	return &remote{fields: fields, sampler: sampler, sender: &pointCounter{}}, nil
+}
+
+// pointCounter counts the points it's sent.
+type pointCounter struct {
+	points atomicbitops.Uint64
+	bytes  atomicbitops.Uint64
+}
+
+var _ sender = (*pointCounter)(nil)
+var _ statusReporter = (*pointCounter)(nil)
+
+// name implements sender.
+func (*pointCounter) name() string {
+	return countName
+}
+
+// send implements sender.
+func (c *pointCounter) send(_ *remote, msg proto.Message, _ pb.MessageType) error {
+	c.points.Add(1)
+	c.bytes.Add(uint64(proto.Size(msg)))
+	return nil
+}
+
+// stop implements sender.
+func (c *pointCounter) stop() {
+	log.Infof("Count sink stopped, points: %d, bytes: %d", c.points.Load(), c.bytes.Load())
+}
+
+// status implements statusReporter.
+func (c *pointCounter) status(status *seccheck.CheckerStatus) {
+	status.PointCount = c.points.Load()
+	status.ByteCount = c.bytes.Load()
+}
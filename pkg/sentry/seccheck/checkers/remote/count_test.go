@@ -0,0 +1,45 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestCount(t *testing.T) {
+	r, err := newCount(nil, nil)
+	if err != nil {
+		t.Fatalf("newCount(): %v", err)
+	}
+	defer r.Stop()
+
+	infos := []*pb.ExitNotifyParentInfo{{ExitStatus: 123}, {ExitStatus: 456}}
+	var size uint64
+	for _, info := range infos {
+		if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+			t.Fatalf("ExitNotifyParent: %v", err)
+		}
+		size += uint64(proto.Size(info))
+	}
+	want := seccheck.CheckerStatus{PointCount: uint64(len(infos)), ByteCount: size}
+	if got := r.Status(); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong status, want: %+v, got: %+v", want, got)
+	}
+}
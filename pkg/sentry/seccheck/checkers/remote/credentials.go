@@ -0,0 +1,97 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Credential references, see readCredential.
+const (
+	credentialFilePrefix = "file:"
+	credentialFDPrefix   = "fd:"
+
+	// maxCredentialSize is the maximum size of a credential.
+	maxCredentialSize = 1 << 20
+)
+
+// parseCredential returns the contents of the credential referenced by the
+// configuration called name, or nil if it's not set.
+func parseCredential(config map[string]interface{}, name string) ([]byte, error) {
+	ref, err := parseConfigString(config, name)
+	if err != nil || ref == "" {
+		return nil, err
+	}
+	data, err := readCredential(ref)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// readCredential returns the contents of the credential referenced by ref.
+//
+// Credentials, e.g. TLS private keys, are referenced from sink configurations
+// instead of being embedded in them, so that configurations can be stored
+// safely, e.g. in version control. They are read by runsc when the sink is set
+// up, outside of the sandbox. A reference is one of:
+//
+//	file:<path>: the file at path.
+//	fd:<n>:      file descriptor n, inherited by runsc from its caller, e.g. a
+//	             memfd filled by a secret manager. It must support pread(2),
+//	             so that it can be read by more than one sink.
+//	<path>:      same as file:<path>.
+//
+// Inline secrets, e.g. PEM blocks, are rejected.
+func readCredential(ref string) ([]byte, error) {
+	if strings.Contains(ref, "\n") || strings.HasPrefix(ref, "-----BEGIN") {
+		return nil, fmt.Errorf("inline credentials are not supported, reference a file or fd instead")
+	}
+	var f *os.File
+	if fdStr := strings.TrimPrefix(ref, credentialFDPrefix); fdStr != ref {
+		n, err := strconv.Atoi(fdStr)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid fd %q", fdStr)
+		}
+		// The FD is duplicated because it may be referenced again, by this or
+		// other sinks.
+		dup, err := unix.FcntlInt(uintptr(n), unix.F_DUPFD_CLOEXEC, 0)
+		if err != nil {
+			return nil, fmt.Errorf("fd %d: %w", n, err)
+		}
+		f = os.NewFile(uintptr(dup), ref)
+	} else {
+		var err error
+		if f, err = os.Open(strings.TrimPrefix(ref, credentialFilePrefix)); err != nil {
+			return nil, err
+		}
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, maxCredentialSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxCredentialSize {
+		return nil, fmt.Errorf("%q is larger than %d bytes", ref, maxCredentialSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+const debugLogName = "log"
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     debugLogName,
+		New:      newDebugLog,
+		Validate: validateFilters,
+	})
+}
+
+// newDebugLog creates a new checker that writes points to the sentry debug
+// log. It doesn't need an endpoint, so it's meant to quickly see what a
+// workload is doing, without a process to consume points.
+func newDebugLog(config map[string]interface{}, _ *fd.FD) (seccheck.Checker, error) {
+	sampler, err := parseSampleRates(config)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFilters(config)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Debug log sink created")
+	return &remote{fields: fields, sampler: sampler, sender: debugLogWriter{}}, nil
+}
+
+// debugLogWriter writes each point to the debug log as a line of JSON.
+type debugLogWriter struct{}
+
+var _ sender = debugLogWriter{}
+
+// name implements sender.
+func (debugLogWriter) name() string {
+	return debugLogName
+}
+
+// send implements sender.
+func (debugLogWriter) send(r *remote, msg proto.Message, msgType pb.MessageType) error {
+	point, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		log.Debugf("Encoding %+v for %s sink: %v", msg, debugLogName, err)
+		return err
+	}
+	log.Infof("Trace point %s: %s", msgType, point)
+	return nil
+}
+
+// stop implements sender.
+func (debugLogWriter) stop() {}
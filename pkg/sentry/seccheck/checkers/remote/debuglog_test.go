@@ -0,0 +1,64 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// logRecorder is a log.Emitter that records log lines.
+type logRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *logRecorder) Emit(_ int, _ log.Level, _ time.Time, format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestDebugLog(t *testing.T) {
+	rec := &logRecorder{}
+	prev := log.Log().Emitter
+	log.SetTarget(rec)
+	defer log.SetTarget(prev)
+
+	r, err := newDebugLog(map[string]interface{}{}, nil)
+	if err != nil {
+		t.Fatalf("newDebugLog(): %v", err)
+	}
+	defer r.Stop()
+	if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, &pb.ExitNotifyParentInfo{ExitStatus: 123}); err != nil {
+		t.Fatalf("ExitNotifyParent: %v", err)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	for _, line := range rec.lines {
+		if strings.Contains(line, pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT.String()) && strings.Contains(line, `"exit_status":123`) {
+			return
+		}
+	}
+	t.Errorf("point not found in log: %q", rec.lines)
+}
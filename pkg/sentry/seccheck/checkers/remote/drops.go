@@ -0,0 +1,133 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// dropStats counts the points dropped per message type since the last
+// DropStats message was sent.
+type dropStats struct {
+	mu sync.Mutex
+
+	// +checklocks:mu
+	counts map[pb.MessageType]uint64
+
+	// since is when counting started.
+	//
+	// +checklocks:mu
+	since time.Time
+
+	// stop is closed to stop reportDrops, which closes done when it returns.
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (d *dropStats) add(msgType pb.MessageType, count uint32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.counts[msgType] += uint64(count)
+}
+
+// take returns the drops counted so far and starts counting again. It returns
+// nil if there were no drops.
+func (d *dropStats) take() *pb.DropStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	if len(d.counts) == 0 {
+		d.since = now
+		return nil
+	}
+	stats := &pb.DropStats{
+		StartTimeNs: d.since.UnixNano(),
+		EndTimeNs:   now.UnixNano(),
+		Drops:       make([]*pb.DropCount, 0, len(d.counts)),
+	}
+	for t, count := range d.counts {
+		stats.Drops = append(stats.Drops, &pb.DropCount{MessageType: t, Count: count})
+	}
+	sort.Slice(stats.Drops, func(i, j int) bool {
+		return stats.Drops[i].MessageType < stats.Drops[j].MessageType
+	})
+	d.counts = make(map[pb.MessageType]uint64)
+	d.since = now
+	return stats
+}
+
+// parseDropStatsInterval returns the "drop_stats_interval" configuration, or 0
+// if it's not set.
+func parseDropStatsInterval(config map[string]interface{}) (time.Duration, error) {
+	ok, interval, err := parseDuration(config, "drop_stats_interval")
+	if err != nil || !ok {
+		return 0, err
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("drop_stats_interval %v must be positive", interval)
+	}
+	return interval, nil
+}
+
+// startDropStats starts sending DropStats messages every interval. It must be
+// called once r is ready to send points.
+func (r *remote) startDropStats(interval time.Duration) {
+	r.drops = &dropStats{
+		counts: make(map[pb.MessageType]uint64),
+		since:  time.Now(),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go r.reportDrops(interval) // S/R-SAFE: sinks are not saved.
+}
+
+// reportDrops sends a DropStats message every interval, until r.drops.stop is
+// closed. DropStats messages go through the same path as points, so they can
+// be filtered out by the remote, or dropped themselves.
+func (r *remote) reportDrops(interval time.Duration) {
+	defer close(r.drops.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.drops.stop:
+			return
+		case <-ticker.C:
+			if stats := r.drops.take(); stats != nil {
+				_ = r.write(stats, pb.MessageType_MESSAGE_DROP_STATS)
+			}
+		}
+	}
+}
+
+// dropped accounts for count points of type msgType that were dropped.
+func (r *remote) dropped(msgType pb.MessageType, count uint32) {
+	r.droppedCount.Add(count)
+	if r.drops != nil {
+		r.drops.add(msgType, count)
+	}
+}
+
+// droppedEntries accounts for queued points that were dropped.
+func (r *remote) droppedEntries(entries []ringEntry) {
+	for _, e := range entries {
+		r.dropped(e.msgType, 1)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+const falcoName = "falco"
+
+// falcoPriorities maps syslog severities, see syslogSeverities, to Falco
+// priorities.
+var falcoPriorities = map[int]string{
+	syslogSeverityWarning: "Warning",
+	syslogSeverityNotice:  "Notice",
+	syslogSeverityInfo:    "Informational",
+}
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     falcoName,
+		Setup:    setupFalco,
+		New:      newFalco,
+		Validate: validateFilters,
+	})
+}
+
+// setupFalco opens the file that events are appended to, see setupFileSink.
+// The sandbox doesn't know the host's name, so it's added to config if not set.
+// The caller is responsible to close to file.
+func setupFalco(config map[string]interface{}) (*os.File, error) {
+	hostname, err := parseConfigString(config, "hostname")
+	if err != nil {
+		return nil, err
+	}
+	if hostname == "" {
+		if hostname, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("getting hostname: %w", err)
+		}
+		config["hostname"] = hostname
+	}
+	return setupFileSink(config)
+}
+
+// newFalco creates a new checker that writes points in the format of Falco's
+// JSON output, one event per line. Tools that consume Falco alerts, e.g.
+// falcosidekick, can then be used with gVisor sandboxes, by tailing the file or
+// pointing path to a FIFO they read from.
+func newFalco(config map[string]interface{}, endpoint *fd.FD) (seccheck.Checker, error) {
+	if endpoint == nil {
+		return nil, fmt.Errorf("falco sink requires an endpoint")
+	}
+	sampler, err := parseSampleRates(config)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFilters(config)
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := parseConfigString(config, "hostname")
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Falco sink created, endpoint FD: %d", endpoint.FD())
+	return &remote{fields: fields, sampler: sampler, sender: &lineWriter{
+		sinkName: falcoName,
+		encode:   falcoEncoder{hostname: hostname}.encode,
+		endpoint: endpoint,
+	}}, nil
+}
+
+// falcoEvent is an event in Falco's output schema.
+type falcoEvent struct {
+	Time         string                 `json:"time"`
+	Rule         string                 `json:"rule"`
+	Priority     string                 `json:"priority"`
+	Source       string                 `json:"source"`
+	Output       string                 `json:"output"`
+	OutputFields map[string]interface{} `json:"output_fields"`
+	Hostname     string                 `json:"hostname,omitempty"`
+	Tags         []string               `json:"tags"`
+}
+
+// falcoEncoder renders points as Falco events.
+type falcoEncoder struct {
+	hostname string
+}
+
+// encode renders a point as a Falco event, e.g.:
+//
+//	{"time":"...","rule":"MESSAGE_SYSCALL_OPEN","priority":"Informational",
+//	 "source":"syscall","output":"open proc.name=cat proc.pid=7 ...",
+//	 "output_fields":{"evt.type":"open","proc.pid":7,...},"hostname":"...",
+//	 "tags":["gvisor"]}
+//
+// Context data is mapped to the equivalent Falco fields when present, and the
+// complete point is included as JSON in the gvisor.point field. Since there
+// are no Falco rules involved, the rule is the point's message type.
+func (e falcoEncoder) encode(msg proto.Message, msgType pb.MessageType, droppedCount uint32) ([]byte, error) {
+	point, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	severity, ok := syslogSeverities[msgType]
+	if !ok {
+		severity = syslogSeverityInfo
+	}
+	ts := time.Now()
+
+	evtType := strings.ToLower(msgType.String())
+	for _, prefix := range []string{"message_syscall_", "message_sentry_", "message_"} {
+		if strings.HasPrefix(evtType, prefix) {
+			evtType = evtType[len(prefix):]
+			break
+		}
+	}
+	fields := map[string]interface{}{
+		"evt.type":             evtType,
+		"gvisor.dropped_count": droppedCount,
+		"gvisor.point":         json.RawMessage(point),
+	}
+	output := []string{evtType}
+	add := func(name string, value interface{}) {
+		fields[name] = value
+		output = append(output, fmt.Sprintf("%s=%v", name, value))
+	}
+	if ctx := contextData(msg); ctx != nil {
+		if ctx.TimeNs != 0 {
+			ts = time.Unix(0, ctx.TimeNs)
+		}
+		if ctx.ProcessName != "" {
+			add("proc.name", ctx.ProcessName)
+		}
+		if ctx.ThreadGroupId != 0 {
+			add("proc.pid", ctx.ThreadGroupId)
+		}
+		if ctx.ThreadId != 0 {
+			add("thread.tid", ctx.ThreadId)
+		}
+		if ctx.Cwd != "" {
+			add("proc.cwd", ctx.Cwd)
+		}
+		if creds := ctx.Credentials; creds != nil {
+			add("user.uid", creds.EffectiveUid)
+			add("group.gid", creds.EffectiveGid)
+		}
+		if ctx.ContainerId != "" {
+			add("container.id", ctx.ContainerId)
+		}
+	}
+	fields["evt.time"] = ts.UnixNano()
+
+	out, err := json.Marshal(falcoEvent{
+		Time:         ts.UTC().Format(time.RFC3339Nano),
+		Rule:         msgType.String(),
+		Priority:     falcoPriorities[severity],
+		Source:       "syscall",
+		Output:       strings.Join(output, " "),
+		OutputFields: fields,
+		Hostname:     e.hostname,
+		Tags:         []string{"gvisor"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestFalco(t *testing.T) {
+	info := &pb.ExitNotifyParentInfo{
+		ContextData: &pb.ContextData{
+			TimeNs:        5_000_000,
+			ThreadGroupId: 7,
+			ProcessName:   "cat",
+			ContainerId:   "abc",
+		},
+		ExitStatus: 123,
+	}
+	line, err := falcoEncoder{hostname: "host"}.encode(info, pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT, 2)
+	if err != nil {
+		t.Fatalf("encode(): %v", err)
+	}
+	if !bytes.HasSuffix(line, []byte("\n")) || bytes.Count(line, []byte("\n")) != 1 {
+		t.Errorf("event must be a single line: %q", line)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(line, &got); err != nil {
+		t.Fatalf("json.Unmarshal(%q): %v", line, err)
+	}
+	for key, want := range map[string]interface{}{
+		"time":     "1970-01-01T00:00:00.005Z",
+		"rule":     "MESSAGE_SENTRY_EXIT_NOTIFY_PARENT",
+		"priority": "Informational",
+		"source":   "syscall",
+		"output":   "exit_notify_parent proc.name=cat proc.pid=7 container.id=abc",
+		"hostname": "host",
+	} {
+		if got[key] != want {
+			t.Errorf("wrong %q, want: %v, got: %v", key, want, got[key])
+		}
+	}
+	fields, ok := got["output_fields"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("output_fields missing: %q", line)
+	}
+	for key, want := range map[string]interface{}{
+		"evt.type":             "exit_notify_parent",
+		"evt.time":             float64(5_000_000),
+		"proc.pid":             float64(7),
+		"container.id":         "abc",
+		"gvisor.dropped_count": float64(2),
+	} {
+		if fields[key] != want {
+			t.Errorf("wrong output field %q, want: %v, got: %v", key, want, fields[key])
+		}
+	}
+	if point, ok := fields["gvisor.point"].(map[string]interface{}); !ok || point["exit_status"] != float64(123) {
+		t.Errorf("wrong gvisor.point: %v", fields["gvisor.point"])
+	}
+}
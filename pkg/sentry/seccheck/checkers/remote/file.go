@@ -0,0 +1,91 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"fmt"
+	"os"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// setupFileSink opens the file that a line-oriented sink appends points to.
+// The caller is responsible to close to file.
+func setupFileSink(config map[string]interface{}) (*os.File, error) {
+	pathOpaque, ok := config["path"]
+	if !ok {
+		return nil, fmt.Errorf("path not present in configuration")
+	}
+	path, ok := pathOpaque.(string)
+	if !ok {
+		return nil, fmt.Errorf("path %q is not a string", pathOpaque)
+	}
+	return os.OpenFile(path, os.O_CREAT|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// lineEncoder renders a point as a single line of text, including the trailing
+// newline. Encoders for message oriented endpoints, e.g. syslog, may omit the
+// newline.
+type lineEncoder func(msg proto.Message, msgType pb.MessageType, droppedCount uint32) ([]byte, error)
+
+// lineWriter writes each point as a line of text to a file, or as a message to
+// a socket, with a single write.
+type lineWriter struct {
+	sinkName string
+	encode   lineEncoder
+
+	// mu serializes writes, so that lines don't interleave.
+	mu sync.Mutex
+
+	// +checklocks:mu
+	endpoint *fd.FD
+}
+
+var _ sender = (*lineWriter)(nil)
+
+// name implements sender.
+func (w *lineWriter) name() string {
+	return w.sinkName
+}
+
+// send implements sender.
+func (w *lineWriter) send(r *remote, msg proto.Message, msgType pb.MessageType) error {
+	line, err := w.encode(msg, msgType, r.droppedCount.Load())
+	if err != nil {
+		log.Debugf("Encoding %+v for %s sink: %v", msg, w.sinkName, err)
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.endpoint.Write(line); err != nil {
+		log.Debugf("Write failed, dropping point: %v", err)
+		r.setError(err)
+		r.droppedCount.Add(1)
+		return err
+	}
+	return nil
+}
+
+// stop implements sender.
+func (w *lineWriter) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.endpoint.Close()
+}
@@ -0,0 +1,277 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+)
+
+// pathFields are the names of the fields that hold paths in point messages,
+// e.g. Open.pathname or ExecveInfo.binary_path.
+var pathFields = []protoreflect.Name{
+	"pathname",
+	"fd_path",
+	"path",
+	"binary_path",
+	"executable_path",
+}
+
+// fdTypes maps the fd types accepted in the "fd_types" filter to the prefix of
+// the fd_path of such FDs. Regular files are the FDs with absolute paths.
+var fdTypes = map[string]string{
+	"file":       "/",
+	"socket":     "socket:[",
+	"pipe":       "pipe:[",
+	"anon_inode": "anon_inode:",
+}
+
+// fieldFilter drops points based on the value of their fields. It's applied by
+// each sink after the message type filter. Each condition only applies to
+// points that have the field it inspects set, e.g. path filters don't affect
+// clone points.
+type fieldFilter struct {
+	// pathPrefixes keeps points where any of the path fields is under one of
+	// the prefixes.
+	pathPrefixes []string
+
+	// excludePathPrefixes drops points where any of the path fields is under
+	// one of the prefixes.
+	excludePathPrefixes []string
+
+	// processNames keeps points where the process name contains one of the
+	// substrings. It requires the "process_name" context field.
+	processNames []string
+
+	// argv keeps points where any of the arguments contains one of the
+	// substrings.
+	argv []string
+
+	// fdTypes keeps points where fd_path is of one of the types, see fdTypes.
+	fdTypes []string
+
+	// filteredOut is the number of points that were not kept.
+	filteredOut atomicbitops.Uint64
+}
+
+// parseFilters returns a fieldFilter for the "filters" configuration, or nil if
+// it's not set, e.g.:
+//
+//	"filters": {
+//	  "path_prefixes": ["/etc", "/var/run/secrets"],
+//	  "exclude_path_prefixes": ["/etc/ld.so.cache"],
+//	  "process_names": ["python"],
+//	  "argv": ["--token"],
+//	  "fd_types": ["file", "socket"]
+//	}
+func parseFilters(config map[string]interface{}) (*fieldFilter, error) {
+	opaque, ok := config["filters"]
+	if !ok {
+		return nil, nil
+	}
+	filters, ok := opaque.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("filters %v is not an object", opaque)
+	}
+	f := &fieldFilter{}
+	for name, opaque := range filters {
+		values, err := parseStrings(name, opaque)
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "path_prefixes":
+			f.pathPrefixes = values
+		case "exclude_path_prefixes":
+			f.excludePathPrefixes = values
+		case "process_names":
+			f.processNames = values
+		case "argv":
+			f.argv = values
+		case "fd_types":
+			for _, value := range values {
+				if _, ok := fdTypes[value]; !ok {
+					return nil, fmt.Errorf("invalid fd type %q, must be one of %v", value, fdTypeNames())
+				}
+			}
+			f.fdTypes = values
+		default:
+			return nil, fmt.Errorf("invalid filter %q", name)
+		}
+	}
+	return f, nil
+}
+
+// validateFilters checks the configuration that selects which points are sent,
+// which is common to all sinks. It implements seccheck.SinkDesc.Validate.
+func validateFilters(config map[string]interface{}) error {
+	if _, err := parseMessageTypes(config); err != nil {
+		return err
+	}
+	if _, err := parseFilters(config); err != nil {
+		return err
+	}
+	if _, err := parseSampleRates(config); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseStrings returns the list of non-empty strings in opaque.
+func parseStrings(name string, opaque interface{}) ([]string, error) {
+	list, ok := opaque.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("filter %s %v is not a list", name, opaque)
+	}
+	values := make([]string, 0, len(list))
+	for _, opaque := range list {
+		value, ok := opaque.(string)
+		if !ok || len(value) == 0 {
+			return nil, fmt.Errorf("filter %s value %v is not a non-empty string", name, opaque)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func fdTypeNames() []string {
+	names := make([]string, 0, len(fdTypes))
+	for name := range fdTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// keep returns true if msg passes the filter. A nil *fieldFilter keeps all
+// points.
+func (f *fieldFilter) keep(msg proto.Message) bool {
+	if f == nil {
+		return true
+	}
+	if f.matches(msg.ProtoReflect()) {
+		return true
+	}
+	f.filteredOut.Add(1)
+	return false
+}
+
+func (f *fieldFilter) matches(msg protoreflect.Message) bool {
+	fields := msg.Descriptor().Fields()
+	if len(f.pathPrefixes) > 0 || len(f.excludePathPrefixes) > 0 {
+		var paths []string
+		for _, name := range pathFields {
+			if path := stringField(msg, fields.ByName(name)); len(path) > 0 {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) > 0 {
+			if len(f.pathPrefixes) > 0 && !underAny(paths, f.pathPrefixes) {
+				return false
+			}
+			if underAny(paths, f.excludePathPrefixes) {
+				return false
+			}
+		}
+	}
+	if len(f.fdTypes) > 0 {
+		if path := stringField(msg, fields.ByName("fd_path")); len(path) > 0 && !isFDType(path, f.fdTypes) {
+			return false
+		}
+	}
+	if len(f.processNames) > 0 {
+		if field := fields.ByName("context_data"); field != nil && field.Kind() == protoreflect.MessageKind && msg.Has(field) {
+			ctx := msg.Get(field).Message()
+			name := stringField(ctx, ctx.Descriptor().Fields().ByName("process_name"))
+			if len(name) > 0 && !containsAny(name, f.processNames) {
+				return false
+			}
+		}
+	}
+	if len(f.argv) > 0 {
+		if field := fields.ByName("argv"); field != nil && field.IsList() && field.Kind() == protoreflect.StringKind {
+			if list := msg.Get(field).List(); list.Len() > 0 {
+				found := false
+				for i := 0; i < list.Len() && !found; i++ {
+					found = containsAny(list.Get(i).String(), f.argv)
+				}
+				if !found {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// stringField returns the value of field in msg, or "" if field is not a
+// singular string field.
+func stringField(msg protoreflect.Message, field protoreflect.FieldDescriptor) string {
+	if field == nil || field.IsList() || field.Kind() != protoreflect.StringKind {
+		return ""
+	}
+	return msg.Get(field).String()
+}
+
+// underAny returns true if any of paths is equal to or under one of prefixes.
+// Prefixes match whole path components, e.g. "/etc" matches "/etc/passwd" but
+// not "/etcd".
+func underAny(paths, prefixes []string) bool {
+	for _, path := range paths {
+		for _, prefix := range prefixes {
+			if path == prefix {
+				return true
+			}
+			if strings.HasPrefix(path, prefix) && (strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/') {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func isFDType(path string, types []string) bool {
+	for _, t := range types {
+		if strings.HasPrefix(path, fdTypes[t]) {
+			return true
+		}
+	}
+	return false
+}
+
+// status reports the number of points that were not kept as a metric.
+func (f *fieldFilter) status(status *seccheck.CheckerStatus) {
+	status.Metrics = append(status.Metrics, seccheck.MetricSample{
+		Family: "runsc_trace_filtered_out_points_total",
+		Type:   "counter",
+		Name:   "runsc_trace_filtered_out_points_total",
+		Value:  f.filteredOut.Load(),
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestFilters(t *testing.T) {
+	config := map[string]interface{}{
+		"filters": map[string]interface{}{
+			"path_prefixes":         []interface{}{"/etc", "/var/run/secrets/"},
+			"exclude_path_prefixes": []interface{}{"/etc/ld.so.cache"},
+			"process_names":         []interface{}{"python"},
+			"argv":                  []interface{}{"--token"},
+			"fd_types":              []interface{}{"file", "socket"},
+		},
+	}
+	checker, err := newCount(config, nil)
+	if err != nil {
+		t.Fatalf("newCount(): %v", err)
+	}
+	r := checker.(*remote)
+
+	python := &pb.ContextData{ProcessName: "python3"}
+	for _, tc := range []struct {
+		name string
+		msg  proto.Message
+		keep bool
+	}{
+		{
+			name: "no-fields",
+			msg:  &pb.Syscall{},
+			keep: true,
+		},
+		{
+			name: "path",
+			msg:  &pb.Open{Pathname: "/etc/passwd"},
+			keep: true,
+		},
+		{
+			name: "path-prefix",
+			msg:  &pb.Open{Pathname: "/etc"},
+			keep: true,
+		},
+		{
+			name: "path-trailing-slash",
+			msg:  &pb.Open{Pathname: "/var/run/secrets/token"},
+			keep: true,
+		},
+		{
+			name: "path-component",
+			msg:  &pb.Open{Pathname: "/etcd/config"},
+		},
+		{
+			name: "path-other",
+			msg:  &pb.Open{Pathname: "/tmp/foo"},
+		},
+		{
+			name: "path-excluded",
+			msg:  &pb.Open{Pathname: "/etc/ld.so.cache"},
+		},
+		{
+			name: "path-any",
+			msg:  &pb.Open{FdPath: "/etc", Pathname: "passwd"},
+			keep: true,
+		},
+		{
+			name: "process-name",
+			msg:  &pb.Open{ContextData: python, Pathname: "/etc/hosts"},
+			keep: true,
+		},
+		{
+			name: "process-name-other",
+			msg:  &pb.Open{ContextData: &pb.ContextData{ProcessName: "bash"}, Pathname: "/etc/hosts"},
+		},
+		{
+			name: "argv",
+			msg:  &pb.Execve{ContextData: python, Pathname: "/etc/script", Argv: []string{"script", "--token=foo"}},
+			keep: true,
+		},
+		{
+			name: "argv-other",
+			msg:  &pb.Execve{ContextData: python, Pathname: "/etc/script", Argv: []string{"script"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := r.fields.keep(tc.msg); got != tc.keep {
+				t.Errorf("keep(%+v), want: %t, got: %t", tc.msg, tc.keep, got)
+			}
+		})
+	}
+
+	r.write(&pb.Open{Pathname: "/etc/passwd"}, pb.MessageType_MESSAGE_SYSCALL_OPEN)
+	r.write(&pb.Open{Pathname: "/tmp/foo"}, pb.MessageType_MESSAGE_SYSCALL_OPEN)
+	status := r.Status()
+	if want := uint64(1); status.PointCount != want {
+		t.Errorf("wrong number of points, want: %d, got: %d", want, status.PointCount)
+	}
+	var filteredOut uint64
+	for _, s := range status.Metrics {
+		if s.Name == "runsc_trace_filtered_out_points_total" {
+			filteredOut = s.Value
+		}
+	}
+	// 5 points from the cases above, plus 1 from the writes.
+	if want := uint64(6); filteredOut != want {
+		t.Errorf("wrong number of filtered out points, want: %d, got: %d", want, filteredOut)
+	}
+
+	f, err := parseFilters(map[string]interface{}{
+		"filters": map[string]interface{}{
+			"fd_types": []interface{}{"file", "socket"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("parseFilters(): %v", err)
+	}
+	for _, tc := range []struct {
+		fdPath string
+		keep   bool
+	}{
+		{fdPath: "", keep: true},
+		{fdPath: "/etc/passwd", keep: true},
+		{fdPath: "socket:[3]", keep: true},
+		{fdPath: "pipe:[3]"},
+		{fdPath: "anon_inode:[eventfd]"},
+	} {
+		if got := f.keep(&pb.Read{FdPath: tc.fdPath}); got != tc.keep {
+			t.Errorf("keep(%q), want: %t, got: %t", tc.fdPath, tc.keep, got)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	gocontext "context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/cleanup"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	sinkpb "gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/sink/sink_go_proto"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+const grpcName = "grpc"
+
+// grpcQueueSize is the default number of points that can be queued for
+// sending before new points are dropped.
+const grpcQueueSize = 1024
+
+// grpcVersionKey is the request metadata key used to report the wire version
+// supported by the sandbox.
+const grpcVersionKey = "gvisor-version"
+
+// grpcSandboxIDKey is the request metadata key used to report the sandbox ID.
+const grpcSandboxIDKey = "gvisor-sandbox-id"
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     grpcName,
+		Setup:    setupGRPCSink,
+		New:      newGRPC,
+		Validate: validateFilters,
+	})
+}
+
+// setupGRPCSink connects a stream socket to the gRPC server and returns a file
+// that the sink uses to talk to it. The caller is responsible to close to file.
+func setupGRPCSink(config map[string]interface{}) (*os.File, error) {
+	addrOpaque, ok := config["endpoint"]
+	if !ok {
+		return nil, fmt.Errorf("endpoint not present in configuration")
+	}
+	addr, ok := addrOpaque.(string)
+	if !ok {
+		return nil, fmt.Errorf("endpoint %q is not a string", addrOpaque)
+	}
+	transport, err := parseTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("gRPC sink connecting to %s %q", transport, addr)
+	if transport == transportTCP {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		f, err := conn.(*net.TCPConn).File()
+		if err != nil {
+			return nil, fmt.Errorf("getting file for tcp connection to %q: %w", addr, err)
+		}
+		// The sink reads and writes to the connection from dedicated goroutines.
+		if err := unix.SetNonblock(int(f.Fd()), false); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		return f, nil
+	}
+
+	socket, err := unix.Socket(unix.AF_UNIX, unix.SOCK_STREAM, 0)
+	if err != nil {
+		return nil, fmt.Errorf("socket(AF_UNIX, SOCK_STREAM, 0): %w", err)
+	}
+	f := os.NewFile(uintptr(socket), addr)
+	cu := cleanup.Make(func() {
+		_ = f.Close()
+	})
+	defer cu.Clean()
+
+	if err := unix.Connect(int(f.Fd()), wire.UnixAddr(addr)); err != nil {
+		return nil, fmt.Errorf("connect(%q): %w", addr, err)
+	}
+	cu.Release()
+	return f, nil
+}
+
+// newGRPC creates a new gRPC checker. gRPC runs over the connection that was
+// established by setupGRPCSink.
+func newGRPC(config map[string]interface{}, endpoint *fd.FD) (seccheck.Checker, error) {
+	if endpoint == nil {
+		return nil, fmt.Errorf("grpc sink requires an endpoint")
+	}
+	sampler, err := parseSampleRates(config)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFilters(config)
+	if err != nil {
+		return nil, err
+	}
+	queueSize, err := parseQueueSize(config, grpcQueueSize)
+	if err != nil {
+		return nil, err
+	}
+	md := []string{grpcVersionKey, strconv.Itoa(wire.CurrentVersion)}
+	if opaque, ok := config["sandbox_id"]; ok {
+		sandboxID, ok := opaque.(string)
+		if !ok {
+			return nil, fmt.Errorf("sandbox_id %q is not a string", opaque)
+		}
+		md = append(md, grpcSandboxIDKey, sandboxID)
+	}
+	cc, err := dialEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	ctx = metadata.AppendToOutgoingContext(ctx, md...)
+	rpc := &rpcStream{
+		conn:   cc,
+		queue:  make(chan *sinkpb.Point, queueSize),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	r := &remote{fields: fields, sampler: sampler, sender: rpc}
+	go rpc.run(ctx, r) // S/R-SAFE: sinks are not saved.
+
+	log.Debugf("gRPC sink created, endpoint FD: %d, queue size: %d", endpoint.FD(), queueSize)
+	return r, nil
+}
+
+// parseQueueSize returns the "queue_size" configuration, or def if it's not
+// set.
+func parseQueueSize(config map[string]interface{}, def int) (int, error) {
+	opaque, ok := config["queue_size"]
+	if !ok {
+		return def, nil
+	}
+	size, ok := opaque.(float64)
+	if !ok || size != float64(int(size)) || size <= 0 {
+		return 0, fmt.Errorf("queue_size %v is not a positive int", opaque)
+	}
+	return int(size), nil
+}
+
+// dialEndpoint creates a gRPC client connection over endpoint. The connection
+// takes ownership of endpoint.
+func dialEndpoint(endpoint *fd.FD) (*grpc.ClientConn, error) {
+	conn := &fdConn{endpoint: endpoint}
+	var dialed atomicbitops.Int32
+	cc, err := grpc.Dial("passthrough:///"+grpcName,
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(gocontext.Context, string) (net.Conn, error) {
+			// The sandbox can't establish new connections, so there is nothing
+			// to reconnect to once the donated connection is gone.
+			if !dialed.CompareAndSwap(0, 1) {
+				return nil, fmt.Errorf("grpc connection is closed")
+			}
+			return conn, nil
+		}))
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return cc, nil
+}
+
+// rpcStream sends points over a gRPC client stream. Points are queued and sent
+// asynchronously, so that gRPC flow control doesn't block the application.
+// Points are dropped when the queue is full.
+type rpcStream struct {
+	conn   *grpc.ClientConn
+	queue  chan *sinkpb.Point
+	cancel gocontext.CancelFunc
+	done   chan struct{}
+}
+
+var _ sender = (*rpcStream)(nil)
+var _ statusReporter = (*rpcStream)(nil)
+
+// name implements sender.
+func (*rpcStream) name() string {
+	return grpcName
+}
+
+// send implements sender. The point is queued to be sent asynchronously.
+func (s *rpcStream) send(r *remote, msg proto.Message, msgType pb.MessageType) error {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		log.Debugf("Marshal(%+v): %v", msg, err)
+		return err
+	}
+	pt := &sinkpb.Point{
+		MessageType:  msgType,
+		Payload:      payload,
+		DroppedCount: r.droppedCount.Load(),
+	}
+	select {
+	case s.queue <- pt:
+		return nil
+	default:
+		r.droppedCount.Add(1)
+		return errQueueFull
+	}
+}
+
+// run opens the stream and sends queued points over it until ctx is canceled.
+func (s *rpcStream) run(ctx gocontext.Context, r *remote) {
+	defer close(s.done)
+
+	stream, err := sinkpb.NewSinkClient(s.conn).Stream(ctx, grpc.WaitForReady(true))
+	if err != nil {
+		log.Warningf("gRPC sink failed to open stream, points will be dropped: %v", err)
+		r.setError(err)
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case pt := <-s.queue:
+			if err := stream.Send(pt); err != nil {
+				log.Warningf("gRPC sink stream failed, points will be dropped: %v", err)
+				r.setError(err)
+				r.droppedCount.Add(1)
+				return
+			}
+		}
+	}
+}
+
+// status implements statusReporter. The stream is disconnected once run
+// returns, since the connection can't be reestablished from the sandbox.
+func (s *rpcStream) status(status *seccheck.CheckerStatus) {
+	status.State = seccheck.SinkConnected
+	select {
+	case <-s.done:
+		status.State = seccheck.SinkDisconnected
+	default:
+	}
+	status.QueueDepth = uint64(len(s.queue))
+}
+
+// stop implements sender. Points that are still queued are dropped.
+func (s *rpcStream) stop() {
+	s.cancel()
+	<-s.done
+	_ = s.conn.Close()
+}
+
+// fdConn is a net.Conn over a connected stream socket.
+type fdConn struct {
+	endpoint *fd.FD
+}
+
+var _ net.Conn = (*fdConn)(nil)
+
+// Read implements net.Conn.Read.
+func (c *fdConn) Read(b []byte) (int, error) {
+	return c.endpoint.Read(b)
+}
+
+// Write implements net.Conn.Write.
+func (c *fdConn) Write(b []byte) (int, error) {
+	return c.endpoint.Write(b)
+}
+
+// Close implements net.Conn.Close.
+func (c *fdConn) Close() error {
+	// Shutdown first to wake up readers blocked on the socket.
+	_ = unix.Shutdown(c.endpoint.FD(), unix.SHUT_RDWR)
+	return c.endpoint.Close()
+}
+
+// LocalAddr implements net.Conn.LocalAddr.
+func (c *fdConn) LocalAddr() net.Addr {
+	return fdAddr{}
+}
+
+// RemoteAddr implements net.Conn.RemoteAddr.
+func (c *fdConn) RemoteAddr() net.Addr {
+	return fdAddr{}
+}
+
+// SetDeadline implements net.Conn.SetDeadline. Deadlines are not supported.
+func (c *fdConn) SetDeadline(time.Time) error {
+	return nil
+}
+
+// SetReadDeadline implements net.Conn.SetReadDeadline. Deadlines are not
+// supported.
+func (c *fdConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline implements net.Conn.SetWriteDeadline. Deadlines are not
+// supported.
+func (c *fdConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// fdAddr is the net.Addr of an fdConn, whose address is unknown.
+type fdAddr struct{}
+
+// Network implements net.Addr.Network.
+func (fdAddr) Network() string {
+	return "fd"
+}
+
+// String implements net.Addr.String.
+func (fdAddr) String() string {
+	return grpcName
+}
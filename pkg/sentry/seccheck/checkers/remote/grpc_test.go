@@ -0,0 +1,111 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	sinkpb "gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/sink/sink_go_proto"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// sinkServer is a sinkpb.SinkServer that forwards the points it receives to a
+// channel.
+type sinkServer struct {
+	sinkpb.UnimplementedSinkServer
+
+	points chan *sinkpb.Point
+}
+
+// Stream implements sinkpb.SinkServer.
+func (s *sinkServer) Stream(stream sinkpb.Sink_StreamServer) error {
+	for {
+		pt, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				return stream.SendAndClose(&sinkpb.StreamResponse{})
+			}
+			return err
+		}
+		s.points <- pt
+	}
+}
+
+func TestGRPC(t *testing.T) {
+	dir, err := os.MkdirTemp(os.TempDir(), "grpc")
+	if err != nil {
+		t.Fatalf("MkdirTemp(): %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "sink.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("net.Listen(%q): %v", path, err)
+	}
+	srv := grpc.NewServer()
+	sink := &sinkServer{points: make(chan *sinkpb.Point, 10)}
+	sinkpb.RegisterSinkServer(srv, sink)
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	config := map[string]interface{}{"endpoint": path}
+	endpoint, err := setupGRPCSink(config)
+	if err != nil {
+		t.Fatalf("setupGRPCSink(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	r, err := newGRPC(config, endpointFD)
+	if err != nil {
+		t.Fatalf("newGRPC(): %v", err)
+	}
+	defer r.Stop()
+
+	info := &pb.ExitNotifyParentInfo{ExitStatus: 123}
+	if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+		t.Fatalf("ExitNotifyParent: %v", err)
+	}
+
+	select {
+	case pt := <-sink.points:
+		if want := pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT; pt.MessageType != want {
+			t.Errorf("wrong message type, want: %v, got: %v", want, pt.MessageType)
+		}
+		got := &pb.ExitNotifyParentInfo{}
+		if err := proto.Unmarshal(pt.Payload, got); err != nil {
+			t.Fatalf("proto.Unmarshal(ExitNotifyParentInfo): %v", err)
+		}
+		if !proto.Equal(info, got) {
+			t.Errorf("Received point is different, want: %+v, got: %+v", info, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for point")
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// maxInternedStrings bounds the memory used to intern strings. Strings that
+// are seen once the table is full are sent as is.
+const maxInternedStrings = 1 << 16
+
+// minInternedLength is the length of the shortest string that is interned.
+// References to shorter strings wouldn't save much.
+const minInternedLength = 8
+
+// stringTable assigns ids to the strings interned on a connection, see
+// pb.Handshake.interning. Points only refer to a string without defining it
+// once a point that defines it was written, so that points that are dropped,
+// or written out of order, e.g. with staging buffers, never leave the remote
+// process with references it can't resolve. Until then, every point with the
+// string defines it again.
+type stringTable struct {
+	mu sync.Mutex
+
+	// ids maps interned strings to their id.
+	//
+	// +checklocks:mu
+	ids map[string]uint32
+
+	// written is indexed by id, and set once a definition of the string was
+	// written.
+	//
+	// +checklocks:mu
+	written []bool
+
+	// references is the number of strings replaced with a reference.
+	references atomicbitops.Uint64
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{ids: make(map[string]uint32)}
+}
+
+// internedField is a field that was replaced with a reference.
+type internedField struct {
+	m     protoreflect.Message
+	fd    protoreflect.FieldDescriptor
+	value string
+}
+
+// internedPoint records the changes made to a point to intern its strings, so
+// that they can be undone once it's serialized.
+type internedPoint struct {
+	msg    protoreflect.Message
+	fields []internedField
+
+	// defined are the ids of the strings defined by the point, in the
+	// ContextData of msg, which was set for this purpose if hadContextData is
+	// false.
+	defined        []uint32
+	hadContextData bool
+}
+
+// intern replaces the strings of msg that are interned with references, and
+// adds the definitions that the remote process may not have to msg. Changes
+// are undone by restore, which must be called before msg is used by anything
+// else. It returns nil if msg is unchanged. A nil *stringTable doesn't intern
+// anything.
+func (t *stringTable) intern(msg proto.Message) *internedPoint {
+	if t == nil {
+		return nil
+	}
+	m := msg.ProtoReflect()
+	// Points without ContextData can't define strings, so they only refer to
+	// strings that were already written.
+	cdField := wire.ContextDataField(m)
+	var (
+		fields  []internedField
+		defined []uint32
+		defs    []*pb.InternedString
+	)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	wire.RangeInterned(msg, func(fm protoreflect.Message, fd protoreflect.FieldDescriptor, value string) {
+		if len(value) < minInternedLength {
+			return
+		}
+		id, ok := t.ids[value]
+		if !ok {
+			if cdField == nil || len(t.written) >= maxInternedStrings {
+				return
+			}
+			id = uint32(len(t.written))
+			t.ids[value] = id
+			t.written = append(t.written, false)
+		}
+		if !t.written[id] {
+			if cdField == nil {
+				return
+			}
+			if !containsID(defined, id) {
+				defined = append(defined, id)
+				defs = append(defs, &pb.InternedString{Id: id, Value: value})
+			}
+		}
+		fields = append(fields, internedField{m: fm, fd: fd, value: value})
+		fm.Set(fd, protoreflect.ValueOfString(wire.InternRef(id)))
+	})
+	if len(fields) == 0 {
+		return nil
+	}
+	t.references.Add(uint64(len(fields)))
+	p := &internedPoint{msg: m, fields: fields, defined: defined}
+	if len(defs) > 0 {
+		p.hadContextData = m.Has(cdField)
+		cd := m.Mutable(cdField).Message().Interface().(*pb.ContextData)
+		cd.InternedStrings = defs
+	}
+	return p
+}
+
+// restore undoes the changes made to the point by intern.
+func (p *internedPoint) restore() {
+	for _, f := range p.fields {
+		f.m.Set(f.fd, protoreflect.ValueOfString(f.value))
+	}
+	if len(p.defined) == 0 {
+		return
+	}
+	cdField := wire.ContextDataField(p.msg)
+	if !p.hadContextData {
+		p.msg.Clear(cdField)
+		return
+	}
+	p.msg.Get(cdField).Message().Interface().(*pb.ContextData).InternedStrings = nil
+}
+
+// setWritten records that the strings with ids were defined by a point that was
+// written, so that the following points can refer to them without defining
+// them. A nil *stringTable ignores it.
+func (t *stringTable) setWritten(ids []uint32) {
+	if t == nil || len(ids) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, id := range ids {
+		t.written[id] = true
+	}
+}
+
+// status reports the number of interned strings and references as metrics.
+func (t *stringTable) status(status *seccheck.CheckerStatus) {
+	t.mu.Lock()
+	count := len(t.written)
+	t.mu.Unlock()
+	status.Metrics = append(status.Metrics,
+		seccheck.MetricSample{
+			Family: "runsc_trace_interned_strings",
+			Type:   "gauge",
+			Name:   "runsc_trace_interned_strings",
+			Value:  uint64(count),
+		},
+		seccheck.MetricSample{
+			Family: "runsc_trace_interned_references_total",
+			Type:   "counter",
+			Name:   "runsc_trace_interned_references_total",
+			Value:  t.references.Load(),
+		},
+	)
+}
+
+func containsID(ids []uint32, id uint32) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// marshalInterned is like marshal, interning the strings of msg if the remote
+// process accepted it. It also returns the ids of the strings that the point
+// defines, to be passed to r.interned.setWritten once the point is written.
+func (r *remote) marshalInterned(buf []byte, msg proto.Message) ([]byte, uint32, []uint32, error) {
+	p := r.interned.intern(msg)
+	if p == nil {
+		out, flags, err := r.marshal(buf, msg, r.compression)
+		return out, flags, nil, err
+	}
+	out, flags, err := r.marshal(buf, msg, r.compression)
+	p.restore()
+	if err == nil && flags&wire.FlagTruncated != 0 {
+		// Truncation may have shortened definitions or references, so the
+		// point is sent with its strings instead.
+		out, flags, err = r.marshal(buf, msg, r.compression)
+		return out, flags, nil, err
+	}
+	return out, flags | wire.FlagInterned, p.defined, err
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestInterning(t *testing.T) {
+	r := &remote{interned: newStringTable()}
+	var d wire.Dictionary
+	// send interns and serializes msg, and checks that the remote process gets
+	// it back unchanged. It returns the strings that msg defines.
+	send := func(msg proto.Message) []*pb.InternedString {
+		t.Helper()
+		orig := proto.Clone(msg)
+		out, flags, defined, err := r.marshalInterned(nil, msg)
+		if err != nil {
+			t.Fatalf("marshalInterned(): %v", err)
+		}
+		if !proto.Equal(orig, msg) {
+			t.Fatalf("marshalInterned() changed the point, want: %+v, got: %+v", orig, msg)
+		}
+		got := msg.ProtoReflect().New().Interface()
+		if err := proto.Unmarshal(out, got); err != nil {
+			t.Fatalf("proto.Unmarshal(): %v", err)
+		}
+		var defs []*pb.InternedString
+		if cd := wire.ContextDataOf(got); cd != nil {
+			defs = cd.InternedStrings
+		}
+		if len(defs) != len(defined) {
+			t.Errorf("wrong definitions, want ids: %v, got: %v", defined, defs)
+		}
+		if flags&wire.FlagInterned != 0 {
+			if err := d.Resolve(got); err != nil {
+				t.Fatalf("Resolve(): %v", err)
+			}
+		}
+		if !proto.Equal(orig, got) {
+			t.Errorf("wrong point received, want: %+v, got: %+v", orig, got)
+		}
+		r.interned.setWritten(defined)
+		return defs
+	}
+
+	// Short strings are not interned, and points without ContextData get one
+	// to define strings.
+	msg := &pb.Read{FdPath: "/var/log/app.log", ContextData: &pb.ContextData{ProcessName: "app"}}
+	if defs := send(msg); len(defs) != 1 || defs[0].Value != msg.FdPath {
+		t.Errorf("wrong definitions, want: %q, got: %v", msg.FdPath, defs)
+	}
+	if defs := send(&pb.Read{FdPath: "/var/log/app.log"}); len(defs) != 0 {
+		t.Errorf("string defined again after it was written: %v", defs)
+	}
+	if defs := send(&pb.Read{FdPath: "/var/log/other.log"}); len(defs) != 1 {
+		t.Errorf("wrong definitions, want: 1, got: %v", defs)
+	}
+
+	// Strings are defined again until a point that defines them is written.
+	msg = &pb.Read{FdPath: "/var/log/unwritten.log"}
+	for i := 0; i < 2; i++ {
+		p := r.interned.intern(msg)
+		if p == nil || len(p.defined) != 1 {
+			t.Fatalf("intern(): want 1 definition, got: %+v", p)
+		}
+		p.restore()
+		if msg.ContextData != nil {
+			t.Errorf("restore() didn't clear ContextData: %+v", msg.ContextData)
+		}
+	}
+}
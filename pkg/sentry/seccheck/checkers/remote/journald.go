@@ -0,0 +1,174 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+const journaldName = "journald"
+
+// journaldDefaultEndpoint is the socket where journald receives entries in its
+// native protocol.
+const journaldDefaultEndpoint = "/run/systemd/journal/socket"
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     journaldName,
+		Setup:    setupJournald,
+		New:      newJournald,
+		Validate: validateFilters,
+	})
+}
+
+// setupJournald connects to journald. The following configuration is used:
+//
+//	endpoint:   journald's socket, defaults to journaldDefaultEndpoint.
+//	identifier: SYSLOG_IDENTIFIER of entries, defaults to "runsc".
+//
+// The caller is responsible to close to file.
+func setupJournald(config map[string]interface{}) (*os.File, error) {
+	endpoint, err := parseConfigString(config, "endpoint")
+	if err != nil {
+		return nil, err
+	}
+	if endpoint == "" {
+		endpoint = journaldDefaultEndpoint
+	}
+	log.Debugf("Journald sink connecting to %q", endpoint)
+	// Entries that can't be sent right away are dropped.
+	return dialFile("unixgram", endpoint, true /* nonblock */)
+}
+
+// newJournald creates a new checker that sends points to journald as
+// structured entries, using the native journal protocol. Entries must fit in a
+// single datagram, larger points are dropped.
+func newJournald(config map[string]interface{}, endpoint *fd.FD) (seccheck.Checker, error) {
+	if endpoint == nil {
+		return nil, fmt.Errorf("journald sink requires an endpoint")
+	}
+	sampler, err := parseSampleRates(config)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFilters(config)
+	if err != nil {
+		return nil, err
+	}
+	identifier, err := parseConfigString(config, "identifier")
+	if err != nil {
+		return nil, err
+	}
+	if identifier == "" {
+		identifier = "runsc"
+	}
+	log.Debugf("Journald sink created, endpoint FD: %d", endpoint.FD())
+	return &remote{fields: fields, sampler: sampler, sender: &lineWriter{
+		sinkName: journaldName,
+		encode:   journaldEncoder{identifier: identifier}.encode,
+		endpoint: endpoint,
+	}}, nil
+}
+
+// journaldEncoder renders points as journal entries.
+type journaldEncoder struct {
+	identifier string
+}
+
+// encode renders a point as a journal entry in the native protocol. MESSAGE is
+// the point as JSON, PRIORITY follows syslogSeverities, and context data is
+// added as GVISOR_* fields when present, e.g.:
+//
+//	MESSAGE={"context_data":{...},"exit_status":0}
+//	PRIORITY=6
+//	SYSLOG_IDENTIFIER=runsc
+//	GVISOR_POINT=MESSAGE_SENTRY_EXIT_NOTIFY_PARENT
+//	GVISOR_CONTAINER_ID=abc
+//	GVISOR_PID=7
+//	GVISOR_DROPPED_COUNT=0
+//
+// Trusted fields, e.g. _PID, are set by journald to the sandbox's, so the
+// point's are reported in separate fields.
+func (e journaldEncoder) encode(msg proto.Message, msgType pb.MessageType, droppedCount uint32) ([]byte, error) {
+	point, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	severity, ok := syslogSeverities[msgType]
+	if !ok {
+		severity = syslogSeverityInfo
+	}
+
+	var b bytes.Buffer
+	journaldField(&b, "MESSAGE", string(point))
+	journaldField(&b, "PRIORITY", strconv.Itoa(severity))
+	journaldField(&b, "SYSLOG_IDENTIFIER", e.identifier)
+	journaldField(&b, "GVISOR_POINT", msgType.String())
+	if ctx := contextData(msg); ctx != nil {
+		if ctx.ContainerId != "" {
+			journaldField(&b, "GVISOR_CONTAINER_ID", ctx.ContainerId)
+		}
+		if ctx.ThreadGroupId != 0 {
+			journaldField(&b, "GVISOR_PID", strconv.FormatInt(int64(ctx.ThreadGroupId), 10))
+		}
+		if ctx.ThreadId != 0 {
+			journaldField(&b, "GVISOR_TID", strconv.FormatInt(int64(ctx.ThreadId), 10))
+		}
+		if ctx.ProcessName != "" {
+			journaldField(&b, "GVISOR_PROCESS_NAME", ctx.ProcessName)
+		}
+		if ctx.Cwd != "" {
+			journaldField(&b, "GVISOR_CWD", ctx.Cwd)
+		}
+		if creds := ctx.Credentials; creds != nil {
+			journaldField(&b, "GVISOR_UID", strconv.FormatUint(uint64(creds.EffectiveUid), 10))
+		}
+		if ctx.TimeNs != 0 {
+			journaldField(&b, "GVISOR_TIME_NS", strconv.FormatInt(ctx.TimeNs, 10))
+		}
+	}
+	journaldField(&b, "GVISOR_DROPPED_COUNT", strconv.FormatUint(uint64(droppedCount), 10))
+	return b.Bytes(), nil
+}
+
+// journaldField appends a field to an entry. Values that contain newlines are
+// serialized with an explicit length, as required by the protocol.
+func journaldField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(value)))
+	b.WriteByte('\n')
+	b.Write(length[:])
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
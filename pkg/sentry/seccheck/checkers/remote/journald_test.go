@@ -0,0 +1,83 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestJournald(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	journal, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("ListenUnixgram(): %v", err)
+	}
+	defer journal.Close()
+
+	config := map[string]interface{}{"endpoint": path}
+	endpoint, err := setupJournald(config)
+	if err != nil {
+		t.Fatalf("setupJournald(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+	r, err := newJournald(config, endpointFD)
+	if err != nil {
+		t.Fatalf("newJournald(): %v", err)
+	}
+	defer r.Stop()
+
+	info := &pb.ExitNotifyParentInfo{
+		ContextData: &pb.ContextData{
+			ThreadGroupId: 7,
+			ProcessName:   "a\nb",
+			ContainerId:   "abc",
+		},
+	}
+	if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+		t.Fatalf("ExitNotifyParent: %v", err)
+	}
+	buf := make([]byte, 4096)
+	n, err := journal.Read(buf)
+	if err != nil {
+		t.Fatalf("Read(): %v", err)
+	}
+	got := string(buf[:n])
+	for _, want := range []string{
+		"MESSAGE={",
+		"\nPRIORITY=6\n",
+		"\nSYSLOG_IDENTIFIER=runsc\n",
+		"\nGVISOR_POINT=MESSAGE_SENTRY_EXIT_NOTIFY_PARENT\n",
+		"\nGVISOR_CONTAINER_ID=abc\n",
+		"\nGVISOR_PID=7\n",
+		"\nGVISOR_PROCESS_NAME\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n",
+		"\nGVISOR_DROPPED_COUNT=0\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("entry doesn't contain %q: %q", want, got)
+		}
+	}
+}
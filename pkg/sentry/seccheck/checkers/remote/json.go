@@ -0,0 +1,82 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+const jsonName = "jsonl"
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     jsonName,
+		Setup:    setupFileSink,
+		New:      newJSON,
+		Validate: validateFilters,
+	})
+}
+
+// newJSON creates a new checker that writes points as newline-delimited JSON.
+func newJSON(config map[string]interface{}, endpoint *fd.FD) (seccheck.Checker, error) {
+	if endpoint == nil {
+		return nil, fmt.Errorf("jsonl sink requires an endpoint")
+	}
+	sampler, err := parseSampleRates(config)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFilters(config)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("JSON lines sink created, endpoint FD: %d", endpoint.FD())
+	return &remote{fields: fields, sampler: sampler, sender: &lineWriter{
+		sinkName: jsonName,
+		encode:   encodeJSON,
+		endpoint: endpoint,
+	}}, nil
+}
+
+// encodeJSON renders a point as a JSON object on its own line, e.g.:
+//
+//	{"type":"MESSAGE_SENTRY_CLONE","dropped_count":0,"point":{...}}
+//
+// point is the point proto in the canonical protobuf JSON mapping, using the
+// field names from the .proto files.
+func encodeJSON(msg proto.Message, msgType pb.MessageType, droppedCount uint32) ([]byte, error) {
+	point, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var line bytes.Buffer
+	line.WriteString(`{"type":`)
+	line.WriteString(strconv.Quote(msgType.String()))
+	line.WriteString(`,"dropped_count":`)
+	line.WriteString(strconv.FormatUint(uint64(droppedCount), 10))
+	line.WriteString(`,"point":`)
+	line.Write(point)
+	line.WriteString("}\n")
+	return line.Bytes(), nil
+}
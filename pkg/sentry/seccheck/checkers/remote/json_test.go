@@ -0,0 +1,86 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestJSON(t *testing.T) {
+	dir, err := os.MkdirTemp(os.TempDir(), "jsonl")
+	if err != nil {
+		t.Fatalf("MkdirTemp(): %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "points.jsonl")
+	config := map[string]interface{}{"path": path}
+	endpoint, err := setupFileSink(config)
+	if err != nil {
+		t.Fatalf("setupFileSink(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	r, err := newJSON(config, endpointFD)
+	if err != nil {
+		t.Fatalf("newJSON(): %v", err)
+	}
+	for _, status := range []int32{123, 456} {
+		if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, &pb.ExitNotifyParentInfo{ExitStatus: status}); err != nil {
+			t.Fatalf("ExitNotifyParent: %v", err)
+		}
+	}
+	r.Stop()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%q): %v", path, err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("wrong number of lines, want: 2, got: %d, data: %q", len(lines), data)
+	}
+	for i, want := range []int32{123, 456} {
+		var got struct {
+			Type         string `json:"type"`
+			DroppedCount uint32 `json:"dropped_count"`
+			Point        struct {
+				ExitStatus int32 `json:"exit_status"`
+			} `json:"point"`
+		}
+		if err := json.Unmarshal([]byte(lines[i]), &got); err != nil {
+			t.Fatalf("json.Unmarshal(%q): %v", lines[i], err)
+		}
+		if got.Type != "MESSAGE_SENTRY_EXIT_NOTIFY_PARENT" {
+			t.Errorf("wrong type, want: MESSAGE_SENTRY_EXIT_NOTIFY_PARENT, got: %q", got.Type)
+		}
+		if got.Point.ExitStatus != want {
+			t.Errorf("wrong exit_status, want: %d, got: %d", want, got.Point.ExitStatus)
+		}
+	}
+}
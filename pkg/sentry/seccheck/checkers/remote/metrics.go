@@ -0,0 +1,197 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"sort"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const metricsName = "metrics"
+
+// metricsMaxContainers is the maximum number of containers that are counted
+// separately. Points from other containers are counted together, under
+// metricsOtherContainer, to bound the number of samples reported.
+const metricsMaxContainers = 1024
+
+// metricsOtherContainer is the container label of points from containers past
+// metricsMaxContainers.
+const metricsOtherContainer = "other"
+
+// metricsSizeBuckets are the upper bounds, in bytes, of the buckets of the
+// point size histogram.
+var metricsSizeBuckets = []int{64, 128, 256, 512, 1024, 2048, 4096, 16384, 65536}
+
+// deniedPoints are the points that report an operation denied to the sandboxed
+// application.
+var deniedPoints = map[pb.MessageType]struct{}{
+	pb.MessageType_MESSAGE_SENTRY_CAPABILITY_DENIED: {},
+	pb.MessageType_MESSAGE_SENTRY_RLIMIT_BREACH:     {},
+	pb.MessageType_MESSAGE_SENTRY_SECCOMP:           {},
+}
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     metricsName,
+		New:      newMetrics,
+		Validate: validateFilters,
+	})
+}
+
+// newMetrics creates a new checker that aggregates points into metrics, without
+// sending them anywhere. Metrics are reported with the sink's status, and can
+// be exported with `runsc trace metrics`.
+func newMetrics(config map[string]interface{}, _ *fd.FD) (seccheck.Checker, error) {
+	sampler, err := parseSampleRates(config)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFilters(config)
+	if err != nil {
+		return nil, err
+	}
+	return &remote{fields: fields, sampler: sampler, sender: &pointMetrics{
+		points:     make(map[pb.MessageType]uint64),
+		containers: make(map[string]uint64),
+		sizes:      make([]uint64, len(metricsSizeBuckets)+1),
+	}}, nil
+}
+
+// pointMetrics aggregates the points it's sent.
+type pointMetrics struct {
+	mu sync.Mutex
+
+	// points is the number of points of each type.
+	//
+	// +checklocks:mu
+	points map[pb.MessageType]uint64
+
+	// containers is the number of points from each container, for points that
+	// have the container ID in their context data.
+	//
+	// +checklocks:mu
+	containers map[string]uint64
+
+	// sizes is the number of points in each bucket of metricsSizeBuckets, with
+	// an additional bucket for larger points.
+	//
+	// +checklocks:mu
+	sizes []uint64
+
+	// +checklocks:mu
+	sizeSum uint64
+}
+
+var _ sender = (*pointMetrics)(nil)
+var _ statusReporter = (*pointMetrics)(nil)
+
+// name implements sender.
+func (*pointMetrics) name() string {
+	return metricsName
+}
+
+// send implements sender.
+func (m *pointMetrics) send(_ *remote, msg proto.Message, msgType pb.MessageType) error {
+	size := proto.Size(msg)
+	bucket := sort.SearchInts(metricsSizeBuckets, size)
+	container := ""
+	if ctx := contextData(msg); ctx != nil {
+		container = ctx.ContainerId
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.points[msgType]++
+	if container != "" {
+		if _, ok := m.containers[container]; !ok && len(m.containers) >= metricsMaxContainers {
+			container = metricsOtherContainer
+		}
+		m.containers[container]++
+	}
+	m.sizes[bucket]++
+	m.sizeSum += uint64(size)
+	return nil
+}
+
+// stop implements sender.
+func (*pointMetrics) stop() {}
+
+// status implements statusReporter.
+func (m *pointMetrics) status(status *seccheck.CheckerStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	types := make([]pb.MessageType, 0, len(m.points))
+	for t := range m.points {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	for _, t := range types {
+		count := m.points[t]
+		status.PointCount += count
+		status.Metrics = append(status.Metrics, counterSample("runsc_trace_points_total", count, "point", t.String()))
+		if _, ok := deniedPoints[t]; ok {
+			status.Metrics = append(status.Metrics, counterSample("runsc_trace_denied_total", count, "point", t.String()))
+		}
+	}
+
+	containers := make([]string, 0, len(m.containers))
+	for c := range m.containers {
+		containers = append(containers, c)
+	}
+	sort.Strings(containers)
+	for _, c := range containers {
+		status.Metrics = append(status.Metrics, counterSample("runsc_trace_container_points_total", m.containers[c], "container", c))
+	}
+
+	const sizeFamily = "runsc_trace_point_bytes"
+	var cumulative uint64
+	for i, count := range m.sizes {
+		cumulative += count
+		le := "+Inf"
+		if i < len(metricsSizeBuckets) {
+			le = strconv.Itoa(metricsSizeBuckets[i])
+		}
+		status.Metrics = append(status.Metrics, seccheck.MetricSample{
+			Family: sizeFamily,
+			Type:   "histogram",
+			Name:   sizeFamily + "_bucket",
+			Labels: map[string]string{"le": le},
+			Value:  cumulative,
+		})
+	}
+	status.Metrics = append(status.Metrics,
+		seccheck.MetricSample{Family: sizeFamily, Type: "histogram", Name: sizeFamily + "_sum", Value: m.sizeSum},
+		seccheck.MetricSample{Family: sizeFamily, Type: "histogram", Name: sizeFamily + "_count", Value: cumulative},
+	)
+}
+
+// counterSample returns a sample of the counter called name, with a single
+// label.
+func counterSample(name string, value uint64, label, labelValue string) seccheck.MetricSample {
+	return seccheck.MetricSample{
+		Family: name,
+		Type:   "counter",
+		Name:   name,
+		Labels: map[string]string{label: labelValue},
+		Value:  value,
+	}
+}
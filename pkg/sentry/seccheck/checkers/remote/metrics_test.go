@@ -0,0 +1,71 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestMetrics(t *testing.T) {
+	r, err := newMetrics(nil, nil)
+	if err != nil {
+		t.Fatalf("newMetrics(): %v", err)
+	}
+	defer r.Stop()
+	for _, container := range []string{"a", "b", "a", ""} {
+		info := &pb.ExitNotifyParentInfo{ContextData: &pb.ContextData{ContainerId: container}}
+		if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+			t.Fatalf("ExitNotifyParent: %v", err)
+		}
+	}
+	if err := r.Seccomp(nil, seccheck.FieldSet{}, &pb.SeccompInfo{}); err != nil {
+		t.Fatalf("Seccomp: %v", err)
+	}
+
+	status := r.Status()
+	if status.PointCount != 5 {
+		t.Errorf("wrong point count, want: 5, got: %d", status.PointCount)
+	}
+	got := make(map[string]uint64)
+	for _, s := range status.Metrics {
+		key := s.Name
+		for _, label := range []string{"point", "container", "le"} {
+			if v, ok := s.Labels[label]; ok {
+				key += "/" + v
+			}
+		}
+		got[key] = s.Value
+	}
+	for key, want := range map[string]uint64{
+		"runsc_trace_points_total/MESSAGE_SENTRY_EXIT_NOTIFY_PARENT": 4,
+		"runsc_trace_points_total/MESSAGE_SENTRY_SECCOMP":            1,
+		"runsc_trace_denied_total/MESSAGE_SENTRY_SECCOMP":            1,
+		"runsc_trace_container_points_total/a":                       2,
+		"runsc_trace_container_points_total/b":                       1,
+		"runsc_trace_point_bytes_bucket/64":                          5,
+		"runsc_trace_point_bytes_bucket/+Inf":                        5,
+		"runsc_trace_point_bytes_count":                              5,
+	} {
+		if got[key] != want {
+			t.Errorf("wrong value for %q, want: %d, got: %d", key, want, got[key])
+		}
+	}
+	if _, ok := got["runsc_trace_denied_total/MESSAGE_SENTRY_EXIT_NOTIFY_PARENT"]; ok {
+		t.Errorf("exit points must not be counted as denied")
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	gocontext "context"
+	"fmt"
+	"strconv"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+const otlpName = "otlp"
+
+const (
+	// otlpQueueSize is the default number of log records that can be queued
+	// for export before new points are dropped.
+	otlpQueueSize = 1024
+
+	// otlpBatchSize is the maximum number of log records exported at once.
+	otlpBatchSize = 256
+
+	// otlpFlushInterval is how long log records are batched for before they
+	// are exported.
+	otlpFlushInterval = time.Second
+
+	// otlpExportTimeout bounds how long a single export can take.
+	otlpExportTimeout = 10 * time.Second
+)
+
+// Resource and log record attribute keys. Container ID follows OpenTelemetry
+// semantic conventions, the others are specific to gVisor.
+const (
+	otlpContainerIDKey  = "container.id"
+	otlpSandboxIDKey    = "gvisor.sandbox.id"
+	otlpMessageTypeKey  = "gvisor.message_type"
+	otlpDroppedCountKey = "gvisor.dropped_count"
+)
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     otlpName,
+		Setup:    setupGRPCSink,
+		New:      newOTLP,
+		Validate: validateFilters,
+	})
+}
+
+// newOTLP creates a new checker that exports points as OpenTelemetry log
+// records to an OTLP/gRPC collector, over the connection that was established
+// by setupGRPCSink.
+func newOTLP(config map[string]interface{}, endpoint *fd.FD) (seccheck.Checker, error) {
+	if endpoint == nil {
+		return nil, fmt.Errorf("otlp sink requires an endpoint")
+	}
+	sampler, err := parseSampleRates(config)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFilters(config)
+	if err != nil {
+		return nil, err
+	}
+	queueSize, err := parseQueueSize(config, otlpQueueSize)
+	if err != nil {
+		return nil, err
+	}
+	var sandboxID string
+	if opaque, ok := config["sandbox_id"]; ok {
+		sandboxID, ok = opaque.(string)
+		if !ok {
+			return nil, fmt.Errorf("sandbox_id %q is not a string", opaque)
+		}
+	}
+	cc, err := dialEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	exp := &otlpExporter{
+		conn:      cc,
+		sandboxID: sandboxID,
+		queue:     make(chan *otlpRecord, queueSize),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	r := &remote{fields: fields, sampler: sampler, sender: exp}
+	go exp.run(ctx, r) // S/R-SAFE: sinks are not saved.
+
+	log.Debugf("OTLP sink created, endpoint FD: %d, queue size: %d", endpoint.FD(), queueSize)
+	return r, nil
+}
+
+// otlpRecord is a log record waiting to be exported, along with the container
+// that it belongs to.
+type otlpRecord struct {
+	containerID string
+	record      *logspb.LogRecord
+}
+
+// otlpExporter exports points to an OTLP collector. Log records are queued and
+// exported in batches asynchronously, so that the collector doesn't block the
+// application. Points are dropped when the queue is full.
+type otlpExporter struct {
+	conn      *grpc.ClientConn
+	sandboxID string
+	queue     chan *otlpRecord
+	cancel    gocontext.CancelFunc
+	done      chan struct{}
+}
+
+var _ sender = (*otlpExporter)(nil)
+var _ statusReporter = (*otlpExporter)(nil)
+
+// name implements sender.
+func (*otlpExporter) name() string {
+	return otlpName
+}
+
+// send implements sender. The point is queued to be exported asynchronously.
+func (e *otlpExporter) send(r *remote, msg proto.Message, msgType pb.MessageType) error {
+	rec, err := newOTLPRecord(msg, msgType, r.droppedCount.Load())
+	if err != nil {
+		log.Debugf("newOTLPRecord(%+v): %v", msg, err)
+		return err
+	}
+	select {
+	case e.queue <- rec:
+		return nil
+	default:
+		r.droppedCount.Add(1)
+		return errQueueFull
+	}
+}
+
+// newOTLPRecord converts a point into a log record. The body of the record is
+// the point in the canonical protobuf JSON mapping.
+func newOTLPRecord(msg proto.Message, msgType pb.MessageType, droppedCount uint32) (*otlpRecord, error) {
+	body, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	rec := &otlpRecord{
+		record: &logspb.LogRecord{
+			SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+			Name:           msgType.String(),
+			Body:           otlpString(string(body)),
+			Attributes: []*commonpb.KeyValue{
+				{Key: otlpMessageTypeKey, Value: otlpString(msgType.String())},
+				{Key: otlpDroppedCountKey, Value: &commonpb.AnyValue{
+					Value: &commonpb.AnyValue_IntValue{IntValue: int64(droppedCount)},
+				}},
+			},
+		},
+	}
+	if ctx := contextData(msg); ctx != nil {
+		rec.containerID = ctx.ContainerId
+		if ctx.TimeNs > 0 {
+			rec.record.TimeUnixNano = uint64(ctx.TimeNs)
+		}
+	}
+	if rec.record.TimeUnixNano == 0 {
+		rec.record.TimeUnixNano = uint64(time.Now().UnixNano())
+	}
+	return rec, nil
+}
+
+// run exports queued log records until ctx is canceled.
+func (e *otlpExporter) run(ctx gocontext.Context, r *remote) {
+	defer close(e.done)
+
+	client := collogspb.NewLogsServiceClient(e.conn)
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	var batch []*otlpRecord
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case rec := <-e.queue:
+			batch = append(batch, rec)
+			if len(batch) < otlpBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		if err := e.export(ctx, client, batch); err != nil {
+			log.Debugf("OTLP sink export failed, %d points dropped: %v", len(batch), err)
+			r.setError(err)
+			r.droppedCount.Add(uint32(len(batch)))
+		}
+		batch = batch[:0]
+	}
+}
+
+// export sends a batch of log records to the collector, grouped by container.
+func (e *otlpExporter) export(ctx gocontext.Context, client collogspb.LogsServiceClient, batch []*otlpRecord) error {
+	req := &collogspb.ExportLogsServiceRequest{}
+	byContainer := make(map[string]*logspb.InstrumentationLibraryLogs)
+	for _, rec := range batch {
+		logs, ok := byContainer[rec.containerID]
+		if !ok {
+			logs = &logspb.InstrumentationLibraryLogs{
+				InstrumentationLibrary: &commonpb.InstrumentationLibrary{
+					Name:    "gvisor.dev/gvisor/pkg/sentry/seccheck",
+					Version: strconv.Itoa(wire.CurrentVersion),
+				},
+			}
+			byContainer[rec.containerID] = logs
+			req.ResourceLogs = append(req.ResourceLogs, &logspb.ResourceLogs{
+				Resource:                   e.resource(rec.containerID),
+				InstrumentationLibraryLogs: []*logspb.InstrumentationLibraryLogs{logs},
+			})
+		}
+		logs.Logs = append(logs.Logs, rec.record)
+	}
+
+	ctx, cancel := gocontext.WithTimeout(ctx, otlpExportTimeout)
+	defer cancel()
+	_, err := client.Export(ctx, req, grpc.WaitForReady(true))
+	return err
+}
+
+// resource returns the OTLP resource that describes containerID.
+func (e *otlpExporter) resource(containerID string) *resourcepb.Resource {
+	res := &resourcepb.Resource{}
+	if e.sandboxID != "" {
+		res.Attributes = append(res.Attributes, &commonpb.KeyValue{Key: otlpSandboxIDKey, Value: otlpString(e.sandboxID)})
+	}
+	if containerID != "" {
+		res.Attributes = append(res.Attributes, &commonpb.KeyValue{Key: otlpContainerIDKey, Value: otlpString(containerID)})
+	}
+	return res
+}
+
+// status implements statusReporter.
+func (e *otlpExporter) status(status *seccheck.CheckerStatus) {
+	status.State = seccheck.SinkConnected
+	status.QueueDepth = uint64(len(e.queue))
+}
+
+// stop implements sender. Log records that haven't been exported yet are
+// dropped.
+func (e *otlpExporter) stop() {
+	e.cancel()
+	<-e.done
+	_ = e.conn.Close()
+}
+
+func otlpString(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
@@ -0,0 +1,126 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// logsServer is a collogspb.LogsServiceServer that forwards the requests it
+// receives to a channel.
+type logsServer struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	reqs chan *collogspb.ExportLogsServiceRequest
+}
+
+// Export implements collogspb.LogsServiceServer.
+func (s *logsServer) Export(_ context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	s.reqs <- req
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func TestOTLP(t *testing.T) {
+	dir, err := os.MkdirTemp(os.TempDir(), "otlp")
+	if err != nil {
+		t.Fatalf("MkdirTemp(): %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "collector.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("net.Listen(%q): %v", path, err)
+	}
+	srv := grpc.NewServer()
+	collector := &logsServer{reqs: make(chan *collogspb.ExportLogsServiceRequest, 10)}
+	collogspb.RegisterLogsServiceServer(srv, collector)
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	config := map[string]interface{}{"endpoint": path, "sandbox_id": "sandbox"}
+	endpoint, err := setupGRPCSink(config)
+	if err != nil {
+		t.Fatalf("setupGRPCSink(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	r, err := newOTLP(config, endpointFD)
+	if err != nil {
+		t.Fatalf("newOTLP(): %v", err)
+	}
+	defer r.Stop()
+
+	info := &pb.ExitNotifyParentInfo{
+		ContextData: &pb.ContextData{ContainerId: "container", TimeNs: 123},
+		ExitStatus:  456,
+	}
+	if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+		t.Fatalf("ExitNotifyParent: %v", err)
+	}
+
+	select {
+	case req := <-collector.reqs:
+		if len(req.ResourceLogs) != 1 {
+			t.Fatalf("wrong number of resources, want: 1, got: %d", len(req.ResourceLogs))
+		}
+		attrs := make(map[string]string)
+		for _, kv := range req.ResourceLogs[0].Resource.Attributes {
+			attrs[kv.Key] = kv.Value.GetStringValue()
+		}
+		if got := attrs[otlpSandboxIDKey]; got != "sandbox" {
+			t.Errorf("wrong sandbox ID, want: %q, got: %q", "sandbox", got)
+		}
+		if got := attrs[otlpContainerIDKey]; got != "container" {
+			t.Errorf("wrong container ID, want: %q, got: %q", "container", got)
+		}
+
+		libs := req.ResourceLogs[0].InstrumentationLibraryLogs
+		if len(libs) != 1 || len(libs[0].Logs) != 1 {
+			t.Fatalf("wrong log records, want: 1, got: %+v", libs)
+		}
+		rec := libs[0].Logs[0]
+		if rec.TimeUnixNano != 123 {
+			t.Errorf("wrong time, want: 123, got: %d", rec.TimeUnixNano)
+		}
+		got := &pb.ExitNotifyParentInfo{}
+		if err := protojson.Unmarshal([]byte(rec.Body.GetStringValue()), got); err != nil {
+			t.Fatalf("protojson.Unmarshal(%q): %v", rec.Body.GetStringValue(), err)
+		}
+		if !proto.Equal(info, got) {
+			t.Errorf("Received point is different, want: %+v, got: %+v", info, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for export")
+	}
+}
@@ -17,10 +17,13 @@
 package remote
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
+	"sort"
 	"time"
 
 	"golang.org/x/sys/unix"
@@ -33,28 +36,163 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
 	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
 )
 
 const name = "remote"
 
+// defaultQueueSize is the default number of points that can be queued for the
+// writer goroutine before new points are dropped.
+const defaultQueueSize = 1024
+
 func init() {
 	seccheck.RegisterSink(seccheck.SinkDesc{
-		Name:  name,
-		Setup: setupSink,
-		New:   new,
+		Name:     name,
+		Setup:    setupSink,
+		New:      new,
+		Validate: validateFilters,
 	})
 }
 
+const (
+	// transportUnix connects to the remote process using a SOCK_SEQPACKET
+	// Unix-domain socket. This is the default.
+	transportUnix = "unix"
+
+	// transportTCP connects to the remote process over TCP. Since TCP doesn't
+	// preserve message boundaries, every message is framed with a length
+	// prefix. See wire.FrameLengthSize.
+	transportTCP = "tcp"
+)
+
 // remote sends a serialized point to a remote process asynchronously over a
-// SOCK_SEQPACKET Unix-domain socket. Each message corresponds to a single
-// serialized point proto, preceded by a standard header. If the point cannot
-// be sent, e.g. buffer full, the point is dropped on the floor to avoid
-// delaying/hanging indefinitely the application.
+// SOCK_SEQPACKET Unix-domain socket or a TCP connection. Each message
+// corresponds to a single serialized point proto, preceded by a standard
+// header. If the point cannot be sent, e.g. buffer full, the point is dropped
+// on the floor to avoid delaying/hanging indefinitely the application.
 type remote struct {
 	endpoint *fd.FD
 
+	// stream is true if endpoint is a stream socket, in which case messages
+	// are framed with a length prefix.
+	stream bool
+
+	// writeMu serializes writes to stream endpoints, and writes from
+	// different goroutines when points are queued, so that messages don't
+	// interleave when a write is partial.
+	writeMu sync.Mutex
+
+	// sender is set for sinks that reuse remote's serialization with a
+	// different transport or encoding, e.g. grpc. If set, points are sent
+	// through it instead of being written to endpoint directly.
+	sender sender
+
+	// compression is the algorithm used to compress payloads, as accepted by
+	// the remote process during handshake.
+	compression pb.Compression
+
+	// encoding is the serialization of payloads, as accepted by the remote
+	// process during handshake. It's also used by shm.
+	encoding pb.Encoding
+
+	// filter is the set of message types that the remote process requested
+	// during handshake. Points of other types are not sent.
+	filter *messageFilter
+
+	// fields is set when points are filtered by the value of their fields,
+	// see parseFilters.
+	fields *fieldFilter
+
+	// sampler is set when only a fraction of the points of some types are
+	// sent, see parseSampleRates.
+	sampler *sampler
+
+	// adaptive is set when the fraction of points of some types that are sent
+	// depends on the load of ring, see parseAdaptiveSampling.
+	adaptive *adaptiveSampler
+
+	// interned is set when strings that are repeated across points are
+	// interned, see pb.Handshake.interning.
+	interned *stringTable
+
+	// ring is set when points are queued and written asynchronously by flush,
+	// instead of being written by the task that generated them. It's a
+	// *stagingBuffers if staging_buffers is set, or a *ringBuffer otherwise.
+	// flushDone is closed when flush returns.
+	ring      pointQueue
+	flushDone chan struct{}
+
+	// batchSize is the maximum number of queued points that are coalesced into
+	// a single write. Batching is disabled if it's 1.
+	batchSize int
+
+	// replay is set in reliable mode, where the remote process acknowledges
+	// the points it receives. Points are retransmitted by receiveAcks if they
+	// aren't acknowledged within retransmitTimeout. acksDone is closed when
+	// receiveAcks returns.
+	replay            *replayBuffer
+	retransmitTimeout time.Duration
+	acksDone          chan struct{}
+
+	// verdicts is set when the operations behind some points wait for the
+	// remote process to allow or deny them, see parseVerdicts.
+	verdicts *verdicts
+
+	// version is the protocol version negotiated during handshake, which is
+	// written in headers, see wire.CurrentVersion.
+	version uint32
+
 	droppedCount atomicbitops.Uint32
 
+	// maxMessageSize is the maximum size of messages, including the header, as
+	// declared by the remote process during handshake. Points are truncated to
+	// fit, see marshal. It's 0 if there is no limit.
+	maxMessageSize int
+
+	// checksum is set to add the checksum of payloads to headers, see
+	// wire.Header.
+	checksum bool
+
+	// drops is set when DropStats messages are sent, see startDropStats.
+	drops *dropStats
+
+	// sessionClosed is set for sinks that send a SessionClosed message when
+	// they are stopped, see common.proto.
+	sessionClosed bool
+
+	// stopReason is why the sink is stopped, reported in SessionClosed. It's
+	// only set by StopReason, right before Stop.
+	stopReason string
+
+	// stopTimeout bounds how long Stop waits for queued points to be written
+	// and acknowledged, and for the remote process to close the connection.
+	stopTimeout time.Duration
+
+	// sequence is the sequence number of the last point, see wire.Header.
+	sequence atomicbitops.Uint64
+
+	// disconnected is set to 1 once a write fails because the remote process
+	// went away. The sandbox can't establish new connections, so the sink
+	// stays disconnected until runsc sets it up again and hands the new
+	// connection to Reconnect, e.g. `runsc trace reload --watch`. Meanwhile,
+	// points are kept in ring, up to its size, or dropped if there is no ring.
+	disconnected atomicbitops.Int32
+
+	// reconnected wakes up flush when the sink is reconnected, see
+	// waitReconnect.
+	reconnected chan struct{}
+
+	// errMu protects lastErr and lastErrTime.
+	errMu sync.Mutex
+
+	// lastErr is the last error that caused points to be dropped, and
+	// lastErrTime is when it happened. They're reported in Status.
+	//
+	// +checklocks:errMu
+	lastErr error
+	// +checklocks:errMu
+	lastErrTime time.Time
+
 	retries        int
 	initialBackoff time.Duration
 	maxBackoff     time.Duration
@@ -62,6 +200,44 @@ type remote struct {
 
 var _ seccheck.Checker = (*remote)(nil)
 
+var _ seccheck.StopReasoner = (*remote)(nil)
+
+var _ seccheck.Reconnecter = (*remote)(nil)
+
+// sender sends points on behalf of remote.
+type sender interface {
+	// name returns the name of the sink.
+	name() string
+
+	// send sends a single point. Points that can't be sent must be dropped and
+	// accounted for in r.droppedCount, and an error returned.
+	send(r *remote, msg proto.Message, msgType pb.MessageType) error
+
+	// stop stops sending points and releases resources.
+	stop()
+}
+
+var (
+	// errQueueFull is returned when a point is dropped because too many points
+	// are waiting to be sent.
+	errQueueFull = errors.New("trace point queue is full")
+
+	// errDisconnected is returned when a point is dropped because the remote
+	// process went away.
+	errDisconnected = errors.New("remote sink is disconnected")
+
+	// errStopped is returned when a point is dropped because the sink was
+	// stopped.
+	errStopped = errors.New("sink is stopped")
+)
+
+// statusReporter is implemented by senders that report more than the number of
+// dropped points.
+type statusReporter interface {
+	// status fills in the sender specific fields of status.
+	status(status *seccheck.CheckerStatus)
+}
+
 // setupSink starts the connection to the remote process and returns a file that
 // can be used to communicate with it. The caller is responsible to close to
 // file.
@@ -74,14 +250,247 @@ func setupSink(config map[string]interface{}) (*os.File, error) {
 	if !ok {
 		return nil, fmt.Errorf("endpoint %q is not a string", addrOpaque)
 	}
-	return setup(addr)
+	transport, err := parseTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	if transport == transportTCP {
+		return setupTCP(addr, config)
+	}
+	return setup(addr, config)
 }
 
-func setup(path string) (*os.File, error) {
+func parseTransport(config map[string]interface{}) (string, error) {
+	opaque, ok := config["transport"]
+	if !ok {
+		return transportUnix, nil
+	}
+	transport, ok := opaque.(string)
+	if !ok {
+		return "", fmt.Errorf("transport %v is not a string", opaque)
+	}
+	switch transport {
+	case transportUnix, transportTCP:
+		return transport, nil
+	default:
+		return "", fmt.Errorf("invalid transport %q, must be %q or %q", transport, transportUnix, transportTCP)
+	}
+}
+
+// compressions maps the values accepted by the "compression" configuration to
+// the algorithm used.
+var compressions = map[string]pb.Compression{
+	"none": pb.Compression_COMPRESSION_NONE,
+	"gzip": pb.Compression_COMPRESSION_GZIP,
+}
+
+// encodings maps the values accepted by the "encoding" configuration to the
+// encoding used.
+var encodings = map[string]pb.Encoding{
+	"proto": pb.Encoding_ENCODING_PROTO,
+	"cbor":  pb.Encoding_ENCODING_CBOR,
+}
+
+// parsePreferences returns config[key], which is either a single name or a
+// list of names in order of preference, or nil if it's not set.
+func parsePreferences(config map[string]interface{}, key string) ([]string, error) {
+	opaque, ok := config[key]
+	if !ok {
+		return nil, nil
+	}
+	if name, ok := opaque.(string); ok {
+		return []string{name}, nil
+	}
+	list, ok := opaque.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("%s %v is not a string or a non-empty list of strings", key, opaque)
+	}
+	names := make([]string, 0, len(list))
+	for _, opaque := range list {
+		name, ok := opaque.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s %v is not a string", key, opaque)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// parseEncodings returns the encodings accepted by the "encoding"
+// configuration, in order of preference.
+func parseEncodings(config map[string]interface{}) ([]pb.Encoding, error) {
+	names, err := parsePreferences(config, "encoding")
+	if err != nil {
+		return nil, err
+	}
+	if names == nil {
+		return []pb.Encoding{pb.Encoding_ENCODING_PROTO}, nil
+	}
+	var accepted []pb.Encoding
+	for _, name := range names {
+		encoding, ok := encodings[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid encoding %q, must be \"proto\" or \"cbor\"", name)
+		}
+		accepted = append(accepted, encoding)
+	}
+	return accepted, nil
+}
+
+// parseEncoding returns the preferred encoding, which is the one negotiated
+// once handshake has updated the configuration.
+func parseEncoding(config map[string]interface{}) (pb.Encoding, error) {
+	accepted, err := parseEncodings(config)
+	if err != nil {
+		return 0, err
+	}
+	return accepted[0], nil
+}
+
+// parseCompressions returns the compressions accepted by the "compression"
+// configuration, in order of preference.
+func parseCompressions(config map[string]interface{}) ([]pb.Compression, error) {
+	names, err := parsePreferences(config, "compression")
+	if err != nil {
+		return nil, err
+	}
+	if names == nil {
+		return []pb.Compression{pb.Compression_COMPRESSION_NONE}, nil
+	}
+	var accepted []pb.Compression
+	for _, name := range names {
+		compression, ok := compressions[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid compression %q, must be \"none\" or \"gzip\"", name)
+		}
+		accepted = append(accepted, compression)
+	}
+	return accepted, nil
+}
+
+// parseCompression returns the preferred compression, which is the one
+// negotiated once handshake has updated the configuration.
+func parseCompression(config map[string]interface{}) (pb.Compression, error) {
+	accepted, err := parseCompressions(config)
+	if err != nil {
+		return 0, err
+	}
+	return accepted[0], nil
+}
+
+// connectConfig controls how setup connects to the remote process. The
+// following configuration is used:
+//
+//	connect_timeout: how long a connection attempt can take, e.g. while the
+//	                 remote's backlog is full. Defaults to 10s.
+//	connect_retries: number of times a failed attempt is retried, e.g. while
+//	                 the remote process is starting. Defaults to 0.
+type connectConfig struct {
+	timeout time.Duration
+	retries int
+}
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+
+	// connectInitialBackoff and connectMaxBackoff bound the delay between
+	// connection attempts.
+	connectInitialBackoff = 100 * time.Millisecond
+	connectMaxBackoff     = 5 * time.Second
+)
+
+func parseConnectConfig(config map[string]interface{}) (connectConfig, error) {
+	c := connectConfig{timeout: defaultConnectTimeout}
+	if ok, timeout, err := parseDuration(config, "connect_timeout"); err != nil {
+		return connectConfig{}, err
+	} else if ok {
+		if timeout <= 0 {
+			return connectConfig{}, fmt.Errorf("connect_timeout %v must be positive", timeout)
+		}
+		c.timeout = timeout
+	}
+	if opaque, ok := config["connect_retries"]; ok {
+		retries, ok := opaque.(float64)
+		if !ok || retries != float64(int(retries)) || retries < 0 {
+			return connectConfig{}, fmt.Errorf("connect_retries %v is not a non-negative int", opaque)
+		}
+		c.retries = int(retries)
+	}
+	return c, nil
+}
+
+// retry calls attempt until it succeeds, up to c.retries more times after the
+// first failure, with exponential backoff in between. desc describes what is
+// being connected to in errors.
+func (c connectConfig) retry(desc string, attempt func() error) error {
+	backoff := connectInitialBackoff
+	for i := 0; ; i++ {
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+		if i >= c.retries {
+			return fmt.Errorf("%s is not available after %d attempt(s): %w", desc, i+1, err)
+		}
+		log.Infof("Connecting to %s failed, retrying (%d/%d) in %v: %v", desc, i+1, c.retries, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > connectMaxBackoff {
+			backoff = connectMaxBackoff
+		}
+	}
+}
+
+// setup connects to the remote process listening on path, which may be in the
+// abstract namespace, see wire.IsAbstract. See connectConfig and handshake for
+// how config is used.
+func setup(path string, config map[string]interface{}) (*os.File, error) {
 	log.Debugf("Remote sink connecting to %q", path)
-	socket, err := unix.Socket(unix.AF_UNIX, unix.SOCK_SEQPACKET, 0)
+	f, err := connect(path, config)
+	if err != nil {
+		return nil, err
+	}
+	cu := cleanup.Make(func() {
+		_ = f.Close()
+	})
+	defer cu.Clean()
+
+	if err := handshake(f, false /* stream */, false /* sharedMemory */, config); err != nil {
+		return nil, err
+	}
+	if err := unix.SetNonblock(int(f.Fd()), true); err != nil {
+		return nil, err
+	}
+
+	cu.Release()
+	return f, nil
+}
+
+// connect connects to the remote process listening on path with a
+// SOCK_SEQPACKET socket, retrying as set in config, see connectConfig. The
+// returned socket is blocking.
+func connect(path string, config map[string]interface{}) (*os.File, error) {
+	cc, err := parseConnectConfig(config)
 	if err != nil {
-		return nil, fmt.Errorf("socket(AF_UNIX, SOCK_SEQPACKET, 0): %w", err)
+		return nil, err
+	}
+	var f *os.File
+	err = cc.retry(fmt.Sprintf("remote sink %q", path), func() error {
+		var err error
+		f, err = connectTimeout(path, cc.timeout)
+		return err
+	})
+	return f, err
+}
+
+// connectTimeout makes a single attempt to connect to path, that gives up
+// after timeout.
+func connectTimeout(path string, timeout time.Duration) (*os.File, error) {
+	// The socket is non-blocking during connect, so that it doesn't wait
+	// indefinitely for room in the remote's backlog.
+	socket, err := unix.Socket(unix.AF_UNIX, unix.SOCK_SEQPACKET|unix.SOCK_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("socket(AF_UNIX, SOCK_SEQPACKET|SOCK_NONBLOCK, 0): %w", err)
 	}
 	f := os.NewFile(uintptr(socket), path)
 	cu := cleanup.Make(func() {
@@ -89,50 +498,381 @@ func setup(path string) (*os.File, error) {
 	})
 	defer cu.Clean()
 
-	addr := unix.SockaddrUnix{Name: path}
-	if err := unix.Connect(int(f.Fd()), &addr); err != nil {
-		return nil, fmt.Errorf("connect(%q): %w", path, err)
+	addr := wire.UnixAddr(path)
+	deadline := time.Now().Add(timeout)
+	for {
+		err := unix.Connect(int(f.Fd()), addr)
+		if err == nil {
+			break
+		}
+		// Unix-domain sockets fail with EAGAIN, rather than EINPROGRESS, when
+		// the backlog is full.
+		if !errors.Is(err, unix.EAGAIN) {
+			return nil, fmt.Errorf("connect(%q): %w", path, err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("connect(%q): timed out after %v", path, timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := unix.SetNonblock(int(f.Fd()), false); err != nil {
+		return nil, err
 	}
+	cu.Release()
+	return f, nil
+}
 
-	// Perform handshake. See common.proto for details about the protocol.
-	hsOut := pb.Handshake{Version: wire.CurrentVersion}
+// setupTCP connects to the remote process listening on the TCP address addr.
+// If TLS is enabled, the TLS session is established before handshake and then
+// offloaded to the kernel, see startTLS. See connectConfig and handshake for
+// how config is used.
+func setupTCP(addr string, config map[string]interface{}) (*os.File, error) {
+	tlsConfig, err := parseTLSConfig(config, addr)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := parseConnectConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if reliable, err := parseBool(config, "reliable"); err != nil {
+		return nil, err
+	} else if reliable && tlsConfig != nil {
+		// Only writes are offloaded to the kernel, so acknowledgments
+		// couldn't be decrypted by the sandbox.
+		return nil, fmt.Errorf("reliable is not supported with tls")
+	}
+	if _, ok := config["verdict_types"]; ok && tlsConfig != nil {
+		// Likewise for verdicts.
+		return nil, fmt.Errorf("verdict_types is not supported with tls")
+	}
+	log.Debugf("Remote sink connecting to tcp %q, tls: %t", addr, tlsConfig != nil)
+	var conn net.Conn
+	if err := cc.retry(fmt.Sprintf("remote sink tcp %q", addr), func() error {
+		var err error
+		conn, err = net.DialTimeout("tcp", addr, cc.timeout)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	// File returns a blocking duplicate of the connection's file descriptor, so
+	// the connection itself is no longer needed after setup.
+	defer conn.Close()
+	f, err := conn.(*net.TCPConn).File()
+	if err != nil {
+		return nil, fmt.Errorf("getting file for tcp connection to %q: %w", addr, err)
+	}
+	cu := cleanup.Make(func() {
+		_ = f.Close()
+	})
+	defer cu.Clean()
+
+	var rw io.ReadWriter = f
+	if tlsConfig != nil {
+		tlsConn, err := startTLS(conn, f, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		// Writes go to f to be encrypted by the kernel, while reads still need
+		// to be decrypted by tlsConn.
+		rw = struct {
+			io.Reader
+			io.Writer
+		}{tlsConn, f}
+	}
+	if err := handshake(rw, true /* stream */, false /* sharedMemory */, config); err != nil {
+		return nil, err
+	}
+	if err := unix.SetNonblock(int(f.Fd()), true); err != nil {
+		return nil, err
+	}
+
+	cu.Release()
+	return f, nil
+}
+
+// handshake performs version exchange with the remote process over rw,
+// negotiates the compression and encoding among the ones accepted in config,
+// and checks that the remote accepts shared memory, acknowledgments and
+// verdicts if requested. The compression and encoding picked, and the message types the
+// remote requests, if any, are added to config to be used by new. See
+// common.proto for details about the protocol.
+func handshake(rw io.ReadWriter, stream, sharedMemory bool, config map[string]interface{}) error {
+	acceptedCompressions, err := parseCompressions(config)
+	if err != nil {
+		return err
+	}
+	acceptedEncodings, err := parseEncodings(config)
+	if err != nil {
+		return err
+	}
+	batchSize, err := parseBatchSize(config)
+	if err != nil {
+		return err
+	}
+	reliable, err := parseBool(config, "reliable")
+	if err != nil {
+		return err
+	}
+	_, verdicts := config["verdict_types"]
+	if verdicts && sharedMemory {
+		return fmt.Errorf("verdict_types is not supported with shared memory")
+	}
+	interning, err := parseBool(config, "interning")
+	if err != nil {
+		return err
+	}
+	if interning && sharedMemory {
+		return fmt.Errorf("interning is not supported with shared memory")
+	}
+	hsOut := pb.Handshake{
+		Version:      wire.CurrentVersion,
+		Compression:  acceptedCompressions[0],
+		Compressions: acceptedCompressions,
+		Encoding:     acceptedEncodings[0],
+		Encodings:    acceptedEncodings,
+		MessageTypes: supportedMessageTypes(),
+		Batch:        batchSize > 1,
+		SharedMemory: sharedMemory,
+		Acks:         reliable,
+		Verdicts:     verdicts,
+		Interning:    interning,
+	}
+	if opaque, ok := config["sandbox_id"]; ok {
+		if hsOut.SandboxId, ok = opaque.(string); !ok {
+			return fmt.Errorf("sandbox_id %v is not a string", opaque)
+		}
+	}
 	out, err := proto.Marshal(&hsOut)
 	if err != nil {
-		return nil, fmt.Errorf("marshalling handshake message: %w", err)
+		return fmt.Errorf("marshalling handshake message: %w", err)
+	}
+	if stream {
+		framed := make([]byte, wire.FrameLengthSize, wire.FrameLengthSize+len(out))
+		binary.LittleEndian.PutUint32(framed, uint32(len(out)))
+		out = append(framed, out...)
 	}
-	if _, err := f.Write(out); err != nil {
-		return nil, fmt.Errorf("sending handshake message: %w", err)
+	if _, err := rw.Write(out); err != nil {
+		return fmt.Errorf("sending handshake message: %w", err)
 	}
 
 	in := make([]byte, 10240)
-	read, err := f.Read(in)
-	if err != nil && !errors.Is(err, io.EOF) {
-		return nil, fmt.Errorf("reading handshake message: %w", err)
-	}
-	// Protect against the handshake becoming larger than the buffer allocated
-	// for it.
-	if read == len(in) {
-		return nil, fmt.Errorf("handshake message too big")
+	var read int
+	if stream {
+		var frame [wire.FrameLengthSize]byte
+		if _, err := io.ReadFull(rw, frame[:]); err != nil {
+			return fmt.Errorf("reading handshake message: %w", err)
+		}
+		length := binary.LittleEndian.Uint32(frame[:])
+		// Protect against the handshake becoming larger than the buffer
+		// allocated for it.
+		if length >= uint32(len(in)) {
+			return fmt.Errorf("handshake message too big")
+		}
+		if read, err = io.ReadFull(rw, in[:length]); err != nil {
+			return fmt.Errorf("reading handshake message: %w", err)
+		}
+	} else {
+		read, err = rw.Read(in)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("reading handshake message: %w", err)
+		}
+		// Protect against the handshake becoming larger than the buffer
+		// allocated for it.
+		if read == len(in) {
+			return fmt.Errorf("handshake message too big")
+		}
 	}
 	hsIn := pb.Handshake{}
 	if err := proto.Unmarshal(in[:read], &hsIn); err != nil {
-		return nil, fmt.Errorf("unmarshalling handshake message: %w", err)
+		return fmt.Errorf("unmarshalling handshake message: %w", err)
+	}
+
+	// Check that remote version can be supported. Newer remotes must support
+	// older versions, so messages are written with the lowest of both.
+	if hsIn.Version < wire.MinSupportedVersion {
+		return fmt.Errorf("remote version (%d) is smaller than minimum supported (%d)", hsIn.Version, wire.MinSupportedVersion)
+	}
+	version := uint32(wire.CurrentVersion)
+	if hsIn.Version < version {
+		version = hsIn.Version
+	}
+	config["version"] = float64(version)
+	if !containsCompression(acceptedCompressions, hsIn.Compression) {
+		return fmt.Errorf("remote doesn't support compression %v, replied with %v", acceptedCompressions, hsIn.Compression)
+	}
+	config["compression"] = compressionName(hsIn.Compression)
+	if !containsEncoding(acceptedEncodings, hsIn.Encoding) {
+		return fmt.Errorf("remote doesn't support encoding %v, replied with %v", acceptedEncodings, hsIn.Encoding)
+	}
+	config["encoding"] = encodingName(hsIn.Encoding)
+	if sharedMemory && !hsIn.SharedMemory {
+		return fmt.Errorf("remote doesn't support shared memory")
+	}
+	if reliable && !hsIn.Acks {
+		return fmt.Errorf("remote doesn't support acknowledgments")
+	}
+	if verdicts && !hsIn.Verdicts {
+		return fmt.Errorf("remote doesn't support verdicts")
+	}
+	if hsIn.MaxMessageSize != 0 {
+		if hsIn.MaxMessageSize < wire.MinMaxMessageSize {
+			return fmt.Errorf("remote max message size (%d) is smaller than minimum supported (%d)", hsIn.MaxMessageSize, wire.MinMaxMessageSize)
+		}
+		config["max_message_size"] = float64(hsIn.MaxMessageSize)
+	}
+	if hsOut.Batch && !hsIn.Batch {
+		log.Infof("Remote doesn't accept batches, points will be sent one at a time")
+		config["batch_size"] = float64(1)
+	}
+	if hsOut.Interning && !hsIn.Interning {
+		log.Infof("Remote doesn't accept interning, strings will be sent as is")
+		config["interning"] = false
 	}
+	if len(hsIn.RequestedTypes) > 0 {
+		var types []interface{}
+		for _, t := range hsIn.RequestedTypes {
+			// Skip types that are unknown to this version.
+			if _, ok := pb.MessageType_name[int32(t)]; ok {
+				types = append(types, t.String())
+			}
+		}
+		config["message_types"] = types
+	}
+	return nil
+}
 
-	// Check that remote version can be supported.
-	const minSupportedVersion = 1
-	if hsIn.Version < minSupportedVersion {
-		return nil, fmt.Errorf("remote version (%d) is smaller than minimum supported (%d)", hsIn.Version, minSupportedVersion)
+func containsCompression(list []pb.Compression, compression pb.Compression) bool {
+	for _, c := range list {
+		if c == compression {
+			return true
+		}
 	}
+	return false
+}
 
-	if err := unix.SetNonblock(int(f.Fd()), true); err != nil {
-		return nil, err
+func containsEncoding(list []pb.Encoding, encoding pb.Encoding) bool {
+	for _, e := range list {
+		if e == encoding {
+			return true
+		}
 	}
+	return false
+}
 
-	cu.Release()
+// compressionName returns the "compression" configuration for compression.
+func compressionName(compression pb.Compression) string {
+	for name, c := range compressions {
+		if c == compression {
+			return name
+		}
+	}
+	panic(fmt.Sprintf("unknown compression %v", compression))
+}
+
+// encodingName returns the "encoding" configuration for encoding.
+func encodingName(encoding pb.Encoding) string {
+	for name, e := range encodings {
+		if e == encoding {
+			return name
+		}
+	}
+	panic(fmt.Sprintf("unknown encoding %v", encoding))
+}
+
+// parseVersion returns the "version" configuration, which is set by handshake
+// to the version negotiated with the remote process, or wire.CurrentVersion
+// if it's not set. Messages can't be written with versions that are not
+// supported.
+func parseVersion(config map[string]interface{}) (uint32, error) {
+	opaque, ok := config["version"]
+	if !ok {
+		return wire.CurrentVersion, nil
+	}
+	version, ok := opaque.(float64)
+	if !ok || version != float64(uint32(version)) || version < wire.MinSupportedVersion || version > wire.CurrentVersion {
+		return 0, fmt.Errorf("version %v must be between %d and %d", opaque, wire.MinSupportedVersion, wire.CurrentVersion)
+	}
+	return uint32(version), nil
+}
+
+// parseBatchSize returns the "batch_size" configuration, or 1 if it's not set.
+func parseBatchSize(config map[string]interface{}) (int, error) {
+	opaque, ok := config["batch_size"]
+	if !ok {
+		return 1, nil
+	}
+	size, ok := opaque.(float64)
+	if !ok || size != float64(int(size)) || size <= 0 {
+		return 0, fmt.Errorf("batch_size %v is not a positive int", opaque)
+	}
+	return int(size), nil
+}
+
+// supportedMessageTypes returns all message types that can be sent.
+func supportedMessageTypes() []pb.MessageType {
+	types := make([]pb.MessageType, 0, len(pb.MessageType_name))
+	for t := range pb.MessageType_name {
+		if t != int32(pb.MessageType_MESSAGE_UNKNOWN) {
+			types = append(types, pb.MessageType(t))
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// messageFilter is the set of message types that the remote process accepts.
+// A nil *messageFilter accepts all types.
+type messageFilter struct {
+	types map[pb.MessageType]struct{}
+}
+
+func parseMessageTypes(config map[string]interface{}) (*messageFilter, error) {
+	opaque, ok := config["message_types"]
+	if !ok {
+		return nil, nil
+	}
+	names, ok := opaque.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("message_types %v is not a list", opaque)
+	}
+	f := &messageFilter{types: make(map[pb.MessageType]struct{})}
+	for _, opaque := range names {
+		name, ok := opaque.(string)
+		if !ok {
+			return nil, fmt.Errorf("message type %v is not a string", opaque)
+		}
+		t, ok := pb.MessageType_value[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid message type %q", name)
+		}
+		f.types[pb.MessageType(t)] = struct{}{}
+	}
 	return f, nil
 }
 
+func (f *messageFilter) accepts(msgType pb.MessageType) bool {
+	if f == nil {
+		return true
+	}
+	_, ok := f.types[msgType]
+	return ok
+}
+
+// parseBool returns the boolean configuration called name, or false if it's not
+// set.
+func parseBool(config map[string]interface{}, name string) (bool, error) {
+	opaque, ok := config[name]
+	if !ok {
+		return false, nil
+	}
+	value, ok := opaque.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s %v is not a bool", name, opaque)
+	}
+	return value, nil
+}
+
 func parseDuration(config map[string]interface{}, name string) (bool, time.Duration, error) {
 	opaque, ok := config[name]
 	if !ok {
@@ -156,9 +896,42 @@ func new(config map[string]interface{}, endpoint *fd.FD) (seccheck.Checker, erro
 	}
 	r := &remote{
 		endpoint:       endpoint,
+		sessionClosed:  true,
 		initialBackoff: 25 * time.Microsecond,
 		maxBackoff:     10 * time.Millisecond,
 	}
+	transport, err := parseTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	r.stream = transport == transportTCP
+	if r.compression, err = parseCompression(config); err != nil {
+		return nil, err
+	}
+	if r.encoding, err = parseEncoding(config); err != nil {
+		return nil, err
+	}
+	if r.filter, err = parseMessageTypes(config); err != nil {
+		return nil, err
+	}
+	if r.fields, err = parseFilters(config); err != nil {
+		return nil, err
+	}
+	if r.sampler, err = parseSampleRates(config); err != nil {
+		return nil, err
+	}
+	if r.checksum, err = parseBool(config, "checksum"); err != nil {
+		return nil, err
+	}
+	if r.maxMessageSize, err = parseMaxMessageSize(config); err != nil {
+		return nil, err
+	}
+	if r.version, err = parseVersion(config); err != nil {
+		return nil, err
+	}
+	if r.stopTimeout, err = parseStopTimeout(config); err != nil {
+		return nil, err
+	}
 	if retriesOpaque, ok := config["retries"]; ok {
 		retries, ok := retriesOpaque.(float64)
 		if !ok {
@@ -182,55 +955,285 @@ func new(config map[string]interface{}, endpoint *fd.FD) (seccheck.Checker, erro
 	if r.initialBackoff > r.maxBackoff {
 		return nil, fmt.Errorf("initial backoff (%v) cannot be larger than max backoff (%v)", r.initialBackoff, r.maxBackoff)
 	}
+	// Points are queued for a writer goroutine, so that a slow remote process
+	// shows up as queue growth and drops rather than as latency for the
+	// workload. Points that wait for a verdict are written synchronously, like
+	// all points if the sink is configured to be.
+	synchronous, err := parseBool(config, "synchronous")
+	if err != nil {
+		return nil, err
+	}
+	defQueueSize := defaultQueueSize
+	if _, verdicts := config["verdict_types"]; verdicts || synchronous {
+		defQueueSize = 0
+	}
+	queueSize, err := parseQueueSize(config, defQueueSize)
+	if err != nil {
+		return nil, err
+	}
+	if synchronous && queueSize > 0 {
+		return nil, fmt.Errorf("synchronous is not supported with queue_size")
+	}
+	if r.batchSize, err = parseBatchSize(config); err != nil {
+		return nil, err
+	}
+	if r.batchSize > 1 && queueSize == 0 {
+		return nil, fmt.Errorf("batch_size requires queue_size to be set")
+	}
+	stagingBuffers, err := parseStagingBuffers(config)
+	if err != nil {
+		return nil, err
+	}
+	if stagingBuffers > 0 && queueSize < stagingBuffers {
+		return nil, fmt.Errorf("staging_buffers (%d) requires queue_size to be set to at least as many points", stagingBuffers)
+	}
+	dropStatsInterval, err := parseDropStatsInterval(config)
+	if err != nil {
+		return nil, err
+	}
+	reliable, err := parseBool(config, "reliable")
+	if err != nil {
+		return nil, err
+	}
+	if reliable {
+		if queueSize == 0 {
+			return nil, fmt.Errorf("reliable requires queue_size to be set")
+		}
+		if stagingBuffers > 0 {
+			// Acknowledgments assume that points are written in sequence
+			// order, which staging buffers don't guarantee across tasks.
+			return nil, fmt.Errorf("reliable is not supported with staging_buffers")
+		}
+		replaySize, err := parseReplaySize(config, queueSize)
+		if err != nil {
+			return nil, err
+		}
+		if replaySize < r.batchSize {
+			return nil, fmt.Errorf("replay_size (%d) cannot be smaller than batch_size (%d)", replaySize, r.batchSize)
+		}
+		r.replay = newReplayBuffer(replaySize)
+		r.retransmitTimeout = defaultRetransmitTimeout
+		if ok, timeout, err := parseDuration(config, "retransmit_timeout"); err != nil {
+			return nil, err
+		} else if ok {
+			r.retransmitTimeout = timeout
+		}
+	}
+	if r.verdicts, err = parseVerdicts(config); err != nil {
+		return nil, err
+	}
+	if r.adaptive, err = parseAdaptiveSampling(config); err != nil {
+		return nil, err
+	}
+	if r.adaptive != nil && queueSize == 0 {
+		return nil, fmt.Errorf("adaptive_sampling requires queue_size to be set")
+	}
+	interning, err := parseBool(config, "interning")
+	if err != nil {
+		return nil, err
+	}
+	if interning {
+		if reliable {
+			// Retransmitted points may define strings after the points that
+			// refer to them were received.
+			return nil, fmt.Errorf("interning is not supported with reliable")
+		}
+		r.interned = newStringTable()
+	}
+	if r.verdicts != nil && queueSize > 0 {
+		return nil, fmt.Errorf("verdict_types is not supported with queue_size")
+	}
+	if queueSize > 0 {
+		if stagingBuffers > 0 {
+			r.ring = newStagingBuffers(stagingBuffers, queueSize)
+		} else {
+			r.ring = newRingBuffer(queueSize)
+		}
+		r.flushDone = make(chan struct{})
+		r.reconnected = make(chan struct{}, 1)
+		go r.flush() // S/R-SAFE: sinks are not saved.
+		if r.replay != nil {
+			r.acksDone = make(chan struct{})
+			go r.receiveAcks() // S/R-SAFE: sinks are not saved.
+		}
+	}
+	if r.verdicts != nil {
+		go r.receiveVerdicts() // S/R-SAFE: sinks are not saved.
+	}
+	if dropStatsInterval > 0 {
+		r.startDropStats(dropStatsInterval)
+	}
+	if r.adaptive != nil {
+		r.startAdaptiveSampling(queueSize)
+	}
 
 	log.Debugf("Remote sink created, endpoint FD: %d, %+v", r.endpoint.FD(), r)
 	return r, nil
 }
 
-func (*remote) Name() string {
+func (r *remote) Name() string {
+	if r.sender != nil {
+		return r.sender.name()
+	}
 	return name
 }
 
+// Status implements seccheck.Checker. Besides drops, it reports whether the
+// remote process is still connected, how many points are queued, and the last
+// error, so that operators can tell whether points are actually received.
 func (r *remote) Status() seccheck.CheckerStatus {
-	return seccheck.CheckerStatus{
+	status := seccheck.CheckerStatus{
 		DroppedCount: uint64(r.droppedCount.Load()),
 	}
+	if r.sender == nil {
+		status.State = seccheck.SinkConnected
+		if r.disconnected.Load() != 0 {
+			status.State = seccheck.SinkDisconnected
+		}
+	}
+	if r.ring != nil {
+		status.QueueDepth = uint64(r.ring.len())
+	}
+	r.errMu.Lock()
+	if r.lastErr != nil {
+		status.LastError = r.lastErr.Error()
+		status.LastErrorTimeNs = r.lastErrTime.UnixNano()
+	}
+	r.errMu.Unlock()
+	if reporter, ok := r.sender.(statusReporter); ok {
+		reporter.status(&status)
+	}
+	if r.replay != nil {
+		r.replay.status(&status)
+	}
+	if r.fields != nil {
+		r.fields.status(&status)
+	}
+	if r.sampler != nil {
+		r.sampler.status(&status)
+	}
+	if r.adaptive != nil {
+		r.adaptive.status(&status)
+	}
+	if r.interned != nil {
+		r.interned.status(&status)
+	}
+	if r.verdicts != nil {
+		r.verdicts.status(&status)
+	}
+	return status
+}
+
+// StopReason implements seccheck.StopReasoner.
+func (r *remote) StopReason(reason string) {
+	r.stopReason = reason
 }
 
-// Stop implements seccheck.Checker.
+// Stop implements seccheck.Checker. Points that are queued are written before
+// the connection is closed, for up to stopTimeout, followed by a SessionClosed
+// message.
 func (r *remote) Stop() {
+	if r.drops != nil {
+		close(r.drops.stop)
+		<-r.drops.done
+	}
+	if r.adaptive != nil {
+		r.adaptive.stopAdapting()
+	}
+	if r.sessionClosed {
+		r.writeSessionClosed()
+	}
+	if r.verdicts != nil {
+		r.stopVerdicts()
+	}
+	if r.sender != nil {
+		r.sender.stop()
+		return
+	}
+	deadline := time.Now().Add(r.stopTimeout)
+	if r.ring != nil {
+		r.stopQueue(deadline)
+	}
 	if r.endpoint != nil {
-		// It's possible to race with Point firing, but in the worst case they will
-		// simply fail to be delivered.
-		r.endpoint.Close()
+		r.closeEndpoint(deadline)
 	}
 }
 
-func (r *remote) write(msg proto.Message, msgType pb.MessageType) {
-	out, err := proto.Marshal(msg)
+// write sends a point to the remote process. It returns an error if the point
+// was dropped, which is handled according to the error policy of the point,
+// see seccheck.PointReq.FailClosed. Points that are filtered out are not
+// errors.
+func (r *remote) write(msg proto.Message, msgType pb.MessageType) error {
+	if !r.filter.accepts(msgType) || !r.fields.keep(msg) || !r.sampler.keep(msgType) || !r.adaptive.keep(msgType) {
+		return nil
+	}
+	if r.sender != nil {
+		return r.sender.send(r, msg, msgType)
+	}
+	// The sequence number is taken even if the point is dropped, so that the
+	// remote can tell that points are missing.
+	sequence, timeNs := r.sequence.Add(1), time.Now().UnixNano()
+	if r.ring == nil && r.disconnected.Load() != 0 {
+		r.dropped(msgType, 1)
+		return errDisconnected
+	}
+	// Points queued in the ring are written later, so only the payloads that
+	// are written before returning can use a pooled buffer.
+	var buf *payloadBuffer
+	if r.ring == nil {
+		buf = getPayloadBuffer()
+	}
+	out, flags, defined, err := r.marshalInterned(buf.bytes(), msg)
+	defer buf.release(out)
 	if err != nil {
-		log.Debugf("Marshal(%+v): %v", msg, err)
-		return
+		log.Debugf("Dropping %v point: %v", msgType, err)
+		r.setError(err)
+		r.dropped(msgType, 1)
+		return err
+	}
+	if r.ring != nil {
+		e := ringEntry{
+			msgType:  msgType,
+			payload:  out,
+			sequence: sequence,
+			timeNs:   timeNs,
+			flags:    flags,
+			defined:  defined,
+		}
+		if !r.ring.push(e) {
+			r.dropped(msgType, 1)
+			return errQueueFull
+		}
+		return nil
 	}
-	hdr := wire.Header{
-		HeaderSize:   uint16(wire.HeaderStructSize),
-		DroppedCount: r.droppedCount.Load(),
-		MessageType:  uint16(msgType),
+	if r.verdicts.requested(msgType, msg) {
+		return r.writeForVerdict(msgType, sequence, timeNs, flags, out, defined)
+	}
+	if err := r.writeMessage(msgType, sequence, timeNs, flags, out); err != nil {
+		return err
+	}
+	r.interned.setWritten(defined)
+	return nil
+}
+
+// writeMessage writes a single point to the endpoint, retrying as configured
+// while it's full.
+func (r *remote) writeMessage(msgType pb.MessageType, sequence uint64, timeNs int64, flags uint32, out []byte) error {
+	hdrOut := r.header(uint16(msgType), sequence, timeNs, flags, out)
+	if r.stream {
+		return r.writeStream(msgType, hdrOut[:], out)
 	}
-	var hdrOut [wire.HeaderStructSize]byte
-	hdr.MarshalUnsafe(hdrOut[:])
 
 	backoff := r.initialBackoff
 	for i := 0; ; i++ {
 		_, err := unix.Writev(r.endpoint.FD(), [][]byte{hdrOut[:], out})
 		if err == nil {
 			// Write succeeded, we're done!
-			return
+			return nil
 		}
 		if !errors.Is(err, unix.EAGAIN) || i >= r.retries {
-			log.Debugf("Write failed, dropping point: %v", err)
-			r.droppedCount.Add(1)
-			return
+			r.writeFailed(err, msgType)
+			return err
 		}
 		log.Debugf("Write failed, retrying (%d/%d) in %v: %v", i+1, r.retries, backoff, err)
 		time.Sleep(backoff)
@@ -241,44 +1244,334 @@ func (r *remote) write(msg proto.Message, msgType pb.MessageType) {
 	}
 }
 
+// writeStream writes a message framed with its length to a stream endpoint.
+// Once part of the message has been written, the rest must follow to keep the
+// stream in sync, so retries are only bounded until the first byte is out.
+func (r *remote) writeStream(msgType pb.MessageType, hdr, payload []byte) error {
+	var frame [wire.FrameLengthSize]byte
+	binary.LittleEndian.PutUint32(frame[:], uint32(len(hdr)+len(payload)))
+	bufs := [][]byte{frame[:], hdr, payload}
+
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	backoff := r.initialBackoff
+	written := false
+	for i := 0; ; i++ {
+		n, err := unix.Writev(r.endpoint.FD(), bufs)
+		if n > 0 {
+			written = true
+			bufs = advance(bufs, n)
+			if len(bufs) == 0 {
+				return nil
+			}
+		}
+		if (err != nil && !errors.Is(err, unix.EAGAIN)) || (!written && i >= r.retries) {
+			r.writeFailed(err, msgType)
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		if r.maxBackoff > 0 && backoff > r.maxBackoff {
+			backoff = r.maxBackoff
+		}
+	}
+}
+
+// writeFailed accounts for a point of type msgType that failed to be written
+// with err.
+func (r *remote) writeFailed(err error, msgType pb.MessageType) {
+	log.Debugf("Write failed, dropping %v point: %v", msgType, err)
+	r.setError(err)
+	r.dropped(msgType, 1)
+	r.checkDisconnected(err)
+}
+
+// setError records err as the last error, see Status.
+func (r *remote) setError(err error) {
+	r.errMu.Lock()
+	defer r.errMu.Unlock()
+	r.lastErr = err
+	r.lastErrTime = time.Now()
+}
+
+// checkDisconnected marks the sink as disconnected if err indicates that the
+// remote process went away.
+func (r *remote) checkDisconnected(err error) {
+	if errors.Is(err, unix.EPIPE) || errors.Is(err, unix.ECONNRESET) || errors.Is(err, unix.ENOTCONN) {
+		if r.disconnected.CompareAndSwap(0, 1) {
+			if r.ring != nil {
+				log.Warningf("Remote sink disconnected, points will be queued until it's reconnected: %v", err)
+			} else {
+				log.Warningf("Remote sink disconnected, points will be dropped until it's reconnected: %v", err)
+			}
+		}
+	}
+}
+
+// Reconnect implements seccheck.Reconnecter. The new connection must have
+// negotiated the same protocol as the previous one during handshake, since
+// queued points were serialized for it. Only sinks that queue points can be
+// reconnected, so that the connection is only used by flush, and it isn't
+// supported with features that keep state in the remote process, i.e.
+// reliable mode and interning.
+func (r *remote) Reconnect(config map[string]interface{}, endpoint *fd.FD) error {
+	if r.sender != nil {
+		return fmt.Errorf("sink %q cannot be reconnected", r.sender.name())
+	}
+	if r.ring == nil {
+		return fmt.Errorf("reconnection requires queue_size to be set")
+	}
+	if r.replay != nil {
+		return fmt.Errorf("reconnection is not supported with reliable")
+	}
+	if r.interned != nil {
+		return fmt.Errorf("reconnection is not supported with interning")
+	}
+	if endpoint == nil {
+		return fmt.Errorf("remote sink requires an endpoint")
+	}
+	transport, err := parseTransport(config)
+	if err != nil {
+		return err
+	}
+	if stream := transport == transportTCP; stream != r.stream {
+		return fmt.Errorf("transport changed to %q", transport)
+	}
+	compression, err := parseCompression(config)
+	if err != nil {
+		return err
+	}
+	if compression != r.compression {
+		return fmt.Errorf("compression changed from %q to %q", compressionName(r.compression), compressionName(compression))
+	}
+	encoding, err := parseEncoding(config)
+	if err != nil {
+		return err
+	}
+	if encoding != r.encoding {
+		return fmt.Errorf("encoding changed from %q to %q", encodingName(r.encoding), encodingName(encoding))
+	}
+	version, err := parseVersion(config)
+	if err != nil {
+		return err
+	}
+	if version != r.version {
+		return fmt.Errorf("version changed from %d to %d", r.version, version)
+	}
+	maxMessageSize, err := parseMaxMessageSize(config)
+	if err != nil {
+		return err
+	}
+	if maxMessageSize != r.maxMessageSize {
+		return fmt.Errorf("max message size changed from %d to %d", r.maxMessageSize, maxMessageSize)
+	}
+
+	r.writeMu.Lock()
+	old := r.endpoint
+	r.endpoint = endpoint
+	r.writeMu.Unlock()
+	old.Close()
+
+	r.disconnected.Store(0)
+	select {
+	case r.reconnected <- struct{}{}:
+	default:
+	}
+	log.Infof("Remote sink reconnected, %d point(s) queued", r.ring.len())
+	return nil
+}
+
+// waitReconnect waits until the sink is reconnected or ring is closed.
+func (r *remote) waitReconnect() {
+	ticker := time.NewTicker(flushPollTimeout)
+	defer ticker.Stop()
+	for r.disconnected.Load() != 0 && !r.ring.isClosed() {
+		select {
+		case <-r.reconnected:
+		case <-ticker.C:
+		}
+	}
+}
+
+// advance drops the first n bytes from bufs.
+func advance(bufs [][]byte, n int) [][]byte {
+	for len(bufs) > 0 && n >= len(bufs[0]) {
+		n -= len(bufs[0])
+		bufs = bufs[1:]
+	}
+	if len(bufs) > 0 {
+		bufs[0] = bufs[0][n:]
+	}
+	return bufs
+}
+
 // Clone implements seccheck.Checker.
 func (r *remote) Clone(_ context.Context, _ seccheck.FieldSet, info *pb.CloneInfo) error {
-	r.write(info, pb.MessageType_MESSAGE_SENTRY_CLONE)
-	return nil
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_CLONE)
 }
 
 // Execve implements seccheck.Checker.
 func (r *remote) Execve(_ context.Context, _ seccheck.FieldSet, info *pb.ExecveInfo) error {
-	r.write(info, pb.MessageType_MESSAGE_SENTRY_EXEC)
-	return nil
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_EXEC)
 }
 
 // ExitNotifyParent implements seccheck.Checker.
 func (r *remote) ExitNotifyParent(_ context.Context, _ seccheck.FieldSet, info *pb.ExitNotifyParentInfo) error {
-	r.write(info, pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT)
-	return nil
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT)
 }
 
 // TaskExit implements seccheck.Checker.
 func (r *remote) TaskExit(_ context.Context, _ seccheck.FieldSet, info *pb.TaskExit) error {
-	r.write(info, pb.MessageType_MESSAGE_SENTRY_TASK_EXIT)
-	return nil
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_TASK_EXIT)
+}
+
+// SignalDeliver implements seccheck.Checker.
+func (r *remote) SignalDeliver(_ context.Context, _ seccheck.FieldSet, info *pb.SignalDeliverInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_SIGNAL_DELIVER)
+}
+
+// OOM implements seccheck.Checker.
+func (r *remote) OOM(_ context.Context, _ seccheck.FieldSet, info *pb.OOMInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_OOM)
+}
+
+// Checkpoint implements seccheck.Checker.
+func (r *remote) Checkpoint(_ context.Context, _ seccheck.FieldSet, info *pb.CheckpointInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_CHECKPOINT)
+}
+
+// Restore implements seccheck.Checker.
+func (r *remote) Restore(_ context.Context, _ seccheck.FieldSet, info *pb.RestoreInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_RESTORE)
+}
+
+// CoreDump implements seccheck.Checker.
+func (r *remote) CoreDump(_ context.Context, _ seccheck.FieldSet, info *pb.CoreDumpInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_CORE_DUMP)
+}
+
+// Seccomp implements seccheck.Checker.
+func (r *remote) Seccomp(_ context.Context, _ seccheck.FieldSet, info *pb.SeccompInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_SECCOMP)
+}
+
+// CapabilityDenied implements seccheck.Checker.
+func (r *remote) CapabilityDenied(_ context.Context, _ seccheck.FieldSet, info *pb.CapabilityDeniedInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_CAPABILITY_DENIED)
+}
+
+// NamespaceCreate implements seccheck.Checker.
+func (r *remote) NamespaceCreate(_ context.Context, _ seccheck.FieldSet, info *pb.NamespaceCreateInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_NAMESPACE_CREATE)
+}
+
+// ExecMap implements seccheck.Checker.
+func (r *remote) ExecMap(_ context.Context, _ seccheck.FieldSet, info *pb.ExecMapInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_EXEC_MAP)
+}
+
+// SyntheticFileWrite implements seccheck.Checker.
+func (r *remote) SyntheticFileWrite(_ context.Context, _ seccheck.FieldSet, info *pb.SyntheticFileWriteInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_SYNTHETIC_FILE_WRITE)
+}
+
+// TCPEstablished implements seccheck.Checker.
+func (r *remote) TCPEstablished(_ context.Context, _ seccheck.FieldSet, info *pb.TCPEstablishedInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_TCP_ESTABLISHED)
+}
+
+// DNSQuery implements seccheck.Checker.
+func (r *remote) DNSQuery(_ context.Context, _ seccheck.FieldSet, info *pb.DNSQueryInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_DNS_QUERY)
+}
+
+// Listen implements seccheck.Checker.
+func (r *remote) Listen(_ context.Context, _ seccheck.FieldSet, info *pb.ListenInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_LISTEN)
+}
+
+// GoferOp implements seccheck.Checker.
+func (r *remote) GoferOp(_ context.Context, _ seccheck.FieldSet, info *pb.GoferOpInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_GOFER_OP)
+}
+
+// FileOpen implements seccheck.Checker.
+func (r *remote) FileOpen(_ context.Context, _ seccheck.FieldSet, info *pb.FileOpenInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_FILE_OPEN)
+}
+
+// TTYData implements seccheck.Checker.
+func (r *remote) TTYData(_ context.Context, _ seccheck.FieldSet, info *pb.TTYDataInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_TTY_DATA)
+}
+
+// MajorFault implements seccheck.Checker.
+func (r *remote) MajorFault(_ context.Context, _ seccheck.FieldSet, info *pb.MajorFaultInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_MAJOR_FAULT)
+}
+
+// RLimitBreach implements seccheck.Checker.
+func (r *remote) RLimitBreach(_ context.Context, _ seccheck.FieldSet, info *pb.RLimitBreachInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_RLIMIT_BREACH)
+}
+
+// CPUThrottle implements seccheck.Checker.
+func (r *remote) CPUThrottle(_ context.Context, _ seccheck.FieldSet, info *pb.CPUThrottleInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_CPU_THROTTLE)
+}
+
+// PolicyViolation implements seccheck.Checker.
+func (r *remote) PolicyViolation(_ context.Context, _ seccheck.FieldSet, info *pb.PolicyViolationInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_POLICY_VIOLATION)
+}
+
+// FilelessExec implements seccheck.Checker.
+func (r *remote) FilelessExec(_ context.Context, _ seccheck.FieldSet, info *pb.FilelessExecInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_FILELESS_EXEC)
+}
+
+// AnomalyAlert implements seccheck.Checker.
+func (r *remote) AnomalyAlert(_ context.Context, _ seccheck.FieldSet, info *pb.AnomalyAlertInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_ANOMALY_ALERT)
+}
+
+// Drift implements seccheck.Checker.
+func (r *remote) Drift(_ context.Context, _ seccheck.FieldSet, info *pb.DriftInfo) error {
+	return r.write(info, pb.MessageType_MESSAGE_SENTRY_DRIFT)
 }
 
 // ContainerStart implements seccheck.Checker.
 func (r *remote) ContainerStart(_ context.Context, _ seccheck.FieldSet, info *pb.Start) error {
-	r.write(info, pb.MessageType_MESSAGE_CONTAINER_START)
-	return nil
+	return r.write(info, pb.MessageType_MESSAGE_CONTAINER_START)
+}
+
+// ContainerStop implements seccheck.Checker.
+func (r *remote) ContainerStop(_ context.Context, _ seccheck.FieldSet, info *pb.Stop) error {
+	return r.write(info, pb.MessageType_MESSAGE_CONTAINER_STOP)
+}
+
+// ContainerPause implements seccheck.Checker.
+func (r *remote) ContainerPause(_ context.Context, _ seccheck.FieldSet, info *pb.Pause) error {
+	return r.write(info, pb.MessageType_MESSAGE_CONTAINER_PAUSE)
+}
+
+// ContainerResume implements seccheck.Checker.
+func (r *remote) ContainerResume(_ context.Context, _ seccheck.FieldSet, info *pb.Resume) error {
+	return r.write(info, pb.MessageType_MESSAGE_CONTAINER_RESUME)
+}
+
+// ContainerExec implements seccheck.Checker.
+func (r *remote) ContainerExec(_ context.Context, _ seccheck.FieldSet, info *pb.Exec) error {
+	return r.write(info, pb.MessageType_MESSAGE_CONTAINER_EXEC)
 }
 
 // RawSyscall implements seccheck.Checker.
 func (r *remote) RawSyscall(_ context.Context, _ seccheck.FieldSet, info *pb.Syscall) error {
-	r.write(info, pb.MessageType_MESSAGE_SYSCALL_RAW)
-	return nil
+	return r.write(info, pb.MessageType_MESSAGE_SYSCALL_RAW)
 }
 
 // Syscall implements seccheck.Checker.
 func (r *remote) Syscall(ctx context.Context, fields seccheck.FieldSet, ctxData *pb.ContextData, msgType pb.MessageType, msg proto.Message) error {
-	r.write(msg, msgType)
-	return nil
+	return r.write(msg, msgType)
 }
@@ -16,7 +16,10 @@ package remote
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -26,13 +29,16 @@ import (
 	"time"
 
 	"github.com/cenkalti/backoff"
+	"golang.org/x/sys/unix"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 	"gvisor.dev/gvisor/pkg/fd"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/conformance"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/test"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
 	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	checktest "gvisor.dev/gvisor/pkg/sentry/seccheck/test"
 	"gvisor.dev/gvisor/pkg/test/testutil"
 )
 
@@ -115,7 +121,7 @@ func TestBasic(t *testing.T) {
 	}
 	defer server.Close()
 
-	endpoint, err := setup(server.Endpoint)
+	endpoint, err := setup(server.Endpoint, nil)
 	if err != nil {
 		t.Fatalf("setup(): %v", err)
 	}
@@ -154,6 +160,249 @@ func TestBasic(t *testing.T) {
 	}
 }
 
+// Test that the remote process can listen in the abstract namespace, written
+// with either a NUL byte or "@".
+func TestAbstract(t *testing.T) {
+	server, err := test.NewAbstractServer()
+	if err != nil {
+		t.Fatalf("NewAbstractServer(): %v", err)
+	}
+	defer server.Close()
+
+	for _, tc := range []struct {
+		name string
+		path string
+	}{
+		{name: "nul", path: server.Endpoint},
+		{name: "at", path: "@" + server.Endpoint[1:]},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server.Reset()
+			endpoint, err := setup(tc.path, nil)
+			if err != nil {
+				t.Fatalf("setup(): %v", err)
+			}
+			endpointFD, err := fd.NewFromFile(endpoint)
+			if err != nil {
+				_ = endpoint.Close()
+				t.Fatalf("NewFromFile(): %v", err)
+			}
+			_ = endpoint.Close()
+
+			r, err := new(nil, endpointFD)
+			if err != nil {
+				t.Fatalf("New(): %v", err)
+			}
+			defer r.Stop()
+
+			info := &pb.ExitNotifyParentInfo{ExitStatus: 123}
+			if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+				t.Fatalf("ExitNotifyParent: %v", err)
+			}
+			server.WaitForCount(1)
+		})
+	}
+}
+
+func TestSequence(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+	server.SetRequestedTypes([]pb.MessageType{pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT})
+
+	config := map[string]interface{}{}
+	endpoint, err := setup(server.Endpoint, config)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+	r, err := new(config, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	defer r.Stop()
+
+	start := time.Now().UnixNano()
+	for i := 0; i < 3; i++ {
+		if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, &pb.ExitNotifyParentInfo{}); err != nil {
+			t.Fatalf("ExitNotifyParent: %v", err)
+		}
+		// Points that are filtered out are not numbered.
+		if err := r.TaskExit(nil, seccheck.FieldSet{}, &pb.TaskExit{}); err != nil {
+			t.Fatalf("TaskExit: %v", err)
+		}
+	}
+	end := time.Now().UnixNano()
+
+	server.WaitForCount(3)
+	for i, pt := range server.GetPoints() {
+		if want := uint64(i + 1); pt.Header.Sequence != want {
+			t.Errorf("wrong sequence number, want: %d, got: %d", want, pt.Header.Sequence)
+		}
+		if pt.Header.TimeNs < start || pt.Header.TimeNs > end {
+			t.Errorf("timestamp %d out of range [%d, %d]", pt.Header.TimeNs, start, end)
+		}
+	}
+}
+
+func TestDropStats(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	config := map[string]interface{}{"drop_stats_interval": "10ms"}
+	endpoint, err := setup(server.Endpoint, config)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+	checker, err := new(config, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	defer checker.Stop()
+	r := checker.(*remote)
+
+	r.dropped(pb.MessageType_MESSAGE_SENTRY_CLONE, 2)
+	r.dropped(pb.MessageType_MESSAGE_SENTRY_EXEC, 1)
+	r.dropped(pb.MessageType_MESSAGE_SENTRY_CLONE, 1)
+
+	server.WaitForCount(1)
+	pt := server.GetPoints()[0]
+	if want := pb.MessageType_MESSAGE_DROP_STATS; pt.MsgType != want {
+		t.Fatalf("wrong message type, want: %v, got: %v", want, pt.MsgType)
+	}
+	if want := uint32(4); pt.Header.DroppedCount != want {
+		t.Errorf("wrong dropped count, want: %d, got: %d", want, pt.Header.DroppedCount)
+	}
+	got := &pb.DropStats{}
+	if err := proto.Unmarshal(pt.Msg, got); err != nil {
+		t.Fatalf("proto.Unmarshal(DropStats): %v", err)
+	}
+	want := []*pb.DropCount{
+		{MessageType: pb.MessageType_MESSAGE_SENTRY_CLONE, Count: 3},
+		{MessageType: pb.MessageType_MESSAGE_SENTRY_EXEC, Count: 1},
+	}
+	if len(got.Drops) != len(want) {
+		t.Fatalf("wrong drops, want: %v, got: %v", want, got.Drops)
+	}
+	for i := range want {
+		if !proto.Equal(want[i], got.Drops[i]) {
+			t.Errorf("wrong drops[%d], want: %v, got: %v", i, want[i], got.Drops[i])
+		}
+	}
+	if got.StartTimeNs == 0 || got.EndTimeNs < got.StartTimeNs {
+		t.Errorf("invalid time range [%d, %d]", got.StartTimeNs, got.EndTimeNs)
+	}
+
+	// Nothing is reported until more points are dropped.
+	time.Sleep(50 * time.Millisecond)
+	if count := server.Count(); count != 1 {
+		t.Errorf("wrong number of messages, want: 1, got: %d", count)
+	}
+}
+
+func TestConnectRetry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "remote.sock")
+
+	// Retries give up when the remote never starts listening.
+	start := time.Now()
+	_, err := connect(path, map[string]interface{}{"connect_retries": float64(2)})
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempt(s)") {
+		t.Fatalf("wrong error: %v", err)
+	}
+	if elapsed, want := time.Since(start), 3*connectInitialBackoff; elapsed < want {
+		t.Errorf("connect() returned after %v, want at least %v", elapsed, want)
+	}
+
+	// Connection succeeds once the remote starts listening.
+	listening := make(chan int, 1)
+	go func() {
+		time.Sleep(connectInitialBackoff)
+		socket, err := unix.Socket(unix.AF_UNIX, unix.SOCK_SEQPACKET, 0)
+		if err != nil {
+			t.Errorf("socket(): %v", err)
+			listening <- -1
+			return
+		}
+		if err := unix.Bind(socket, &unix.SockaddrUnix{Name: path}); err != nil {
+			t.Errorf("bind(): %v", err)
+		} else if err := unix.Listen(socket, 1); err != nil {
+			t.Errorf("listen(): %v", err)
+		}
+		listening <- socket
+	}()
+	f, err := connect(path, map[string]interface{}{"connect_retries": float64(5)})
+	if socket := <-listening; socket >= 0 {
+		defer unix.Close(socket)
+	}
+	if err != nil {
+		t.Fatalf("connect(): %v", err)
+	}
+	_ = f.Close()
+}
+
+func TestConnectConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config map[string]interface{}
+		want   connectConfig
+		err    string
+	}{
+		{
+			name:   "default",
+			config: map[string]interface{}{},
+			want:   connectConfig{timeout: defaultConnectTimeout},
+		},
+		{
+			name: "all",
+			config: map[string]interface{}{
+				"connect_timeout": "1s",
+				"connect_retries": float64(3),
+			},
+			want: connectConfig{timeout: time.Second, retries: 3},
+		},
+		{
+			name:   "bad-timeout",
+			config: map[string]interface{}{"connect_timeout": "0s"},
+			err:    "must be positive",
+		},
+		{
+			name:   "bad-retries",
+			config: map[string]interface{}{"connect_retries": float64(-1)},
+			err:    "connect_retries",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseConnectConfig(tc.config)
+			if len(tc.err) == 0 {
+				if err != nil {
+					t.Fatalf("parseConnectConfig(%v): %v", tc.config, err)
+				}
+				if got != tc.want {
+					t.Errorf("wrong config: want: %+v, got: %+v", tc.want, got)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("wrong error: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
 func TestVersionUnsupported(t *testing.T) {
 	server, err := test.NewServer()
 	if err != nil {
@@ -163,40 +412,1085 @@ func TestVersionUnsupported(t *testing.T) {
 
 	server.SetVersion(0)
 
-	_, err = setup(server.Endpoint)
+	_, err = setup(server.Endpoint, nil)
 	if err == nil || !strings.Contains(err.Error(), "remote version") {
 		t.Fatalf("Wrong error: %v", err)
 	}
 }
 
-func TestVersionNewer(t *testing.T) {
-	server, err := test.NewServer()
+func TestVersionNewer(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	server.SetVersion(wire.CurrentVersion + 10)
+
+	config := map[string]interface{}{}
+	endpoint, err := setup(server.Endpoint, config)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	_ = endpoint.Close()
+	if want, got := float64(wire.CurrentVersion), config["version"]; want != got {
+		t.Errorf("wrong version, want: %v, got: %v", want, got)
+	}
+}
+
+// Test that messages are written with the version of older remotes.
+func TestVersionOlder(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	server.SetVersion(wire.MinSupportedVersion)
+
+	config := map[string]interface{}{}
+	endpoint, err := setup(server.Endpoint, config)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	r, err := new(config, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	info := &pb.ExitNotifyParentInfo{ExitStatus: 123}
+	if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+		t.Fatalf("ExitNotifyParent: %v", err)
+	}
+
+	// The server rejects messages that don't have the negotiated version.
+	server.WaitForCount(1)
+	if got := server.GetPoints()[0].Header.Version; got != wire.MinSupportedVersion {
+		t.Errorf("wrong message version, want: %d, got: %d", wire.MinSupportedVersion, got)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	config := map[string]interface{}{"compression": "gzip"}
+	endpoint, err := setup(server.Endpoint, config)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	r, err := new(config, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	info := &pb.ExitNotifyParentInfo{ExitStatus: 123}
+	if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+		t.Fatalf("ExitNotifyParent: %v", err)
+	}
+
+	server.WaitForCount(1)
+	pt := server.GetPoints()[0]
+	got := &pb.ExitNotifyParentInfo{}
+	if err := proto.Unmarshal(pt.Msg, got); err != nil {
+		t.Errorf("proto.Unmarshal(ExitNotifyParentInfo): %v", err)
+	}
+	if !proto.Equal(info, got) {
+		t.Errorf("Received point is different, want: %+v, got: %+v", info, got)
+	}
+}
+
+// Test that setup fails if the remote doesn't support compression.
+func TestCompressionUnsupported(t *testing.T) {
+	server, err := newExampleServer(true)
+	if err != nil {
+		t.Fatalf("newExampleServer(): %v", err)
+	}
+	defer server.stop()
+
+	_, err = setup(server.path, map[string]interface{}{"compression": "gzip"})
+	if err == nil || !strings.Contains(err.Error(), "compression") {
+		t.Fatalf("Wrong error: %v", err)
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	config := map[string]interface{}{"encoding": "cbor"}
+	endpoint, err := setup(server.Endpoint, config)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	if hs := server.Handshake(); hs == nil || hs.Encoding != pb.Encoding_ENCODING_CBOR {
+		t.Errorf("CBOR not requested in handshake: %+v", hs)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	r, err := new(config, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	defer r.Stop()
+
+	info := &pb.ExitNotifyParentInfo{ExitStatus: 123}
+	if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+		t.Fatalf("ExitNotifyParent: %v", err)
+	}
+
+	server.WaitForCount(1)
+	pt := server.GetPoints()[0]
+	if want := wire.MarshalCBOR(info); !bytes.Equal(pt.Msg, want) {
+		t.Errorf("wrong payload, want: %x, got: %x", want, pt.Msg)
+	}
+}
+
+// Test that setup fails if the remote doesn't support the encoding.
+func TestEncodingUnsupported(t *testing.T) {
+	server, err := newExampleServer(true)
+	if err != nil {
+		t.Fatalf("newExampleServer(): %v", err)
+	}
+	defer server.stop()
+
+	_, err = setup(server.path, map[string]interface{}{"encoding": "cbor"})
+	if err == nil || !strings.Contains(err.Error(), "encoding") {
+		t.Fatalf("Wrong error: %v", err)
+	}
+}
+
+// Test that the first compression and encoding in the sink's preferences that
+// the remote supports are picked.
+func TestNegotiation(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+	exampleServer, err := newExampleServer(true)
+	if err != nil {
+		t.Fatalf("newExampleServer(): %v", err)
+	}
+	defer exampleServer.stop()
+
+	for _, tc := range []struct {
+		name            string
+		path            string
+		wantCompression string
+		wantEncoding    string
+	}{
+		{
+			name:            "supported",
+			path:            server.Endpoint,
+			wantCompression: "gzip",
+			wantEncoding:    "cbor",
+		},
+		{
+			// The example server only supports protobuf without compression.
+			name:            "fallback",
+			path:            exampleServer.path,
+			wantCompression: "none",
+			wantEncoding:    "proto",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := map[string]interface{}{
+				"compression": []interface{}{"gzip", "none"},
+				"encoding":    []interface{}{"cbor", "proto"},
+			}
+			endpoint, err := setup(tc.path, config)
+			if err != nil {
+				t.Fatalf("setup(): %v", err)
+			}
+			_ = endpoint.Close()
+			if got := config["compression"]; got != tc.wantCompression {
+				t.Errorf("wrong compression, want: %q, got: %v", tc.wantCompression, got)
+			}
+			if got := config["encoding"]; got != tc.wantEncoding {
+				t.Errorf("wrong encoding, want: %q, got: %v", tc.wantEncoding, got)
+			}
+		})
+	}
+}
+
+// Test that points carry a checksum of the payload as sent, which the server
+// verifies.
+func TestChecksum(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	config := map[string]interface{}{"checksum": true, "compression": "gzip"}
+	endpoint, err := setup(server.Endpoint, config)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	r, err := new(config, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	info := &pb.ExitNotifyParentInfo{ExitStatus: 123}
+	if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+		t.Fatalf("ExitNotifyParent: %v", err)
+	}
+
+	server.WaitForCount(1)
+	pt := server.GetPoints()[0]
+	if pt.Header.Flags&wire.FlagChecksum == 0 {
+		t.Errorf("Header doesn't have a checksum: %+v", pt.Header)
+	}
+	got := &pb.ExitNotifyParentInfo{}
+	if err := proto.Unmarshal(pt.Msg, got); err != nil {
+		t.Errorf("proto.Unmarshal(ExitNotifyParentInfo): %v", err)
+	}
+	if !proto.Equal(info, got) {
+		t.Errorf("Received point is different, want: %+v, got: %+v", info, got)
+	}
+}
+
+// Test that points larger than the maximum message size declared by the remote
+// are truncated to fit, and flagged as such.
+func TestMaxMessageSize(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+	const maxSize = 1024
+	server.SetMaxMessageSize(maxSize)
+
+	config := map[string]interface{}{}
+	endpoint, err := setup(server.Endpoint, config)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	if want, got := float64(maxSize), config["max_message_size"]; want != got {
+		t.Errorf("wrong max_message_size, want: %v, got: %v", want, got)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	r, err := new(config, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	small := &pb.ExecveInfo{BinaryPath: "/bin/true"}
+	large := &pb.ExecveInfo{
+		BinaryPath: "/bin/echo",
+		Argv:       []string{"echo", strings.Repeat("x", 2*maxSize)},
+		Env:        []string{"PATH=/bin"},
+	}
+	for _, info := range []*pb.ExecveInfo{small, large} {
+		if err := r.Execve(nil, seccheck.FieldSet{}, info); err != nil {
+			t.Fatalf("Execve: %v", err)
+		}
+	}
+	if want, got := 2*maxSize, len(large.Argv[1]); want != got {
+		t.Errorf("point was modified, argv[1] length: %d, want: %d", got, want)
+	}
+
+	server.WaitForCount(2)
+	pts := server.GetPoints()
+	if flags := pts[0].Header.Flags; flags&wire.FlagTruncated != 0 {
+		t.Errorf("small point was truncated, flags: %#x", flags)
+	}
+	pt := pts[1]
+	if flags := pt.Header.Flags; flags&wire.FlagTruncated == 0 {
+		t.Errorf("large point wasn't truncated, flags: %#x", flags)
+	}
+	if size := wire.HeaderStructSize + len(pt.Msg); size > maxSize {
+		t.Errorf("message too big, size: %d, max: %d", size, maxSize)
+	}
+	got := &pb.ExecveInfo{}
+	if err := proto.Unmarshal(pt.Msg, got); err != nil {
+		t.Fatalf("proto.Unmarshal(ExecveInfo): %v", err)
+	}
+	// Only the longest field is cut.
+	want := proto.Clone(large).(*pb.ExecveInfo)
+	want.Argv[1] = got.Argv[1]
+	if !proto.Equal(want, got) || !strings.HasPrefix(large.Argv[1], got.Argv[1]) {
+		t.Errorf("wrong truncated point, want: %+v, got: %+v", want, got)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	endpoint, err := setup(server.Endpoint, nil)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	r, err := new(map[string]interface{}{"queue_size": float64(100)}, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	defer r.Stop()
+
+	const count = 10
+	for i := 0; i < count; i++ {
+		info := &pb.ExitNotifyParentInfo{ExitStatus: int32(i)}
+		if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+			t.Fatalf("ExitNotifyParent: %v", err)
+		}
+	}
+
+	server.WaitForCount(count)
+	for i, pt := range server.GetPoints() {
+		got := &pb.ExitNotifyParentInfo{}
+		if err := proto.Unmarshal(pt.Msg, got); err != nil {
+			t.Fatalf("proto.Unmarshal(ExitNotifyParentInfo): %v", err)
+		}
+		if got.ExitStatus != int32(i) {
+			t.Errorf("point %d out of order, got: %+v", i, got)
+		}
+	}
+}
+
+// Test that points are queued for the writer goroutine, unless the sink is
+// configured to be synchronous.
+func TestWriterQueue(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config map[string]interface{}
+		queued bool
+	}{
+		{
+			name:   "default",
+			config: map[string]interface{}{},
+			queued: true,
+		},
+		{
+			name:   "synchronous",
+			config: map[string]interface{}{"synchronous": true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server, err := test.NewServer()
+			if err != nil {
+				t.Fatalf("newServer(): %v", err)
+			}
+			defer server.Close()
+
+			endpoint, err := setup(server.Endpoint, tc.config)
+			if err != nil {
+				t.Fatalf("setup(): %v", err)
+			}
+			endpointFD, err := fd.NewFromFile(endpoint)
+			if err != nil {
+				_ = endpoint.Close()
+				t.Fatalf("NewFromFile(): %v", err)
+			}
+			_ = endpoint.Close()
+
+			checker, err := new(tc.config, endpointFD)
+			if err != nil {
+				t.Fatalf("New(): %v", err)
+			}
+			r := checker.(*remote)
+			defer r.Stop()
+			if got := r.ring != nil; got != tc.queued {
+				t.Errorf("points queued: got: %t, want: %t", got, tc.queued)
+			}
+
+			info := &pb.ExitNotifyParentInfo{ExitStatus: 123}
+			if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+				t.Fatalf("ExitNotifyParent: %v", err)
+			}
+			server.WaitForCount(1)
+		})
+	}
+}
+
+func TestReliable(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"queue_size": float64(100),
+		"batch_size": float64(4),
+		"reliable":   true,
+		// Shorter than the server's acknowledgment interval, so that points are
+		// retransmitted.
+		"retransmit_timeout": "5ms",
+	}
+	endpoint, err := setup(server.Endpoint, config)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	if !server.Handshake().Acks {
+		t.Errorf("acknowledgments not requested in handshake")
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	r, err := new(config, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	defer r.Stop()
+
+	const count = 10
+	for i := 0; i < count; i++ {
+		info := &pb.ExitNotifyParentInfo{ExitStatus: int32(i)}
+		if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+			t.Fatalf("ExitNotifyParent: %v", err)
+		}
+	}
+
+	// Wait for all points to be acknowledged.
+	unacked := func() uint64 {
+		for _, s := range r.Status().Metrics {
+			if s.Name == "runsc_trace_unacked_points" {
+				return s.Value
+			}
+		}
+		t.Fatalf("runsc_trace_unacked_points not reported")
+		return 0
+	}
+	for deadline := time.Now().Add(10 * time.Second); unacked() > 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("points not acknowledged, unacked: %d", unacked())
+		}
+	}
+
+	// Retransmitted points must not be seen twice.
+	points := server.GetPoints()
+	if len(points) != count {
+		t.Fatalf("wrong number of points, want: %d, got: %d", count, len(points))
+	}
+	for i, pt := range points {
+		if want := uint64(i + 1); pt.Header.Sequence != want {
+			t.Errorf("wrong sequence number, want: %d, got: %d", want, pt.Header.Sequence)
+		}
+	}
+}
+
+// Test that points are dropped without being written once the remote process
+// goes away.
+func TestDisconnect(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	endpoint, err := setup(server.Endpoint, nil)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	// Points are written synchronously, so that the write fails as soon as the
+	// remote process is gone.
+	checker, err := new(map[string]interface{}{"synchronous": true}, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	r := checker.(*remote)
+	defer r.Stop()
+	if got := r.Status().State; got != seccheck.SinkConnected {
+		t.Errorf("wrong state, want: %q, got: %q", seccheck.SinkConnected, got)
+	}
+
+	server.Close()
+	info := &pb.ExitNotifyParentInfo{ExitStatus: 123}
+	for i := 0; i < 2; i++ {
+		// Dropped points are reported, so that the error policy of the point
+		// applies.
+		if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err == nil {
+			t.Errorf("ExitNotifyParent() should fail after the remote process went away")
+		}
+	}
+	if r.disconnected.Load() == 0 {
+		t.Errorf("remote is not disconnected")
+	}
+	status := r.Status()
+	if want, got := uint64(2), status.DroppedCount; want != got {
+		t.Errorf("wrong dropped count, want: %d, got: %d", want, got)
+	}
+	if status.State != seccheck.SinkDisconnected {
+		t.Errorf("wrong state, want: %q, got: %q", seccheck.SinkDisconnected, status.State)
+	}
+	if status.LastError == "" || status.LastErrorTimeNs == 0 {
+		t.Errorf("last error not reported: %+v", status)
+	}
+}
+
+// Test that points are queued while the remote process is gone, and written
+// once the sink is reconnected to a new one.
+func TestReconnect(t *testing.T) {
+	connect := func(server *test.Server) (map[string]interface{}, *fd.FD) {
+		config := map[string]interface{}{"queue_size": float64(100)}
+		endpoint, err := setup(server.Endpoint, config)
+		if err != nil {
+			t.Fatalf("setup(): %v", err)
+		}
+		defer endpoint.Close()
+		endpointFD, err := fd.NewFromFile(endpoint)
+		if err != nil {
+			t.Fatalf("NewFromFile(): %v", err)
+		}
+		return config, endpointFD
+	}
+
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+	config, endpointFD := connect(server)
+	checker, err := new(config, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	r := checker.(*remote)
+	defer r.Stop()
+
+	// Send points until the sink notices that the remote process is gone.
+	server.Close()
+	for deadline := time.Now().Add(10 * time.Second); r.disconnected.Load() == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("remote is not disconnected")
+		}
+		_ = r.ExitNotifyParent(nil, seccheck.FieldSet{}, &pb.ExitNotifyParentInfo{})
+	}
+
+	const count = 10
+	for i := 0; i < count; i++ {
+		info := &pb.ExitNotifyParentInfo{ExitStatus: int32(i + 1)}
+		if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+			t.Fatalf("ExitNotifyParent(): %v", err)
+		}
+	}
+	if status := r.Status(); status.State != seccheck.SinkDisconnected || status.QueueDepth != count {
+		t.Errorf("wrong status, want: %q with %d queued points, got: %+v", seccheck.SinkDisconnected, count, status)
+	}
+
+	newServer, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer newServer.Close()
+	newConfig, newEndpointFD := connect(newServer)
+	if err := r.Reconnect(newConfig, newEndpointFD); err != nil {
+		t.Fatalf("Reconnect(): %v", err)
+	}
+
+	newServer.WaitForCount(count)
+	for i, pt := range newServer.GetPoints() {
+		got := &pb.ExitNotifyParentInfo{}
+		if err := proto.Unmarshal(pt.Msg, got); err != nil {
+			t.Fatalf("proto.Unmarshal(ExitNotifyParentInfo): %v", err)
+		}
+		if want := int32(i + 1); got.ExitStatus != want {
+			t.Errorf("wrong point, want exit status: %d, got: %+v", want, got)
+		}
+	}
+	if got := r.Status().State; got != seccheck.SinkConnected {
+		t.Errorf("wrong state, want: %q, got: %q", seccheck.SinkConnected, got)
+	}
+}
+
+// Test that sinks without a queue, or that keep state in the remote process,
+// can't be reconnected.
+func TestReconnectUnsupported(t *testing.T) {
+	for _, r := range []*remote{
+		{},
+		{ring: newRingBuffer(10), replay: newReplayBuffer(10)},
+		{ring: newRingBuffer(10), interned: newStringTable()},
+	} {
+		if err := r.Reconnect(nil, fd.New(-1)); err == nil {
+			t.Errorf("Reconnect() should fail for %+v", r)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"queue_size": float64(100),
+		"batch_size": float64(10),
+	}
+	endpoint, err := setup(server.Endpoint, config)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+	if got := config["batch_size"]; got != float64(10) {
+		t.Errorf("batch_size changed by handshake: %v", got)
+	}
+
+	checker, err := new(config, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	r := checker.(*remote)
+	defer r.Stop()
+
+	// Send the points in batches directly, to not depend on timing.
+	const count = 25
+	var entries []ringEntry
+	for i := 0; i < count; i++ {
+		payload, err := proto.Marshal(&pb.ExitNotifyParentInfo{ExitStatus: int32(i)})
+		if err != nil {
+			t.Fatalf("proto.Marshal(): %v", err)
+		}
+		entries = append(entries, ringEntry{
+			msgType: pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT,
+			payload: payload,
+		})
+	}
+	if err := r.writeBatch(entries); err != nil {
+		t.Fatalf("writeBatch(): %v", err)
+	}
+
+	server.WaitForCount(count)
+	for i, pt := range server.GetPoints() {
+		got := &pb.ExitNotifyParentInfo{}
+		if err := proto.Unmarshal(pt.Msg, got); err != nil {
+			t.Fatalf("proto.Unmarshal(ExitNotifyParentInfo): %v", err)
+		}
+		if got.ExitStatus != int32(i) {
+			t.Errorf("point %d out of order, got: %+v", i, got)
+		}
+	}
+}
+
+// Test that points are sent one at a time if the remote doesn't accept
+// batches.
+// Test that queued points are written when the sink is stopped, followed by a
+// SessionClosed message.
+func TestStop(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	endpoint, err := setup(server.Endpoint, nil)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	config := map[string]interface{}{
+		"queue_size":          float64(100),
+		"drop_stats_interval": "1h",
+	}
+	checker, err := new(config, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	r := checker.(*remote)
+
+	const count = 10
+	for i := 0; i < count; i++ {
+		info := &pb.ExitNotifyParentInfo{ExitStatus: int32(i)}
+		if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+			t.Fatalf("ExitNotifyParent: %v", err)
+		}
+	}
+	r.dropped(pb.MessageType_MESSAGE_SENTRY_CLONE, 2)
+	r.Stop()
+	server.WaitForNoClients()
+
+	points := server.GetPoints()
+	if len(points) != count+1 {
+		t.Fatalf("wrong number of messages, want: %d, got: %d", count+1, len(points))
+	}
+	pt := points[count]
+	if want := pb.MessageType_MESSAGE_SESSION_CLOSED; pt.MsgType != want {
+		t.Fatalf("wrong message type, want: %v, got: %v", want, pt.MsgType)
+	}
+	got := &pb.SessionClosed{}
+	if err := proto.Unmarshal(pt.Msg, got); err != nil {
+		t.Fatalf("proto.Unmarshal(SessionClosed): %v", err)
+	}
+	want := &pb.SessionClosed{
+		LastSequence: count,
+		DroppedCount: 2,
+		Drops:        []*pb.DropCount{{MessageType: pb.MessageType_MESSAGE_SENTRY_CLONE, Count: 2}},
+	}
+	if !proto.Equal(want, got) {
+		t.Errorf("wrong SessionClosed, want: %+v, got: %+v", want, got)
+	}
+}
+
+func TestBatchUnsupported(t *testing.T) {
+	server, err := newExampleServer(true)
+	if err != nil {
+		t.Fatalf("newExampleServer(): %v", err)
+	}
+	defer server.stop()
+
+	config := map[string]interface{}{
+		"queue_size": float64(100),
+		"batch_size": float64(10),
+	}
+	endpoint, err := setup(server.path, config)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	_ = endpoint.Close()
+	if got := config["batch_size"]; got != float64(1) {
+		t.Errorf("wrong batch_size, want: 1, got: %v", got)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	server.SetRequestedTypes([]pb.MessageType{pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT})
+
+	config := map[string]interface{}{"sandbox_id": "sandbox"}
+	endpoint, err := setup(server.Endpoint, config)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	hs := server.Handshake()
+	if hs.SandboxId != "sandbox" {
+		t.Errorf("wrong sandbox ID, want: %q, got: %q", "sandbox", hs.SandboxId)
+	}
+	if len(hs.MessageTypes) != len(pb.MessageType_name)-1 {
+		t.Errorf("wrong number of message types, want: %d, got: %d", len(pb.MessageType_name)-1, len(hs.MessageTypes))
+	}
+
+	r, err := new(config, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	defer r.Stop()
+
+	// Clone was not requested and must not be sent.
+	if err := r.Clone(nil, seccheck.FieldSet{}, &pb.CloneInfo{}); err != nil {
+		t.Fatalf("Clone: %v", err)
+	}
+	if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, &pb.ExitNotifyParentInfo{}); err != nil {
+		t.Fatalf("ExitNotifyParent: %v", err)
+	}
+	server.WaitForCount(1)
+	pts := server.GetPoints()
+	if len(pts) != 1 {
+		t.Fatalf("wrong number of points, want: 1, got: %d", len(pts))
+	}
+	if want := pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT; pts[0].MsgType != want {
+		t.Errorf("wrong message type, want: %v, got: %v", want, pts[0].MsgType)
+	}
+	if got := r.Status().DroppedCount; got != 0 {
+		t.Errorf("wrong dropped count, want: 0, got: %d", got)
+	}
+}
+
+// Test that the example C++ server works. It's easier to test from here and
+// also changes that can break it will likely originate here.
+func TestExample(t *testing.T) {
+	server, err := newExampleServer(false)
+	if err != nil {
+		t.Fatalf("newExampleServer(): %v", err)
+	}
+	defer server.stop()
+
+	endpoint, err := setup(server.path, nil)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	r, err := new(nil, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	info := pb.ExitNotifyParentInfo{ExitStatus: 123}
+	if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, &info); err != nil {
+		t.Fatalf("ExitNotifyParent: %v", err)
+	}
+	check := func() error {
+		gotRaw := server.out.String()
+		// Collapse whitespace.
+		got := strings.Join(strings.Fields(gotRaw), " ")
+		if !strings.Contains(got, "ExitNotifyParentInfo => exit_status: 123") {
+			return fmt.Errorf("ExitNotifyParentInfo point didn't get to the server, out: %q, raw: %q", got, gotRaw)
+		}
+		return nil
+	}
+	if err := testutil.Poll(check, time.Second); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+// writeGolden writes the point of g with r, through the method of seccheck.Checker
+// that the sentry calls for it.
+func writeGolden(r *remote, g *conformance.Golden) error {
+	switch g.Type {
+	case pb.MessageType_MESSAGE_DROP_STATS, pb.MessageType_MESSAGE_SESSION_CLOSED:
+		// Sent by the sink itself rather than the sentry.
+		return r.write(g.Msg, g.Type)
+	default:
+		return checktest.Dispatch(nil, r, seccheck.FieldSet{}, g.Msg)
+	}
+}
+
+// TestConformance checks that the sink writes the golden frames of the
+// conformance suite for the golden points.
+func TestConformance(t *testing.T) {
+	goldens, err := conformance.Load()
+	if err != nil {
+		t.Fatalf("conformance.Load(): %v", err)
+	}
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("Socketpair(): %v", err)
+	}
+	defer unix.Close(fds[1])
+
+	r := &remote{endpoint: fd.New(fds[0]), version: wire.CurrentVersion, checksum: true}
+	defer r.endpoint.Close()
+
+	buf := make([]byte, 1<<20)
+	for _, g := range goldens {
+		t.Run(g.Name, func(t *testing.T) {
+			if err := writeGolden(r, g); err != nil {
+				t.Fatalf("writing %v: %v", g.Type, err)
+			}
+			n, err := unix.Read(fds[1], buf)
+			if err != nil {
+				t.Fatalf("Read(): %v", err)
+			}
+			if err := conformance.CheckFrame(g, buf[:n], wire.CurrentVersion); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestExampleConformance checks that the example server, which reads messages
+// in C++, decodes every golden point. The server exits if it fails to parse a
+// point, so the points that follow are missing from its output.
+func TestExampleConformance(t *testing.T) {
+	goldens, err := conformance.Load()
 	if err != nil {
-		t.Fatalf("newServer(): %v", err)
+		t.Fatalf("conformance.Load(): %v", err)
 	}
-	defer server.Close()
-
-	server.SetVersion(wire.CurrentVersion + 10)
+	server, err := newExampleServer(false)
+	if err != nil {
+		t.Fatalf("newExampleServer(): %v", err)
+	}
+	defer server.stop()
 
-	endpoint, err := setup(server.Endpoint)
+	endpoint, err := setup(server.path, nil)
 	if err != nil {
 		t.Fatalf("setup(): %v", err)
 	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
 	_ = endpoint.Close()
+
+	checker, err := new(nil, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	r := checker.(*remote)
+	for _, g := range goldens {
+		if err := writeGolden(r, g); err != nil {
+			t.Fatalf("writing %v: %v", g.Type, err)
+		}
+	}
+
+	// The server prints "<name> => <point>" for points, and "E|X <name> <point>"
+	// for syscalls.
+	check := func() error {
+		printed := make(map[string]struct{})
+		for _, line := range strings.Split(server.out.String(), "\n") {
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) >= 2 && fields[1] == "=>":
+				printed[fields[0]] = struct{}{}
+			case len(fields) >= 2 && (fields[0] == "E" || fields[0] == "X"):
+				printed[fields[1]] = struct{}{}
+			}
+		}
+		for _, g := range goldens {
+			name := string(g.Msg.ProtoReflect().Descriptor().Name())
+			if _, ok := printed[name]; !ok {
+				return fmt.Errorf("%v point not decoded as %s, out: %q", g.Type, name, server.out.String())
+			}
+		}
+		return nil
+	}
+	if err := testutil.Poll(check, 5*time.Second); err != nil {
+		t.Error(err)
+	}
 }
 
-// Test that the example C++ server works. It's easier to test from here and
-// also changes that can break it will likely originate here.
-func TestExample(t *testing.T) {
-	server, err := newExampleServer(false)
+// readFrame reads a single length-prefixed message from a stream connection.
+func readFrame(conn net.Conn) ([]byte, error) {
+	var frame [wire.FrameLengthSize]byte
+	if _, err := io.ReadFull(conn, frame[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.LittleEndian.Uint32(frame[:]))
+	if _, err := io.ReadFull(conn, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func TestTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
-		t.Fatalf("newExampleServer(): %v", err)
+		t.Fatalf("net.Listen(): %v", err)
 	}
-	defer server.stop()
+	defer ln.Close()
+
+	type result struct {
+		msgs [][]byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer conn.Close()
+
+		// Handshake.
+		if _, err := readFrame(conn); err != nil {
+			done <- result{err: fmt.Errorf("reading handshake: %w", err)}
+			return
+		}
+		out, err := proto.Marshal(&pb.Handshake{Version: wire.CurrentVersion})
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		var frame [wire.FrameLengthSize]byte
+		binary.LittleEndian.PutUint32(frame[:], uint32(len(out)))
+		if _, err := conn.Write(append(frame[:], out...)); err != nil {
+			done <- result{err: fmt.Errorf("writing handshake: %w", err)}
+			return
+		}
+
+		// Messages must be delimited by their frames, even if they get coalesced
+		// in the stream.
+		var res result
+		for i := 0; i < 2; i++ {
+			msg, err := readFrame(conn)
+			if err != nil {
+				res.err = fmt.Errorf("reading message: %w", err)
+				break
+			}
+			res.msgs = append(res.msgs, msg)
+		}
+		done <- res
+	}()
 
-	endpoint, err := setup(server.path)
+	config := map[string]interface{}{
+		"endpoint":  ln.Addr().String(),
+		"transport": "tcp",
+	}
+	endpoint, err := setupSink(config)
 	if err != nil {
-		t.Fatalf("setup(): %v", err)
+		t.Fatalf("setupSink(): %v", err)
 	}
 	endpointFD, err := fd.NewFromFile(endpoint)
 	if err != nil {
@@ -205,26 +1499,92 @@ func TestExample(t *testing.T) {
 	}
 	_ = endpoint.Close()
 
-	r, err := new(nil, endpointFD)
+	r, err := new(config, endpointFD)
 	if err != nil {
 		t.Fatalf("New(): %v", err)
 	}
+	defer r.Stop()
 
-	info := pb.ExitNotifyParentInfo{ExitStatus: 123}
-	if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, &info); err != nil {
-		t.Fatalf("ExitNotifyParent: %v", err)
+	infos := []*pb.ExitNotifyParentInfo{{ExitStatus: 123}, {ExitStatus: 456}}
+	for _, info := range infos {
+		if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+			t.Fatalf("ExitNotifyParent: %v", err)
+		}
 	}
-	check := func() error {
-		gotRaw := server.out.String()
-		// Collapse whitespace.
-		got := strings.Join(strings.Fields(gotRaw), " ")
-		if !strings.Contains(got, "ExitNotifyParentInfo => exit_status: 123") {
-			return fmt.Errorf("ExitNotifyParentInfo point didn't get to the server, out: %q, raw: %q", got, gotRaw)
+
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	for i, msg := range res.msgs {
+		hdr := wire.Header{}
+		hdr.UnmarshalUnsafe(msg[:wire.HeaderStructSize])
+		if want := pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT; pb.MessageType(hdr.MessageType) != want {
+			t.Errorf("wrong message type, want: %v, got: %v", want, hdr.MessageType)
+		}
+		got := &pb.ExitNotifyParentInfo{}
+		if err := proto.Unmarshal(msg[hdr.HeaderSize:], got); err != nil {
+			t.Fatalf("proto.Unmarshal(ExitNotifyParentInfo): %v", err)
+		}
+		if !proto.Equal(infos[i], got) {
+			t.Errorf("Received point is different, want: %+v, got: %+v", infos[i], got)
 		}
-		return nil
 	}
-	if err := testutil.Poll(check, time.Second); err != nil {
-		t.Errorf(err.Error())
+}
+
+func TestWriteFrames(t *testing.T) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("Socketpair(): %v", err)
+	}
+	peer := os.NewFile(uintptr(fds[1]), "peer")
+	defer peer.Close()
+	conn, err := net.FileConn(peer)
+	if err != nil {
+		t.Fatalf("FileConn(): %v", err)
+	}
+	defer conn.Close()
+
+	r := &remote{endpoint: fd.New(fds[0]), stream: true, batchSize: 1}
+	defer r.endpoint.Close()
+	if got := r.writeCount(); got != maxFramesPerWrite {
+		t.Errorf("writeCount(), want: %d, got: %d", maxFramesPerWrite, got)
+	}
+
+	var entries []ringEntry
+	for i := int32(0); i < 10; i++ {
+		out, err := proto.Marshal(&pb.ExitNotifyParentInfo{ExitStatus: i})
+		if err != nil {
+			t.Fatalf("proto.Marshal(): %v", err)
+		}
+		entries = append(entries, ringEntry{
+			msgType:  pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT,
+			payload:  out,
+			sequence: uint64(i),
+		})
+	}
+	if err := r.writeEntries(entries); err != nil {
+		t.Fatalf("writeEntries(): %v", err)
+	}
+
+	// Each entry must arrive in its own frame, in order.
+	for i := range entries {
+		msg, err := readFrame(conn)
+		if err != nil {
+			t.Fatalf("reading message %d: %v", i, err)
+		}
+		hdr := wire.Header{}
+		hdr.UnmarshalUnsafe(msg[:wire.HeaderStructSize])
+		if hdr.Sequence != uint64(i) {
+			t.Errorf("wrong sequence, want: %d, got: %d", i, hdr.Sequence)
+		}
+		got := &pb.ExitNotifyParentInfo{}
+		if err := proto.Unmarshal(msg[hdr.HeaderSize:], got); err != nil {
+			t.Fatalf("proto.Unmarshal(ExitNotifyParentInfo): %v", err)
+		}
+		if got.ExitStatus != int32(i) {
+			t.Errorf("wrong exit status, want: %d, got: %d", i, got.ExitStatus)
+		}
 	}
 }
 
@@ -236,9 +1596,15 @@ func TestConfig(t *testing.T) {
 		err    string
 	}{
 		{
-			name:   "default",
-			config: map[string]interface{}{},
+			name: "synchronous",
+			config: map[string]interface{}{
+				"synchronous": true,
+			},
 			want: &remote{
+				version:        wire.CurrentVersion,
+				sessionClosed:  true,
+				stopTimeout:    defaultStopTimeout,
+				batchSize:      1,
 				retries:        0,
 				initialBackoff: 25 * time.Microsecond,
 				maxBackoff:     10 * time.Millisecond,
@@ -247,16 +1613,143 @@ func TestConfig(t *testing.T) {
 		{
 			name: "all",
 			config: map[string]interface{}{
+				"synchronous": true,
 				"retries":     float64(10),
 				"backoff":     "1s",
 				"backoff_max": "10s",
 			},
 			want: &remote{
+				version:        wire.CurrentVersion,
+				sessionClosed:  true,
+				stopTimeout:    defaultStopTimeout,
+				batchSize:      1,
 				retries:        10,
 				initialBackoff: time.Second,
 				maxBackoff:     10 * time.Second,
 			},
 		},
+		{
+			name: "tcp",
+			config: map[string]interface{}{
+				"synchronous": true,
+				"transport":   "tcp",
+			},
+			want: &remote{
+				version:        wire.CurrentVersion,
+				sessionClosed:  true,
+				stopTimeout:    defaultStopTimeout,
+				batchSize:      1,
+				stream:         true,
+				initialBackoff: 25 * time.Microsecond,
+				maxBackoff:     10 * time.Millisecond,
+			},
+		},
+		{
+			name: "gzip",
+			config: map[string]interface{}{
+				"synchronous": true,
+				"compression": "gzip",
+			},
+			want: &remote{
+				version:        wire.CurrentVersion,
+				sessionClosed:  true,
+				stopTimeout:    defaultStopTimeout,
+				batchSize:      1,
+				compression:    pb.Compression_COMPRESSION_GZIP,
+				initialBackoff: 25 * time.Microsecond,
+				maxBackoff:     10 * time.Millisecond,
+			},
+		},
+		{
+			name: "bad-compression",
+			config: map[string]interface{}{
+				"compression": "lz4",
+			},
+			err: "invalid compression",
+		},
+		{
+			name: "bad-compression-list",
+			config: map[string]interface{}{
+				"compression": []interface{}{"gzip", "lz4"},
+			},
+			err: "invalid compression",
+		},
+		{
+			name: "empty-compression-list",
+			config: map[string]interface{}{
+				"compression": []interface{}{},
+			},
+			err: "non-empty list",
+		},
+		{
+			name: "bad-queue-size",
+			config: map[string]interface{}{
+				"queue_size": float64(-1),
+			},
+			err: "queue_size",
+		},
+		{
+			name: "bad-message-types",
+			config: map[string]interface{}{
+				"message_types": []interface{}{"MESSAGE_SENTRY_FOO"},
+			},
+			err: "invalid message type",
+		},
+		{
+			name: "batch",
+			config: map[string]interface{}{
+				"synchronous": true,
+				"batch_size":  float64(10),
+			},
+			err: "requires queue_size",
+		},
+		{
+			name: "synchronous-queue",
+			config: map[string]interface{}{
+				"synchronous": true,
+				"queue_size":  float64(10),
+			},
+			err: "not supported with queue_size",
+		},
+		{
+			name: "bad-batch-size",
+			config: map[string]interface{}{
+				"batch_size": float64(0),
+			},
+			err: "batch_size",
+		},
+		{
+			name: "bad-staging-buffers",
+			config: map[string]interface{}{
+				"queue_size":      float64(10),
+				"staging_buffers": float64(1.5),
+			},
+			err: "staging_buffers",
+		},
+		{
+			name: "staging-buffers-queue-size",
+			config: map[string]interface{}{
+				"queue_size":      float64(2),
+				"staging_buffers": float64(4),
+			},
+			err: "requires queue_size",
+		},
+		{
+			name: "staging-buffers-reliable",
+			config: map[string]interface{}{
+				"queue_size":      float64(10),
+				"staging_buffers": float64(2),
+				"reliable":        true,
+			},
+			err: "not supported with staging_buffers",
+		},
+		{
+			name: "bad-transport",
+			config: map[string]interface{}{
+				"transport": "udp",
+			},
+			err: "invalid transport",
+		},
 		{
 			name: "bad-retries",
 			config: map[string]interface{}{
@@ -278,6 +1771,137 @@ func TestConfig(t *testing.T) {
 			},
 			err: "is not an string",
 		},
+		{
+			name: "max-message-size",
+			config: map[string]interface{}{
+				"synchronous":      true,
+				"max_message_size": float64(4096),
+			},
+			want: &remote{
+				version:        wire.CurrentVersion,
+				sessionClosed:  true,
+				stopTimeout:    defaultStopTimeout,
+				batchSize:      1,
+				maxMessageSize: 4096,
+				initialBackoff: 25 * time.Microsecond,
+				maxBackoff:     10 * time.Millisecond,
+			},
+		},
+		{
+			name: "bad-max-message-size",
+			config: map[string]interface{}{
+				"max_message_size": float64(100),
+			},
+			err: "max_message_size",
+		},
+		{
+			name: "bad-encoding",
+			config: map[string]interface{}{
+				"encoding": "msgpack",
+			},
+			err: "invalid encoding",
+		},
+		{
+			name: "bad-sample-rates",
+			config: map[string]interface{}{
+				"sample_rates": map[string]interface{}{"MESSAGE_SYSCALL_READ": float64(2)},
+			},
+			err: "between 0 and 1",
+		},
+		{
+			name: "adaptive-sampling-no-types",
+			config: map[string]interface{}{
+				"adaptive_sampling": map[string]interface{}{"high_watermark": 0.8},
+			},
+			err: "requires message_types",
+		},
+		{
+			name: "adaptive-sampling-watermarks",
+			config: map[string]interface{}{
+				"adaptive_sampling": map[string]interface{}{
+					"message_types":  []interface{}{"MESSAGE_SYSCALL_READ"},
+					"high_watermark": 0.2,
+					"low_watermark":  0.5,
+				},
+			},
+			err: "must be lower than high_watermark",
+		},
+		{
+			name: "adaptive-sampling-synchronous",
+			config: map[string]interface{}{
+				"synchronous": true,
+				"adaptive_sampling": map[string]interface{}{
+					"message_types": []interface{}{"MESSAGE_SYSCALL_READ"},
+				},
+			},
+			err: "adaptive_sampling requires queue_size",
+		},
+		{
+			name: "interning-reliable",
+			config: map[string]interface{}{
+				"interning": true,
+				"reliable":  true,
+			},
+			err: "interning is not supported with reliable",
+		},
+		{
+			name: "bad-filter",
+			config: map[string]interface{}{
+				"filters": map[string]interface{}{"uid": []interface{}{"0"}},
+			},
+			err: "invalid filter",
+		},
+		{
+			name: "bad-fd-type",
+			config: map[string]interface{}{
+				"filters": map[string]interface{}{"fd_types": []interface{}{"tty"}},
+			},
+			err: "invalid fd type",
+		},
+		{
+			name: "bad-verdict-types",
+			config: map[string]interface{}{
+				"verdict_types": []interface{}{"MESSAGE_SENTRY_FOO"},
+			},
+			err: "invalid verdict type",
+		},
+		{
+			name: "bad-verdict-error-policy",
+			config: map[string]interface{}{
+				"verdict_types":        []interface{}{"MESSAGE_SENTRY_EXEC"},
+				"verdict_error_policy": "ignore",
+			},
+			err: "invalid verdict_error_policy",
+		},
+		{
+			name: "verdicts-queue",
+			config: map[string]interface{}{
+				"verdict_types": []interface{}{"MESSAGE_SENTRY_EXEC"},
+				"queue_size":    float64(10),
+			},
+			err: "not supported with queue_size",
+		},
+		{
+			name: "stop-timeout",
+			config: map[string]interface{}{
+				"synchronous":  true,
+				"stop_timeout": "0s",
+			},
+			want: &remote{
+				version:        wire.CurrentVersion,
+				sessionClosed:  true,
+				batchSize:      1,
+				initialBackoff: 25 * time.Microsecond,
+				maxBackoff:     10 * time.Millisecond,
+			},
+		},
+		{
+			name: "bad-stop-timeout",
+			config: map[string]interface{}{
+				"stop_timeout": "-1s",
+			},
+			err: "stop_timeout",
+		},
 		{
 			name: "bad-invalid-backoffs",
 			config: map[string]interface{}{
@@ -315,7 +1939,7 @@ func BenchmarkSmall(t *testing.B) {
 	}
 	defer server.stop()
 
-	endpoint, err := setup(server.path)
+	endpoint, err := setup(server.path, nil)
 	if err != nil {
 		t.Fatalf("setup(): %v", err)
 	}
@@ -342,6 +1966,89 @@ func BenchmarkSmall(t *testing.B) {
 	})
 }
 
+// BenchmarkPointRemoteSink measures the cost of points of each type that are
+// sent to a remote sink, from the check made by the caller to the sink
+// queueing the point. See the seccheck benchmarks for the disabled cost.
+func BenchmarkPointRemoteSink(b *testing.B) {
+	const sysno = 257
+	for _, tc := range []struct {
+		name string
+		pt   seccheck.Point
+		send func(s *seccheck.State, fields seccheck.FieldSet) error
+	}{
+		{
+			name: "sentry",
+			pt:   seccheck.PointClone,
+			send: func(s *seccheck.State, fields seccheck.FieldSet) error {
+				info := &pb.CloneInfo{}
+				return s.SendToCheckers("" /* cid */, seccheck.PointClone, func(c seccheck.Checker) error {
+					return c.Clone(nil, fields, info)
+				})
+			},
+		},
+		{
+			name: "syscall",
+			pt:   seccheck.GetPointForSyscall(seccheck.SyscallEnter, sysno),
+			send: func(s *seccheck.State, fields seccheck.FieldSet) error {
+				msg := seccheck.NewOpen()
+				defer seccheck.ReleaseMessage(msg)
+				msg.Sysno = sysno
+				msg.Pathname = "/etc/hosts"
+				return s.SendSyscallToCheckers(nil, "" /* cid */, seccheck.GetPointForSyscall(seccheck.SyscallEnter, sysno), fields, nil, pb.MessageType_MESSAGE_SYSCALL_OPEN, msg)
+			},
+		},
+		{
+			name: "raw_syscall",
+			pt:   seccheck.GetPointForSyscall(seccheck.SyscallRawEnter, sysno),
+			send: func(s *seccheck.State, fields seccheck.FieldSet) error {
+				info := &pb.Syscall{Sysno: sysno}
+				return s.SendRawSyscallToCheckers(nil, "" /* cid */, seccheck.GetPointForSyscall(seccheck.SyscallRawEnter, sysno), fields, info)
+			},
+		},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			server, err := test.NewServer()
+			if err != nil {
+				b.Fatalf("newServer(): %v", err)
+			}
+			defer server.Close()
+
+			endpoint, err := setup(server.Endpoint, nil)
+			if err != nil {
+				b.Fatalf("setup(): %v", err)
+			}
+			endpointFD, err := fd.NewFromFile(endpoint)
+			if err != nil {
+				_ = endpoint.Close()
+				b.Fatalf("NewFromFile(): %v", err)
+			}
+			_ = endpoint.Close()
+
+			r, err := new(nil, endpointFD)
+			if err != nil {
+				b.Fatalf("New(): %v", err)
+			}
+			defer r.Stop()
+			var s seccheck.State
+			s.AppendChecker(r, []seccheck.PointReq{{Pt: tc.pt}})
+
+			b.ResetTimer()
+			b.RunParallel(func(sub *testing.PB) {
+				for sub.Next() {
+					if !s.Enabled(tc.pt) {
+						b.Fatalf("point %v is not enabled", tc.pt)
+					}
+					// Points are dropped once the queue is full, which is
+					// part of the cost being measured.
+					if err := tc.send(&s, s.GetFieldSet(tc.pt)); err != nil && err != errQueueFull {
+						b.Fatalf("send(): %v", err)
+					}
+				}
+			})
+		})
+	}
+}
+
 func BenchmarkProtoAny(t *testing.B) {
 	info := &pb.ExitNotifyParentInfo{ExitStatus: 123}
 
@@ -0,0 +1,384 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// flushPollTimeout bounds how long the flusher waits for the endpoint to become
+// writable before checking whether the sink was stopped.
+const flushPollTimeout = 100 * time.Millisecond
+
+// maxBatchBytes is the maximum size of a batch. A single point larger than
+// that is sent in a batch by itself.
+const maxBatchBytes = 64 << 10
+
+// maxFramesPerWrite bounds the number of frames written to stream endpoints
+// with a single writev(2), which takes 2 iovecs per frame, see IOV_MAX.
+const maxFramesPerWrite = 256
+
+// ringEntry is a serialized point waiting to be written.
+type ringEntry struct {
+	msgType pb.MessageType
+	payload []byte
+
+	// sequence, timeNs and flags are set in the header, see wire.Header.
+	sequence uint64
+	timeNs   int64
+	flags    uint32
+
+	// defined are the ids of the strings that the point defines, see
+	// stringTable.
+	defined []uint32
+}
+
+// batchedSize returns the size of the entry in a batch, see
+// wire.BatchMessageType.
+func (e *ringEntry) batchedSize() int {
+	return wire.BatchLengthSize + wire.HeaderStructSize + len(e.payload)
+}
+
+// ringBuffer is a bounded FIFO of serialized points. Producers never block:
+// points are rejected when the buffer is full.
+type ringBuffer struct {
+	mu   sync.Mutex
+	cond sync.Cond
+
+	// +checklocks:mu
+	entries []ringEntry
+
+	// head is the index of the oldest entry in entries.
+	//
+	// +checklocks:mu
+	head int
+
+	// count is the number of entries in the buffer.
+	//
+	// +checklocks:mu
+	count int
+
+	// draining is set once no more entries are accepted, but the ones in the
+	// buffer are still returned by popBatch.
+	//
+	// +checklocks:mu
+	draining bool
+
+	// +checklocks:mu
+	closed bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	b := &ringBuffer{entries: make([]ringEntry, size)}
+	b.cond.L = &b.mu
+	return b
+}
+
+// push appends e to the buffer. It returns false if the buffer is full,
+// draining or closed, in which case e is not added.
+func (b *ringBuffer) push(e ringEntry) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed || b.draining || b.count == len(b.entries) {
+		return false
+	}
+	b.entries[(b.head+b.count)%len(b.entries)] = e
+	b.count++
+	b.cond.Signal()
+	return true
+}
+
+// popBatch removes and returns up to maxCount of the oldest entries in the
+// buffer, as long as they add up to no more than maxBytes in a batch. The
+// oldest entry is always returned, regardless of its size. It waits for an
+// entry to be pushed if the buffer is empty, and returns nil once the buffer
+// is closed, or once it's empty and draining.
+func (b *ringBuffer) popBatch(maxCount, maxBytes int) []ringEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.count == 0 && !b.closed && !b.draining {
+		b.cond.Wait()
+	}
+	if b.closed || b.count == 0 {
+		return nil
+	}
+	entries, _ := b.popLocked(nil, maxCount, maxBytes, 0)
+	return entries
+}
+
+// popAvailable is like popBatch, but it appends the entries to entries, which
+// add up to size bytes in the batch, and it doesn't wait if the buffer is
+// empty. The oldest entry is only returned regardless of its size if entries
+// is empty. done is true once the buffer is closed, or once it's empty and
+// draining.
+func (b *ringBuffer) popAvailable(entries []ringEntry, maxCount, maxBytes, size int) (_ []ringEntry, _ int, done bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return entries, size, true
+	}
+	entries, size = b.popLocked(entries, maxCount, maxBytes, size)
+	return entries, size, b.count == 0 && b.draining
+}
+
+// popLocked moves the oldest entries in the buffer to entries, see
+// popAvailable.
+//
+// +checklocks:b.mu
+func (b *ringBuffer) popLocked(entries []ringEntry, maxCount, maxBytes, size int) ([]ringEntry, int) {
+	for b.count > 0 && len(entries) < maxCount {
+		e := b.entries[b.head]
+		if len(entries) > 0 && size+e.batchedSize() > maxBytes {
+			break
+		}
+		size += e.batchedSize()
+		entries = append(entries, e)
+		b.entries[b.head] = ringEntry{}
+		b.head = (b.head + 1) % len(b.entries)
+		b.count--
+	}
+	return entries, size
+}
+
+// drain stops accepting new entries. popBatch returns the entries that are
+// left, and then nil.
+func (b *ringBuffer) drain() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.draining = true
+	b.cond.Broadcast()
+}
+
+// close closes the buffer and returns the number of entries that were
+// discarded.
+func (b *ringBuffer) close() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	discarded := b.count
+	b.count = 0
+	b.cond.Broadcast()
+	return discarded
+}
+
+// len returns the number of entries in the buffer.
+func (b *ringBuffer) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count
+}
+
+func (b *ringBuffer) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+// pointQueue holds the points waiting to be written by flush. It's
+// implemented by ringBuffer and stagingBuffers.
+type pointQueue interface {
+	// push appends e to the queue. It returns false if e was rejected
+	// because the queue is full, draining or closed.
+	push(e ringEntry) bool
+	// popBatch removes and returns the oldest entries in the queue, see
+	// ringBuffer.popBatch.
+	popBatch(maxCount, maxBytes int) []ringEntry
+	// drain stops accepting new entries.
+	drain()
+	// close closes the queue and returns the number of entries discarded.
+	close() int
+	// len returns the number of entries in the queue.
+	len() int
+	isClosed() bool
+}
+
+// flush writes points from r.ring to the endpoint until the ring is closed, or
+// until it's drained.
+// Since points are written from here rather than from the task that generated
+// them, the endpoint can be waited on without delaying the application. The
+// header is built right before the point is written, so that it reports all
+// points dropped up to then. If batching is enabled, points that are queued
+// together are coalesced into a single write. In reliable mode, points are
+// kept in r.replay until the remote process acknowledges them.
+func (r *remote) flush() {
+	defer close(r.flushDone)
+	for {
+		// Points stay queued while the sink is disconnected, and are written
+		// once it's reconnected.
+		r.waitReconnect()
+		entries := r.ring.popBatch(r.writeCount(), r.batchBytesLimit())
+		if entries == nil {
+			return
+		}
+		if r.replay != nil && !r.replay.add(entries) {
+			r.droppedEntries(entries)
+			continue
+		}
+		if err := r.writeEntries(entries); err != nil {
+			r.setError(err)
+			if r.replay != nil {
+				// The points are retransmitted later, unless the remote
+				// process went away.
+				log.Debugf("Write failed: %v", err)
+				r.checkDisconnected(err)
+			} else {
+				log.Debugf("Write failed, dropping %d point(s): %v", len(entries), err)
+				r.droppedEntries(entries)
+				r.checkDisconnected(err)
+			}
+			continue
+		}
+		for _, e := range entries {
+			r.interned.setWritten(e.defined)
+		}
+	}
+}
+
+// batchBytesLimit returns the maximum size of the payload of a batch. It's
+// lower than maxBatchBytes if the remote process limits the size of messages.
+func (r *remote) batchBytesLimit() int {
+	if r.maxMessageSize > 0 && r.maxMessageSize-wire.HeaderStructSize < maxBatchBytes {
+		return r.maxMessageSize - wire.HeaderStructSize
+	}
+	return maxBatchBytes
+}
+
+// writeCount returns the maximum number of entries written together, see
+// writeEntries.
+func (r *remote) writeCount() int {
+	if r.batchSize == 1 && r.stream {
+		return maxFramesPerWrite
+	}
+	return r.batchSize
+}
+
+// writeEntries writes entries to the endpoint, in a batch if there is more
+// than one and batches are enabled. Otherwise, the entries are written to
+// stream endpoints as consecutive frames, with a single syscall. Writes are
+// serialized with writeMu, since points can be retransmitted concurrently with
+// flush.
+func (r *remote) writeEntries(entries []ringEntry) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	if len(entries) == 1 {
+		e := entries[0]
+		hdr := r.header(uint16(e.msgType), e.sequence, e.timeNs, e.flags, e.payload)
+		return r.writeWait(hdr[:], e.payload)
+	}
+	if r.batchSize == 1 {
+		return r.writeFrames(entries)
+	}
+	return r.writeBatch(entries)
+}
+
+// writeFrames writes entries to a stream endpoint as consecutive frames, in a
+// single writev(2) unless the endpoint is full.
+func (r *remote) writeFrames(entries []ringEntry) error {
+	const prefixSize = wire.FrameLengthSize + wire.HeaderStructSize
+	prefixes := make([]byte, len(entries)*prefixSize)
+	bufs := make([][]byte, 0, 2*len(entries))
+	for i, e := range entries {
+		prefix := prefixes[i*prefixSize : (i+1)*prefixSize]
+		binary.LittleEndian.PutUint32(prefix, uint32(wire.HeaderStructSize+len(e.payload)))
+		hdr := r.header(uint16(e.msgType), e.sequence, e.timeNs, e.flags, e.payload)
+		copy(prefix[wire.FrameLengthSize:], hdr[:])
+		bufs = append(bufs, prefix, e.payload)
+	}
+	return r.writeBufsWait(bufs)
+}
+
+// header returns the serialized header for a message of type msgType with
+// payload. See wire.Header for the other fields.
+func (r *remote) header(msgType uint16, sequence uint64, timeNs int64, flags uint32, payload []byte) [wire.HeaderStructSize]byte {
+	hdr := wire.Header{
+		HeaderSize:   uint16(wire.HeaderStructSize),
+		DroppedCount: r.droppedCount.Load(),
+		MessageType:  msgType,
+		Sequence:     sequence,
+		TimeNs:       timeNs,
+		Flags:        flags,
+		Version:      r.version,
+	}
+	if r.checksum {
+		hdr.SetChecksum(payload)
+	}
+	var out [wire.HeaderStructSize]byte
+	hdr.MarshalUnsafe(out[:])
+	return out
+}
+
+// writeBatch writes entries to the endpoint in a single batch message. See
+// wire.BatchMessageType for the format.
+func (r *remote) writeBatch(entries []ringEntry) error {
+	size := 0
+	for _, e := range entries {
+		size += wire.BatchLengthSize + wire.HeaderStructSize + len(e.payload)
+	}
+	batch := make([]byte, 0, size)
+	for _, e := range entries {
+		var length [wire.BatchLengthSize]byte
+		binary.LittleEndian.PutUint32(length[:], uint32(wire.HeaderStructSize+len(e.payload)))
+		batch = append(batch, length[:]...)
+		hdr := r.header(uint16(e.msgType), e.sequence, e.timeNs, e.flags, e.payload)
+		batch = append(batch, hdr[:]...)
+		batch = append(batch, e.payload...)
+	}
+	hdr := r.header(wire.BatchMessageType, 0 /* sequence */, 0 /* timeNs */, 0 /* flags */, batch)
+	return r.writeWait(hdr[:], batch)
+}
+
+// writeWait writes a message to the endpoint, waiting for it to become
+// writable as needed. It gives up if the ring is closed in the meantime.
+func (r *remote) writeWait(hdr, payload []byte) error {
+	bufs := [][]byte{hdr, payload}
+	if r.stream {
+		var frame [wire.FrameLengthSize]byte
+		binary.LittleEndian.PutUint32(frame[:], uint32(len(hdr)+len(payload)))
+		bufs = append([][]byte{frame[:]}, bufs...)
+	}
+	return r.writeBufsWait(bufs)
+}
+
+// writeBufsWait is like writeWait, for a message made of bufs, or several
+// frames on stream endpoints.
+func (r *remote) writeBufsWait(bufs [][]byte) error {
+	timeout := unix.NsecToTimespec(flushPollTimeout.Nanoseconds())
+	for {
+		n, err := unix.Writev(r.endpoint.FD(), bufs)
+		if n > 0 {
+			if bufs = advance(bufs, n); len(bufs) == 0 {
+				return nil
+			}
+		}
+		if err != nil && !errors.Is(err, unix.EAGAIN) {
+			return err
+		}
+		if r.ring.isClosed() {
+			return unix.ECANCELED
+		}
+		fds := []unix.PollFd{{Fd: int32(r.endpoint.FD()), Events: unix.POLLOUT}}
+		if _, err := unix.Ppoll(fds, &timeout, nil); err != nil && !errors.Is(err, unix.EINTR) {
+			return err
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"testing"
+
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestRingBuffer(t *testing.T) {
+	b := newRingBuffer(2)
+	for i := 0; i < 2; i++ {
+		if !b.push(ringEntry{msgType: pb.MessageType(i)}) {
+			t.Fatalf("push(%d) failed", i)
+		}
+	}
+	if b.push(ringEntry{}) {
+		t.Fatalf("push() succeeded on full buffer")
+	}
+	if es := b.popBatch(1, 0); len(es) != 1 || es[0].msgType != 0 {
+		t.Fatalf("popBatch(): %+v", es)
+	}
+	// Wrap around.
+	if !b.push(ringEntry{msgType: 2}) {
+		t.Fatalf("push(2) failed")
+	}
+	for want := 1; want <= 2; want++ {
+		if es := b.popBatch(1, 0); len(es) != 1 || es[0].msgType != pb.MessageType(want) {
+			t.Fatalf("popBatch(): %+v, want: %d", es, want)
+		}
+	}
+
+	if !b.push(ringEntry{}) {
+		t.Fatalf("push() failed")
+	}
+	if discarded := b.close(); discarded != 1 {
+		t.Errorf("close() discarded %d entries, want: 1", discarded)
+	}
+	if es := b.popBatch(1, 0); es != nil {
+		t.Errorf("popBatch() succeeded on closed buffer: %+v", es)
+	}
+	if b.push(ringEntry{}) {
+		t.Errorf("push() succeeded on closed buffer")
+	}
+}
+
+func TestRingBufferBatch(t *testing.T) {
+	b := newRingBuffer(10)
+	for i := 0; i < 5; i++ {
+		if !b.push(ringEntry{msgType: pb.MessageType(i), payload: make([]byte, 10)}) {
+			t.Fatalf("push(%d) failed", i)
+		}
+	}
+	// Limited by count.
+	if es := b.popBatch(2, 100); len(es) != 2 || es[0].msgType != 0 || es[1].msgType != 1 {
+		t.Fatalf("popBatch(2, 100): %+v", es)
+	}
+	// Limited by size.
+	if es := b.popBatch(10, 25); len(es) != 2 || es[0].msgType != 2 {
+		t.Fatalf("popBatch(10, 25): %+v", es)
+	}
+	// The oldest entry is returned even if it's too large.
+	if es := b.popBatch(10, 5); len(es) != 1 || es[0].msgType != 4 {
+		t.Fatalf("popBatch(10, 5): %+v", es)
+	}
+}
+
+func TestRingBufferDrain(t *testing.T) {
+	b := newRingBuffer(10)
+	for i := 0; i < 2; i++ {
+		if !b.push(ringEntry{msgType: pb.MessageType(i)}) {
+			t.Fatalf("push(%d) failed", i)
+		}
+	}
+	b.drain()
+	if b.push(ringEntry{}) {
+		t.Errorf("push() succeeded on draining buffer")
+	}
+	// Entries left are still returned, and then popBatch doesn't wait.
+	if es := b.popBatch(10, maxBatchBytes); len(es) != 2 {
+		t.Fatalf("popBatch(): %+v", es)
+	}
+	if es := b.popBatch(10, maxBatchBytes); es != nil {
+		t.Errorf("popBatch() succeeded on drained buffer: %+v", es)
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const rotateName = "file"
+
+const (
+	// rotateMaxSize is the default size, in bytes, after which the sink moves on
+	// to the next file.
+	rotateMaxSize = 64 << 20
+
+	// rotateMaxFiles is the default number of files that are retained.
+	rotateMaxFiles = 5
+
+	// rotateFilesLimit is the maximum number of files that can be retained,
+	// limited by the number of file descriptors that can be passed in a single
+	// SCM_RIGHTS message.
+	rotateFilesLimit = 253
+)
+
+// Formats supported by the "format" configuration.
+const (
+	// rotateFormatFramed writes points in the same format as the tcp transport
+	// of the remote sink, i.e. each point is preceded by its length and a
+	// wire.Header. This is the default.
+	rotateFormatFramed = "framed"
+
+	// rotateFormatJSON writes points as JSON lines, like the jsonl sink.
+	rotateFormatJSON = "jsonl"
+)
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     rotateName,
+		Setup:    setupRotate,
+		New:      newRotate,
+		Validate: validateFilters,
+	})
+}
+
+// rotateConfig is the configuration of the file sink:
+//
+//	directory: host directory where files are written. Required.
+//	prefix:    files are named <prefix>.0 to <prefix>.<max_files-1>.
+//	           Defaults to "trace".
+//	format:    "framed" (default) or "jsonl".
+//	max_size:  size in bytes after which the sink moves on to the next file.
+//	max_files: number of files retained.
+//	checksum:  whether the checksum of payloads is added to headers, in the
+//	           "framed" format.
+type rotateConfig struct {
+	directory string
+	prefix    string
+	format    string
+	maxSize   int64
+	maxFiles  int
+	checksum  bool
+}
+
+func parseRotateConfig(config map[string]interface{}) (rotateConfig, error) {
+	c := rotateConfig{
+		prefix:   "trace",
+		format:   rotateFormatFramed,
+		maxSize:  rotateMaxSize,
+		maxFiles: rotateMaxFiles,
+	}
+	var err error
+	if c.directory, err = parseConfigString(config, "directory"); err != nil {
+		return rotateConfig{}, err
+	}
+	if c.directory == "" {
+		return rotateConfig{}, fmt.Errorf("directory not present in configuration")
+	}
+	if prefix, err := parseConfigString(config, "prefix"); err != nil {
+		return rotateConfig{}, err
+	} else if prefix != "" {
+		if prefix != filepath.Base(prefix) {
+			return rotateConfig{}, fmt.Errorf("prefix %q must be a file name", prefix)
+		}
+		c.prefix = prefix
+	}
+	if format, err := parseConfigString(config, "format"); err != nil {
+		return rotateConfig{}, err
+	} else if format != "" {
+		if format != rotateFormatFramed && format != rotateFormatJSON {
+			return rotateConfig{}, fmt.Errorf("invalid format %q, must be %q or %q", format, rotateFormatFramed, rotateFormatJSON)
+		}
+		c.format = format
+	}
+	if opaque, ok := config["max_size"]; ok {
+		size, ok := opaque.(float64)
+		if !ok || size != float64(int64(size)) || size <= 0 {
+			return rotateConfig{}, fmt.Errorf("max_size %v is not a positive int", opaque)
+		}
+		c.maxSize = int64(size)
+	}
+	if opaque, ok := config["max_files"]; ok {
+		files, ok := opaque.(float64)
+		if !ok || files != float64(int(files)) || files <= 0 || files > rotateFilesLimit {
+			return rotateConfig{}, fmt.Errorf("max_files %v must be an int between 1 and %d", opaque, rotateFilesLimit)
+		}
+		c.maxFiles = int(files)
+	}
+	if c.checksum, err = parseBool(config, "checksum"); err != nil {
+		return rotateConfig{}, err
+	}
+	return c, nil
+}
+
+// setupRotate opens all files that the sink writes to, since the sandbox can't
+// create files. Files are written to in turns, so the one to be written first
+// is the oldest one, which is truncated. The files are passed to the sink with
+// SCM_RIGHTS over the returned socket, in the order they're written to. The
+// caller is responsible to close to file.
+func setupRotate(config map[string]interface{}) (*os.File, error) {
+	c, err := parseRotateConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(c.directory, 0755); err != nil {
+		return nil, err
+	}
+
+	files := make([]*os.File, 0, c.maxFiles)
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	// Start with an empty file if there is one, otherwise with the least
+	// recently modified.
+	first := 0
+	var firstStat os.FileInfo
+	for i := 0; i < c.maxFiles; i++ {
+		path := filepath.Join(c.directory, c.prefix+"."+strconv.Itoa(i))
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+		stat, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if firstStat == nil || (firstStat.Size() > 0 && (stat.Size() == 0 || stat.ModTime().Before(firstStat.ModTime()))) {
+			first, firstStat = i, stat
+		}
+	}
+	if err := files[first].Truncate(0); err != nil {
+		return nil, err
+	}
+
+	fds := make([]int, 0, len(files))
+	for i := range files {
+		fds = append(fds, int(files[(first+i)%len(files)].Fd()))
+	}
+	endpoint, err := sendFiles(c.directory, fds)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("File sink set up, directory: %q, first file: %d", c.directory, first)
+	return endpoint, nil
+}
+
+// sendFiles returns a socket from which fds can be received with receiveFiles.
+// fds can be closed once sendFiles returns.
+func sendFiles(name string, fds []int) (*os.File, error) {
+	pair, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("socketpair(AF_UNIX, SOCK_SEQPACKET): %w", err)
+	}
+	// Files stay queued in the socket after the sending end is closed.
+	defer unix.Close(pair[1])
+	endpoint := os.NewFile(uintptr(pair[0]), name)
+	if err := unix.Sendmsg(pair[1], []byte{0}, unix.UnixRights(fds...), nil, 0); err != nil {
+		_ = endpoint.Close()
+		return nil, fmt.Errorf("sending files: %w", err)
+	}
+	return endpoint, nil
+}
+
+// newRotate creates a new checker that writes points to a set of files,
+// moving on to the next file when the current one grows past max_size. Once
+// all files have been used, the oldest one is truncated and reused. Readers
+// can order the files by modification time.
+func newRotate(config map[string]interface{}, endpoint *fd.FD) (seccheck.Checker, error) {
+	if endpoint == nil {
+		return nil, fmt.Errorf("file sink requires an endpoint")
+	}
+	sampler, err := parseSampleRates(config)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFilters(config)
+	if err != nil {
+		return nil, err
+	}
+	c, err := parseRotateConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	files, err := receiveFiles(endpoint, c.maxFiles)
+	_ = endpoint.Close()
+	if err != nil {
+		return nil, err
+	}
+	w := &rotatingWriter{
+		files:   files,
+		maxSize: c.maxSize,
+		encode:  framedEncoder{checksum: c.checksum}.encode,
+	}
+	if c.format == rotateFormatJSON {
+		w.encode = encodeJSON
+	}
+	log.Debugf("File sink created, files: %d, max size: %d, format: %s", len(files), c.maxSize, c.format)
+	return &remote{fields: fields, sampler: sampler, sender: w}, nil
+}
+
+// receiveFiles receives the files sent by sendFiles over endpoint.
+func receiveFiles(endpoint *fd.FD, count int) ([]*fd.FD, error) {
+	var buf [1]byte
+	oob := make([]byte, unix.CmsgSpace(count*4))
+	// Flags match what is allowed by the sandbox's seccomp filters.
+	_, oobn, flags, _, err := unix.Recvmsg(endpoint.FD(), buf[:], oob, unix.MSG_DONTWAIT|unix.MSG_TRUNC)
+	if err != nil {
+		return nil, fmt.Errorf("receiving files: %w", err)
+	}
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return nil, fmt.Errorf("parsing control message: %w", err)
+	}
+	var fds []int
+	for _, msg := range msgs {
+		rights, err := unix.ParseUnixRights(&msg)
+		if err != nil {
+			return nil, fmt.Errorf("parsing control message: %w", err)
+		}
+		fds = append(fds, rights...)
+	}
+	files := make([]*fd.FD, 0, len(fds))
+	for _, f := range fds {
+		files = append(files, fd.New(f))
+	}
+	if flags&unix.MSG_CTRUNC != 0 || len(files) != count {
+		for _, f := range files {
+			_ = f.Close()
+		}
+		return nil, fmt.Errorf("received %d files, want: %d", len(files), count)
+	}
+	return files, nil
+}
+
+// framedEncoder renders points as wire messages.
+type framedEncoder struct {
+	// checksum is set to add the checksum of payloads to headers, which lets
+	// readers skip corrupted messages, see wire.FrameReader.
+	checksum bool
+}
+
+// encode renders a point as a wire message, preceded by its length.
+func (e framedEncoder) encode(msg proto.Message, msgType pb.MessageType, droppedCount uint32) ([]byte, error) {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, wire.FrameLengthSize+wire.HeaderStructSize, wire.FrameLengthSize+wire.HeaderStructSize+len(payload))
+	binary.LittleEndian.PutUint32(out, uint32(wire.HeaderStructSize+len(payload)))
+	// Points are not numbered, since their order in the files is already the
+	// order in which they were written.
+	hdr := wire.Header{
+		HeaderSize:   uint16(wire.HeaderStructSize),
+		DroppedCount: droppedCount,
+		MessageType:  uint16(msgType),
+		TimeNs:       time.Now().UnixNano(),
+		Version:      wire.CurrentVersion,
+	}
+	if e.checksum {
+		hdr.SetChecksum(payload)
+	}
+	hdr.MarshalUnsafe(out[wire.FrameLengthSize:])
+	return append(out, payload...), nil
+}
+
+// rotatingWriter writes points to files in turns, moving on to the next file
+// once the current one reaches maxSize.
+type rotatingWriter struct {
+	encode  lineEncoder
+	maxSize int64
+
+	mu sync.Mutex
+
+	// files are the files written to, in order. They're opened for appending,
+	// so files are truncated before being reused.
+	//
+	// +checklocks:mu
+	files []*fd.FD
+
+	// cur is the index of the file being written to.
+	//
+	// +checklocks:mu
+	cur int
+
+	// size is the number of bytes written to the current file.
+	//
+	// +checklocks:mu
+	size int64
+}
+
+var _ sender = (*rotatingWriter)(nil)
+
+// name implements sender.
+func (*rotatingWriter) name() string {
+	return rotateName
+}
+
+// send implements sender.
+func (w *rotatingWriter) send(r *remote, msg proto.Message, msgType pb.MessageType) error {
+	out, err := w.encode(msg, msgType, r.droppedCount.Load())
+	if err != nil {
+		log.Debugf("Encoding %+v for %s sink: %v", msg, rotateName, err)
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size > 0 && w.size+int64(len(out)) > w.maxSize {
+		if err := w.rotateLocked(); err != nil {
+			log.Debugf("Rotating file failed, dropping point: %v", err)
+			r.setError(err)
+			r.droppedCount.Add(1)
+			return err
+		}
+	}
+	n, err := w.files[w.cur].Write(out)
+	w.size += int64(n)
+	if err != nil {
+		log.Debugf("Write failed, dropping point: %v", err)
+		r.setError(err)
+		r.droppedCount.Add(1)
+		return err
+	}
+	return nil
+}
+
+// rotateLocked moves on to the next file, discarding its contents.
+//
+// +checklocks:mu
+func (w *rotatingWriter) rotateLocked() error {
+	next := (w.cur + 1) % len(w.files)
+	if err := unix.Ftruncate(w.files[next].FD(), 0); err != nil {
+		return err
+	}
+	w.cur = next
+	w.size = 0
+	return nil
+}
+
+// stop implements sender.
+func (w *rotatingWriter) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, f := range w.files {
+		_ = f.Close()
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// readFramedFile returns the exit status of all ExitNotifyParentInfo points in
+// a file written by the file sink in the framed format.
+func readFramedFile(t *testing.T, path string) []int32 {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%q): %v", path, err)
+	}
+	var statuses []int32
+	for len(data) > 0 {
+		length := binary.LittleEndian.Uint32(data)
+		msg := data[wire.FrameLengthSize : wire.FrameLengthSize+length]
+		data = data[wire.FrameLengthSize+length:]
+
+		hdr := wire.Header{}
+		hdr.UnmarshalUnsafe(msg[:wire.HeaderStructSize])
+		if want := pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT; pb.MessageType(hdr.MessageType) != want {
+			t.Errorf("wrong message type, want: %v, got: %v", want, hdr.MessageType)
+		}
+		got := &pb.ExitNotifyParentInfo{}
+		if err := proto.Unmarshal(msg[hdr.HeaderSize:], got); err != nil {
+			t.Fatalf("proto.Unmarshal(ExitNotifyParentInfo): %v", err)
+		}
+		statuses = append(statuses, got.ExitStatus)
+	}
+	return statuses
+}
+
+// newRotateSink sets up and creates a file sink with config.
+func newRotateSink(t *testing.T, config map[string]interface{}) seccheck.Checker {
+	t.Helper()
+	endpoint, err := setupRotate(config)
+	if err != nil {
+		t.Fatalf("setupRotate(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	r, err := newRotate(config, endpointFD)
+	if err != nil {
+		t.Fatalf("newRotate(): %v", err)
+	}
+	return r
+}
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	// Each file fits 2 points, all statuses below have the same size.
+	point, err := framedEncoder{}.encode(&pb.ExitNotifyParentInfo{ExitStatus: 100}, pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT, 0)
+	if err != nil {
+		t.Fatalf("encode(): %v", err)
+	}
+	config := map[string]interface{}{
+		"directory": dir,
+		"max_size":  float64(2 * len(point)),
+		"max_files": float64(3),
+	}
+	r := newRotateSink(t, config)
+	for status := int32(100); status < 108; status++ {
+		if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, &pb.ExitNotifyParentInfo{ExitStatus: status}); err != nil {
+			t.Fatalf("ExitNotifyParent: %v", err)
+		}
+	}
+	r.Stop()
+
+	// The first file was reused once all files were full.
+	for i, want := range [][]int32{{106, 107}, {102, 103}, {104, 105}} {
+		path := filepath.Join(dir, fmt.Sprintf("trace.%d", i))
+		if got := readFramedFile(t, path); !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong points in %q, want: %v, got: %v", path, want, got)
+		}
+	}
+
+	// A new sink must start with the least recently modified file.
+	now := time.Now()
+	for i, age := range []time.Duration{0, 2 * time.Hour, time.Hour} {
+		path := filepath.Join(dir, fmt.Sprintf("trace.%d", i))
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatalf("Chtimes(%q): %v", path, err)
+		}
+	}
+	r = newRotateSink(t, config)
+	if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, &pb.ExitNotifyParentInfo{ExitStatus: 200}); err != nil {
+		t.Fatalf("ExitNotifyParent: %v", err)
+	}
+	r.Stop()
+	for i, want := range [][]int32{{106, 107}, {200}, {104, 105}} {
+		path := filepath.Join(dir, fmt.Sprintf("trace.%d", i))
+		if got := readFramedFile(t, path); !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong points in %q, want: %v, got: %v", path, want, got)
+		}
+	}
+}
+
+func TestRotateJSON(t *testing.T) {
+	dir := t.TempDir()
+	config := map[string]interface{}{
+		"directory": dir,
+		"prefix":    "points",
+		"format":    "jsonl",
+		"max_files": float64(1),
+	}
+	r := newRotateSink(t, config)
+	if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, &pb.ExitNotifyParentInfo{ExitStatus: 123}); err != nil {
+		t.Fatalf("ExitNotifyParent: %v", err)
+	}
+	r.Stop()
+
+	path := filepath.Join(dir, "points.0")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%q): %v", path, err)
+	}
+	if want := `"point":{"exit_status":123}`; !strings.Contains(string(data), want) {
+		t.Errorf("file doesn't contain %q: %q", want, data)
+	}
+}
+
+func TestRotateConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config map[string]interface{}
+		err    string
+	}{
+		{
+			name:   "no-directory",
+			config: map[string]interface{}{},
+			err:    "directory not present",
+		},
+		{
+			name:   "bad-prefix",
+			config: map[string]interface{}{"directory": "/tmp", "prefix": "../trace"},
+			err:    "must be a file name",
+		},
+		{
+			name:   "bad-format",
+			config: map[string]interface{}{"directory": "/tmp", "format": "xml"},
+			err:    "invalid format",
+		},
+		{
+			name:   "bad-max-size",
+			config: map[string]interface{}{"directory": "/tmp", "max_size": float64(0)},
+			err:    "max_size",
+		},
+		{
+			name:   "bad-max-files",
+			config: map[string]interface{}{"directory": "/tmp", "max_files": float64(1000)},
+			err:    "max_files",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parseRotateConfig(tc.config); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("wrong error: want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
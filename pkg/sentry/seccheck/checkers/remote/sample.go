@@ -0,0 +1,102 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// sampler keeps a fraction of the points of some message types. It's applied
+// by each sink, so that sinks in the same session can sample differently, e.g.
+// a SIEM gets all execve points but only 1% of reads.
+type sampler struct {
+	// rates is the fraction of points kept by message type. Points of other
+	// types are all kept.
+	rates map[pb.MessageType]float64
+
+	// seen is the number of points of each type in rates.
+	seen map[pb.MessageType]*atomicbitops.Uint64
+
+	// sampledOut is the number of points that were not kept.
+	sampledOut atomicbitops.Uint64
+}
+
+// parseSampleRates returns a sampler for the "sample_rates" configuration, or
+// nil if it's not set. It maps message types to the fraction of points to
+// keep, between 0 and 1, e.g. {"MESSAGE_SYSCALL_READ": 0.01}.
+func parseSampleRates(config map[string]interface{}) (*sampler, error) {
+	opaque, ok := config["sample_rates"]
+	if !ok {
+		return nil, nil
+	}
+	rates, ok := opaque.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("sample_rates %v is not an object", opaque)
+	}
+	s := &sampler{
+		rates: make(map[pb.MessageType]float64),
+		seen:  make(map[pb.MessageType]*atomicbitops.Uint64),
+	}
+	for name, opaque := range rates {
+		t, ok := pb.MessageType_value[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid message type %q in sample_rates", name)
+		}
+		msgType := pb.MessageType(t)
+		if msgType == pb.MessageType_MESSAGE_DROP_STATS || msgType == pb.MessageType_MESSAGE_SESSION_CLOSED {
+			return nil, fmt.Errorf("%v messages cannot be sampled", msgType)
+		}
+		rate, ok := opaque.(float64)
+		if !ok || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("sample rate %v for %s must be a number between 0 and 1", opaque, name)
+		}
+		s.rates[msgType] = rate
+		s.seen[msgType] = &atomicbitops.Uint64{}
+	}
+	return s, nil
+}
+
+// keep returns true if a point of type msgType is kept. Points are kept at
+// regular intervals rather than randomly, e.g. with a rate of 0.01, the 100th,
+// 200th, etc. points are kept. A nil *sampler keeps all points.
+func (s *sampler) keep(msgType pb.MessageType) bool {
+	if s == nil {
+		return true
+	}
+	rate, ok := s.rates[msgType]
+	if !ok {
+		return true
+	}
+	n := s.seen[msgType].Add(1)
+	if uint64(float64(n)*rate) > uint64(float64(n-1)*rate) {
+		return true
+	}
+	s.sampledOut.Add(1)
+	return false
+}
+
+// status reports the number of points that were not kept as a metric.
+func (s *sampler) status(status *seccheck.CheckerStatus) {
+	status.Metrics = append(status.Metrics, seccheck.MetricSample{
+		Family: "runsc_trace_sampled_out_points_total",
+		Type:   "counter",
+		Name:   "runsc_trace_sampled_out_points_total",
+		Value:  s.sampledOut.Load(),
+	})
+}
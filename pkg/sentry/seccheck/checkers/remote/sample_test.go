@@ -0,0 +1,67 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"strings"
+	"testing"
+
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestSampleRates(t *testing.T) {
+	config := map[string]interface{}{
+		"sample_rates": map[string]interface{}{
+			"MESSAGE_SYSCALL_READ":  0.25,
+			"MESSAGE_SYSCALL_WRITE": float64(0),
+		},
+	}
+	if _, err := parseSampleRates(config); err == nil || !strings.Contains(err.Error(), "invalid message type") {
+		t.Fatalf("parseSampleRates(): want invalid message type error, got: %v", err)
+	}
+	config["sample_rates"] = map[string]interface{}{
+		"MESSAGE_SYSCALL_READ": 0.25,
+		"MESSAGE_SYSCALL_OPEN": float64(0),
+	}
+	checker, err := newCount(config, nil)
+	if err != nil {
+		t.Fatalf("newCount(): %v", err)
+	}
+	r := checker.(*remote)
+
+	// Points of types that are not sampled are all kept.
+	for i := 0; i < 8; i++ {
+		for _, msgType := range []pb.MessageType{
+			pb.MessageType_MESSAGE_SYSCALL_READ,
+			pb.MessageType_MESSAGE_SYSCALL_OPEN,
+			pb.MessageType_MESSAGE_SENTRY_EXEC,
+		} {
+			r.write(&pb.Syscall{}, msgType)
+		}
+	}
+	status := r.Status()
+	if want := uint64(8 + 2); status.PointCount != want {
+		t.Errorf("wrong number of points, want: %d, got: %d", want, status.PointCount)
+	}
+	var sampledOut uint64
+	for _, s := range status.Metrics {
+		if s.Name == "runsc_trace_sampled_out_points_total" {
+			sampledOut = s.Value
+		}
+	}
+	if want := uint64(6 + 8); sampledOut != want {
+		t.Errorf("wrong number of sampled out points, want: %d, got: %d", want, sampledOut)
+	}
+}
@@ -17,10 +17,12 @@
 package server
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"golang.org/x/sys/unix"
 	"google.golang.org/protobuf/proto"
@@ -53,6 +55,41 @@ type MessageHandler interface {
 	Close()
 }
 
+// Negotiator can be optionally implemented by a MessageHandler to inspect the
+// handshake received from the sentry.
+type Negotiator interface {
+	// Negotiate is called with the handshake received from the sentry. It
+	// returns the message types that the handler wants to receive, or nil to
+	// receive all of them. Returning an error closes the connection.
+	Negotiate(hs *pb.Handshake) ([]pb.MessageType, error)
+}
+
+// MessageSizeLimiter can be optionally implemented by a MessageHandler to limit
+// the size of the messages it receives. See Handshake.max_message_size in
+// common.proto.
+type MessageSizeLimiter interface {
+	// MaxMessageSize returns the maximum size of messages, or 0 for no limit.
+	MaxMessageSize() uint32
+}
+
+// EncodingAccepter can be optionally implemented by a MessageHandler that
+// accepts payloads in encodings other than protobuf. See Encoding in
+// common.proto.
+type EncodingAccepter interface {
+	// AcceptsEncoding returns true if the handler can decode payloads with
+	// encoding.
+	AcceptsEncoding(encoding pb.Encoding) bool
+}
+
+// Judge can be optionally implemented by a MessageHandler to allow or deny the
+// operations behind the messages that have wire.FlagVerdictRequested set. See
+// Verdict in common.proto.
+type Judge interface {
+	// Verdict is called after Message for messages that request a verdict. It
+	// returns whether the operation is allowed, and optionally why.
+	Verdict(hdr wire.Header, payload []byte) (allow bool, reason string)
+}
+
 type client struct {
 	socket  *unet.Socket
 	handler MessageHandler
@@ -67,7 +104,8 @@ func (c client) close() {
 // from different clients. Implementors decide how clients and messages are
 // handled, e.g. counting messages for testing.
 type CommonServer struct {
-	// Endpoint is the path to the socket that the server listens to.
+	// Endpoint is the path to the socket that the server listens to. It may be
+	// in the abstract namespace, see wire.IsAbstract.
 	Endpoint string
 
 	socket *unet.ServerSocket
@@ -87,7 +125,8 @@ func (s *CommonServer) Init(path string, handler ClientHandler) {
 	s.cond = sync.Cond{L: &sync.Mutex{}}
 }
 
-// Start creates the socket file and listens for new connections.
+// Start creates the socket file, unless Endpoint is in the abstract namespace,
+// and listens for new connections.
 func (s *CommonServer) Start() error {
 	socket, err := unix.Socket(unix.AF_UNIX, unix.SOCK_SEQPACKET, 0)
 	if err != nil {
@@ -98,8 +137,7 @@ func (s *CommonServer) Start() error {
 	})
 	defer cu.Clean()
 
-	sa := &unix.SockaddrUnix{Name: s.Endpoint}
-	if err := unix.Bind(socket, sa); err != nil {
+	if err := unix.Bind(socket, wire.UnixAddr(s.Endpoint)); err != nil {
 		return fmt.Errorf("bind(%q): %w", s.Endpoint, err)
 	}
 
@@ -142,45 +180,138 @@ func (s *CommonServer) run() {
 		s.cond.Broadcast()
 		s.cond.L.Unlock()
 
-		if err := s.handshake(client); err != nil {
+		hs, err := s.handshake(client)
+		if err != nil {
 			log.Warningf(err.Error())
 			s.closeClient(client)
 			continue
 		}
-		go s.handleClient(client)
+		if hs.SharedMemory {
+			go s.handleShmClient(client, hs)
+		} else {
+			go s.handleClient(client, hs)
+		}
 	}
 }
 
-// handshake performs version exchange with client. See common.proto for details
-// about the protocol.
-func (s *CommonServer) handshake(client client) error {
+// handshake performs version exchange with client and returns the handshake
+// sent back, which has the options accepted for the connection, and the
+// version negotiated. See common.proto for details about the protocol.
+func (s *CommonServer) handshake(client client) (*pb.Handshake, error) {
 	var in [1024]byte
 	read, err := client.socket.Read(in[:])
 	if err != nil {
-		return fmt.Errorf("reading handshake message: %w", err)
+		return nil, fmt.Errorf("reading handshake message: %w", err)
 	}
 	hsIn := pb.Handshake{}
 	if err := proto.Unmarshal(in[:read], &hsIn); err != nil {
-		return fmt.Errorf("unmarshalling handshake message: %w", err)
+		return nil, fmt.Errorf("unmarshalling handshake message: %w", err)
 	}
-	if hsIn.Version != wire.CurrentVersion {
-		return fmt.Errorf("wrong version number, want: %d, got, %d", wire.CurrentVersion, hsIn.Version)
+	if hsIn.Version < wire.MinSupportedVersion {
+		return nil, fmt.Errorf("unsupported version number, min: %d, got: %d", wire.MinSupportedVersion, hsIn.Version)
 	}
 
-	hsOut := pb.Handshake{Version: client.handler.Version()}
+	hsOut := pb.Handshake{
+		Version: client.handler.Version(),
+		// Batches are unpacked before they reach the handler.
+		Batch:        hsIn.Batch,
+		SharedMemory: hsIn.SharedMemory,
+		// Acknowledgments are sent by handleClient, and retransmitted
+		// messages are discarded before they reach the handler.
+		Acks: hsIn.Acks && !hsIn.SharedMemory,
+	}
+	if _, ok := client.handler.(Judge); ok {
+		// Verdicts are sent by handleClient.
+		hsOut.Verdicts = hsIn.Verdicts && !hsIn.SharedMemory
+	}
+	if n, ok := client.handler.(Negotiator); ok {
+		if hsOut.RequestedTypes, err = n.Negotiate(&hsIn); err != nil {
+			return nil, err
+		}
+	}
+	if l, ok := client.handler.(MessageSizeLimiter); ok {
+		hsOut.MaxMessageSize = l.MaxMessageSize()
+	}
+	compression, compressionOK := pickCompression(&hsIn)
+	hsOut.Compression = compression
+	encoding, encodingOK := pickEncoding(&hsIn, client.handler)
+	hsOut.Encoding = encoding
 	out, err := proto.Marshal(&hsOut)
 	if err != nil {
-		return fmt.Errorf("marshalling handshake message: %w", err)
+		return nil, fmt.Errorf("marshalling handshake message: %w", err)
 	}
 	if _, err := client.socket.Write(out); err != nil {
-		return fmt.Errorf("sending handshake message: %w", err)
+		return nil, fmt.Errorf("sending handshake message: %w", err)
 	}
-	return nil
+	if !compressionOK {
+		return nil, fmt.Errorf("unsupported compression %v", hsIn.Compression)
+	}
+	if !encodingOK {
+		return nil, fmt.Errorf("unsupported encoding %v", hsIn.Encoding)
+	}
+	// Messages are written with the lowest version of both ends.
+	if hsIn.Version < hsOut.Version {
+		hsOut.Version = hsIn.Version
+	}
+	return &hsOut, nil
+}
+
+// pickCompression returns the first compression offered in hs that is
+// supported, and false if none is. Clients that predate the list of
+// compressions only offer hs.Compression.
+func pickCompression(hs *pb.Handshake) (pb.Compression, bool) {
+	offered := hs.Compressions
+	if len(offered) == 0 {
+		offered = []pb.Compression{hs.Compression}
+	}
+	for _, compression := range offered {
+		switch compression {
+		case pb.Compression_COMPRESSION_NONE, pb.Compression_COMPRESSION_GZIP:
+			return compression, true
+		}
+	}
+	return pb.Compression_COMPRESSION_NONE, false
+}
+
+// pickEncoding returns the first encoding offered in hs that handler accepts,
+// and false if none is. Protobuf is accepted by all handlers, and others only
+// by handlers that implement EncodingAccepter. Clients that predate the list of
+// encodings only offer hs.Encoding.
+func pickEncoding(hs *pb.Handshake, handler MessageHandler) (pb.Encoding, bool) {
+	offered := hs.Encodings
+	if len(offered) == 0 {
+		offered = []pb.Encoding{hs.Encoding}
+	}
+	accepter, _ := handler.(EncodingAccepter)
+	for _, encoding := range offered {
+		if encoding == pb.Encoding_ENCODING_PROTO || (accepter != nil && accepter.AcceptsEncoding(encoding)) {
+			return encoding, true
+		}
+	}
+	return pb.Encoding_ENCODING_PROTO, false
 }
 
-func (s *CommonServer) handleClient(client client) {
+// handleClient reads messages from client until it disconnects, with the
+// options accepted in hs. If acks are enabled, received messages are
+// acknowledged, see pb.Ack. If verdicts are enabled, they are sent for the
+// messages that request them, see pb.Verdict.
+func (s *CommonServer) handleClient(client client, hs *pb.Handshake) {
 	defer s.closeClient(client)
 
+	handler := client.handler
+	if hs.Verdicts {
+		handler = &verdictSender{
+			MessageHandler: handler,
+			judge:          handler.(Judge),
+			socket:         client.socket,
+		}
+	}
+	var tracker *ackTracker
+	if hs.Acks {
+		tracker = &ackTracker{MessageHandler: client.handler, socket: client.socket}
+		defer tracker.stop()
+		handler = tracker
+	}
 	var buf = make([]byte, 1024*1024)
 	for {
 		read, err := client.socket.Read(buf)
@@ -191,18 +322,194 @@ func (s *CommonServer) handleClient(client client) {
 			}
 			panic(err)
 		}
-		if read < wire.HeaderStructSize {
-			panic("message too small")
+		if hs.MaxMessageSize != 0 && read > int(hs.MaxMessageSize) {
+			panic(fmt.Sprintf("message too big, size: %d, max: %d", read, hs.MaxMessageSize))
 		}
-		hdr := wire.Header{}
-		hdr.UnmarshalUnsafe(buf[0:wire.HeaderStructSize])
-		if read < int(hdr.HeaderSize) {
-			panic(fmt.Sprintf("message truncated, header size: %d, read: %d", hdr.HeaderSize, read))
-		}
-		if err := client.handler.Message(buf[:read], hdr, buf[hdr.HeaderSize:read]); err != nil {
+		if err := HandleMessage(handler, hs.Compression, hs.Version, buf[:read]); err != nil {
 			panic(err)
 		}
+		if tracker != nil {
+			tracker.received()
+		}
+	}
+}
+
+// ackInterval is the maximum delay before received messages are acknowledged.
+const ackInterval = 50 * time.Millisecond
+
+// ackTracker wraps a MessageHandler to acknowledge the messages it receives,
+// and to discard the ones that are retransmitted.
+type ackTracker struct {
+	MessageHandler
+
+	socket *unet.Socket
+
+	mu sync.Mutex
+
+	// highest is the highest sequence number received.
+	//
+	// +checklocks:mu
+	highest uint64
+
+	// acked is the highest sequence number acknowledged.
+	//
+	// +checklocks:mu
+	acked uint64
+
+	// duplicate is set when a message is received again, which means that
+	// the sentry is waiting for an acknowledgment.
+	//
+	// +checklocks:mu
+	duplicate bool
+
+	// timer is set while an acknowledgment is scheduled.
+	//
+	// +checklocks:mu
+	timer *time.Timer
+}
+
+// Message implements MessageHandler.
+func (t *ackTracker) Message(raw []byte, hdr wire.Header, payload []byte) error {
+	// Messages from senders that don't number them are never discarded.
+	if hdr.Sequence != 0 {
+		t.mu.Lock()
+		if hdr.Sequence <= t.highest {
+			t.duplicate = true
+			t.mu.Unlock()
+			return nil
+		}
+		t.highest = hdr.Sequence
+		t.mu.Unlock()
+	}
+	return t.MessageHandler.Message(raw, hdr, payload)
+}
+
+// received is called after messages are handled. Messages that were received
+// again are acknowledged right away, otherwise an acknowledgment is scheduled
+// to cover all messages received within ackInterval.
+func (t *ackTracker) received() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.duplicate {
+		t.ackLocked()
+		return
+	}
+	if t.highest > t.acked && t.timer == nil {
+		t.timer = time.AfterFunc(ackInterval, func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timer = nil
+			t.ackLocked()
+		})
+	}
+}
+
+// +checklocks:t.mu
+func (t *ackTracker) ackLocked() {
+	t.duplicate = false
+	out, err := proto.Marshal(&pb.Ack{Sequence: t.highest})
+	if err != nil {
+		log.Warningf("Marshal(Ack): %v", err)
+		return
+	}
+	if _, err := t.socket.Write(out); err != nil {
+		log.Debugf("Sending acknowledgment: %v", err)
+		return
+	}
+	t.acked = t.highest
+}
+
+func (t *ackTracker) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+}
+
+// verdictSender wraps a MessageHandler to send verdicts for the messages that
+// request them.
+type verdictSender struct {
+	MessageHandler
+
+	judge  Judge
+	socket *unet.Socket
+}
+
+// Message implements MessageHandler.
+func (v *verdictSender) Message(raw []byte, hdr wire.Header, payload []byte) error {
+	if err := v.MessageHandler.Message(raw, hdr, payload); err != nil {
+		return err
+	}
+	if hdr.Flags&wire.FlagVerdictRequested == 0 {
+		return nil
+	}
+	allow, reason := v.judge.Verdict(hdr, payload)
+	out, err := proto.Marshal(&pb.Verdict{Sequence: hdr.Sequence, Allow: allow, Reason: reason})
+	if err != nil {
+		return fmt.Errorf("marshalling verdict: %w", err)
+	}
+	if _, err := v.socket.Write(out); err != nil {
+		log.Debugf("Sending verdict: %v", err)
+	}
+	return nil
+}
+
+// HandleMessage hands the message in buf to handler. Batches are split into
+// the messages they contain, and compressed payloads are decompressed, so that
+// the handler only sees single, uncompressed messages. Messages must have
+// been written with version, as negotiated during handshake. It's the
+// reference implementation for remotes that read messages themselves, and
+// returns an error for any message that is malformed, e.g. truncated.
+func HandleMessage(handler MessageHandler, compression pb.Compression, version uint32, buf []byte) error {
+	// Older clients send smaller headers, in which case the fields they don't
+	// know about are left zeroed.
+	hdr, _, err := wire.ParseHeader(buf)
+	if err != nil {
+		return err
+	}
+	if err := hdr.CheckVersion(version); err != nil {
+		return err
+	}
+
+	if !hdr.VerifyChecksum(buf[hdr.HeaderSize:]) {
+		return fmt.Errorf("checksum mismatch, message type: %d, sequence: %d", hdr.MessageType, hdr.Sequence)
+	}
+
+	if hdr.MessageType == wire.BatchMessageType {
+		for batch := buf[hdr.HeaderSize:]; len(batch) > 0; {
+			if len(batch) < wire.BatchLengthSize {
+				return fmt.Errorf("batch truncated")
+			}
+			length := int(binary.LittleEndian.Uint32(batch))
+			batch = batch[wire.BatchLengthSize:]
+			if len(batch) < length {
+				return fmt.Errorf("batch truncated, message size: %d, left: %d", length, len(batch))
+			}
+			// Batches are never nested, and recursing into them would let
+			// a crafted message exhaust the stack.
+			if inner, _, err := wire.ParseHeader(batch[:length]); err == nil && inner.MessageType == wire.BatchMessageType {
+				return fmt.Errorf("nested batch")
+			}
+			if err := HandleMessage(handler, compression, version, batch[:length]); err != nil {
+				return err
+			}
+			batch = batch[length:]
+		}
+		return nil
 	}
+
+	raw, payload := buf, buf[hdr.HeaderSize:]
+	if compression != pb.Compression_COMPRESSION_NONE {
+		payload, err = wire.Decompress(compression, payload)
+		if err != nil {
+			return err
+		}
+		raw = append(append([]byte(nil), buf[:hdr.HeaderSize]...), payload...)
+		payload = raw[hdr.HeaderSize:]
+	}
+	return handler.Message(raw, hdr, payload)
 }
 
 func (s *CommonServer) closeClient(client client) {
@@ -232,7 +539,9 @@ func (s *CommonServer) Close() {
 	s.clients = nil
 	s.cond.Broadcast()
 	s.cond.L.Unlock()
-	_ = os.Remove(s.Endpoint)
+	if !wire.IsAbstract(s.Endpoint) {
+		_ = os.Remove(s.Endpoint)
+	}
 }
 
 // WaitForNoClients waits until the number of clients connected reaches 0.
@@ -0,0 +1,133 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// shmWaitTimeout bounds how long the reader waits for the ring before checking
+// whether the client went away.
+const shmWaitTimeout = 100 * time.Millisecond
+
+// handleShmClient reads messages from the shared memory ring of a client that
+// negotiated it during handshake, until the client stops or disconnects. hs is
+// the handshake sent back to the client.
+func (s *CommonServer) handleShmClient(client client, hs *pb.Handshake) {
+	defer s.closeClient(client)
+
+	mem, err := receiveRing(client)
+	if err != nil {
+		log.Warningf("Receiving shared memory ring: %v", err)
+		return
+	}
+	defer unix.Munmap(mem)
+	ring, err := wire.NewShmRing(mem)
+	if err != nil {
+		log.Warningf("Invalid shared memory ring: %v", err)
+		return
+	}
+	if err := readRing(client, ring, hs.Compression, hs.Version); err != nil {
+		log.Warningf("Reading shared memory ring: %v", err)
+	}
+}
+
+// receiveRing receives the memory file of the ring from client, and maps it.
+func receiveRing(client client) ([]byte, error) {
+	var buf [1]byte
+	r := client.socket.Reader(true)
+	r.EnableFDs(1)
+	if _, err := r.ReadVec([][]byte{buf[:]}); err != nil {
+		r.CloseFDs()
+		return nil, err
+	}
+	fds, err := r.ExtractFDs()
+	if err != nil {
+		return nil, err
+	}
+	if len(fds) != 1 {
+		for _, fd := range fds {
+			_ = unix.Close(fd)
+		}
+		return nil, fmt.Errorf("received %d files, want: 1", len(fds))
+	}
+	defer unix.Close(fds[0])
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fds[0], &stat); err != nil {
+		return nil, err
+	}
+	return unix.Mmap(fds[0], 0, int(stat.Size), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+}
+
+// readRing hands messages in ring to the client's handler as they're written.
+// The client is not trusted, so offsets and lengths are checked against the
+// size of the ring.
+func readRing(client client, ring *wire.ShmRing, compression pb.Compression, version uint32) error {
+	buf := make([]byte, ring.Size())
+	read := ring.ReadOffset().Load()
+	for {
+		written := ring.WriteOffset().Load()
+		if written == read {
+			if ring.Closed().Load() != 0 || disconnected(client) {
+				return nil
+			}
+			// Ask to be woken up, and check again in case the message was
+			// written before the doorbell was set.
+			ring.Doorbell().Store(1)
+			if ring.WriteOffset().Load() == read {
+				if err := ring.Wait(shmWaitTimeout); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if written-read > ring.Size() || written-read < wire.FrameLengthSize {
+			return fmt.Errorf("invalid offsets, written: %d, read: %d", written, read)
+		}
+		var frame [wire.FrameLengthSize]byte
+		ring.CopyOut(frame[:], read)
+		length := uint64(binary.LittleEndian.Uint32(frame[:]))
+		if length > written-read-wire.FrameLengthSize {
+			return fmt.Errorf("message truncated, size: %d, available: %d", length, written-read-wire.FrameLengthSize)
+		}
+		msg := buf[:length]
+		ring.CopyOut(msg, read+wire.FrameLengthSize)
+		read += wire.FrameLengthSize + length
+		ring.ReadOffset().Store(read)
+		if err := HandleMessage(client.handler, compression, version, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// disconnected returns true if the client closed its connection, or the
+// connection was closed by the server.
+func disconnected(client client) bool {
+	fd := client.socket.FD()
+	if fd < 0 {
+		return true
+	}
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN | unix.POLLRDHUP}}
+	n, err := unix.Poll(fds, 0)
+	return err == nil && n > 0
+}
@@ -0,0 +1,339 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/cleanup"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/memutil"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const shmName = "shm"
+
+const (
+	// shmRingSize is the default size of the data area of the ring.
+	shmRingSize = 1 << 20
+
+	// shmRingSizeLimit is the maximum size of the data area of the ring.
+	shmRingSizeLimit = 1 << 30
+)
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     shmName,
+		Setup:    setupShm,
+		New:      newShm,
+		Validate: validateFilters,
+	})
+}
+
+// parseRingSize returns the "ring_size" configuration, or shmRingSize if it's
+// not set.
+func parseRingSize(config map[string]interface{}) (int, error) {
+	opaque, ok := config["ring_size"]
+	if !ok {
+		return shmRingSize, nil
+	}
+	size, ok := opaque.(float64)
+	if !ok || size != float64(int(size)) || size < wire.ShmHeaderSize || size > shmRingSizeLimit || int(size)&(int(size)-1) != 0 {
+		return 0, fmt.Errorf("ring_size %v must be a power of 2 between %d and %d", opaque, wire.ShmHeaderSize, shmRingSizeLimit)
+	}
+	return int(size), nil
+}
+
+// setupShm connects to the remote process listening on the SOCK_SEQPACKET
+// socket in "endpoint", see connectConfig, and negotiates the use of shared
+// memory during handshake. It then creates the memory file for the ring, and
+// sends it to the remote process. The memory file and the connection are passed to the sink
+// with SCM_RIGHTS over the returned socket. The caller is responsible to close
+// to file.
+func setupShm(config map[string]interface{}) (*os.File, error) {
+	path, err := parseConfigString(config, "endpoint")
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, fmt.Errorf("endpoint not present in configuration")
+	}
+	ringSize, err := parseRingSize(config)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Shared memory sink connecting to %q", path)
+	conn, err := connect(path, config)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := handshake(conn, false /* stream */, true /* sharedMemory */, config); err != nil {
+		return nil, err
+	}
+
+	memfd, err := memutil.CreateMemFD("seccheck_shm", linux.MFD_CLOEXEC|linux.MFD_ALLOW_SEALING)
+	if err != nil {
+		return nil, fmt.Errorf("creating memfd: %w", err)
+	}
+	defer unix.Close(memfd)
+	if err := unix.Ftruncate(memfd, int64(wire.ShmHeaderSize+ringSize)); err != nil {
+		return nil, fmt.Errorf("ftruncate(memfd, %d): %w", wire.ShmHeaderSize+ringSize, err)
+	}
+	// The remote process is not trusted. Prevent it from shrinking the file,
+	// which would fault the sandbox when it writes to the ring.
+	if _, _, e := unix.RawSyscall(unix.SYS_FCNTL, uintptr(memfd), linux.F_ADD_SEALS, linux.F_SEAL_SHRINK|linux.F_SEAL_GROW|linux.F_SEAL_SEAL); e != 0 {
+		return nil, fmt.Errorf("applying memfd seals: %w", e)
+	}
+	if err := unix.Sendmsg(int(conn.Fd()), []byte{0}, unix.UnixRights(memfd), nil, 0); err != nil {
+		return nil, fmt.Errorf("sending memfd: %w", err)
+	}
+
+	// The connection is passed along so that the remote process can detect
+	// when the sandbox goes away.
+	endpoint, err := sendFiles(path, []int{memfd, int(conn.Fd())})
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Shared memory sink set up, ring size: %d", ringSize)
+	return endpoint, nil
+}
+
+// newShm creates a new checker that writes points to a ring in memory shared
+// with the remote process, see wire.ShmRing. Points are written without any
+// system call, unless the remote process is waiting for them. Points that
+// don't fit in the ring are dropped. Points left in the ring when the sink is
+// stopped can still be read by the remote process.
+func newShm(config map[string]interface{}, endpoint *fd.FD) (seccheck.Checker, error) {
+	if endpoint == nil {
+		return nil, fmt.Errorf("shm sink requires an endpoint")
+	}
+	ringSize, err := parseRingSize(config)
+	if err != nil {
+		return nil, err
+	}
+	compression, err := parseCompression(config)
+	if err != nil {
+		return nil, err
+	}
+	encoding, err := parseEncoding(config)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := parseMessageTypes(config)
+	if err != nil {
+		return nil, err
+	}
+	sampler, err := parseSampleRates(config)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFilters(config)
+	if err != nil {
+		return nil, err
+	}
+	dropStatsInterval, err := parseDropStatsInterval(config)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := parseBool(config, "checksum")
+	if err != nil {
+		return nil, err
+	}
+	maxMessageSize, err := parseMaxMessageSize(config)
+	if err != nil {
+		return nil, err
+	}
+	version, err := parseVersion(config)
+	if err != nil {
+		return nil, err
+	}
+	files, err := receiveFiles(endpoint, 2)
+	_ = endpoint.Close()
+	if err != nil {
+		return nil, err
+	}
+	cu := cleanup.Make(func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	})
+	defer cu.Clean()
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(files[0].FD(), &stat); err != nil {
+		return nil, fmt.Errorf("fstat(memfd): %w", err)
+	}
+	if stat.Size != int64(wire.ShmHeaderSize+ringSize) {
+		return nil, fmt.Errorf("memfd size: %d, want: %d", stat.Size, wire.ShmHeaderSize+ringSize)
+	}
+	mem, err := unix.Mmap(files[0].FD(), 0, int(stat.Size), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("mmap(memfd): %w", err)
+	}
+	ring, err := wire.NewShmRing(mem)
+	if err != nil {
+		_ = unix.Munmap(mem)
+		return nil, err
+	}
+
+	cu.Release()
+	w := &shmWriter{
+		files:       files,
+		mem:         mem,
+		ring:        ring,
+		compression: compression,
+	}
+	r := &remote{
+		sender:         w,
+		encoding:       encoding,
+		filter:         filter,
+		fields:         fields,
+		sampler:        sampler,
+		checksum:       checksum,
+		maxMessageSize: maxMessageSize,
+		version:        version,
+		sessionClosed:  true,
+	}
+	if dropStatsInterval > 0 {
+		r.startDropStats(dropStatsInterval)
+	}
+	log.Debugf("Shared memory sink created, ring size: %d", ringSize)
+	return r, nil
+}
+
+// shmWriter writes points to a shared memory ring.
+type shmWriter struct {
+	// files are the ring's memory file and the connection to the remote
+	// process.
+	files []*fd.FD
+
+	compression pb.Compression
+
+	mu sync.Mutex
+
+	// mem is the mapping of the memory file. It's nil once the sink is stopped.
+	//
+	// +checklocks:mu
+	mem []byte
+
+	// +checklocks:mu
+	ring *wire.ShmRing
+
+	// written is the total number of bytes written to the ring. It's kept
+	// locally because the write offset in the ring can be changed by the remote
+	// process.
+	//
+	// +checklocks:mu
+	written uint64
+}
+
+var _ sender = (*shmWriter)(nil)
+var _ statusReporter = (*shmWriter)(nil)
+
+// name implements sender.
+func (*shmWriter) name() string {
+	return shmName
+}
+
+// send implements sender.
+func (w *shmWriter) send(r *remote, msg proto.Message, msgType pb.MessageType) error {
+	timeNs := time.Now().UnixNano()
+	// The payload is copied into the ring before returning.
+	buf := getPayloadBuffer()
+	out, flags, err := r.marshal(buf.bytes(), msg, w.compression)
+	defer buf.release(out)
+	if err != nil {
+		log.Debugf("Dropping %v point: %v", msgType, err)
+		r.setError(err)
+		r.dropped(msgType, 1)
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.mem == nil {
+		r.dropped(msgType, 1)
+		return errStopped
+	}
+	// The sequence number is taken with the lock held, so that points are
+	// numbered in the order they're written to the ring.
+	hdr := r.header(uint16(msgType), r.sequence.Add(1), timeNs, flags, out)
+	length := uint64(wire.HeaderStructSize + len(out))
+	used := w.written - w.ring.ReadOffset().Load()
+	if used > w.ring.Size() || wire.FrameLengthSize+length > w.ring.Size()-used {
+		// The ring is full, or the read offset is bogus.
+		r.dropped(msgType, 1)
+		return errQueueFull
+	}
+	var frame [wire.FrameLengthSize]byte
+	binary.LittleEndian.PutUint32(frame[:], uint32(length))
+	w.ring.CopyIn(w.written, frame[:])
+	w.ring.CopyIn(w.written+wire.FrameLengthSize, hdr[:])
+	w.ring.CopyIn(w.written+wire.FrameLengthSize+wire.HeaderStructSize, out)
+	w.written += wire.FrameLengthSize + length
+	w.ring.WriteOffset().Store(w.written)
+	if err := w.ring.Wake(); err != nil {
+		log.Debugf("Waking up shared memory reader: %v", err)
+	}
+	return nil
+}
+
+// status implements statusReporter. The remote process is disconnected once it
+// closes its end of the connection.
+func (w *shmWriter) status(status *seccheck.CheckerStatus) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.mem == nil {
+		return
+	}
+	status.State = seccheck.SinkConnected
+	fds := []unix.PollFd{{Fd: int32(w.files[1].FD()), Events: unix.POLLRDHUP}}
+	if n, err := unix.Poll(fds, 0); err == nil && n > 0 {
+		status.State = seccheck.SinkDisconnected
+	}
+}
+
+// stop implements sender.
+func (w *shmWriter) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.mem == nil {
+		return
+	}
+	w.ring.Closed().Store(1)
+	if err := w.ring.Wake(); err != nil {
+		log.Debugf("Waking up shared memory reader: %v", err)
+	}
+	if err := unix.Munmap(w.mem); err != nil {
+		log.Warningf("munmap(memfd): %v", err)
+	}
+	w.mem = nil
+	w.ring = nil
+	for _, f := range w.files {
+		_ = f.Close()
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/test"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestShm(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	for _, size := range []float64{0, 5000, 1 << 31} {
+		if _, err := setupShm(map[string]interface{}{"endpoint": server.Endpoint, "ring_size": size}); err == nil || !strings.Contains(err.Error(), "ring_size") {
+			t.Errorf("setupShm(ring_size: %v), want ring_size error, got: %v", size, err)
+		}
+	}
+	config := map[string]interface{}{
+		"endpoint":  server.Endpoint,
+		"ring_size": float64(1 << 16),
+	}
+	endpoint, err := setupShm(config)
+	if err != nil {
+		t.Fatalf("setupShm(): %v", err)
+	}
+	if hs := server.Handshake(); hs == nil || !hs.SharedMemory {
+		t.Errorf("shared memory not requested in handshake: %+v", hs)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	r, err := newShm(config, endpointFD)
+	if err != nil {
+		t.Fatalf("newShm(): %v", err)
+	}
+	const count = 1000
+	failed := 0
+	for status := int32(0); status < count; status++ {
+		if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, &pb.ExitNotifyParentInfo{ExitStatus: status}); err != nil {
+			failed++
+		}
+	}
+	// Points that didn't fit in the ring are dropped.
+	dropped := int(r.Status().DroppedCount)
+	if failed != dropped {
+		t.Errorf("wrong number of failed points, want: %d, got: %d", dropped, failed)
+	}
+	server.WaitForCount(count - dropped)
+	r.Stop()
+	// The server disconnects once it sees that the ring was closed.
+	server.WaitForNoClients()
+
+	// Points are followed by SessionClosed, which fits since the ring was
+	// emptied.
+	points := server.GetPoints()
+	if got, want := len(points), count-dropped+1; got != want {
+		t.Fatalf("wrong number of points, want: %d, got: %d", want, got)
+	}
+	closed := points[len(points)-1]
+	if want := pb.MessageType_MESSAGE_SESSION_CLOSED; closed.MsgType != want {
+		t.Errorf("wrong message type, want: %v, got: %v", want, closed.MsgType)
+	}
+	points = points[:len(points)-1]
+	last := int32(-1)
+	for _, pt := range points {
+		got := &pb.ExitNotifyParentInfo{}
+		if err := proto.Unmarshal(pt.Msg, got); err != nil {
+			t.Fatalf("proto.Unmarshal(ExitNotifyParentInfo): %v", err)
+		}
+		if got.ExitStatus <= last {
+			t.Errorf("points out of order, got %d after %d", got.ExitStatus, last)
+		}
+		last = got.ExitStatus
+	}
+}
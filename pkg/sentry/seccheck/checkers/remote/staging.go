@@ -0,0 +1,151 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"fmt"
+	"sort"
+
+	"gvisor.dev/gvisor/pkg/goid"
+)
+
+// parseStagingBuffers returns the "staging_buffers" configuration, or 0 if
+// it's not set.
+func parseStagingBuffers(config map[string]interface{}) (int, error) {
+	opaque, ok := config["staging_buffers"]
+	if !ok {
+		return 0, nil
+	}
+	count, ok := opaque.(float64)
+	if !ok || count != float64(int(count)) || count <= 0 {
+		return 0, fmt.Errorf("staging_buffers %v is not a positive int", opaque)
+	}
+	return int(count), nil
+}
+
+// stagingBuffers is a pointQueue made of several ringBuffers, so that tasks
+// that generate points concurrently rarely contend on the same lock. Points
+// are staged in the buffer picked by the goroutine of the task that generated
+// them, which keeps the points of each task in order, and flush collects them
+// from all buffers.
+type stagingBuffers struct {
+	buffers []*ringBuffer
+
+	// wake has room for a single wakeup of popBatch, which is sent whenever
+	// an entry is pushed, and when the buffers are drained or closed.
+	wake chan struct{}
+
+	// next is the index of the buffer that popBatch collects from first, so
+	// that no buffer is starved when batches are full. It's only accessed by
+	// popBatch, which is only called by flush.
+	next int
+}
+
+// newStagingBuffers returns count buffers that hold size entries in total.
+func newStagingBuffers(count, size int) *stagingBuffers {
+	s := &stagingBuffers{
+		buffers: make([]*ringBuffer, count),
+		wake:    make(chan struct{}, 1),
+	}
+	for i := range s.buffers {
+		// The first buffers get the remainder of the division.
+		n := size / count
+		if i < size%count {
+			n++
+		}
+		s.buffers[i] = newRingBuffer(n)
+	}
+	return s
+}
+
+func (s *stagingBuffers) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// push implements pointQueue.push. The entry is rejected if the buffer of the
+// calling goroutine is full, even if others have room.
+func (s *stagingBuffers) push(e ringEntry) bool {
+	b := s.buffers[uint64(goid.Get())%uint64(len(s.buffers))]
+	if !b.push(e) {
+		return false
+	}
+	s.notify()
+	return true
+}
+
+// popBatch implements pointQueue.popBatch. Entries collected from different
+// buffers are sorted by sequence, so that points are written in about the
+// order that they were generated.
+func (s *stagingBuffers) popBatch(maxCount, maxBytes int) []ringEntry {
+	for {
+		var entries []ringEntry
+		size, done := 0, 0
+		for i := range s.buffers {
+			b := s.buffers[(s.next+i)%len(s.buffers)]
+			var finished bool
+			entries, size, finished = b.popAvailable(entries, maxCount, maxBytes, size)
+			if finished {
+				done++
+			}
+		}
+		s.next = (s.next + 1) % len(s.buffers)
+		if len(entries) > 0 {
+			sort.SliceStable(entries, func(i, j int) bool {
+				return entries[i].sequence < entries[j].sequence
+			})
+			return entries
+		}
+		if done == len(s.buffers) {
+			return nil
+		}
+		<-s.wake
+	}
+}
+
+// drain implements pointQueue.drain.
+func (s *stagingBuffers) drain() {
+	for _, b := range s.buffers {
+		b.drain()
+	}
+	s.notify()
+}
+
+// close implements pointQueue.close.
+func (s *stagingBuffers) close() int {
+	discarded := 0
+	for _, b := range s.buffers {
+		discarded += b.close()
+	}
+	s.notify()
+	return discarded
+}
+
+// len implements pointQueue.len.
+func (s *stagingBuffers) len() int {
+	n := 0
+	for _, b := range s.buffers {
+		n += b.len()
+	}
+	return n
+}
+
+// isClosed implements pointQueue.isClosed.
+func (s *stagingBuffers) isClosed() bool {
+	// All buffers are closed together.
+	return s.buffers[0].isClosed()
+}
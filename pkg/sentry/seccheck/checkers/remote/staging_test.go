@@ -0,0 +1,154 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"sync"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/test"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestStagingBuffers(t *testing.T) {
+	s := newStagingBuffers(4, 10)
+	if got := len(s.buffers[0].entries) + len(s.buffers[3].entries); got != 5 {
+		t.Errorf("wrong buffer sizes, want: 3 + 2, got: %d", got)
+	}
+
+	// Entries pushed from different goroutines are returned in sequence
+	// order.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(sequence uint64) {
+			defer wg.Done()
+			if !s.push(ringEntry{sequence: sequence}) {
+				t.Errorf("push(%d) failed", sequence)
+			}
+		}(uint64(4 - i))
+	}
+	wg.Wait()
+	if got := s.len(); got != 4 {
+		t.Errorf("len(): got: %d, want: 4", got)
+	}
+	es := s.popBatch(10, maxBatchBytes)
+	if len(es) != 4 {
+		t.Fatalf("popBatch(): %+v", es)
+	}
+	for i, e := range es {
+		if e.sequence != uint64(i+1) {
+			t.Errorf("popBatch(): entry %d out of order: %+v", i, es)
+		}
+	}
+
+	// popBatch waits for entries to be pushed.
+	done := make(chan []ringEntry)
+	go func() {
+		done <- s.popBatch(10, maxBatchBytes)
+	}()
+	if !s.push(ringEntry{sequence: 5}) {
+		t.Fatalf("push(5) failed")
+	}
+	if es := <-done; len(es) != 1 || es[0].sequence != 5 {
+		t.Fatalf("popBatch(): %+v", es)
+	}
+
+	// Entries left are still returned after drain, and then popBatch doesn't
+	// wait.
+	if !s.push(ringEntry{sequence: 6}) {
+		t.Fatalf("push(6) failed")
+	}
+	s.drain()
+	if s.push(ringEntry{}) {
+		t.Errorf("push() succeeded on draining buffers")
+	}
+	if es := s.popBatch(10, maxBatchBytes); len(es) != 1 {
+		t.Fatalf("popBatch(): %+v", es)
+	}
+	if es := s.popBatch(10, maxBatchBytes); es != nil {
+		t.Errorf("popBatch() succeeded on drained buffers: %+v", es)
+	}
+	if discarded := s.close(); discarded != 0 {
+		t.Errorf("close() discarded %d entries, want: 0", discarded)
+	}
+	if !s.isClosed() {
+		t.Errorf("isClosed(): got: false, want: true")
+	}
+}
+
+func TestStagingBuffersQueue(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	endpoint, err := setup(server.Endpoint, nil)
+	if err != nil {
+		t.Fatalf("setup(): %v", err)
+	}
+	endpointFD, err := fd.NewFromFile(endpoint)
+	if err != nil {
+		_ = endpoint.Close()
+		t.Fatalf("NewFromFile(): %v", err)
+	}
+	_ = endpoint.Close()
+
+	config := map[string]interface{}{
+		"queue_size":      float64(400),
+		"staging_buffers": float64(4),
+		"batch_size":      float64(8),
+	}
+	r, err := new(config, endpointFD)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	defer r.Stop()
+
+	// Each goroutine stands for a task, whose points must stay in order.
+	const tasks, count = 4, 10
+	var wg sync.WaitGroup
+	for task := 0; task < tasks; task++ {
+		wg.Add(1)
+		go func(task int) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				info := &pb.ExitNotifyParentInfo{ExitStatus: int32(task*count + i)}
+				if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+					t.Errorf("ExitNotifyParent: %v", err)
+				}
+			}
+		}(task)
+	}
+	wg.Wait()
+
+	server.WaitForCount(tasks * count)
+	last := make(map[int32]int32)
+	for _, pt := range server.GetPoints() {
+		got := &pb.ExitNotifyParentInfo{}
+		if err := proto.Unmarshal(pt.Msg, got); err != nil {
+			t.Fatalf("proto.Unmarshal(ExitNotifyParentInfo): %v", err)
+		}
+		task := got.ExitStatus / count
+		if prev, ok := last[task]; ok && got.ExitStatus <= prev {
+			t.Errorf("point %+v of task %d out of order, previous: %d", got, task, prev)
+		}
+		last[task] = got.ExitStatus
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/log"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// defaultStopTimeout is how long Stop waits for queued points to be written,
+// unless "stop_timeout" is set.
+const defaultStopTimeout = time.Second
+
+// stopPollInterval is how often Stop checks whether points were acknowledged.
+const stopPollInterval = 10 * time.Millisecond
+
+// parseStopTimeout returns the "stop_timeout" configuration, or
+// defaultStopTimeout if it's not set.
+func parseStopTimeout(config map[string]interface{}) (time.Duration, error) {
+	ok, timeout, err := parseDuration(config, "stop_timeout")
+	if err != nil || !ok {
+		return defaultStopTimeout, err
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("stop_timeout %v cannot be negative", timeout)
+	}
+	return timeout, nil
+}
+
+// writeSessionClosed sends the final SessionClosed message, see common.proto.
+// It must be called after drop stats are stopped.
+func (r *remote) writeSessionClosed() {
+	msg := &pb.SessionClosed{
+		LastSequence: r.sequence.Load(),
+		DroppedCount: uint64(r.droppedCount.Load()),
+		Reason:       r.stopReason,
+	}
+	if r.drops != nil {
+		if stats := r.drops.take(); stats != nil {
+			msg.Drops = stats.Drops
+		}
+	}
+	_ = r.write(msg, pb.MessageType_MESSAGE_SESSION_CLOSED)
+}
+
+// stopQueue writes the points left in r.ring, and waits for them to be
+// acknowledged in reliable mode. Points that are still queued at deadline are
+// dropped.
+func (r *remote) stopQueue(deadline time.Time) {
+	r.ring.drain()
+	wait := time.Until(deadline)
+	if r.disconnected.Load() != 0 {
+		// Queued points can't be written until the sink is reconnected.
+		wait = 0
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-r.flushDone:
+		if r.replay != nil {
+			r.waitAcked(deadline)
+		}
+	case <-timer.C:
+		log.Warningf("Remote sink stop timed out with points still queued")
+	}
+
+	r.droppedCount.Add(uint32(r.ring.close()))
+	if r.replay != nil {
+		// Points that weren't acknowledged may or may not have been received,
+		// so they aren't counted as dropped.
+		if unacked := r.replay.close(); unacked > 0 {
+			log.Warningf("Remote sink stopped with %d unacknowledged point(s)", unacked)
+		}
+		<-r.acksDone
+	}
+	<-r.flushDone
+}
+
+// waitAcked waits until all points written are acknowledged, the remote
+// process goes away, or deadline.
+func (r *remote) waitAcked(deadline time.Time) {
+	ticker := time.NewTicker(stopPollInterval)
+	defer ticker.Stop()
+	for !r.replay.empty() && r.disconnected.Load() == 0 && time.Now().Before(deadline) {
+		select {
+		case <-r.acksDone:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// closeEndpoint closes the connection to the remote process. Closing a TCP
+// connection with unread data, e.g. acknowledgments, resets it, and the remote
+// process could lose the points that it didn't read yet. So stream endpoints
+// are shut down for writing first, and closed once the remote process closes
+// its side, or at deadline.
+func (r *remote) closeEndpoint(deadline time.Time) {
+	if r.stream {
+		if err := unix.Shutdown(r.endpoint.FD(), unix.SHUT_WR); err != nil {
+			log.Debugf("shutdown(SHUT_WR): %v", err)
+		} else {
+			r.waitEOF(deadline)
+		}
+	}
+	// It's possible to race with Point firing, but in the worst case they will
+	// simply fail to be delivered.
+	r.endpoint.Close()
+}
+
+// waitEOF reads and discards data from the endpoint until the remote process
+// closes the connection, or deadline.
+func (r *remote) waitEOF(deadline time.Time) {
+	buf := make([]byte, 4096)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return
+		}
+		if remaining > flushPollTimeout {
+			remaining = flushPollTimeout
+		}
+		timeout := unix.NsecToTimespec(remaining.Nanoseconds())
+		fds := []unix.PollFd{{Fd: int32(r.endpoint.FD()), Events: unix.POLLIN}}
+		if _, err := unix.Ppoll(fds, &timeout, nil); err != nil && !errors.Is(err, unix.EINTR) {
+			return
+		}
+		if fds[0].Revents == 0 {
+			continue
+		}
+		n, err := unix.Read(r.endpoint.FD(), buf)
+		if n == 0 && err == nil {
+			return
+		}
+		if err != nil && !errors.Is(err, unix.EAGAIN) && !errors.Is(err, unix.EINTR) {
+			return
+		}
+	}
+}
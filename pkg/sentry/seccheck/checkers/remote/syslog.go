@@ -0,0 +1,322 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+const syslogName = "syslog"
+
+// Transports supported by the syslog sink, in addition to transportTCP.
+const (
+	// syslogTransportUnixgram sends messages to a local daemon over a
+	// SOCK_DGRAM Unix-domain socket. This is the default.
+	syslogTransportUnixgram = "unixgram"
+
+	// syslogTransportUDP sends messages over UDP, as described in RFC 5426.
+	syslogTransportUDP = "udp"
+)
+
+// syslogDefaultEndpoint is the socket of the local syslog daemon.
+const syslogDefaultEndpoint = "/dev/log"
+
+// syslogSDID is the SD-ID of the structured data element added to every
+// message. gVisor doesn't have a private enterprise number, so the one
+// reserved for documentation by RFC 5612 is used.
+const syslogSDID = "gvisor@32473"
+
+// Syslog severities from RFC 5424, section 6.2.1.
+const (
+	syslogSeverityWarning = 4
+	syslogSeverityNotice  = 5
+	syslogSeverityInfo    = 6
+)
+
+// syslogSeverities holds the severity of points that are more relevant to
+// security monitoring than most. Other points are informational.
+var syslogSeverities = map[pb.MessageType]int{
+	pb.MessageType_MESSAGE_SENTRY_CAPABILITY_DENIED: syslogSeverityNotice,
+	pb.MessageType_MESSAGE_SENTRY_CORE_DUMP:         syslogSeverityWarning,
+	pb.MessageType_MESSAGE_SENTRY_OOM:               syslogSeverityWarning,
+	pb.MessageType_MESSAGE_SENTRY_RLIMIT_BREACH:     syslogSeverityNotice,
+	pb.MessageType_MESSAGE_SENTRY_SECCOMP:           syslogSeverityWarning,
+}
+
+// syslogFacilities maps the values accepted by the "facility" configuration to
+// the facility codes from RFC 5424, section 6.2.1.
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:     syslogName,
+		Setup:    setupSyslog,
+		New:      newSyslog,
+		Validate: validateFilters,
+	})
+}
+
+// syslogConfig is the configuration of the syslog sink:
+//
+//	transport: "unixgram" (default), "udp" or "tcp".
+//	endpoint:  socket path for unixgram, or host:port otherwise. Defaults to
+//	           /dev/log for unixgram.
+//	facility:  facility name, e.g. "daemon" or "local0". Defaults to "user".
+//	hostname:  HOSTNAME of messages. Defaults to the host's name.
+//	app_name:  APP-NAME of messages. Defaults to "runsc".
+type syslogConfig struct {
+	transport string
+	endpoint  string
+	facility  int
+	hostname  string
+	appName   string
+}
+
+func parseSyslogConfig(config map[string]interface{}) (syslogConfig, error) {
+	c := syslogConfig{
+		transport: syslogTransportUnixgram,
+		facility:  syslogFacilities["user"],
+		appName:   "runsc",
+	}
+	var err error
+	if transport, err := parseConfigString(config, "transport"); err != nil {
+		return syslogConfig{}, err
+	} else if transport != "" {
+		switch transport {
+		case syslogTransportUnixgram, syslogTransportUDP, transportTCP:
+			c.transport = transport
+		default:
+			return syslogConfig{}, fmt.Errorf("invalid transport %q, must be %q, %q or %q", transport, syslogTransportUnixgram, syslogTransportUDP, transportTCP)
+		}
+	}
+	if c.endpoint, err = parseConfigString(config, "endpoint"); err != nil {
+		return syslogConfig{}, err
+	}
+	if c.endpoint == "" {
+		if c.transport != syslogTransportUnixgram {
+			return syslogConfig{}, fmt.Errorf("endpoint not present in configuration")
+		}
+		c.endpoint = syslogDefaultEndpoint
+	}
+	if facility, err := parseConfigString(config, "facility"); err != nil {
+		return syslogConfig{}, err
+	} else if facility != "" {
+		code, ok := syslogFacilities[facility]
+		if !ok {
+			return syslogConfig{}, fmt.Errorf("invalid facility %q", facility)
+		}
+		c.facility = code
+	}
+	if c.hostname, err = parseConfigString(config, "hostname"); err != nil {
+		return syslogConfig{}, err
+	}
+	if appName, err := parseConfigString(config, "app_name"); err != nil {
+		return syslogConfig{}, err
+	} else if appName != "" {
+		c.appName = appName
+	}
+	return c, nil
+}
+
+// setupSyslog connects to the syslog daemon. The sandbox doesn't know the
+// host's name, so it's added to config if not set. The caller is responsible to
+// close to file.
+func setupSyslog(config map[string]interface{}) (*os.File, error) {
+	c, err := parseSyslogConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if c.hostname == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("getting hostname: %w", err)
+		}
+		config["hostname"] = hostname
+	}
+
+	log.Debugf("Syslog sink connecting to %s %q", c.transport, c.endpoint)
+	// Datagrams that can't be sent right away are dropped. Writes to TCP are
+	// blocking, like writes to files, since a partial write would break
+	// framing.
+	return dialFile(c.transport, c.endpoint, c.transport != transportTCP)
+}
+
+// dialFile connects to addr on the named network, and returns the connection's
+// file. Unix addresses may be in the abstract namespace, see wire.IsAbstract.
+func dialFile(network, addr string, nonblock bool) (*os.File, error) {
+	if strings.HasPrefix(network, "unix") {
+		addr = wire.UnixPath(addr)
+	}
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	// File returns a blocking duplicate of the connection's file descriptor, so
+	// the connection itself is no longer needed after setup.
+	defer conn.Close()
+	f, err := conn.(interface{ File() (*os.File, error) }).File()
+	if err != nil {
+		return nil, fmt.Errorf("getting file for %s connection to %q: %w", network, addr, err)
+	}
+	if nonblock {
+		if err := unix.SetNonblock(int(f.Fd()), true); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// newSyslog creates a new checker that sends points as RFC 5424 syslog
+// messages.
+func newSyslog(config map[string]interface{}, endpoint *fd.FD) (seccheck.Checker, error) {
+	if endpoint == nil {
+		return nil, fmt.Errorf("syslog sink requires an endpoint")
+	}
+	sampler, err := parseSampleRates(config)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFilters(config)
+	if err != nil {
+		return nil, err
+	}
+	c, err := parseSyslogConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Syslog sink created, endpoint FD: %d, transport: %s", endpoint.FD(), c.transport)
+	return &remote{fields: fields, sampler: sampler, sender: &lineWriter{
+		sinkName: syslogName,
+		encode:   c.encode,
+		endpoint: endpoint,
+	}}, nil
+}
+
+// encode renders a point as an RFC 5424 message, e.g.:
+//
+//	<14>1 2022-06-01T12:00:00.000000Z host runsc 7 SENTRY_CLONE [gvisor@32473 type="MESSAGE_SENTRY_CLONE" dropped_count="0"] {...}
+//
+// PROCID is the thread group ID of the task that generated the point, and the
+// complete point is included as JSON in MSG. Messages sent over TCP are framed
+// with octet counting, as described in RFC 6587, section 3.4.1.
+func (c syslogConfig) encode(msg proto.Message, msgType pb.MessageType, droppedCount uint32) ([]byte, error) {
+	point, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	severity, ok := syslogSeverities[msgType]
+	if !ok {
+		severity = syslogSeverityInfo
+	}
+	ts := time.Now()
+	procID := ""
+	ctx := contextData(msg)
+	if ctx != nil {
+		if ctx.TimeNs != 0 {
+			ts = time.Unix(0, ctx.TimeNs)
+		}
+		if ctx.ThreadGroupId != 0 {
+			procID = strconv.FormatInt(int64(ctx.ThreadGroupId), 10)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s [%s",
+		c.facility*8+severity,
+		ts.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(c.hostname, 255),
+		syslogHeaderField(c.appName, 48),
+		syslogHeaderField(procID, 128),
+		syslogHeaderField(strings.TrimPrefix(msgType.String(), "MESSAGE_"), 32),
+		syslogSDID)
+	addParam := func(name, value string) {
+		fmt.Fprintf(&b, ` %s="%s"`, name, syslogParamEscaper.Replace(value))
+	}
+	addParam("type", msgType.String())
+	if ctx != nil {
+		if ctx.ContainerId != "" {
+			addParam("container_id", ctx.ContainerId)
+		}
+		if ctx.ProcessName != "" {
+			addParam("process_name", ctx.ProcessName)
+		}
+	}
+	addParam("dropped_count", strconv.FormatUint(uint64(droppedCount), 10))
+	b.WriteString("] ")
+	b.Write(point)
+
+	out := b.String()
+	if c.transport == transportTCP {
+		out = strconv.Itoa(len(out)) + " " + out
+	}
+	return []byte(out), nil
+}
+
+// syslogHeaderField returns s as a header field of at most max characters.
+// Header fields only allow printable US-ASCII characters, others are replaced
+// with '_'. Empty fields are represented with "-".
+func syslogHeaderField(s string, max int) string {
+	if s == "" {
+		return "-"
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	return strings.Map(func(r rune) rune {
+		if r < '!' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// syslogParamEscaper escapes SD-PARAM values, see RFC 5424, section 6.3.3.
+var syslogParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
@@ -0,0 +1,137 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestSyslog(t *testing.T) {
+	info := &pb.ExitNotifyParentInfo{
+		ContextData: &pb.ContextData{
+			TimeNs:        5_000_000,
+			ThreadGroupId: 7,
+			ProcessName:   `a"b]`,
+			ContainerId:   "abc",
+		},
+		ExitStatus: 123,
+	}
+	const want = `<134>1 1970-01-01T00:00:00.005000Z my_host runsc 7 SENTRY_EXIT_NOTIFY_PARENT [gvisor@32473 type="MESSAGE_SENTRY_EXIT_NOTIFY_PARENT" container_id="abc" process_name="a\"b\]" dropped_count="2"] {`
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log")
+	daemon, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("ListenUnixgram(): %v", err)
+	}
+	defer daemon.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen(): %v", err)
+	}
+	defer listener.Close()
+
+	for _, tc := range []struct {
+		transport string
+		endpoint  string
+		read      func() (string, error)
+	}{
+		{
+			transport: "unixgram",
+			endpoint:  path,
+			read: func() (string, error) {
+				buf := make([]byte, 4096)
+				n, err := daemon.Read(buf)
+				return string(buf[:n]), err
+			},
+		},
+		{
+			transport: "tcp",
+			endpoint:  listener.Addr().String(),
+			read: func() (string, error) {
+				conn, err := listener.Accept()
+				if err != nil {
+					return "", err
+				}
+				defer conn.Close()
+				var length int
+				if _, err := fmt.Fscanf(conn, "%d ", &length); err != nil {
+					return "", err
+				}
+				buf := make([]byte, length)
+				_, err = io.ReadFull(conn, buf)
+				return string(buf), err
+			},
+		},
+	} {
+		t.Run(tc.transport, func(t *testing.T) {
+			config := map[string]interface{}{
+				"transport": tc.transport,
+				"endpoint":  tc.endpoint,
+				"facility":  "local0",
+				"hostname":  "my host",
+			}
+			endpoint, err := setupSyslog(config)
+			if err != nil {
+				t.Fatalf("setupSyslog(): %v", err)
+			}
+			endpointFD, err := fd.NewFromFile(endpoint)
+			if err != nil {
+				_ = endpoint.Close()
+				t.Fatalf("NewFromFile(): %v", err)
+			}
+			_ = endpoint.Close()
+			r, err := newSyslog(config, endpointFD)
+			if err != nil {
+				t.Fatalf("newSyslog(): %v", err)
+			}
+			defer r.Stop()
+			r.(*remote).droppedCount.Store(2)
+			if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, info); err != nil {
+				t.Fatalf("ExitNotifyParent: %v", err)
+			}
+
+			got, err := tc.read()
+			if err != nil {
+				t.Fatalf("reading message: %v", err)
+			}
+			if !strings.HasPrefix(got, want) {
+				t.Errorf("wrong message, want prefix: %q, got: %q", want, got)
+			}
+			if strings.Contains(got, "\n") {
+				t.Errorf("message must not contain newlines: %q", got)
+			}
+		})
+	}
+
+	for _, config := range []map[string]interface{}{
+		{"transport": "udp"},
+		{"transport": "pipe"},
+		{"facility": "local8"},
+	} {
+		if _, err := parseSyslogConfig(config); err == nil {
+			t.Errorf("parseSyslogConfig(%v) succeeded, want error", config)
+		}
+	}
+}
@@ -16,7 +16,9 @@
 package test
 
 import (
+	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"path/filepath"
 
@@ -40,6 +42,18 @@ type Server struct {
 
 	// +checklocks:mu
 	version uint32
+
+	// +checklocks:mu
+	requestedTypes []pb.MessageType
+
+	// +checklocks:mu
+	handshake *pb.Handshake
+
+	// +checklocks:mu
+	maxMessageSize uint32
+
+	// +checklocks:mu
+	verdict func(Message) (bool, string)
 }
 
 // Message corresponds to a single message sent from checkers.Remote.
@@ -48,6 +62,8 @@ type Message struct {
 	MsgType pb.MessageType
 	// Msg is the payload to the message that can be decoded using MsgType.
 	Msg []byte
+	// Header is the header of the message.
+	Header wire.Header
 }
 
 // NewServer creates a new server that listens to a UDS that it creates under
@@ -57,13 +73,27 @@ func NewServer() (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
+	s, err := newServer(filepath.Join(dir, "remote.sock"))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewAbstractServer creates a new server that listens to a UDS in the abstract
+// namespace.
+func NewAbstractServer() (*Server, error) {
+	return newServer(fmt.Sprintf("\x00gvisor-remote-%d-%d", os.Getpid(), rand.Uint64()))
+}
+
+func newServer(path string) (*Server, error) {
 	s := &Server{
 		version: wire.CurrentVersion,
 		cond:    sync.Cond{L: &sync.Mutex{}},
 	}
-	s.CommonServer.Init(filepath.Join(dir, "remote.sock"), s)
+	s.CommonServer.Init(path, s)
 	if err := s.CommonServer.Start(); err != nil {
-		_ = os.RemoveAll(dir)
 		return nil, err
 	}
 	return s, nil
@@ -71,6 +101,11 @@ func NewServer() (*Server, error) {
 
 // NewClient returns a new MessageHandler to process messages.
 func (s *Server) NewClient() (server.MessageHandler, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.verdict != nil {
+		return &judgeHandler{msgHandler: msgHandler{owner: s}, verdict: s.verdict}, nil
+	}
 	return &msgHandler{owner: s}, nil
 }
 
@@ -117,15 +152,49 @@ func (s *Server) SetVersion(newVersion uint32) {
 	s.version = newVersion
 }
 
+// SetRequestedTypes sets the message types requested in handshake.
+func (s *Server) SetRequestedTypes(types []pb.MessageType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requestedTypes = types
+}
+
+// SetMaxMessageSize sets the maximum message size declared in handshake.
+func (s *Server) SetMaxMessageSize(size uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxMessageSize = size
+}
+
+// SetVerdict makes the server accept verdicts during handshake, and reply to
+// the messages that request one with the result of verdict.
+func (s *Server) SetVerdict(verdict func(Message) (allow bool, reason string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.verdict = verdict
+}
+
+// Handshake returns the last handshake received, or nil if none was received.
+func (s *Server) Handshake() *pb.Handshake {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handshake
+}
+
 type msgHandler struct {
 	owner *Server
 }
 
+var _ server.Negotiator = (*msgHandler)(nil)
+var _ server.MessageSizeLimiter = (*msgHandler)(nil)
+var _ server.EncodingAccepter = (*msgHandler)(nil)
+
 // Message stores the message type and payload.
 func (m *msgHandler) Message(_ []byte, hdr wire.Header, payload []byte) error {
 	msg := Message{
 		MsgType: pb.MessageType(hdr.MessageType),
 		Msg:     make([]byte, len(payload)),
+		Header:  hdr,
 	}
 	copy(msg.Msg, payload)
 
@@ -143,5 +212,43 @@ func (m *msgHandler) Version() uint32 {
 	return m.owner.version
 }
 
+// Negotiate implements server.Negotiator.
+func (m *msgHandler) Negotiate(hs *pb.Handshake) ([]pb.MessageType, error) {
+	m.owner.mu.Lock()
+	defer m.owner.mu.Unlock()
+	m.owner.handshake = hs
+	return m.owner.requestedTypes, nil
+}
+
+// MaxMessageSize implements server.MessageSizeLimiter.
+func (m *msgHandler) MaxMessageSize() uint32 {
+	m.owner.mu.Lock()
+	defer m.owner.mu.Unlock()
+	return m.owner.maxMessageSize
+}
+
+// AcceptsEncoding implements server.EncodingAccepter. Payloads are stored as
+// received, so all encodings are accepted.
+func (m *msgHandler) AcceptsEncoding(pb.Encoding) bool {
+	return true
+}
+
 // Close implements server.MessageHandler.
 func (m *msgHandler) Close() {}
+
+// judgeHandler is a msgHandler that replies with verdicts.
+type judgeHandler struct {
+	msgHandler
+	verdict func(Message) (bool, string)
+}
+
+var _ server.Judge = (*judgeHandler)(nil)
+
+// Verdict implements server.Judge.
+func (j *judgeHandler) Verdict(hdr wire.Header, payload []byte) (bool, string) {
+	return j.verdict(Message{
+		MsgType: pb.MessageType(hdr.MessageType),
+		Msg:     payload,
+		Header:  hdr,
+	})
+}
@@ -0,0 +1,258 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/hostarch"
+)
+
+// TLS for the tcp transport is set up in the host, and then offloaded to the
+// kernel (kTLS): the TLS handshake is done by setupTCP, and the resulting
+// transmit keys are installed in the socket. The sink keeps writing plaintext
+// messages to the socket, which the kernel encrypts. This keeps certificates
+// and private keys out of the sandbox, and doesn't require any TLS support in
+// the sentry. Only TLS 1.3 is supported.
+//
+// The constants below are from include/uapi/linux/tls.h.
+const (
+	tlsTX = 1
+
+	tls13Version = 0x0304
+
+	tlsCipherAESGCM128        = 51
+	tlsCipherAESGCM256        = 52
+	tlsCipherChaCha20Poly1305 = 54
+)
+
+// tlsTrafficSecretLabel is the key log label of the secret used to derive the
+// keys that protect application data sent by the client.
+const tlsTrafficSecretLabel = "CLIENT_TRAFFIC_SECRET_0"
+
+// tlsCipher describes how the kernel implements a TLS 1.3 cipher suite.
+type tlsCipher struct {
+	kernelCipher uint16
+	keySize      int
+	hash         func() hash.Hash
+}
+
+// tlsCiphers are the TLS 1.3 cipher suites that the kernel supports.
+var tlsCiphers = map[uint16]tlsCipher{
+	tls.TLS_AES_128_GCM_SHA256:       {kernelCipher: tlsCipherAESGCM128, keySize: 16, hash: sha256.New},
+	tls.TLS_AES_256_GCM_SHA384:       {kernelCipher: tlsCipherAESGCM256, keySize: 32, hash: sha512.New384},
+	tls.TLS_CHACHA20_POLY1305_SHA256: {kernelCipher: tlsCipherChaCha20Poly1305, keySize: 32, hash: sha256.New},
+}
+
+// parseTLSConfig returns the TLS configuration used to connect to addr, or nil
+// if TLS is not enabled. PEMs are credential references, see readCredential.
+// The following keys are used:
+//
+//	tls:             enables TLS, only allowed with the tcp transport.
+//	tls_ca:          PEM with the CAs used to verify the remote's
+//	                 certificate. System CAs are used if not set.
+//	tls_cert:        PEM with the client certificate, for mutual TLS.
+//	tls_key:         PEM with the private key of tls_cert.
+//	tls_server_name: name used to verify the remote's certificate. Defaults
+//	                 to the host in addr.
+func parseTLSConfig(config map[string]interface{}, addr string) (*tls.Config, error) {
+	opaque, ok := config["tls"]
+	if !ok {
+		return nil, nil
+	}
+	enabled, ok := opaque.(bool)
+	if !ok {
+		return nil, fmt.Errorf("tls %v is not a bool", opaque)
+	}
+	if !enabled {
+		return nil, nil
+	}
+	transport, err := parseTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	if transport != transportTCP {
+		return nil, fmt.Errorf("tls requires the %q transport", transportTCP)
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS13}
+	ca, err := parseCredential(config, "tls_ca")
+	if err != nil {
+		return nil, err
+	}
+	if ca != nil {
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in tls_ca %q", config["tls_ca"])
+		}
+	}
+	certRef, err := parseConfigString(config, "tls_cert")
+	if err != nil {
+		return nil, err
+	}
+	keyRef, err := parseConfigString(config, "tls_key")
+	if err != nil {
+		return nil, err
+	}
+	if (certRef == "") != (keyRef == "") {
+		return nil, fmt.Errorf("tls_cert and tls_key must be set together")
+	}
+	if certRef != "" {
+		cert, err := parseCredential(config, "tls_cert")
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseCredential(config, "tls_key")
+		if err != nil {
+			return nil, err
+		}
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("loading tls_cert and tls_key: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+	if cfg.ServerName, err = parseConfigString(config, "tls_server_name"); err != nil {
+		return nil, err
+	}
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ServerName = host
+	}
+	return cfg, nil
+}
+
+// parseConfigString returns the string configuration called name, or "" if
+// it's not set.
+func parseConfigString(config map[string]interface{}, name string) (string, error) {
+	opaque, ok := config[name]
+	if !ok {
+		return "", nil
+	}
+	s, ok := opaque.(string)
+	if !ok {
+		return "", fmt.Errorf("%s %v is not a string", name, opaque)
+	}
+	return s, nil
+}
+
+// startTLS performs the TLS handshake over conn, and installs the transmit keys
+// in f, which must refer to the same socket as conn. From then on, data written
+// to f is encrypted by the kernel. The returned connection can still be used
+// to read from the remote, but must not be written to or closed, since it would
+// send records that are not accounted for by the kernel.
+func startTLS(conn net.Conn, f *os.File, cfg *tls.Config) (*tls.Conn, error) {
+	var keyLog bytes.Buffer
+	cfg = cfg.Clone()
+	cfg.KeyLogWriter = &keyLog
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, fmt.Errorf("tls handshake: %w", err)
+	}
+	secret, err := clientTrafficSecret(keyLog.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	suite := tlsConn.ConnectionState().CipherSuite
+	cipher, ok := tlsCiphers[suite]
+	if !ok {
+		return nil, fmt.Errorf("tls cipher suite %s is not supported by the kernel", tls.CipherSuiteName(suite))
+	}
+	info := cipher.cryptoInfo(secret)
+
+	if err := unix.SetsockoptString(int(f.Fd()), unix.SOL_TCP, unix.TCP_ULP, "tls"); err != nil {
+		return nil, fmt.Errorf("enabling kernel TLS: %w", err)
+	}
+	if err := unix.SetsockoptString(int(f.Fd()), unix.SOL_TLS, tlsTX, string(info)); err != nil {
+		return nil, fmt.Errorf("setting kernel TLS keys: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// clientTrafficSecret returns the client application traffic secret from a key
+// log in NSS format.
+func clientTrafficSecret(keyLog []byte) ([]byte, error) {
+	s := bufio.NewScanner(bytes.NewReader(keyLog))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 3 && fields[0] == tlsTrafficSecretLabel {
+			return hex.DecodeString(fields[2])
+		}
+	}
+	return nil, fmt.Errorf("tls traffic secret not found")
+}
+
+// cryptoInfo returns the struct tls12_crypto_info_* that configures the kernel
+// to encrypt records with the keys derived from secret. It's only valid before
+// any application data has been sent, since the record sequence number is 0.
+func (c tlsCipher) cryptoInfo(secret []byte) []byte {
+	key := hkdfExpandLabel(c.hash, secret, "key", c.keySize)
+	iv := hkdfExpandLabel(c.hash, secret, "iv", 12)
+
+	info := make([]byte, 4)
+	hostarch.ByteOrder.PutUint16(info[0:], tls13Version)
+	hostarch.ByteOrder.PutUint16(info[2:], c.kernelCipher)
+	if c.kernelCipher == tlsCipherChaCha20Poly1305 {
+		// The whole IV is used as is.
+		info = append(info, iv...)
+		info = append(info, key...)
+	} else {
+		// The first 4 bytes of the IV are called salt, and are passed after the
+		// key.
+		info = append(info, iv[4:]...)
+		info = append(info, key...)
+		info = append(info, iv[:4]...)
+	}
+	var recSeq [8]byte
+	return append(info, recSeq[:]...)
+}
+
+// hkdfExpandLabel implements HKDF-Expand-Label from RFC 8446, section 7.1,
+// with an empty context.
+func hkdfExpandLabel(h func() hash.Hash, secret []byte, label string, length int) []byte {
+	const prefix = "tls13 "
+	info := []byte{byte(length >> 8), byte(length), byte(len(prefix) + len(label))}
+	info = append(info, prefix...)
+	info = append(info, label...)
+	info = append(info, 0 /* context length */)
+
+	// HKDF-Expand from RFC 5869, section 2.3.
+	var out, t []byte
+	for i := byte(1); len(out) < length; i++ {
+		mac := hmac.New(h, secret)
+		mac.Write(t)
+		mac.Write(info)
+		mac.Write([]byte{i})
+		t = mac.Sum(nil)
+		out = append(out, t...)
+	}
+	return out[:length]
+}
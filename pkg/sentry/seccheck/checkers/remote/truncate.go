@@ -0,0 +1,270 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// maxTruncateAttempts bounds how many times a point is truncated further when
+// compression makes it larger than expected.
+const maxTruncateAttempts = 4
+
+// maxPooledPayloadSize is the capacity above which payload buffers are not
+// kept for reuse, so that a few large points don't pin memory.
+const maxPooledPayloadSize = 64 << 10
+
+// payloadBuffers holds the buffers that points are serialized into when the
+// payload is written before write returns.
+var payloadBuffers = sync.Pool{
+	New: func() interface{} {
+		return &payloadBuffer{}
+	},
+}
+
+// payloadBuffer is a reusable buffer for payloads. Its capacity grows to the
+// largest payload serialized into it, which gives the following points a size
+// hint without computing it first.
+type payloadBuffer struct {
+	buf []byte
+}
+
+// getPayloadBuffer returns a buffer from payloadBuffers.
+func getPayloadBuffer() *payloadBuffer {
+	return payloadBuffers.Get().(*payloadBuffer)
+}
+
+// bytes returns the buffer to serialize into. It's nil for a nil
+// payloadBuffer, which allocates a new payload.
+func (b *payloadBuffer) bytes() []byte {
+	if b == nil {
+		return nil
+	}
+	return b.buf[:0]
+}
+
+// release returns b to payloadBuffers, keeping out as its buffer if out
+// outgrew it. out must not be used afterwards.
+func (b *payloadBuffer) release(out []byte) {
+	if b == nil {
+		return
+	}
+	if cap(out) > cap(b.buf) && cap(out) <= maxPooledPayloadSize {
+		b.buf = out[:0]
+	}
+	payloadBuffers.Put(b)
+}
+
+// parseMaxMessageSize returns the "max_message_size" configuration, or 0 if
+// it's not set. It's set by handshake when the remote process declares a
+// maximum message size.
+func parseMaxMessageSize(config map[string]interface{}) (int, error) {
+	opaque, ok := config["max_message_size"]
+	if !ok {
+		return 0, nil
+	}
+	size, ok := opaque.(float64)
+	if !ok || size != float64(int(size)) || size < wire.MinMaxMessageSize {
+		return 0, fmt.Errorf("max_message_size %v must be an int not smaller than %d", opaque, wire.MinMaxMessageSize)
+	}
+	return int(size), nil
+}
+
+// marshal serializes msg with r.encoding and compresses it with compression.
+// If r.maxMessageSize is set and the message doesn't fit, msg is truncated
+// until it does, and wire.FlagTruncated is returned. msg itself is not
+// modified. msg is serialized into buf, which is reused for every attempt, so
+// the result may alias buf.
+func (r *remote) marshal(buf []byte, msg proto.Message, compression pb.Compression) ([]byte, uint32, error) {
+	out, err := compress(buf, msg, r.encoding, compression)
+	if err != nil || r.maxMessageSize == 0 || wire.HeaderStructSize+len(out) <= r.maxMessageSize {
+		return out, 0, err
+	}
+	// Truncation applies to the serialized protobuf, so the target is lowered
+	// in proportion if the payload is larger, e.g. because compression doesn't
+	// shrink it enough, or because of the encoding.
+	limit := r.maxMessageSize - wire.HeaderStructSize
+	target := limit
+	for i := 0; i < maxTruncateAttempts && target > 0; i++ {
+		truncated, err := truncate(msg, target)
+		if err != nil {
+			return nil, 0, err
+		}
+		if out, err = compress(buf, truncated, r.encoding, compression); err != nil {
+			return nil, 0, err
+		}
+		if len(out) <= limit {
+			return out, wire.FlagTruncated, nil
+		}
+		target = target * limit / len(out)
+	}
+	return nil, 0, fmt.Errorf("point doesn't fit in %d bytes", r.maxMessageSize)
+}
+
+// compress serializes msg with encoding into buf and compresses it with
+// compression.
+func compress(buf []byte, msg proto.Message, encoding pb.Encoding, compression pb.Compression) ([]byte, error) {
+	out, err := wire.MarshalAppend(buf, encoding, msg)
+	if err != nil {
+		return nil, fmt.Errorf("Marshal(%+v): %w", msg, err)
+	}
+	if compression != pb.Compression_COMPRESSION_NONE {
+		if out, err = wire.Compress(compression, out); err != nil {
+			return nil, fmt.Errorf("Compress(%v): %w", compression, err)
+		}
+	}
+	return out, nil
+}
+
+// truncate returns a copy of msg that is serialized in at most size bytes.
+// Truncation is deterministic: string and bytes fields, including the ones in
+// repeated and nested fields, are shortened to a common length, so that the
+// longest ones are cut first. Strings are cut at a UTF-8 boundary. If that's
+// not enough, e.g. with large argv made of short arguments, the longest
+// repeated fields are halved until msg fits.
+func truncate(msg proto.Message, size int) (proto.Message, error) {
+	cpy := proto.Clone(msg)
+	excess := proto.Size(cpy) - size
+	if excess <= 0 {
+		return cpy, nil
+	}
+
+	var tr truncator
+	tr.collect(cpy.ProtoReflect())
+	// Find the largest length that removes enough bytes. The encoded size may
+	// shrink even more, since length prefixes and empty fields shrink too.
+	lo, hi := 0, 0
+	for _, v := range tr.values {
+		if v.length > hi {
+			hi = v.length
+		}
+	}
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		if tr.excess(mid) >= excess {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	for _, v := range tr.values {
+		v.cut(lo)
+	}
+
+	for proto.Size(cpy) > size {
+		var longest protoreflect.List
+		for _, l := range tr.lists {
+			if l.Len() > 0 && (longest == nil || l.Len() > longest.Len()) {
+				longest = l
+			}
+		}
+		if longest == nil {
+			return nil, fmt.Errorf("point doesn't fit in %d bytes after truncation", size)
+		}
+		longest.Truncate(longest.Len() / 2)
+	}
+	return cpy, nil
+}
+
+// truncator holds the fields of a message that can be truncated, in the order
+// they are found in the message.
+type truncator struct {
+	values []truncatable
+	lists  []protoreflect.List
+}
+
+// truncatable is a string or bytes value. It's either a field of msg, or the
+// element at index in list.
+type truncatable struct {
+	msg    protoreflect.Message
+	field  protoreflect.FieldDescriptor
+	list   protoreflect.List
+	index  int
+	length int
+}
+
+func (t *truncator) collect(msg protoreflect.Message) {
+	msg.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch {
+		case field.IsMap():
+			// Maps are not used in points.
+		case field.IsList():
+			list := value.List()
+			t.lists = append(t.lists, list)
+			for i := 0; i < list.Len(); i++ {
+				t.collectValue(msg, field, list, i, list.Get(i))
+			}
+		default:
+			t.collectValue(msg, field, nil, 0, value)
+		}
+		return true
+	})
+}
+
+func (t *truncator) collectValue(msg protoreflect.Message, field protoreflect.FieldDescriptor, list protoreflect.List, index int, value protoreflect.Value) {
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		t.values = append(t.values, truncatable{msg: msg, field: field, list: list, index: index, length: len(value.String())})
+	case protoreflect.BytesKind:
+		t.values = append(t.values, truncatable{msg: msg, field: field, list: list, index: index, length: len(value.Bytes())})
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		t.collect(value.Message())
+	}
+}
+
+// excess returns how many bytes are removed by cutting all values to length.
+func (t *truncator) excess(length int) int {
+	excess := 0
+	for _, v := range t.values {
+		if v.length > length {
+			excess += v.length - length
+		}
+	}
+	return excess
+}
+
+// cut shortens the value to at most length bytes.
+func (v *truncatable) cut(length int) {
+	if v.length <= length {
+		return
+	}
+	var value protoreflect.Value
+	if v.list != nil {
+		value = v.list.Get(v.index)
+	} else {
+		value = v.msg.Get(v.field)
+	}
+	if v.field.Kind() == protoreflect.StringKind {
+		s := value.String()
+		for length > 0 && !utf8.RuneStart(s[length]) {
+			length--
+		}
+		value = protoreflect.ValueOfString(s[:length])
+	} else {
+		value = protoreflect.ValueOfBytes(value.Bytes()[:length])
+	}
+	if v.list != nil {
+		v.list.Set(v.index, value)
+	} else {
+		v.msg.Set(v.field, value)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"testing"
+)
+
+func TestPayloadBuffer(t *testing.T) {
+	var nilBuf *payloadBuffer
+	if got := nilBuf.bytes(); got != nil {
+		t.Errorf("bytes() of nil buffer: got: %v, want: nil", got)
+	}
+	nilBuf.release(make([]byte, 10))
+
+	b := &payloadBuffer{}
+	b.release(make([]byte, 10, 100))
+	if got := cap(b.bytes()); got != 100 {
+		t.Errorf("cap(bytes()) after growing: got: %d, want: 100", got)
+	}
+	b.release(make([]byte, 10))
+	if got := cap(b.bytes()); got != 100 {
+		t.Errorf("cap(bytes()) after smaller payload: got: %d, want: 100", got)
+	}
+	b.release(make([]byte, maxPooledPayloadSize+1))
+	if got := cap(b.bytes()); got != 100 {
+		t.Errorf("cap(bytes()) after oversized payload: got: %d, want: 100", got)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/test"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestVerdicts(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+	// release unblocks the server once the test is done with timeouts.
+	release := make(chan struct{})
+	defer close(release)
+	server.SetVerdict(func(msg test.Message) (bool, string) {
+		info := &pb.ExecveInfo{}
+		if err := proto.Unmarshal(msg.Msg, info); err != nil {
+			return false, err.Error()
+		}
+		switch info.BinaryPath {
+		case "/bin/evil":
+			return false, "evil"
+		case "/bin/slow":
+			<-release
+		}
+		return true, ""
+	})
+
+	for _, tc := range []struct {
+		name    string
+		policy  string
+		path    string
+		want    error
+		verdict string
+	}{
+		{
+			name:    "allow",
+			path:    "/bin/true",
+			verdict: "allow",
+		},
+		{
+			name:    "deny",
+			path:    "/bin/evil",
+			want:    seccheck.ErrDenied,
+			verdict: "deny",
+		},
+		{
+			name:    "timeout-fail-open",
+			policy:  seccheck.ErrorPolicyFailOpen,
+			path:    "/bin/slow",
+			want:    errVerdictTimeout,
+			verdict: "none",
+		},
+		{
+			name:    "timeout-fail-closed",
+			policy:  seccheck.ErrorPolicyFailClosed,
+			path:    "/bin/slow",
+			want:    seccheck.ErrDenied,
+			verdict: "none",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := map[string]interface{}{
+				"verdict_types":   []interface{}{"MESSAGE_SENTRY_EXEC"},
+				"verdict_timeout": "100ms",
+			}
+			if tc.policy != "" {
+				config["verdict_error_policy"] = tc.policy
+			}
+			endpoint, err := setup(server.Endpoint, config)
+			if err != nil {
+				t.Fatalf("setup(): %v", err)
+			}
+			if !server.Handshake().Verdicts {
+				t.Errorf("verdicts not requested in handshake")
+			}
+			endpointFD, err := fd.NewFromFile(endpoint)
+			if err != nil {
+				_ = endpoint.Close()
+				t.Fatalf("NewFromFile(): %v", err)
+			}
+			_ = endpoint.Close()
+
+			c, err := new(config, endpointFD)
+			if err != nil {
+				t.Fatalf("New(): %v", err)
+			}
+			r := c.(*remote)
+			defer r.Stop()
+
+			// Points of other types don't wait for a verdict.
+			if err := r.ExitNotifyParent(nil, seccheck.FieldSet{}, &pb.ExitNotifyParentInfo{}); err != nil {
+				t.Fatalf("ExitNotifyParent: %v", err)
+			}
+			if err := r.Execve(nil, seccheck.FieldSet{}, &pb.ExecveInfo{BinaryPath: tc.path}); err != tc.want {
+				t.Errorf("Execve(%q): got: %v, want: %v", tc.path, err, tc.want)
+			}
+			var count uint64
+			for _, s := range r.Status().Metrics {
+				if s.Name == "runsc_trace_verdicts_total" && s.Labels["verdict"] == tc.verdict {
+					count = s.Value
+				}
+			}
+			if count != 1 {
+				t.Errorf("runsc_trace_verdicts_total{verdict=%q}: got: %d, want: 1", tc.verdict, count)
+			}
+		})
+	}
+
+	pts := server.GetPoints()
+	for _, pt := range pts {
+		requested := pt.Header.Flags&wire.FlagVerdictRequested != 0
+		if want := pt.MsgType == pb.MessageType_MESSAGE_SENTRY_EXEC; requested != want {
+			t.Errorf("%v point: verdict requested: %t, want: %t", pt.MsgType, requested, want)
+		}
+	}
+}
+
+func TestVerdictsUnsupported(t *testing.T) {
+	server, err := test.NewServer()
+	if err != nil {
+		t.Fatalf("newServer(): %v", err)
+	}
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"verdict_types": []interface{}{"MESSAGE_SENTRY_EXEC"},
+	}
+	if _, err := setup(server.Endpoint, config); err == nil || !strings.Contains(err.Error(), "doesn't support verdicts") {
+		t.Errorf("setup(): got: %v, want: remote doesn't support verdicts", err)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import "golang.org/x/sys/unix"
+
+// IsAbstract returns true if path is a unix domain socket address in the
+// abstract namespace, i.e. it starts with a NUL byte or, as it's commonly
+// written, with "@". Abstract sockets don't need a path in the filesystem, so
+// they don't need to be visible to the sandbox or the host mounts.
+func IsAbstract(path string) bool {
+	return len(path) > 0 && (path[0] == 0 || path[0] == '@')
+}
+
+// UnixPath returns path in the form expected by the Go socket APIs, e.g.
+// net.Dial, which use "@" for the leading NUL byte of abstract addresses.
+// Other paths are returned unchanged.
+func UnixPath(path string) string {
+	if len(path) > 0 && path[0] == 0 {
+		return "@" + path[1:]
+	}
+	return path
+}
+
+// UnixAddr returns the address of the unix domain socket at path, which may be
+// in the abstract namespace, see IsAbstract.
+func UnixAddr(path string) *unix.SockaddrUnix {
+	// A NUL byte would otherwise be copied as is, and the address would be
+	// followed by a NUL terminator that isn't part of an abstract name.
+	return &unix.SockaddrUnix{Name: UnixPath(path)}
+}
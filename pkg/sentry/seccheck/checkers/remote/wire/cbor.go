@@ -0,0 +1,170 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// CBOR major types, see RFC 8949.
+const (
+	cborUnsigned = 0 << 5
+	cborNegative = 1 << 5
+	cborBytes    = 2 << 5
+	cborText     = 3 << 5
+	cborArray    = 4 << 5
+	cborMap      = 5 << 5
+	cborFalse    = 7<<5 | 20
+	cborTrue     = 7<<5 | 21
+	cborFloat64  = 7<<5 | 27
+)
+
+// Marshal serializes msg with the given encoding.
+func Marshal(encoding pb.Encoding, msg proto.Message) ([]byte, error) {
+	return MarshalAppend(nil, encoding, msg)
+}
+
+// MarshalAppend serializes msg with the given encoding, appending it to buf.
+// buf is only grown once for protobuf, which computes the size first.
+func MarshalAppend(buf []byte, encoding pb.Encoding, msg proto.Message) ([]byte, error) {
+	switch encoding {
+	case pb.Encoding_ENCODING_PROTO:
+		return proto.MarshalOptions{}.MarshalAppend(buf, msg)
+	case pb.Encoding_ENCODING_CBOR:
+		return appendCBORMessage(buf, msg.ProtoReflect()), nil
+	default:
+		return nil, fmt.Errorf("invalid encoding %v", encoding)
+	}
+}
+
+// MarshalCBOR serializes msg in CBOR, see RFC 8949. Messages are encoded as
+// maps keyed by the field names from the .proto files, with only the fields
+// that are set, in field number order. Enums are encoded as the name of their
+// value, or as their number if it's unknown. Repeated fields are encoded as
+// arrays, map fields as maps, and floating point numbers as double precision.
+func MarshalCBOR(msg proto.Message) []byte {
+	return appendCBORMessage(nil, msg.ProtoReflect())
+}
+
+func appendCBORMessage(buf []byte, msg protoreflect.Message) []byte {
+	fields := msg.Descriptor().Fields()
+	var set []protoreflect.FieldDescriptor
+	for i := 0; i < fields.Len(); i++ {
+		if field := fields.Get(i); msg.Has(field) {
+			set = append(set, field)
+		}
+	}
+	// Fields are listed in declaration order, which may differ from the
+	// number order.
+	sort.Slice(set, func(i, j int) bool { return set[i].Number() < set[j].Number() })
+	buf = appendCBORHead(buf, cborMap, uint64(len(set)))
+	for _, field := range set {
+		buf = appendCBORText(buf, string(field.Name()))
+		value := msg.Get(field)
+		switch {
+		case field.IsList():
+			list := value.List()
+			buf = appendCBORHead(buf, cborArray, uint64(list.Len()))
+			for i := 0; i < list.Len(); i++ {
+				buf = appendCBORValue(buf, field, list.Get(i))
+			}
+		case field.IsMap():
+			m := value.Map()
+			buf = appendCBORHead(buf, cborMap, uint64(m.Len()))
+			m.Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+				buf = appendCBORValue(buf, field.MapKey(), key.Value())
+				buf = appendCBORValue(buf, field.MapValue(), value)
+				return true
+			})
+		default:
+			buf = appendCBORValue(buf, field, value)
+		}
+	}
+	return buf
+}
+
+func appendCBORValue(buf []byte, field protoreflect.FieldDescriptor, value protoreflect.Value) []byte {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		if value.Bool() {
+			return append(buf, cborTrue)
+		}
+		return append(buf, cborFalse)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return appendCBORInt(buf, value.Int())
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return appendCBORHead(buf, cborUnsigned, value.Uint())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return appendBigEndian(append(buf, cborFloat64), math.Float64bits(value.Float()), 8)
+	case protoreflect.StringKind:
+		return appendCBORText(buf, value.String())
+	case protoreflect.BytesKind:
+		buf = appendCBORHead(buf, cborBytes, uint64(len(value.Bytes())))
+		return append(buf, value.Bytes()...)
+	case protoreflect.EnumKind:
+		if v := field.Enum().Values().ByNumber(value.Enum()); v != nil {
+			return appendCBORText(buf, string(v.Name()))
+		}
+		return appendCBORInt(buf, int64(value.Enum()))
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return appendCBORMessage(buf, value.Message())
+	default:
+		panic(fmt.Sprintf("unknown field kind %v", field.Kind()))
+	}
+}
+
+func appendCBORInt(buf []byte, v int64) []byte {
+	if v < 0 {
+		return appendCBORHead(buf, cborNegative, uint64(-(v + 1)))
+	}
+	return appendCBORHead(buf, cborUnsigned, uint64(v))
+}
+
+func appendCBORText(buf []byte, s string) []byte {
+	buf = appendCBORHead(buf, cborText, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// appendCBORHead appends the initial bytes of a data item of the given major
+// type, with argument n, e.g. the length of a string.
+func appendCBORHead(buf []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(buf, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return appendBigEndian(append(buf, major|25), n, 2)
+	case n <= math.MaxUint32:
+		return appendBigEndian(append(buf, major|26), n, 4)
+	default:
+		return appendBigEndian(append(buf, major|27), n, 8)
+	}
+}
+
+// appendBigEndian appends the size lowest bytes of n, in big endian order.
+func appendBigEndian(buf []byte, n uint64, size int) []byte {
+	for i := size - 1; i >= 0; i-- {
+		buf = append(buf, byte(n>>(8*i)))
+	}
+	return buf
+}
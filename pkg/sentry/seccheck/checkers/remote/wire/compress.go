@@ -0,0 +1,84 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// gzipWriters caches gzip writers, which are expensive to create, across
+// points.
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+		return w
+	},
+}
+
+// Compress returns payload compressed with the given algorithm.
+func Compress(compression pb.Compression, payload []byte) ([]byte, error) {
+	switch compression {
+	case pb.Compression_COMPRESSION_NONE:
+		return payload, nil
+	case pb.Compression_COMPRESSION_GZIP:
+		var buf bytes.Buffer
+		w := gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(w)
+		w.Reset(&buf)
+		if _, err := w.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %v", compression)
+	}
+}
+
+// MaxDecompressedSize is the largest payload that Decompress returns, so that
+// a small compressed message can't make the remote allocate unbounded memory.
+const MaxDecompressedSize = 64 << 20
+
+// Decompress returns payload decompressed with the given algorithm.
+func Decompress(compression pb.Compression, payload []byte) ([]byte, error) {
+	switch compression {
+	case pb.Compression_COMPRESSION_NONE:
+		return payload, nil
+	case pb.Compression_COMPRESSION_GZIP:
+		r, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		out, err := io.ReadAll(io.LimitReader(r, MaxDecompressedSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > MaxDecompressedSize {
+			return nil, fmt.Errorf("decompressed payload larger than %d bytes", MaxDecompressedSize)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %v", compression)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// FrameReader reads messages framed with their length, see FrameLengthSize,
+// e.g. from a TCP relay or a file written by the file sink. Frames that are
+// truncated or corrupted are skipped: when a frame is invalid, FrameReader
+// scans forward one byte at a time until it finds a valid frame. Frames are
+// validated with their checksum, so only streams written with checksums can
+// be resynchronized reliably. While resynchronizing, frames without checksum
+// are skipped as well.
+type FrameReader struct {
+	r       *bufio.Reader
+	maxSize int
+
+	// resync is set after an invalid frame was found, until the next valid
+	// frame.
+	resync bool
+
+	// Skipped is the number of bytes skipped so far because they were not
+	// part of a valid frame.
+	Skipped uint64
+}
+
+// NewFrameReader returns a FrameReader that reads from r. Frames larger than
+// maxSize, not including the length prefix, are considered invalid.
+func NewFrameReader(r io.Reader, maxSize int) *FrameReader {
+	return &FrameReader{
+		r:       bufio.NewReaderSize(r, FrameLengthSize+maxSize),
+		maxSize: maxSize,
+	}
+}
+
+// Next returns the next valid message, split into its header and payload.
+// Headers shorter than HeaderStructSize are extended with zeroed fields.
+// Payload is only valid until the next call. It returns io.EOF at the end of
+// the stream, or io.ErrUnexpectedEOF if the stream ends in a truncated frame.
+// Messages written with an unsupported version are consumed and returned as
+// an error, so that the caller can decide whether to keep reading.
+func (f *FrameReader) Next() (Header, []byte, error) {
+	for {
+		prefix, err := f.r.Peek(FrameLengthSize)
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(prefix) > 0 {
+				f.Skipped += uint64(len(prefix))
+				return Header{}, nil, io.ErrUnexpectedEOF
+			}
+			return Header{}, nil, err
+		}
+		length := int(binary.LittleEndian.Uint32(prefix))
+		if length < HeaderMinSize || length > f.maxSize {
+			f.skip()
+			continue
+		}
+		frame, err := f.r.Peek(FrameLengthSize + length)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return Header{}, nil, err
+			}
+			// The length may be corrupted rather than the frame truncated, so
+			// keep looking for frames in what's left.
+			f.skip()
+			continue
+		}
+		hdr, payload, err := ParseHeader(frame[FrameLengthSize:])
+		if err != nil || !hdr.VerifyChecksum(payload) || (f.resync && hdr.Flags&FlagChecksum == 0) {
+			f.skip()
+			continue
+		}
+		f.resync = false
+		// Discarding buffered bytes doesn't invalidate them.
+		_, _ = f.r.Discard(len(frame))
+		// Streams don't have a handshake, so any version that is supported is
+		// accepted. Other versions are reported rather than skipped, since the
+		// frame is valid but can't be interpreted.
+		if v := hdr.ProtocolVersion(); v < MinSupportedVersion || v > CurrentVersion {
+			return Header{}, nil, fmt.Errorf("unsupported message version %d, sequence: %d", v, hdr.Sequence)
+		}
+		return hdr, payload, nil
+	}
+}
+
+// skip discards the first byte of the invalid frame that is buffered.
+func (f *FrameReader) skip() {
+	f.resync = true
+	f.Skipped++
+	_, _ = f.r.Discard(1)
+}
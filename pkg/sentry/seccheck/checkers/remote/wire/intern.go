@@ -0,0 +1,182 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"fmt"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// FlagInterned is set in Header.Flags when the point has references to
+// interned strings, which must be resolved with a Dictionary. It's only set if
+// the remote accepted interning during handshake.
+const FlagInterned = 1 << 3
+
+// internRefPrefix starts references to interned strings. Interned fields never
+// start with a NUL otherwise.
+const internRefPrefix = "\x00"
+
+// internedFields are the names of the string fields that are interned, in
+// points and in their ContextData. They are repeated across points, e.g. the
+// path of a file that is read over and over.
+var internedFields = map[protoreflect.Name]struct{}{
+	"binary_path":     {},
+	"container_id":    {},
+	"cwd":             {},
+	"executable_path": {},
+	"fd_path":         {},
+	"new_fd_path":     {},
+	"new_pathname":    {},
+	"old_fd_path":     {},
+	"old_pathname":    {},
+	"path":            {},
+	"pathname":        {},
+	"process_name":    {},
+}
+
+// InternRef returns the reference to the string interned with id.
+func InternRef(id uint32) string {
+	return internRefPrefix + strconv.FormatUint(uint64(id), 10)
+}
+
+// parseInternRef returns the id that s refers to, if s is a reference.
+func parseInternRef(s string) (uint32, bool) {
+	if len(s) <= len(internRefPrefix) || s[:len(internRefPrefix)] != internRefPrefix {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(s[len(internRefPrefix):], 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(id), true
+}
+
+// RangeInterned calls fn for each string field of msg that is interned, and
+// that is set. Only the fields of msg itself and of its ContextData are
+// interned.
+func RangeInterned(msg proto.Message, fn func(m protoreflect.Message, fd protoreflect.FieldDescriptor, value string)) {
+	rangeInterned(msg.ProtoReflect(), fn)
+	if cd := ContextDataOf(msg); cd != nil {
+		rangeInterned(cd.ProtoReflect(), fn)
+	}
+}
+
+func rangeInterned(m protoreflect.Message, fn func(m protoreflect.Message, fd protoreflect.FieldDescriptor, value string)) {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Kind() != protoreflect.StringKind || fd.Cardinality() == protoreflect.Repeated {
+			continue
+		}
+		if _, ok := internedFields[fd.Name()]; !ok || !m.Has(fd) {
+			continue
+		}
+		fn(m, fd, m.Get(fd).String())
+	}
+}
+
+var contextDataName = (&pb.ContextData{}).ProtoReflect().Descriptor().FullName()
+
+// ContextDataField returns the descriptor of the ContextData field of m, or
+// nil if it doesn't have one.
+func ContextDataField(m protoreflect.Message) protoreflect.FieldDescriptor {
+	fd := m.Descriptor().Fields().ByName("context_data")
+	if fd == nil || fd.Message() == nil || fd.Message().FullName() != contextDataName {
+		return nil
+	}
+	return fd
+}
+
+// ContextDataOf returns the ContextData of msg, or nil if it's not set.
+func ContextDataOf(msg proto.Message) *pb.ContextData {
+	m := msg.ProtoReflect()
+	fd := ContextDataField(m)
+	if fd == nil || !m.Has(fd) {
+		return nil
+	}
+	return m.Get(fd).Message().Interface().(*pb.ContextData)
+}
+
+// WorkloadOf returns the workload that msg comes from, or nil if it's not
+// set. Points about containers or the sandbox carry it in their own workload
+// field, and other points in their ContextData.
+func WorkloadOf(msg proto.Message) *pb.Workload {
+	if m, ok := msg.(interface{ GetWorkload() *pb.Workload }); ok {
+		return m.GetWorkload()
+	}
+	return ContextDataOf(msg).GetWorkload()
+}
+
+// TimeOf returns the time when msg was captured, from CLOCK_REALTIME and
+// CLOCK_MONOTONIC, or zeros if it's not set. Like the workload, points about
+// containers or the sandbox carry it in their own fields.
+func TimeOf(msg proto.Message) (realtimeNs, monotonicNs int64) {
+	if m, ok := msg.(interface {
+		GetTimeNs() int64
+		GetMonotonicTimeNs() int64
+	}); ok {
+		return m.GetTimeNs(), m.GetMonotonicTimeNs()
+	}
+	ctx := ContextDataOf(msg)
+	return ctx.GetTimeNs(), ctx.GetMonotonicTimeNs()
+}
+
+// Dictionary holds the strings interned on a connection, to resolve the
+// references in the points received from it. See pb.Handshake.interning.
+type Dictionary struct {
+	strings map[uint32]string
+}
+
+// Resolve adds the strings defined by msg to d, and replaces the references
+// in msg with the strings they refer to. Definitions are removed from msg. It
+// returns an error if a reference is to a string that wasn't defined.
+func (d *Dictionary) Resolve(msg proto.Message) error {
+	cd := ContextDataOf(msg)
+	if cd != nil && len(cd.InternedStrings) > 0 {
+		if d.strings == nil {
+			d.strings = make(map[uint32]string)
+		}
+		for _, s := range cd.InternedStrings {
+			d.strings[s.Id] = s.Value
+		}
+		cd.InternedStrings = nil
+		// The sentry sets ContextData just to carry definitions in points
+		// that don't have it otherwise.
+		if proto.Size(cd) == 0 {
+			m := msg.ProtoReflect()
+			m.Clear(ContextDataField(m))
+		}
+	}
+	var err error
+	RangeInterned(msg, func(m protoreflect.Message, fd protoreflect.FieldDescriptor, value string) {
+		id, ok := parseInternRef(value)
+		if !ok {
+			return
+		}
+		s, ok := d.strings[id]
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("reference to undefined string %d in %s", id, fd.FullName())
+			}
+			return
+		}
+		m.Set(fd, protoreflect.ValueOfString(s))
+	})
+	return err
+}
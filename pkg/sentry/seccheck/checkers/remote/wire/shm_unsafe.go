@@ -0,0 +1,136 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+)
+
+// ShmHeaderSize is the size of the control page at the start of a shared
+// memory ring. The data area follows it, and its size must be a power of 2.
+//
+// The control page contains, at the following offsets:
+//
+//	ShmWriteOffset: total number of bytes written to the ring, uint64.
+//	ShmReadOffset:  total number of bytes read from the ring, uint64.
+//	ShmDoorbell:    futex word set to 1 by the reader before it waits for
+//	                data, uint32. The writer resets it and wakes the reader.
+//	ShmClosed:      set to 1 by the writer when it stops, uint32.
+//
+// Offsets into the data area are the total number of bytes modulo the size of
+// the data area, i.e. messages wrap around. Each message is preceded by its
+// length, like on stream transports, see FrameLengthSize. Offsets are in
+// separate cache lines to avoid false sharing between the writer and reader.
+const ShmHeaderSize = 4096
+
+// Offsets in the control page of a shared memory ring. See ShmHeaderSize.
+const (
+	ShmWriteOffset = 0
+	ShmReadOffset  = 64
+	ShmDoorbell    = 128
+	ShmClosed      = 192
+)
+
+// ShmRing is a shared memory ring mapped in the current process. The memory
+// is shared with a potentially untrusted peer, so offsets read from it must be
+// validated.
+type ShmRing struct {
+	mem  []byte
+	data []byte
+}
+
+// NewShmRing returns the ring in mem, which must be a shared mapping of the
+// ring's memory file.
+func NewShmRing(mem []byte) (*ShmRing, error) {
+	if len(mem) <= ShmHeaderSize {
+		return nil, fmt.Errorf("shared memory ring too small: %d bytes", len(mem))
+	}
+	data := mem[ShmHeaderSize:]
+	if len(data)&(len(data)-1) != 0 {
+		return nil, fmt.Errorf("shared memory ring size %d is not a power of 2", len(data))
+	}
+	return &ShmRing{mem: mem, data: data}, nil
+}
+
+// Size returns the size of the data area of the ring.
+func (r *ShmRing) Size() uint64 {
+	return uint64(len(r.data))
+}
+
+// WriteOffset returns the total number of bytes written to the ring.
+func (r *ShmRing) WriteOffset() *atomicbitops.Uint64 {
+	return (*atomicbitops.Uint64)(unsafe.Pointer(&r.mem[ShmWriteOffset]))
+}
+
+// ReadOffset returns the total number of bytes read from the ring.
+func (r *ShmRing) ReadOffset() *atomicbitops.Uint64 {
+	return (*atomicbitops.Uint64)(unsafe.Pointer(&r.mem[ShmReadOffset]))
+}
+
+// Doorbell returns the futex word used by the reader to wait for data.
+func (r *ShmRing) Doorbell() *atomicbitops.Uint32 {
+	return (*atomicbitops.Uint32)(unsafe.Pointer(&r.mem[ShmDoorbell]))
+}
+
+// Closed returns the word set when the writer stops.
+func (r *ShmRing) Closed() *atomicbitops.Uint32 {
+	return (*atomicbitops.Uint32)(unsafe.Pointer(&r.mem[ShmClosed]))
+}
+
+// CopyIn copies b into the data area at offset off, wrapping around as needed.
+func (r *ShmRing) CopyIn(off uint64, b []byte) {
+	start := off & (r.Size() - 1)
+	n := copy(r.data[start:], b)
+	copy(r.data, b[n:])
+}
+
+// CopyOut copies from the data area at offset off into b, wrapping around as
+// needed.
+func (r *ShmRing) CopyOut(b []byte, off uint64) {
+	start := off & (r.Size() - 1)
+	n := copy(b, r.data[start:])
+	copy(b[n:], r.data)
+}
+
+// Wake wakes up the reader if it's waiting for data.
+func (r *ShmRing) Wake() error {
+	if r.Doorbell().Load() == 0 {
+		return nil
+	}
+	r.Doorbell().Store(0)
+	// The futex is shared with another process, so it can't be private.
+	if _, _, e := unix.RawSyscall(unix.SYS_FUTEX, uintptr(unsafe.Pointer(&r.mem[ShmDoorbell])), linux.FUTEX_WAKE, 1); e != 0 {
+		return e
+	}
+	return nil
+}
+
+// Wait waits for up to timeout to be woken up by the writer. The caller must
+// have set the doorbell to 1, and checked that there is no data to read
+// afterwards.
+func (r *ShmRing) Wait(timeout time.Duration) error {
+	ts := unix.NsecToTimespec(timeout.Nanoseconds())
+	_, _, e := unix.Syscall6(unix.SYS_FUTEX, uintptr(unsafe.Pointer(&r.mem[ShmDoorbell])), linux.FUTEX_WAIT, 1, uintptr(unsafe.Pointer(&ts)), 0, 0)
+	if e != 0 && e != unix.EAGAIN && e != unix.EINTR && e != unix.ETIMEDOUT {
+		return e
+	}
+	return nil
+}
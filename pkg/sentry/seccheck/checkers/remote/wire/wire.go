@@ -15,17 +15,42 @@
 // Package wire defines structs used in the wire format for the remote checker.
 package wire
 
-// CurrentVersion is the current wire and protocol version.
-const CurrentVersion = 1
+import (
+	"fmt"
+	"hash/crc32"
+)
+
+// CurrentVersion is the current wire and protocol version. Both ends of a
+// connection send the version they support during handshake, and messages are
+// then written with the lowest of the two, see Header.Version. Versions are:
+//
+//	1: the original protocol. Headers don't carry a version.
+//	2: headers carry the version they were written with.
+//
+// Features that remotes may not support, e.g. batches or compression, are
+// negotiated separately during handshake. The version changes when the format
+// of existing messages changes in a way that remotes can't detect otherwise.
+const CurrentVersion = 2
+
+// MinSupportedVersion is the oldest version that can be negotiated.
+const MinSupportedVersion = 1
 
 // HeaderStructSize size of header struct in bytes.
-const HeaderStructSize = 8
+const HeaderStructSize = 40
+
+// HeaderMinSize is the size in bytes of the first version of the header, which
+// only had HeaderSize, MessageType and DroppedCount.
+const HeaderMinSize = 8
 
 // Header is used to describe the message being sent to the remote process.
 //
-//	0 --------- 16 ---------- 32 ----------- 64 -----------+
-//	| HeaderSize | MessageType | DroppedCount | Payload... |
-//	+---- 16 ----+---- 16 -----+----- 32 -----+------------+
+//	0 --------- 16 ---------- 32 ----------- 64 ------- 128 ----- 192 ----- 224 --- 256 ---- 288 -------- 320 ---------+
+//	| HeaderSize | MessageType | DroppedCount | Sequence | TimeNs | Checksum | Flags | Version | Reserved | Payload... |
+//	+---- 16 ----+---- 16 -----+----- 32 -----+--- 64 ---+-- 64 --+--- 32 ---+-- 32 -+--- 32 --+--- 32 ---+------------+
+//
+// Sequence, TimeNs, Checksum, Flags and Version were added after the first
+// version of the header. Remotes must check HeaderSize before reading them, and skip
+// fields past the ones they know about.
 //
 // +marshal
 type Header struct {
@@ -36,12 +61,141 @@ type Header struct {
 	HeaderSize uint16
 
 	// MessageType describes the payload. It must be one of the pb.MessageType
-	// values and determine how the payload is interpreted. This is more efficient
+	// values, or BatchMessageType, and determine how the payload is interpreted. This is more efficient
 	// than using protobuf.Any because Any uses the full protobuf name to identify
 	// the type.
 	MessageType uint16
 
 	// DroppedCount is the number of points that failed to be written and had to
-	// be dropped. It wraps around after max(uint32).
+	// be dropped. It wraps around after max(uint32). Sinks can also report
+	// drops by message type, see DropStats in common.proto.
 	DroppedCount uint32
+
+	// Sequence numbers the points sent over a connection, starting at 1. It's
+	// assigned when the point is generated, so a gap in the sequence means that
+	// points were lost, and a lower number than the previous one means that
+	// points were reordered. It's 0 for batch headers, and for sinks that don't
+	// number points.
+	Sequence uint64
+
+	// TimeNs is the time when the point was generated, in nanoseconds since the
+	// Unix epoch. It's 0 for batch headers.
+	TimeNs int64
+
+	// Checksum is the CRC32 (Castagnoli) of the payload, as sent, i.e. after
+	// compression. It's only valid if FlagChecksum is set.
+	Checksum uint32
+
+	// Flags is a combination of the Flag* values.
+	Flags uint32
+
+	// Version is the protocol version negotiated during handshake, see
+	// CurrentVersion. 0 means version 1, which predates the field.
+	Version uint32
+
+	// Reserved keeps the header 8-byte aligned. It must be 0.
+	_ uint32
+}
+
+// ProtocolVersion returns the protocol version that the message was written
+// with.
+func (h *Header) ProtocolVersion() uint32 {
+	if h.Version == 0 {
+		return 1
+	}
+	return h.Version
+}
+
+// CheckVersion returns an error if the message wasn't written with version.
+// Remotes must not try to interpret messages written with a version other
+// than the one negotiated.
+func (h *Header) CheckVersion(version uint32) error {
+	if got := h.ProtocolVersion(); got != version {
+		return fmt.Errorf("message version %d doesn't match negotiated version %d", got, version)
+	}
+	return nil
 }
+
+// ParseHeader parses the header at the start of msg, which is a complete
+// message, and returns the header and the payload that follows it. Headers
+// shorter than HeaderStructSize, written by older versions, are extended with
+// zeroed fields, and fields past HeaderStructSize, written by newer versions,
+// are skipped. It's the reference implementation for remotes.
+func ParseHeader(msg []byte) (Header, []byte, error) {
+	if len(msg) < HeaderMinSize {
+		return Header{}, nil, fmt.Errorf("message too small: %d bytes", len(msg))
+	}
+	var raw [HeaderStructSize]byte
+	copy(raw[:], msg)
+	var hdr Header
+	hdr.UnmarshalUnsafe(raw[:])
+	if hdr.HeaderSize < HeaderMinSize {
+		return Header{}, nil, fmt.Errorf("invalid header size: %d", hdr.HeaderSize)
+	}
+	if len(msg) < int(hdr.HeaderSize) {
+		return Header{}, nil, fmt.Errorf("message truncated, header size: %d, read: %d", hdr.HeaderSize, len(msg))
+	}
+	if hdr.HeaderSize < HeaderStructSize {
+		// Fields past the header, if any, belong to the payload.
+		for i := int(hdr.HeaderSize); i < len(raw); i++ {
+			raw[i] = 0
+		}
+		hdr.UnmarshalUnsafe(raw[:])
+	}
+	return hdr, msg[hdr.HeaderSize:], nil
+}
+
+const (
+	// FlagChecksum is set in Header.Flags when Header.Checksum is valid.
+	FlagChecksum = 1 << 0
+
+	// FlagTruncated is set in Header.Flags when the point was truncated to fit
+	// in the maximum message size declared by the remote during handshake.
+	// String and bytes fields are shortened, longest first, and repeated
+	// fields may lose their last elements if that's not enough.
+	FlagTruncated = 1 << 1
+
+	// FlagVerdictRequested is set in Header.Flags when the sentry waits for a
+	// pb.Verdict to let the operation that triggered the point proceed. It's
+	// only set if the remote accepted verdicts during handshake.
+	FlagVerdictRequested = 1 << 2
+)
+
+// MinMaxMessageSize is the smallest maximum message size that the remote can
+// declare during handshake.
+const MinMaxMessageSize = 1024
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// SetChecksum sets the checksum of payload in h.
+func (h *Header) SetChecksum(payload []byte) {
+	h.Checksum = crc32.Checksum(payload, crc32cTable)
+	h.Flags |= FlagChecksum
+}
+
+// VerifyChecksum returns false if h has a checksum that doesn't match payload.
+func (h *Header) VerifyChecksum(payload []byte) bool {
+	return h.Flags&FlagChecksum == 0 || h.Checksum == crc32.Checksum(payload, crc32cTable)
+}
+
+// FrameLengthSize is the size in bytes of the length prefix that precedes
+// every message, including the handshake, on stream transports, e.g. TCP,
+// which don't preserve message boundaries. The length is encoded as a
+// little-endian uint32 and doesn't include the prefix itself.
+const FrameLengthSize = 4
+
+// BatchMessageType is the Header.MessageType of a batch of messages. Batches
+// are only sent if the remote accepts them during handshake. The payload of a
+// batch is a sequence of complete messages, each preceded by its length:
+//
+//	+--------+----- 32 -----+----------------+----- 32 -----+---------
+//	| Header | Length (LE)  | Header+Payload | Length (LE)  | ...
+//	+--------+--------------+----------------+--------------+---------
+//
+// Length is the size in bytes of the message that follows, not including the
+// length itself.
+const BatchMessageType = 0xffff
+
+// BatchLengthSize is the size in bytes of the length that precedes every
+// message in a batch.
+const BatchLengthSize = 4
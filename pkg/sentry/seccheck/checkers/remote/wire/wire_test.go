@@ -14,7 +14,16 @@
 
 package wire
 
-import "testing"
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
 
 func TestHeaderSize(t *testing.T) {
 	hdr := Header{}
@@ -22,3 +31,275 @@ func TestHeaderSize(t *testing.T) {
 		t.Errorf("wrong const header size, want: %v, got: %v", want, got)
 	}
 }
+
+func TestChecksum(t *testing.T) {
+	payload := []byte("payload")
+	hdr := Header{}
+	if !hdr.VerifyChecksum(payload) {
+		t.Errorf("header without checksum must always be valid")
+	}
+	hdr.SetChecksum(payload)
+	if !hdr.VerifyChecksum(payload) {
+		t.Errorf("checksum doesn't match payload")
+	}
+	if hdr.VerifyChecksum([]byte("Payload")) {
+		t.Errorf("checksum matches corrupted payload")
+	}
+}
+
+// frame returns a framed message with payload.
+func frame(payload string, sequence uint64, checksum bool) []byte {
+	hdr := Header{HeaderSize: HeaderStructSize, MessageType: 1, Sequence: sequence}
+	if checksum {
+		hdr.SetChecksum([]byte(payload))
+	}
+	out := make([]byte, FrameLengthSize+HeaderStructSize, FrameLengthSize+HeaderStructSize+len(payload))
+	binary.LittleEndian.PutUint32(out, uint32(HeaderStructSize+len(payload)))
+	hdr.MarshalUnsafe(out[FrameLengthSize:])
+	return append(out, payload...)
+}
+
+func TestFrameReader(t *testing.T) {
+	truncated := frame("truncated", 2, true)
+	corrupted := frame("corrupted", 4, true)
+	corrupted[len(corrupted)-1] ^= 0xff
+
+	var stream []byte
+	stream = append(stream, frame("first", 1, true)...)
+	stream = append(stream, truncated[:len(truncated)-3]...)
+	stream = append(stream, frame("third", 3, true)...)
+	stream = append(stream, corrupted...)
+	// Frames without checksum are skipped until the reader is synchronized
+	// again.
+	stream = append(stream, frame("fifth", 5, false)...)
+	stream = append(stream, frame("sixth", 6, true)...)
+	stream = append(stream, frame("seventh", 7, false)...)
+	last := frame("last", 8, true)
+	stream = append(stream, last[:len(last)-1]...)
+
+	r := NewFrameReader(bytes.NewReader(stream), 1024)
+	for _, want := range []struct {
+		sequence uint64
+		payload  string
+	}{
+		{1, "first"},
+		{3, "third"},
+		{6, "sixth"},
+		{7, "seventh"},
+	} {
+		hdr, payload, err := r.Next()
+		if err != nil {
+			t.Fatalf("Next(): %v", err)
+		}
+		if hdr.Sequence != want.sequence || string(payload) != want.payload {
+			t.Errorf("wrong message, want: %d %q, got: %d %q", want.sequence, want.payload, hdr.Sequence, payload)
+		}
+	}
+	if _, _, err := r.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("wrong error for truncated stream, want: %v, got: %v", io.ErrUnexpectedEOF, err)
+	}
+	if want := uint64(len(truncated) - 3 + len(corrupted) + len(frame("fifth", 5, false)) + len(last) - 1); r.Skipped != want {
+		t.Errorf("wrong number of bytes skipped, want: %d, got: %d", want, r.Skipped)
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	// The first version of the header only had HeaderSize, MessageType and
+	// DroppedCount, and no version.
+	old := []byte{8, 0, 3, 0, 5, 0, 0, 0, 'p'}
+	hdr, payload, err := ParseHeader(old)
+	if err != nil {
+		t.Fatalf("ParseHeader(): %v", err)
+	}
+	if hdr.MessageType != 3 || hdr.DroppedCount != 5 || hdr.Sequence != 0 || string(payload) != "p" {
+		t.Errorf("wrong message: %+v, payload: %q", hdr, payload)
+	}
+	if v := hdr.ProtocolVersion(); v != 1 {
+		t.Errorf("wrong version, want: 1, got: %d", v)
+	}
+	if err := hdr.CheckVersion(1); err != nil {
+		t.Errorf("CheckVersion(1): %v", err)
+	}
+	if err := hdr.CheckVersion(CurrentVersion); err == nil {
+		t.Errorf("CheckVersion(%d) succeeded for version 1 message", CurrentVersion)
+	}
+
+	for _, tc := range []struct {
+		name string
+		msg  []byte
+	}{
+		{name: "empty"},
+		{name: "small-header-size", msg: []byte{4, 0, 3, 0, 5, 0, 0, 0}},
+		{name: "truncated", msg: []byte{16, 0, 3, 0, 5, 0, 0, 0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := ParseHeader(tc.msg); err == nil {
+				t.Errorf("ParseHeader(%v) succeeded", tc.msg)
+			}
+		})
+	}
+}
+
+func TestFrameReaderVersion(t *testing.T) {
+	newer := Header{HeaderSize: HeaderStructSize, MessageType: 1, Sequence: 1, Version: CurrentVersion + 1}
+	var msg [FrameLengthSize + HeaderStructSize]byte
+	binary.LittleEndian.PutUint32(msg[:], HeaderStructSize)
+	newer.MarshalUnsafe(msg[FrameLengthSize:])
+	stream := append(msg[:], frame("current", 2, true)...)
+
+	r := NewFrameReader(bytes.NewReader(stream), 1024)
+	if _, _, err := r.Next(); err == nil {
+		t.Errorf("Next() succeeded for version %d", newer.Version)
+	}
+	// The reader can keep going after a message with an unsupported version.
+	hdr, payload, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next(): %v", err)
+	}
+	if hdr.Sequence != 2 || string(payload) != "current" {
+		t.Errorf("wrong message, want: 2 %q, got: %d %q", "current", hdr.Sequence, payload)
+	}
+}
+
+func TestMarshalCBOR(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		msg  proto.Message
+		want []byte
+	}{
+		{
+			name: "empty",
+			msg:  &pb.ExitNotifyParentInfo{},
+			want: []byte{0xa0},
+		},
+		{
+			name: "int",
+			msg:  &pb.ExitNotifyParentInfo{ExitStatus: 123},
+			want: append(append([]byte{0xa1, 0x6b}, "exit_status"...), 0x18, 0x7b),
+		},
+		{
+			name: "negative",
+			msg:  &pb.ExitNotifyParentInfo{ExitStatus: -1},
+			want: append(append([]byte{0xa1, 0x6b}, "exit_status"...), 0x20),
+		},
+		{
+			name: "enum",
+			msg:  &pb.DropCount{MessageType: pb.MessageType_MESSAGE_SENTRY_CLONE, Count: 3},
+			want: append(append(append(append([]byte{0xa2, 0x6c}, "message_type"...), 0x74), "MESSAGE_SENTRY_CLONE"...), append([]byte{0x65}, "count\x03"...)...),
+		},
+		{
+			name: "repeated",
+			msg:  &pb.DropStats{Drops: []*pb.DropCount{{Count: 1}, {Count: 300}}},
+			want: append(append(append([]byte{0xa1, 0x65}, "drops"...), 0x82, 0xa1, 0x65), append(append([]byte("count"), 0x01, 0xa1, 0x65), append([]byte("count"), 0x19, 0x01, 0x2c)...)...),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := MarshalCBOR(tc.msg); !bytes.Equal(got, tc.want) {
+				t.Errorf("MarshalCBOR(%+v), want: %x, got: %x", tc.msg, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMarshalAppend(t *testing.T) {
+	msg := &pb.DropStats{Drops: []*pb.DropCount{{Count: 1}, {Count: 300}}}
+	for _, encoding := range []pb.Encoding{pb.Encoding_ENCODING_PROTO, pb.Encoding_ENCODING_CBOR} {
+		t.Run(encoding.String(), func(t *testing.T) {
+			want, err := Marshal(encoding, msg)
+			if err != nil {
+				t.Fatalf("Marshal(): %v", err)
+			}
+			prefix := []byte("prefix")
+			buf := make([]byte, len(prefix), len(prefix)+len(want))
+			copy(buf, prefix)
+			got, err := MarshalAppend(buf, encoding, msg)
+			if err != nil {
+				t.Fatalf("MarshalAppend(): %v", err)
+			}
+			if !bytes.Equal(got[:len(prefix)], prefix) || !bytes.Equal(got[len(prefix):], want) {
+				t.Errorf("MarshalAppend(): got: %x, want: %x followed by %x", got, prefix, want)
+			}
+			if &got[0] != &buf[0] {
+				t.Errorf("MarshalAppend() reallocated a buffer large enough for the payload")
+			}
+		})
+	}
+}
+
+func TestDictionary(t *testing.T) {
+	var d Dictionary
+	msg := &pb.Open{
+		ContextData: &pb.ContextData{
+			ProcessName: InternRef(1),
+			InternedStrings: []*pb.InternedString{
+				{Id: 0, Value: "/etc/passwd"},
+				{Id: 1, Value: "python3"},
+			},
+		},
+		FdPath:   InternRef(0),
+		Pathname: "passwd",
+	}
+	if err := d.Resolve(msg); err != nil {
+		t.Fatalf("Resolve(): %v", err)
+	}
+	want := &pb.Open{
+		ContextData: &pb.ContextData{ProcessName: "python3"},
+		FdPath:      "/etc/passwd",
+		Pathname:    "passwd",
+	}
+	if !proto.Equal(want, msg) {
+		t.Errorf("Resolve(), want: %+v, got: %+v", want, msg)
+	}
+
+	// Strings stay defined for the following points.
+	read := &pb.Read{FdPath: InternRef(0)}
+	if err := d.Resolve(read); err != nil {
+		t.Fatalf("Resolve(): %v", err)
+	}
+	if want := "/etc/passwd"; read.FdPath != want {
+		t.Errorf("Resolve(), want: %q, got: %q", want, read.FdPath)
+	}
+
+	if err := d.Resolve(&pb.Read{FdPath: InternRef(2)}); err == nil {
+		t.Errorf("Resolve() of an undefined string, want error")
+	}
+}
+
+func TestWorkloadAndTimeOf(t *testing.T) {
+	workload := &pb.Workload{SandboxId: "sandbox"}
+	for _, tc := range []struct {
+		name      string
+		msg       proto.Message
+		workload  *pb.Workload
+		realtime  int64
+		monotonic int64
+	}{
+		{
+			name: "empty",
+			msg:  &pb.Open{},
+		},
+		{
+			name:      "context",
+			msg:       &pb.Open{ContextData: &pb.ContextData{Workload: workload, TimeNs: 1, MonotonicTimeNs: 2}},
+			workload:  workload,
+			realtime:  1,
+			monotonic: 2,
+		},
+		{
+			name:      "sandbox",
+			msg:       &pb.CheckpointInfo{Workload: workload, TimeNs: 1, MonotonicTimeNs: 2},
+			workload:  workload,
+			realtime:  1,
+			monotonic: 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := WorkloadOf(tc.msg); got != tc.workload {
+				t.Errorf("WorkloadOf(): got: %v, want: %v", got, tc.workload)
+			}
+			if realtime, monotonic := TimeOf(tc.msg); realtime != tc.realtime || monotonic != tc.monotonic {
+				t.Errorf("TimeOf(): got: %d, %d, want: %d, %d", realtime, monotonic, tc.realtime, tc.monotonic)
+			}
+		})
+	}
+}
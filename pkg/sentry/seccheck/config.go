@@ -17,30 +17,96 @@ package seccheck
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"sort"
 	"sync"
 
 	"gvisor.dev/gvisor/pkg/fd"
 	"gvisor.dev/gvisor/pkg/log"
 )
 
-// DefaultSessionName is the name of the only session that can exist in the
-// system for now. When multiple sessions are supported, this can be removed.
+// DefaultSessionName is the conventional name of the session that is always
+// on, e.g. the one created from the pod init configuration.
 const DefaultSessionName = "Default"
 
+// maxSessions is the maximum number of sessions that can exist at the same
+// time. Every session adds sinks that points are sent to.
+const maxSessions = 8
+
+// sessionNameRE matches valid session names.
+var sessionNameRE = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// session is an existing session. Its checkers are registered in Global.
+type session struct {
+	points []PointConfig
+	reqs   []PointReq
+	optIn  bool
+	// containers restricts the session to some containers. It's nil if the
+	// session applies to all containers.
+	containers *ContainerFilter
+	payload    *PayloadConfig
+	exec       *ExecConfig
+	rateLimit  *RateLimitConfig
+	quota      *QuotaConfig
+	// limiter enforces rateLimit, quota, and the rate limits of points. It
+	// may be nil.
+	limiter  *sessionLimiter
+	checkers []Checker
+	// sinks are the names of the sinks that created checkers, in the same
+	// order.
+	sinks []string
+	// sinkIndexes are the indexes in SessionConfig.Sinks of the sinks that
+	// created checkers, in the same order. They differ from the indexes of
+	// checkers if sinks that failed to be created were ignored.
+	sinkIndexes []int
+}
+
 var (
 	sessionsMu = sync.Mutex{}
-	sessions   = make(map[string]*State)
+	sessions   = make(map[string]*session)
 )
 
 // SessionConfig describes a new session configuration. A session consists of a
-// set of points to be enabled and sinks where the points are sent to.
+// set of points to be enabled and sinks where the points are sent to. Several
+// sessions can exist, each with its own name, points, and sinks, e.g. an audit
+// session that is always on, and another one created during an investigation.
 type SessionConfig struct {
+	// Version is the version of the configuration format, see ConfigVersion
+	// and DecodeConfig. 0 is the same as 1.
+	Version int `json:"version,omitempty"`
 	// Name is the unique session name.
 	Name string `json:"name,omitempty"`
 	// Points is the set of points to enable in this session.
 	Points []PointConfig `json:"points,omitempty"`
-	// Sinks are the sinks that will process the points enabled above.
+	// Presets are the names of curated sets of points to enable in addition
+	// to Points, e.g. "security-essentials", see Presets. Points override
+	// the configuration of the same point in a preset.
+	Presets []string `json:"presets,omitempty"`
+	// Sinks are the sinks that will process the points enabled above. Every
+	// point is sent to all sinks, e.g. a local file for forensics and a remote
+	// process for live alerting. Each sink buffers points and handles failures
+	// on its own, so that a slow or failed sink doesn't affect the others.
 	Sinks []SinkConfig `json:"sinks,omitempty"`
+	// Payload configures the capture of data buffers for points that request
+	// the "data" optional field. It may be nil to use the defaults.
+	Payload *PayloadConfig `json:"payload,omitempty"`
+	// Exec configures how much of the arguments and environment of new
+	// process images is reported by sentry/execve. It may be nil to report
+	// them in full.
+	Exec *ExecConfig `json:"exec,omitempty"`
+	// OptIn restricts the session to the containers that opt into it, see
+	// AddContainer. Otherwise, the session applies to all containers.
+	OptIn bool `json:"opt_in,omitempty"`
+	// Containers restricts the session to some containers, e.g. to exclude
+	// sidecars. It may be nil to apply to all containers. It applies to
+	// containers that opted into the session if OptIn is set.
+	Containers *ContainerFilter `json:"containers,omitempty"`
+	// RateLimit caps the rate at which points are sent to the sinks of the
+	// session. It may be nil for no limit.
+	RateLimit *RateLimitConfig `json:"rate_limit,omitempty"`
+	// Quota deletes the session once it sent a given amount of points, or
+	// after some time. It may be nil for no quota.
+	Quota *QuotaConfig `json:"quota,omitempty"`
 }
 
 // PointConfig describes a point to be enabled in a given session.
@@ -51,8 +117,26 @@ type PointConfig struct {
 	OptionalFields []string `json:"optional_fields,omitempty"`
 	// ContextFields is the list of context fields to collect.
 	ContextFields []string `json:"context_fields,omitempty"`
+	// ErrorPolicy is what happens when a sink fails to process the point,
+	// e.g. because the point was dropped: ErrorPolicyFailOpen (default) or
+	// ErrorPolicyFailClosed.
+	ErrorPolicy string `json:"error_policy,omitempty"`
+	// RateLimit gives the point its own rate limit, instead of sharing
+	// SessionConfig.RateLimit with the other points. It may be nil.
+	RateLimit *RateLimitConfig `json:"rate_limit,omitempty"`
 }
 
+// Error policies, see PointConfig.ErrorPolicy.
+const (
+	// ErrorPolicyFailOpen logs the failure and lets the operation that
+	// triggered the point continue.
+	ErrorPolicyFailOpen = "fail-open"
+	// ErrorPolicyFailClosed fails the operation that triggered the point. Only
+	// points generated before their operation takes effect can fail it, i.e.
+	// sentry/execve and sentry/clone.
+	ErrorPolicyFailClosed = "fail-closed"
+)
+
 // SinkConfig describes the sink that will process the points in a given
 // session.
 type SinkConfig struct {
@@ -60,11 +144,15 @@ type SinkConfig struct {
 	Name string `json:"name,omitempty"`
 	// Config is a opaque json object that is passed to the sink.
 	Config map[string]interface{} `json:"config,omitempty"`
-	// IgnoreSetupError makes errors during sink setup to be ignored. Otherwise,
-	// failures will prevent the container from starting.
+	// IgnoreSetupError makes errors during sink setup and creation to be
+	// ignored, in which case points are only sent to the remaining sinks.
+	// Otherwise, failures will prevent the container from starting.
 	IgnoreSetupError bool `json:"ignore_setup_error,omitempty"`
 	// Status is the runtime status for the sink.
 	Status CheckerStatus `json:"status,omitempty"`
+	// Index is the index of the sink in the configuration that the session
+	// was created with. It's only set by List, see Reconnect.
+	Index int `json:"index,omitempty"`
 	// FD is the endpoint returned from Setup. It may be nil.
 	FD *fd.FD `json:"-"`
 }
@@ -84,58 +172,348 @@ func Create(conf *SessionConfig, force bool) error {
 		}
 		log.Infof("Trace session %q was deleted to be replaced", conf.Name)
 	}
-	if conf.Name != DefaultSessionName {
-		return fmt.Errorf(`only a single "Default" session is supported`)
+	if !sessionNameRE.MatchString(conf.Name) {
+		return fmt.Errorf("invalid session name %q, must be 1 to 64 letters, digits, '_', '.', or '-'", conf.Name)
+	}
+	if len(sessions) >= maxSessions {
+		return fmt.Errorf("too many sessions, at most %d can exist", maxSessions)
+	}
+
+	points, err := sessionPoints(conf)
+	if err != nil {
+		return err
+	}
+	reqs, err := PointReqs(points)
+	if err != nil {
+		return err
+	}
+	if _, err := newPayload(conf.Payload); err != nil {
+		return err
+	}
+	if err := conf.Exec.validate(); err != nil {
+		return err
+	}
+	limiter, err := newSessionLimiter(conf, points)
+	if err != nil {
+		return err
+	}
+
+	// Points are sent to each sink independently. All sinks are created before
+	// any is registered, so that a failure doesn't leave a partial session
+	// behind.
+	var (
+		checkers    []Checker
+		sinks       []string
+		sinkIndexes []int
+	)
+	for i, sinkConfig := range conf.Sinks {
+		checker, err := newSink(sinkConfig)
+		if err != nil {
+			if !sinkConfig.IgnoreSetupError {
+				for _, c := range checkers {
+					c.Stop()
+				}
+				return err
+			}
+			log.Warningf("Ignoring sink %q creation failure: %v", sinkConfig.Name, err)
+			continue
+		}
+		checkers = append(checkers, checker)
+		sinks = append(sinks, sinkConfig.Name)
+		sinkIndexes = append(sinkIndexes, i)
+	}
+	limiter.start(conf.Name, checkers)
+	scope := containerScopeLocked(conf.Name, conf.OptIn, conf.Containers, reqs)
+	for _, checker := range checkers {
+		Global.appendScopedChecker(checker, reqs, scope, limiter)
+	}
+
+	sessions[conf.Name] = &session{
+		points:      points,
+		reqs:        reqs,
+		optIn:       conf.OptIn,
+		containers:  conf.Containers,
+		payload:     conf.Payload,
+		exec:        conf.Exec,
+		rateLimit:   conf.RateLimit,
+		quota:       conf.Quota,
+		limiter:     limiter,
+		checkers:    checkers,
+		sinks:       sinks,
+		sinkIndexes: sinkIndexes,
+	}
+	updatePayloadLocked()
+	updateExecArgsLocked()
+	return nil
+}
+
+// Update changes the points, payload, exec and rate limit configuration of an
+// existing session, while its sinks keep running. Rate limit budgets and
+// quotas start over. If conf has sinks, they must be the same sinks as the
+// session's, in the same order, and their configuration is reloaded in place,
+// see Reloader. Otherwise, sinks cannot be changed, and the session must be
+// created again with force instead.
+func Update(conf *SessionConfig) error {
+	log.Debugf("Updating seccheck: %+v", conf)
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	session, ok := sessions[conf.Name]
+	if !ok {
+		return fmt.Errorf("session %q not found", conf.Name)
+	}
+	var reloaders []Reloader
+	if len(conf.Sinks) > 0 {
+		var err error
+		if reloaders, err = sinkReloaders(session, conf.Sinks); err != nil {
+			return err
+		}
+	}
+	points, err := sessionPoints(conf)
+	if err != nil {
+		return err
+	}
+	reqs, err := PointReqs(points)
+	if err != nil {
+		return err
+	}
+	if _, err := newPayload(conf.Payload); err != nil {
+		return err
+	}
+	if err := conf.Exec.validate(); err != nil {
+		return err
+	}
+	limiter, err := newSessionLimiter(conf, points)
+	if err != nil {
+		return err
+	}
+	// Configurations were validated above, so sinks only fail to reload in
+	// unusual cases, which may leave some of them reloaded.
+	for i, r := range reloaders {
+		if err := r.Reload(conf.Sinks[i].Config); err != nil {
+			return fmt.Errorf("reloading sink %q: %w", conf.Sinks[i].Name, err)
+		}
+	}
+	session.limiter.stop()
+	limiter.start(conf.Name, session.checkers)
+	Global.setPoints(session.checkers, reqs, containerScopeLocked(conf.Name, conf.OptIn, conf.Containers, reqs), limiter)
+	session.points = points
+	session.reqs = reqs
+	session.optIn = conf.OptIn
+	session.containers = conf.Containers
+	session.payload = conf.Payload
+	session.exec = conf.Exec
+	session.rateLimit = conf.RateLimit
+	session.quota = conf.Quota
+	session.limiter = limiter
+	updatePayloadLocked()
+	updateExecArgsLocked()
+	return nil
+}
+
+// sinkReloaders returns the checkers of session that reload the configuration
+// of sinks, after validating it. sinks must be the sinks that session was
+// created with, in the same order.
+func sinkReloaders(session *session, sinks []SinkConfig) ([]Reloader, error) {
+	if len(sinks) != len(session.sinks) {
+		return nil, fmt.Errorf("sinks cannot be added or removed, create the session again with force instead")
+	}
+	reloaders := make([]Reloader, 0, len(sinks))
+	for i, sinkConfig := range sinks {
+		if sinkConfig.Name != session.sinks[i] {
+			return nil, fmt.Errorf("sink %q cannot be replaced with %q, create the session again with force instead", session.sinks[i], sinkConfig.Name)
+		}
+		r, ok := session.checkers[i].(Reloader)
+		if !ok {
+			return nil, fmt.Errorf("sink %q cannot be reloaded, create the session again with force instead", sinkConfig.Name)
+		}
+		sink, err := findSinkDesc(sinkConfig.Name)
+		if err != nil {
+			return nil, err
+		}
+		if sink.Validate != nil {
+			if err := sink.Validate(sinkConfig.Config); err != nil {
+				return nil, fmt.Errorf("invalid configuration for sink %q: %w", sinkConfig.Name, err)
+			}
+		}
+		reloaders = append(reloaders, r)
+	}
+	return reloaders, nil
+}
+
+// Validate checks conf without creating the session: the session name, points
+// and their fields, presets, payload and exec settings, and the configuration of sinks that
+// support validation. Sinks are not set up.
+func Validate(conf *SessionConfig) error {
+	if !sessionNameRE.MatchString(conf.Name) {
+		return fmt.Errorf("invalid session name %q, must be 1 to 64 letters, digits, '_', '.', or '-'", conf.Name)
+	}
+	points, err := sessionPoints(conf)
+	if err != nil {
+		return err
+	}
+	if _, err := PointReqs(points); err != nil {
+		return err
+	}
+	if _, err := newPayload(conf.Payload); err != nil {
+		return err
+	}
+	if err := conf.Exec.validate(); err != nil {
+		return err
+	}
+	if _, err := newSessionLimiter(conf, points); err != nil {
+		return err
+	}
+	for _, sinkConfig := range conf.Sinks {
+		sink, err := findSinkDesc(sinkConfig.Name)
+		if err != nil {
+			return err
+		}
+		if sink.Validate == nil {
+			continue
+		}
+		if err := sink.Validate(sinkConfig.Config); err != nil {
+			return fmt.Errorf("invalid configuration for sink %q: %w", sinkConfig.Name, err)
+		}
+	}
+	return nil
+}
+
+// updatePayloadLocked sets the Payload of Global from the configuration of all
+// sessions. Data is captured once for all sessions, so the smallest limits of
+// all sessions apply, and the redactors of all sessions are applied.
+//
+// +checklocks:sessionsMu
+func updatePayloadLocked() {
+	if len(sessions) == 0 {
+		Global.SetPayload(nil)
+		return
+	}
+	names := make([]string, 0, len(sessions))
+	for name := range sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	merged := &PayloadConfig{MaxSize: maxPayloadMaxSize}
+	for _, name := range names {
+		conf := sessions[name].payload
+		if conf == nil {
+			conf = &PayloadConfig{}
+		}
+		maxSize := conf.MaxSize
+		if maxSize == 0 {
+			maxSize = defaultPayloadMaxSize
+		}
+		if maxSize < merged.MaxSize {
+			merged.MaxSize = maxSize
+		}
+		rate := conf.Rate
+		if rate == 0 {
+			rate = defaultPayloadRate
+		}
+		if merged.Rate == 0 || rate < merged.Rate {
+			merged.Rate = rate
+		}
+		for _, redactor := range conf.Redactors {
+			if !containsString(merged.Redactors, redactor) {
+				merged.Redactors = append(merged.Redactors, redactor)
+			}
+		}
+	}
+	payload, err := newPayload(merged)
+	if err != nil {
+		// Each configuration was validated when its session was created.
+		panic(fmt.Sprintf("invalid payload configuration %+v: %v", merged, err))
+	}
+	Global.SetPayload(payload)
+}
+
+// updateExecArgsLocked sets the ExecArgs of Global from the configuration of
+// all sessions, see mergeExecConfigs.
+//
+// +checklocks:sessionsMu
+func updateExecArgsLocked() {
+	confs := make([]*ExecConfig, 0, len(sessions))
+	for _, s := range sessions {
+		confs = append(confs, s.exec)
+	}
+	Global.SetExecArgs(mergeExecConfigs(confs))
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
 	}
-	state := &Global
+	return false
+}
 
+// PointReqs returns the requirements for the points in configs, to register a
+// Checker with State.AppendChecker.
+func PointReqs(configs []PointConfig) ([]PointReq, error) {
 	var reqs []PointReq
-	for _, ptConfig := range conf.Points {
+	for _, ptConfig := range configs {
 		desc, err := findPointDesc(ptConfig.Name)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		req := PointReq{Pt: desc.ID}
 
 		mask, err := setFields(ptConfig.OptionalFields, desc.OptionalFields)
 		if err != nil {
-			return fmt.Errorf("configuring point %q: %w", ptConfig.Name, err)
+			return nil, fmt.Errorf("configuring point %q: %w", ptConfig.Name, err)
 		}
 		req.Fields.Local = mask
 
 		mask, err = setFields(ptConfig.ContextFields, desc.ContextFields)
 		if err != nil {
-			return fmt.Errorf("configuring point %q: %w", ptConfig.Name, err)
+			return nil, fmt.Errorf("configuring point %q: %w", ptConfig.Name, err)
+		}
+		if len(desc.ContextFields) > 0 {
+			// Points are always attributed to their workload, and stamped
+			// with the time they're captured at.
+			mask.Add(FieldCtxtWorkload)
+			mask.Add(FieldCtxtTime)
 		}
 		req.Fields.Context = mask
 
-		reqs = append(reqs, req)
-	}
-
-	for _, sinkConfig := range conf.Sinks {
-		sink, err := findSinkDesc(sinkConfig.Name)
-		if err != nil {
-			return err
-		}
-		checker, err := sink.New(sinkConfig.Config, sinkConfig.FD)
-		if err != nil {
-			return fmt.Errorf("creating event sink: %w", err)
+		switch ptConfig.ErrorPolicy {
+		case "", ErrorPolicyFailOpen:
+		case ErrorPolicyFailClosed:
+			req.FailClosed = true
+		default:
+			return nil, fmt.Errorf("configuring point %q: invalid error policy %q, must be %q or %q", ptConfig.Name, ptConfig.ErrorPolicy, ErrorPolicyFailOpen, ErrorPolicyFailClosed)
 		}
-		state.AppendChecker(checker, reqs)
-	}
 
-	sessions[conf.Name] = state
-	return nil
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
 }
 
-// SetupSinks runs the setup step of all sinks in the configuration.
-func SetupSinks(sinks []SinkConfig) ([]*os.File, error) {
+// sandboxIDKey is the sink configuration key that is set to the sandbox ID,
+// unless already present, so that sinks can report where points come from.
+const sandboxIDKey = "sandbox_id"
+
+// SetupSinks runs the setup step of all sinks in the configuration. The sandbox
+// ID is added to the configuration of each sink. Sinks may also add entries to
+// their configuration during setup, so the configuration must be passed to the
+// sandbox after setup.
+func SetupSinks(sinks []SinkConfig, sandboxID string) ([]*os.File, error) {
 	var files []*os.File
-	for _, sink := range sinks {
-		sinkFile, err := setupSink(sink)
+	for i := range sinks {
+		sink := &sinks[i]
+		if sink.Config == nil {
+			sink.Config = make(map[string]interface{})
+		}
+		if _, ok := sink.Config[sandboxIDKey]; !ok {
+			sink.Config[sandboxIDKey] = sandboxID
+		}
+		sinkFile, err := setupSink(*sink)
 		if err != nil {
 			if !sink.IgnoreSetupError {
-				return nil, err
+				return nil, fmt.Errorf("setting up event sink %q: %w", sink.Name, err)
 			}
 			log.Warningf("Ignoring sink setup failure: %v", err)
 			// Set sinkFile is nil and append it to the list to ensure the file
@@ -159,6 +537,57 @@ func setupSink(config SinkConfig) (*os.File, error) {
 	return sink.Setup(config.Config)
 }
 
+// newSink creates the checker for a given sink.
+func newSink(config SinkConfig) (Checker, error) {
+	sink, err := findSinkDesc(config.Name)
+	if err != nil {
+		return nil, err
+	}
+	checker, err := sink.New(config.Config, config.FD)
+	if err != nil {
+		return nil, fmt.Errorf("creating event sink %q: %w", config.Name, err)
+	}
+	return checker, nil
+}
+
+// Reconnect hands sink.FD, the new connection to the remote process of the sink
+// at index in the configuration that session name was created with, to the
+// sink. sink is the configuration of the sink after its Setup function
+// created sink.FD. The sink must implement Reconnecter. sink.FD is closed if
+// it can't be handed to the sink.
+func Reconnect(name string, index int, sink SinkConfig) error {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	err := reconnectLocked(name, index, sink)
+	if err != nil && sink.FD != nil {
+		sink.FD.Close()
+	}
+	return err
+}
+
+// +checklocks:sessionsMu
+func reconnectLocked(name string, index int, sink SinkConfig) error {
+	session := sessions[name]
+	if session == nil {
+		return fmt.Errorf("session %q not found", name)
+	}
+	for i, sinkIndex := range session.sinkIndexes {
+		if sinkIndex != index {
+			continue
+		}
+		if session.sinks[i] != sink.Name {
+			return fmt.Errorf("sink %d of session %q is %q, not %q", index, name, session.sinks[i], sink.Name)
+		}
+		r, ok := session.checkers[i].(Reconnecter)
+		if !ok {
+			return fmt.Errorf("sink %q cannot be reconnected", sink.Name)
+		}
+		return r.Reconnect(sink.Config, sink.FD)
+	}
+	return fmt.Errorf("session %q has no sink %d", name, index)
+}
+
 // Delete deletes an existing session.
 func Delete(name string) error {
 	sessionsMu.Lock()
@@ -166,6 +595,16 @@ func Delete(name string) error {
 	return deleteLocked(name)
 }
 
+// DeleteAll deletes all sessions. It's called when the sandbox exits, so that
+// sinks can flush the points they still hold.
+func DeleteAll() {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	for name := range sessions {
+		_ = deleteLocked(name)
+	}
+}
+
 // +checklocks:sessionsMu
 func deleteLocked(name string) error {
 	session := sessions[name]
@@ -173,8 +612,11 @@ func deleteLocked(name string) error {
 		return fmt.Errorf("session %q not found", name)
 	}
 
-	session.clearCheckers()
+	session.limiter.stop()
+	Global.removeCheckers(session.checkers)
 	delete(sessions, name)
+	updatePayloadLocked()
+	updateExecArgsLocked()
 	return nil
 }
 
@@ -183,13 +625,19 @@ func List(out *[]SessionConfig) {
 	sessionsMu.Lock()
 	defer sessionsMu.Unlock()
 
-	for name, state := range sessions {
-		// Only report session name. Consider adding rest of the fields as needed.
-		session := SessionConfig{Name: name}
-		for _, checker := range state.getCheckers() {
+	for name, s := range sessions {
+		// Only report session name and points. Consider adding rest of the
+		// fields as needed.
+		session := SessionConfig{Name: name, Points: s.points, OptIn: s.optIn, Containers: s.containers, RateLimit: s.rateLimit, Quota: s.quota}
+		for i, checker := range s.checkers {
+			// Points dropped due to rate limiting are not sent to any sink,
+			// so they are reported as dropped by all sinks.
+			status := checker.Status()
+			status.DroppedCount += s.limiter.droppedCount()
 			session.Sinks = append(session.Sinks, SinkConfig{
 				Name:   checker.Name(),
-				Status: checker.Status(),
+				Status: status,
+				Index:  s.sinkIndexes[i],
 			})
 		}
 		*out = append(*out, session)
@@ -0,0 +1,206 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"sync"
+
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// containerInfo is a container known to seccheck, see AddContainer.
+type containerInfo struct {
+	// name is the name of the container, e.g. from its Kubernetes pod spec.
+	// It may be empty.
+	name string
+
+	// sessions are the names of the sessions that the container opted into.
+	sessions []string
+
+	// points restricts the points sent for the container to these, among the
+	// session's points. It's nil if not restricted.
+	points *pointMask
+}
+
+// matches returns true if the container's ID or name is one of ids.
+func (c *containerInfo) matches(cid string, ids []string) bool {
+	return containsString(ids, cid) || (len(c.name) > 0 && containsString(ids, c.name))
+}
+
+// containers maps container IDs to their information. It's protected by
+// sessionsMu.
+var containers = make(map[string]containerInfo)
+
+// ContainerFilter restricts a session to some containers of the sandbox, e.g.
+// to exclude sidecars from noisy points. Containers are matched by ID or by
+// name, see AddContainer.
+type ContainerFilter struct {
+	// Include are the containers that the session applies to. The session
+	// applies to all containers if it's empty.
+	Include []string `json:"include,omitempty"`
+	// Exclude are the containers that the session doesn't apply to, even if
+	// they are in Include.
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// AddContainer makes container cid known to sessions, with the given name,
+// which may be empty. The container opts into the given sessions, which must
+// be opt-in sessions to be affected, see SessionConfig.OptIn. If points is not
+// empty, only these points are sent for the container, if they are enabled in
+// the session. Sessions don't need to exist, the container is added to them
+// when they are created. If cid was already added, it's replaced.
+func AddContainer(cid, name string, sessionNames []string, points []string) error {
+	info := containerInfo{name: name, sessions: sessionNames}
+	if len(points) > 0 {
+		info.points = &pointMask{}
+		for _, name := range points {
+			desc, err := findPointDesc(name)
+			if err != nil {
+				return err
+			}
+			info.points.add(desc.ID)
+		}
+	}
+
+	workloadMu.Lock()
+	containerNames[cid] = name
+	workloadMu.Unlock()
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	containers[cid] = info
+	updateScopesLocked()
+	return nil
+}
+
+// RemoveContainer reverts AddContainer, e.g. when the container is destroyed.
+func RemoveContainer(cid string) {
+	workloadMu.Lock()
+	delete(containerNames, cid)
+	workloadMu.Unlock()
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if _, ok := containers[cid]; !ok {
+		return
+	}
+	delete(containers, cid)
+	updateScopesLocked()
+}
+
+// Pod is the Kubernetes pod that the sandbox runs, if any.
+type Pod struct {
+	Name      string
+	Namespace string
+	UID       string
+}
+
+var (
+	// workloadMu protects the workload identity below. It's separate from
+	// sessionsMu because it's read for every point.
+	workloadMu sync.RWMutex
+
+	// sandboxID is the ID of the sandbox, see SetSandbox.
+	//
+	// +checklocks:workloadMu
+	sandboxID string
+
+	// pod is the pod that the sandbox runs, see SetSandbox.
+	//
+	// +checklocks:workloadMu
+	pod Pod
+
+	// containerNames maps the IDs of the containers added with AddContainer to
+	// their names.
+	//
+	// +checklocks:workloadMu
+	containerNames = make(map[string]string)
+)
+
+// SetSandbox sets the sandbox ID and the pod reported in the workload of every
+// point, see Workload. The pod is empty if the sandbox doesn't run a
+// Kubernetes pod.
+func SetSandbox(id string, p Pod) {
+	workloadMu.Lock()
+	defer workloadMu.Unlock()
+	sandboxID = id
+	pod = p
+}
+
+// Workload returns the workload identity of container cid, to be set in the
+// points that come from it. cid may be empty for points about the whole
+// sandbox. A new message is returned every time, since checkers may modify
+// the points they receive.
+func Workload(cid string) *pb.Workload {
+	workloadMu.RLock()
+	defer workloadMu.RUnlock()
+	return &pb.Workload{
+		SandboxId:     sandboxID,
+		ContainerId:   cid,
+		ContainerName: containerNames[cid],
+		PodName:       pod.Name,
+		PodNamespace:  pod.Namespace,
+		PodUid:        pod.UID,
+	}
+}
+
+// updateScopesLocked updates the containers that sessions apply to, after
+// containers changed.
+//
+// +checklocks:sessionsMu
+func updateScopesLocked() {
+	for name, s := range sessions {
+		if s.optIn || s.containers != nil {
+			Global.setPoints(s.checkers, s.reqs, containerScopeLocked(name, s.optIn, s.containers, s.reqs), s.limiter)
+		}
+	}
+}
+
+// containerScopeLocked returns the containers that session name applies to and
+// the points enabled for each of them, as used by pointChecker. It's nil if
+// the session applies to all containers.
+//
+// +checklocks:sessionsMu
+func containerScopeLocked(name string, optIn bool, filter *ContainerFilter, reqs []PointReq) map[string]pointMask {
+	if !optIn && filter == nil {
+		return nil
+	}
+	var enabled pointMask
+	for _, req := range reqs {
+		enabled.add(req.Pt)
+	}
+	scope := make(map[string]pointMask)
+	for cid, c := range containers {
+		if optIn && !containsString(c.sessions, name) {
+			continue
+		}
+		if filter != nil {
+			if len(filter.Include) > 0 && !c.matches(cid, filter.Include) {
+				continue
+			}
+			if c.matches(cid, filter.Exclude) {
+				continue
+			}
+		}
+		mask := enabled
+		if optIn && c.points != nil {
+			for i := range mask {
+				mask[i] &= c.points[i]
+			}
+		}
+		scope[cid] = mask
+	}
+	return scope
+}
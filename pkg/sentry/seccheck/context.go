@@ -0,0 +1,54 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"gvisor.dev/gvisor/pkg/context"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// contextID is the seccheck package's type for context.Context.Value keys.
+type contextID int
+
+const (
+	// CtxLoadContextDataFunc is a Context.Value key for a function that fills
+	// ContextData for the task associated with the context.
+	CtxLoadContextDataFunc contextID = iota
+
+	// CtxContainerID is a Context.Value key for the ID of the container of the
+	// task associated with the context.
+	CtxContainerID
+)
+
+// LoadContextData sets info from ctx based on mask. It's a no-op if ctx is not
+// associated with a task.
+//
+// It's intended for packages that can't depend on the kernel package, e.g.
+// mm, and thus can't call kernel.LoadSeccheckData directly.
+func LoadContextData(ctx context.Context, mask FieldMask, info *pb.ContextData) {
+	if f := ctx.Value(CtxLoadContextDataFunc); f != nil {
+		f.(func(FieldMask, *pb.ContextData))(mask, info)
+	}
+}
+
+// ContainerID returns the ID of the container of the task associated with ctx,
+// or an empty string if ctx is not associated with a task. It's intended to be
+// passed to State.SendToCheckers.
+func ContainerID(ctx context.Context) string {
+	if cid := ctx.Value(CtxContainerID); cid != nil {
+		return cid.(string)
+	}
+	return ""
+}
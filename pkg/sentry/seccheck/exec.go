@@ -0,0 +1,166 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExecConfig configures how much of the arguments and environment of new
+// process images is reported by sentry/execve. By default, they're reported
+// in full, within the limits that execve(2) itself enforces.
+type ExecConfig struct {
+	// MaxArgvSize is the number of bytes of the argument vector reported,
+	// counting a terminating null byte for each argument. Arguments beyond it
+	// are dropped. 0 means no limit.
+	MaxArgvSize int `json:"max_argv_size,omitempty"`
+	// MaxEnvSize is the same as MaxArgvSize for the environment, after Env is
+	// applied.
+	MaxEnvSize int `json:"max_env_size,omitempty"`
+	// Env is the allowlist of the names of environment variables reported,
+	// e.g. "PATH". Names ending with '*' match all variables that start with
+	// the rest of the name, e.g. "KUBERNETES_*". All variables are reported
+	// if it's empty.
+	Env []string `json:"env,omitempty"`
+}
+
+// ExecArgs trims the arguments and environment of new process images
+// according to the ExecConfig of all sessions, see State.GetExecArgs.
+type ExecArgs struct {
+	maxArgvSize int
+	maxEnvSize  int
+
+	// allowlists are the Env allowlists of all sessions that set one. A
+	// variable is reported only if all of them allow it.
+	allowlists [][]string
+}
+
+// validate checks that conf is valid.
+func (conf *ExecConfig) validate() error {
+	if conf == nil {
+		return nil
+	}
+	if conf.MaxArgvSize < 0 {
+		return fmt.Errorf("exec max_argv_size %d cannot be negative", conf.MaxArgvSize)
+	}
+	if conf.MaxEnvSize < 0 {
+		return fmt.Errorf("exec max_env_size %d cannot be negative", conf.MaxEnvSize)
+	}
+	for _, name := range conf.Env {
+		if prefix := strings.TrimSuffix(name, "*"); len(prefix) == 0 || strings.ContainsAny(prefix, "=*") {
+			return fmt.Errorf("invalid exec env %q, must be a variable name optionally followed by '*'", name)
+		}
+	}
+	return nil
+}
+
+// Argv returns argv trimmed to MaxArgvSize. truncated is true if arguments
+// were dropped.
+func (e *ExecArgs) Argv(argv []string) (trimmed []string, truncated bool) {
+	if e == nil {
+		return argv, false
+	}
+	return trimVector(argv, e.maxArgvSize)
+}
+
+// Env returns the variables of env allowed by the Env allowlists, trimmed to
+// MaxEnvSize. truncated is true if allowed variables were dropped.
+func (e *ExecArgs) Env(env []string) (trimmed []string, truncated bool) {
+	if e == nil {
+		return env, false
+	}
+	if len(e.allowlists) > 0 {
+		allowed := make([]string, 0, len(env))
+		for _, v := range env {
+			if e.allowed(v) {
+				allowed = append(allowed, v)
+			}
+		}
+		env = allowed
+	}
+	return trimVector(env, e.maxEnvSize)
+}
+
+// allowed returns true if environment variable v, in the form "NAME=value", is
+// allowed by all allowlists.
+func (e *ExecArgs) allowed(v string) bool {
+	name := v
+	if i := strings.IndexByte(v, '='); i >= 0 {
+		name = v[:i]
+	}
+	for _, allowlist := range e.allowlists {
+		if !matchesEnv(allowlist, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesEnv returns true if name is one of the names in allowlist.
+func matchesEnv(allowlist []string, name string) bool {
+	for _, allowed := range allowlist {
+		if prefix := strings.TrimSuffix(allowed, "*"); len(prefix) < len(allowed) {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// trimVector returns the elements of v that fit in maxSize bytes, counting a
+// null byte after each of them. maxSize 0 means no limit.
+func trimVector(v []string, maxSize int) ([]string, bool) {
+	if maxSize == 0 {
+		return v, false
+	}
+	size := 0
+	for i, s := range v {
+		size += len(s) + 1
+		if size > maxSize {
+			return v[:i], true
+		}
+	}
+	return v, false
+}
+
+// mergeExecConfigs returns the ExecArgs for the configurations of all
+// sessions. The arguments are reported once for all sessions, so the smallest
+// limits of all sessions apply, and a variable is reported only if it's
+// allowed by all sessions. It's nil if no session limits anything.
+func mergeExecConfigs(confs []*ExecConfig) *ExecArgs {
+	var e ExecArgs
+	for _, conf := range confs {
+		if conf == nil {
+			continue
+		}
+		if conf.MaxArgvSize > 0 && (e.maxArgvSize == 0 || conf.MaxArgvSize < e.maxArgvSize) {
+			e.maxArgvSize = conf.MaxArgvSize
+		}
+		if conf.MaxEnvSize > 0 && (e.maxEnvSize == 0 || conf.MaxEnvSize < e.maxEnvSize) {
+			e.maxEnvSize = conf.MaxEnvSize
+		}
+		if len(conf.Env) > 0 {
+			e.allowlists = append(e.allowlists, conf.Env)
+		}
+	}
+	if e.maxArgvSize == 0 && e.maxEnvSize == 0 && len(e.allowlists) == 0 {
+		return nil
+	}
+	return &e
+}
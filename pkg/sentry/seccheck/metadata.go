@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sort"
 
 	"gvisor.dev/gvisor/pkg/fd"
 )
@@ -26,9 +27,36 @@ import (
 const (
 	PointClone Point = iota
 	PointContainerStart
+	PointContainerStop
+	PointContainerPause
+	PointContainerResume
+	PointContainerExec
 	PointExecve
 	PointExitNotifyParent
 	PointTaskExit
+	PointSignalDeliver
+	PointOOM
+	PointCheckpoint
+	PointRestore
+	PointCoreDump
+	PointSeccomp
+	PointCapabilityDenied
+	PointNamespaceCreate
+	PointExecMap
+	PointSyntheticFileWrite
+	PointTCPEstablished
+	PointDNSQuery
+	PointListen
+	PointGoferOp
+	PointFileOpen
+	PointTTYData
+	PointMajorFault
+	PointRLimitBreach
+	PointCPUThrottle
+	PointPolicyViolation
+	PointFilelessExec
+	PointAnomalyAlert
+	PointDrift
 
 	// Add new Points above this line.
 	pointLengthBeforeSyscalls
@@ -39,12 +67,19 @@ const (
 	FieldCtxtContainerID Field = iota
 	FieldCtxtCredentials
 	FieldCtxtCwd
+	FieldCtxtNamespaceThreadGroupID
+	FieldCtxtNamespaceThreadID
 	FieldCtxtProcessName
 	FieldCtxtThreadGroupID
 	FieldCtxtThreadGroupStartTime
 	FieldCtxtThreadID
 	FieldCtxtThreadStartTime
 	FieldCtxtTime
+
+	// FieldCtxtWorkload is the workload identity, see Workload. It can't be
+	// requested: it's added to every point that has context fields, like
+	// FieldCtxtTime.
+	FieldCtxtWorkload
 )
 
 // Fields for container/start point.
@@ -54,11 +89,22 @@ const (
 	FieldContainerStartEnv Field = iota
 )
 
+// Fields for container/exec point.
+const (
+	// FieldContainerExecEnv is an optional field to collect list of environment
+	// variables set for the process being executed.
+	FieldContainerExecEnv Field = iota
+)
+
 // Fields for sentry/execve point.
 const (
 	// FieldSentryExecveBinaryInfo is an optional field to collect information
 	// about the binary being executed.
 	FieldSentryExecveBinaryInfo Field = iota
+	// FieldSentryExecveBinarySHA256 is an optional field to collect the
+	// SHA-256 hash of the binary being executed, which requires reading the
+	// entire binary.
+	FieldSentryExecveBinarySHA256
 )
 
 // Points is a map with all the Points registered in the system.
@@ -87,6 +133,14 @@ var defaultContextFields = []FieldDesc{
 		ID:   FieldCtxtThreadGroupStartTime,
 		Name: "thread_group_start_time",
 	},
+	{
+		ID:   FieldCtxtNamespaceThreadID,
+		Name: "ns_thread_id",
+	},
+	{
+		ID:   FieldCtxtNamespaceThreadGroupID,
+		Name: "ns_group_id",
+	},
 	{
 		ID:   FieldCtxtContainerID,
 		Name: "container_id",
@@ -105,6 +159,17 @@ var defaultContextFields = []FieldDesc{
 	},
 }
 
+// contextFieldsWithout returns defaultContextFields excluding the given field.
+func contextFieldsWithout(id Field) []FieldDesc {
+	var fields []FieldDesc
+	for _, f := range defaultContextFields {
+		if f.ID != id {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
 // SinkDesc describes a sink that is available to be configured.
 type SinkDesc struct {
 	// Name is a unique identifier for the sink.
@@ -112,15 +177,23 @@ type SinkDesc struct {
 	// Setup is called outside the protection of the sandbox. This is done to
 	// allow the sink to do whatever is necessary to set it up. If it returns a
 	// file, this file is donated to the sandbox and passed to the sink when New
-	// is called. config is an opaque json object passed to the sink.
+	// is called. config is an opaque json object passed to the sink. Setup may
+	// add entries to config to pass them to New, e.g. settings negotiated with
+	// a remote process.
 	Setup func(config map[string]interface{}) (*os.File, error)
 	// New creates a new sink. config is an opaque json object passed to the sink.
 	// endpoing is a file descriptor to the file returned in Setup. It's set to -1
 	// if Setup returned nil.
 	New func(config map[string]interface{}, endpoint *fd.FD) (Checker, error)
+	// Validate checks config without setting up or creating the sink, e.g. to
+	// catch errors before the configuration is deployed. It's optional.
+	Validate func(config map[string]interface{}) error
 }
 
-// RegisterSink registers a new sink to make it discoverable.
+// RegisterSink registers a new sink to make it discoverable. Sinks usually
+// register themselves from init, so that importing their package, including
+// from outside this repository, is enough for sessions to use them by name in
+// SinkConfig.Name.
 func RegisterSink(sink SinkDesc) {
 	if _, ok := sinks[sink.Name]; ok {
 		panic(fmt.Sprintf("Sink %q already registered", sink.Name))
@@ -128,6 +201,16 @@ func RegisterSink(sink SinkDesc) {
 	sinks[sink.Name] = sink
 }
 
+// SinkNames returns the names of all registered sinks, sorted.
+func SinkNames() []string {
+	names := make([]string, 0, len(sinks))
+	for name := range sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // PointDesc describes a Point that is available to be configured.
 // Schema for these points are defined in pkg/sentry/seccheck/points/.
 type PointDesc struct {
@@ -222,6 +305,29 @@ func init() {
 		},
 		ContextFields: defaultContextFields,
 	})
+	registerPoint(PointDesc{
+		ID:   PointContainerStop,
+		Name: "container/stop",
+	})
+	registerPoint(PointDesc{
+		ID:   PointContainerPause,
+		Name: "container/pause",
+	})
+	registerPoint(PointDesc{
+		ID:   PointContainerResume,
+		Name: "container/resume",
+	})
+	registerPoint(PointDesc{
+		ID:   PointContainerExec,
+		Name: "container/exec",
+		OptionalFields: []FieldDesc{
+			{
+				ID:   FieldContainerExecEnv,
+				Name: "env",
+			},
+		},
+		ContextFields: defaultContextFields,
+	})
 
 	// Points from the sentry namespace.
 	registerPoint(PointDesc{
@@ -237,6 +343,10 @@ func init() {
 				ID:   FieldSentryExecveBinaryInfo,
 				Name: "binary_info",
 			},
+			{
+				ID:   FieldSentryExecveBinarySHA256,
+				Name: "binary_sha256",
+			},
 		},
 		ContextFields: defaultContextFields,
 	})
@@ -250,4 +360,118 @@ func init() {
 		Name:          "sentry/task_exit",
 		ContextFields: defaultContextFields,
 	})
+	registerPoint(PointDesc{
+		ID:            PointSignalDeliver,
+		Name:          "sentry/signal_deliver",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointOOM,
+		Name:          "sentry/oom",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:   PointCheckpoint,
+		Name: "sentry/checkpoint",
+	})
+	registerPoint(PointDesc{
+		ID:   PointRestore,
+		Name: "sentry/restore",
+	})
+	registerPoint(PointDesc{
+		ID:            PointCoreDump,
+		Name:          "sentry/core_dump",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointSeccomp,
+		Name:          "sentry/seccomp",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointCapabilityDenied,
+		Name:          "sentry/capability_denied",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointNamespaceCreate,
+		Name:          "sentry/namespace_create",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointExecMap,
+		Name:          "sentry/exec_map",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointSyntheticFileWrite,
+		Name:          "sentry/synthetic_file_write",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointTCPEstablished,
+		Name:          "sentry/tcp_established",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointDNSQuery,
+		Name:          "sentry/dns_query",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointListen,
+		Name:          "sentry/listen",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:   PointGoferOp,
+		Name: "sentry/gofer_op",
+		// Gofer operations are reported with filesystem locks held, which
+		// prevents cwd from being resolved.
+		ContextFields: contextFieldsWithout(FieldCtxtCwd),
+	})
+	registerPoint(PointDesc{
+		ID:            PointFileOpen,
+		Name:          "sentry/file_open",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointTTYData,
+		Name:          "sentry/tty_data",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointMajorFault,
+		Name:          "sentry/major_fault",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointRLimitBreach,
+		Name:          "sentry/rlimit_breach",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:   PointCPUThrottle,
+		Name: "sentry/cpu_throttle",
+	})
+	registerPoint(PointDesc{
+		ID:            PointPolicyViolation,
+		Name:          "sentry/policy_violation",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointFilelessExec,
+		Name:          "sentry/fileless_exec",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointAnomalyAlert,
+		Name:          "sentry/anomaly_alert",
+		ContextFields: defaultContextFields,
+	})
+	registerPoint(PointDesc{
+		ID:            PointDrift,
+		Name:          "sentry/drift",
+		ContextFields: defaultContextFields,
+	})
 }
@@ -23,8 +23,17 @@ func init() {
 			ID:   FieldSyscallPath,
 			Name: "fd_path",
 		},
+		{
+			ID:   FieldSyscallReadData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(2, "open", []FieldDesc{
+		{
+			ID:   FieldSyscallOpenPathname,
+			Name: "pathname",
+		},
 	})
-	addSyscallPoint(2, "open", nil)
 	addSyscallPoint(3, "close", []FieldDesc{
 		{
 			ID:   FieldSyscallPath,
@@ -38,23 +47,45 @@ func init() {
 			Name: "fd_path",
 		},
 	})
+	addSyscallPoint(44, "sendto", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallWriteData,
+			Name: "data",
+		},
+	})
 	addSyscallPoint(59, "execve", []FieldDesc{
 		{
 			ID:   FieldSyscallExecveEnvv,
 			Name: "envv",
 		},
+		{
+			ID:   FieldSyscallExecveAsync,
+			Name: "async_args",
+		},
 	})
 	addSyscallPoint(85, "creat", []FieldDesc{
 		{
 			ID:   FieldSyscallPath,
 			Name: "fd_path",
 		},
+		{
+			ID:   FieldSyscallOpenPathname,
+			Name: "pathname",
+		},
 	})
 	addSyscallPoint(257, "openat", []FieldDesc{
 		{
 			ID:   FieldSyscallPath,
 			Name: "fd_path",
 		},
+		{
+			ID:   FieldSyscallOpenPathname,
+			Name: "pathname",
+		},
 	})
 	addSyscallPoint(322, "execveat", []FieldDesc{
 		{
@@ -65,6 +96,10 @@ func init() {
 			ID:   FieldSyscallExecveEnvv,
 			Name: "envv",
 		},
+		{
+			ID:   FieldSyscallExecveAsync,
+			Name: "async_args",
+		},
 	})
 	addSyscallPoint(80, "chdir", nil)
 	addSyscallPoint(81, "fchdir", []FieldDesc{
@@ -81,12 +116,25 @@ func init() {
 			Name: "fd_path",
 		},
 	})
+	addSyscallPoint(101, "ptrace", nil)
 	addSyscallPoint(105, "setuid", nil)
 	addSyscallPoint(106, "setgid", nil)
 	addSyscallPoint(112, "setsid", nil)
+	addSyscallPoint(113, "setreuid", nil)
+	addSyscallPoint(114, "setregid", nil)
 	addSyscallPoint(117, "setresuid", nil)
 	addSyscallPoint(119, "setresgid", nil)
 	addSyscallPoint(161, "chroot", nil)
+	addSyscallPoint(155, "pivot_root", nil)
+	addSyscallPoint(165, "mount", nil)
+	addSyscallPoint(166, "umount2", nil)
+	addSyscallPoint(9, "mmap", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(10, "mprotect", nil)
 	addSyscallPoint(302, "prlimit64", nil)
 	addSyscallPoint(284, "eventfd", nil)
 	addSyscallPoint(290, "eventfd2", nil)
@@ -169,8 +217,226 @@ func init() {
 		},
 	})
 	addSyscallPoint(53, "socketpair", nil)
+	addSyscallPoint(64, "semget", nil)
+	addSyscallPoint(65, "semop", nil)
+	addSyscallPoint(68, "msgget", nil)
+	addSyscallPoint(69, "msgsnd", nil)
+	addSyscallPoint(70, "msgrcv", nil)
+	addSyscallPoint(240, "mq_open", nil)
+	addSyscallPoint(242, "mq_timedsend", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(243, "mq_timedreceive", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(48, "shutdown", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(51, "getsockname", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(52, "getpeername", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(54, "setsockopt", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(55, "getsockopt", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(73, "flock", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(132, "utime", nil)
+	addSyscallPoint(235, "utimes", nil)
+	addSyscallPoint(261, "futimesat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(280, "utimensat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(285, "fallocate", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(74, "fsync", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(75, "fdatasync", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(162, "sync", nil)
+	addSyscallPoint(306, "syncfs", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(82, "rename", nil)
+	addSyscallPoint(84, "rmdir", nil)
+	addSyscallPoint(87, "unlink", nil)
+	addSyscallPoint(263, "unlinkat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(264, "renameat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(133, "mknod", nil)
+	addSyscallPoint(259, "mknodat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(163, "acct", nil)
+	addSyscallPoint(135, "personality", nil)
+	addSyscallPoint(63, "uname", nil)
+	addSyscallPoint(99, "sysinfo", nil)
+	addSyscallPoint(17, "pread64", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallReadData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(19, "readv", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallReadData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(295, "preadv", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallReadData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(327, "preadv2", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallReadData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(1, "write", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallWriteData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(18, "pwrite64", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallWriteData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(20, "writev", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallWriteData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(296, "pwritev", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallWriteData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(328, "pwritev2", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallWriteData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(46, "sendmsg", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallWriteData,
+			Name: "data",
+		},
+	})
 
-	const lastSyscallInTable = 441
 	for i := 0; i <= lastSyscallInTable; i++ {
 		addRawSyscallPoint(uintptr(i))
 	}
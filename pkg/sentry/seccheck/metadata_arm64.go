@@ -23,6 +23,10 @@ func init() {
 			ID:   FieldSyscallPath,
 			Name: "fd_path",
 		},
+		{
+			ID:   FieldSyscallReadData,
+			Name: "data",
+		},
 	})
 	addSyscallPoint(57, "close", []FieldDesc{
 		{
@@ -37,17 +41,35 @@ func init() {
 			Name: "fd_path",
 		},
 	})
+	addSyscallPoint(206, "sendto", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallWriteData,
+			Name: "data",
+		},
+	})
 	addSyscallPoint(221, "execve", []FieldDesc{
 		{
 			ID:   FieldSyscallExecveEnvv,
 			Name: "envv",
 		},
+		{
+			ID:   FieldSyscallExecveAsync,
+			Name: "async_args",
+		},
 	})
 	addSyscallPoint(56, "openat", []FieldDesc{
 		{
 			ID:   FieldSyscallPath,
 			Name: "fd_path",
 		},
+		{
+			ID:   FieldSyscallOpenPathname,
+			Name: "pathname",
+		},
 	})
 	addSyscallPoint(281, "execveat", []FieldDesc{
 		{
@@ -58,6 +80,10 @@ func init() {
 			ID:   FieldSyscallExecveEnvv,
 			Name: "envv",
 		},
+		{
+			ID:   FieldSyscallExecveAsync,
+			Name: "async_args",
+		},
 	})
 	addSyscallPoint(49, "chdir", nil)
 	addSyscallPoint(50, "fchdir", []FieldDesc{
@@ -66,13 +92,26 @@ func init() {
 			Name: "fd_path",
 		},
 	})
+	addSyscallPoint(117, "ptrace", nil)
 	addSyscallPoint(146, "setuid", nil)
 	addSyscallPoint(144, "setgid", nil)
 	addSyscallPoint(157, "setsid", nil)
+	addSyscallPoint(145, "setreuid", nil)
+	addSyscallPoint(143, "setregid", nil)
 	addSyscallPoint(147, "setresuid", nil)
 	addSyscallPoint(149, "setresgid", nil)
 	addSyscallPoint(261, "prlimit64", nil)
 	addSyscallPoint(51, "chroot", nil)
+	addSyscallPoint(41, "pivot_root", nil)
+	addSyscallPoint(40, "mount", nil)
+	addSyscallPoint(39, "umount2", nil)
+	addSyscallPoint(222, "mmap", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(226, "mprotect", nil)
 	addSyscallPoint(23, "dup", []FieldDesc{
 		{
 			ID:   FieldSyscallPath,
@@ -145,8 +184,214 @@ func init() {
 		},
 	})
 	addSyscallPoint(199, "socketpair", nil)
+	addSyscallPoint(190, "semget", nil)
+	addSyscallPoint(193, "semop", nil)
+	addSyscallPoint(186, "msgget", nil)
+	addSyscallPoint(189, "msgsnd", nil)
+	addSyscallPoint(188, "msgrcv", nil)
+	addSyscallPoint(180, "mq_open", nil)
+	addSyscallPoint(182, "mq_timedsend", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(183, "mq_timedreceive", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(210, "shutdown", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(204, "getsockname", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(205, "getpeername", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(208, "setsockopt", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(209, "getsockopt", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(32, "flock", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(88, "utimensat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(47, "fallocate", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(82, "fsync", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(83, "fdatasync", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(81, "sync", nil)
+	addSyscallPoint(267, "syncfs", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(33, "mknodat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(35, "unlinkat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(38, "renameat", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+	})
+	addSyscallPoint(89, "acct", nil)
+	addSyscallPoint(92, "personality", nil)
+	addSyscallPoint(160, "uname", nil)
+	addSyscallPoint(179, "sysinfo", nil)
+	addSyscallPoint(67, "pread64", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallReadData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(65, "readv", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallReadData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(69, "preadv", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallReadData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(286, "preadv2", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallReadData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(64, "write", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallWriteData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(68, "pwrite64", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallWriteData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(66, "writev", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallWriteData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(70, "pwritev", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallWriteData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(287, "pwritev2", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallWriteData,
+			Name: "data",
+		},
+	})
+	addSyscallPoint(211, "sendmsg", []FieldDesc{
+		{
+			ID:   FieldSyscallPath,
+			Name: "fd_path",
+		},
+		{
+			ID:   FieldSyscallWriteData,
+			Name: "data",
+		},
+	})
 
-	const lastSyscallInTable = 441
 	for i := 0; i <= lastSyscallInTable; i++ {
 		addRawSyscallPoint(uintptr(i))
 	}
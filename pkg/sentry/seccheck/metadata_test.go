@@ -24,6 +24,15 @@ func TestSinkRegistration(t *testing.T) {
 	if _, ok := sinks["test"]; !ok {
 		t.Errorf("sink registration failed")
 	}
+	found := false
+	for _, name := range SinkNames() {
+		if name == "test" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("SinkNames() doesn't include registered sink: %v", SinkNames())
+	}
 
 	defer func() {
 		recover()
@@ -102,3 +111,44 @@ func TestPointRegistrationFields(t *testing.T) {
 		})
 	}
 }
+
+func TestPresets(t *testing.T) {
+	for _, name := range PresetNames() {
+		t.Run(name, func(t *testing.T) {
+			points, err := sessionPoints(&SessionConfig{Presets: []string{name}})
+			if err != nil {
+				t.Fatalf("sessionPoints(): %v", err)
+			}
+			if len(points) == 0 {
+				t.Fatalf("preset %q has no points", name)
+			}
+			if _, err := PointReqs(points); err != nil {
+				t.Errorf("PointReqs(): %v", err)
+			}
+		})
+	}
+}
+
+func TestPresetOverride(t *testing.T) {
+	conf := &SessionConfig{
+		Presets: []string{"security-essentials", "security-essentials"},
+		Points:  []PointConfig{{Name: "sentry/execve"}},
+	}
+	points, err := sessionPoints(conf)
+	if err != nil {
+		t.Fatalf("sessionPoints(): %v", err)
+	}
+	if want := len(Presets["security-essentials"]); len(points) != want {
+		t.Errorf("wrong number of points, want: %d, got: %d", want, len(points))
+	}
+	for _, pt := range points {
+		if pt.Name == "sentry/execve" && len(pt.OptionalFields) > 0 {
+			t.Errorf("preset configuration of %q was not overridden: %+v", pt.Name, pt)
+		}
+	}
+
+	conf.Presets = []string{"foo"}
+	if _, err := sessionPoints(conf); err == nil {
+		t.Errorf("sessionPoints() with invalid preset should fail")
+	}
+}
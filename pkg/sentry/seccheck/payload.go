@@ -0,0 +1,138 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+const (
+	// defaultPayloadMaxSize is the number of bytes captured from each buffer,
+	// unless PayloadConfig.MaxSize is set.
+	defaultPayloadMaxSize = 256
+
+	// maxPayloadMaxSize bounds PayloadConfig.MaxSize, since captured data is
+	// copied for every point.
+	maxPayloadMaxSize = 4096
+
+	// defaultPayloadRate is the sustained rate, in bytes per second, at which
+	// data is captured, unless PayloadConfig.Rate is set.
+	defaultPayloadRate = 64 << 10
+)
+
+// PayloadConfig configures the capture of data buffers, e.g. the data returned
+// by read(2) or passed to write(2), for points that request the "data"
+// optional field.
+type PayloadConfig struct {
+	// MaxSize is the number of bytes captured from each buffer. Defaults to
+	// 256 bytes and cannot exceed 4096 bytes.
+	MaxSize int `json:"max_size,omitempty"`
+	// Rate is the sustained rate, in bytes per second, at which data is
+	// captured across the sandbox. Data beyond this rate is dropped. Defaults
+	// to 64KiB/s.
+	Rate int `json:"rate,omitempty"`
+	// Redactors is the list of redactors applied to the captured data, in
+	// order. Redactors must be registered with RegisterRedactor.
+	Redactors []string `json:"redactors,omitempty"`
+}
+
+// Redactor modifies captured data before it's sent to sinks, e.g. to mask
+// secrets. It may modify data in place, and returns the data to send.
+type Redactor func(data []byte) []byte
+
+var redactors = map[string]Redactor{}
+
+// RegisterRedactor registers a new redactor to make it available in
+// PayloadConfig.Redactors.
+func RegisterRedactor(name string, redactor Redactor) {
+	if _, ok := redactors[name]; ok {
+		panic(fmt.Sprintf("Redactor %q already registered", name))
+	}
+	redactors[name] = redactor
+}
+
+// Payload captures data buffers according to a PayloadConfig.
+type Payload struct {
+	maxSize   int
+	limiter   *rate.Limiter
+	redactors []Redactor
+
+	// dropped is the number of bytes dropped due to rate limiting since the
+	// last capture.
+	dropped atomicbitops.Uint64
+}
+
+// newPayload returns a Payload for conf. A nil conf uses the default limits.
+func newPayload(conf *PayloadConfig) (*Payload, error) {
+	p := &Payload{maxSize: defaultPayloadMaxSize}
+	limit := defaultPayloadRate
+	if conf != nil {
+		if conf.MaxSize < 0 || conf.MaxSize > maxPayloadMaxSize {
+			return nil, fmt.Errorf("payload max_size %d must be between 0 and %d", conf.MaxSize, maxPayloadMaxSize)
+		}
+		if conf.MaxSize > 0 {
+			p.maxSize = conf.MaxSize
+		}
+		if conf.Rate < 0 {
+			return nil, fmt.Errorf("payload rate %d cannot be negative", conf.Rate)
+		}
+		if conf.Rate > 0 {
+			limit = conf.Rate
+		}
+		for _, name := range conf.Redactors {
+			redactor, ok := redactors[name]
+			if !ok {
+				return nil, fmt.Errorf("redactor %q not found", name)
+			}
+			p.redactors = append(p.redactors, redactor)
+		}
+	}
+	burst := limit
+	if burst < p.maxSize {
+		burst = p.maxSize
+	}
+	p.limiter = rate.NewLimiter(rate.Limit(limit), burst)
+	return p, nil
+}
+
+// Capture copies in up to MaxSize bytes of the first n bytes of src, and
+// applies the redactors to them. truncated is true if the data returned
+// doesn't hold all n bytes. dropped is the number of bytes dropped due to rate
+// limiting since the previous capture. If the capture itself is rate limited,
+// data is nil.
+func (p *Payload) Capture(ctx context.Context, src usermem.IOSequence, n int64) (data []byte, truncated bool, dropped uint64) {
+	size := n
+	if size > int64(p.maxSize) {
+		size = int64(p.maxSize)
+	}
+	if !p.limiter.AllowN(time.Now(), int(size)) {
+		p.dropped.Add(uint64(n))
+		return nil, false, 0
+	}
+
+	buf := make([]byte, size)
+	c, _ := src.CopyIn(ctx, buf)
+	data = buf[:c]
+	for _, redactor := range p.redactors {
+		data = redactor(data)
+	}
+	return data, int64(c) < n, p.dropped.Swap(0)
+}
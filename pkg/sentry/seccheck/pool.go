@@ -0,0 +1,65 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"google.golang.org/protobuf/proto"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// Messages of the most frequent syscall points are pooled to reduce allocations
+// and GC pressure when tracing at high rates. Whoever sends them to checkers
+// releases them once SendToCheckers returns, which is why Checkers must not
+// retain the messages passed to them.
+var (
+	openPool = sync.Pool{New: func() interface{} { return &pb.Open{} }}
+	readPool = sync.Pool{New: func() interface{} { return &pb.Read{} }}
+)
+
+// NewOpen returns an empty pb.Open, to be released with ReleaseMessage.
+func NewOpen() *pb.Open {
+	return openPool.Get().(*pb.Open)
+}
+
+// NewRead returns an empty pb.Read, to be released with ReleaseMessage.
+func NewRead() *pb.Read {
+	return readPool.Get().(*pb.Read)
+}
+
+// ReleaseMessage resets msg and returns it to its pool, if messages of its
+// type are pooled. msg must not be used afterwards.
+func ReleaseMessage(msg proto.Message) {
+	switch m := msg.(type) {
+	case *pb.Open:
+		m.Reset()
+		openPool.Put(m)
+	case *pb.Read:
+		m.Reset()
+		readPool.Put(m)
+	}
+}
+
+// ReleaseSyscallMessages releases the messages sent for the enter and exit
+// points of a syscall, either of which may be nil. The exit point may reuse
+// the message of the enter point, in which case it's only released once.
+func ReleaseSyscallMessages(enter, exit proto.Message) {
+	if enter != nil && enter != exit {
+		ReleaseMessage(enter)
+	}
+	if exit != nil {
+		ReleaseMessage(exit)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"fmt"
+	"sort"
+)
+
+// presetContextFields are the context fields collected by presets. They
+// identify the workload and the process that triggered the point, both in the
+// sandbox and as seen by the process.
+var presetContextFields = []string{"time", "container_id", "group_id", "thread_group_start_time", "ns_group_id", "process_name", "credentials"}
+
+// pathContextFields are the context fields collected by presets for points
+// with paths, which may be relative to the working directory.
+var pathContextFields = append([]string{"cwd"}, presetContextFields...)
+
+// pathPolicyPoints are the points of the path-policy preset.
+var pathPolicyPoints = append([]PointConfig{
+	{Name: "syscall/openat/enter", OptionalFields: []string{"fd_path", "pathname"}, ContextFields: pathContextFields},
+	{Name: "syscall/unlinkat/enter", OptionalFields: []string{"fd_path"}, ContextFields: pathContextFields},
+	{Name: "syscall/renameat/enter", OptionalFields: []string{"fd_path"}, ContextFields: pathContextFields},
+}, archPathPolicyPoints...)
+
+// egressPolicyPoints are the points of the egress-policy preset.
+var egressPolicyPoints = []PointConfig{
+	{Name: "syscall/connect/enter", ContextFields: presetContextFields},
+	{Name: "syscall/sendto/enter", ContextFields: presetContextFields},
+}
+
+// rawSyscallEnterPoints returns the raw enter points of all syscalls.
+func rawSyscallEnterPoints() []PointConfig {
+	points := make([]PointConfig, 0, lastSyscallInTable+1)
+	for i := 0; i <= lastSyscallInTable; i++ {
+		points = append(points, PointConfig{Name: fmt.Sprintf("syscall/sysno/%d/enter", i)})
+	}
+	return points
+}
+
+// Presets maps the name of each preset to the points it enables. Presets only
+// include points available on all architectures, except for the points that
+// archPathPolicyPoints adds, so that the same configuration can be used
+// everywhere. See SessionConfig.Presets.
+var Presets = map[string][]PointConfig{
+	// security-essentials covers process execution, privilege changes, and
+	// attempts to break out of the sandbox configuration.
+	"security-essentials": {
+		{Name: "container/start", ContextFields: presetContextFields},
+		{Name: "container/exec", ContextFields: presetContextFields},
+		{Name: "sentry/execve", OptionalFields: []string{"binary_info"}, ContextFields: pathContextFields},
+		{Name: "sentry/exit_notify_parent", ContextFields: presetContextFields},
+		{Name: "sentry/capability_denied", ContextFields: presetContextFields},
+		{Name: "sentry/seccomp", ContextFields: presetContextFields},
+		{Name: "sentry/policy_violation", ContextFields: presetContextFields},
+		{Name: "sentry/fileless_exec", ContextFields: presetContextFields},
+		{Name: "sentry/drift", ContextFields: presetContextFields},
+		{Name: "sentry/namespace_create", ContextFields: presetContextFields},
+		{Name: "sentry/exec_map", ContextFields: presetContextFields},
+		{Name: "syscall/setuid/enter", ContextFields: presetContextFields},
+		{Name: "syscall/setresuid/enter", ContextFields: presetContextFields},
+		{Name: "syscall/setgid/enter", ContextFields: presetContextFields},
+		{Name: "syscall/setresgid/enter", ContextFields: presetContextFields},
+		{Name: "syscall/setreuid/enter", ContextFields: presetContextFields},
+		{Name: "syscall/setregid/enter", ContextFields: presetContextFields},
+		{Name: "syscall/chroot/enter", ContextFields: presetContextFields},
+		{Name: "syscall/ptrace/enter", ContextFields: presetContextFields},
+		{Name: "syscall/mount/enter", ContextFields: presetContextFields},
+		{Name: "syscall/umount2/enter", ContextFields: presetContextFields},
+		{Name: "syscall/pivot_root/enter", ContextFields: presetContextFields},
+	},
+	// file-audit covers files being opened or created, including writes to
+	// synthetic files, e.g. in /proc, and operations sent to the gofer.
+	"file-audit": {
+		{Name: "sentry/file_open", ContextFields: append([]string{"cwd"}, presetContextFields...)},
+		{Name: "sentry/synthetic_file_write", ContextFields: presetContextFields},
+		{Name: "sentry/gofer_op", ContextFields: presetContextFields},
+		{Name: "syscall/openat/enter", OptionalFields: []string{"fd_path", "pathname"}, ContextFields: append([]string{"cwd"}, presetContextFields...)},
+		{Name: "syscall/mknodat/enter", ContextFields: append([]string{"cwd"}, presetContextFields...)},
+		{Name: "syscall/chdir/enter", ContextFields: append([]string{"cwd"}, presetContextFields...)},
+	},
+	// path-policy covers the operations checked by the policy sink,
+	// with the fields it needs to resolve paths.
+	"path-policy": pathPolicyPoints,
+	// egress-policy covers the destinations checked by the policy sink.
+	"egress-policy": egressPolicyPoints,
+	// exec-policy covers the binaries checked by the policy sink, including
+	// fileless executions and drift. Add the binary_sha256 field to check
+	// digests.
+	"exec-policy": {
+		{Name: "sentry/execve", ContextFields: pathContextFields},
+		{Name: "sentry/fileless_exec", ContextFields: presetContextFields},
+		{Name: "sentry/drift", ContextFields: presetContextFields},
+	},
+	// process-memory-policy covers the cross-process memory access checked
+	// by the policy sink.
+	"process-memory-policy": append([]PointConfig{
+		{Name: "syscall/ptrace/enter", ContextFields: presetContextFields},
+	}, pathPolicyPoints...),
+	// mount-policy covers the changes to the mount table checked by the
+	// policy sink, with the fields it needs to resolve paths.
+	"mount-policy": {
+		{Name: "syscall/mount/enter", ContextFields: pathContextFields},
+		{Name: "syscall/umount2/enter", ContextFields: pathContextFields},
+		{Name: "syscall/pivot_root/enter", ContextFields: pathContextFields},
+		{Name: "syscall/chroot/enter", ContextFields: pathContextFields},
+	},
+	// anomaly covers the metrics counted by the anomaly sink, and the alerts
+	// that it sends.
+	"anomaly": {
+		{Name: "syscall/openat/exit", ContextFields: presetContextFields},
+		{Name: "syscall/connect/enter", ContextFields: presetContextFields},
+		{Name: "syscall/sendto/enter", ContextFields: presetContextFields},
+		{Name: "sentry/execve", ContextFields: presetContextFields},
+		{Name: "sentry/anomaly_alert", ContextFields: presetContextFields},
+	},
+	// wx-policy covers the mappings checked by the policy sink for
+	// deny_writable_executable.
+	"wx-policy": {
+		{Name: "syscall/mmap/enter", ContextFields: presetContextFields},
+		{Name: "syscall/mprotect/enter", ContextFields: presetContextFields},
+	},
+	// learn covers all syscalls and the operations checked by the policy
+	// sink, for the learn sink to generate profiles from.
+	"learn": append(append(rawSyscallEnterPoints(), pathPolicyPoints...), egressPolicyPoints...),
+	// network-audit covers connections established, accepted, and listened
+	// on, and DNS queries.
+	"network-audit": {
+		{Name: "sentry/tcp_established", ContextFields: presetContextFields},
+		{Name: "sentry/listen", ContextFields: presetContextFields},
+		{Name: "sentry/dns_query", ContextFields: presetContextFields},
+		{Name: "syscall/socket/enter", ContextFields: presetContextFields},
+		{Name: "syscall/connect/enter", OptionalFields: []string{"fd_path"}, ContextFields: presetContextFields},
+		{Name: "syscall/bind/enter", OptionalFields: []string{"fd_path"}, ContextFields: presetContextFields},
+		{Name: "syscall/accept4/exit", OptionalFields: []string{"fd_path"}, ContextFields: presetContextFields},
+	},
+}
+
+// sessionPoints returns the points of conf, which are the points of its
+// presets followed by its own points. A point that is both in a preset and in
+// conf.Points uses the configuration from conf.Points.
+func sessionPoints(conf *SessionConfig) ([]PointConfig, error) {
+	if len(conf.Presets) == 0 {
+		return conf.Points, nil
+	}
+	explicit := make(map[string]struct{}, len(conf.Points))
+	for _, pt := range conf.Points {
+		explicit[pt.Name] = struct{}{}
+	}
+	var points []PointConfig
+	added := make(map[string]struct{})
+	for _, name := range conf.Presets {
+		preset, ok := Presets[name]
+		if !ok {
+			return nil, fmt.Errorf("preset %q not found, must be one of %v", name, PresetNames())
+		}
+		for _, pt := range preset {
+			if _, ok := explicit[pt.Name]; ok {
+				continue
+			}
+			if _, ok := added[pt.Name]; ok {
+				continue
+			}
+			added[pt.Name] = struct{}{}
+			points = append(points, pt)
+		}
+	}
+	return append(points, conf.Points...), nil
+}
+
+// PresetNames returns the names of all presets, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,28 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build amd64
+// +build amd64
+
+package seccheck
+
+// archPathPolicyPoints are the points of the path-policy preset for syscalls
+// that only exist on amd64. Other architectures only have their *at variants.
+var archPathPolicyPoints = []PointConfig{
+	{Name: "syscall/open/enter", OptionalFields: []string{"pathname"}, ContextFields: pathContextFields},
+	{Name: "syscall/creat/enter", OptionalFields: []string{"pathname"}, ContextFields: pathContextFields},
+	{Name: "syscall/unlink/enter", ContextFields: pathContextFields},
+	{Name: "syscall/rmdir/enter", ContextFields: pathContextFields},
+	{Name: "syscall/rename/enter", ContextFields: pathContextFields},
+}
@@ -0,0 +1,227 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"fmt"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// QuotaConfig limits the total amount of points of a session, e.g. to prevent
+// a forgotten debug session from running forever against a production
+// workload. Once a limit is exceeded, the session is deleted. Its sinks are
+// stopped, which makes them send their final summary, e.g. SessionClosed for
+// remote sinks, with the reason in StopReasoner.
+type QuotaConfig struct {
+	// MaxPoints is the number of points sent to the sinks of the session.
+	MaxPoints uint64 `json:"max_points,omitempty"`
+	// MaxBytes is the number of bytes sent by any sink of the session, as
+	// reported by CheckerStatus.ByteCount. Sinks that don't report it are not
+	// limited. It's checked periodically, so it may be exceeded slightly.
+	MaxBytes uint64 `json:"max_bytes,omitempty"`
+	// MaxDuration is how long the session runs, e.g. "1h".
+	MaxDuration string `json:"max_duration,omitempty"`
+}
+
+// StopReasoner can be implemented by Checkers to report why they are stopped,
+// e.g. in a final message to a remote process. StopReason is called right
+// before Stop, and only if there's a specific reason, e.g. the session exceeded
+// its quota.
+type StopReasoner interface {
+	StopReason(reason string)
+}
+
+// quotaBytesCheckInterval is how often, in points, the bytes sent by sinks are
+// checked against QuotaConfig.MaxBytes, since getting them from sinks is
+// relatively expensive.
+const quotaBytesCheckInterval = 64
+
+// quota enforces the QuotaConfig of a session.
+type quota struct {
+	maxPoints   uint64
+	maxBytes    uint64
+	maxDuration time.Duration
+
+	// checkers are the Checkers of the session, and exceed is called from its
+	// own goroutine when a limit is exceeded, with the reason. They're set by
+	// start, before points are sent.
+	checkers []Checker
+	exceed   func(reason string)
+
+	// timer enforces maxDuration. It's nil if there's no maxDuration.
+	timer *time.Timer
+
+	// points is the number of points sent so far.
+	points atomicbitops.Uint64
+
+	// exceeded is set to 1 once a limit is exceeded, and once ensures that
+	// exceed is called only once.
+	exceeded atomicbitops.Uint32
+	once     sync.Once
+}
+
+// newQuota returns the quota for conf, which may be nil.
+func newQuota(conf *QuotaConfig) (*quota, error) {
+	if conf == nil {
+		return nil, nil
+	}
+	q := &quota{maxPoints: conf.MaxPoints, maxBytes: conf.MaxBytes}
+	if len(conf.MaxDuration) > 0 {
+		duration, err := time.ParseDuration(conf.MaxDuration)
+		if err != nil {
+			return nil, fmt.Errorf("quota max_duration: %w", err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("quota max_duration %v must be positive", duration)
+		}
+		q.maxDuration = duration
+	}
+	if q.maxPoints == 0 && q.maxBytes == 0 && q.maxDuration == 0 {
+		return nil, fmt.Errorf("quota must set at least one of max_points, max_bytes, or max_duration")
+	}
+	return q, nil
+}
+
+// start starts enforcing the quota for the given checkers. exceed is called
+// when a limit is exceeded.
+func (q *quota) start(checkers []Checker, exceed func(reason string)) {
+	q.checkers = checkers
+	q.exceed = exceed
+	if q.maxDuration > 0 {
+		q.timer = time.AfterFunc(q.maxDuration, func() {
+			q.setExceeded(fmt.Sprintf("max_duration %v reached", q.maxDuration))
+		})
+	}
+}
+
+// stop stops enforcing the quota, e.g. because the session was deleted.
+func (q *quota) stop() {
+	if q.timer != nil {
+		q.timer.Stop()
+	}
+}
+
+// allow counts a point and returns true if it can be sent.
+func (q *quota) allow() bool {
+	if q.exceeded.Load() != 0 {
+		return false
+	}
+	n := q.points.Add(1)
+	if q.maxPoints > 0 && n > q.maxPoints {
+		q.setExceeded(fmt.Sprintf("max_points %d reached", q.maxPoints))
+		return false
+	}
+	if q.maxBytes > 0 && n%quotaBytesCheckInterval == 0 {
+		for _, c := range q.checkers {
+			if c.Status().ByteCount >= q.maxBytes {
+				q.setExceeded(fmt.Sprintf("max_bytes %d reached by sink %q", q.maxBytes, c.Name()))
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (q *quota) setExceeded(reason string) {
+	q.once.Do(func() {
+		q.exceeded.Store(1)
+		// exceed deletes the session, which stops its sinks and may block, so
+		// it's not called from the goroutine that sent the point.
+		go q.exceed(reason)
+	})
+}
+
+// sessionLimiter enforces the rate limits and quota of a session. It's shared
+// by all Checkers of the session, and replaced when the session is updated.
+type sessionLimiter struct {
+	// rate and quota may be nil.
+	rate  *rateLimiter
+	quota *quota
+}
+
+// newSessionLimiter returns the sessionLimiter for conf, with the given
+// points. It returns nil if the session has no limits.
+func newSessionLimiter(conf *SessionConfig, points []PointConfig) (*sessionLimiter, error) {
+	rate, err := newRateLimiter(conf.RateLimit, points)
+	if err != nil {
+		return nil, err
+	}
+	quota, err := newQuota(conf.Quota)
+	if err != nil {
+		return nil, err
+	}
+	if rate == nil && quota == nil {
+		return nil, nil
+	}
+	return &sessionLimiter{rate: rate, quota: quota}, nil
+}
+
+// start starts enforcing the limits of session name, whose Checkers are
+// checkers.
+func (l *sessionLimiter) start(name string, checkers []Checker) {
+	if l == nil || l.quota == nil {
+		return
+	}
+	l.quota.start(checkers, func(reason string) {
+		stopExceeded(name, l, reason)
+	})
+}
+
+// stop stops enforcing the limits. l may be nil.
+func (l *sessionLimiter) stop() {
+	if l != nil && l.quota != nil {
+		l.quota.stop()
+	}
+}
+
+// allow returns true if point p can be sent, and counts it against the limits.
+func (l *sessionLimiter) allow(p Point) bool {
+	if l.rate != nil && !l.rate.allow(p) {
+		return false
+	}
+	return l.quota == nil || l.quota.allow()
+}
+
+// droppedCount returns the number of points dropped due to rate limiting. l
+// may be nil.
+func (l *sessionLimiter) droppedCount() uint64 {
+	if l == nil {
+		return 0
+	}
+	return l.rate.droppedCount()
+}
+
+// stopExceeded deletes session name because it exceeded the quota enforced by
+// limiter, unless the session was updated or replaced since.
+func stopExceeded(name string, limiter *sessionLimiter, reason string) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	s, ok := sessions[name]
+	if !ok || s.limiter != limiter {
+		return
+	}
+	log.Infof("Trace session %q exceeded its quota, deleting it: %s", name, reason)
+	for _, checker := range s.checkers {
+		if r, ok := checker.(StopReasoner); ok {
+			r.StopReason(reason)
+		}
+	}
+	_ = deleteLocked(name)
+}
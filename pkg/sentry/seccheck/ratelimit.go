@@ -0,0 +1,123 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"golang.org/x/time/rate"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+)
+
+// RateLimitConfig limits the rate at which points are sent to the sinks of a
+// session, to cap the overhead that the session imposes on the workload.
+// Points beyond the limit are dropped. Dropped points never fail the operation
+// that triggered them, regardless of PointConfig.ErrorPolicy.
+type RateLimitConfig struct {
+	// Rate is the sustained number of points per second.
+	Rate float64 `json:"rate"`
+	// Burst is the number of points that can be sent at once, above Rate.
+	// Defaults to Rate, rounded up.
+	Burst int `json:"burst,omitempty"`
+}
+
+// rateLimiter enforces the rate limits of a session, see sessionLimiter.
+type rateLimiter struct {
+	// session is the budget shared by all points that don't have their own.
+	// It's nil if the session isn't limited.
+	session *rate.Limiter
+
+	// points are the budgets of the points that override the session's, see
+	// PointConfig.RateLimit. It's immutable.
+	points map[Point]*rate.Limiter
+
+	// dropped is the number of points dropped due to rate limiting.
+	dropped atomicbitops.Uint64
+}
+
+// newLimiter returns a token bucket for conf.
+func newLimiter(conf *RateLimitConfig) (*rate.Limiter, error) {
+	if conf.Rate <= 0 || math.IsInf(conf.Rate, 0) || math.IsNaN(conf.Rate) {
+		return nil, fmt.Errorf("rate limit %v must be a positive number", conf.Rate)
+	}
+	if conf.Burst < 0 {
+		return nil, fmt.Errorf("rate limit burst %d cannot be negative", conf.Burst)
+	}
+	burst := conf.Burst
+	if burst == 0 {
+		burst = int(math.Ceil(conf.Rate))
+	}
+	return rate.NewLimiter(rate.Limit(conf.Rate), burst), nil
+}
+
+// newRateLimiter returns the rateLimiter for a session with the given rate
+// limit and points. It returns nil if neither the session nor its points are
+// limited.
+func newRateLimiter(conf *RateLimitConfig, points []PointConfig) (*rateLimiter, error) {
+	l := &rateLimiter{}
+	if conf != nil {
+		limiter, err := newLimiter(conf)
+		if err != nil {
+			return nil, err
+		}
+		l.session = limiter
+	}
+	for _, ptConfig := range points {
+		if ptConfig.RateLimit == nil {
+			continue
+		}
+		desc, err := findPointDesc(ptConfig.Name)
+		if err != nil {
+			return nil, err
+		}
+		limiter, err := newLimiter(ptConfig.RateLimit)
+		if err != nil {
+			return nil, fmt.Errorf("point %q: %w", ptConfig.Name, err)
+		}
+		if l.points == nil {
+			l.points = make(map[Point]*rate.Limiter)
+		}
+		l.points[desc.ID] = limiter
+	}
+	if l.session == nil && l.points == nil {
+		return nil, nil
+	}
+	return l, nil
+}
+
+// allow returns true if point p can be sent, and consumes its budget.
+// Otherwise, the point is counted as dropped.
+func (l *rateLimiter) allow(p Point) bool {
+	limiter, ok := l.points[p]
+	if !ok {
+		limiter = l.session
+	}
+	if limiter == nil || limiter.AllowN(time.Now(), 1) {
+		return true
+	}
+	l.dropped.Add(1)
+	return false
+}
+
+// droppedCount returns the number of points dropped due to rate limiting. l
+// may be nil.
+func (l *rateLimiter) droppedCount() uint64 {
+	if l == nil {
+		return 0
+	}
+	return l.dropped.Load()
+}
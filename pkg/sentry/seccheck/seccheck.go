@@ -17,9 +17,15 @@
 package seccheck
 
 import (
+	"fmt"
+	"time"
+
 	"google.golang.org/protobuf/proto"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
 	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 	"gvisor.dev/gvisor/pkg/sync"
 )
@@ -70,6 +76,11 @@ func (fm *FieldMask) Contains(field Field) bool {
 	return fm.mask&(1<<field) != 0
 }
 
+// ContainsAll returns true if fm contains all Fields in other.
+func (fm *FieldMask) ContainsAll(other FieldMask) bool {
+	return fm.mask&other.mask == other.mask
+}
+
 // Add adds a Field to the mask.
 func (fm *FieldMask) Add(field Field) {
 	fm.mask |= 1 << field
@@ -88,13 +99,15 @@ func (fm *FieldMask) Empty() bool {
 // A Checker performs security checks at checkpoints.
 //
 // Each Checker method X is called at checkpoint X; if the method may return a
-// non-nil error and does so, it causes the checked operation to fail
-// immediately (without calling subsequent Checkers) and return the error. The
-// info argument contains information relevant to the check. The mask argument
+// non-nil error and does so, the error is handled according to the error
+// policy of the checkpoint, see PointReq.FailClosed, unless it denies the operation, see IsDenied. The info argument contains information relevant to the check. The mask argument
 // indicates what fields in info are valid; the mask should usually be a
 // superset of fields requested by the Checker's corresponding PointReq, but
 // may be missing requested fields in some cases (e.g. if the Checker is
 // registered concurrently with invocations of checkpoints).
+//
+// Checkers must not retain info, or any message passed to them, after the
+// method returns, since it may be reused for other points, see ReleaseMessage.
 type Checker interface {
 	// Name return the checker name.
 	Name() string
@@ -107,17 +120,107 @@ type Checker interface {
 	Execve(ctx context.Context, fields FieldSet, info *pb.ExecveInfo) error
 	ExitNotifyParent(ctx context.Context, fields FieldSet, info *pb.ExitNotifyParentInfo) error
 	TaskExit(context.Context, FieldSet, *pb.TaskExit) error
+	SignalDeliver(context.Context, FieldSet, *pb.SignalDeliverInfo) error
+	OOM(context.Context, FieldSet, *pb.OOMInfo) error
+	Checkpoint(context.Context, FieldSet, *pb.CheckpointInfo) error
+	Restore(context.Context, FieldSet, *pb.RestoreInfo) error
+	CoreDump(context.Context, FieldSet, *pb.CoreDumpInfo) error
+	Seccomp(context.Context, FieldSet, *pb.SeccompInfo) error
+	CapabilityDenied(context.Context, FieldSet, *pb.CapabilityDeniedInfo) error
+	NamespaceCreate(context.Context, FieldSet, *pb.NamespaceCreateInfo) error
+	ExecMap(context.Context, FieldSet, *pb.ExecMapInfo) error
+	SyntheticFileWrite(context.Context, FieldSet, *pb.SyntheticFileWriteInfo) error
+	TCPEstablished(context.Context, FieldSet, *pb.TCPEstablishedInfo) error
+	DNSQuery(context.Context, FieldSet, *pb.DNSQueryInfo) error
+	Listen(context.Context, FieldSet, *pb.ListenInfo) error
+	GoferOp(context.Context, FieldSet, *pb.GoferOpInfo) error
+	FileOpen(context.Context, FieldSet, *pb.FileOpenInfo) error
+	TTYData(context.Context, FieldSet, *pb.TTYDataInfo) error
+	MajorFault(context.Context, FieldSet, *pb.MajorFaultInfo) error
+	RLimitBreach(context.Context, FieldSet, *pb.RLimitBreachInfo) error
+	CPUThrottle(context.Context, FieldSet, *pb.CPUThrottleInfo) error
+	PolicyViolation(context.Context, FieldSet, *pb.PolicyViolationInfo) error
+	FilelessExec(context.Context, FieldSet, *pb.FilelessExecInfo) error
+	AnomalyAlert(context.Context, FieldSet, *pb.AnomalyAlertInfo) error
+	Drift(context.Context, FieldSet, *pb.DriftInfo) error
 
 	ContainerStart(context.Context, FieldSet, *pb.Start) error
+	ContainerStop(context.Context, FieldSet, *pb.Stop) error
+	ContainerPause(context.Context, FieldSet, *pb.Pause) error
+	ContainerResume(context.Context, FieldSet, *pb.Resume) error
+	ContainerExec(context.Context, FieldSet, *pb.Exec) error
 
 	Syscall(context.Context, FieldSet, *pb.ContextData, pb.MessageType, proto.Message) error
 	RawSyscall(context.Context, FieldSet, *pb.Syscall) error
 }
 
+// Reloader is implemented by checkers whose configuration can be replaced
+// while they run, see Update.
+type Reloader interface {
+	// Reload atomically replaces the configuration of the checker with
+	// config, in the format of SinkConfig.Config. The checker keeps its
+	// previous configuration if config is invalid.
+	Reload(config map[string]interface{}) error
+}
+
+// Reconnecter is implemented by checkers whose connection to a remote process
+// can be replaced while they run, e.g. after the remote process restarted, see
+// Reconnect.
+type Reconnecter interface {
+	// Reconnect replaces the connection of the checker with endpoint, which
+	// was created by the Setup function of the sink with config, in the
+	// format of SinkConfig.Config. The checker takes ownership of endpoint
+	// only if it returns nil.
+	Reconnect(config map[string]interface{}, endpoint *fd.FD) error
+}
+
 // CheckerStatus represents stats about each checker instance.
 type CheckerStatus struct {
 	// DroppedCount is the number of trace points dropped.
 	DroppedCount uint64
+	// PointCount is the number of trace points processed. It's only reported
+	// by some sinks.
+	PointCount uint64
+	// ByteCount is the size of the trace points processed. It's only reported
+	// by some sinks.
+	ByteCount uint64
+	// Metrics are aggregates computed from the trace points processed. They're
+	// only reported by some sinks.
+	Metrics []MetricSample `json:",omitempty"`
+	// State is the state of the connection to the remote process, see
+	// SinkConnected. It's only reported by sinks that send trace points to a
+	// remote process.
+	State string `json:",omitempty"`
+	// QueueDepth is the number of trace points waiting to be sent. It's only
+	// reported by sinks that queue trace points.
+	QueueDepth uint64
+	// LastError is the last error that caused trace points to be dropped, and
+	// LastErrorTimeNs is when it happened.
+	LastError       string `json:",omitempty"`
+	LastErrorTimeNs int64  `json:",omitempty"`
+}
+
+// States of the connection to the remote process, see CheckerStatus.State.
+const (
+	// SinkConnected means that trace points are being sent.
+	SinkConnected = "connected"
+	// SinkDisconnected means that the remote process went away. Trace points
+	// are queued, up to the size of the queue of the sink, or dropped if it
+	// doesn't have one, until the sink is reconnected, see Reconnect.
+	SinkDisconnected = "disconnected"
+)
+
+// MetricSample is a single sample of a metric, in the Prometheus data model.
+type MetricSample struct {
+	// Family is the name of the metric family that the sample belongs to.
+	Family string
+	// Type is the Prometheus type of the family, e.g. "counter" or "histogram".
+	Type string
+	// Name is the name of the sample. It's the same as Family, except for
+	// histograms, e.g. Family+"_bucket".
+	Name   string
+	Labels map[string]string
+	Value  uint64
 }
 
 // CheckerDefaults may be embedded by implementations of Checker to obtain
@@ -163,11 +266,146 @@ func (CheckerDefaults) ContainerStart(context.Context, FieldSet, *pb.Start) erro
 	return nil
 }
 
+// ContainerStop implements Checker.ContainerStop.
+func (CheckerDefaults) ContainerStop(context.Context, FieldSet, *pb.Stop) error {
+	return nil
+}
+
+// ContainerPause implements Checker.ContainerPause.
+func (CheckerDefaults) ContainerPause(context.Context, FieldSet, *pb.Pause) error {
+	return nil
+}
+
+// ContainerResume implements Checker.ContainerResume.
+func (CheckerDefaults) ContainerResume(context.Context, FieldSet, *pb.Resume) error {
+	return nil
+}
+
+// ContainerExec implements Checker.ContainerExec.
+func (CheckerDefaults) ContainerExec(context.Context, FieldSet, *pb.Exec) error {
+	return nil
+}
+
 // TaskExit implements Checker.TaskExit.
 func (CheckerDefaults) TaskExit(context.Context, FieldSet, *pb.TaskExit) error {
 	return nil
 }
 
+// SignalDeliver implements Checker.SignalDeliver.
+func (CheckerDefaults) SignalDeliver(context.Context, FieldSet, *pb.SignalDeliverInfo) error {
+	return nil
+}
+
+// OOM implements Checker.OOM.
+func (CheckerDefaults) OOM(context.Context, FieldSet, *pb.OOMInfo) error {
+	return nil
+}
+
+// Checkpoint implements Checker.Checkpoint.
+func (CheckerDefaults) Checkpoint(context.Context, FieldSet, *pb.CheckpointInfo) error {
+	return nil
+}
+
+// Restore implements Checker.Restore.
+func (CheckerDefaults) Restore(context.Context, FieldSet, *pb.RestoreInfo) error {
+	return nil
+}
+
+// CoreDump implements Checker.CoreDump.
+func (CheckerDefaults) CoreDump(context.Context, FieldSet, *pb.CoreDumpInfo) error {
+	return nil
+}
+
+// Seccomp implements Checker.Seccomp.
+func (CheckerDefaults) Seccomp(context.Context, FieldSet, *pb.SeccompInfo) error {
+	return nil
+}
+
+// CapabilityDenied implements Checker.CapabilityDenied.
+func (CheckerDefaults) CapabilityDenied(context.Context, FieldSet, *pb.CapabilityDeniedInfo) error {
+	return nil
+}
+
+// NamespaceCreate implements Checker.NamespaceCreate.
+func (CheckerDefaults) NamespaceCreate(context.Context, FieldSet, *pb.NamespaceCreateInfo) error {
+	return nil
+}
+
+// ExecMap implements Checker.ExecMap.
+func (CheckerDefaults) ExecMap(context.Context, FieldSet, *pb.ExecMapInfo) error {
+	return nil
+}
+
+// SyntheticFileWrite implements Checker.SyntheticFileWrite.
+func (CheckerDefaults) SyntheticFileWrite(context.Context, FieldSet, *pb.SyntheticFileWriteInfo) error {
+	return nil
+}
+
+// TCPEstablished implements Checker.TCPEstablished.
+func (CheckerDefaults) TCPEstablished(context.Context, FieldSet, *pb.TCPEstablishedInfo) error {
+	return nil
+}
+
+// DNSQuery implements Checker.DNSQuery.
+func (CheckerDefaults) DNSQuery(context.Context, FieldSet, *pb.DNSQueryInfo) error {
+	return nil
+}
+
+// Listen implements Checker.Listen.
+func (CheckerDefaults) Listen(context.Context, FieldSet, *pb.ListenInfo) error {
+	return nil
+}
+
+// GoferOp implements Checker.GoferOp.
+func (CheckerDefaults) GoferOp(context.Context, FieldSet, *pb.GoferOpInfo) error {
+	return nil
+}
+
+// FileOpen implements Checker.FileOpen.
+func (CheckerDefaults) FileOpen(context.Context, FieldSet, *pb.FileOpenInfo) error {
+	return nil
+}
+
+// TTYData implements Checker.TTYData.
+func (CheckerDefaults) TTYData(context.Context, FieldSet, *pb.TTYDataInfo) error {
+	return nil
+}
+
+// MajorFault implements Checker.MajorFault.
+func (CheckerDefaults) MajorFault(context.Context, FieldSet, *pb.MajorFaultInfo) error {
+	return nil
+}
+
+// RLimitBreach implements Checker.RLimitBreach.
+func (CheckerDefaults) RLimitBreach(context.Context, FieldSet, *pb.RLimitBreachInfo) error {
+	return nil
+}
+
+// CPUThrottle implements Checker.CPUThrottle.
+func (CheckerDefaults) CPUThrottle(context.Context, FieldSet, *pb.CPUThrottleInfo) error {
+	return nil
+}
+
+// PolicyViolation implements Checker.PolicyViolation.
+func (CheckerDefaults) PolicyViolation(context.Context, FieldSet, *pb.PolicyViolationInfo) error {
+	return nil
+}
+
+// FilelessExec implements Checker.FilelessExec.
+func (CheckerDefaults) FilelessExec(context.Context, FieldSet, *pb.FilelessExecInfo) error {
+	return nil
+}
+
+// AnomalyAlert implements Checker.AnomalyAlert.
+func (CheckerDefaults) AnomalyAlert(context.Context, FieldSet, *pb.AnomalyAlertInfo) error {
+	return nil
+}
+
+// Drift implements Checker.Drift.
+func (CheckerDefaults) Drift(context.Context, FieldSet, *pb.DriftInfo) error {
+	return nil
+}
+
 // RawSyscall implements Checker.RawSyscall.
 func (CheckerDefaults) RawSyscall(context.Context, FieldSet, *pb.Syscall) error {
 	return nil
@@ -183,6 +421,75 @@ func (CheckerDefaults) Syscall(context.Context, FieldSet, *pb.ContextData, pb.Me
 type PointReq struct {
 	Pt     Point
 	Fields FieldSet
+
+	// FailClosed makes errors returned by the Checker at Pt, e.g. because the
+	// point couldn't be sent, cause the checked operation to fail immediately
+	// (without calling subsequent Checkers) and return the error. Otherwise,
+	// errors are logged and ignored.
+	FailClosed bool
+}
+
+// ErrDenied is returned by Checkers that deny the operation that triggered a
+// point, e.g. on behalf of a policy engine, rather than fail to process the
+// point. Regardless of the error policy of the point, it fails the operation
+// with EPERM, without calling subsequent Checkers. Only points generated
+// before their operation takes effect can fail it, i.e. sentry/execve,
+// sentry/clone and syscall enter points.
+var ErrDenied = linuxerr.EPERM
+
+// ErrDeniedUnreachable is like ErrDenied, but fails the operation with
+// ENETUNREACH, for Checkers that deny network operations as if their
+// destination was unreachable.
+var ErrDeniedUnreachable = linuxerr.ENETUNREACH
+
+// ViolationAction is the action taken on a Violation.
+type ViolationAction int
+
+const (
+	// ViolationDeny fails the operation.
+	ViolationDeny ViolationAction = iota
+	// ViolationKillContainer fails the operation and kills the container that
+	// performed it.
+	ViolationKillContainer
+	// ViolationKillSandbox fails the operation and kills the whole sandbox.
+	ViolationKillSandbox
+	// ViolationPauseContainer fails the operation and pauses all tasks in the
+	// container that performed it, keeping their memory and files intact for
+	// inspection until the container is resumed or killed.
+	ViolationPauseContainer
+)
+
+// Violation is returned by Checkers when the operation that triggered a point
+// violates a rule. The violation is reported to sentry/policy_violation and,
+// unless the rule is audit-only, the operation is denied like with ErrDenied
+// and Action is taken.
+type Violation struct {
+	// Rule describes the rule that was violated.
+	Rule string
+	// Reason describes the operation that violated the rule.
+	Reason string
+	// Action is taken unless Audit is true.
+	Action ViolationAction
+	// Audit is true if the rule is audit-only, in which case the operation is
+	// allowed.
+	Audit bool
+	// Err is the error that the operation fails with, see IsDenied. It's
+	// ErrDenied if nil.
+	Err error
+}
+
+// Error implements error.
+func (v *Violation) Error() string {
+	return fmt.Sprintf("policy violation: %s: %s", v.Rule, v.Reason)
+}
+
+// IsDenied returns true if err denies the operation that triggered a point,
+// see ErrDenied and Violation.
+func IsDenied(err error) bool {
+	if v, ok := err.(*Violation); ok {
+		return !v.Audit
+	}
+	return err == ErrDenied || err == ErrDeniedUnreachable
 }
 
 // Global is the method receiver of all seccheck functions.
@@ -200,55 +507,301 @@ type State struct {
 	// Mutation of enabledPoints is serialized by registrationMu.
 	enabledPoints [numPointBitmaskUint32s]atomicbitops.Uint32
 
-	// registrationSeq supports store-free atomic reads of registeredCheckers.
+	// syscallsEnabled is 1 if any syscall checkpoint is set in enabledPoints,
+	// so that the syscall path costs a single load when none is.
+	//
+	// Mutation of syscallsEnabled is serialized by registrationMu.
+	syscallsEnabled atomicbitops.Uint32
+
+	// enabledSyscalls is indexed by syscall number, and has bit 1<<typ set
+	// for each SyscallType whose point is set in enabledPoints for that
+	// syscall. It lets SyscallEnabled check a syscall point with a single
+	// indexed load instead of computing the point.
+	//
+	// Mutation of enabledSyscalls is serialized by registrationMu.
+	enabledSyscalls [syscallsMax]atomicbitops.Uint32
+
+	// registrationSeq supports store-free atomic reads of checkers and
+	// pointFields.
 	registrationSeq sync.SeqCount
 
 	// checkers is the set of all registered Checkers in order of execution.
+	// Each one is a *pointChecker, which has the checkpoints it executes at.
 	//
 	// checkers is accessed using instantiations of SeqAtomic functions.
 	// Mutation of checkers is serialized by registrationMu.
 	checkers []Checker
 
+	// pointFields is the union of the FieldSets requested by all Checkers for
+	// each checkpoint. The map is never modified once it's stored.
+	//
+	// pointFields is accessed using instantiations of SeqAtomic functions.
+	// Mutation of pointFields is serialized by registrationMu.
 	pointFields map[Point]FieldSet
+
+	// payload captures data buffers for points that request it.
+	//
+	// Mutation of payload is serialized by registrationMu.
+	payload *Payload
+
+	// execArgs trims the arguments and environment reported by sentry/execve.
+	// It's nil if they're reported in full.
+	//
+	// Mutation of execArgs is serialized by registrationMu.
+	execArgs *ExecArgs
+
+	// retireMu serializes the retirement of Checkers that are unregistered,
+	// see retireLocked. It's not held by registrationMu, so that Checkers can
+	// be registered while others are stopped.
+	retireMu sync.Mutex
+
+	// epoch is incremented when Checkers are retired.
+	epoch atomicbitops.Uint32
+
+	// dispatches counts the calls to SendToCheckers in progress, indexed by
+	// the parity of the epoch that they started in.
+	dispatches [2]atomicbitops.Int64
 }
 
-// AppendChecker registers the given Checker to execute at checkpoints. The
-// Checker will execute after all previously-registered Checkers, and only if
-// those Checkers return a nil error.
-func (s *State) AppendChecker(c Checker, reqs []PointReq) {
-	s.registrationMu.Lock()
-	defer s.registrationMu.Unlock()
+// pointMask is a set of checkpoints.
+type pointMask [numPointBitmaskUint32s]uint32
 
-	s.appendCheckerLocked(c)
-	if s.pointFields == nil {
-		s.pointFields = make(map[Point]FieldSet)
-	}
+func (m *pointMask) add(p Point) {
+	m[p/32] |= uint32(1) << (p % 32)
+}
+
+func (m *pointMask) contains(p Point) bool {
+	word, bit := p/32, p%32
+	return int(word) < len(m) && m[word]&(uint32(1)<<bit) != 0
+}
+
+// pointChecker is a registered Checker, with the checkpoints it executes at.
+// It's immutable once registered.
+type pointChecker struct {
+	Checker
+
+	reqs    []PointReq
+	enabled pointMask
+
+	// failClosed are the checkpoints where errors from the Checker are
+	// returned, see PointReq.FailClosed.
+	failClosed pointMask
+
+	// containers is set when the Checker only executes for some containers.
+	// It maps container IDs to the checkpoints the Checker executes at for
+	// that container, which are a subset of enabled. Points that are not
+	// generated by a container, e.g. checkpoint, are not affected.
+	containers map[string]pointMask
+
+	// limiter enforces the rate limits and quota of the session that the
+	// Checker belongs to. It's shared by all Checkers of the session, and may
+	// be nil.
+	limiter *sessionLimiter
+}
+
+func newPointChecker(c Checker, reqs []PointReq, containers map[string]pointMask, limiter *sessionLimiter) *pointChecker {
+	pc := &pointChecker{Checker: c, reqs: reqs, containers: containers, limiter: limiter}
 	for _, req := range reqs {
-		word, bit := req.Pt/32, req.Pt%32
-		s.enabledPoints[word].Store(s.enabledPoints[word].RacyLoad() | (uint32(1) << bit))
+		pc.enabled.add(req.Pt)
+		if req.FailClosed {
+			pc.failClosed.add(req.Pt)
+		}
+	}
+	return pc
+}
 
-		s.pointFields[req.Pt] = req.Fields
+// enabledAt returns true if the Checker executes at checkpoint p for points
+// generated by container cid, or not generated by a container if cid is empty.
+func (pc *pointChecker) enabledAt(cid string, p Point) bool {
+	if !pc.enabled.contains(p) {
+		return false
+	}
+	if pc.containers == nil || len(cid) == 0 {
+		return true
 	}
+	mask, ok := pc.containers[cid]
+	return ok && mask.contains(p)
 }
 
-func (s *State) clearCheckers() {
+// AppendChecker registers the given Checker to execute at the checkpoints in
+// reqs. The Checker will execute after all previously-registered Checkers, and
+// only if those Checkers didn't fail the checked operation, see
+// PointReq.FailClosed.
+func (s *State) AppendChecker(c Checker, reqs []PointReq) {
+	s.appendScopedChecker(c, reqs, nil, nil)
+}
+
+// appendScopedChecker is like AppendChecker, but the Checker only executes for
+// the containers in containers, see pointChecker.containers. containers may be
+// nil to execute for all containers. Points are rate limited by limiter, which
+// may be nil.
+func (s *State) appendScopedChecker(c Checker, reqs []PointReq, containers map[string]pointMask, limiter *sessionLimiter) {
 	s.registrationMu.Lock()
 	defer s.registrationMu.Unlock()
 
+	s.appendCheckerLocked(newPointChecker(c, reqs, containers, limiter))
+	s.updatePointsLocked()
+}
+
+func (s *State) clearCheckers() {
+	s.retireMu.Lock()
+	defer s.retireMu.Unlock()
+
+	s.registrationMu.Lock()
 	for i := range s.enabledPoints {
 		s.enabledPoints[i].Store(0)
 	}
-	s.pointFields = nil
+	s.syscallsEnabled.Store(0)
+	for i := range s.enabledSyscalls {
+		s.enabledSyscalls[i].Store(0)
+	}
+	s.payload = nil
+	s.execArgs = nil
 
 	oldCheckers := s.getCheckers()
 	s.registrationSeq.BeginWrite()
 	s.checkers = nil
+	s.pointFields = nil
 	s.registrationSeq.EndWrite()
-	for _, checker := range oldCheckers {
+	s.registrationMu.Unlock()
+
+	s.retireLocked(oldCheckers)
+}
+
+// removeCheckers unregisters and stops the given Checkers. Points are not
+// blocked while the Checkers are stopped.
+func (s *State) removeCheckers(remove []Checker) {
+	s.retireMu.Lock()
+	defer s.retireMu.Unlock()
+
+	s.registrationMu.Lock()
+	// Readers may still hold the old slice, so a new one is built.
+	var checkers []Checker
+	for _, c := range s.getCheckers() {
+		if !containsChecker(remove, c.(*pointChecker).Checker) {
+			checkers = append(checkers, c)
+		}
+	}
+	s.registrationSeq.BeginWrite()
+	s.checkers = checkers
+	s.registrationSeq.EndWrite()
+	s.updatePointsLocked()
+	s.registrationMu.Unlock()
+
+	s.retireLocked(remove)
+}
+
+// retireLocked waits for the calls to SendToCheckers that may still use
+// Checkers that were unregistered to return, and then stops the given
+// Checkers. Calls that start afterwards use the new set of Checkers, so points
+// are never blocked.
+//
+// Preconditions:
+//   - s.retireMu must be locked.
+//   - The Checkers must have been unregistered.
+func (s *State) retireLocked(stop []Checker) {
+	old := s.epoch.Add(1) - 1
+	for s.dispatches[old%2].Load() != 0 {
+		time.Sleep(retirePollInterval)
+	}
+	for _, checker := range stop {
 		checker.Stop()
 	}
 }
 
+// retirePollInterval is how often retireLocked checks for calls to
+// SendToCheckers to return.
+const retirePollInterval = time.Millisecond
+
+// beginDispatch records a call to SendToCheckers, which must be followed by
+// a call to endDispatch with the returned epoch once the call no longer uses
+// the Checkers.
+func (s *State) beginDispatch() uint32 {
+	for {
+		epoch := s.epoch.Load()
+		s.dispatches[epoch%2].Add(1)
+		// The epoch may have changed before the call was counted, in which case
+		// retireLocked may not wait for it.
+		if s.epoch.Load() == epoch {
+			return epoch
+		}
+		s.dispatches[epoch%2].Add(-1)
+	}
+}
+
+// endDispatch records that a call to SendToCheckers returned.
+func (s *State) endDispatch(epoch uint32) {
+	s.dispatches[epoch%2].Add(-1)
+}
+
+// setPoints replaces the checkpoints at which the given Checkers execute with
+// the ones in reqs, the containers they execute for with containers, and their
+// rate limiter with limiter.
+func (s *State) setPoints(update []Checker, reqs []PointReq, containers map[string]pointMask, limiter *sessionLimiter) {
+	s.registrationMu.Lock()
+	defer s.registrationMu.Unlock()
+
+	// Readers may still hold the old slice, so a new one is built.
+	var checkers []Checker
+	for _, c := range s.getCheckers() {
+		if pc := c.(*pointChecker); containsChecker(update, pc.Checker) {
+			c = newPointChecker(pc.Checker, reqs, containers, limiter)
+		}
+		checkers = append(checkers, c)
+	}
+	s.registrationSeq.BeginWrite()
+	s.checkers = checkers
+	s.registrationSeq.EndWrite()
+	s.updatePointsLocked()
+}
+
+// updatePointsLocked updates enabledPoints, enabledSyscalls and pointFields
+// with the union of the checkpoints and fields of all registered Checkers.
+//
+// Preconditions: s.registrationMu must be locked.
+func (s *State) updatePointsLocked() {
+	var enabled pointMask
+	var syscalls uint32
+	var enabledSyscalls [syscallsMax]uint32
+	pointFields := make(map[Point]FieldSet)
+	for _, c := range s.getCheckers() {
+		pc := c.(*pointChecker)
+		for i := range enabled {
+			enabled[i] |= pc.enabled[i]
+		}
+		for _, req := range pc.reqs {
+			if req.Pt >= pointLengthBeforeSyscalls {
+				syscalls = 1
+				off := req.Pt - pointLengthBeforeSyscalls
+				enabledSyscalls[off/Point(syscallTypesCount)] |= uint32(1) << (off % Point(syscallTypesCount))
+			}
+			fields := pointFields[req.Pt]
+			fields.Local.mask |= req.Fields.Local.mask
+			fields.Context.mask |= req.Fields.Context.mask
+			pointFields[req.Pt] = fields
+		}
+	}
+	for i := range s.enabledPoints {
+		s.enabledPoints[i].Store(enabled[i])
+	}
+	s.syscallsEnabled.Store(syscalls)
+	for i := range s.enabledSyscalls {
+		s.enabledSyscalls[i].Store(enabledSyscalls[i])
+	}
+	s.registrationSeq.BeginWrite()
+	s.pointFields = pointFields
+	s.registrationSeq.EndWrite()
+}
+
+func containsChecker(checkers []Checker, c Checker) bool {
+	for _, checker := range checkers {
+		if checker == c {
+			return true
+		}
+	}
+	return false
+}
+
 // Enabled returns true if any Checker is registered for the given checkpoint.
 func (s *State) Enabled(p Point) bool {
 	word, bit := p/32, p%32
@@ -269,19 +822,127 @@ func (s *State) appendCheckerLocked(c Checker) {
 	s.registrationSeq.EndWrite()
 }
 
-// SendToCheckers iterates over all checkers and calls fn for each one of them.
-func (s *State) SendToCheckers(fn func(c Checker) error) error {
-	for _, c := range s.getCheckers() {
-		if err := fn(c); err != nil {
+// SendToCheckers calls fn for each checker registered at checkpoint p for
+// container cid. cid is the ID of the container that generated the point, or
+// empty if the point isn't generated by a container. Fields passed to fn are
+// usually from GetFieldSet, which is the union of the fields requested by all
+// checkers at p. It returns the first error from a checker that fails closed at
+// p, see PointReq.FailClosed, or that denies the operation, see IsDenied. Points beyond the rate limit or quota of a
+// session are not sent to its checkers, see RateLimitConfig and QuotaConfig.
+// Otherwise, it returns the first audit-only Violation, if any, so that the
+// caller can report it.
+func (s *State) SendToCheckers(cid string, p Point, fn func(c Checker) error) error {
+	epoch := s.beginDispatch()
+	defer s.endDispatch(epoch)
+	return s.sendTo(s.getCheckers(), cid, p, fn)
+}
+
+// sendTo implements SendToCheckers for the given checkers.
+//
+// Preconditions: The call must be recorded with beginDispatch.
+func (s *State) sendTo(checkers []Checker, cid string, p Point, fn func(c Checker) error) error {
+	d := dispatch{cid: cid, p: p}
+	for _, c := range checkers {
+		pc := c.(*pointChecker)
+		if !d.admit(pc) {
+			continue
+		}
+		if err, stop := d.result(pc, fn(pc.Checker)); stop {
 			return err
 		}
 	}
-	return nil
+	return d.audit
 }
 
+// dispatch is the state of a point being sent to checkers, see
+// SendToCheckers.
+type dispatch struct {
+	cid string
+	p   Point
+
+	// limiter is the limiter of the last session that the point was counted
+	// for, and allowed whether it allowed the point.
+	limiter *sessionLimiter
+	allowed bool
+
+	// audit is the first audit-only Violation returned by a checker.
+	audit error
+}
+
+// admit returns true if the point must be sent to pc.
+func (d *dispatch) admit(pc *pointChecker) bool {
+	if !pc.enabledAt(d.cid, d.p) {
+		return false
+	}
+	if pc.limiter != nil {
+		// Checkers of a session are registered together and share its
+		// limiter, so the point is only counted once for all of them.
+		if pc.limiter != d.limiter {
+			d.limiter, d.allowed = pc.limiter, pc.limiter.allow(d.p)
+		}
+		return d.allowed
+	}
+	return true
+}
+
+// result handles err, returned by pc for the point. It returns true if err
+// must be returned without sending the point to the next checkers.
+func (d *dispatch) result(pc *pointChecker, err error) (error, bool) {
+	if err == nil {
+		return nil, false
+	}
+	if IsDenied(err) || pc.failClosed.contains(d.p) {
+		return err, true
+	}
+	if v, ok := err.(*Violation); ok {
+		// Audit-only violations don't prevent subsequent checkers from
+		// denying the operation.
+		if d.audit == nil {
+			d.audit = v
+		}
+		return nil, false
+	}
+	failOpenLog.Warningf("Ignoring error from sink %q: %v", pc.Name(), err)
+	return nil, false
+}
+
+// failOpenLog logs errors that are ignored because of the error policy of the
+// point, which may happen for every point while a sink is failing.
+var failOpenLog = log.BasicRateLimitedLogger(time.Minute)
+
 // GetFieldSet returns the FieldSet that has been configured for a given Point.
 func (s *State) GetFieldSet(p Point) FieldSet {
+	return SeqAtomicLoadPointFields(&s.registrationSeq, &s.pointFields)[p]
+}
+
+// SetPayload sets the Payload used to capture data buffers.
+func (s *State) SetPayload(p *Payload) {
+	s.registrationMu.Lock()
+	defer s.registrationMu.Unlock()
+	s.payload = p
+}
+
+// GetPayload returns the Payload used to capture data buffers. It's only nil
+// if no session is configured.
+func (s *State) GetPayload() *Payload {
+	s.registrationMu.RLock()
+	defer s.registrationMu.RUnlock()
+	return s.payload
+}
+
+// SetExecArgs sets the ExecArgs used to trim the arguments and environment
+// reported by sentry/execve.
+func (s *State) SetExecArgs(e *ExecArgs) {
+	s.registrationMu.Lock()
+	defer s.registrationMu.Unlock()
+	s.execArgs = e
+}
+
+// GetExecArgs returns the ExecArgs used to trim the arguments and environment
+// reported by sentry/execve. It's nil if they're reported in full, and the
+// methods of ExecArgs accept a nil receiver.
+func (s *State) GetExecArgs() *ExecArgs {
 	s.registrationMu.RLock()
 	defer s.registrationMu.RUnlock()
-	return s.pointFields[p]
+	return s.execArgs
 }
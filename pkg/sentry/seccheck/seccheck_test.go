@@ -15,11 +15,19 @@
 package seccheck
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"google.golang.org/protobuf/proto"
 	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/fd"
 	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/usermem"
 )
 
 type testChecker struct {
@@ -80,7 +88,7 @@ func TestCheckerRegistered(t *testing.T) {
 	if !fields.Context.Contains(FieldCtxtCredentials) {
 		t.Errorf("fields.Context.Contains(PointContextCredentials): got false, wanted true")
 	}
-	if err := s.SendToCheckers(func(c Checker) error {
+	if err := s.SendToCheckers("" /* cid */, PointClone, func(c Checker) error {
 		return c.Clone(context.Background(), fields, &pb.CloneInfo{})
 	}); err != nil {
 		t.Errorf("Clone(): got %v, wanted nil", err)
@@ -90,6 +98,226 @@ func TestCheckerRegistered(t *testing.T) {
 	}
 }
 
+func TestSyscallsEnabled(t *testing.T) {
+	var s State
+	if s.SyscallsEnabled() {
+		t.Errorf("SyscallsEnabled(): got true, wanted false")
+	}
+	s.AppendChecker(&testChecker{}, []PointReq{{Pt: PointClone}})
+	if s.SyscallsEnabled() {
+		t.Errorf("SyscallsEnabled() with PointClone: got true, wanted false")
+	}
+	pt := GetPointForSyscall(SyscallExit, 1)
+	s.AppendChecker(&testChecker{}, []PointReq{{Pt: pt}})
+	if !s.SyscallsEnabled() {
+		t.Errorf("SyscallsEnabled() with %v: got false, wanted true", pt)
+	}
+	if !s.SyscallEnabled(SyscallExit, 1) {
+		t.Errorf("SyscallEnabled(SyscallExit, 1): got false, wanted true")
+	}
+	s.clearCheckers()
+	if s.SyscallsEnabled() {
+		t.Errorf("SyscallsEnabled() after clearCheckers(): got true, wanted false")
+	}
+}
+
+func TestSyscallEnabled(t *testing.T) {
+	var s State
+	enabled := []struct {
+		typ   SyscallType
+		sysno uintptr
+	}{
+		{typ: SyscallEnter, sysno: 0},
+		{typ: SyscallRawExit, sysno: 1},
+		{typ: SyscallExit, sysno: syscallsMax - 1},
+	}
+	var reqs []PointReq
+	for _, e := range enabled {
+		reqs = append(reqs, PointReq{Pt: GetPointForSyscall(e.typ, e.sysno)})
+	}
+	s.AppendChecker(&testChecker{}, reqs)
+
+	for sysno := uintptr(0); sysno < syscallsMax; sysno++ {
+		for typ := SyscallEnter; typ < syscallTypesCount; typ++ {
+			want := s.Enabled(GetPointForSyscall(typ, sysno))
+			if got := s.SyscallEnabled(typ, sysno); got != want {
+				t.Fatalf("SyscallEnabled(%v, %d): got %t, wanted %t", typ, sysno, got, want)
+			}
+		}
+	}
+	for _, e := range enabled {
+		if !s.SyscallEnabled(e.typ, e.sysno) {
+			t.Errorf("SyscallEnabled(%v, %d): got false, wanted true", e.typ, e.sysno)
+		}
+	}
+	if s.SyscallEnabled(SyscallEnter, syscallsMax) {
+		t.Errorf("SyscallEnabled(SyscallEnter, %d): got true, wanted false", syscallsMax)
+	}
+	s.clearCheckers()
+	for _, e := range enabled {
+		if s.SyscallEnabled(e.typ, e.sysno) {
+			t.Errorf("SyscallEnabled(%v, %d) after clearCheckers(): got true, wanted false", e.typ, e.sysno)
+		}
+	}
+}
+
+// retireChecker is a Checker that records when it's stopped.
+type retireChecker struct {
+	testChecker
+
+	stopped chan struct{}
+}
+
+// Stop implements Checker.Stop.
+func (c *retireChecker) Stop() {
+	close(c.stopped)
+}
+
+func TestRemoveCheckersWaitsForDispatch(t *testing.T) {
+	var s State
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	removed := &retireChecker{stopped: make(chan struct{})}
+	removed.onClone = func(context.Context, FieldSet, *pb.CloneInfo) error {
+		close(entered)
+		<-release
+		return nil
+	}
+	s.AppendChecker(removed, []PointReq{{Pt: PointClone}})
+	s.AppendChecker(&testChecker{}, []PointReq{{Pt: PointExecve}})
+
+	dispatched := make(chan error)
+	go func() {
+		dispatched <- s.SendToCheckers("" /* cid */, PointClone, func(c Checker) error {
+			return c.Clone(context.Background(), FieldSet{}, &pb.CloneInfo{})
+		})
+	}()
+	<-entered
+	done := make(chan struct{})
+	go func() {
+		s.removeCheckers([]Checker{removed})
+		close(done)
+	}()
+
+	// Points are not blocked while the Checker is being removed.
+	for s.Enabled(PointClone) {
+		time.Sleep(time.Millisecond)
+	}
+	_ = s.GetFieldSet(PointExecve)
+	if err := s.SendToCheckers("" /* cid */, PointExecve, func(Checker) error { return nil }); err != nil {
+		t.Errorf("SendToCheckers(PointExecve): %v", err)
+	}
+	select {
+	case <-removed.stopped:
+		t.Fatalf("Checker stopped while a point was being sent to it")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-dispatched; err != nil {
+		t.Errorf("SendToCheckers(PointClone): %v", err)
+	}
+	<-done
+	select {
+	case <-removed.stopped:
+	default:
+		t.Errorf("Checker wasn't stopped")
+	}
+}
+
+// syscallChecker is a Checker that returns err from syscall points.
+type syscallChecker struct {
+	CheckerDefaults
+
+	err   error
+	calls int
+}
+
+// Name implements Checker.Name.
+func (*syscallChecker) Name() string {
+	return "syscall-checker"
+}
+
+// Syscall implements Checker.Syscall.
+func (c *syscallChecker) Syscall(context.Context, FieldSet, *pb.ContextData, pb.MessageType, proto.Message) error {
+	c.calls++
+	return c.err
+}
+
+// RawSyscall implements Checker.RawSyscall.
+func (c *syscallChecker) RawSyscall(context.Context, FieldSet, *pb.Syscall) error {
+	c.calls++
+	return c.err
+}
+
+func TestSendSyscallToCheckers(t *testing.T) {
+	pt := GetPointForSyscall(SyscallEnter, 1)
+	audit := &Violation{Audit: true}
+	for _, tc := range []struct {
+		name     string
+		checkers []*syscallChecker
+		want     error
+		calls    []int
+	}{
+		{
+			name:     "single",
+			checkers: []*syscallChecker{{}},
+			calls:    []int{1},
+		},
+		{
+			name:     "single-denied",
+			checkers: []*syscallChecker{{err: ErrDenied}},
+			want:     ErrDenied,
+			calls:    []int{1},
+		},
+		{
+			name:     "single-audit",
+			checkers: []*syscallChecker{{err: audit}},
+			want:     audit,
+			calls:    []int{1},
+		},
+		{
+			name:     "single-fail-open",
+			checkers: []*syscallChecker{{err: fmt.Errorf("failed")}},
+			calls:    []int{1},
+		},
+		{
+			name:     "multiple-denied",
+			checkers: []*syscallChecker{{err: audit}, {err: ErrDenied}, {}},
+			want:     ErrDenied,
+			calls:    []int{1, 1, 0},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var s State
+			for _, c := range tc.checkers {
+				s.AppendChecker(c, []PointReq{{Pt: pt}})
+			}
+			err := s.SendSyscallToCheckers(context.Background(), "" /* cid */, pt, FieldSet{}, nil, pb.MessageType_MESSAGE_UNKNOWN, &pb.Open{})
+			if err != tc.want {
+				t.Errorf("SendSyscallToCheckers(): got: %v, want: %v", err, tc.want)
+			}
+			err = s.SendRawSyscallToCheckers(context.Background(), "" /* cid */, pt, FieldSet{}, &pb.Syscall{})
+			if err != tc.want {
+				t.Errorf("SendRawSyscallToCheckers(): got: %v, want: %v", err, tc.want)
+			}
+			for i, c := range tc.checkers {
+				if want := 2 * tc.calls[i]; c.calls != want {
+					t.Errorf("checker %d called %d times, want: %d", i, c.calls, want)
+				}
+			}
+			// Checkers are not called for other points.
+			other := GetPointForSyscall(SyscallExit, 1)
+			if err := s.SendSyscallToCheckers(context.Background(), "" /* cid */, other, FieldSet{}, nil, pb.MessageType_MESSAGE_UNKNOWN, &pb.Open{}); err != nil {
+				t.Errorf("SendSyscallToCheckers(%v): %v", other, err)
+			}
+			if c := tc.checkers[0]; c.calls != 2*tc.calls[0] {
+				t.Errorf("checker called for point %v", other)
+			}
+		})
+	}
+}
+
 func TestMultipleCheckersRegistered(t *testing.T) {
 	var s State
 	checkersCalled := [2]bool{}
@@ -119,7 +347,7 @@ func TestMultipleCheckersRegistered(t *testing.T) {
 	// CloneReq() should return the union of requested fields from all calls to
 	// AppendChecker.
 	fields := s.GetFieldSet(PointClone)
-	if err := s.SendToCheckers(func(c Checker) error {
+	if err := s.SendToCheckers("" /* cid */, PointClone, func(c Checker) error {
 		return c.Clone(context.Background(), fields, &pb.CloneInfo{})
 	}); err != nil {
 		t.Errorf("Clone(): got %v, wanted nil", err)
@@ -144,7 +372,7 @@ func TestCheckpointReturnsFirstCheckerError(t *testing.T) {
 		},
 	}
 	reqs := []PointReq{
-		{Pt: PointClone},
+		{Pt: PointClone, FailClosed: true},
 	}
 
 	s.AppendChecker(checker, reqs)
@@ -160,7 +388,7 @@ func TestCheckpointReturnsFirstCheckerError(t *testing.T) {
 	if !s.Enabled(PointClone) {
 		t.Errorf("Enabled(PointClone): got false, wanted true")
 	}
-	if err := s.SendToCheckers(func(c Checker) error {
+	if err := s.SendToCheckers("" /* cid */, PointClone, func(c Checker) error {
 		return c.Clone(context.Background(), FieldSet{}, &pb.CloneInfo{})
 	}); err != errFirstChecker {
 		t.Errorf("Clone(): got %v, wanted %v", err, errFirstChecker)
@@ -173,6 +401,1009 @@ func TestCheckpointReturnsFirstCheckerError(t *testing.T) {
 	}
 }
 
+func TestCheckpointIgnoresFailOpenCheckerError(t *testing.T) {
+	var s State
+	checkersCalled := [2]bool{}
+	s.AppendChecker(&testChecker{
+		onClone: func(context.Context, FieldSet, *pb.CloneInfo) error {
+			checkersCalled[0] = true
+			return errors.New("first Checker error")
+		},
+	}, []PointReq{{Pt: PointClone}})
+	s.AppendChecker(&testChecker{
+		onClone: func(context.Context, FieldSet, *pb.CloneInfo) error {
+			checkersCalled[1] = true
+			return nil
+		},
+	}, []PointReq{{Pt: PointClone}})
+
+	if err := s.SendToCheckers("" /* cid */, PointClone, func(c Checker) error {
+		return c.Clone(context.Background(), FieldSet{}, &pb.CloneInfo{})
+	}); err != nil {
+		t.Errorf("Clone(): got %v, wanted nil", err)
+	}
+	if !checkersCalled[0] || !checkersCalled[1] {
+		t.Errorf("Clone() did not call all Checkers: %v", checkersCalled)
+	}
+}
+
+func TestCheckpointReturnsViolations(t *testing.T) {
+	var s State
+	audit := &Violation{Rule: "audit", Audit: true}
+	deny := &Violation{Rule: "deny"}
+	checkersCalled := [3]bool{}
+	for i, err := range []error{audit, nil, deny} {
+		i, err := i, err
+		s.AppendChecker(&testChecker{
+			onClone: func(context.Context, FieldSet, *pb.CloneInfo) error {
+				checkersCalled[i] = true
+				return err
+			},
+		}, []PointReq{{Pt: PointClone}})
+	}
+
+	// Audit-only violations don't stop subsequent checkers from denying the
+	// operation.
+	if err := s.SendToCheckers("" /* cid */, PointClone, func(c Checker) error {
+		return c.Clone(context.Background(), FieldSet{}, &pb.CloneInfo{})
+	}); err != deny {
+		t.Errorf("Clone(): got %v, wanted %v", err, deny)
+	}
+	if !checkersCalled[0] || !checkersCalled[1] || !checkersCalled[2] {
+		t.Errorf("Clone() did not call all Checkers: %v", checkersCalled)
+	}
+	if !IsDenied(deny) || IsDenied(audit) {
+		t.Errorf("IsDenied(): got %t for %v and %t for %v", IsDenied(deny), deny, IsDenied(audit), audit)
+	}
+
+	// Without denials, audit-only violations are returned to be reported.
+	deny.Audit = true
+	if err := s.SendToCheckers("" /* cid */, PointClone, func(c Checker) error {
+		return c.Clone(context.Background(), FieldSet{}, &pb.CloneInfo{})
+	}); err != audit {
+		t.Errorf("Clone(): got %v, wanted %v", err, audit)
+	}
+}
+
+func TestErrorPolicyConfig(t *testing.T) {
+	for _, tc := range []struct {
+		policy     string
+		failClosed bool
+		err        bool
+	}{
+		{policy: ""},
+		{policy: ErrorPolicyFailOpen},
+		{policy: ErrorPolicyFailClosed, failClosed: true},
+		{policy: "foo", err: true},
+	} {
+		t.Run(tc.policy, func(t *testing.T) {
+			reqs, err := PointReqs([]PointConfig{{Name: "sentry/clone", ErrorPolicy: tc.policy}})
+			if tc.err {
+				if err == nil {
+					t.Fatalf("PointReqs() with error policy %q should fail", tc.policy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PointReqs(): %v", err)
+			}
+			if reqs[0].FailClosed != tc.failClosed {
+				t.Errorf("wrong FailClosed, want: %v, got: %v", tc.failClosed, reqs[0].FailClosed)
+			}
+		})
+	}
+}
+
+// stopChecker is a Checker that records whether it was stopped.
+type stopChecker struct {
+	CheckerDefaults
+
+	stopped bool
+	reason  string
+}
+
+// Name implements Checker.Name.
+func (*stopChecker) Name() string {
+	return "stop-checker"
+}
+
+// Stop implements Checker.Stop.
+func (c *stopChecker) Stop() {
+	c.stopped = true
+}
+
+// StopReason implements StopReasoner.
+func (c *stopChecker) StopReason(reason string) {
+	c.reason = reason
+}
+
+// createdCheckers holds the checkers created by the "test-ok" sink.
+var createdCheckers []*stopChecker
+
+// reloadChecker is a checker that records the configurations it's created
+// and reloaded with, and the endpoints it's reconnected with.
+type reloadChecker struct {
+	CheckerDefaults
+	configs   []map[string]interface{}
+	endpoints []*fd.FD
+}
+
+// Name implements Checker.Name.
+func (*reloadChecker) Name() string {
+	return "reload-checker"
+}
+
+// Reload implements Reloader.
+func (c *reloadChecker) Reload(config map[string]interface{}) error {
+	c.configs = append(c.configs, config)
+	return nil
+}
+
+// Reconnect implements Reconnecter.
+func (c *reloadChecker) Reconnect(_ map[string]interface{}, endpoint *fd.FD) error {
+	c.endpoints = append(c.endpoints, endpoint)
+	return nil
+}
+
+// reloadCheckers holds the checkers created by the "test-reload" sink.
+var reloadCheckers []*reloadChecker
+
+func init() {
+	RegisterSink(SinkDesc{
+		Name: "test-ok",
+		New: func(map[string]interface{}, *fd.FD) (Checker, error) {
+			c := &stopChecker{}
+			createdCheckers = append(createdCheckers, c)
+			return c, nil
+		},
+	})
+	RegisterSink(SinkDesc{
+		Name: "test-reload",
+		New: func(config map[string]interface{}, _ *fd.FD) (Checker, error) {
+			c := &reloadChecker{configs: []map[string]interface{}{config}}
+			reloadCheckers = append(reloadCheckers, c)
+			return c, nil
+		},
+		Validate: func(config map[string]interface{}) error {
+			if _, ok := config["invalid"]; ok {
+				return errors.New("invalid config")
+			}
+			return nil
+		},
+	})
+	RegisterSink(SinkDesc{
+		Name: "test-fail",
+		New: func(map[string]interface{}, *fd.FD) (Checker, error) {
+			return nil, errors.New("sink failure")
+		},
+	})
+}
+
+func TestCreateMultipleSinks(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		sinks    []SinkConfig
+		checkers int
+		err      bool
+	}{
+		{
+			name:     "all",
+			sinks:    []SinkConfig{{Name: "test-ok"}, {Name: "test-ok"}},
+			checkers: 2,
+		},
+		{
+			name:     "ignore-failure",
+			sinks:    []SinkConfig{{Name: "test-ok"}, {Name: "test-fail", IgnoreSetupError: true}, {Name: "test-ok"}},
+			checkers: 2,
+		},
+		{
+			name:  "failure",
+			sinks: []SinkConfig{{Name: "test-ok"}, {Name: "test-fail"}},
+			err:   true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			createdCheckers = nil
+			conf := &SessionConfig{Name: DefaultSessionName, Sinks: tc.sinks}
+			err := Create(conf, false)
+			if tc.err {
+				if err == nil {
+					_ = Delete(DefaultSessionName)
+					t.Fatalf("Create(): got nil, wanted error")
+				}
+				if got := len(Global.getCheckers()); got != 0 {
+					t.Errorf("Create() left %d checkers registered", got)
+				}
+				for i, c := range createdCheckers {
+					if !c.stopped {
+						t.Errorf("checker %d was not stopped", i)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create(): %v", err)
+			}
+			defer func() {
+				if err := Delete(DefaultSessionName); err != nil {
+					t.Errorf("Delete(): %v", err)
+				}
+			}()
+			if got := len(Global.getCheckers()); got != tc.checkers {
+				t.Errorf("wrong number of checkers, got: %d, want: %d", got, tc.checkers)
+			}
+		})
+	}
+}
+
+func TestCheckerNotCalledForOtherPoints(t *testing.T) {
+	var s State
+	called := false
+	checker := &testChecker{
+		onClone: func(context.Context, FieldSet, *pb.CloneInfo) error {
+			called = true
+			return nil
+		},
+	}
+	s.AppendChecker(checker, []PointReq{{Pt: PointExecve}})
+	s.AppendChecker(&testChecker{}, []PointReq{{Pt: PointClone}})
+
+	if !s.Enabled(PointClone) {
+		t.Errorf("Enabled(PointClone): got false, wanted true")
+	}
+	if err := s.SendToCheckers("" /* cid */, PointClone, func(c Checker) error {
+		return c.Clone(context.Background(), FieldSet{}, &pb.CloneInfo{})
+	}); err != nil {
+		t.Errorf("Clone(): got %v, wanted nil", err)
+	}
+	if called {
+		t.Errorf("Clone() called Checker registered for PointExecve")
+	}
+}
+
+func TestMultipleSessions(t *testing.T) {
+	createdCheckers = nil
+	for _, conf := range []*SessionConfig{
+		{
+			Name:   "audit",
+			Points: []PointConfig{{Name: "sentry/clone", ContextFields: []string{"container_id"}}},
+			Sinks:  []SinkConfig{{Name: "test-ok"}},
+		},
+		{
+			Name:   "ir",
+			Points: []PointConfig{{Name: "sentry/clone", ContextFields: []string{"cwd"}}, {Name: "sentry/execve"}},
+			Sinks:  []SinkConfig{{Name: "test-ok"}},
+		},
+	} {
+		if err := Create(conf, false); err != nil {
+			t.Fatalf("Create(%q): %v", conf.Name, err)
+		}
+	}
+	defer DeleteAll()
+	audit, ir := createdCheckers[0], createdCheckers[1]
+
+	receivers := func(p Point) []Checker {
+		var got []Checker
+		_ = Global.SendToCheckers("" /* cid */, p, func(c Checker) error {
+			got = append(got, c)
+			return nil
+		})
+		return got
+	}
+	if got := receivers(PointClone); len(got) != 2 {
+		t.Errorf("PointClone sent to %d checkers, want: 2", len(got))
+	}
+	if got := receivers(PointExecve); len(got) != 1 || got[0] != ir {
+		t.Errorf("PointExecve sent to wrong checkers: %v", got)
+	}
+	fields := Global.GetFieldSet(PointClone)
+	if !fields.Context.Contains(FieldCtxtContainerID) || !fields.Context.Contains(FieldCtxtCwd) {
+		t.Errorf("GetFieldSet(PointClone) is not the union of all sessions: %+v", fields)
+	}
+
+	if err := Delete("ir"); err != nil {
+		t.Fatalf("Delete(): %v", err)
+	}
+	if !ir.stopped || audit.stopped {
+		t.Errorf("Delete() stopped wrong checkers, audit: %t, ir: %t", audit.stopped, ir.stopped)
+	}
+	if Global.Enabled(PointExecve) {
+		t.Errorf("Enabled(PointExecve): got true, wanted false")
+	}
+	if got := receivers(PointClone); len(got) != 1 || got[0] != audit {
+		t.Errorf("PointClone sent to wrong checkers: %v", got)
+	}
+	if fields := Global.GetFieldSet(PointClone); fields.Context.Contains(FieldCtxtCwd) {
+		t.Errorf("GetFieldSet(PointClone) has fields of deleted session: %+v", fields)
+	}
+}
+
+func TestOptInSessions(t *testing.T) {
+	createdCheckers = nil
+	for _, conf := range []*SessionConfig{
+		{
+			Name:   "all",
+			Points: []PointConfig{{Name: "sentry/clone"}, {Name: "sentry/execve"}},
+			Sinks:  []SinkConfig{{Name: "test-ok"}},
+		},
+		{
+			Name:   "scoped",
+			Points: []PointConfig{{Name: "sentry/clone"}, {Name: "sentry/execve"}},
+			Sinks:  []SinkConfig{{Name: "test-ok"}},
+			OptIn:  true,
+		},
+	} {
+		if err := Create(conf, false); err != nil {
+			t.Fatalf("Create(%q): %v", conf.Name, err)
+		}
+	}
+	defer DeleteAll()
+	all, scoped := createdCheckers[0], createdCheckers[1]
+
+	for _, c := range []struct {
+		cid      string
+		sessions []string
+		points   []string
+	}{
+		{cid: "c1", sessions: []string{"scoped"}},
+		{cid: "c2", sessions: []string{"scoped"}, points: []string{"sentry/execve"}},
+		{cid: "c3", sessions: []string{"all"}},
+		{cid: "c4", sessions: []string{"later"}},
+	} {
+		if err := AddContainer(c.cid, "", c.sessions, c.points); err != nil {
+			t.Fatalf("AddContainer(%q): %v", c.cid, err)
+		}
+		defer RemoveContainer(c.cid)
+	}
+	if err := AddContainer("c5", "", []string{"scoped"}, []string{"sentry/foo"}); err == nil {
+		t.Errorf("AddContainer() with invalid point: want error, got nil")
+	}
+
+	receivers := func(cid string, p Point) []Checker {
+		var got []Checker
+		_ = Global.SendToCheckers(cid, p, func(c Checker) error {
+			got = append(got, c)
+			return nil
+		})
+		return got
+	}
+	// Checkers are compared by identity, since they are all equal.
+	same := func(a, b []Checker) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+	for _, tc := range []struct {
+		cid  string
+		p    Point
+		want []Checker
+	}{
+		{cid: "c1", p: PointClone, want: []Checker{all, scoped}},
+		{cid: "c2", p: PointClone, want: []Checker{all}},
+		{cid: "c2", p: PointExecve, want: []Checker{all, scoped}},
+		{cid: "c3", p: PointClone, want: []Checker{all}},
+		{cid: "other", p: PointClone, want: []Checker{all}},
+		{cid: "", p: PointClone, want: []Checker{all, scoped}},
+	} {
+		if got := receivers(tc.cid, tc.p); !same(got, tc.want) {
+			t.Errorf("point %v of container %q sent to wrong checkers, want: %v, got: %v", tc.p, tc.cid, tc.want, got)
+		}
+	}
+
+	// Containers are added to sessions created after them.
+	if err := Create(&SessionConfig{
+		Name:   "later",
+		Points: []PointConfig{{Name: "sentry/clone"}},
+		Sinks:  []SinkConfig{{Name: "test-ok"}},
+		OptIn:  true,
+	}, false); err != nil {
+		t.Fatalf("Create(later): %v", err)
+	}
+	later := createdCheckers[2]
+	if got := receivers("c4", PointClone); !same(got, []Checker{all, later}) {
+		t.Errorf("point of container c4 sent to wrong checkers: %v", got)
+	}
+
+	// Adding a container again replaces its sessions.
+	if err := AddContainer("c3", "", []string{"scoped"}, nil); err != nil {
+		t.Fatalf("AddContainer(c3): %v", err)
+	}
+	if got := receivers("c3", PointClone); !same(got, []Checker{all, scoped}) {
+		t.Errorf("point of re-added container sent to wrong checkers: %v", got)
+	}
+
+	RemoveContainer("c1")
+	if got := receivers("c1", PointClone); !same(got, []Checker{all}) {
+		t.Errorf("point of removed container sent to wrong checkers: %v", got)
+	}
+}
+
+func TestContainerFilter(t *testing.T) {
+	createdCheckers = nil
+	for _, conf := range []*SessionConfig{
+		{
+			Name:   "all",
+			Points: []PointConfig{{Name: "sentry/clone"}},
+			Sinks:  []SinkConfig{{Name: "test-ok"}},
+		},
+		{
+			Name:       "include",
+			Points:     []PointConfig{{Name: "sentry/clone"}},
+			Sinks:      []SinkConfig{{Name: "test-ok"}},
+			Containers: &ContainerFilter{Include: []string{"app", "c3"}},
+		},
+		{
+			Name:       "exclude",
+			Points:     []PointConfig{{Name: "sentry/clone"}},
+			Sinks:      []SinkConfig{{Name: "test-ok"}},
+			Containers: &ContainerFilter{Exclude: []string{"sidecar"}},
+		},
+	} {
+		if err := Create(conf, false); err != nil {
+			t.Fatalf("Create(%q): %v", conf.Name, err)
+		}
+	}
+	defer DeleteAll()
+	all, include, exclude := createdCheckers[0], createdCheckers[1], createdCheckers[2]
+
+	for _, c := range []struct {
+		cid  string
+		name string
+	}{
+		{cid: "c1", name: "app"},
+		{cid: "c2", name: "sidecar"},
+		{cid: "c3"},
+	} {
+		if err := AddContainer(c.cid, c.name, nil, nil); err != nil {
+			t.Fatalf("AddContainer(%q): %v", c.cid, err)
+		}
+		defer RemoveContainer(c.cid)
+	}
+
+	for _, tc := range []struct {
+		cid  string
+		want []Checker
+	}{
+		{cid: "c1", want: []Checker{all, include, exclude}},
+		{cid: "c2", want: []Checker{all}},
+		{cid: "c3", want: []Checker{all, include, exclude}},
+		{cid: "other", want: []Checker{all}},
+	} {
+		var got []Checker
+		_ = Global.SendToCheckers(tc.cid, PointClone, func(c Checker) error {
+			got = append(got, c)
+			return nil
+		})
+		if len(got) != len(tc.want) {
+			t.Errorf("point of container %q sent to wrong checkers, want: %v, got: %v", tc.cid, tc.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("point of container %q sent to wrong checkers, want: %v, got: %v", tc.cid, tc.want, got)
+				break
+			}
+		}
+	}
+
+	var list []SessionConfig
+	List(&list)
+	for _, s := range list {
+		if s.Name == "include" && (s.Containers == nil || !reflect.DeepEqual(s.Containers.Include, []string{"app", "c3"})) {
+			t.Errorf("List() wrong containers for session %q: %+v", s.Name, s.Containers)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	createdCheckers = nil
+	conf := &SessionConfig{
+		Name: "limited",
+		Points: []PointConfig{
+			{Name: "sentry/clone"},
+			{Name: "sentry/execve", RateLimit: &RateLimitConfig{Rate: 0.001, Burst: 5}},
+		},
+		Sinks:     []SinkConfig{{Name: "test-ok"}, {Name: "test-ok"}},
+		RateLimit: &RateLimitConfig{Rate: 0.001, Burst: 2},
+	}
+	if err := Create(conf, false); err != nil {
+		t.Fatalf("Create(): %v", err)
+	}
+	defer DeleteAll()
+
+	count := func(p Point, n int) map[Checker]int {
+		got := make(map[Checker]int)
+		for i := 0; i < n; i++ {
+			_ = Global.SendToCheckers("", p, func(c Checker) error {
+				got[c]++
+				return nil
+			})
+		}
+		return got
+	}
+	// The budget is shared by all sinks of the session.
+	for i, c := range count(PointClone, 3) {
+		if c != 2 {
+			t.Errorf("sink %v got %d clone points, want: 2", i, c)
+		}
+	}
+	// Points with their own limit don't use the session's budget.
+	for i, c := range count(PointExecve, 6) {
+		if c != 5 {
+			t.Errorf("sink %v got %d execve points, want: 5", i, c)
+		}
+	}
+
+	var list []SessionConfig
+	List(&list)
+	if len(list) != 1 {
+		t.Fatalf("List(), want 1 session, got: %d", len(list))
+	}
+	for _, sink := range list[0].Sinks {
+		if want := uint64(2); sink.Status.DroppedCount != want {
+			t.Errorf("sink %q dropped count, want: %d, got: %d", sink.Name, want, sink.Status.DroppedCount)
+		}
+	}
+
+	for _, limit := range []RateLimitConfig{{Rate: 0}, {Rate: -1}, {Rate: 1, Burst: -1}} {
+		limit := limit
+		if err := Validate(&SessionConfig{Name: "invalid", RateLimit: &limit}); err == nil {
+			t.Errorf("Validate() with rate limit %+v: want error, got nil", limit)
+		}
+	}
+}
+
+func TestQuota(t *testing.T) {
+	createdCheckers = nil
+	if err := Create(&SessionConfig{
+		Name:   "quota",
+		Points: []PointConfig{{Name: "sentry/clone"}},
+		Sinks:  []SinkConfig{{Name: "test-ok"}},
+		Quota:  &QuotaConfig{MaxPoints: 3},
+	}, false); err != nil {
+		t.Fatalf("Create(): %v", err)
+	}
+	defer DeleteAll()
+	checker := createdCheckers[0]
+
+	count := 0
+	for i := 0; i < 5; i++ {
+		_ = Global.SendToCheckers("", PointClone, func(Checker) error {
+			count++
+			return nil
+		})
+	}
+	if count != 3 {
+		t.Errorf("wrong number of points sent, want: 3, got: %d", count)
+	}
+
+	// The session is deleted asynchronously.
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		var list []SessionConfig
+		List(&list)
+		if len(list) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session wasn't deleted after exceeding its quota")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !checker.stopped {
+		t.Errorf("sink wasn't stopped")
+	}
+	if !strings.Contains(checker.reason, "max_points") {
+		t.Errorf("wrong stop reason: %q", checker.reason)
+	}
+
+	for _, quota := range []QuotaConfig{{}, {MaxDuration: "foo"}, {MaxDuration: "-1s"}} {
+		quota := quota
+		if err := Validate(&SessionConfig{Name: "invalid", Quota: &quota}); err == nil {
+			t.Errorf("Validate() with quota %+v: want error, got nil", quota)
+		}
+	}
+}
+
+func TestQuotaDuration(t *testing.T) {
+	if err := Create(&SessionConfig{
+		Name:   "quota",
+		Points: []PointConfig{{Name: "sentry/clone"}},
+		Sinks:  []SinkConfig{{Name: "test-ok"}},
+		Quota:  &QuotaConfig{MaxDuration: "10ms"},
+	}, false); err != nil {
+		t.Fatalf("Create(): %v", err)
+	}
+	defer DeleteAll()
+
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		var list []SessionConfig
+		List(&list)
+		if len(list) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session wasn't deleted after its max_duration")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		json     string
+		warnings []string
+		err      string
+	}{
+		{
+			name: "unversioned",
+			json: `{"name": "a", "points": [{"name": "sentry/clone"}]}`,
+		},
+		{
+			name: "current",
+			json: fmt.Sprintf(`{"version": %d, "name": "a"}`, ConfigVersion),
+		},
+		{
+			name: "unknown-field",
+			json: `{"name": "a", "pionts": []}`,
+			err:  "unknown field",
+		},
+		{
+			name: "newer",
+			json: fmt.Sprintf(`{"version": %d, "name": "a", "foo": 1, "points": [{"name": "sentry/clone", "bar": true}], "sinks": [{"name": "null", "config": {"baz": 1}}]}`, ConfigVersion+1),
+			warnings: []string{
+				"newer than the supported version",
+				`"foo"`,
+				`"points[0].bar"`,
+			},
+		},
+		{
+			name: "newer-bad-type",
+			json: fmt.Sprintf(`{"version": %d, "name": 1}`, ConfigVersion+1),
+			err:  "cannot unmarshal",
+		},
+		{
+			name: "negative",
+			json: `{"version": -1}`,
+			err:  "invalid configuration version",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var conf SessionConfig
+			warnings, err := DecodeConfig([]byte(tc.json), &conf)
+			if len(tc.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("DecodeConfig(), want error: %q, got: %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeConfig(): %v", err)
+			}
+			if conf.Name != "a" {
+				t.Errorf("DecodeConfig() wrong name: %q", conf.Name)
+			}
+			if len(warnings) != len(tc.warnings) {
+				t.Fatalf("DecodeConfig(), want warnings: %q, got: %q", tc.warnings, warnings)
+			}
+			for i, w := range warnings {
+				if !strings.Contains(w, tc.warnings[i]) {
+					t.Errorf("DecodeConfig(), want warning: %q, got: %q", tc.warnings[i], w)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionNames(t *testing.T) {
+	defer DeleteAll()
+	for _, name := range []string{"", "a b", "../foo", strings.Repeat("a", 65)} {
+		if err := Create(&SessionConfig{Name: name}, false); err == nil || !strings.Contains(err.Error(), "invalid session name") {
+			t.Errorf("Create(%q) wrong error: %v", name, err)
+		}
+	}
+	for i := 0; i < maxSessions; i++ {
+		if err := Create(&SessionConfig{Name: fmt.Sprintf("session-%d", i)}, false); err != nil {
+			t.Fatalf("Create(): %v", err)
+		}
+	}
+	if err := Create(&SessionConfig{Name: "one-too-many"}, false); err == nil || !strings.Contains(err.Error(), "too many sessions") {
+		t.Errorf("Create() wrong error: %v", err)
+	}
+	// Sessions can still be replaced at the limit.
+	if err := Create(&SessionConfig{Name: "session-0"}, true); err != nil {
+		t.Errorf("Create(force): %v", err)
+	}
+}
+
+func TestMergedPayload(t *testing.T) {
+	defer DeleteAll()
+	RegisterRedactor("test-merge-1", func(data []byte) []byte { return data })
+	RegisterRedactor("test-merge-2", func(data []byte) []byte { return data })
+	for _, conf := range []*SessionConfig{
+		{Name: "a", Payload: &PayloadConfig{MaxSize: 1024, Redactors: []string{"test-merge-1"}}},
+		{Name: "b", Payload: &PayloadConfig{MaxSize: 16, Rate: 100, Redactors: []string{"test-merge-2", "test-merge-1"}}},
+	} {
+		if err := Create(conf, false); err != nil {
+			t.Fatalf("Create(%q): %v", conf.Name, err)
+		}
+	}
+	p := Global.GetPayload()
+	if p.maxSize != 16 || p.limiter.Limit() != 100 || len(p.redactors) != 2 {
+		t.Errorf("wrong merged payload, max size: %d, rate: %v, redactors: %d", p.maxSize, p.limiter.Limit(), len(p.redactors))
+	}
+	if err := Delete("b"); err != nil {
+		t.Fatalf("Delete(): %v", err)
+	}
+	if p := Global.GetPayload(); p.maxSize != 1024 || len(p.redactors) != 1 {
+		t.Errorf("wrong payload after delete, max size: %d, redactors: %d", p.maxSize, len(p.redactors))
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	createdCheckers = nil
+	conf := &SessionConfig{
+		Name:   DefaultSessionName,
+		Points: []PointConfig{{Name: "sentry/clone"}},
+		Sinks:  []SinkConfig{{Name: "test-ok"}},
+	}
+	if err := Create(conf, false); err != nil {
+		t.Fatalf("Create(): %v", err)
+	}
+	defer func() {
+		if err := Delete(DefaultSessionName); err != nil {
+			t.Errorf("Delete(): %v", err)
+		}
+	}()
+
+	update := &SessionConfig{
+		Name: DefaultSessionName,
+		Points: []PointConfig{
+			{
+				Name:          "sentry/execve",
+				ContextFields: []string{"container_id"},
+			},
+		},
+	}
+	if err := Update(update); err != nil {
+		t.Fatalf("Update(): %v", err)
+	}
+	if Global.Enabled(PointClone) {
+		t.Errorf("Enabled(PointClone): got true, wanted false")
+	}
+	if !Global.Enabled(PointExecve) {
+		t.Errorf("Enabled(PointExecve): got false, wanted true")
+	}
+	if fields := Global.GetFieldSet(PointExecve); !fields.Context.Contains(FieldCtxtContainerID) {
+		t.Errorf("Update() didn't set context fields: %+v", fields)
+	}
+	if got := len(Global.getCheckers()); got != 1 || createdCheckers[0].stopped {
+		t.Errorf("Update() changed sinks, checkers: %d, stopped: %t", got, createdCheckers[0].stopped)
+	}
+
+	for _, tc := range []struct {
+		name string
+		conf *SessionConfig
+		err  string
+	}{
+		{
+			name: "not-found",
+			conf: &SessionConfig{Name: "foo"},
+			err:  "not found",
+		},
+		{
+			name: "sinks",
+			conf: &SessionConfig{Name: DefaultSessionName, Sinks: []SinkConfig{{Name: "test-ok"}}},
+			err:  "cannot be reloaded",
+		},
+		{
+			name: "added-sinks",
+			conf: &SessionConfig{Name: DefaultSessionName, Sinks: []SinkConfig{{Name: "test-ok"}, {Name: "test-ok"}}},
+			err:  "cannot be added or removed",
+		},
+		{
+			name: "replaced-sinks",
+			conf: &SessionConfig{Name: DefaultSessionName, Sinks: []SinkConfig{{Name: "test-reload"}}},
+			err:  "cannot be replaced",
+		},
+		{
+			name: "bad-point",
+			conf: &SessionConfig{Name: DefaultSessionName, Points: []PointConfig{{Name: "foo"}}},
+			err:  "not found",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := Update(tc.conf); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Update() wrong error, want: %q, got: %v", tc.err, err)
+			}
+			if !Global.Enabled(PointExecve) {
+				t.Errorf("failed Update() changed points")
+			}
+		})
+	}
+}
+
+func TestUpdateReload(t *testing.T) {
+	reloadCheckers = nil
+	conf := &SessionConfig{
+		Name:   DefaultSessionName,
+		Points: []PointConfig{{Name: "sentry/clone"}},
+		Sinks:  []SinkConfig{{Name: "test-reload", Config: map[string]interface{}{"v": "1"}}},
+	}
+	if err := Create(conf, false); err != nil {
+		t.Fatalf("Create(): %v", err)
+	}
+	defer func() {
+		if err := Delete(DefaultSessionName); err != nil {
+			t.Errorf("Delete(): %v", err)
+		}
+	}()
+
+	// Invalid configurations are rejected before anything changes.
+	invalid := &SessionConfig{
+		Name:   DefaultSessionName,
+		Points: []PointConfig{{Name: "sentry/execve"}},
+		Sinks:  []SinkConfig{{Name: "test-reload", Config: map[string]interface{}{"invalid": true}}},
+	}
+	if err := Update(invalid); err == nil || !strings.Contains(err.Error(), "invalid config") {
+		t.Errorf("Update() wrong error, want: %q, got: %v", "invalid config", err)
+	}
+	if !Global.Enabled(PointClone) || len(reloadCheckers[0].configs) != 1 {
+		t.Errorf("failed Update() changed the session, configs: %v", reloadCheckers[0].configs)
+	}
+
+	update := &SessionConfig{
+		Name:   DefaultSessionName,
+		Points: []PointConfig{{Name: "sentry/execve"}},
+		Sinks:  []SinkConfig{{Name: "test-reload", Config: map[string]interface{}{"v": "2"}}},
+	}
+	if err := Update(update); err != nil {
+		t.Fatalf("Update(): %v", err)
+	}
+	if len(reloadCheckers) != 1 {
+		t.Fatalf("Update() created sinks: %d", len(reloadCheckers))
+	}
+	want := []map[string]interface{}{{"v": "1"}, {"v": "2"}}
+	if got := reloadCheckers[0].configs; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong configurations, want: %v, got: %v", want, got)
+	}
+	if !Global.Enabled(PointExecve) {
+		t.Errorf("Enabled(PointExecve): got false, wanted true")
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	reloadCheckers = nil
+	conf := &SessionConfig{
+		Name:  DefaultSessionName,
+		Sinks: []SinkConfig{{Name: "test-fail", IgnoreSetupError: true}, {Name: "test-ok"}, {Name: "test-reload"}},
+	}
+	if err := Create(conf, false); err != nil {
+		t.Fatalf("Create(): %v", err)
+	}
+	defer func() {
+		if err := Delete(DefaultSessionName); err != nil {
+			t.Errorf("Delete(): %v", err)
+		}
+	}()
+
+	// Sinks are reported with their index in the configuration, which skips
+	// the sink that failed to be created.
+	var sessions []SessionConfig
+	List(&sessions)
+	if len(sessions) != 1 || len(sessions[0].Sinks) != 2 || sessions[0].Sinks[0].Index != 1 || sessions[0].Sinks[1].Index != 2 {
+		t.Fatalf("wrong sink indexes: %+v", sessions)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		index int
+		sink  string
+		err   string
+	}{
+		{name: "failed-sink", index: 0, sink: "test-fail", err: "has no sink"},
+		{name: "unsupported", index: 1, sink: "test-ok", err: "cannot be reconnected"},
+		{name: "wrong-sink", index: 2, sink: "test-ok", err: "not \"test-ok\""},
+		{name: "reconnect", index: 2, sink: "test-reload"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Reconnect(DefaultSessionName, tc.index, SinkConfig{Name: tc.sink, FD: fd.New(-1)})
+			if tc.err == "" {
+				if err != nil {
+					t.Errorf("Reconnect(): %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Reconnect() wrong error, want: %q, got: %v", tc.err, err)
+			}
+		})
+	}
+	if got := len(reloadCheckers[0].endpoints); got != 1 {
+		t.Errorf("wrong number of reconnections, want: 1, got: %d", got)
+	}
+}
+
+func TestPayloadConfig(t *testing.T) {
+	RegisterRedactor("test-upper", bytes.ToUpper)
+	for _, tc := range []struct {
+		name string
+		conf *PayloadConfig
+		err  string
+	}{
+		{
+			name: "default",
+		},
+		{
+			name: "all",
+			conf: &PayloadConfig{MaxSize: 10, Rate: 100, Redactors: []string{"test-upper"}},
+		},
+		{
+			name: "max-size",
+			conf: &PayloadConfig{MaxSize: maxPayloadMaxSize + 1},
+			err:  "max_size",
+		},
+		{
+			name: "rate",
+			conf: &PayloadConfig{Rate: -1},
+			err:  "rate",
+		},
+		{
+			name: "redactor",
+			conf: &PayloadConfig{Redactors: []string{"foo"}},
+			err:  "redactor",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newPayload(tc.conf)
+			if len(tc.err) == 0 {
+				if err != nil {
+					t.Errorf("newPayload(): %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("newPayload() wrong error, want: %q, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestPayloadCapture(t *testing.T) {
+	RegisterRedactor("test-mask", func(data []byte) []byte {
+		return bytes.ReplaceAll(data, []byte("secret"), []byte("******"))
+	})
+	p, err := newPayload(&PayloadConfig{MaxSize: 10, Rate: 20, Redactors: []string{"test-mask"}})
+	if err != nil {
+		t.Fatalf("newPayload(): %v", err)
+	}
+	ctx := context.Background()
+
+	data, truncated, dropped := p.Capture(ctx, usermem.BytesIOSequence([]byte("a secret")), 8)
+	if string(data) != "a ******" || truncated || dropped != 0 {
+		t.Errorf("Capture(): got: %q, %t, %d", data, truncated, dropped)
+	}
+	data, truncated, dropped = p.Capture(ctx, usermem.BytesIOSequence([]byte("0123456789abcdef")), 16)
+	if string(data) != "0123456789" || !truncated || dropped != 0 {
+		t.Errorf("Capture(): got: %q, %t, %d", data, truncated, dropped)
+	}
+	// The rate limit allows 20 bytes in a burst, 18 of which were captured.
+	data, _, _ = p.Capture(ctx, usermem.BytesIOSequence([]byte("0123456789")), 10)
+	if data != nil {
+		t.Errorf("Capture() not rate limited, got: %q", data)
+	}
+	// Capture again once the limiter allows it, and check the drop is reported.
+	time.Sleep(time.Second)
+	data, _, dropped = p.Capture(ctx, usermem.BytesIOSequence([]byte("abc")), 3)
+	if string(data) != "abc" || dropped != 10 {
+		t.Errorf("Capture(): got: %q, dropped: %d", data, dropped)
+	}
+}
+
 func TestFieldMaskEmpty(t *testing.T) {
 	fd := FieldMask{}
 	if !fd.Empty() {
@@ -199,6 +1430,24 @@ func TestFieldMaskMake(t *testing.T) {
 	}
 }
 
+func TestFieldMaskContainsAll(t *testing.T) {
+	fd := MakeFieldMask(Field(0), Field(2))
+	for _, tc := range []struct {
+		other FieldMask
+		want  bool
+	}{
+		{other: FieldMask{}, want: true},
+		{other: MakeFieldMask(Field(2)), want: true},
+		{other: MakeFieldMask(Field(0), Field(2)), want: true},
+		{other: MakeFieldMask(Field(1)), want: false},
+		{other: MakeFieldMask(Field(0), Field(1)), want: false},
+	} {
+		if got := fd.ContainsAll(tc.other); got != tc.want {
+			t.Errorf("%+v.ContainsAll(%+v): got: %t, want: %t", fd, tc.other, got, tc.want)
+		}
+	}
+}
+
 func TestFieldMask(t *testing.T) {
 	zero := Field(0)
 	one := Field(1)
@@ -261,3 +1510,170 @@ func TestFieldMask(t *testing.T) {
 		t.Errorf("FieldMask must not contain %v: %+v", want, fd)
 	}
 }
+
+func TestReleaseMessage(t *testing.T) {
+	open := NewOpen()
+	open.Pathname = "/etc/passwd"
+	open.Exit = &pb.Exit{Result: 3}
+	ReleaseMessage(open)
+	if open.Pathname != "" || open.Exit != nil {
+		t.Errorf("ReleaseMessage(): got: %+v, want: reset message", open)
+	}
+
+	read := NewRead()
+	read.Data = []byte("data")
+	ReleaseMessage(read)
+	if read.Data != nil {
+		t.Errorf("ReleaseMessage(): got: %+v, want: reset message", read)
+	}
+
+	// Messages that aren't pooled are left alone.
+	clone := &pb.CloneInfo{CreatedThreadId: 1}
+	ReleaseMessage(clone)
+	if clone.CreatedThreadId != 1 {
+		t.Errorf("ReleaseMessage(): got: %+v, want: unchanged message", clone)
+	}
+}
+
+func TestWorkload(t *testing.T) {
+	SetSandbox("sandbox", Pod{Name: "web", Namespace: "default", UID: "1234"})
+	defer SetSandbox("", Pod{})
+	if err := AddContainer("cid", "app", nil, nil); err != nil {
+		t.Fatalf("AddContainer(): %v", err)
+	}
+	defer RemoveContainer("cid")
+
+	want := &pb.Workload{
+		SandboxId:     "sandbox",
+		ContainerId:   "cid",
+		ContainerName: "app",
+		PodName:       "web",
+		PodNamespace:  "default",
+		PodUid:        "1234",
+	}
+	if got := Workload("cid"); !proto.Equal(got, want) {
+		t.Errorf("Workload(): got: %v, want: %v", got, want)
+	}
+	// Points about the whole sandbox have no container.
+	want.ContainerId = ""
+	want.ContainerName = ""
+	if got := Workload(""); !proto.Equal(got, want) {
+		t.Errorf("Workload(\"\"): got: %v, want: %v", got, want)
+	}
+
+	reqs, err := PointReqs([]PointConfig{
+		{Name: "sentry/clone"},
+		{Name: "container/stop"},
+	})
+	if err != nil {
+		t.Fatalf("PointReqs(): %v", err)
+	}
+	// The workload and time are added to points with context, even if no
+	// context field was requested.
+	if !reqs[0].Fields.Context.Contains(FieldCtxtWorkload) || !reqs[0].Fields.Context.Contains(FieldCtxtTime) {
+		t.Errorf("sentry/clone doesn't have the workload and time: %+v", reqs[0].Fields)
+	}
+	if !reqs[1].Fields.Context.Empty() {
+		t.Errorf("container/stop has context fields: %+v", reqs[1].Fields)
+	}
+}
+
+func TestNamespaceContextFields(t *testing.T) {
+	reqs, err := PointReqs([]PointConfig{
+		{Name: "sentry/execve", ContextFields: []string{"group_id", "ns_thread_id", "ns_group_id"}},
+	})
+	if err != nil {
+		t.Fatalf("PointReqs(): %v", err)
+	}
+	for _, field := range []Field{FieldCtxtThreadGroupID, FieldCtxtNamespaceThreadID, FieldCtxtNamespaceThreadGroupID} {
+		if !reqs[0].Fields.Context.Contains(field) {
+			t.Errorf("context field %d not set: %+v", field, reqs[0].Fields)
+		}
+	}
+	if reqs[0].Fields.Context.Contains(FieldCtxtThreadID) {
+		t.Errorf("thread_id set without being requested: %+v", reqs[0].Fields)
+	}
+}
+
+func TestExecConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		conf *ExecConfig
+		err  string
+	}{
+		{
+			name: "default",
+		},
+		{
+			name: "all",
+			conf: &ExecConfig{MaxArgvSize: 100, MaxEnvSize: 100, Env: []string{"PATH", "KUBERNETES_*"}},
+		},
+		{
+			name: "max-argv-size",
+			conf: &ExecConfig{MaxArgvSize: -1},
+			err:  "max_argv_size",
+		},
+		{
+			name: "max-env-size",
+			conf: &ExecConfig{MaxEnvSize: -1},
+			err:  "max_env_size",
+		},
+		{
+			name: "env-value",
+			conf: &ExecConfig{Env: []string{"PATH=/bin"}},
+			err:  "invalid exec env",
+		},
+		{
+			name: "env-wildcard",
+			conf: &ExecConfig{Env: []string{"*"}},
+			err:  "invalid exec env",
+		},
+		{
+			name: "env-inner-wildcard",
+			conf: &ExecConfig{Env: []string{"A*B"}},
+			err:  "invalid exec env",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.conf.validate()
+			if len(tc.err) == 0 {
+				if err != nil {
+					t.Errorf("validate(): %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("validate() wrong error, want: %q, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestExecArgs(t *testing.T) {
+	argv := []string{"sh", "-c", "id"}
+	env := []string{"PATH=/bin", "HOME=/root", "KUBERNETES_PORT=443", "SECRET=foo"}
+
+	// Arguments are reported in full by default.
+	var none *ExecArgs
+	if got, truncated := none.Argv(argv); !reflect.DeepEqual(got, argv) || truncated {
+		t.Errorf("Argv(): got: %q, %t", got, truncated)
+	}
+	if got, truncated := none.Env(env); !reflect.DeepEqual(got, env) || truncated {
+		t.Errorf("Env(): got: %q, %t", got, truncated)
+	}
+	if e := mergeExecConfigs([]*ExecConfig{nil, {}}); e != nil {
+		t.Errorf("mergeExecConfigs() without limits: got: %+v, want: nil", e)
+	}
+
+	e := mergeExecConfigs([]*ExecConfig{
+		{MaxArgvSize: 100, Env: []string{"PATH", "KUBERNETES_*", "SECRET"}},
+		{MaxArgvSize: 6, MaxEnvSize: 100, Env: []string{"PATH", "KUBERNETES_*", "HOME"}},
+	})
+	// "sh" and "-c" take 6 bytes with their null bytes.
+	if got, truncated := e.Argv(argv); !reflect.DeepEqual(got, argv[:2]) || !truncated {
+		t.Errorf("Argv(): got: %q, %t", got, truncated)
+	}
+	// Variables must be allowed by both sessions.
+	want := []string{"PATH=/bin", "KUBERNETES_PORT=443"}
+	if got, truncated := e.Env(env); !reflect.DeepEqual(got, want) || truncated {
+		t.Errorf("Env(): got: %q, %t, want: %q", got, truncated, want)
+	}
+}
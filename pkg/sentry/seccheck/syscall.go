@@ -14,6 +14,12 @@
 
 package seccheck
 
+import (
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/context"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
 // SyscallType is an enum that denotes different types of syscall points. There
 // are 2 types of syscall point: fully-schematized and raw. Schematizes are
 // points that have syscall specific format, e.g. open => {path, flags, mode}.
@@ -39,6 +45,9 @@ const (
 	// Copied from kernel.maxSyscallNum to avoid reverse dependency.
 	syscallsMax   = 2000
 	syscallPoints = syscallsMax * int(syscallTypesCount)
+
+	// lastSyscallInTable is the highest syscall number with raw points.
+	lastSyscallInTable = 441
 )
 
 // Fields that are common for many syscalls.
@@ -54,6 +63,38 @@ const (
 	// variables. Start after FieldSyscallPath because execveat(2) can collect
 	// path from FD.
 	FieldSyscallExecveEnvv = FieldSyscallPath + 1
+
+	// FieldSyscallExecveAsync is an optional field to serialize and send the
+	// enter point from a goroutine, so that execve doesn't wait for large
+	// vectors to be serialized. argv and envv are still copied in by the task.
+	// The point may be sent after the points that follow it, and it can't deny
+	// the syscall.
+	FieldSyscallExecveAsync = FieldSyscallExecveEnvv + 1
+)
+
+// Fields for read(2) and related syscalls.
+const (
+	// FieldSyscallReadData is an optional field to collect the data read, up
+	// to the limits set in SessionConfig.Payload. Start after FieldSyscallPath
+	// because reads also collect path from FD.
+	FieldSyscallReadData = FieldSyscallPath + 1
+)
+
+// Fields for write(2) and related syscalls, including sendto(2) and
+// sendmsg(2).
+const (
+	// FieldSyscallWriteData is an optional field to collect the data written,
+	// up to the limits set in SessionConfig.Payload. Start after
+	// FieldSyscallPath because writes also collect path from FD.
+	FieldSyscallWriteData = FieldSyscallPath + 1
+)
+
+// Fields for open(2) and related syscalls.
+const (
+	// FieldSyscallOpenPathname is an optional field to collect the pathname
+	// argument, which requires copying up to PATH_MAX bytes in from the task.
+	// Start after FieldSyscallPath because openat(2) can collect path from FD.
+	FieldSyscallOpenPathname = FieldSyscallPath + 1
 )
 
 // GetPointForSyscall translates the syscall number to the corresponding Point.
@@ -61,11 +102,63 @@ func GetPointForSyscall(typ SyscallType, sysno uintptr) Point {
 	return Point(sysno)*Point(syscallTypesCount) + Point(typ) + pointLengthBeforeSyscalls
 }
 
+// SyscallsEnabled returns true if the point of any syscall is enabled. It's
+// cheaper than SyscallEnabled, and is checked first on the syscall path.
+func (s *State) SyscallsEnabled() bool {
+	return s.syscallsEnabled.Load() != 0
+}
+
 // SyscallEnabled checks if the corresponding point for the syscall is enabled.
 func (s *State) SyscallEnabled(typ SyscallType, sysno uintptr) bool {
 	// Prevent overflow.
 	if sysno >= syscallsMax {
 		return false
 	}
-	return s.Enabled(GetPointForSyscall(typ, sysno))
+	return s.enabledSyscalls[sysno].Load()&(uint32(1)<<typ) != 0
+}
+
+// SendSyscallToCheckers calls Checker.Syscall for each checker registered at
+// the syscall point p, like SendToCheckers. Syscall points are the most
+// frequent, so the common case of a single registered checker is handled
+// without a closure.
+func (s *State) SendSyscallToCheckers(ctx context.Context, cid string, p Point, fields FieldSet, ctxData *pb.ContextData, msgType pb.MessageType, msg proto.Message) error {
+	epoch := s.beginDispatch()
+	defer s.endDispatch(epoch)
+	checkers := s.getCheckers()
+	if len(checkers) != 1 {
+		return s.sendTo(checkers, cid, p, func(c Checker) error {
+			return c.Syscall(ctx, fields, ctxData, msgType, msg)
+		})
+	}
+	d := dispatch{cid: cid, p: p}
+	pc := checkers[0].(*pointChecker)
+	if !d.admit(pc) {
+		return nil
+	}
+	if err, stop := d.result(pc, pc.Checker.Syscall(ctx, fields, ctxData, msgType, msg)); stop {
+		return err
+	}
+	return d.audit
+}
+
+// SendRawSyscallToCheckers is like SendSyscallToCheckers for raw syscall
+// points, calling Checker.RawSyscall.
+func (s *State) SendRawSyscallToCheckers(ctx context.Context, cid string, p Point, fields FieldSet, info *pb.Syscall) error {
+	epoch := s.beginDispatch()
+	defer s.endDispatch(epoch)
+	checkers := s.getCheckers()
+	if len(checkers) != 1 {
+		return s.sendTo(checkers, cid, p, func(c Checker) error {
+			return c.RawSyscall(ctx, fields, info)
+		})
+	}
+	d := dispatch{cid: cid, p: p}
+	pc := checkers[0].(*pointChecker)
+	if !d.admit(pc) {
+		return nil
+	}
+	if err, stop := d.result(pc, pc.Checker.RawSyscall(ctx, fields, info)); stop {
+		return err
+	}
+	return d.audit
 }
@@ -0,0 +1,117 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"os"
+	"path/filepath"
+
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/consumer"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// Consumer is a fake consumer of the remote sink that stores the decoded
+// events that it receives. Sinks that export events through the remote
+// protocol can be tested by sending events to them and checking what Consumer
+// received.
+type Consumer struct {
+	*consumer.Consumer
+
+	dir string
+
+	cond sync.Cond
+
+	// +checklocks:cond.L
+	events []*consumer.Event
+}
+
+// NewConsumer creates a Consumer that listens to a UDS that it creates under
+// os.TempDir. Close must be called to stop it and remove the socket.
+func NewConsumer() (*Consumer, error) {
+	dir, err := os.MkdirTemp(os.TempDir(), "consumer")
+	if err != nil {
+		return nil, err
+	}
+	c := &Consumer{
+		Consumer: consumer.New(filepath.Join(dir, "consumer.sock")),
+		dir:      dir,
+		cond:     sync.Cond{L: &sync.Mutex{}},
+	}
+	c.HandleAll(c.add)
+	if err := c.Start(); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close stops the consumer and removes its socket.
+func (c *Consumer) Close() {
+	c.Consumer.Close()
+	_ = os.RemoveAll(c.dir)
+}
+
+// SinkConfig returns the configuration of a remote sink that sends events to
+// c.
+func (c *Consumer) SinkConfig() seccheck.SinkConfig {
+	return seccheck.SinkConfig{
+		Name:   "remote",
+		Config: map[string]interface{}{"endpoint": c.Endpoint},
+	}
+}
+
+func (c *Consumer) add(e *consumer.Event) error {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	c.events = append(c.events, e)
+	c.cond.Broadcast()
+	return nil
+}
+
+// Count returns the number of events received.
+func (c *Consumer) Count() int {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	return len(c.events)
+}
+
+// Events returns all events received.
+func (c *Consumer) Events() []*consumer.Event {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	cpy := make([]*consumer.Event, len(c.events))
+	copy(cpy, c.events)
+	return cpy
+}
+
+// Reset throws away all events received so far and returns the number of
+// events discarded.
+func (c *Consumer) Reset() int {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	count := len(c.events)
+	c.events = nil
+	return count
+}
+
+// WaitForCount waits for the number of events to reach count.
+func (c *Consumer) WaitForCount(count int) {
+	c.cond.L.Lock()
+	defer c.cond.L.Unlock()
+	for len(c.events) < count {
+		c.cond.Wait()
+	}
+}
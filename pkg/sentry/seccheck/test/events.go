@@ -0,0 +1,151 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/consumer"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// maxFillDepth limits how deep Fill goes into nested messages, since some
+// messages are recursive.
+const maxFillDepth = 4
+
+// MessageTypes returns all message types that have a message, in the order of
+// their values.
+func MessageTypes() []pb.MessageType {
+	var types []pb.MessageType
+	for v := range pb.MessageType_name {
+		if _, err := consumer.NewMessage(pb.MessageType(v)); err == nil {
+			types = append(types, pb.MessageType(v))
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// MessageType returns the type of msg.
+func MessageType(msg proto.Message) (pb.MessageType, error) {
+	name := msg.ProtoReflect().Descriptor().FullName()
+	for _, t := range MessageTypes() {
+		other, _ := consumer.NewMessage(t)
+		if other.ProtoReflect().Descriptor().FullName() == name {
+			return t, nil
+		}
+	}
+	return pb.MessageType_MESSAGE_UNKNOWN, fmt.Errorf("unknown message %q", name)
+}
+
+// NewEvent returns a message of type t with all fields set by Fill, to check
+// that checkers handle every field.
+func NewEvent(t pb.MessageType) (proto.Message, error) {
+	msg, err := consumer.NewMessage(t)
+	if err != nil {
+		return nil, err
+	}
+	Fill(msg)
+	return msg, nil
+}
+
+// Fill sets all fields of msg, including nested messages, to non-zero values
+// derived from the field names and numbers, so that they are predictable and
+// differ between fields. Lists and maps get one element each. Oneofs get their
+// first field set.
+func Fill(msg proto.Message) {
+	fill(msg.ProtoReflect(), 0)
+}
+
+func fill(msg protoreflect.Message, depth int) {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if oneof := fd.ContainingOneof(); oneof != nil && oneof.Fields().Get(0) != fd {
+			continue
+		}
+		switch {
+		case fd.IsList():
+			if v, ok := fillValue(msg, fd, depth); ok {
+				msg.Mutable(fd).List().Append(v)
+			}
+		case fd.IsMap():
+			key, _ := scalarValue(fd.MapKey())
+			if fd.MapValue().Kind() == protoreflect.MessageKind {
+				if depth < maxFillDepth {
+					fill(msg.Mutable(fd).Map().Mutable(key.MapKey()).Message(), depth+1)
+				}
+			} else if v, ok := scalarValue(fd.MapValue()); ok {
+				msg.Mutable(fd).Map().Set(key.MapKey(), v)
+			}
+		default:
+			if v, ok := fillValue(msg, fd, depth); ok {
+				msg.Set(fd, v)
+			}
+		}
+	}
+}
+
+// fillValue returns a value for field fd of msg, or false if the field must
+// be left unset.
+func fillValue(msg protoreflect.Message, fd protoreflect.FieldDescriptor, depth int) (protoreflect.Value, bool) {
+	if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		if depth >= maxFillDepth {
+			return protoreflect.Value{}, false
+		}
+		var v protoreflect.Value
+		if fd.IsList() {
+			v = msg.Mutable(fd).List().NewElement()
+		} else {
+			v = msg.NewField(fd)
+		}
+		fill(v.Message(), depth+1)
+		return v, true
+	}
+	return scalarValue(fd)
+}
+
+// scalarValue returns a non-zero value for the scalar field fd.
+func scalarValue(fd protoreflect.FieldDescriptor) (protoreflect.Value, bool) {
+	n := int64(fd.Number())
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(true), true
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		// Use the last value, since the first one is usually the zero value.
+		return protoreflect.ValueOfEnum(values.Get(values.Len() - 1).Number()), true
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(n)), true
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(n), true
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(n)), true
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(uint64(n)), true
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(n)), true
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(float64(n)), true
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(string(fd.Name())), true
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(fd.Name())), true
+	}
+	return protoreflect.Value{}, false
+}
@@ -0,0 +1,175 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test provides helpers to unit test Checker implementations, e.g.
+// custom sinks, without running a sandbox. A typical test creates a State with
+// the checker under test, builds events with NewEvent, sends them with Send,
+// and inspects what the checker did with them, e.g. using Consumer for sinks
+// that export events through the remote protocol.
+package test
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// NewState returns a State with checker registered for points, as if a
+// session with the points had been created.
+func NewState(checker seccheck.Checker, points ...seccheck.PointConfig) (*seccheck.State, error) {
+	reqs, err := seccheck.PointReqs(points)
+	if err != nil {
+		return nil, err
+	}
+	s := &seccheck.State{}
+	s.AppendChecker(checker, reqs)
+	return s, nil
+}
+
+// Send sends msg to the checkers in s that are registered for point, the same
+// way the sentry does, including the error policy of the point. Checkers are
+// only called if the point is enabled.
+func Send(ctx context.Context, s *seccheck.State, point string, msg proto.Message) error {
+	desc, ok := seccheck.Points[point]
+	if !ok {
+		return fmt.Errorf("point %q not found", point)
+	}
+	if !s.Enabled(desc.ID) {
+		return nil
+	}
+	fields := s.GetFieldSet(desc.ID)
+	return s.SendToCheckers("" /* cid */, desc.ID, func(c seccheck.Checker) error {
+		return Dispatch(ctx, c, fields, msg)
+	})
+}
+
+// Dispatch calls the method of c that handles messages of the type of msg.
+func Dispatch(ctx context.Context, c seccheck.Checker, fields seccheck.FieldSet, msg proto.Message) error {
+	switch m := msg.(type) {
+	case *pb.CloneInfo:
+		return c.Clone(ctx, fields, m)
+	case *pb.ExecveInfo:
+		return c.Execve(ctx, fields, m)
+	case *pb.ExitNotifyParentInfo:
+		return c.ExitNotifyParent(ctx, fields, m)
+	case *pb.TaskExit:
+		return c.TaskExit(ctx, fields, m)
+	case *pb.SignalDeliverInfo:
+		return c.SignalDeliver(ctx, fields, m)
+	case *pb.OOMInfo:
+		return c.OOM(ctx, fields, m)
+	case *pb.CheckpointInfo:
+		return c.Checkpoint(ctx, fields, m)
+	case *pb.RestoreInfo:
+		return c.Restore(ctx, fields, m)
+	case *pb.CoreDumpInfo:
+		return c.CoreDump(ctx, fields, m)
+	case *pb.SeccompInfo:
+		return c.Seccomp(ctx, fields, m)
+	case *pb.CapabilityDeniedInfo:
+		return c.CapabilityDenied(ctx, fields, m)
+	case *pb.NamespaceCreateInfo:
+		return c.NamespaceCreate(ctx, fields, m)
+	case *pb.ExecMapInfo:
+		return c.ExecMap(ctx, fields, m)
+	case *pb.SyntheticFileWriteInfo:
+		return c.SyntheticFileWrite(ctx, fields, m)
+	case *pb.TCPEstablishedInfo:
+		return c.TCPEstablished(ctx, fields, m)
+	case *pb.DNSQueryInfo:
+		return c.DNSQuery(ctx, fields, m)
+	case *pb.ListenInfo:
+		return c.Listen(ctx, fields, m)
+	case *pb.GoferOpInfo:
+		return c.GoferOp(ctx, fields, m)
+	case *pb.FileOpenInfo:
+		return c.FileOpen(ctx, fields, m)
+	case *pb.TTYDataInfo:
+		return c.TTYData(ctx, fields, m)
+	case *pb.MajorFaultInfo:
+		return c.MajorFault(ctx, fields, m)
+	case *pb.RLimitBreachInfo:
+		return c.RLimitBreach(ctx, fields, m)
+	case *pb.CPUThrottleInfo:
+		return c.CPUThrottle(ctx, fields, m)
+	case *pb.PolicyViolationInfo:
+		return c.PolicyViolation(ctx, fields, m)
+	case *pb.FilelessExecInfo:
+		return c.FilelessExec(ctx, fields, m)
+	case *pb.AnomalyAlertInfo:
+		return c.AnomalyAlert(ctx, fields, m)
+	case *pb.DriftInfo:
+		return c.Drift(ctx, fields, m)
+	case *pb.Start:
+		return c.ContainerStart(ctx, fields, m)
+	case *pb.Stop:
+		return c.ContainerStop(ctx, fields, m)
+	case *pb.Pause:
+		return c.ContainerPause(ctx, fields, m)
+	case *pb.Resume:
+		return c.ContainerResume(ctx, fields, m)
+	case *pb.Exec:
+		return c.ContainerExec(ctx, fields, m)
+	case *pb.Syscall:
+		return c.RawSyscall(ctx, fields, m)
+	}
+
+	t, err := MessageType(msg)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(t.String(), "MESSAGE_SYSCALL_") {
+		return fmt.Errorf("%v messages are not sent to checkers", t)
+	}
+	var ctxData *pb.ContextData
+	if withCtx, ok := msg.(interface{ GetContextData() *pb.ContextData }); ok {
+		ctxData = withCtx.GetContextData()
+	}
+	return c.Syscall(ctx, fields, ctxData, t, msg)
+}
+
+// NewSink creates a sink the same way sessions do: Setup is called with
+// config, and its file, if any, is passed to New.
+func NewSink(desc seccheck.SinkDesc, config map[string]interface{}) (seccheck.Checker, error) {
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+	var endpoint *fd.FD
+	if desc.Setup != nil {
+		f, err := desc.Setup(config)
+		if err != nil {
+			return nil, fmt.Errorf("setting up sink %q: %w", desc.Name, err)
+		}
+		if f != nil {
+			endpoint, err = fd.NewFromFile(f)
+			_ = f.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	checker, err := desc.New(config, endpoint)
+	if err != nil {
+		if endpoint != nil {
+			_ = endpoint.Close()
+		}
+		return nil, fmt.Errorf("creating sink %q: %w", desc.Name, err)
+	}
+	return checker, nil
+}
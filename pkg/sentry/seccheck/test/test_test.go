@@ -0,0 +1,232 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+type recorder struct {
+	seccheck.CheckerDefaults
+
+	types []pb.MessageType
+}
+
+// Name implements seccheck.Checker.
+func (*recorder) Name() string {
+	return "recorder"
+}
+
+// Clone implements seccheck.Checker.
+func (r *recorder) Clone(context.Context, seccheck.FieldSet, *pb.CloneInfo) error {
+	r.types = append(r.types, pb.MessageType_MESSAGE_SENTRY_CLONE)
+	return nil
+}
+
+// ContainerStart implements seccheck.Checker.
+func (r *recorder) ContainerStart(context.Context, seccheck.FieldSet, *pb.Start) error {
+	r.types = append(r.types, pb.MessageType_MESSAGE_CONTAINER_START)
+	return nil
+}
+
+// Syscall implements seccheck.Checker.
+func (r *recorder) Syscall(_ context.Context, _ seccheck.FieldSet, ctxData *pb.ContextData, t pb.MessageType, _ proto.Message) error {
+	if ctxData == nil {
+		return os.ErrInvalid
+	}
+	r.types = append(r.types, t)
+	return nil
+}
+
+// RawSyscall implements seccheck.Checker.
+func (r *recorder) RawSyscall(context.Context, seccheck.FieldSet, *pb.Syscall) error {
+	r.types = append(r.types, pb.MessageType_MESSAGE_SYSCALL_RAW)
+	return nil
+}
+
+func TestNewEvent(t *testing.T) {
+	types := MessageTypes()
+	if len(types) == 0 {
+		t.Fatalf("MessageTypes() returned no types")
+	}
+	for _, typ := range types {
+		t.Run(typ.String(), func(t *testing.T) {
+			msg, err := NewEvent(typ)
+			if err != nil {
+				t.Fatalf("NewEvent(): %v", err)
+			}
+			if got, err := MessageType(msg); err != nil || got != typ {
+				t.Errorf("MessageType(): %v, %v, want: %v", got, err, typ)
+			}
+			data, err := proto.Marshal(msg)
+			if err != nil {
+				t.Fatalf("proto.Marshal(): %v", err)
+			}
+			if len(data) == 0 {
+				t.Errorf("NewEvent() returned an empty message")
+			}
+		})
+	}
+}
+
+func TestFill(t *testing.T) {
+	open := &pb.Open{}
+	Fill(open)
+	if open.ContextData == nil || open.ContextData.ThreadId == 0 {
+		t.Errorf("nested message not filled: %+v", open.ContextData)
+	}
+	if open.Exit == nil || open.Exit.Result == 0 {
+		t.Errorf("exit not filled: %+v", open.Exit)
+	}
+	if open.Pathname != "pathname" {
+		t.Errorf("wrong pathname, want: pathname, got: %q", open.Pathname)
+	}
+	if open.Fd == 0 || open.Flags == 0 {
+		t.Errorf("scalars not filled: %+v", open)
+	}
+
+	start := &pb.Start{}
+	Fill(start)
+	if len(start.Args) != 1 || start.Args[0] != "args" {
+		t.Errorf("wrong args, want: [args], got: %v", start.Args)
+	}
+}
+
+func TestSend(t *testing.T) {
+	r := &recorder{}
+	s, err := NewState(r,
+		seccheck.PointConfig{Name: "sentry/clone"},
+		seccheck.PointConfig{Name: "container/start"},
+	)
+	if err != nil {
+		t.Fatalf("NewState(): %v", err)
+	}
+
+	ctx := context.Background()
+	for _, tc := range []struct {
+		point string
+		typ   pb.MessageType
+	}{
+		{point: "sentry/clone", typ: pb.MessageType_MESSAGE_SENTRY_CLONE},
+		{point: "container/start", typ: pb.MessageType_MESSAGE_CONTAINER_START},
+		// Not enabled, must not reach the checker.
+		{point: "sentry/execve", typ: pb.MessageType_MESSAGE_SENTRY_EXEC},
+	} {
+		msg, err := NewEvent(tc.typ)
+		if err != nil {
+			t.Fatalf("NewEvent(%v): %v", tc.typ, err)
+		}
+		if err := Send(ctx, s, tc.point, msg); err != nil {
+			t.Errorf("Send(%q): %v", tc.point, err)
+		}
+	}
+	want := []pb.MessageType{pb.MessageType_MESSAGE_SENTRY_CLONE, pb.MessageType_MESSAGE_CONTAINER_START}
+	if len(r.types) != len(want) || r.types[0] != want[0] || r.types[1] != want[1] {
+		t.Errorf("wrong events, want: %v, got: %v", want, r.types)
+	}
+
+	if err := Send(ctx, s, "foo/bar", &pb.CloneInfo{}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Send() with unknown point, want: not found, got: %v", err)
+	}
+	if _, err := NewState(r, seccheck.PointConfig{Name: "foo/bar"}); err == nil {
+		t.Errorf("NewState() with unknown point should fail")
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	ctx := context.Background()
+	for _, typ := range []pb.MessageType{
+		pb.MessageType_MESSAGE_SYSCALL_OPEN,
+		pb.MessageType_MESSAGE_SYSCALL_RAW,
+		pb.MessageType_MESSAGE_SYSCALL_MMAP,
+	} {
+		r := &recorder{}
+		msg, err := NewEvent(typ)
+		if err != nil {
+			t.Fatalf("NewEvent(%v): %v", typ, err)
+		}
+		if err := Dispatch(ctx, r, seccheck.FieldSet{}, msg); err != nil {
+			t.Errorf("Dispatch(%v): %v", typ, err)
+		}
+		if len(r.types) != 1 || r.types[0] != typ {
+			t.Errorf("Dispatch(%v) called checker with: %v", typ, r.types)
+		}
+	}
+
+	if err := Dispatch(ctx, &recorder{}, seccheck.FieldSet{}, &pb.DropStats{}); err == nil {
+		t.Errorf("Dispatch(DropStats) should fail")
+	}
+}
+
+func TestNewSink(t *testing.T) {
+	var gotEndpoint *fd.FD
+	desc := seccheck.SinkDesc{
+		Name: "test",
+		Setup: func(config map[string]interface{}) (*os.File, error) {
+			config["setup"] = true
+			r, w, err := os.Pipe()
+			if err != nil {
+				return nil, err
+			}
+			_ = w.Close()
+			return r, nil
+		},
+		New: func(config map[string]interface{}, endpoint *fd.FD) (seccheck.Checker, error) {
+			if config["setup"] != true {
+				t.Errorf("config not passed from Setup to New: %v", config)
+			}
+			gotEndpoint = endpoint
+			return &recorder{}, nil
+		},
+	}
+	checker, err := NewSink(desc, nil)
+	if err != nil {
+		t.Fatalf("NewSink(): %v", err)
+	}
+	if checker.Name() != "recorder" {
+		t.Errorf("wrong checker: %q", checker.Name())
+	}
+	if gotEndpoint == nil {
+		t.Fatalf("endpoint not passed to New")
+	}
+	_ = gotEndpoint.Close()
+}
+
+func TestConsumer(t *testing.T) {
+	c, err := NewConsumer()
+	if err != nil {
+		t.Fatalf("NewConsumer(): %v", err)
+	}
+	defer c.Close()
+
+	cfg := c.SinkConfig()
+	if cfg.Name != "remote" || cfg.Config["endpoint"] != c.Endpoint {
+		t.Errorf("wrong sink config: %+v", cfg)
+	}
+	if _, err := os.Stat(c.Endpoint); err != nil {
+		t.Errorf("socket not created: %v", err)
+	}
+	if c.Count() != 0 {
+		t.Errorf("wrong count, want: 0, got: %d", c.Count())
+	}
+}
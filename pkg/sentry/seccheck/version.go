@@ -0,0 +1,144 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ConfigVersion is the version of the trace configuration format, e.g.
+// SessionConfig, supported by this release. It's incremented when fields are
+// added, so that older releases can tell fields that they don't know about
+// from typos.
+const ConfigVersion = 1
+
+// DecodeConfig decodes a trace configuration in json format from data into
+// out, e.g. a *SessionConfig. The version of the configuration is read from
+// its "version" field, and defaults to 1.
+//
+// Unknown fields are errors, since they are most likely typos, unless the
+// configuration is newer than ConfigVersion. Then, e.g. during a rolling
+// upgrade, unknown fields are ignored and returned as warnings, so that older
+// releases apply the parts of the configuration that they support.
+func DecodeConfig(data []byte, out interface{}) ([]string, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	if header.Version < 0 {
+		return nil, fmt.Errorf("invalid configuration version %d", header.Version)
+	}
+	if header.Version <= ConfigVersion {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		return nil, decoder.Decode(out)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	warnings := []string{fmt.Sprintf("configuration version %d is newer than the supported version %d, unknown fields are ignored", header.Version, ConfigVersion)}
+	for _, field := range unknownFields(reflect.TypeOf(out), generic, "") {
+		warnings = append(warnings, fmt.Sprintf("ignoring unknown field %q", field))
+	}
+	return warnings, nil
+}
+
+// unknownFields returns the paths of the fields in v, decoded from json into
+// an interface{}, that are not in type t.
+func unknownFields(t reflect.Type, v interface{}, path string) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var unknown []string
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, name := range sortedKeys(obj) {
+			fieldPath := name
+			if len(path) > 0 {
+				fieldPath = path + "." + name
+			}
+			field, ok := jsonField(t, name)
+			if !ok {
+				unknown = append(unknown, fieldPath)
+				continue
+			}
+			unknown = append(unknown, unknownFields(field.Type, obj[name], fieldPath)...)
+		}
+	case reflect.Slice, reflect.Array:
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, elem := range list {
+			unknown = append(unknown, unknownFields(t.Elem(), elem, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case reflect.Map:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, key := range sortedKeys(obj) {
+			unknown = append(unknown, unknownFields(t.Elem(), obj[key], path+"."+key)...)
+		}
+	}
+	return unknown
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// jsonField returns the field of struct type t that json name is decoded
+// into, matching names case-insensitively like encoding/json.
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue // unexported
+		}
+		fieldName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if fieldName == "-" {
+			continue
+		}
+		if len(fieldName) == 0 {
+			fieldName = field.Name
+		}
+		if strings.EqualFold(fieldName, name) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netstack
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// SeccheckDispatcher implements stack.TransportEventDispatcher by reporting
+// transport endpoint events to seccheck.
+type SeccheckDispatcher struct{}
+
+var _ stack.TransportEventDispatcher = SeccheckDispatcher{}
+
+// OnTCPEstablished implements stack.TransportEventDispatcher.OnTCPEstablished.
+func (SeccheckDispatcher) OnTCPEstablished(info stack.TCPEstablishedInfo) {
+	if !seccheck.Global.Enabled(seccheck.PointTCPEstablished) {
+		return
+	}
+	msg := &pb.TCPEstablishedInfo{
+		Family:        seccheckFamily(info.NetProto),
+		LocalAddress:  []byte(info.ID.LocalAddress),
+		LocalPort:     uint32(info.ID.LocalPort),
+		RemoteAddress: []byte(info.ID.RemoteAddress),
+		RemotePort:    uint32(info.ID.RemotePort),
+		Active:        info.Active,
+	}
+	fields := seccheck.Global.GetFieldSet(seccheck.PointTCPEstablished)
+	msg.ContextData = seccheckContextData(info.Owner, fields.Context)
+	seccheck.Global.SendToCheckers("" /* cid */, seccheck.PointTCPEstablished, func(c seccheck.Checker) error {
+		return c.TCPEstablished(context.Background(), fields, msg)
+	})
+}
+
+// OnDNSQuery implements stack.TransportEventDispatcher.OnDNSQuery.
+func (SeccheckDispatcher) OnDNSQuery(info stack.DNSQueryInfo) {
+	if !seccheck.Global.Enabled(seccheck.PointDNSQuery) {
+		return
+	}
+	msg := &pb.DNSQueryInfo{
+		Family:        seccheckFamily(info.NetProto),
+		LocalAddress:  []byte(info.ID.LocalAddress),
+		LocalPort:     uint32(info.ID.LocalPort),
+		RemoteAddress: []byte(info.ID.RemoteAddress),
+		RemotePort:    uint32(info.ID.RemotePort),
+		Name:          info.Name,
+		Type:          uint32(info.Type),
+	}
+	fields := seccheck.Global.GetFieldSet(seccheck.PointDNSQuery)
+	msg.ContextData = seccheckContextData(info.Owner, fields.Context)
+	seccheck.Global.SendToCheckers("" /* cid */, seccheck.PointDNSQuery, func(c seccheck.Checker) error {
+		return c.DNSQuery(context.Background(), fields, msg)
+	})
+}
+
+// OnListen implements stack.TransportEventDispatcher.OnListen.
+func (SeccheckDispatcher) OnListen(info stack.ListenInfo) {
+	if !seccheck.Global.Enabled(seccheck.PointListen) {
+		return
+	}
+	msg := &pb.ListenInfo{
+		Family:   seccheckFamily(info.NetProto),
+		Protocol: uint32(info.TransProto),
+		Address:  []byte(info.ID.LocalAddress),
+		Port:     uint32(info.ID.LocalPort),
+	}
+	fields := seccheck.Global.GetFieldSet(seccheck.PointListen)
+	msg.ContextData = seccheckContextData(info.Owner, fields.Context)
+	seccheck.Global.SendToCheckers("" /* cid */, seccheck.PointListen, func(c seccheck.Checker) error {
+		return c.Listen(context.Background(), fields, msg)
+	})
+}
+
+// seccheckFamily returns the address family corresponding to netProto.
+func seccheckFamily(netProto tcpip.NetworkProtocolNumber) uint32 {
+	if netProto == header.IPv6ProtocolNumber {
+		return linux.AF_INET6
+	}
+	return linux.AF_INET
+}
+
+// seccheckContextData returns the context data for the task owning an
+// endpoint, or nil if the endpoint isn't owned by a task or no context fields
+// are requested.
+func seccheckContextData(owner tcpip.PacketOwner, mask seccheck.FieldMask) *pb.ContextData {
+	t, ok := owner.(*kernel.Task)
+	if !ok || mask.Empty() {
+		return nil
+	}
+	data := &pb.ContextData{}
+	kernel.LoadSeccheckData(t, mask, data)
+	return data
+}
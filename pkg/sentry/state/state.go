@@ -100,6 +100,12 @@ func (opts SaveOpts) Save(ctx context.Context, k *kernel.Kernel, w *watchdog.Wat
 	return err
 }
 
+// PreviousMetadata returns the metadata read from the state file during the
+// last restore, or nil if the sandbox was not restored.
+func PreviousMetadata() map[string]string {
+	return previousMetadata
+}
+
 // LoadOpts contains load-related options.
 type LoadOpts struct {
 	// Destination is the load source.
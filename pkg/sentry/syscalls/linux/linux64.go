@@ -53,7 +53,7 @@ var AMD64 = &kernel.SyscallTable{
 	AuditNumber: linux.AUDIT_ARCH_X86_64,
 	Table: map[uintptr]kernel.Syscall{
 		0:   syscalls.SupportedPoint("read", Read, PointRead),
-		1:   syscalls.Supported("write", Write),
+		1:   syscalls.SupportedPoint("write", Write, PointWrite),
 		2:   syscalls.PartiallySupportedPoint("open", Open, PointOpen, "Options O_DIRECT, O_NOATIME, O_PATH, O_TMPFILE, O_SYNC are not supported.", nil),
 		3:   syscalls.Supported("close", Close),
 		4:   syscalls.Supported("stat", Stat),
@@ -61,18 +61,18 @@ var AMD64 = &kernel.SyscallTable{
 		6:   syscalls.Supported("lstat", Lstat),
 		7:   syscalls.Supported("poll", Poll),
 		8:   syscalls.Supported("lseek", Lseek),
-		9:   syscalls.PartiallySupported("mmap", Mmap, "Generally supported with exceptions. Options MAP_FIXED_NOREPLACE, MAP_SHARED_VALIDATE, MAP_SYNC MAP_GROWSDOWN, MAP_HUGETLB are not supported.", nil),
-		10:  syscalls.Supported("mprotect", Mprotect),
+		9:   syscalls.PartiallySupportedPoint("mmap", Mmap, PointMmap, "Generally supported with exceptions. Options MAP_FIXED_NOREPLACE, MAP_SHARED_VALIDATE, MAP_SYNC MAP_GROWSDOWN, MAP_HUGETLB are not supported.", nil),
+		10:  syscalls.SupportedPoint("mprotect", Mprotect, PointMprotect),
 		11:  syscalls.Supported("munmap", Munmap),
 		12:  syscalls.Supported("brk", Brk),
 		13:  syscalls.Supported("rt_sigaction", RtSigaction),
 		14:  syscalls.Supported("rt_sigprocmask", RtSigprocmask),
 		15:  syscalls.Supported("rt_sigreturn", RtSigreturn),
 		16:  syscalls.PartiallySupported("ioctl", Ioctl, "Only a few ioctls are implemented for backing devices and file systems.", nil),
-		17:  syscalls.Supported("pread64", Pread64),
-		18:  syscalls.Supported("pwrite64", Pwrite64),
-		19:  syscalls.Supported("readv", Readv),
-		20:  syscalls.Supported("writev", Writev),
+		17:  syscalls.SupportedPoint("pread64", Pread64, PointPread64),
+		18:  syscalls.SupportedPoint("pwrite64", Pwrite64, PointPwrite64),
+		19:  syscalls.SupportedPoint("readv", Readv, PointReadv),
+		20:  syscalls.SupportedPoint("writev", Writev, PointWritev),
 		21:  syscalls.Supported("access", Access),
 		22:  syscalls.SupportedPoint("pipe", Pipe, PointPipe),
 		23:  syscalls.Supported("select", Select),
@@ -96,18 +96,18 @@ var AMD64 = &kernel.SyscallTable{
 		41:  syscalls.PartiallySupported("socket", Socket, "Limited support for AF_NETLINK, NETLINK_ROUTE sockets. Limited support for SOCK_RAW.", nil),
 		42:  syscalls.SupportedPoint("connect", Connect, PointConnect),
 		43:  syscalls.SupportedPoint("accept", Accept, PointAccept),
-		44:  syscalls.Supported("sendto", SendTo),
+		44:  syscalls.SupportedPoint("sendto", SendTo, PointSendto),
 		45:  syscalls.Supported("recvfrom", RecvFrom),
-		46:  syscalls.Supported("sendmsg", SendMsg),
+		46:  syscalls.SupportedPoint("sendmsg", SendMsg, PointSendmsg),
 		47:  syscalls.PartiallySupported("recvmsg", RecvMsg, "Not all flags and control messages are supported.", nil),
-		48:  syscalls.PartiallySupported("shutdown", Shutdown, "Not all flags and control messages are supported.", nil),
+		48:  syscalls.PartiallySupportedPoint("shutdown", Shutdown, PointShutdown, "Not all flags and control messages are supported.", nil),
 		49:  syscalls.PartiallySupportedPoint("bind", Bind, PointBind, "Autobind for abstract Unix sockets is not supported.", nil),
 		50:  syscalls.Supported("listen", Listen),
-		51:  syscalls.Supported("getsockname", GetSockName),
-		52:  syscalls.Supported("getpeername", GetPeerName),
+		51:  syscalls.SupportedPoint("getsockname", GetSockName, PointGetsockname),
+		52:  syscalls.SupportedPoint("getpeername", GetPeerName, PointGetpeername),
 		53:  syscalls.SupportedPoint("socketpair", SocketPair, PointSocketpair),
-		54:  syscalls.PartiallySupported("setsockopt", SetSockOpt, "Not all socket options are supported.", nil),
-		55:  syscalls.PartiallySupported("getsockopt", GetSockOpt, "Not all socket options are supported.", nil),
+		54:  syscalls.PartiallySupportedPoint("setsockopt", SetSockOpt, PointSetsockopt, "Not all socket options are supported.", nil),
+		55:  syscalls.PartiallySupportedPoint("getsockopt", GetSockOpt, PointGetsockopt, "Not all socket options are supported.", nil),
 		56:  syscalls.PartiallySupportedPoint("clone", Clone, PointClone, "Mount namespace (CLONE_NEWNS) not supported. Options CLONE_PARENT, CLONE_SYSVSEM not supported.", nil),
 		57:  syscalls.SupportedPoint("fork", Fork, PointFork),
 		58:  syscalls.SupportedPoint("vfork", Vfork, PointVfork),
@@ -115,31 +115,31 @@ var AMD64 = &kernel.SyscallTable{
 		60:  syscalls.Supported("exit", Exit),
 		61:  syscalls.Supported("wait4", Wait4),
 		62:  syscalls.Supported("kill", Kill),
-		63:  syscalls.Supported("uname", Uname),
-		64:  syscalls.Supported("semget", Semget),
-		65:  syscalls.PartiallySupported("semop", Semop, "Option SEM_UNDO not supported.", nil),
+		63:  syscalls.SupportedPoint("uname", Uname, PointUname),
+		64:  syscalls.SupportedPoint("semget", Semget, PointSemget),
+		65:  syscalls.PartiallySupportedPoint("semop", Semop, PointSemop, "Option SEM_UNDO not supported.", nil),
 		66:  syscalls.Supported("semctl", Semctl),
 		67:  syscalls.Supported("shmdt", Shmdt),
-		68:  syscalls.Supported("msgget", Msgget),
-		69:  syscalls.Supported("msgsnd", Msgsnd),
-		70:  syscalls.Supported("msgrcv", Msgrcv),
+		68:  syscalls.SupportedPoint("msgget", Msgget, PointMsgget),
+		69:  syscalls.SupportedPoint("msgsnd", Msgsnd, PointMsgsnd),
+		70:  syscalls.SupportedPoint("msgrcv", Msgrcv, PointMsgrcv),
 		71:  syscalls.Supported("msgctl", Msgctl),
 		72:  syscalls.PartiallySupportedPoint("fcntl", Fcntl, PointFcntl, "Not all options are supported.", nil),
-		73:  syscalls.PartiallySupported("flock", Flock, "Locks are held within the sandbox only.", nil),
-		74:  syscalls.PartiallySupported("fsync", Fsync, "Full data flush is not guaranteed at this time.", nil),
-		75:  syscalls.PartiallySupported("fdatasync", Fdatasync, "Full data flush is not guaranteed at this time.", nil),
+		73:  syscalls.PartiallySupportedPoint("flock", Flock, PointFlock, "Locks are held within the sandbox only.", nil),
+		74:  syscalls.PartiallySupportedPoint("fsync", Fsync, PointFsync, "Full data flush is not guaranteed at this time.", nil),
+		75:  syscalls.PartiallySupportedPoint("fdatasync", Fdatasync, PointFdatasync, "Full data flush is not guaranteed at this time.", nil),
 		76:  syscalls.Supported("truncate", Truncate),
 		77:  syscalls.Supported("ftruncate", Ftruncate),
 		78:  syscalls.Supported("getdents", Getdents),
 		79:  syscalls.Supported("getcwd", Getcwd),
 		80:  syscalls.SupportedPoint("chdir", Chdir, PointChdir),
 		81:  syscalls.SupportedPoint("fchdir", Fchdir, PointFchdir),
-		82:  syscalls.Supported("rename", Rename),
+		82:  syscalls.SupportedPoint("rename", Rename, PointRename),
 		83:  syscalls.Supported("mkdir", Mkdir),
-		84:  syscalls.Supported("rmdir", Rmdir),
-		85:  syscalls.Supported("creat", Creat),
+		84:  syscalls.SupportedPoint("rmdir", Rmdir, PointRmdir),
+		85:  syscalls.SupportedPoint("creat", Creat, PointCreat),
 		86:  syscalls.PartiallySupported("link", Link, "Limited support with Gofer. Link count and linked files may get out of sync because gVisor is not aware of external hardlinks.", nil),
-		87:  syscalls.Supported("unlink", Unlink),
+		87:  syscalls.SupportedPoint("unlink", Unlink, PointUnlink),
 		88:  syscalls.Supported("symlink", Symlink),
 		89:  syscalls.Supported("readlink", Readlink),
 		90:  syscalls.Supported("chmod", Chmod),
@@ -151,9 +151,9 @@ var AMD64 = &kernel.SyscallTable{
 		96:  syscalls.Supported("gettimeofday", Gettimeofday),
 		97:  syscalls.Supported("getrlimit", Getrlimit),
 		98:  syscalls.PartiallySupported("getrusage", Getrusage, "Fields ru_maxrss, ru_minflt, ru_majflt, ru_inblock, ru_oublock are not supported. Fields ru_utime and ru_stime have low precision.", nil),
-		99:  syscalls.PartiallySupported("sysinfo", Sysinfo, "Fields loads, sharedram, bufferram, totalswap, freeswap, totalhigh, freehigh not supported.", nil),
+		99:  syscalls.PartiallySupportedPoint("sysinfo", Sysinfo, PointSysinfo, "Fields loads, sharedram, bufferram, totalswap, freeswap, totalhigh, freehigh not supported.", nil),
 		100: syscalls.Supported("times", Times),
-		101: syscalls.PartiallySupported("ptrace", Ptrace, "Options PTRACE_PEEKSIGINFO, PTRACE_SECCOMP_GET_FILTER not supported.", nil),
+		101: syscalls.PartiallySupportedPoint("ptrace", Ptrace, PointPtrace, "Options PTRACE_PEEKSIGINFO, PTRACE_SECCOMP_GET_FILTER not supported.", nil),
 		102: syscalls.Supported("getuid", Getuid),
 		103: syscalls.PartiallySupported("syslog", Syslog, "Outputs a dummy message for security reasons.", nil),
 		104: syscalls.Supported("getgid", Getgid),
@@ -165,8 +165,8 @@ var AMD64 = &kernel.SyscallTable{
 		110: syscalls.Supported("getppid", Getppid),
 		111: syscalls.Supported("getpgrp", Getpgrp),
 		112: syscalls.SupportedPoint("setsid", Setsid, PointSetsid),
-		113: syscalls.Supported("setreuid", Setreuid),
-		114: syscalls.Supported("setregid", Setregid),
+		113: syscalls.SupportedPoint("setreuid", Setreuid, PointSetreuid),
+		114: syscalls.SupportedPoint("setregid", Setregid, PointSetregid),
 		115: syscalls.Supported("getgroups", Getgroups),
 		116: syscalls.Supported("setgroups", Setgroups),
 		117: syscalls.SupportedPoint("setresuid", Setresuid, PointSetresuid),
@@ -184,10 +184,10 @@ var AMD64 = &kernel.SyscallTable{
 		129: syscalls.Supported("rt_sigqueueinfo", RtSigqueueinfo),
 		130: syscalls.Supported("rt_sigsuspend", RtSigsuspend),
 		131: syscalls.Supported("sigaltstack", Sigaltstack),
-		132: syscalls.Supported("utime", Utime),
-		133: syscalls.PartiallySupported("mknod", Mknod, "Device creation is not generally supported. Only regular file and FIFO creation are supported.", nil),
+		132: syscalls.SupportedPoint("utime", Utime, PointUtime),
+		133: syscalls.PartiallySupportedPoint("mknod", Mknod, PointMknod, "Device creation is not generally supported. Only regular file and FIFO creation are supported.", nil),
 		134: syscalls.Error("uselib", linuxerr.ENOSYS, "Obsolete", nil),
-		135: syscalls.ErrorWithEvent("personality", linuxerr.EINVAL, "Unable to change personality.", nil),
+		135: syscalls.ErrorWithEventPoint("personality", linuxerr.EINVAL, PointPersonality, "Unable to change personality.", nil),
 		136: syscalls.ErrorWithEvent("ustat", linuxerr.ENOSYS, "Needs filesystem support.", nil),
 		137: syscalls.PartiallySupported("statfs", Statfs, "Depends on the backing file system implementation.", nil),
 		138: syscalls.PartiallySupported("fstatfs", Fstatfs, "Depends on the backing file system implementation.", nil),
@@ -214,11 +214,11 @@ var AMD64 = &kernel.SyscallTable{
 		159: syscalls.CapError("adjtimex", linux.CAP_SYS_TIME, "", nil),
 		160: syscalls.PartiallySupported("setrlimit", Setrlimit, "Not all rlimits are enforced.", nil),
 		161: syscalls.SupportedPoint("chroot", Chroot, PointChroot),
-		162: syscalls.PartiallySupported("sync", Sync, "Full data flush is not guaranteed at this time.", nil),
-		163: syscalls.CapError("acct", linux.CAP_SYS_PACCT, "", nil),
+		162: syscalls.PartiallySupportedPoint("sync", Sync, PointSync, "Full data flush is not guaranteed at this time.", nil),
+		163: syscalls.CapErrorPoint("acct", linux.CAP_SYS_PACCT, PointAcct, "", nil),
 		164: syscalls.CapError("settimeofday", linux.CAP_SYS_TIME, "", nil),
-		165: syscalls.PartiallySupported("mount", Mount, "Not all options or file systems are supported.", nil),
-		166: syscalls.PartiallySupported("umount2", Umount2, "Not all options or file systems are supported.", nil),
+		165: syscalls.PartiallySupportedPoint("mount", Mount, PointMount, "Not all options or file systems are supported.", nil),
+		166: syscalls.PartiallySupportedPoint("umount2", Umount2, PointUmount2, "Not all options or file systems are supported.", nil),
 		167: syscalls.CapError("swapon", linux.CAP_SYS_ADMIN, "", nil),
 		168: syscalls.CapError("swapoff", linux.CAP_SYS_ADMIN, "", nil),
 		169: syscalls.CapError("reboot", linux.CAP_SYS_BOOT, "", nil),
@@ -287,17 +287,17 @@ var AMD64 = &kernel.SyscallTable{
 		232: syscalls.Supported("epoll_wait", EpollWait),
 		233: syscalls.Supported("epoll_ctl", EpollCtl),
 		234: syscalls.Supported("tgkill", Tgkill),
-		235: syscalls.Supported("utimes", Utimes),
+		235: syscalls.SupportedPoint("utimes", Utimes, PointUtimes),
 		236: syscalls.Error("vserver", linuxerr.ENOSYS, "Not implemented by Linux", nil),
 		237: syscalls.PartiallySupported("mbind", Mbind, "Stub implementation. Only a single NUMA node is advertised, and mempolicy is ignored accordingly, but mbind() will succeed and has effects reflected by get_mempolicy.", []string{"gvisor.dev/issue/262"}),
 		238: syscalls.PartiallySupported("set_mempolicy", SetMempolicy, "Stub implementation.", nil),
 		239: syscalls.PartiallySupported("get_mempolicy", GetMempolicy, "Stub implementation.", nil),
-		240: syscalls.ErrorWithEvent("mq_open", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),         // TODO(b/29354921)
-		241: syscalls.ErrorWithEvent("mq_unlink", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),       // TODO(b/29354921)
-		242: syscalls.ErrorWithEvent("mq_timedsend", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),    // TODO(b/29354921)
-		243: syscalls.ErrorWithEvent("mq_timedreceive", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}), // TODO(b/29354921)
-		244: syscalls.ErrorWithEvent("mq_notify", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),       // TODO(b/29354921)
-		245: syscalls.ErrorWithEvent("mq_getsetattr", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),   // TODO(b/29354921)
+		240: syscalls.ErrorWithEventPoint("mq_open", linuxerr.ENOSYS, PointMqOpen, "", []string{"gvisor.dev/issue/136"}),                 // TODO(b/29354921)
+		241: syscalls.ErrorWithEvent("mq_unlink", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),                                 // TODO(b/29354921)
+		242: syscalls.ErrorWithEventPoint("mq_timedsend", linuxerr.ENOSYS, PointMqTimedsend, "", []string{"gvisor.dev/issue/136"}),       // TODO(b/29354921)
+		243: syscalls.ErrorWithEventPoint("mq_timedreceive", linuxerr.ENOSYS, PointMqTimedreceive, "", []string{"gvisor.dev/issue/136"}), // TODO(b/29354921)
+		244: syscalls.ErrorWithEvent("mq_notify", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),                                 // TODO(b/29354921)
+		245: syscalls.ErrorWithEvent("mq_getsetattr", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),                             // TODO(b/29354921)
 		246: syscalls.CapError("kexec_load", linux.CAP_SYS_BOOT, "", nil),
 		247: syscalls.Supported("waitid", Waitid),
 		248: syscalls.Error("add_key", linuxerr.EACCES, "Not available to user.", nil),
@@ -311,12 +311,12 @@ var AMD64 = &kernel.SyscallTable{
 		256: syscalls.CapError("migrate_pages", linux.CAP_SYS_NICE, "", nil),
 		257: syscalls.SupportedPoint("openat", Openat, PointOpenat),
 		258: syscalls.Supported("mkdirat", Mkdirat),
-		259: syscalls.Supported("mknodat", Mknodat),
+		259: syscalls.SupportedPoint("mknodat", Mknodat, PointMknodat),
 		260: syscalls.Supported("fchownat", Fchownat),
-		261: syscalls.Supported("futimesat", Futimesat),
+		261: syscalls.SupportedPoint("futimesat", Futimesat, PointFutimesat),
 		262: syscalls.Supported("fstatat", Fstatat),
-		263: syscalls.Supported("unlinkat", Unlinkat),
-		264: syscalls.Supported("renameat", Renameat),
+		263: syscalls.SupportedPoint("unlinkat", Unlinkat, PointUnlinkat),
+		264: syscalls.SupportedPoint("renameat", Renameat, PointRenameat),
 		265: syscalls.PartiallySupported("linkat", Linkat, "See link(2).", nil),
 		266: syscalls.Supported("symlinkat", Symlinkat),
 		267: syscalls.Supported("readlinkat", Readlinkat),
@@ -332,12 +332,12 @@ var AMD64 = &kernel.SyscallTable{
 		277: syscalls.PartiallySupported("sync_file_range", SyncFileRange, "Full data flush is not guaranteed at this time.", nil),
 		278: syscalls.ErrorWithEvent("vmsplice", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/138"}), // TODO(b/29354098)
 		279: syscalls.CapError("move_pages", linux.CAP_SYS_NICE, "", nil),                               // requires cap_sys_nice (mostly)
-		280: syscalls.Supported("utimensat", Utimensat),
+		280: syscalls.SupportedPoint("utimensat", Utimensat, PointUtimensat),
 		281: syscalls.Supported("epoll_pwait", EpollPwait),
 		282: syscalls.PartiallySupportedPoint("signalfd", Signalfd, PointSignalfd, "Semantics are slightly different.", []string{"gvisor.dev/issue/139"}),
 		283: syscalls.SupportedPoint("timerfd_create", TimerfdCreate, PointTimerfdCreate),
 		284: syscalls.SupportedPoint("eventfd", Eventfd, PointEventfd),
-		285: syscalls.PartiallySupported("fallocate", Fallocate, "Not all options are supported.", nil),
+		285: syscalls.PartiallySupportedPoint("fallocate", Fallocate, PointFallocate, "Not all options are supported.", nil),
 		286: syscalls.SupportedPoint("timerfd_settime", TimerfdSettime, PointTimerfdSettime),
 		287: syscalls.SupportedPoint("timerfd_gettime", TimerfdGettime, PointTimerfdGettime),
 		288: syscalls.SupportedPoint("accept4", Accept4, PointAccept4),
@@ -347,8 +347,8 @@ var AMD64 = &kernel.SyscallTable{
 		292: syscalls.SupportedPoint("dup3", Dup3, PointDup3),
 		293: syscalls.SupportedPoint("pipe2", Pipe2, PointPipe2),
 		294: syscalls.PartiallySupportedPoint("inotify_init1", InotifyInit1, PointInotifyInit1, "Inotify events are only available inside the sandbox. Hard links are treated as different watch targets in gofer fs.", nil),
-		295: syscalls.Supported("preadv", Preadv),
-		296: syscalls.Supported("pwritev", Pwritev),
+		295: syscalls.SupportedPoint("preadv", Preadv, PointPreadv),
+		296: syscalls.SupportedPoint("pwritev", Pwritev, PointPwritev),
 		297: syscalls.Supported("rt_tgsigqueueinfo", RtTgsigqueueinfo),
 		298: syscalls.ErrorWithEvent("perf_event_open", linuxerr.ENODEV, "No support for perf counters", nil),
 		299: syscalls.PartiallySupported("recvmmsg", RecvMMsg, "Not all flags and control messages are supported.", nil),
@@ -358,7 +358,7 @@ var AMD64 = &kernel.SyscallTable{
 		303: syscalls.Error("name_to_handle_at", linuxerr.EOPNOTSUPP, "Not supported by gVisor filesystems", nil),
 		304: syscalls.Error("open_by_handle_at", linuxerr.EOPNOTSUPP, "Not supported by gVisor filesystems", nil),
 		305: syscalls.CapError("clock_adjtime", linux.CAP_SYS_TIME, "", nil),
-		306: syscalls.PartiallySupported("syncfs", Syncfs, "Depends on backing file system.", nil),
+		306: syscalls.PartiallySupportedPoint("syncfs", Syncfs, PointSyncfs, "Depends on backing file system.", nil),
 		307: syscalls.PartiallySupported("sendmmsg", SendMMsg, "Not all flags and control messages are supported.", nil),
 		308: syscalls.ErrorWithEvent("setns", linuxerr.EOPNOTSUPP, "Needs filesystem support", []string{"gvisor.dev/issue/140"}), // TODO(b/29354995)
 		309: syscalls.Supported("getcpu", Getcpu),
@@ -382,8 +382,8 @@ var AMD64 = &kernel.SyscallTable{
 		// Syscalls implemented after 325 are "backports" from versions
 		// of Linux after 4.4.
 		326: syscalls.ErrorWithEvent("copy_file_range", linuxerr.ENOSYS, "", nil),
-		327: syscalls.Supported("preadv2", Preadv2),
-		328: syscalls.PartiallySupported("pwritev2", Pwritev2, "Flag RWF_HIPRI is not supported.", nil),
+		327: syscalls.SupportedPoint("preadv2", Preadv2, PointPreadv2),
+		328: syscalls.PartiallySupportedPoint("pwritev2", Pwritev2, PointPwritev2, "Flag RWF_HIPRI is not supported.", nil),
 		329: syscalls.ErrorWithEvent("pkey_mprotect", linuxerr.ENOSYS, "", nil),
 		330: syscalls.ErrorWithEvent("pkey_alloc", linuxerr.ENOSYS, "", nil),
 		331: syscalls.ErrorWithEvent("pkey_free", linuxerr.ENOSYS, "", nil),
@@ -462,22 +462,22 @@ var ARM64 = &kernel.SyscallTable{
 		29:  syscalls.PartiallySupported("ioctl", Ioctl, "Only a few ioctls are implemented for backing devices and file systems.", nil),
 		30:  syscalls.CapError("ioprio_set", linux.CAP_SYS_ADMIN, "", nil), // requires cap_sys_nice or cap_sys_admin (depending)
 		31:  syscalls.CapError("ioprio_get", linux.CAP_SYS_ADMIN, "", nil), // requires cap_sys_nice or cap_sys_admin (depending)
-		32:  syscalls.PartiallySupported("flock", Flock, "Locks are held within the sandbox only.", nil),
-		33:  syscalls.Supported("mknodat", Mknodat),
+		32:  syscalls.PartiallySupportedPoint("flock", Flock, PointFlock, "Locks are held within the sandbox only.", nil),
+		33:  syscalls.SupportedPoint("mknodat", Mknodat, PointMknodat),
 		34:  syscalls.Supported("mkdirat", Mkdirat),
-		35:  syscalls.Supported("unlinkat", Unlinkat),
+		35:  syscalls.SupportedPoint("unlinkat", Unlinkat, PointUnlinkat),
 		36:  syscalls.Supported("symlinkat", Symlinkat),
 		37:  syscalls.Supported("linkat", Linkat),
-		38:  syscalls.Supported("renameat", Renameat),
-		39:  syscalls.PartiallySupported("umount2", Umount2, "Not all options or file systems are supported.", nil),
-		40:  syscalls.PartiallySupported("mount", Mount, "Not all options or file systems are supported.", nil),
+		38:  syscalls.SupportedPoint("renameat", Renameat, PointRenameat),
+		39:  syscalls.PartiallySupportedPoint("umount2", Umount2, PointUmount2, "Not all options or file systems are supported.", nil),
+		40:  syscalls.PartiallySupportedPoint("mount", Mount, PointMount, "Not all options or file systems are supported.", nil),
 		41:  syscalls.Error("pivot_root", linuxerr.EPERM, "", nil),
 		42:  syscalls.Error("nfsservctl", linuxerr.ENOSYS, "Removed after Linux 3.1.", nil),
 		43:  syscalls.PartiallySupported("statfs", Statfs, "Depends on the backing file system implementation.", nil),
 		44:  syscalls.PartiallySupported("fstatfs", Fstatfs, "Depends on the backing file system implementation.", nil),
 		45:  syscalls.Supported("truncate", Truncate),
 		46:  syscalls.Supported("ftruncate", Ftruncate),
-		47:  syscalls.PartiallySupported("fallocate", Fallocate, "Not all options are supported.", nil),
+		47:  syscalls.PartiallySupportedPoint("fallocate", Fallocate, PointFallocate, "Not all options are supported.", nil),
 		48:  syscalls.Supported("faccessat", Faccessat),
 		49:  syscalls.SupportedPoint("chdir", Chdir, PointChdir),
 		50:  syscalls.SupportedPoint("fchdir", Fchdir, PointFchdir),
@@ -494,13 +494,13 @@ var ARM64 = &kernel.SyscallTable{
 		61:  syscalls.Supported("getdents64", Getdents64),
 		62:  syscalls.Supported("lseek", Lseek),
 		63:  syscalls.SupportedPoint("read", Read, PointRead),
-		64:  syscalls.Supported("write", Write),
-		65:  syscalls.Supported("readv", Readv),
-		66:  syscalls.Supported("writev", Writev),
-		67:  syscalls.Supported("pread64", Pread64),
-		68:  syscalls.Supported("pwrite64", Pwrite64),
-		69:  syscalls.Supported("preadv", Preadv),
-		70:  syscalls.Supported("pwritev", Pwritev),
+		64:  syscalls.SupportedPoint("write", Write, PointWrite),
+		65:  syscalls.SupportedPoint("readv", Readv, PointReadv),
+		66:  syscalls.SupportedPoint("writev", Writev, PointWritev),
+		67:  syscalls.SupportedPoint("pread64", Pread64, PointPread64),
+		68:  syscalls.SupportedPoint("pwrite64", Pwrite64, PointPwrite64),
+		69:  syscalls.SupportedPoint("preadv", Preadv, PointPreadv),
+		70:  syscalls.SupportedPoint("pwritev", Pwritev, PointPwritev),
 		71:  syscalls.Supported("sendfile", Sendfile),
 		72:  syscalls.Supported("pselect", Pselect),
 		73:  syscalls.Supported("ppoll", Ppoll),
@@ -511,18 +511,18 @@ var ARM64 = &kernel.SyscallTable{
 		78:  syscalls.Supported("readlinkat", Readlinkat),
 		79:  syscalls.Supported("fstatat", Fstatat),
 		80:  syscalls.Supported("fstat", Fstat),
-		81:  syscalls.PartiallySupported("sync", Sync, "Full data flush is not guaranteed at this time.", nil),
-		82:  syscalls.PartiallySupported("fsync", Fsync, "Full data flush is not guaranteed at this time.", nil),
-		83:  syscalls.PartiallySupported("fdatasync", Fdatasync, "Full data flush is not guaranteed at this time.", nil),
+		81:  syscalls.PartiallySupportedPoint("sync", Sync, PointSync, "Full data flush is not guaranteed at this time.", nil),
+		82:  syscalls.PartiallySupportedPoint("fsync", Fsync, PointFsync, "Full data flush is not guaranteed at this time.", nil),
+		83:  syscalls.PartiallySupportedPoint("fdatasync", Fdatasync, PointFdatasync, "Full data flush is not guaranteed at this time.", nil),
 		84:  syscalls.PartiallySupported("sync_file_range", SyncFileRange, "Full data flush is not guaranteed at this time.", nil),
 		85:  syscalls.SupportedPoint("timerfd_create", TimerfdCreate, PointTimerfdCreate),
 		86:  syscalls.SupportedPoint("timerfd_settime", TimerfdSettime, PointTimerfdSettime),
 		87:  syscalls.SupportedPoint("timerfd_gettime", TimerfdGettime, PointTimerfdGettime),
-		88:  syscalls.Supported("utimensat", Utimensat),
-		89:  syscalls.CapError("acct", linux.CAP_SYS_PACCT, "", nil),
+		88:  syscalls.SupportedPoint("utimensat", Utimensat, PointUtimensat),
+		89:  syscalls.CapErrorPoint("acct", linux.CAP_SYS_PACCT, PointAcct, "", nil),
 		90:  syscalls.Supported("capget", Capget),
 		91:  syscalls.Supported("capset", Capset),
-		92:  syscalls.ErrorWithEvent("personality", linuxerr.EINVAL, "Unable to change personality.", nil),
+		92:  syscalls.ErrorWithEventPoint("personality", linuxerr.EINVAL, PointPersonality, "Unable to change personality.", nil),
 		93:  syscalls.Supported("exit", Exit),
 		94:  syscalls.Supported("exit_group", ExitGroup),
 		95:  syscalls.Supported("waitid", Waitid),
@@ -547,7 +547,7 @@ var ARM64 = &kernel.SyscallTable{
 		114: syscalls.Supported("clock_getres", ClockGetres),
 		115: syscalls.Supported("clock_nanosleep", ClockNanosleep),
 		116: syscalls.PartiallySupported("syslog", Syslog, "Outputs a dummy message for security reasons.", nil),
-		117: syscalls.PartiallySupported("ptrace", Ptrace, "Options PTRACE_PEEKSIGINFO, PTRACE_SECCOMP_GET_FILTER not supported.", nil),
+		117: syscalls.PartiallySupportedPoint("ptrace", Ptrace, PointPtrace, "Options PTRACE_PEEKSIGINFO, PTRACE_SECCOMP_GET_FILTER not supported.", nil),
 		118: syscalls.CapError("sched_setparam", linux.CAP_SYS_NICE, "", nil),
 		119: syscalls.PartiallySupported("sched_setscheduler", SchedSetscheduler, "Stub implementation.", nil),
 		120: syscalls.PartiallySupported("sched_getscheduler", SchedGetscheduler, "Stub implementation.", nil),
@@ -573,9 +573,9 @@ var ARM64 = &kernel.SyscallTable{
 		140: syscalls.PartiallySupported("setpriority", Setpriority, "Stub implementation.", nil),
 		141: syscalls.PartiallySupported("getpriority", Getpriority, "Stub implementation.", nil),
 		142: syscalls.CapError("reboot", linux.CAP_SYS_BOOT, "", nil),
-		143: syscalls.Supported("setregid", Setregid),
+		143: syscalls.SupportedPoint("setregid", Setregid, PointSetregid),
 		144: syscalls.SupportedPoint("setgid", Setgid, PointSetgid),
-		145: syscalls.Supported("setreuid", Setreuid),
+		145: syscalls.SupportedPoint("setreuid", Setreuid, PointSetreuid),
 		146: syscalls.SupportedPoint("setuid", Setuid, PointSetuid),
 		147: syscalls.SupportedPoint("setresuid", Setresuid, PointSetresuid),
 		148: syscalls.Supported("getresuid", Getresuid),
@@ -590,7 +590,7 @@ var ARM64 = &kernel.SyscallTable{
 		157: syscalls.SupportedPoint("setsid", Setsid, PointSetsid),
 		158: syscalls.Supported("getgroups", Getgroups),
 		159: syscalls.Supported("setgroups", Setgroups),
-		160: syscalls.Supported("uname", Uname),
+		160: syscalls.SupportedPoint("uname", Uname, PointUname),
 		161: syscalls.Supported("sethostname", Sethostname),
 		162: syscalls.Supported("setdomainname", Setdomainname),
 		163: syscalls.Supported("getrlimit", Getrlimit),
@@ -609,21 +609,21 @@ var ARM64 = &kernel.SyscallTable{
 		176: syscalls.Supported("getgid", Getgid),
 		177: syscalls.Supported("getegid", Getegid),
 		178: syscalls.Supported("gettid", Gettid),
-		179: syscalls.PartiallySupported("sysinfo", Sysinfo, "Fields loads, sharedram, bufferram, totalswap, freeswap, totalhigh, freehigh not supported.", nil),
-		180: syscalls.ErrorWithEvent("mq_open", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),         // TODO(b/29354921)
-		181: syscalls.ErrorWithEvent("mq_unlink", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),       // TODO(b/29354921)
-		182: syscalls.ErrorWithEvent("mq_timedsend", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),    // TODO(b/29354921)
-		183: syscalls.ErrorWithEvent("mq_timedreceive", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}), // TODO(b/29354921)
-		184: syscalls.ErrorWithEvent("mq_notify", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),       // TODO(b/29354921)
-		185: syscalls.ErrorWithEvent("mq_getsetattr", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),   // TODO(b/29354921)
-		186: syscalls.Supported("msgget", Msgget),
+		179: syscalls.PartiallySupportedPoint("sysinfo", Sysinfo, PointSysinfo, "Fields loads, sharedram, bufferram, totalswap, freeswap, totalhigh, freehigh not supported.", nil),
+		180: syscalls.ErrorWithEventPoint("mq_open", linuxerr.ENOSYS, PointMqOpen, "", []string{"gvisor.dev/issue/136"}),                 // TODO(b/29354921)
+		181: syscalls.ErrorWithEvent("mq_unlink", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),                                 // TODO(b/29354921)
+		182: syscalls.ErrorWithEventPoint("mq_timedsend", linuxerr.ENOSYS, PointMqTimedsend, "", []string{"gvisor.dev/issue/136"}),       // TODO(b/29354921)
+		183: syscalls.ErrorWithEventPoint("mq_timedreceive", linuxerr.ENOSYS, PointMqTimedreceive, "", []string{"gvisor.dev/issue/136"}), // TODO(b/29354921)
+		184: syscalls.ErrorWithEvent("mq_notify", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),                                 // TODO(b/29354921)
+		185: syscalls.ErrorWithEvent("mq_getsetattr", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/136"}),                             // TODO(b/29354921)
+		186: syscalls.SupportedPoint("msgget", Msgget, PointMsgget),
 		187: syscalls.Supported("msgctl", Msgctl),
-		188: syscalls.Supported("msgrcv", Msgrcv),
-		189: syscalls.Supported("msgsnd", Msgsnd),
-		190: syscalls.Supported("semget", Semget),
+		188: syscalls.SupportedPoint("msgrcv", Msgrcv, PointMsgrcv),
+		189: syscalls.SupportedPoint("msgsnd", Msgsnd, PointMsgsnd),
+		190: syscalls.SupportedPoint("semget", Semget, PointSemget),
 		191: syscalls.Supported("semctl", Semctl),
 		192: syscalls.Supported("semtimedop", Semtimedop),
-		193: syscalls.PartiallySupported("semop", Semop, "Option SEM_UNDO not supported.", nil),
+		193: syscalls.PartiallySupportedPoint("semop", Semop, PointSemop, "Option SEM_UNDO not supported.", nil),
 		194: syscalls.PartiallySupported("shmget", Shmget, "Option SHM_HUGETLB is not supported.", nil),
 		195: syscalls.PartiallySupported("shmctl", Shmctl, "Options SHM_LOCK, SHM_UNLOCK are not supported.", nil),
 		196: syscalls.PartiallySupported("shmat", Shmat, "Option SHM_RND is not supported.", nil),
@@ -634,14 +634,14 @@ var ARM64 = &kernel.SyscallTable{
 		201: syscalls.Supported("listen", Listen),
 		202: syscalls.SupportedPoint("accept", Accept, PointAccept),
 		203: syscalls.SupportedPoint("connect", Connect, PointConnect),
-		204: syscalls.Supported("getsockname", GetSockName),
-		205: syscalls.Supported("getpeername", GetPeerName),
-		206: syscalls.Supported("sendto", SendTo),
+		204: syscalls.SupportedPoint("getsockname", GetSockName, PointGetsockname),
+		205: syscalls.SupportedPoint("getpeername", GetPeerName, PointGetpeername),
+		206: syscalls.SupportedPoint("sendto", SendTo, PointSendto),
 		207: syscalls.Supported("recvfrom", RecvFrom),
-		208: syscalls.PartiallySupported("setsockopt", SetSockOpt, "Not all socket options are supported.", nil),
-		209: syscalls.PartiallySupported("getsockopt", GetSockOpt, "Not all socket options are supported.", nil),
-		210: syscalls.PartiallySupported("shutdown", Shutdown, "Not all flags and control messages are supported.", nil),
-		211: syscalls.Supported("sendmsg", SendMsg),
+		208: syscalls.PartiallySupportedPoint("setsockopt", SetSockOpt, PointSetsockopt, "Not all socket options are supported.", nil),
+		209: syscalls.PartiallySupportedPoint("getsockopt", GetSockOpt, PointGetsockopt, "Not all socket options are supported.", nil),
+		210: syscalls.PartiallySupportedPoint("shutdown", Shutdown, PointShutdown, "Not all flags and control messages are supported.", nil),
+		211: syscalls.SupportedPoint("sendmsg", SendMsg, PointSendmsg),
 		212: syscalls.PartiallySupported("recvmsg", RecvMsg, "Not all flags and control messages are supported.", nil),
 		213: syscalls.Supported("readahead", Readahead),
 		214: syscalls.Supported("brk", Brk),
@@ -652,11 +652,11 @@ var ARM64 = &kernel.SyscallTable{
 		219: syscalls.Error("keyctl", linuxerr.EACCES, "Not available to user.", nil),
 		220: syscalls.PartiallySupportedPoint("clone", Clone, PointClone, "Mount namespace (CLONE_NEWNS) not supported. Options CLONE_PARENT, CLONE_SYSVSEM not supported.", nil),
 		221: syscalls.SupportedPoint("execve", Execve, PointExecve),
-		222: syscalls.PartiallySupported("mmap", Mmap, "Generally supported with exceptions. Options MAP_FIXED_NOREPLACE, MAP_SHARED_VALIDATE, MAP_SYNC MAP_GROWSDOWN, MAP_HUGETLB are not supported.", nil),
+		222: syscalls.PartiallySupportedPoint("mmap", Mmap, PointMmap, "Generally supported with exceptions. Options MAP_FIXED_NOREPLACE, MAP_SHARED_VALIDATE, MAP_SYNC MAP_GROWSDOWN, MAP_HUGETLB are not supported.", nil),
 		223: syscalls.PartiallySupported("fadvise64", Fadvise64, "Not all options are supported.", nil),
 		224: syscalls.CapError("swapon", linux.CAP_SYS_ADMIN, "", nil),
 		225: syscalls.CapError("swapoff", linux.CAP_SYS_ADMIN, "", nil),
-		226: syscalls.Supported("mprotect", Mprotect),
+		226: syscalls.SupportedPoint("mprotect", Mprotect, PointMprotect),
 		227: syscalls.PartiallySupported("msync", Msync, "Full data flush is not guaranteed at this time.", nil),
 		228: syscalls.PartiallySupported("mlock", Mlock, "Stub implementation. The sandbox lacks appropriate permissions.", nil),
 		229: syscalls.PartiallySupported("munlock", Munlock, "Stub implementation. The sandbox lacks appropriate permissions.", nil),
@@ -681,7 +681,7 @@ var ARM64 = &kernel.SyscallTable{
 		264: syscalls.Error("name_to_handle_at", linuxerr.EOPNOTSUPP, "Not supported by gVisor filesystems", nil),
 		265: syscalls.Error("open_by_handle_at", linuxerr.EOPNOTSUPP, "Not supported by gVisor filesystems", nil),
 		266: syscalls.CapError("clock_adjtime", linux.CAP_SYS_TIME, "", nil),
-		267: syscalls.PartiallySupported("syncfs", Syncfs, "Depends on backing file system.", nil),
+		267: syscalls.PartiallySupportedPoint("syncfs", Syncfs, PointSyncfs, "Depends on backing file system.", nil),
 		268: syscalls.ErrorWithEvent("setns", linuxerr.EOPNOTSUPP, "Needs filesystem support", []string{"gvisor.dev/issue/140"}), // TODO(b/29354995)
 		269: syscalls.PartiallySupported("sendmmsg", SendMMsg, "Not all flags and control messages are supported.", nil),
 		270: syscalls.ErrorWithEvent("process_vm_readv", linuxerr.ENOSYS, "", []string{"gvisor.dev/issue/158"}),
@@ -702,8 +702,8 @@ var ARM64 = &kernel.SyscallTable{
 
 		// Syscalls after 284 are "backports" from versions of Linux after 4.4.
 		285: syscalls.ErrorWithEvent("copy_file_range", linuxerr.ENOSYS, "", nil),
-		286: syscalls.Supported("preadv2", Preadv2),
-		287: syscalls.PartiallySupported("pwritev2", Pwritev2, "Flag RWF_HIPRI is not supported.", nil),
+		286: syscalls.SupportedPoint("preadv2", Preadv2, PointPreadv2),
+		287: syscalls.PartiallySupportedPoint("pwritev2", Pwritev2, PointPwritev2, "Flag RWF_HIPRI is not supported.", nil),
 		288: syscalls.ErrorWithEvent("pkey_mprotect", linuxerr.ENOSYS, "", nil),
 		289: syscalls.ErrorWithEvent("pkey_alloc", linuxerr.ENOSYS, "", nil),
 		290: syscalls.ErrorWithEvent("pkey_free", linuxerr.ENOSYS, "", nil),
@@ -19,11 +19,14 @@ import (
 
 	"google.golang.org/protobuf/proto"
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/usermem"
 )
 
 func newExitMaybe(info kernel.SyscallInfo) *pb.Exit {
@@ -36,6 +39,20 @@ func newExitMaybe(info kernel.SyscallInfo) *pb.Exit {
 	}
 }
 
+// enterMsg returns the message built for the enter point of the syscall, if
+// it can be reused at exit, see kernel.SyscallInfo.Enter. Converters reuse it
+// when their messages only depend on the syscall arguments, setting just the
+// context and the exit result.
+func enterMsg(info kernel.SyscallInfo) proto.Message {
+	if !info.Exit {
+		return nil
+	}
+	return info.Enter
+}
+
+// getFilePath returns the path of the file at fd. Paths are cached in the FD
+// table, since read and write points in hot loops resolve the same FDs over
+// and over.
 func getFilePath(t *kernel.Task, fd int32) string {
 	if fd < 0 {
 		return ""
@@ -51,27 +68,36 @@ func getFilePath(t *kernel.Task, fd int32) string {
 	defer file.DecRef(t)
 
 	root := t.MountNamespaceVFS2().Root()
+	if path, ok := fdt.CachedPath(fd, file, root); ok {
+		return path
+	}
 	path, err := t.Kernel().VFS().PathnameWithDeleted(t, root, file.VirtualDentry())
 	if err != nil {
 		return fmt.Sprintf("[err: %v]", err)
 	}
+	fdt.CachePath(fd, file, root, path)
 	return path
 }
 
 // PointOpen converts open(2) syscall to proto.
-func PointOpen(t *kernel.Task, _ seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
-	p := &pb.Open{
-		ContextData: cxtData,
-		Sysno:       uint64(info.Sysno),
-		Fd:          linux.AT_FDCWD,
-		Flags:       info.Args[1].Uint(),
-		Mode:        uint32(info.Args[2].ModeT()),
-	}
-	addr := info.Args[0].Pointer()
-	if addr > 0 {
-		path, err := t.CopyInString(addr, linux.PATH_MAX)
-		if err == nil { // if NO error
-			p.Pathname = path
+func PointOpen(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	if p, ok := enterMsg(info).(*pb.Open); ok {
+		p.ContextData = cxtData
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_OPEN
+	}
+	p := seccheck.NewOpen()
+	p.ContextData = cxtData
+	p.Sysno = uint64(info.Sysno)
+	p.Fd = linux.AT_FDCWD
+	p.Flags = info.Args[1].Uint()
+	p.Mode = uint32(info.Args[2].ModeT())
+	if fields.Local.Contains(seccheck.FieldSyscallOpenPathname) {
+		if addr := info.Args[0].Pointer(); addr > 0 {
+			path, err := t.CopyInString(addr, linux.PATH_MAX)
+			if err == nil { // if NO error
+				p.Pathname = path
+			}
 		}
 	}
 	p.Exit = newExitMaybe(info)
@@ -80,18 +106,23 @@ func PointOpen(t *kernel.Task, _ seccheck.FieldSet, cxtData *pb.ContextData, inf
 
 // PointOpenat converts openat(2) syscall to proto.
 func PointOpenat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
-	p := &pb.Open{
-		ContextData: cxtData,
-		Sysno:       uint64(info.Sysno),
-		Fd:          int64(info.Args[0].Int()),
-		Flags:       info.Args[2].Uint(),
-	}
-
-	addr := info.Args[1].Pointer()
-	if addr > 0 {
-		path, err := t.CopyInString(addr, linux.PATH_MAX)
-		if err == nil { // if NO error
-			p.Pathname = path
+	if p, ok := enterMsg(info).(*pb.Open); ok {
+		p.ContextData = cxtData
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_OPEN
+	}
+	p := seccheck.NewOpen()
+	p.ContextData = cxtData
+	p.Sysno = uint64(info.Sysno)
+	p.Fd = int64(info.Args[0].Int())
+	p.Flags = info.Args[2].Uint()
+
+	if fields.Local.Contains(seccheck.FieldSyscallOpenPathname) {
+		if addr := info.Args[1].Pointer(); addr > 0 {
+			path, err := t.CopyInString(addr, linux.PATH_MAX)
+			if err == nil { // if NO error
+				p.Pathname = path
+			}
 		}
 	}
 	if p.Flags&linux.O_CREAT != 0 {
@@ -109,19 +140,24 @@ func PointOpenat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextDa
 
 // PointCreat converts creat(2) syscall to proto.
 func PointCreat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
-	p := &pb.Open{
-		ContextData: cxtData,
-		Sysno:       uint64(info.Sysno),
-		Fd:          linux.AT_FDCWD,
-		Flags:       linux.O_WRONLY | linux.O_CREAT | linux.O_TRUNC,
-		Mode:        uint32(info.Args[1].ModeT()),
-	}
-
-	addr := info.Args[0].Pointer()
-	if addr > 0 {
-		path, err := t.CopyInString(addr, linux.PATH_MAX)
-		if err == nil { // if NO error
-			p.Pathname = path
+	if p, ok := enterMsg(info).(*pb.Open); ok {
+		p.ContextData = cxtData
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_OPEN
+	}
+	p := seccheck.NewOpen()
+	p.ContextData = cxtData
+	p.Sysno = uint64(info.Sysno)
+	p.Fd = linux.AT_FDCWD
+	p.Flags = linux.O_WRONLY | linux.O_CREAT | linux.O_TRUNC
+	p.Mode = uint32(info.Args[1].ModeT())
+
+	if fields.Local.Contains(seccheck.FieldSyscallOpenPathname) {
+		if addr := info.Args[0].Pointer(); addr > 0 {
+			path, err := t.CopyInString(addr, linux.PATH_MAX)
+			if err == nil { // if NO error
+				p.Pathname = path
+			}
 		}
 	}
 
@@ -150,9 +186,133 @@ func PointClose(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextDat
 	return p, pb.MessageType_MESSAGE_SYSCALL_CLOSE
 }
 
+// captureData captures the data transferred by the syscall, if field is
+// requested in fields. buf returns the buffers that the data was transferred
+// from or into, and it's only called if any data was transferred.
+func captureData(t *kernel.Task, fields seccheck.FieldSet, field seccheck.Field, info kernel.SyscallInfo, buf func(opts usermem.IOOpts) (usermem.IOSequence, error)) (data []byte, truncated bool, dropped uint64) {
+	if !fields.Local.Contains(field) || !info.Exit || int64(info.Rval) <= 0 {
+		return nil, false, 0
+	}
+	payload := seccheck.Global.GetPayload()
+	if payload == nil {
+		return nil, false, 0
+	}
+	src, err := buf(usermem.IOOpts{AddressSpaceActive: true})
+	if err != nil {
+		return nil, false, 0
+	}
+	return payload.Capture(t, src, int64(info.Rval))
+}
+
+// readData captures the data read into p, if requested in fields.
+func readData(t *kernel.Task, fields seccheck.FieldSet, info kernel.SyscallInfo, p *pb.Read, dst func(opts usermem.IOOpts) (usermem.IOSequence, error)) {
+	p.Data, p.DataTruncated, p.DroppedBytes = captureData(t, fields, seccheck.FieldSyscallReadData, info, dst)
+}
+
+// writeData captures the data written from p, if requested in fields.
+func writeData(t *kernel.Task, fields seccheck.FieldSet, info kernel.SyscallInfo, p *pb.Write, src func(opts usermem.IOOpts) (usermem.IOSequence, error)) {
+	p.Data, p.DataTruncated, p.DroppedBytes = captureData(t, fields, seccheck.FieldSyscallWriteData, info, src)
+}
+
 // PointRead converts read(2) syscall to proto.
 func PointRead(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
-	p := &pb.Read{
+	p := seccheck.NewRead()
+	p.ContextData = cxtData
+	p.Sysno = uint64(info.Sysno)
+	p.Fd = int64(info.Args[0].Int())
+	p.Count = uint64(info.Args[2].SizeT())
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, int32(p.Fd))
+	}
+	readData(t, fields, info, p, func(opts usermem.IOOpts) (usermem.IOSequence, error) {
+		return t.SingleIOSequence(info.Args[1].Pointer(), int(info.Rval), opts)
+	})
+
+	p.Exit = newExitMaybe(info)
+
+	return p, pb.MessageType_MESSAGE_SYSCALL_READ
+}
+
+// PointPread64 converts pread64(2) syscall to proto.
+func PointPread64(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := seccheck.NewRead()
+	p.ContextData = cxtData
+	p.Sysno = uint64(info.Sysno)
+	p.Fd = int64(info.Args[0].Int())
+	p.Count = uint64(info.Args[2].SizeT())
+	p.HasOffset = true
+	p.Offset = info.Args[3].Int64()
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, int32(p.Fd))
+	}
+	readData(t, fields, info, p, func(opts usermem.IOOpts) (usermem.IOSequence, error) {
+		return t.SingleIOSequence(info.Args[1].Pointer(), int(info.Rval), opts)
+	})
+
+	p.Exit = newExitMaybe(info)
+
+	return p, pb.MessageType_MESSAGE_SYSCALL_READ
+}
+
+// iovecsLength returns the combined length of the iovecs at addr, or 0 if they
+// cannot be read.
+func iovecsLength(t *kernel.Task, addr hostarch.Addr, iovcnt int) uint64 {
+	if iovcnt <= 0 || iovcnt > linux.UIO_MAXIOV {
+		return 0
+	}
+	iovecs, err := t.CopyInIovecs(addr, iovcnt)
+	if err != nil {
+		return 0
+	}
+	return uint64(iovecs.NumBytes())
+}
+
+// pointReadvHelper converts readv(2), preadv(2), and preadv2(2) syscall to
+// proto.
+func pointReadvHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo, hasOffset bool, offset int64, flags uint32) (proto.Message, pb.MessageType) {
+	p := seccheck.NewRead()
+	p.ContextData = cxtData
+	p.Sysno = uint64(info.Sysno)
+	p.Fd = int64(info.Args[0].Int())
+	p.Count = iovecsLength(t, info.Args[1].Pointer(), int(info.Args[2].Int()))
+	p.HasOffset = hasOffset
+	p.Offset = offset
+	p.Flags = flags
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, int32(p.Fd))
+	}
+	readData(t, fields, info, p, func(opts usermem.IOOpts) (usermem.IOSequence, error) {
+		return t.IovecsIOSequence(info.Args[1].Pointer(), int(info.Args[2].Int()), opts)
+	})
+
+	p.Exit = newExitMaybe(info)
+
+	return p, pb.MessageType_MESSAGE_SYSCALL_READ
+}
+
+// PointReadv calls pointReadvHelper to convert readv(2) syscall to proto.
+func PointReadv(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointReadvHelper(t, fields, cxtData, info, false, 0, 0)
+}
+
+// PointPreadv calls pointReadvHelper to convert preadv(2) syscall to proto.
+func PointPreadv(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	offset := info.Args[3].Int64()
+	return pointReadvHelper(t, fields, cxtData, info, true, offset, 0)
+}
+
+// PointPreadv2 calls pointReadvHelper to convert preadv2(2) syscall to proto.
+func PointPreadv2(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	// The offset argument is split into high/low values, see Preadv2 for
+	// details. An offset of -1 uses the current file offset, like readv(2).
+	offset := info.Args[3].Int64()
+	flags := info.Args[5].Uint()
+	return pointReadvHelper(t, fields, cxtData, info, offset != -1, offset, flags)
+}
+
+// PointWrite converts write(2) syscall to proto.
+func PointWrite(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Write{
 		ContextData: cxtData,
 		Sysno:       uint64(info.Sysno),
 		Fd:          int64(info.Args[0].Int()),
@@ -161,10 +321,79 @@ func PointRead(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData
 	if fields.Local.Contains(seccheck.FieldSyscallPath) {
 		p.FdPath = getFilePath(t, int32(p.Fd))
 	}
+	writeData(t, fields, info, p, func(opts usermem.IOOpts) (usermem.IOSequence, error) {
+		return t.SingleIOSequence(info.Args[1].Pointer(), int(info.Rval), opts)
+	})
 
 	p.Exit = newExitMaybe(info)
 
-	return p, pb.MessageType_MESSAGE_SYSCALL_READ
+	return p, pb.MessageType_MESSAGE_SYSCALL_WRITE
+}
+
+// PointPwrite64 converts pwrite64(2) syscall to proto.
+func PointPwrite64(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Write{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          int64(info.Args[0].Int()),
+		Count:       uint64(info.Args[2].SizeT()),
+		HasOffset:   true,
+		Offset:      info.Args[3].Int64(),
+	}
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, int32(p.Fd))
+	}
+	writeData(t, fields, info, p, func(opts usermem.IOOpts) (usermem.IOSequence, error) {
+		return t.SingleIOSequence(info.Args[1].Pointer(), int(info.Rval), opts)
+	})
+
+	p.Exit = newExitMaybe(info)
+
+	return p, pb.MessageType_MESSAGE_SYSCALL_WRITE
+}
+
+// pointWritevHelper converts writev(2), pwritev(2), and pwritev2(2) syscall to
+// proto.
+func pointWritevHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo, hasOffset bool, offset int64, flags uint32) (proto.Message, pb.MessageType) {
+	p := &pb.Write{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          int64(info.Args[0].Int()),
+		Count:       iovecsLength(t, info.Args[1].Pointer(), int(info.Args[2].Int())),
+		HasOffset:   hasOffset,
+		Offset:      offset,
+		Flags:       flags,
+	}
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, int32(p.Fd))
+	}
+	writeData(t, fields, info, p, func(opts usermem.IOOpts) (usermem.IOSequence, error) {
+		return t.IovecsIOSequence(info.Args[1].Pointer(), int(info.Args[2].Int()), opts)
+	})
+
+	p.Exit = newExitMaybe(info)
+
+	return p, pb.MessageType_MESSAGE_SYSCALL_WRITE
+}
+
+// PointWritev calls pointWritevHelper to convert writev(2) syscall to proto.
+func PointWritev(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointWritevHelper(t, fields, cxtData, info, false, 0, 0)
+}
+
+// PointPwritev calls pointWritevHelper to convert pwritev(2) syscall to proto.
+func PointPwritev(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	offset := info.Args[3].Int64()
+	return pointWritevHelper(t, fields, cxtData, info, true, offset, 0)
+}
+
+// PointPwritev2 calls pointWritevHelper to convert pwritev2(2) syscall to
+// proto.
+func PointPwritev2(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	// See PointPreadv2 for the offset argument.
+	offset := info.Args[3].Int64()
+	flags := info.Args[5].Uint()
+	return pointWritevHelper(t, fields, cxtData, info, offset != -1, offset, flags)
 }
 
 // PointSocket converts socket(2) syscall to proto.
@@ -203,36 +432,139 @@ func PointConnect(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextD
 	return p, pb.MessageType_MESSAGE_SYSCALL_CONNECT
 }
 
-// PointExecve converts execve(2) syscall to proto.
-func PointExecve(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
-	p := &pb.Execve{
+// PointSendto converts sendto(2) syscall to proto.
+func PointSendto(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Sendto{
 		ContextData: cxtData,
 		Sysno:       uint64(info.Sysno),
+		Fd:          int64(info.Args[0].Int()),
+		Flags:       info.Args[3].Uint(),
 	}
-	if pathname, err := t.CopyInString(info.Args[0].Pointer(), linux.PATH_MAX); err == nil { // if NO error
-		p.Pathname = pathname
+
+	if addr := info.Args[4].Pointer(); addr != 0 {
+		p.Address, _ = CaptureAddress(t, addr, info.Args[5].Uint())
 	}
-	if argvAddr := info.Args[1].Pointer(); argvAddr != 0 {
-		if argv, err := t.CopyInVector(argvAddr, ExecMaxElemSize, ExecMaxTotalSize); err == nil { // if NO error
-			p.Argv = argv
-		}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, int32(p.Fd))
 	}
+	p.Data, p.DataTruncated, p.DroppedBytes = captureData(t, fields, seccheck.FieldSyscallWriteData, info, func(opts usermem.IOOpts) (usermem.IOSequence, error) {
+		return t.SingleIOSequence(info.Args[1].Pointer(), int(info.Rval), opts)
+	})
 
-	if fields.Local.Contains(seccheck.FieldSyscallExecveEnvv) {
-		if envvAddr := info.Args[2].Pointer(); envvAddr != 0 {
-			if envv, err := t.CopyInVector(envvAddr, ExecMaxElemSize, ExecMaxTotalSize); err == nil { // if NO error
-				p.Envv = envv
-			}
+	p.Exit = newExitMaybe(info)
+
+	return p, pb.MessageType_MESSAGE_SYSCALL_SENDTO
+}
+
+// PointSendmsg converts sendmsg(2) syscall to proto.
+func PointSendmsg(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Sendmsg{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          int64(info.Args[0].Int()),
+		Flags:       info.Args[2].Uint(),
+	}
+
+	var msg MessageHeader64
+	if _, err := msg.CopyIn(t, info.Args[1].Pointer()); err == nil {
+		if msg.Name != 0 {
+			p.Address, _ = CaptureAddress(t, hostarch.Addr(msg.Name), msg.NameLen)
 		}
+		if msg.IovLen <= linux.UIO_MAXIOV {
+			p.Data, p.DataTruncated, p.DroppedBytes = captureData(t, fields, seccheck.FieldSyscallWriteData, info, func(opts usermem.IOOpts) (usermem.IOSequence, error) {
+				return t.IovecsIOSequence(hostarch.Addr(msg.Iov), int(msg.IovLen), opts)
+			})
+		}
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, int32(p.Fd))
+	}
+
+	p.Exit = newExitMaybe(info)
+
+	return p, pb.MessageType_MESSAGE_SYSCALL_SENDMSG
+}
+
+// PointPtrace converts ptrace(2) syscall to proto.
+func PointPtrace(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Ptrace{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Request:     info.Args[0].Int64(),
+		Pid:         info.Args[1].Int(),
+		Addr:        info.Args[2].Uint64(),
+		Data:        info.Args[3].Uint64(),
+	}
+
+	p.Exit = newExitMaybe(info)
+
+	return p, pb.MessageType_MESSAGE_SYSCALL_PTRACE
+}
+
+// PointMmap converts mmap(2) syscall to proto.
+func PointMmap(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Mmap{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Address:     info.Args[0].Uint64(),
+		Length:      info.Args[1].Uint64(),
+		Prot:        info.Args[2].Int(),
+		Flags:       info.Args[3].Int(),
+		Fd:          int64(info.Args[4].Int()),
+		Offset:      info.Args[5].Uint64(),
+	}
+	if fields.Local.Contains(seccheck.FieldSyscallPath) && p.Flags&linux.MAP_ANONYMOUS == 0 {
+		p.FdPath = getFilePath(t, int32(p.Fd))
+	}
+
+	p.Exit = newExitMaybe(info)
+
+	return p, pb.MessageType_MESSAGE_SYSCALL_MMAP
+}
+
+// PointMprotect converts mprotect(2) syscall to proto.
+func PointMprotect(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Mprotect{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Address:     info.Args[0].Uint64(),
+		Length:      info.Args[1].Uint64(),
+		Prot:        info.Args[2].Int(),
 	}
 
 	p.Exit = newExitMaybe(info)
 
-	return p, pb.MessageType_MESSAGE_SYSCALL_EXECVE
+	return p, pb.MessageType_MESSAGE_SYSCALL_MPROTECT
+}
+
+// PointExecve converts execve(2) syscall to proto.
+func PointExecve(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	if p, ok := enterMsg(info).(*pb.Execve); ok {
+		p.ContextData = cxtData
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_EXECVE
+	}
+	p := &pb.Execve{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+	}
+	if pathname, err := t.CopyInString(info.Args[0].Pointer(), linux.PATH_MAX); err == nil { // if NO error
+		p.Pathname = pathname
+	}
+	p.Exit = newExitMaybe(info)
+
+	return copyInExecveArgs(t, fields, info, p, info.Args[1].Pointer(), info.Args[2].Pointer()), pb.MessageType_MESSAGE_SYSCALL_EXECVE
 }
 
 // PointExecveat converts execveat(2) syscall to proto.
 func PointExecveat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	if p, ok := enterMsg(info).(*pb.Execve); ok {
+		p.ContextData = cxtData
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_EXECVE
+	}
 	p := &pb.Execve{
 		ContextData: cxtData,
 		Sysno:       uint64(info.Sysno),
@@ -242,30 +574,74 @@ func PointExecveat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.Context
 	if pathname, err := t.CopyInString(info.Args[1].Pointer(), linux.PATH_MAX); err == nil { // if NO error
 		p.Pathname = pathname
 	}
-	if argvAddr := info.Args[2].Pointer(); argvAddr != 0 {
-		if argv, err := t.CopyInVector(argvAddr, ExecMaxElemSize, ExecMaxTotalSize); err == nil { // if NO error
-			p.Argv = argv
-		}
-	}
-
-	if fields.Local.Contains(seccheck.FieldSyscallExecveEnvv) {
-		if envvAddr := info.Args[3].Pointer(); envvAddr != 0 {
-			if envv, err := t.CopyInVector(envvAddr, ExecMaxElemSize, ExecMaxTotalSize); err == nil { // if NO error
-				p.Envv = envv
-			}
-		}
-	}
 	if fields.Local.Contains(seccheck.FieldSyscallPath) {
 		p.FdPath = getFilePath(t, int32(p.Fd))
 	}
 
 	p.Exit = newExitMaybe(info)
 
-	return p, pb.MessageType_MESSAGE_SYSCALL_EXECVE
+	return copyInExecveArgs(t, fields, info, p, info.Args[2].Pointer(), info.Args[3].Pointer()), pb.MessageType_MESSAGE_SYSCALL_EXECVE
+}
+
+// maxAsyncExecveArgs bounds the number of execve points that are sent
+// asynchronously at the same time, see seccheck.FieldSyscallExecveAsync.
+// Beyond it, points are sent synchronously.
+const maxAsyncExecveArgs = 64
+
+// asyncExecveArgs is the number of execve points that are being sent
+// asynchronously.
+var asyncExecveArgs atomicbitops.Int32
+
+// copyInExecveArgs sets the argv and envv of p, from the vectors at argvAddr
+// and envvAddr, and returns p. The vectors are always copied in by the task.
+// For enter points with the async_args field, p is then serialized and sent by
+// a goroutine instead, and nil is returned so that p is not sent by the task.
+func copyInExecveArgs(t *kernel.Task, fields seccheck.FieldSet, info kernel.SyscallInfo, p *pb.Execve, argvAddr, envvAddr hostarch.Addr) proto.Message {
+	if !fields.Local.Contains(seccheck.FieldSyscallExecveEnvv) {
+		envvAddr = 0
+	}
+	// Copy the vectors before execve replaces the address space. The strings
+	// returned are private to the point and safe to use from another goroutine.
+	var argv, envv []string
+	if argvAddr != 0 {
+		if v, err := t.CopyInVector(argvAddr, ExecMaxElemSize, ExecMaxTotalSize); err == nil { // if NO error
+			argv = v
+		}
+	}
+	if envvAddr != 0 {
+		if v, err := t.CopyInVector(envvAddr, ExecMaxElemSize, ExecMaxTotalSize); err == nil { // if NO error
+			envv = v
+		}
+	}
+	if !info.Exit && fields.Local.Contains(seccheck.FieldSyscallExecveAsync) {
+		if asyncExecveArgs.Add(1) <= maxAsyncExecveArgs {
+			ctx := t.Kernel().SupervisorContext()
+			cid := t.ContainerID()
+			pt := seccheck.GetPointForSyscall(seccheck.SyscallEnter, info.Sysno)
+			go func() { // S/R-SAFE: only uses data copied in by the task.
+				defer asyncExecveArgs.Add(-1)
+				p.Argv = argv
+				p.Envv = envv
+				if err := seccheck.Global.SendSyscallToCheckers(ctx, cid, pt, fields, p.ContextData, pb.MessageType_MESSAGE_SYSCALL_EXECVE, p); err != nil {
+					log.Debugf("Sending execve point asynchronously: %v", err)
+				}
+			}()
+			return nil
+		}
+		asyncExecveArgs.Add(-1)
+	}
+	p.Argv = argv
+	p.Envv = envv
+	return p
 }
 
 // pointChdirHelper converts chdir(2) and fchdir(2) syscall to proto.
 func pointChdirHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo, fd int64, path hostarch.Addr) (proto.Message, pb.MessageType) {
+	if p, ok := enterMsg(info).(*pb.Chdir); ok {
+		p.ContextData = cxtData
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_CHDIR
+	}
 	p := &pb.Chdir{
 		ContextData: cxtData,
 		Sysno:       uint64(info.Sysno),
@@ -346,6 +722,32 @@ func pointSetresidHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.C
 	return p, pb.MessageType_MESSAGE_SYSCALL_SETRESID
 }
 
+// pointSetreidHelper converts setreuid(2) and setregid(2) syscall to proto.
+func pointSetreidHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Setresid{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Rgid:        info.Args[0].Uint(),
+		Egid:        info.Args[1].Uint(),
+		// The saved ID is not passed, report it as unchanged.
+		Sgid: ^uint32(0),
+	}
+
+	p.Exit = newExitMaybe(info)
+
+	return p, pb.MessageType_MESSAGE_SYSCALL_SETRESID
+}
+
+// PointSetreuid calls pointSetreidHelper to convert setreuid(2) syscall to proto.
+func PointSetreuid(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointSetreidHelper(t, fields, cxtData, info)
+}
+
+// PointSetregid calls pointSetreidHelper to convert setregid(2) syscall to proto.
+func PointSetregid(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointSetreidHelper(t, fields, cxtData, info)
+}
+
 // PointSetresuid calls pointSetresidHelper to convert setresuid(2) syscall to proto.
 func PointSetresuid(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
 	return pointSetresidHelper(t, fields, cxtData, info)
@@ -458,6 +860,24 @@ func PointFcntl(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextDat
 		Args:        info.Args[2].Int64(),
 	}
 
+	switch p.Cmd {
+	case linux.F_GETLK, linux.F_SETLK, linux.F_SETLKW:
+		// F_GETLK overwrites the lock with the conflicting lock, if any. Only read
+		// it on exit to report what was returned.
+		if p.Cmd != linux.F_GETLK || info.Exit {
+			var flock linux.Flock
+			if _, err := flock.CopyIn(t, info.Args[2].Pointer()); err == nil { // if NO error
+				p.Lock = &pb.FcntlLock{
+					Type:   int32(flock.Type),
+					Whence: int32(flock.Whence),
+					Start:  flock.Start,
+					Len:    flock.Len,
+					Pid:    flock.PID,
+				}
+			}
+		}
+	}
+
 	if fields.Local.Contains(seccheck.FieldSyscallPath) {
 		p.FdPath = getFilePath(t, int32(p.Fd))
 	}
@@ -542,6 +962,11 @@ func PointSignalfd4(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.Contex
 
 // PointChroot converts chroot(2) syscall to proto.
 func PointChroot(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	if p, ok := enterMsg(info).(*pb.Chroot); ok {
+		p.ContextData = cxtData
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_CHROOT
+	}
 	p := &pb.Chroot{
 		ContextData: cxtData,
 		Sysno:       uint64(info.Sysno),
@@ -553,6 +978,72 @@ func PointChroot(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextDa
 	return p, pb.MessageType_MESSAGE_SYSCALL_CHROOT
 }
 
+// PointMount converts mount(2) syscall to proto.
+func PointMount(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	if p, ok := enterMsg(info).(*pb.Mount); ok {
+		p.ContextData = cxtData
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_MOUNT
+	}
+	p := &pb.Mount{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Flags:       info.Args[3].Uint64(),
+	}
+	// Like mount(2), copy in at most a page of the source and type.
+	if source, err := t.CopyInString(info.Args[0].Pointer(), hostarch.PageSize); err == nil { // if NO error
+		p.Source = source
+	}
+	if target, err := t.CopyInString(info.Args[1].Pointer(), linux.PATH_MAX); err == nil { // if NO error
+		p.Target = target
+	}
+	if fstype, err := t.CopyInString(info.Args[2].Pointer(), hostarch.PageSize); err == nil { // if NO error
+		p.Fstype = fstype
+	}
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_MOUNT
+}
+
+// PointUmount2 converts umount2(2) syscall to proto.
+func PointUmount2(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	if p, ok := enterMsg(info).(*pb.Umount); ok {
+		p.ContextData = cxtData
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_UMOUNT
+	}
+	p := &pb.Umount{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Flags:       info.Args[1].Uint(),
+	}
+	if target, err := t.CopyInString(info.Args[0].Pointer(), linux.PATH_MAX); err == nil { // if NO error
+		p.Target = target
+	}
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_UMOUNT
+}
+
+// PointPivotRoot converts pivot_root(2) syscall to proto.
+func PointPivotRoot(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	if p, ok := enterMsg(info).(*pb.PivotRoot); ok {
+		p.ContextData = cxtData
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_PIVOT_ROOT
+	}
+	p := &pb.PivotRoot{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+	}
+	if newRoot, err := t.CopyInString(info.Args[0].Pointer(), linux.PATH_MAX); err == nil { // if NO error
+		p.NewRoot = newRoot
+	}
+	if putOld, err := t.CopyInString(info.Args[1].Pointer(), linux.PATH_MAX); err == nil { // if NO error
+		p.PutOld = putOld
+	}
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_PIVOT_ROOT
+}
+
 // PointClone converts clone(2) syscall to proto.
 func PointClone(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
 	p := &pb.Clone{
@@ -815,3 +1306,697 @@ func PointSocketpair(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.Conte
 	p.Exit = newExitMaybe(info)
 	return p, pb.MessageType_MESSAGE_SYSCALL_SOCKETPAIR
 }
+
+// PointSemget converts semget(2) syscall to proto.
+func PointSemget(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Semget{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Key:         info.Args[0].Int(),
+		Nsems:       info.Args[1].Int(),
+		Flags:       info.Args[2].Int(),
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_SEMGET
+}
+
+// PointSemop converts semop(2) syscall to proto.
+func PointSemop(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Semop{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Id:          info.Args[0].Int(),
+	}
+	if nsops := info.Args[2].SizeT(); nsops > 0 && nsops <= opsMax {
+		ops := make([]linux.Sembuf, nsops)
+		if _, err := linux.CopySembufSliceIn(t, info.Args[1].Pointer(), ops); err == nil { // if NO error
+			p.Ops = make([]*pb.Sembuf, 0, len(ops))
+			for _, op := range ops {
+				p.Ops = append(p.Ops, &pb.Sembuf{
+					Num:   uint32(op.SemNum),
+					Op:    int32(op.SemOp),
+					Flags: int32(op.SemFlg),
+				})
+			}
+		}
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_SEMOP
+}
+
+// PointMsgget converts msgget(2) syscall to proto.
+func PointMsgget(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Msgget{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Key:         info.Args[0].Int(),
+		Flags:       info.Args[1].Int(),
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_MSGGET
+}
+
+// PointMsgsnd converts msgsnd(2) syscall to proto.
+func PointMsgsnd(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Msgsnd{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Id:          info.Args[0].Int(),
+		Size:        info.Args[2].Int64(),
+		Flags:       info.Args[3].Int(),
+	}
+	if msgAddr := info.Args[1].Pointer(); msgAddr != 0 {
+		var mType int64
+		if _, err := primitive.CopyInt64In(t, msgAddr, &mType); err == nil { // if NO error
+			p.Type = mType
+		}
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_MSGSND
+}
+
+// PointMsgrcv converts msgrcv(2) syscall to proto.
+func PointMsgrcv(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Msgrcv{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Id:          info.Args[0].Int(),
+		Size:        info.Args[2].Int64(),
+		Type:        info.Args[3].Int64(),
+		Flags:       info.Args[4].Int(),
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_MSGRCV
+}
+
+// PointMqOpen converts mq_open(2) syscall to proto.
+func PointMqOpen(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.MqOpen{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Flags:       info.Args[1].Int(),
+		Mode:        uint32(info.Args[2].ModeT()),
+	}
+	if nameAddr := info.Args[0].Pointer(); nameAddr > 0 {
+		if name, err := t.CopyInString(nameAddr, linux.NAME_MAX); err == nil { // if NO error
+			p.Name = name
+		}
+	}
+	if attrAddr := info.Args[3].Pointer(); attrAddr != 0 {
+		var attr linux.MqAttr
+		if _, err := attr.CopyIn(t, attrAddr); err == nil { // if NO error
+			p.Attr = &pb.MqAttr{
+				Flags:   attr.MqFlags,
+				MaxMsg:  attr.MqMaxmsg,
+				MsgSize: attr.MqMsgsize,
+				CurMsgs: attr.MqCurmsgs,
+			}
+		}
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_MQ_OPEN
+}
+
+// PointMqTimedsend converts mq_timedsend(2) syscall to proto.
+func PointMqTimedsend(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.MqSend{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          info.Args[0].Int(),
+		Size:        uint64(info.Args[2].SizeT()),
+		Priority:    info.Args[3].Uint(),
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, p.Fd)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_MQ_SEND
+}
+
+// PointMqTimedreceive converts mq_timedreceive(2) syscall to proto.
+func PointMqTimedreceive(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.MqReceive{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          info.Args[0].Int(),
+		Size:        uint64(info.Args[2].SizeT()),
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, p.Fd)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_MQ_RECEIVE
+}
+
+// PointShutdown converts shutdown(2) syscall to proto.
+func PointShutdown(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Shutdown{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          info.Args[0].Int(),
+		How:         info.Args[1].Int(),
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, p.Fd)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_SHUTDOWN
+}
+
+// getNameAddressLen returns the size of the caller's buffer for the address
+// written out by getsockname(2) and getpeername(2). It must be read on enter,
+// since the syscall overwrites it with the size of the address.
+func getNameAddressLen(t *kernel.Task, info kernel.SyscallInfo) uint32 {
+	var bufLen uint32
+	if addrLenPointer := info.Args[2].Pointer(); addrLenPointer != 0 {
+		if _, err := primitive.CopyUint32In(t, addrLenPointer, &bufLen); err != nil {
+			return 0
+		}
+	}
+	return bufLen
+}
+
+// getNameAddressExitLen returns the size of the address written out by
+// getsockname(2) and getpeername(2), which the syscall writes to addrlen. It's
+// used in place of the size of the caller's buffer when the enter point is not
+// enabled, in which case the address may include bytes past the caller's
+// buffer if it was truncated.
+func getNameAddressExitLen(t *kernel.Task, info kernel.SyscallInfo) uint32 {
+	if !info.Exit || info.Errno != 0 {
+		return 0
+	}
+	return getNameAddressLen(t, info)
+}
+
+// getNameAddressHelper returns the address written out by getsockname(2) and
+// getpeername(2), which is only available after the syscall returns. The
+// address is truncated if it didn't fit in the caller's buffer of bufLen
+// bytes, in which case only bufLen bytes were written out.
+func getNameAddressHelper(t *kernel.Task, info kernel.SyscallInfo, bufLen uint32) []byte {
+	if !info.Exit || info.Errno != 0 || bufLen == 0 {
+		return nil
+	}
+	addr := info.Args[1].Pointer()
+	if addrLenPointer := info.Args[2].Pointer(); addrLenPointer != 0 {
+		var addrLen uint32
+		if _, err := primitive.CopyUint32In(t, addrLenPointer, &addrLen); err == nil { // if NO error
+			if addrLen > bufLen {
+				addrLen = bufLen
+			}
+			if address, err := CaptureAddress(t, addr, addrLen); err == nil { // if NO error
+				return address
+			}
+		}
+	}
+	return nil
+}
+
+// PointGetsockname converts getsockname(2) syscall to proto.
+func PointGetsockname(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	// The size of the caller's buffer is only known on enter.
+	if p, ok := enterMsg(info).(*pb.Getsockname); ok {
+		p.ContextData = cxtData
+		p.Address = getNameAddressHelper(t, info, p.AddressLen)
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_GETSOCKNAME
+	}
+	p := &pb.Getsockname{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          info.Args[0].Int(),
+	}
+	if !info.Exit {
+		p.AddressLen = getNameAddressLen(t, info)
+	} else {
+		// Only the exit point is enabled, see getNameAddressExitLen.
+		p.Address = getNameAddressHelper(t, info, getNameAddressExitLen(t, info))
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, p.Fd)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_GETSOCKNAME
+}
+
+// PointGetpeername converts getpeername(2) syscall to proto.
+func PointGetpeername(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	// The size of the caller's buffer is only known on enter.
+	if p, ok := enterMsg(info).(*pb.Getpeername); ok {
+		p.ContextData = cxtData
+		p.Address = getNameAddressHelper(t, info, p.AddressLen)
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_GETPEERNAME
+	}
+	p := &pb.Getpeername{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          info.Args[0].Int(),
+	}
+	if !info.Exit {
+		p.AddressLen = getNameAddressLen(t, info)
+	} else {
+		// Only the exit point is enabled, see getNameAddressExitLen.
+		p.Address = getNameAddressHelper(t, info, getNameAddressExitLen(t, info))
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, p.Fd)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_GETPEERNAME
+}
+
+// sockOptNames returns the decoded names for the socket option level and
+// option name.
+func sockOptNames(level, optname int32) (string, string) {
+	levelName := linux.SockOptLevels.Parse(uint64(level))
+	optName := linux.SockOptNames[uint64(level)].Parse(uint64(optname))
+	return levelName, optName
+}
+
+// captureSockOptVal copies in the socket option value at addr, up to
+// maxOptLen bytes.
+func captureSockOptVal(t *kernel.Task, addr hostarch.Addr, optLen uint32) []byte {
+	if addr == 0 || optLen == 0 || optLen > maxOptLen {
+		return nil
+	}
+	buf := make([]byte, optLen)
+	if _, err := t.CopyInBytes(addr, buf); err != nil {
+		return nil
+	}
+	return buf
+}
+
+// PointSetsockopt converts setsockopt(2) syscall to proto.
+func PointSetsockopt(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Setsockopt{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          info.Args[0].Int(),
+		Level:       info.Args[1].Int(),
+		Optname:     info.Args[2].Int(),
+	}
+	p.LevelName, p.OptnameName = sockOptNames(p.Level, p.Optname)
+	p.Optval = captureSockOptVal(t, info.Args[3].Pointer(), info.Args[4].Uint())
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, p.Fd)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_SETSOCKOPT
+}
+
+// PointGetsockopt converts getsockopt(2) syscall to proto.
+func PointGetsockopt(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Getsockopt{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          info.Args[0].Int(),
+		Level:       info.Args[1].Int(),
+		Optname:     info.Args[2].Int(),
+	}
+	p.LevelName, p.OptnameName = sockOptNames(p.Level, p.Optname)
+	if info.Exit && info.Errno == 0 {
+		if optLenAddr := info.Args[4].Pointer(); optLenAddr != 0 {
+			var optLen uint32
+			if _, err := primitive.CopyUint32In(t, optLenAddr, &optLen); err == nil { // if NO error
+				p.Optval = captureSockOptVal(t, info.Args[3].Pointer(), optLen)
+			}
+		}
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, p.Fd)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_GETSOCKOPT
+}
+
+// PointFlock converts flock(2) syscall to proto.
+func PointFlock(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Flock{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          info.Args[0].Int(),
+		Operation:   info.Args[1].Int(),
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, p.Fd)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_FLOCK
+}
+
+// pointUtimesHelper converts utime(2), utimes(2), futimesat(2), and
+// utimensat(2) syscall to proto. Times must be set by the caller.
+func pointUtimesHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo, fd int64, pathAddr hostarch.Addr) *pb.Utimes {
+	p := &pb.Utimes{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          fd,
+	}
+	if pathAddr > 0 {
+		if pathname, err := t.CopyInString(pathAddr, linux.PATH_MAX); err == nil { // if NO error
+			p.Pathname = pathname
+		}
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, int32(p.Fd))
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p
+}
+
+// PointUtime converts utime(2) syscall to proto.
+func PointUtime(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := pointUtimesHelper(t, fields, cxtData, info, linux.AT_FDCWD, info.Args[0].Pointer())
+	if timesAddr := info.Args[1].Pointer(); timesAddr != 0 {
+		var times linux.Utime
+		if _, err := times.CopyIn(t, timesAddr); err == nil { // if NO error
+			p.Atime = &pb.Timespec{Sec: times.Actime}
+			p.Mtime = &pb.Timespec{Sec: times.Modtime}
+		}
+	}
+	return p, pb.MessageType_MESSAGE_SYSCALL_UTIMES
+}
+
+// copyInUtimesTimeval populates p with the timeval array used by utimes(2) and
+// futimesat(2).
+func copyInUtimesTimeval(t *kernel.Task, timesAddr hostarch.Addr, p *pb.Utimes) {
+	if timesAddr == 0 {
+		return
+	}
+	var times [2]linux.Timeval
+	if _, err := linux.CopyTimevalSliceIn(t, timesAddr, times[:]); err == nil { // if NO error
+		p.Atime = &pb.Timespec{Sec: times[0].Sec, Nsec: times[0].Usec * 1000}
+		p.Mtime = &pb.Timespec{Sec: times[1].Sec, Nsec: times[1].Usec * 1000}
+	}
+}
+
+// PointUtimes converts utimes(2) syscall to proto.
+func PointUtimes(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := pointUtimesHelper(t, fields, cxtData, info, linux.AT_FDCWD, info.Args[0].Pointer())
+	copyInUtimesTimeval(t, info.Args[1].Pointer(), p)
+	return p, pb.MessageType_MESSAGE_SYSCALL_UTIMES
+}
+
+// PointFutimesat converts futimesat(2) syscall to proto.
+func PointFutimesat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := pointUtimesHelper(t, fields, cxtData, info, int64(info.Args[0].Int()), info.Args[1].Pointer())
+	copyInUtimesTimeval(t, info.Args[2].Pointer(), p)
+	return p, pb.MessageType_MESSAGE_SYSCALL_UTIMES
+}
+
+// PointUtimensat converts utimensat(2) syscall to proto.
+func PointUtimensat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := pointUtimesHelper(t, fields, cxtData, info, int64(info.Args[0].Int()), info.Args[1].Pointer())
+	p.Flags = info.Args[3].Int()
+	if timesAddr := info.Args[2].Pointer(); timesAddr != 0 {
+		var times [2]linux.Timespec
+		if _, err := linux.CopyTimespecSliceIn(t, timesAddr, times[:]); err == nil { // if NO error
+			p.Atime = getValues(times[0])
+			p.Mtime = getValues(times[1])
+		}
+	}
+	return p, pb.MessageType_MESSAGE_SYSCALL_UTIMES
+}
+
+// PointFallocate converts fallocate(2) syscall to proto.
+func PointFallocate(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Fallocate{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          info.Args[0].Int(),
+		Mode:        info.Args[1].Uint(),
+		Offset:      info.Args[2].Int64(),
+		Len:         info.Args[3].Int64(),
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, p.Fd)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_FALLOCATE
+}
+
+// pointFsyncHelper converts fsync(2), fdatasync(2), and syncfs(2) syscall to
+// proto.
+func pointFsyncHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Fsync{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          info.Args[0].Int(),
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, p.Fd)
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_FSYNC
+}
+
+// PointFsync calls pointFsyncHelper to convert fsync(2) syscall to proto.
+func PointFsync(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointFsyncHelper(t, fields, cxtData, info)
+}
+
+// PointFdatasync calls pointFsyncHelper to convert fdatasync(2) syscall to
+// proto.
+func PointFdatasync(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointFsyncHelper(t, fields, cxtData, info)
+}
+
+// PointSyncfs calls pointFsyncHelper to convert syncfs(2) syscall to proto.
+func PointSyncfs(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	return pointFsyncHelper(t, fields, cxtData, info)
+}
+
+// PointSync converts sync(2) syscall to proto.
+func PointSync(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Sync{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_SYNC
+}
+
+// pointMknodHelper converts mknod(2) and mknodat(2) syscall to proto.
+func pointMknodHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo, fd int64, pathAddr hostarch.Addr, mode uint32, dev uint32) (proto.Message, pb.MessageType) {
+	if p, ok := enterMsg(info).(*pb.Mknod); ok {
+		p.ContextData = cxtData
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_MKNOD
+	}
+	major, minor := linux.DecodeDeviceID(dev)
+	p := &pb.Mknod{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          fd,
+		Mode:        mode,
+		DevMajor:    uint32(major),
+		DevMinor:    minor,
+	}
+	if pathAddr > 0 {
+		if pathname, err := t.CopyInString(pathAddr, linux.PATH_MAX); err == nil { // if NO error
+			p.Pathname = pathname
+		}
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, int32(p.Fd))
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_MKNOD
+}
+
+// PointMknod calls pointMknodHelper to convert mknod(2) syscall to proto.
+func PointMknod(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	pathAddr := info.Args[0].Pointer()
+	mode := uint32(info.Args[1].ModeT())
+	dev := info.Args[2].Uint()
+	return pointMknodHelper(t, fields, cxtData, info, linux.AT_FDCWD, pathAddr, mode, dev)
+}
+
+// PointMknodat calls pointMknodHelper to convert mknodat(2) syscall to proto.
+func PointMknodat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	fd := int64(info.Args[0].Int())
+	pathAddr := info.Args[1].Pointer()
+	mode := uint32(info.Args[2].ModeT())
+	dev := info.Args[3].Uint()
+	return pointMknodHelper(t, fields, cxtData, info, fd, pathAddr, mode, dev)
+}
+
+// pointUnlinkHelper converts unlink(2), unlinkat(2) and rmdir(2) syscalls to
+// proto.
+func pointUnlinkHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo, fd int64, pathAddr hostarch.Addr, flags uint32) (proto.Message, pb.MessageType) {
+	if p, ok := enterMsg(info).(*pb.Unlink); ok {
+		p.ContextData = cxtData
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_UNLINK
+	}
+	p := &pb.Unlink{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Fd:          fd,
+		Flags:       flags,
+	}
+	if pathAddr > 0 {
+		if pathname, err := t.CopyInString(pathAddr, linux.PATH_MAX); err == nil { // if NO error
+			p.Pathname = pathname
+		}
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.FdPath = getFilePath(t, int32(p.Fd))
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_UNLINK
+}
+
+// PointUnlink calls pointUnlinkHelper to convert unlink(2) syscall to proto.
+func PointUnlink(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	pathAddr := info.Args[0].Pointer()
+	return pointUnlinkHelper(t, fields, cxtData, info, linux.AT_FDCWD, pathAddr, 0)
+}
+
+// PointUnlinkat calls pointUnlinkHelper to convert unlinkat(2) syscall to
+// proto.
+func PointUnlinkat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	fd := int64(info.Args[0].Int())
+	pathAddr := info.Args[1].Pointer()
+	flags := info.Args[2].Uint()
+	return pointUnlinkHelper(t, fields, cxtData, info, fd, pathAddr, flags)
+}
+
+// PointRmdir calls pointUnlinkHelper to convert rmdir(2) syscall to proto.
+func PointRmdir(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	pathAddr := info.Args[0].Pointer()
+	return pointUnlinkHelper(t, fields, cxtData, info, linux.AT_FDCWD, pathAddr, linux.AT_REMOVEDIR)
+}
+
+// pointRenameHelper converts rename(2) and renameat(2) syscalls to proto.
+func pointRenameHelper(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo, oldFD int64, oldAddr hostarch.Addr, newFD int64, newAddr hostarch.Addr) (proto.Message, pb.MessageType) {
+	if p, ok := enterMsg(info).(*pb.Rename); ok {
+		p.ContextData = cxtData
+		p.Exit = newExitMaybe(info)
+		return p, pb.MessageType_MESSAGE_SYSCALL_RENAME
+	}
+	p := &pb.Rename{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		OldFd:       oldFD,
+		NewFd:       newFD,
+	}
+	if oldAddr > 0 {
+		if pathname, err := t.CopyInString(oldAddr, linux.PATH_MAX); err == nil { // if NO error
+			p.OldPathname = pathname
+		}
+	}
+	if newAddr > 0 {
+		if pathname, err := t.CopyInString(newAddr, linux.PATH_MAX); err == nil { // if NO error
+			p.NewPathname = pathname
+		}
+	}
+
+	if fields.Local.Contains(seccheck.FieldSyscallPath) {
+		p.OldFdPath = getFilePath(t, int32(p.OldFd))
+		p.NewFdPath = getFilePath(t, int32(p.NewFd))
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_RENAME
+}
+
+// PointRename calls pointRenameHelper to convert rename(2) syscall to proto.
+func PointRename(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	oldAddr := info.Args[0].Pointer()
+	newAddr := info.Args[1].Pointer()
+	return pointRenameHelper(t, fields, cxtData, info, linux.AT_FDCWD, oldAddr, linux.AT_FDCWD, newAddr)
+}
+
+// PointRenameat calls pointRenameHelper to convert renameat(2) syscall to
+// proto.
+func PointRenameat(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	oldFD := int64(info.Args[0].Int())
+	oldAddr := info.Args[1].Pointer()
+	newFD := int64(info.Args[2].Int())
+	newAddr := info.Args[3].Pointer()
+	return pointRenameHelper(t, fields, cxtData, info, oldFD, oldAddr, newFD, newAddr)
+}
+
+// PointAcct converts acct(2) syscall to proto.
+func PointAcct(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Acct{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+	}
+	if pathAddr := info.Args[0].Pointer(); pathAddr > 0 {
+		if pathname, err := t.CopyInString(pathAddr, linux.PATH_MAX); err == nil { // if NO error
+			p.Pathname = pathname
+		}
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_ACCT
+}
+
+// PointPersonality converts personality(2) syscall to proto.
+func PointPersonality(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Personality{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Persona:     info.Args[0].Uint(),
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_PERSONALITY
+}
+
+// PointUname converts uname(2) syscall to proto.
+func PointUname(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Uname{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_UNAME
+}
+
+// PointSysinfo converts sysinfo(2) syscall to proto.
+func PointSysinfo(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Sysinfo{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+	}
+
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_SYSINFO
+}
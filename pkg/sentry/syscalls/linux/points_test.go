@@ -0,0 +1,61 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// TestOpenEnterExitRelease checks that a message reused by the exit point is
+// only returned to its pool once.
+func TestOpenEnterExitRelease(t *testing.T) {
+	// Without the pathname field, converters don't need a task.
+	var fields seccheck.FieldSet
+	args := arch.SyscallArguments{{}, {Value: linux.O_RDONLY}, {}}
+	enter, _ := PointOpen(nil, fields, nil, kernel.SyscallInfo{Sysno: 2, Args: args})
+	exit, msgType := PointOpen(nil, fields, nil, kernel.SyscallInfo{
+		Exit:  true,
+		Sysno: 2,
+		Args:  args,
+		Rval:  3,
+		Enter: enter,
+	})
+	if msgType != pb.MessageType_MESSAGE_SYSCALL_OPEN {
+		t.Fatalf("PointOpen(): got type: %v, want: %v", msgType, pb.MessageType_MESSAGE_SYSCALL_OPEN)
+	}
+	if exit != enter {
+		t.Fatalf("PointOpen(): exit point didn't reuse the enter message")
+	}
+	if got := exit.(*pb.Open).Exit.GetResult(); got != 3 {
+		t.Errorf("PointOpen(): got result: %d, want: 3", got)
+	}
+	seccheck.ReleaseSyscallMessages(enter, exit)
+
+	// If the message was released twice, the pool hands it out twice.
+	seen := make(map[*pb.Open]struct{})
+	for i := 0; i < 4; i++ {
+		msg := seccheck.NewOpen()
+		if _, ok := seen[msg]; ok {
+			t.Fatalf("NewOpen() returned %p more than once", msg)
+		}
+		seen[msg] = struct{}{}
+	}
+}
@@ -111,7 +111,9 @@ func prlimit64(t *kernel.Task, resource limits.LimitType, newLim *limits.Limit)
 	// "A privileged process (under Linux: one with the CAP_SYS_RESOURCE
 	// capability in the initial user namespace) may make arbitrary changes
 	// to either limit value."
-	privileged := t.HasCapabilityIn(linux.CAP_SYS_RESOURCE, t.Kernel().RootUserNamespace())
+	// Limits.Set only needs this if the hard limit is raised, so don't report
+	// the check as a denial.
+	privileged := t.HasCapabilityInNoAudit(linux.CAP_SYS_RESOURCE, t.Kernel().RootUserNamespace())
 
 	oldLim, err := t.ThreadGroup().Limits().Set(resource, *newLim, privileged)
 	if err != nil {
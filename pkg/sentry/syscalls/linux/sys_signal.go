@@ -46,10 +46,6 @@ func mayKill(t *kernel.Task, target *kernel.Task, sig linux.Signal) bool {
 		return true
 	}
 
-	if t.HasCapabilityIn(linux.CAP_KILL, target.UserNamespace()) {
-		return true
-	}
-
 	creds := t.Credentials()
 	tcreds := target.Credentials()
 	if creds.EffectiveKUID == tcreds.SavedKUID ||
@@ -62,7 +58,10 @@ func mayKill(t *kernel.Task, target *kernel.Task, sig linux.Signal) bool {
 	if sig == linux.SIGCONT && target.ThreadGroup().Session() == t.ThreadGroup().Session() {
 		return true
 	}
-	return false
+
+	// Check CAP_KILL last, so that only signals that are actually denied are
+	// reported as capability denials.
+	return t.HasCapabilityIn(linux.CAP_KILL, target.UserNamespace())
 }
 
 // Kill implements linux syscall kill(2).
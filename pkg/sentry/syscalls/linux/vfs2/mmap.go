@@ -86,6 +86,11 @@ func Mmap(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.SyscallC
 		if err := file.ConfigureMMap(t, &opts); err != nil {
 			return 0, nil, err
 		}
+		if opts.Perms.Execute {
+			if err := t.FilelessMMapSeccheck(file, opts.Offset, opts.Length); err != nil {
+				return 0, nil, err
+			}
+		}
 	} else if shared {
 		// Back shared anonymous mappings with an anonymous tmpfs file.
 		opts.Offset = 0
@@ -23,6 +23,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/limits"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 )
 
@@ -245,6 +246,9 @@ func Fallocate(t *kernel.Task, args arch.SyscallArguments) (uintptr, *kernel.Sys
 			Signo: int32(linux.SIGXFSZ),
 			Code:  linux.SI_USER,
 		})
+		if seccheck.Global.Enabled(seccheck.PointRLimitBreach) {
+			kernel.RLimitSeccheck(t, limits.FileSize)
+		}
 		return 0, nil, linuxerr.EFBIG
 	}
 
@@ -467,6 +471,9 @@ func handleSetSizeError(t *kernel.Task, err error) error {
 	if err == linuxerr.ErrExceedsFileSizeLimit {
 		// Convert error to EFBIG and send a SIGXFSZ per setrlimit(2).
 		t.SendSignal(kernel.SignalInfoNoInfo(linux.SIGXFSZ, t, t))
+		if seccheck.Global.Enabled(seccheck.PointRLimitBreach) {
+			kernel.RLimitSeccheck(t, limits.FileSize)
+		}
 		return linuxerr.EFBIG
 	}
 	return err
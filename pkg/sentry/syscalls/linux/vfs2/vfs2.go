@@ -25,7 +25,7 @@ func Override() {
 	// Override AMD64.
 	s := linux.AMD64
 	s.Table[0] = syscalls.SupportedPoint("read", Read, linux.PointRead)
-	s.Table[1] = syscalls.Supported("write", Write)
+	s.Table[1] = syscalls.SupportedPoint("write", Write, linux.PointWrite)
 	s.Table[2] = syscalls.SupportedPoint("open", Open, linux.PointOpen)
 	s.Table[3] = syscalls.SupportedPoint("close", Close, linux.PointClose)
 	s.Table[4] = syscalls.Supported("stat", Stat)
@@ -33,12 +33,12 @@ func Override() {
 	s.Table[6] = syscalls.Supported("lstat", Lstat)
 	s.Table[7] = syscalls.Supported("poll", Poll)
 	s.Table[8] = syscalls.Supported("lseek", Lseek)
-	s.Table[9] = syscalls.Supported("mmap", Mmap)
+	s.Table[9] = syscalls.SupportedPoint("mmap", Mmap, linux.PointMmap)
 	s.Table[16] = syscalls.Supported("ioctl", Ioctl)
-	s.Table[17] = syscalls.Supported("pread64", Pread64)
-	s.Table[18] = syscalls.Supported("pwrite64", Pwrite64)
-	s.Table[19] = syscalls.Supported("readv", Readv)
-	s.Table[20] = syscalls.Supported("writev", Writev)
+	s.Table[17] = syscalls.SupportedPoint("pread64", Pread64, linux.PointPread64)
+	s.Table[18] = syscalls.SupportedPoint("pwrite64", Pwrite64, linux.PointPwrite64)
+	s.Table[19] = syscalls.SupportedPoint("readv", Readv, linux.PointReadv)
+	s.Table[20] = syscalls.SupportedPoint("writev", Writev, linux.PointWritev)
 	s.Table[21] = syscalls.Supported("access", Access)
 	s.Table[22] = syscalls.SupportedPoint("pipe", Pipe, linux.PointPipe)
 	s.Table[23] = syscalls.Supported("select", Select)
@@ -48,35 +48,35 @@ func Override() {
 	s.Table[41] = syscalls.SupportedPoint("socket", Socket, linux.PointSocket)
 	s.Table[42] = syscalls.SupportedPoint("connect", Connect, linux.PointConnect)
 	s.Table[43] = syscalls.SupportedPoint("accept", Accept, linux.PointAccept)
-	s.Table[44] = syscalls.Supported("sendto", SendTo)
+	s.Table[44] = syscalls.SupportedPoint("sendto", SendTo, linux.PointSendto)
 	s.Table[45] = syscalls.Supported("recvfrom", RecvFrom)
-	s.Table[46] = syscalls.Supported("sendmsg", SendMsg)
+	s.Table[46] = syscalls.SupportedPoint("sendmsg", SendMsg, linux.PointSendmsg)
 	s.Table[47] = syscalls.Supported("recvmsg", RecvMsg)
-	s.Table[48] = syscalls.Supported("shutdown", Shutdown)
+	s.Table[48] = syscalls.SupportedPoint("shutdown", Shutdown, linux.PointShutdown)
 	s.Table[49] = syscalls.SupportedPoint("bind", Bind, linux.PointBind)
 	s.Table[50] = syscalls.Supported("listen", Listen)
-	s.Table[51] = syscalls.Supported("getsockname", GetSockName)
-	s.Table[52] = syscalls.Supported("getpeername", GetPeerName)
+	s.Table[51] = syscalls.SupportedPoint("getsockname", GetSockName, linux.PointGetsockname)
+	s.Table[52] = syscalls.SupportedPoint("getpeername", GetPeerName, linux.PointGetpeername)
 	s.Table[53] = syscalls.SupportedPoint("socketpair", SocketPair, linux.PointSocketpair)
-	s.Table[54] = syscalls.Supported("setsockopt", SetSockOpt)
-	s.Table[55] = syscalls.Supported("getsockopt", GetSockOpt)
+	s.Table[54] = syscalls.SupportedPoint("setsockopt", SetSockOpt, linux.PointSetsockopt)
+	s.Table[55] = syscalls.SupportedPoint("getsockopt", GetSockOpt, linux.PointGetsockopt)
 	s.Table[59] = syscalls.SupportedPoint("execve", Execve, linux.PointExecve)
 	s.Table[72] = syscalls.SupportedPoint("fcntl", Fcntl, linux.PointFcntl)
-	s.Table[73] = syscalls.Supported("flock", Flock)
-	s.Table[74] = syscalls.Supported("fsync", Fsync)
-	s.Table[75] = syscalls.Supported("fdatasync", Fdatasync)
+	s.Table[73] = syscalls.SupportedPoint("flock", Flock, linux.PointFlock)
+	s.Table[74] = syscalls.SupportedPoint("fsync", Fsync, linux.PointFsync)
+	s.Table[75] = syscalls.SupportedPoint("fdatasync", Fdatasync, linux.PointFdatasync)
 	s.Table[76] = syscalls.Supported("truncate", Truncate)
 	s.Table[77] = syscalls.Supported("ftruncate", Ftruncate)
 	s.Table[78] = syscalls.Supported("getdents", Getdents)
 	s.Table[79] = syscalls.Supported("getcwd", Getcwd)
 	s.Table[80] = syscalls.SupportedPoint("chdir", Chdir, linux.PointChdir)
 	s.Table[81] = syscalls.SupportedPoint("fchdir", Fchdir, linux.PointFchdir)
-	s.Table[82] = syscalls.Supported("rename", Rename)
+	s.Table[82] = syscalls.SupportedPoint("rename", Rename, linux.PointRename)
 	s.Table[83] = syscalls.Supported("mkdir", Mkdir)
-	s.Table[84] = syscalls.Supported("rmdir", Rmdir)
+	s.Table[84] = syscalls.SupportedPoint("rmdir", Rmdir, linux.PointRmdir)
 	s.Table[85] = syscalls.SupportedPoint("creat", Creat, linux.PointCreat)
 	s.Table[86] = syscalls.Supported("link", Link)
-	s.Table[87] = syscalls.Supported("unlink", Unlink)
+	s.Table[87] = syscalls.SupportedPoint("unlink", Unlink, linux.PointUnlink)
 	s.Table[88] = syscalls.Supported("symlink", Symlink)
 	s.Table[89] = syscalls.Supported("readlink", Readlink)
 	s.Table[90] = syscalls.Supported("chmod", Chmod)
@@ -84,15 +84,15 @@ func Override() {
 	s.Table[92] = syscalls.Supported("chown", Chown)
 	s.Table[93] = syscalls.Supported("fchown", Fchown)
 	s.Table[94] = syscalls.Supported("lchown", Lchown)
-	s.Table[132] = syscalls.Supported("utime", Utime)
-	s.Table[133] = syscalls.Supported("mknod", Mknod)
+	s.Table[132] = syscalls.SupportedPoint("utime", Utime, linux.PointUtime)
+	s.Table[133] = syscalls.SupportedPoint("mknod", Mknod, linux.PointMknod)
 	s.Table[137] = syscalls.Supported("statfs", Statfs)
 	s.Table[138] = syscalls.Supported("fstatfs", Fstatfs)
-	s.Table[155] = syscalls.Supported("pivot_root", PivotRoot)
+	s.Table[155] = syscalls.SupportedPoint("pivot_root", PivotRoot, linux.PointPivotRoot)
 	s.Table[161] = syscalls.SupportedPoint("chroot", Chroot, linux.PointChroot)
-	s.Table[162] = syscalls.Supported("sync", Sync)
-	s.Table[165] = syscalls.Supported("mount", Mount)
-	s.Table[166] = syscalls.Supported("umount2", Umount2)
+	s.Table[162] = syscalls.SupportedPoint("sync", Sync, linux.PointSync)
+	s.Table[165] = syscalls.SupportedPoint("mount", Mount, linux.PointMount)
+	s.Table[166] = syscalls.SupportedPoint("umount2", Umount2, linux.PointUmount2)
 	s.Table[187] = syscalls.Supported("readahead", Readahead)
 	s.Table[188] = syscalls.Supported("setxattr", SetXattr)
 	s.Table[189] = syscalls.Supported("lsetxattr", Lsetxattr)
@@ -112,20 +112,20 @@ func Override() {
 	s.Table[221] = syscalls.PartiallySupported("fadvise64", Fadvise64, "The syscall is 'supported', but ignores all provided advice.", nil)
 	s.Table[232] = syscalls.Supported("epoll_wait", EpollWait)
 	s.Table[233] = syscalls.Supported("epoll_ctl", EpollCtl)
-	s.Table[235] = syscalls.Supported("utimes", Utimes)
-	s.Table[240] = syscalls.Supported("mq_open", MqOpen)
+	s.Table[235] = syscalls.SupportedPoint("utimes", Utimes, linux.PointUtimes)
+	s.Table[240] = syscalls.SupportedPoint("mq_open", MqOpen, linux.PointMqOpen)
 	s.Table[241] = syscalls.Supported("mq_unlink", MqUnlink)
 	s.Table[253] = syscalls.PartiallySupportedPoint("inotify_init", InotifyInit, linux.PointInotifyInit, "inotify events are only available inside the sandbox.", nil)
 	s.Table[254] = syscalls.PartiallySupportedPoint("inotify_add_watch", InotifyAddWatch, linux.PointInotifyAddWatch, "inotify events are only available inside the sandbox.", nil)
 	s.Table[255] = syscalls.PartiallySupportedPoint("inotify_rm_watch", InotifyRmWatch, linux.PointInotifyRmWatch, "inotify events are only available inside the sandbox.", nil)
 	s.Table[257] = syscalls.SupportedPoint("openat", Openat, linux.PointOpenat)
 	s.Table[258] = syscalls.Supported("mkdirat", Mkdirat)
-	s.Table[259] = syscalls.Supported("mknodat", Mknodat)
+	s.Table[259] = syscalls.SupportedPoint("mknodat", Mknodat, linux.PointMknodat)
 	s.Table[260] = syscalls.Supported("fchownat", Fchownat)
-	s.Table[261] = syscalls.Supported("futimesat", Futimesat)
+	s.Table[261] = syscalls.SupportedPoint("futimesat", Futimesat, linux.PointFutimesat)
 	s.Table[262] = syscalls.Supported("newfstatat", Newfstatat)
-	s.Table[263] = syscalls.Supported("unlinkat", Unlinkat)
-	s.Table[264] = syscalls.Supported("renameat", Renameat)
+	s.Table[263] = syscalls.SupportedPoint("unlinkat", Unlinkat, linux.PointUnlinkat)
+	s.Table[264] = syscalls.SupportedPoint("renameat", Renameat, linux.PointRenameat)
 	s.Table[265] = syscalls.Supported("linkat", Linkat)
 	s.Table[266] = syscalls.Supported("symlinkat", Symlinkat)
 	s.Table[267] = syscalls.Supported("readlinkat", Readlinkat)
@@ -136,12 +136,12 @@ func Override() {
 	s.Table[275] = syscalls.Supported("splice", Splice)
 	s.Table[276] = syscalls.Supported("tee", Tee)
 	s.Table[277] = syscalls.Supported("sync_file_range", SyncFileRange)
-	s.Table[280] = syscalls.Supported("utimensat", Utimensat)
+	s.Table[280] = syscalls.SupportedPoint("utimensat", Utimensat, linux.PointUtimensat)
 	s.Table[281] = syscalls.Supported("epoll_pwait", EpollPwait)
 	s.Table[282] = syscalls.SupportedPoint("signalfd", Signalfd, linux.PointSignalfd)
 	s.Table[283] = syscalls.SupportedPoint("timerfd_create", TimerfdCreate, linux.PointTimerfdCreate)
 	s.Table[284] = syscalls.SupportedPoint("eventfd", Eventfd, linux.PointEventfd)
-	s.Table[285] = syscalls.PartiallySupported("fallocate", Fallocate, "Not all options are supported.", nil)
+	s.Table[285] = syscalls.PartiallySupportedPoint("fallocate", Fallocate, linux.PointFallocate, "Not all options are supported.", nil)
 	s.Table[286] = syscalls.SupportedPoint("timerfd_settime", TimerfdSettime, linux.PointTimerfdSettime)
 	s.Table[287] = syscalls.SupportedPoint("timerfd_gettime", TimerfdGettime, linux.PointTimerfdGettime)
 	s.Table[288] = syscalls.SupportedPoint("accept4", Accept4, linux.PointAccept4)
@@ -151,16 +151,16 @@ func Override() {
 	s.Table[292] = syscalls.SupportedPoint("dup3", Dup3, linux.PointDup3)
 	s.Table[293] = syscalls.SupportedPoint("pipe2", Pipe2, linux.PointPipe2)
 	s.Table[294] = syscalls.PartiallySupportedPoint("inotify_init1", InotifyInit1, linux.PointInotifyInit1, "inotify events are only available inside the sandbox.", nil)
-	s.Table[295] = syscalls.Supported("preadv", Preadv)
-	s.Table[296] = syscalls.Supported("pwritev", Pwritev)
+	s.Table[295] = syscalls.SupportedPoint("preadv", Preadv, linux.PointPreadv)
+	s.Table[296] = syscalls.SupportedPoint("pwritev", Pwritev, linux.PointPwritev)
 	s.Table[299] = syscalls.Supported("recvmmsg", RecvMMsg)
-	s.Table[306] = syscalls.Supported("syncfs", Syncfs)
+	s.Table[306] = syscalls.SupportedPoint("syncfs", Syncfs, linux.PointSyncfs)
 	s.Table[307] = syscalls.Supported("sendmmsg", SendMMsg)
 	s.Table[316] = syscalls.Supported("renameat2", Renameat2)
 	s.Table[319] = syscalls.Supported("memfd_create", MemfdCreate)
 	s.Table[322] = syscalls.SupportedPoint("execveat", Execveat, linux.PointExecveat)
-	s.Table[327] = syscalls.Supported("preadv2", Preadv2)
-	s.Table[328] = syscalls.Supported("pwritev2", Pwritev2)
+	s.Table[327] = syscalls.SupportedPoint("preadv2", Preadv2, linux.PointPreadv2)
+	s.Table[328] = syscalls.SupportedPoint("pwritev2", Pwritev2, linux.PointPwritev2)
 	s.Table[332] = syscalls.Supported("statx", Statx)
 	s.Table[436] = syscalls.Supported("close_range", CloseRange)
 	s.Table[439] = syscalls.Supported("faccessat2", Faccessat2)
@@ -194,21 +194,21 @@ func Override() {
 	s.Table[27] = syscalls.PartiallySupportedPoint("inotify_add_watch", InotifyAddWatch, linux.PointInotifyAddWatch, "inotify events are only available inside the sandbox.", nil)
 	s.Table[28] = syscalls.PartiallySupportedPoint("inotify_rm_watch", InotifyRmWatch, linux.PointInotifyRmWatch, "inotify events are only available inside the sandbox.", nil)
 	s.Table[29] = syscalls.Supported("ioctl", Ioctl)
-	s.Table[32] = syscalls.Supported("flock", Flock)
-	s.Table[33] = syscalls.Supported("mknodat", Mknodat)
+	s.Table[32] = syscalls.SupportedPoint("flock", Flock, linux.PointFlock)
+	s.Table[33] = syscalls.SupportedPoint("mknodat", Mknodat, linux.PointMknodat)
 	s.Table[34] = syscalls.Supported("mkdirat", Mkdirat)
-	s.Table[35] = syscalls.Supported("unlinkat", Unlinkat)
+	s.Table[35] = syscalls.SupportedPoint("unlinkat", Unlinkat, linux.PointUnlinkat)
 	s.Table[36] = syscalls.Supported("symlinkat", Symlinkat)
 	s.Table[37] = syscalls.Supported("linkat", Linkat)
-	s.Table[38] = syscalls.Supported("renameat", Renameat)
-	s.Table[39] = syscalls.Supported("umount2", Umount2)
-	s.Table[40] = syscalls.Supported("mount", Mount)
-	s.Table[41] = syscalls.Supported("pivot_root", PivotRoot)
+	s.Table[38] = syscalls.SupportedPoint("renameat", Renameat, linux.PointRenameat)
+	s.Table[39] = syscalls.SupportedPoint("umount2", Umount2, linux.PointUmount2)
+	s.Table[40] = syscalls.SupportedPoint("mount", Mount, linux.PointMount)
+	s.Table[41] = syscalls.SupportedPoint("pivot_root", PivotRoot, linux.PointPivotRoot)
 	s.Table[43] = syscalls.Supported("statfs", Statfs)
 	s.Table[44] = syscalls.Supported("fstatfs", Fstatfs)
 	s.Table[45] = syscalls.Supported("truncate", Truncate)
 	s.Table[46] = syscalls.Supported("ftruncate", Ftruncate)
-	s.Table[47] = syscalls.PartiallySupported("fallocate", Fallocate, "Not all options are supported.", nil)
+	s.Table[47] = syscalls.PartiallySupportedPoint("fallocate", Fallocate, linux.PointFallocate, "Not all options are supported.", nil)
 	s.Table[48] = syscalls.Supported("faccessat", Faccessat)
 	s.Table[49] = syscalls.SupportedPoint("chdir", Chdir, linux.PointChdir)
 	s.Table[50] = syscalls.SupportedPoint("fchdir", Fchdir, linux.PointFchdir)
@@ -223,13 +223,13 @@ func Override() {
 	s.Table[61] = syscalls.Supported("getdents64", Getdents64)
 	s.Table[62] = syscalls.Supported("lseek", Lseek)
 	s.Table[63] = syscalls.SupportedPoint("read", Read, linux.PointRead)
-	s.Table[64] = syscalls.Supported("write", Write)
-	s.Table[65] = syscalls.Supported("readv", Readv)
-	s.Table[66] = syscalls.Supported("writev", Writev)
-	s.Table[67] = syscalls.Supported("pread64", Pread64)
-	s.Table[68] = syscalls.Supported("pwrite64", Pwrite64)
-	s.Table[69] = syscalls.Supported("preadv", Preadv)
-	s.Table[70] = syscalls.Supported("pwritev", Pwritev)
+	s.Table[64] = syscalls.SupportedPoint("write", Write, linux.PointWrite)
+	s.Table[65] = syscalls.SupportedPoint("readv", Readv, linux.PointReadv)
+	s.Table[66] = syscalls.SupportedPoint("writev", Writev, linux.PointWritev)
+	s.Table[67] = syscalls.SupportedPoint("pread64", Pread64, linux.PointPread64)
+	s.Table[68] = syscalls.SupportedPoint("pwrite64", Pwrite64, linux.PointPwrite64)
+	s.Table[69] = syscalls.SupportedPoint("preadv", Preadv, linux.PointPreadv)
+	s.Table[70] = syscalls.SupportedPoint("pwritev", Pwritev, linux.PointPwritev)
 	s.Table[71] = syscalls.Supported("sendfile", Sendfile)
 	s.Table[72] = syscalls.Supported("pselect", Pselect)
 	s.Table[73] = syscalls.Supported("ppoll", Ppoll)
@@ -239,15 +239,15 @@ func Override() {
 	s.Table[78] = syscalls.Supported("readlinkat", Readlinkat)
 	s.Table[79] = syscalls.Supported("newfstatat", Newfstatat)
 	s.Table[80] = syscalls.Supported("fstat", Fstat)
-	s.Table[81] = syscalls.Supported("sync", Sync)
-	s.Table[82] = syscalls.Supported("fsync", Fsync)
-	s.Table[83] = syscalls.Supported("fdatasync", Fdatasync)
+	s.Table[81] = syscalls.SupportedPoint("sync", Sync, linux.PointSync)
+	s.Table[82] = syscalls.SupportedPoint("fsync", Fsync, linux.PointFsync)
+	s.Table[83] = syscalls.SupportedPoint("fdatasync", Fdatasync, linux.PointFdatasync)
 	s.Table[84] = syscalls.Supported("sync_file_range", SyncFileRange)
 	s.Table[85] = syscalls.SupportedPoint("timerfd_create", TimerfdCreate, linux.PointTimerfdCreate)
 	s.Table[86] = syscalls.SupportedPoint("timerfd_settime", TimerfdSettime, linux.PointTimerfdSettime)
 	s.Table[87] = syscalls.SupportedPoint("timerfd_gettime", TimerfdGettime, linux.PointTimerfdGettime)
-	s.Table[88] = syscalls.Supported("utimensat", Utimensat)
-	s.Table[180] = syscalls.Supported("mq_open", MqOpen)
+	s.Table[88] = syscalls.SupportedPoint("utimensat", Utimensat, linux.PointUtimensat)
+	s.Table[180] = syscalls.SupportedPoint("mq_open", MqOpen, linux.PointMqOpen)
 	s.Table[181] = syscalls.Supported("mq_unlink", MqUnlink)
 	s.Table[198] = syscalls.SupportedPoint("socket", Socket, linux.PointSocket)
 	s.Table[199] = syscalls.SupportedPoint("socketpair", SocketPair, linux.PointSocketpair)
@@ -255,28 +255,28 @@ func Override() {
 	s.Table[201] = syscalls.Supported("listen", Listen)
 	s.Table[202] = syscalls.SupportedPoint("accept", Accept, linux.PointAccept)
 	s.Table[203] = syscalls.SupportedPoint("connect", Connect, linux.PointConnect)
-	s.Table[204] = syscalls.Supported("getsockname", GetSockName)
-	s.Table[205] = syscalls.Supported("getpeername", GetPeerName)
-	s.Table[206] = syscalls.Supported("sendto", SendTo)
+	s.Table[204] = syscalls.SupportedPoint("getsockname", GetSockName, linux.PointGetsockname)
+	s.Table[205] = syscalls.SupportedPoint("getpeername", GetPeerName, linux.PointGetpeername)
+	s.Table[206] = syscalls.SupportedPoint("sendto", SendTo, linux.PointSendto)
 	s.Table[207] = syscalls.Supported("recvfrom", RecvFrom)
-	s.Table[208] = syscalls.Supported("setsockopt", SetSockOpt)
-	s.Table[209] = syscalls.Supported("getsockopt", GetSockOpt)
-	s.Table[210] = syscalls.Supported("shutdown", Shutdown)
-	s.Table[211] = syscalls.Supported("sendmsg", SendMsg)
+	s.Table[208] = syscalls.SupportedPoint("setsockopt", SetSockOpt, linux.PointSetsockopt)
+	s.Table[209] = syscalls.SupportedPoint("getsockopt", GetSockOpt, linux.PointGetsockopt)
+	s.Table[210] = syscalls.SupportedPoint("shutdown", Shutdown, linux.PointShutdown)
+	s.Table[211] = syscalls.SupportedPoint("sendmsg", SendMsg, linux.PointSendmsg)
 	s.Table[212] = syscalls.Supported("recvmsg", RecvMsg)
 	s.Table[213] = syscalls.Supported("readahead", Readahead)
 	s.Table[221] = syscalls.SupportedPoint("execve", Execve, linux.PointExecve)
-	s.Table[222] = syscalls.Supported("mmap", Mmap)
+	s.Table[222] = syscalls.SupportedPoint("mmap", Mmap, linux.PointMmap)
 	s.Table[223] = syscalls.PartiallySupported("fadvise64", Fadvise64, "Not all options are supported.", nil)
 	s.Table[242] = syscalls.SupportedPoint("accept4", Accept4, linux.PointAccept4)
 	s.Table[243] = syscalls.Supported("recvmmsg", RecvMMsg)
-	s.Table[267] = syscalls.Supported("syncfs", Syncfs)
+	s.Table[267] = syscalls.SupportedPoint("syncfs", Syncfs, linux.PointSyncfs)
 	s.Table[269] = syscalls.Supported("sendmmsg", SendMMsg)
 	s.Table[276] = syscalls.Supported("renameat2", Renameat2)
 	s.Table[279] = syscalls.Supported("memfd_create", MemfdCreate)
 	s.Table[281] = syscalls.SupportedPoint("execveat", Execveat, linux.PointExecveat)
-	s.Table[286] = syscalls.Supported("preadv2", Preadv2)
-	s.Table[287] = syscalls.Supported("pwritev2", Pwritev2)
+	s.Table[286] = syscalls.SupportedPoint("preadv2", Preadv2, linux.PointPreadv2)
+	s.Table[287] = syscalls.SupportedPoint("pwritev2", Pwritev2, linux.PointPwritev2)
 	s.Table[291] = syscalls.Supported("statx", Statx)
 	s.Table[436] = syscalls.Supported("close_range", CloseRange)
 	s.Table[439] = syscalls.Supported("faccessat2", Faccessat2)
@@ -104,6 +104,15 @@ func ErrorWithEvent(name string, err error, note string, urls []string) kernel.S
 	}
 }
 
+// ErrorWithEventPoint gives a syscall function that sends an unimplemented
+// syscall event via the event channel and returns the passed error, with a
+// corresponding seccheck.Point.
+func ErrorWithEventPoint(name string, err error, cb kernel.SyscallToProto, note string, urls []string) kernel.Syscall {
+	sys := ErrorWithEvent(name, err, note, urls)
+	sys.PointCallback = cb
+	return sys
+}
+
 // CapError gives a syscall function that checks for capability c.  If the task
 // has the capability, it returns ENOSYS, otherwise EPERM. To unprivileged
 // tasks, it will seem like there is an implementation.
@@ -125,3 +134,11 @@ func CapError(name string, c linux.Capability, note string, urls []string) kerne
 		URLs:         urls,
 	}
 }
+
+// CapErrorPoint gives a syscall function that checks for capability c, like
+// CapError, with a corresponding seccheck.Point.
+func CapErrorPoint(name string, c linux.Capability, cb kernel.SyscallToProto, note string, urls []string) kernel.Syscall {
+	sys := CapError(name, c, note, urls)
+	sys.PointCallback = cb
+	return sys
+}
@@ -25,6 +25,8 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	fslock "gvisor.dev/gvisor/pkg/sentry/fs/lock"
 	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/usermem"
 	"gvisor.dev/gvisor/pkg/waiter"
@@ -378,6 +380,19 @@ func (fd *DynamicBytesFileDescriptionImpl) pwriteLocked(ctx context.Context, src
 	if !ok {
 		return 0, linuxerr.EIO
 	}
+
+	// Capture the value before the write consumes src.
+	var value []byte
+	checkWrite := seccheck.Global.Enabled(seccheck.PointSyntheticFileWrite)
+	if checkWrite {
+		value = make([]byte, src.NumBytes())
+		if len(value) > maxSyntheticFileWriteValue {
+			value = value[:maxSyntheticFileWriteValue]
+		}
+		c, _ := src.CopyIn(ctx, value)
+		value = value[:c]
+	}
+
 	n, err := writable.Write(ctx, fd.vfsfd, src, offset)
 	if err != nil {
 		return 0, err
@@ -385,9 +400,42 @@ func (fd *DynamicBytesFileDescriptionImpl) pwriteLocked(ctx context.Context, src
 
 	// Invalidate cached data that might exist prior to this call.
 	fd.buf.Reset()
+
+	if checkWrite {
+		fd.writeSeccheck(ctx, offset, n, value)
+	}
 	return n, nil
 }
 
+// maxSyntheticFileWriteValue is the maximum number of bytes written to a
+// synthetic file that are reported to seccheck.
+const maxSyntheticFileWriteValue = 256
+
+// writeSeccheck reports a write of n bytes at offset to seccheck. value holds
+// the first bytes written.
+func (fd *DynamicBytesFileDescriptionImpl) writeSeccheck(ctx context.Context, offset, n int64, value []byte) {
+	if int64(len(value)) > n {
+		value = value[:n]
+	}
+	vfsObj := fd.vfsfd.Mount().Filesystem().VirtualFilesystem()
+	info := &pb.SyntheticFileWriteInfo{
+		Path:      vfsObj.seccheckPathname(ctx, fd.vfsfd.VirtualDentry()),
+		FsType:    fd.vfsfd.Mount().Filesystem().FilesystemType().Name(),
+		Offset:    offset,
+		Size:      n,
+		Value:     value,
+		Truncated: int64(len(value)) < n,
+	}
+	fields := seccheck.Global.GetFieldSet(seccheck.PointSyntheticFileWrite)
+	if !fields.Context.Empty() {
+		info.ContextData = &pb.ContextData{}
+		seccheck.LoadContextData(ctx, fields.Context, info.ContextData)
+	}
+	seccheck.Global.SendToCheckers(seccheck.ContainerID(ctx), seccheck.PointSyntheticFileWrite, func(c seccheck.Checker) error {
+		return c.SyntheticFileWrite(ctx, fields, info)
+	})
+}
+
 // PWrite implements FileDescriptionImpl.PWrite.
 func (fd *DynamicBytesFileDescriptionImpl) PWrite(ctx context.Context, src usermem.IOSequence, offset int64, opts WriteOptions) (int64, error) {
 	fd.mu.Lock()
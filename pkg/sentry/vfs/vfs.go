@@ -46,6 +46,8 @@ import (
 	"gvisor.dev/gvisor/pkg/fspath"
 	"gvisor.dev/gvisor/pkg/sentry/fsmetric"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 	"gvisor.dev/gvisor/pkg/sentry/socket/unix/transport"
 	"gvisor.dev/gvisor/pkg/sync"
 )
@@ -418,7 +420,11 @@ func (vfs *VirtualFilesystem) OpenAt(ctx context.Context, creds *auth.Credential
 		pop.FollowFinalSymlink = false
 	}
 	if opts.Flags&linux.O_PATH != 0 {
-		return vfs.openOPathFD(ctx, creds, pop, opts.Flags)
+		fd, err := vfs.openOPathFD(ctx, creds, pop, opts.Flags)
+		if err == nil && seccheck.Global.Enabled(seccheck.PointFileOpen) {
+			vfs.openSeccheck(ctx, fd, opts)
+		}
+		return fd, err
 	}
 	rp := vfs.getResolvingPath(creds, pop)
 	if opts.Flags&linux.O_DIRECTORY != 0 {
@@ -448,6 +454,9 @@ func (vfs *VirtualFilesystem) OpenAt(ctx context.Context, creds *auth.Credential
 			}
 
 			fd.Dentry().InotifyWithParent(ctx, linux.IN_OPEN, 0, PathEvent)
+			if seccheck.Global.Enabled(seccheck.PointFileOpen) {
+				vfs.openSeccheck(ctx, fd, opts)
+			}
 			return fd, nil
 		}
 		if !rp.handleError(ctx, err) {
@@ -457,6 +466,37 @@ func (vfs *VirtualFilesystem) OpenAt(ctx context.Context, creds *auth.Credential
 	}
 }
 
+// openSeccheck reports the opening of fd with opts to seccheck.
+func (vfs *VirtualFilesystem) openSeccheck(ctx context.Context, fd *FileDescription, opts *OpenOptions) {
+	info := &pb.FileOpenInfo{
+		Path:     vfs.seccheckPathname(ctx, fd.VirtualDentry()),
+		FsType:   fd.Mount().Filesystem().FilesystemType().Name(),
+		Flags:    opts.Flags,
+		Mode:     uint32(opts.Mode),
+		FileExec: opts.FileExec,
+	}
+	fields := seccheck.Global.GetFieldSet(seccheck.PointFileOpen)
+	if !fields.Context.Empty() {
+		info.ContextData = &pb.ContextData{}
+		seccheck.LoadContextData(ctx, fields.Context, info.ContextData)
+	}
+	seccheck.Global.SendToCheckers(seccheck.ContainerID(ctx), seccheck.PointFileOpen, func(c seccheck.Checker) error {
+		return c.FileOpen(ctx, fields, info)
+	})
+}
+
+// seccheckPathname returns the path of vd relative to the root directory of
+// ctx, or an empty string if ctx doesn't have a root directory.
+func (vfs *VirtualFilesystem) seccheckPathname(ctx context.Context, vd VirtualDentry) string {
+	root := RootFromContext(ctx)
+	if !root.Ok() {
+		return ""
+	}
+	defer root.DecRef(ctx)
+	path, _ := vfs.PathnameWithDeleted(ctx, root, vd)
+	return path
+}
+
 // ReadlinkAt returns the target of the symbolic link at the given path.
 func (vfs *VirtualFilesystem) ReadlinkAt(ctx context.Context, creds *auth.Credentials, pop *PathOperation) (string, error) {
 	rp := vfs.getResolvingPath(creds, pop)
@@ -0,0 +1,102 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+const (
+	dnsID            = 0
+	dnsFlags         = 2
+	dnsQuestionCount = 4
+)
+
+const (
+	// DNSMinimumSize is the size of the DNS message header, as per RFC 1035
+	// section 4.1.1.
+	DNSMinimumSize = 12
+
+	// DNSPort is the well-known port for DNS.
+	DNSPort = 53
+
+	// dnsResponseFlag is the QR bit in the first byte of the flags field.
+	dnsResponseFlag = 0x80
+
+	// dnsMaxNameLength is the maximum length of a domain name in its wire
+	// format, as per RFC 1035 section 2.3.4.
+	dnsMaxNameLength = 255
+
+	// dnsLabelPointerMask identifies a compressed label, as per RFC 1035
+	// section 4.1.4.
+	dnsLabelPointerMask = 0xc0
+)
+
+// DNS represents a DNS message stored in a byte array.
+type DNS []byte
+
+// ID returns the "ID" field of the DNS header.
+func (b DNS) ID() uint16 {
+	return binary.BigEndian.Uint16(b[dnsID:])
+}
+
+// IsQuery returns true if the message is a query, as opposed to a response.
+func (b DNS) IsQuery() bool {
+	return b[dnsFlags]&dnsResponseFlag == 0
+}
+
+// QuestionCount returns the "QDCOUNT" field of the DNS header.
+func (b DNS) QuestionCount() uint16 {
+	return binary.BigEndian.Uint16(b[dnsQuestionCount:])
+}
+
+// FirstQuestion parses the first entry of the question section, returning the
+// queried name and record type. ok is false if the message doesn't hold a
+// well-formed question.
+func (b DNS) FirstQuestion() (name string, qtype uint16, ok bool) {
+	if len(b) < DNSMinimumSize || b.QuestionCount() == 0 {
+		return "", 0, false
+	}
+	var labels []string
+	off := DNSMinimumSize
+	nameLen := 0
+	for {
+		if off >= len(b) {
+			return "", 0, false
+		}
+		l := int(b[off])
+		off++
+		if l == 0 {
+			break
+		}
+		// Questions are not expected to use compression, since nothing
+		// precedes them that could be pointed to.
+		if l&dnsLabelPointerMask != 0 {
+			return "", 0, false
+		}
+		nameLen += l + 1
+		if nameLen > dnsMaxNameLength || off+l > len(b) {
+			return "", 0, false
+		}
+		labels = append(labels, string(b[off:off+l]))
+		off += l
+	}
+	// QTYPE and QCLASS follow the name.
+	if off+4 > len(b) {
+		return "", 0, false
+	}
+	return strings.Join(labels, "."), binary.BigEndian.Uint16(b[off:]), true
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header_test
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+)
+
+func TestDNSFirstQuestion(t *testing.T) {
+	hdr := []byte{
+		0x12, 0x34, // ID
+		0x01, 0x00, // Flags: standard query, recursion desired.
+		0x00, 0x01, // QDCOUNT
+		0x00, 0x00, // ANCOUNT
+		0x00, 0x00, // NSCOUNT
+		0x00, 0x00, // ARCOUNT
+	}
+	question := []byte{
+		7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+		0x00, 0x1c, // QTYPE: AAAA
+		0x00, 0x01, // QCLASS: IN
+	}
+
+	for _, tc := range []struct {
+		name      string
+		msg       []byte
+		wantName  string
+		wantType  uint16
+		wantValid bool
+	}{
+		{
+			name:      "valid",
+			msg:       append(append([]byte{}, hdr...), question...),
+			wantName:  "example.com",
+			wantType:  28,
+			wantValid: true,
+		},
+		{
+			name: "header only",
+			msg:  hdr,
+		},
+		{
+			name: "truncated name",
+			msg:  append(append([]byte{}, hdr...), question[:5]...),
+		},
+		{
+			name: "missing type",
+			msg:  append(append([]byte{}, hdr...), question[:13]...),
+		},
+		{
+			name: "compressed name",
+			msg:  append(append([]byte{}, hdr...), 0xc0, 0x0c, 0x00, 0x01, 0x00, 0x01),
+		},
+		{
+			name: "too short",
+			msg:  hdr[:4],
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			name, qtype, ok := header.DNS(tc.msg).FirstQuestion()
+			if ok != tc.wantValid {
+				t.Fatalf("FirstQuestion() ok = %t, want %t", ok, tc.wantValid)
+			}
+			if name != tc.wantName || qtype != tc.wantType {
+				t.Errorf("FirstQuestion() = (%q, %d), want (%q, %d)", name, qtype, tc.wantName, tc.wantType)
+			}
+		})
+	}
+}
@@ -134,6 +134,10 @@ type Stack struct {
 	// integrator NUD related events.
 	nudDisp NUDDispatcher
 
+	// transportEventDisp is the transport event dispatcher that is used to
+	// send the netstack integrator transport endpoint related events.
+	transportEventDisp TransportEventDispatcher
+
 	// uniqueIDGenerator is a generator of unique identifiers.
 	uniqueIDGenerator UniqueID
 
@@ -217,6 +221,10 @@ type Options struct {
 	// receive NUD related events.
 	NUDDisp NUDDispatcher
 
+	// TransportEventDisp is the transport event dispatcher that an integrator
+	// can provide to receive transport endpoint related events.
+	TransportEventDisp TransportEventDispatcher
+
 	// RawFactory produces raw endpoints. Raw endpoints are enabled only if
 	// this is non-nil.
 	RawFactory RawFactory
@@ -381,6 +389,7 @@ func New(opts Options) *Stack {
 		nudConfigs:                   opts.NUDConfigs,
 		uniqueIDGenerator:            opts.UniqueID,
 		nudDisp:                      opts.NUDDisp,
+		transportEventDisp:           opts.TransportEventDisp,
 		randomGenerator:              randomGenerator,
 		secureRNG:                    opts.SecureRNG,
 		sendBufferSize: tcpip.SendBufferSizeOption{
@@ -500,6 +509,12 @@ func (s *Stack) Clock() tcpip.Clock {
 	return s.clock
 }
 
+// TransportEventDispatcher returns the Stack's transport event dispatcher, or
+// nil if none was provided.
+func (s *Stack) TransportEventDispatcher() TransportEventDispatcher {
+	return s.transportEventDisp
+}
+
 // Stats returns a mutable copy of the current stats.
 //
 // This is not generally exported via the public interface, but is available
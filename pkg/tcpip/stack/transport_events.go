@@ -0,0 +1,99 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+// TCPEstablishedInfo holds information about a TCP connection that completed
+// its 3-way handshake.
+type TCPEstablishedInfo struct {
+	// NetProto is the network protocol of the connection.
+	NetProto tcpip.NetworkProtocolNumber
+
+	// ID identifies the connection.
+	ID TransportEndpointID
+
+	// Active is true if the connection was initiated locally, i.e. connect(2),
+	// and false if it was accepted from a listening endpoint.
+	Active bool
+
+	// Owner is the owner of the endpoint, if any. Accepted connections inherit
+	// the owner of the listening endpoint.
+	Owner tcpip.PacketOwner
+}
+
+// DNSQueryInfo holds information about an outbound DNS query.
+type DNSQueryInfo struct {
+	// NetProto is the network protocol the query was sent over.
+	NetProto tcpip.NetworkProtocolNumber
+
+	// ID identifies the endpoint that sent the query.
+	ID TransportEndpointID
+
+	// Name is the queried domain name.
+	Name string
+
+	// Type is the queried record type, e.g. 1 for A records.
+	Type uint16
+
+	// Owner is the owner of the endpoint, if any.
+	Owner tcpip.PacketOwner
+}
+
+// ListenInfo holds information about an endpoint that entered the listening
+// state.
+type ListenInfo struct {
+	// NetProto is the network protocol of the endpoint.
+	NetProto tcpip.NetworkProtocolNumber
+
+	// TransProto is the transport protocol of the endpoint.
+	TransProto tcpip.TransportProtocolNumber
+
+	// ID identifies the endpoint. Only the local address and port are set.
+	ID TransportEndpointID
+
+	// Owner is the owner of the endpoint, if any.
+	Owner tcpip.PacketOwner
+}
+
+// TransportEventDispatcher is the interface integrators of netstack must
+// implement to receive and handle transport endpoint related events.
+type TransportEventDispatcher interface {
+	// OnTCPEstablished will be called when a TCP connection completes its
+	// 3-way handshake, in either direction.
+	//
+	// This function is called with the endpoint lock held, so it must not
+	// block or call back into the endpoint.
+	//
+	// May be called concurrently.
+	OnTCPEstablished(TCPEstablishedInfo)
+
+	// OnDNSQuery will be called when a DNS query is sent over UDP.
+	//
+	// May be called concurrently.
+	OnDNSQuery(DNSQueryInfo)
+
+	// OnListen will be called when an endpoint enters the listening state.
+	// It's not called when the backlog of an already listening endpoint is
+	// adjusted.
+	//
+	// This function is called with the endpoint lock held, so it must not
+	// block or call back into the endpoint.
+	//
+	// May be called concurrently.
+	OnListen(ListenInfo)
+}
@@ -204,6 +204,13 @@ func (e *Endpoint) SetOwner(owner tcpip.PacketOwner) {
 	e.owner = owner
 }
 
+// Owner returns the owner of transmitted packets.
+func (e *Endpoint) Owner() tcpip.PacketOwner {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.owner
+}
+
 // +checklocksread:e.mu
 func (e *Endpoint) calculateTTL(route *stack.Route) uint8 {
 	remoteAddress := route.RemoteAddress()
@@ -664,6 +664,15 @@ func (h *handshake) transitionToStateEstablishedLocked(s *segment) {
 
 	h.ep.setEndpointState(StateEstablished)
 
+	if d := h.ep.stack.TransportEventDispatcher(); d != nil {
+		d.OnTCPEstablished(stack.TCPEstablishedInfo{
+			NetProto: h.ep.TransportEndpointInfo.NetProto,
+			ID:       h.ep.TransportEndpointInfo.ID,
+			Active:   h.active,
+			Owner:    h.ep.owner,
+		})
+	}
+
 	// Completing the 3-way handshake is an indication that the route is valid
 	// and the remote is reachable as the only way we can complete a handshake
 	// is if our SYN reached the remote and their ACK reached us.
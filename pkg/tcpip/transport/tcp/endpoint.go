@@ -2612,6 +2612,18 @@ func (e *endpoint) listen(backlog int) tcpip.Error {
 	rcvWnd := seqnum.Size(e.receiveBufferAvailable())
 	e.listenCtx = newListenContext(e.stack, e.protocol, e, rcvWnd, e.ops.GetV6Only(), e.NetProto)
 
+	if d := e.stack.TransportEventDispatcher(); d != nil {
+		d.OnListen(stack.ListenInfo{
+			NetProto:   e.NetProto,
+			TransProto: ProtocolNumber,
+			ID: stack.TransportEndpointID{
+				LocalAddress: e.TransportEndpointInfo.ID.LocalAddress,
+				LocalPort:    e.TransportEndpointInfo.ID.LocalPort,
+			},
+			Owner: e.owner,
+		})
+	}
+
 	return nil
 }
 
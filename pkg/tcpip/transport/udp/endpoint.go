@@ -528,9 +528,39 @@ func (e *endpoint) write(p tcpip.Payloader, opts tcpip.WriteOptions) (int64, tcp
 
 	// Track count of packets sent.
 	e.stack.Stats().UDP.PacketsSent.Increment()
+
+	if udpInfo.remotePort == header.DNSPort {
+		if d := e.stack.TransportEventDispatcher(); d != nil {
+			e.dispatchDNSQuery(d, &pktInfo, &udpInfo)
+		}
+	}
 	return int64(len(udpInfo.data)), nil
 }
 
+// dispatchDNSQuery reports the DNS query held in udpInfo, if any, to d.
+func (e *endpoint) dispatchDNSQuery(d stack.TransportEventDispatcher, pktInfo *network.WritePacketInfo, udpInfo *udpPacketInfo) {
+	dns := header.DNS(udpInfo.data)
+	if len(dns) < header.DNSMinimumSize || !dns.IsQuery() {
+		return
+	}
+	name, qtype, ok := dns.FirstQuestion()
+	if !ok {
+		return
+	}
+	d.OnDNSQuery(stack.DNSQueryInfo{
+		NetProto: pktInfo.NetProto,
+		ID: stack.TransportEndpointID{
+			LocalPort:     udpInfo.localPort,
+			LocalAddress:  pktInfo.LocalAddress,
+			RemotePort:    udpInfo.remotePort,
+			RemoteAddress: pktInfo.RemoteAddress,
+		},
+		Name:  name,
+		Type:  qtype,
+		Owner: e.net.Owner(),
+	})
+}
+
 // OnReuseAddressSet implements tcpip.SocketOptionsHandler.
 func (e *endpoint) OnReuseAddressSet(v bool) {
 	e.mu.Lock()
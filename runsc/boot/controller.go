@@ -22,6 +22,7 @@ import (
 
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/control/server"
 	"gvisor.dev/gvisor/pkg/fd"
 	"gvisor.dev/gvisor/pkg/log"
@@ -29,6 +30,7 @@ import (
 	controlpb "gvisor.dev/gvisor/pkg/sentry/control/control_go_proto"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 	"gvisor.dev/gvisor/pkg/sentry/socket/netstack"
 	"gvisor.dev/gvisor/pkg/sentry/state"
 	"gvisor.dev/gvisor/pkg/sentry/time"
@@ -91,8 +93,20 @@ const (
 	// ContMgrListTraceSessions lists a trace session.
 	ContMgrListTraceSessions = "containerManager.ListTraceSessions"
 
+	// ContMgrUpdateTraceSession updates the points of a trace session, and
+	// reloads the configuration of its sinks.
+	ContMgrUpdateTraceSession = "containerManager.UpdateTraceSession"
+
+	// ContMgrReconnectTraceSink hands a new connection to a sink of a trace
+	// session whose remote process went away.
+	ContMgrReconnectTraceSink = "containerManager.ReconnectTraceSink"
+
 	// ContMgrProcfsDump dumps sandbox procfs state.
 	ContMgrProcfsDump = "containerManager.ProcfsDump"
+
+	// ContMgrResumeQuarantined resumes a container that was paused by a
+	// policy violation.
+	ContMgrResumeQuarantined = "containerManager.ResumeQuarantined"
 )
 
 const (
@@ -354,11 +368,20 @@ func (cm *containerManager) StartSubcontainer(args *StartArgs, _ *struct{}) erro
 	return nil
 }
 
+// DestroySubcontainerArgs are arguments to the DestroySubcontainer method.
+type DestroySubcontainerArgs struct {
+	// CID is the container ID.
+	CID string
+
+	// Initiator is the host process that requests the operation.
+	Initiator control.Initiator
+}
+
 // DestroySubcontainer stops a container if it is still running and cleans up
 // its filesystem.
-func (cm *containerManager) DestroySubcontainer(cid *string, _ *struct{}) error {
-	log.Debugf("containerManager.DestroySubcontainer, cid: %s", *cid)
-	return cm.l.destroySubcontainer(*cid)
+func (cm *containerManager) DestroySubcontainer(args *DestroySubcontainerArgs, _ *struct{}) error {
+	log.Debugf("containerManager.DestroySubcontainer, cid: %s", args.CID)
+	return cm.l.destroySubcontainer(args.CID, &args.Initiator)
 }
 
 // ExecuteAsync starts running a command on a created or running sandbox. It
@@ -509,6 +532,19 @@ func (cm *containerManager) Restore(o *RestoreOpts, _ *struct{}) error {
 		return fmt.Errorf("starting sandbox: %v", err)
 	}
 
+	if seccheck.Global.Enabled(seccheck.PointRestore) {
+		info := pb.RestoreInfo{
+			SandboxId: o.SandboxID,
+			Metadata:  state.PreviousMetadata(),
+			Workload:  seccheck.Workload("" /* cid */),
+		}
+		info.TimeNs, info.MonotonicTimeNs = cm.l.k.SeccheckTime()
+		fields := seccheck.Global.GetFieldSet(seccheck.PointRestore)
+		_ = seccheck.Global.SendToCheckers("" /* cid */, seccheck.PointRestore, func(c seccheck.Checker) error {
+			return c.Restore(context.Background(), fields, &info)
+		})
+	}
+
 	return nil
 }
 
@@ -624,6 +660,39 @@ func (cm *containerManager) DeleteTraceSession(name *string, _ *struct{}) error
 	return seccheck.Delete(*name)
 }
 
+// UpdateTraceSession updates the points of an existing trace session, and
+// reloads the configuration of its sinks, see seccheck.Update.
+func (cm *containerManager) UpdateTraceSession(config *seccheck.SessionConfig, _ *struct{}) error {
+	log.Debugf("containerManager.UpdateTraceSession: config: %+v", config)
+	return seccheck.Update(config)
+}
+
+// ReconnectTraceSinkArgs are arguments to the ReconnectTraceSink method.
+type ReconnectTraceSinkArgs struct {
+	// Session is the name of the trace session.
+	Session string
+	// Index is the index of the sink in the configuration of the session.
+	Index int
+	// Sink is the configuration of the sink after setup.
+	Sink seccheck.SinkConfig
+	urpc.FilePayload
+}
+
+// ReconnectTraceSink hands the new connection in args.Files to a sink of a
+// trace session, see seccheck.Reconnect.
+func (cm *containerManager) ReconnectTraceSink(args *ReconnectTraceSinkArgs, _ *struct{}) error {
+	log.Debugf("containerManager.ReconnectTraceSink: session: %q, sink: %d", args.Session, args.Index)
+	if len(args.Files) != 1 || args.Files[0] == nil {
+		return fmt.Errorf("sink file is required")
+	}
+	fd, err := fd.NewFromFile(args.Files[0])
+	if err != nil {
+		return err
+	}
+	args.Sink.FD = fd
+	return seccheck.Reconnect(args.Session, args.Index, args.Sink)
+}
+
 // ListTraceSessions lists trace sessions.
 func (cm *containerManager) ListTraceSessions(_ *struct{}, out *[]seccheck.SessionConfig) error {
 	log.Debugf("containerManager.ListTraceSessions")
@@ -631,6 +700,16 @@ func (cm *containerManager) ListTraceSessions(_ *struct{}, out *[]seccheck.Sessi
 	return nil
 }
 
+// ResumeQuarantined resumes the tasks of a container that a trace session
+// paused after a policy violation, see seccheck.ViolationPauseContainer.
+func (cm *containerManager) ResumeQuarantined(cid *string, _ *struct{}) error {
+	log.Debugf("containerManager.ResumeQuarantined: cid: %s", *cid)
+	if !cm.l.k.TaskSet().ResumeContainer(*cid) {
+		return fmt.Errorf("container %q is not quarantined", *cid)
+	}
+	return nil
+}
+
 // ProcfsDump dumps procfs state of the sandbox.
 func (cm *containerManager) ProcfsDump(_ *struct{}, out *[]procfs.ProcessProcfsDump) error {
 	log.Debugf("containerManager.ProcfsDump")
@@ -0,0 +1,143 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// cpuThrottleInterval is how often the sandbox cgroup's CPU statistics are
+// sampled to detect throttling.
+const cpuThrottleInterval = 10 * time.Second
+
+// cpuThrottleStats holds the CFS bandwidth statistics from a cgroup's cpu.stat
+// file.
+type cpuThrottleStats struct {
+	periods          uint64
+	throttledPeriods uint64
+	throttledTime    time.Duration
+}
+
+// parseCPUStat parses the throttling statistics from the contents of a cgroup
+// v1 or v2 cpu.stat file.
+func parseCPUStat(data []byte) (cpuThrottleStats, error) {
+	var stats cpuThrottleStats
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 {
+			return cpuThrottleStats{}, fmt.Errorf("invalid cpu.stat line: %q", sc.Text())
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return cpuThrottleStats{}, fmt.Errorf("invalid cpu.stat line: %q: %w", sc.Text(), err)
+		}
+		switch fields[0] {
+		case "nr_periods":
+			stats.periods = value
+		case "nr_throttled":
+			stats.throttledPeriods = value
+		case "throttled_time":
+			// cgroup v1 reports nanoseconds.
+			stats.throttledTime = time.Duration(value)
+		case "throttled_usec":
+			// cgroup v2 reports microseconds.
+			stats.throttledTime = time.Duration(value) * time.Microsecond
+		}
+	}
+	return stats, sc.Err()
+}
+
+// readCPUStat reads the throttling statistics from the cpu.stat file f.
+func readCPUStat(f *fd.FD) (cpuThrottleStats, error) {
+	buf := make([]byte, 4096)
+	n, err := f.ReadAt(buf, 0)
+	if n == 0 && err != nil {
+		return cpuThrottleStats{}, err
+	}
+	return parseCPUStat(buf[:n])
+}
+
+// startCPUThrottleMonitor starts monitorCPUThrottle for the cpu.stat file f,
+// and returns a function that stops it and waits for it to close f.
+func startCPUThrottleMonitor(k *kernel.Kernel, f *fd.FD) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		monitorCPUThrottle(k, f, stop)
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// monitorCPUThrottle samples the sandbox cgroup's cpu.stat file f every
+// cpuThrottleInterval, and reports intervals in which the sandbox was
+// throttled to the checkers registered for seccheck.PointCPUThrottle. It
+// closes f and returns when stop is closed, or if f can't be read.
+func monitorCPUThrottle(k *kernel.Kernel, f *fd.FD, stop <-chan struct{}) {
+	defer f.Close()
+	ctx := k.SupervisorContext()
+
+	last, err := readCPUStat(f)
+	if err != nil {
+		log.Warningf("Reading cpu.stat failed, CPU throttling won't be reported: %v", err)
+		return
+	}
+	lastTime := time.Now()
+	ticker := time.NewTicker(cpuThrottleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		cur, err := readCPUStat(f)
+		if err != nil {
+			log.Warningf("Reading cpu.stat failed, CPU throttling won't be reported: %v", err)
+			return
+		}
+		now := time.Now()
+		if cur.throttledPeriods > last.throttledPeriods && seccheck.Global.Enabled(seccheck.PointCPUThrottle) {
+			info := &pb.CPUThrottleInfo{
+				Periods:          cur.periods - last.periods,
+				ThrottledPeriods: cur.throttledPeriods - last.throttledPeriods,
+				ThrottledTimeNs:  uint64(cur.throttledTime - last.throttledTime),
+				IntervalNs:       uint64(now.Sub(lastTime)),
+				Workload:         seccheck.Workload("" /* cid */),
+			}
+			info.TimeNs, info.MonotonicTimeNs = k.SeccheckTime()
+			fields := seccheck.Global.GetFieldSet(seccheck.PointCPUThrottle)
+			_ = seccheck.Global.SendToCheckers("" /* cid */, seccheck.PointCPUThrottle, func(c seccheck.Checker) error {
+				return c.CPUThrottle(ctx, fields, info)
+			})
+		}
+		last, lastTime = cur, now
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCPUStat(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data string
+		want cpuThrottleStats
+		err  bool
+	}{
+		{
+			name: "v1",
+			data: "nr_periods 10\nnr_throttled 3\nthrottled_time 5000\n",
+			want: cpuThrottleStats{periods: 10, throttledPeriods: 3, throttledTime: 5000},
+		},
+		{
+			name: "v2",
+			data: "usage_usec 100\nuser_usec 60\nsystem_usec 40\nnr_periods 10\nnr_throttled 3\nthrottled_usec 5\n",
+			want: cpuThrottleStats{periods: 10, throttledPeriods: 3, throttledTime: 5 * time.Microsecond},
+		},
+		{
+			name: "no-quota",
+			data: "usage_usec 100\n",
+		},
+		{
+			name: "invalid",
+			data: "nr_periods ten\n",
+			err:  true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseCPUStat([]byte(tc.data))
+			if tc.err {
+				if err == nil {
+					t.Fatalf("parseCPUStat(%q) succeeded, want error", tc.data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCPUStat(%q): %v", tc.data, err)
+			}
+			if got != tc.want {
+				t.Errorf("parseCPUStat(%q) = %+v, want %+v", tc.data, got, tc.want)
+			}
+		})
+	}
+}
@@ -85,8 +85,9 @@ var allowedSyscalls = seccomp.SyscallRules{
 			seccomp.EqualTo(linux.FUTEX_WAKE | linux.FUTEX_PRIVATE_FLAG),
 			seccomp.MatchAny{},
 		},
-		// Non-private variants are included for flipcall support. They are otherwise
-		// unncessary, as the sentry will use only private futexes internally.
+		// Non-private variants are included for flipcall and the shm trace sink.
+		// They are otherwise unncessary, as the sentry will use only private
+		// futexes internally.
 		{
 			seccomp.MatchAny{},
 			seccomp.EqualTo(linux.FUTEX_WAIT),
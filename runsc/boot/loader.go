@@ -21,6 +21,7 @@ import (
 	mrand "math/rand"
 	"os"
 	"runtime"
+	"sort"
 	gtime "time"
 
 	specs "github.com/opencontainers/runtime-spec/specs-go"
@@ -125,6 +126,10 @@ type Loader struct {
 	// should be called when a sandbox is destroyed.
 	stopProfiling func()
 
+	// stopCPUThrottle stops reporting CPU throttling of the sandbox. It should
+	// be called when a sandbox is destroyed.
+	stopCPUThrottle func()
+
 	// restore is set to true if we are restoring a container.
 	restore bool
 
@@ -229,6 +234,9 @@ type Args struct {
 	// SinkFDs is an ordered array of file descriptors to be used by seccheck
 	// sinks configured from the --pod-init-config file.
 	SinkFDs []int
+	// CPUStatFD is the file descriptor to the sandbox cgroup's cpu.stat file,
+	// used to report CPU throttling. Valid if >= 0.
+	CPUStatFD int
 }
 
 // make sure stdioFDs are always the same on initial start and on restore
@@ -415,22 +423,34 @@ func New(args Args) (*Loader, error) {
 	defer hostFilesystem.DecRef(k.SupervisorContext())
 	k.SetHostMount(k.VFS().NewDisconnectedMount(hostFilesystem, nil, &vfs.MountOptions{}))
 
+	podName, podNamespace, podUID := specutils.Pod(args.Spec)
+	seccheck.SetSandbox(args.ID, seccheck.Pod{Name: podName, Namespace: podNamespace, UID: podUID})
 	if args.PodInitConfigFD >= 0 {
 		if err := setupSeccheck(args.PodInitConfigFD, args.SinkFDs); err != nil {
 			log.Warningf("unable to configure event session: %v", err)
 		}
 	}
+	if len(args.Conf.TraceLog) > 0 {
+		if err := setupTraceLog(args.Conf.TraceLog); err != nil {
+			return nil, fmt.Errorf("configuring --trace-log: %w", err)
+		}
+	}
+	stopCPUThrottle := func() {}
+	if args.CPUStatFD >= 0 {
+		stopCPUThrottle = startCPUThrottleMonitor(k, fd.New(args.CPUStatFD))
+	}
 
 	eid := execID{cid: args.ID}
 	l := &Loader{
-		k:             k,
-		watchdog:      dog,
-		sandboxID:     args.ID,
-		processes:     map[execID]*execProcess{eid: {}},
-		mountHints:    mountHints,
-		root:          info,
-		stopProfiling: stopProfiling,
-		productName:   args.ProductName,
+		k:               k,
+		watchdog:        dog,
+		sandboxID:       args.ID,
+		processes:       map[execID]*execProcess{eid: {}},
+		mountHints:      mountHints,
+		root:            info,
+		stopProfiling:   stopProfiling,
+		stopCPUThrottle: stopCPUThrottle,
+		productName:     args.ProductName,
 	}
 
 	// We don't care about child signals; some platforms can generate a
@@ -504,12 +524,21 @@ func (l *Loader) Destroy() {
 		l.stopSignalForwarding()
 	}
 	l.watchdog.Stop()
+	l.stopCPUThrottle()
 
 	// Stop the control server. This will indirectly stop any
 	// long-running control operations that are in flight, e.g.
 	// profiling operations.
 	l.ctrl.stop()
 
+	// Report the containers that stop with the sandbox before stopping trace
+	// sessions, so that sinks flush these points too and then tell the remote
+	// process that the session is over.
+	if seccheck.Global.Enabled(seccheck.PointContainerStop) {
+		l.sandboxStopSeccheck()
+	}
+	seccheck.DeleteAll()
+
 	// Release all kernel resources. This is only safe after we can no longer
 	// save/restore.
 	l.k.Release()
@@ -649,7 +678,7 @@ func (l *Loader) run() error {
 				evt.ContextData = &pb.ContextData{}
 				kernel.LoadSeccheckData(tg.Leader(), fields.Context, evt.ContextData)
 			}
-			_ = seccheck.Global.SendToCheckers(func(c seccheck.Checker) error {
+			_ = seccheck.Global.SendToCheckers(l.sandboxID, seccheck.PointContainerStart, func(c seccheck.Checker) error {
 				return c.ContainerStart(context.Background(), fields, &evt)
 			})
 		}
@@ -797,7 +826,7 @@ func (l *Loader) startSubcontainer(spec *specs.Spec, conf *config.Config, cid st
 			evt.ContextData = &pb.ContextData{}
 			kernel.LoadSeccheckData(ep.tg.Leader(), fields.Context, evt.ContextData)
 		}
-		_ = seccheck.Global.SendToCheckers(func(c seccheck.Checker) error {
+		_ = seccheck.Global.SendToCheckers(cid, seccheck.PointContainerStart, func(c seccheck.Checker) error {
 			return c.ContainerStart(context.Background(), fields, &evt)
 		})
 	}
@@ -823,6 +852,10 @@ func (l *Loader) createContainerProcess(root bool, cid string, info *containerIn
 	}
 	l.startGoferMonitor(cid, int32(info.goferFDs[0].FD()))
 
+	if err := addTraceContainer(cid, info.spec); err != nil {
+		return nil, nil, err
+	}
+
 	mntr := newContainerMounter(info, l.k, l.mountHints, l.productName)
 	if root {
 		if err := mntr.processHints(info.conf, info.procArgs.Credentials); err != nil {
@@ -923,8 +956,8 @@ func (l *Loader) startGoferMonitor(cid string, rootfsGoferFD int32) {
 }
 
 // destroySubcontainer stops a container if it is still running and cleans up
-// its filesystem.
-func (l *Loader) destroySubcontainer(cid string) error {
+// its filesystem. initiator is the host process that requests it, if any.
+func (l *Loader) destroySubcontainer(cid string, initiator *control.Initiator) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
@@ -946,6 +979,10 @@ func (l *Loader) destroySubcontainer(cid string) error {
 				t.ThreadGroup().WaitExited()
 			}
 		}
+
+		if seccheck.Global.Enabled(seccheck.PointContainerStop) {
+			l.containerStopSeccheck(cid, tg, initiator.Proto())
+		}
 	}
 
 	// No more failure from this point on. Remove all container thread groups
@@ -955,11 +992,49 @@ func (l *Loader) destroySubcontainer(cid string) error {
 			delete(l.processes, key)
 		}
 	}
+	seccheck.RemoveContainer(cid)
 
 	log.Debugf("Container destroyed, cid: %s", cid)
 	return nil
 }
 
+// containerStopSeccheck sends seccheck.PointContainerStop for container cid,
+// whose init process is tg. initiator may be nil if the container wasn't
+// stopped by a host process.
+func (l *Loader) containerStopSeccheck(cid string, tg *kernel.ThreadGroup, initiator *pb.Initiator) {
+	evt := pb.Stop{
+		Id:         cid,
+		ExitStatus: int32(tg.ExitStatus()),
+		Workload:   seccheck.Workload(cid),
+		Initiator:  initiator,
+	}
+	evt.TimeNs, evt.MonotonicTimeNs = l.k.SeccheckTime()
+	fields := seccheck.Global.GetFieldSet(seccheck.PointContainerStop)
+	_ = seccheck.Global.SendToCheckers(cid, seccheck.PointContainerStop, func(c seccheck.Checker) error {
+		return c.ContainerStop(context.Background(), fields, &evt)
+	})
+}
+
+// sandboxStopSeccheck sends seccheck.PointContainerStop for the containers
+// that stop along with the sandbox, which are never destroyed with
+// destroySubcontainer. This includes the root container.
+func (l *Loader) sandboxStopSeccheck() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var cids []string
+	for key, p := range l.processes {
+		// Skip exec'd processes and containers that never started.
+		if key.pid == 0 && p.tg != nil {
+			cids = append(cids, key.cid)
+		}
+	}
+	sort.Strings(cids)
+	for _, cid := range cids {
+		l.containerStopSeccheck(cid, l.processes[execID{cid: cid}].tg, nil)
+	}
+}
+
 func (l *Loader) executeAsync(args *control.ExecArgs) (kernel.ThreadID, error) {
 	// Hold the lock for the entire operation to ensure that exec'd process is
 	// added to 'processes' in case it races with destroyContainer().
@@ -1015,6 +1090,29 @@ func (l *Loader) executeAsync(args *control.ExecArgs) (kernel.ThreadID, error) {
 	}
 	log.Debugf("updated processes: %v", l.processes)
 
+	if seccheck.Global.Enabled(seccheck.PointContainerExec) {
+		evt := pb.Exec{
+			Id:        args.ContainerID,
+			Cwd:       args.WorkingDirectory,
+			Args:      args.Argv,
+			Terminal:  args.StdioIsPty,
+			Uid:       uint32(args.KUID),
+			Gid:       uint32(args.KGID),
+			Initiator: args.Initiator.Proto(),
+		}
+		fields := seccheck.Global.GetFieldSet(seccheck.PointContainerExec)
+		if fields.Local.Contains(seccheck.FieldContainerExecEnv) {
+			evt.Env = args.Envv
+		}
+		if !fields.Context.Empty() {
+			evt.ContextData = &pb.ContextData{}
+			kernel.LoadSeccheckData(newTG.Leader(), fields.Context, evt.ContextData)
+		}
+		_ = seccheck.Global.SendToCheckers(args.ContainerID, seccheck.PointContainerExec, func(c seccheck.Checker) error {
+			return c.ContainerExec(context.Background(), fields, &evt)
+		})
+	}
+
 	return tgid, nil
 }
 
@@ -1151,6 +1249,7 @@ func newEmptySandboxNetworkStack(clock tcpip.Clock, uniqueID stack.UniqueID, all
 		AllowPacketEndpointWrite: allowPacketEndpointWrite,
 		UniqueID:                 uniqueID,
 		DefaultIPTables:          netfilter.DefaultLinuxTables,
+		TransportEventDisp:       netstack.SeccheckDispatcher{},
 	})}
 
 	// Enable SACK Recovery.
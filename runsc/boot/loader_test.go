@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -27,12 +29,14 @@ import (
 	"gvisor.dev/gvisor/pkg/fspath"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/p9"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/unet"
 	"gvisor.dev/gvisor/runsc/config"
 	"gvisor.dev/gvisor/runsc/flag"
 	"gvisor.dev/gvisor/runsc/fsgofer"
+	"gvisor.dev/gvisor/runsc/specutils"
 )
 
 func init() {
@@ -450,3 +454,123 @@ func TestCreateMountNamespace(t *testing.T) {
 		})
 	}
 }
+
+// TestInitConfigSessions checks that all trace sessions in the init config are
+// created, with the sink files in order.
+func TestInitConfigSessions(t *testing.T) {
+	initConf := InitConfig{
+		TraceSession: seccheck.SessionConfig{
+			Name:  seccheck.DefaultSessionName,
+			Sinks: []seccheck.SinkConfig{{Name: "null"}},
+		},
+		TraceSessions: []seccheck.SessionConfig{
+			{
+				Name:  "audit",
+				Sinks: []seccheck.SinkConfig{{Name: "null"}, {Name: "null"}},
+			},
+		},
+	}
+	if err := initConf.create([]int{-1, -1}); err == nil {
+		seccheck.DeleteAll()
+		t.Fatalf("create() with missing sink files succeeded")
+	}
+	seccheck.DeleteAll()
+
+	if err := initConf.create([]int{-1, -1, -1}); err != nil {
+		t.Fatalf("create(): %v", err)
+	}
+	defer seccheck.DeleteAll()
+	var sessions []seccheck.SessionConfig
+	seccheck.List(&sessions)
+	sinks := make(map[string]int)
+	for _, session := range sessions {
+		sinks[session.Name] = len(session.Sinks)
+	}
+	if want := map[string]int{seccheck.DefaultSessionName: 1, "audit": 2}; !reflect.DeepEqual(sinks, want) {
+		t.Errorf("wrong sessions, want: %v, got: %v", want, sinks)
+	}
+}
+
+func TestTraceAnnotations(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		annotations map[string]string
+		err         string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "sessions",
+			annotations: map[string]string{
+				annotationTraceSessions: "audit, ir",
+			},
+		},
+		{
+			name: "points",
+			annotations: map[string]string{
+				annotationTraceSessions: "audit",
+				annotationTracePoints:   "sentry/clone,sentry/execve",
+			},
+		},
+		{
+			name: "container-name",
+			annotations: map[string]string{
+				specutils.ContainerdContainerNameAnnotation: "app",
+			},
+		},
+		{
+			name: "points-without-sessions",
+			annotations: map[string]string{
+				annotationTracePoints: "sentry/clone",
+			},
+			err: "requires",
+		},
+		{
+			name: "invalid-point",
+			annotations: map[string]string{
+				annotationTraceSessions: "audit",
+				annotationTracePoints:   "sentry/foo",
+			},
+			err: "not found",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &specs.Spec{Annotations: tc.annotations}
+			err := addTraceContainer(tc.name, spec)
+			defer seccheck.RemoveContainer(tc.name)
+			if len(tc.err) == 0 {
+				if err != nil {
+					t.Errorf("addTraceContainer(): %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("addTraceContainer(), want error: %q, got: %v", tc.err, err)
+			}
+		})
+	}
+
+	if got, want := splitAnnotation(" a,,b ,"), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("splitAnnotation(), want: %q, got: %q", want, got)
+	}
+}
+
+func TestTraceWorkload(t *testing.T) {
+	spec := &specs.Spec{Annotations: map[string]string{
+		specutils.ContainerdContainerNameAnnotation: "app",
+		specutils.ContainerdPodNameAnnotation:       "web",
+		specutils.ContainerdPodNamespaceAnnotation:  "default",
+		specutils.ContainerdPodUIDAnnotation:        "1234",
+	}}
+	name, namespace, uid := specutils.Pod(spec)
+	seccheck.SetSandbox("sandbox", seccheck.Pod{Name: name, Namespace: namespace, UID: uid})
+	defer seccheck.SetSandbox("", seccheck.Pod{})
+	if err := addTraceContainer("cid", spec); err != nil {
+		t.Fatalf("addTraceContainer(): %v", err)
+	}
+	defer seccheck.RemoveContainer("cid")
+
+	w := seccheck.Workload("cid")
+	if w.SandboxId != "sandbox" || w.ContainerName != "app" || w.PodName != "web" || w.PodNamespace != "default" || w.PodUid != "1234" {
+		t.Errorf("wrong workload: %v", w)
+	}
+}
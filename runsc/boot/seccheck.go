@@ -16,21 +16,76 @@ package boot
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/runsc/specutils"
 
 	// Register supported of checkers.
+	_ "gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/anomaly"
 	_ "gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/null"
+	_ "gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/policy"
 	_ "gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote"
 )
 
+const (
+	// annotationTraceSessions is the OCI annotation with the comma-separated
+	// names of the trace sessions that a container opts into, see
+	// seccheck.SessionConfig.OptIn.
+	annotationTraceSessions = "dev.gvisor.trace/session"
+
+	// annotationTracePoints is the OCI annotation with the comma-separated
+	// names of the points that are sent for the container to the sessions in
+	// annotationTraceSessions. All of the sessions' points are sent if it's
+	// not set.
+	annotationTracePoints = "dev.gvisor.trace/points"
+)
+
 // InitConfig represents the configuration to apply during pod creation. For
-// now, it supports setting up an seccheck session.
+// now, it supports setting up seccheck sessions.
 type InitConfig struct {
+	// Version is the version of the configuration format, see
+	// seccheck.ConfigVersion.
+	Version int `json:"version,omitempty"`
+
 	TraceSession seccheck.SessionConfig `json:"trace_session"`
+
+	// TraceSessions are additional sessions, each with a unique name, e.g. an
+	// audit session to a file alongside one to a remote process.
+	TraceSessions []seccheck.SessionConfig `json:"trace_sessions,omitempty"`
+}
+
+// Sessions returns all sessions in the configuration. TraceSession is
+// skipped if it's not set.
+func (c *InitConfig) Sessions() []*seccheck.SessionConfig {
+	var sessions []*seccheck.SessionConfig
+	if len(c.TraceSession.Name) > 0 || len(c.TraceSession.Points) > 0 || len(c.TraceSession.Sinks) > 0 {
+		sessions = append(sessions, &c.TraceSession)
+	}
+	for i := range c.TraceSessions {
+		sessions = append(sessions, &c.TraceSessions[i])
+	}
+	return sessions
+}
+
+// traceLogSessionName is the name of the session created by --trace-log.
+const traceLogSessionName = "trace-log"
+
+// setupTraceLog creates a session that writes the points of the given
+// comma-separated presets to the debug log, see config.Config.TraceLog.
+func setupTraceLog(presets string) error {
+	return seccheck.Create(&seccheck.SessionConfig{
+		Name:    traceLogSessionName,
+		Presets: splitAnnotation(presets),
+		Sinks:   []seccheck.SinkConfig{{Name: "log"}},
+	}, false /* force */)
 }
 
 func setupSeccheck(configFD int, sinkFDs []int) error {
@@ -55,26 +110,100 @@ func LoadInitConfig(path string) (*InitConfig, error) {
 }
 
 func loadInitConfig(reader io.Reader) (*InitConfig, error) {
-	decoder := json.NewDecoder(reader)
-	decoder.DisallowUnknownFields()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
 	init := &InitConfig{}
-	if err := decoder.Decode(init); err != nil {
+	warnings, err := seccheck.DecodeConfig(data, init)
+	if err != nil {
 		return nil, err
 	}
+	for _, warning := range warnings {
+		log.Warningf("Pod init config: %s", warning)
+	}
 	return init, nil
 }
 
 // Setup performs the actions defined in the InitConfig, e.g. setup seccheck
-// session.
-func (c *InitConfig) Setup() ([]*os.File, error) {
-	return seccheck.SetupSinks(c.TraceSession.Sinks)
+// session. Setup may change the InitConfig, use File to pass the result to the
+// sandbox.
+func (c *InitConfig) Setup(sandboxID string) ([]*os.File, error) {
+	// Sink files of all sessions are passed in order, see create.
+	var files []*os.File
+	for _, session := range c.Sessions() {
+		sessionFiles, err := seccheck.SetupSinks(session.Sinks, sandboxID)
+		if err != nil {
+			for _, f := range files {
+				_ = f.Close()
+			}
+			return nil, fmt.Errorf("trace session %q: %w", session.Name, err)
+		}
+		files = append(files, sessionFiles...)
+	}
+	return files, nil
+}
+
+// File returns a file containing the InitConfig in json format, to be loaded
+// by the sandbox.
+func (c *InitConfig) File() (*os.File, error) {
+	memfd, err := unix.MemfdCreate("pod-init-config", unix.MFD_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("memfd_create: %w", err)
+	}
+	f := os.NewFile(uintptr(memfd), "pod-init-config")
+	if err := json.NewEncoder(f).Encode(c); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("writing init config: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
 }
 
 func (c *InitConfig) create(sinkFDs []int) error {
-	for i, sinkFD := range sinkFDs {
-		if sinkFD >= 0 {
-			c.TraceSession.Sinks[i].FD = fd.New(sinkFD)
+	for _, session := range c.Sessions() {
+		if len(sinkFDs) < len(session.Sinks) {
+			return fmt.Errorf("trace session %q: missing sink files, got: %d, want: %d", session.Name, len(sinkFDs), len(session.Sinks))
+		}
+		for i, sinkFD := range sinkFDs[:len(session.Sinks)] {
+			if sinkFD >= 0 {
+				session.Sinks[i].FD = fd.New(sinkFD)
+			}
+		}
+		sinkFDs = sinkFDs[len(session.Sinks):]
+		if err := seccheck.Create(session, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addTraceContainer makes container cid known to trace sessions, with its name
+// from the pod spec, and opts it into the sessions named in the annotations of
+// its spec. Containers are added even if they don't opt into any session, so
+// that sessions can include or exclude them by name.
+func addTraceContainer(cid string, spec *specs.Spec) error {
+	sessions := splitAnnotation(spec.Annotations[annotationTraceSessions])
+	points := splitAnnotation(spec.Annotations[annotationTracePoints])
+	if len(sessions) == 0 && len(points) > 0 {
+		return fmt.Errorf("annotation %q requires %q to be set", annotationTracePoints, annotationTraceSessions)
+	}
+	if err := seccheck.AddContainer(cid, specutils.ContainerName(spec), sessions, points); err != nil {
+		return fmt.Errorf("annotation %q: %w", annotationTracePoints, err)
+	}
+	return nil
+}
+
+// splitAnnotation returns the non-empty comma-separated values in value.
+func splitAnnotation(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			values = append(values, v)
 		}
 	}
-	return seccheck.Create(&c.TraceSession, false)
+	return values
 }
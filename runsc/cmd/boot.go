@@ -106,6 +106,10 @@ type Boot struct {
 
 	sinkFDs intFlags
 
+	// cpuStatFD is the file descriptor to the sandbox cgroup's cpu.stat file.
+	// Valid if >= 0.
+	cpuStatFD int
+
 	// pidns is set if the sandbox is in its own pid namespace.
 	pidns bool
 
@@ -161,6 +165,7 @@ func (b *Boot) SetFlags(f *flag.FlagSet) {
 	f.IntVar(&b.traceFD, "trace-fd", -1, "file descriptor to write Go execution trace to. -1 disables tracing.")
 	f.IntVar(&b.podInitConfigFD, "pod-init-config-fd", -1, "file descriptor to the pod init configuration file.")
 	f.Var(&b.sinkFDs, "sink-fds", "ordered list of file descriptors to be used by the sinks defined in --pod-init-config.")
+	f.IntVar(&b.cpuStatFD, "cpu-stat-fd", -1, "file descriptor to the sandbox cgroup's cpu.stat file, used to report CPU throttling. -1 disables reporting.")
 }
 
 // Execute implements subcommands.Command.Execute.  It starts a sandbox in a
@@ -316,6 +321,7 @@ func (b *Boot) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 		ProductName:     b.productName,
 		PodInitConfigFD: b.podInitConfigFD,
 		SinkFDs:         b.sinkFDs.GetArray(),
+		CPUStatFD:       b.cpuStatFD,
 	}
 	l, err := boot.New(bootArgs)
 	if err != nil {
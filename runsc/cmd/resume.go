@@ -25,7 +25,9 @@ import (
 )
 
 // Resume implements subcommands.Command for the "resume" command.
-type Resume struct{}
+type Resume struct {
+	quarantined bool
+}
 
 // Name implements subcommands.Command.Name.
 func (*Resume) Name() string {
@@ -39,12 +41,13 @@ func (*Resume) Synopsis() string {
 
 // Usage implements subcommands.Command.Usage.
 func (*Resume) Usage() string {
-	return `resume <container id> - resume a paused container.
+	return `resume [flags] <container id> - resume a paused container.
 `
 }
 
 // SetFlags implements subcommands.Command.SetFlags.
-func (r *Resume) SetFlags(*flag.FlagSet) {
+func (r *Resume) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&r.quarantined, "quarantined", false, "resume a container that a trace session paused after a policy violation")
 }
 
 // Execute implements subcommands.Command.Execute.
@@ -62,6 +65,12 @@ func (r *Resume) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}
 		util.Fatalf("loading container: %v", err)
 	}
 
+	if r.quarantined {
+		if err := cont.ResumeQuarantined(); err != nil {
+			util.Fatalf("resume failed: %v", err)
+		}
+		return subcommands.ExitSuccess
+	}
 	if err := cont.Resume(); err != nil {
 		util.Fatalf("resume failed: %v", err)
 	}
@@ -94,31 +94,50 @@ func (l *create) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}
 }
 
 func decodeTraceConfig(path string) (*seccheck.SessionConfig, error) {
-	file, err := os.Open(path)
+	sessionConfig, initConfig, warnings, err := loadConfigFile(path)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	decoder := json.NewDecoder(file)
-	decoder.DisallowUnknownFields()
-	sessionConfig := &seccheck.SessionConfig{}
-	err = decoder.Decode(sessionConfig)
-	if err == nil {
-		// Success, we're done.
+	for _, warning := range warnings {
+		log.Warningf("Trace configuration %q: %s", path, warning)
+	}
+	if sessionConfig != nil {
 		return sessionConfig, nil
 	}
+	// If file is an InitConfig, use its trace session as convenience in case
+	// the caller wants to reuse it.
+	return &initConfig.TraceSession, nil
+}
 
-	// If file cannot be decoded as a SessionConfig, try with InitConfig as
-	// convenience in case the caller wants to reuse a trace session from
-	// InitConfig file.
-	log.Debugf("Config file is not a seccheck.SessionConfig, try with boot.InitConfig instead: %v", err)
-	if _, err := file.Seek(0, 0); err != nil {
-		return nil, err
+// loadConfigFile decodes path, which is either a seccheck.SessionConfig or a
+// boot.InitConfig, and returns the one that was decoded. Warnings are returned
+// for configurations newer than this release, see seccheck.DecodeConfig.
+func loadConfigFile(path string) (*seccheck.SessionConfig, *boot.InitConfig, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, nil, err
 	}
+	// Fields are checked first, since unknown fields may be allowed if the
+	// configuration is newer.
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid configuration file: %w", err)
+	}
+	_, hasSession := fields["trace_session"]
+	_, hasSessions := fields["trace_sessions"]
+	if !hasSession && !hasSessions {
+		sessionConfig := &seccheck.SessionConfig{}
+		warnings, err := seccheck.DecodeConfig(data, sessionConfig)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid configuration file: %w", err)
+		}
+		return sessionConfig, nil, warnings, nil
+	}
+	log.Debugf("Config file is a boot.InitConfig")
 	initConfig := &boot.InitConfig{}
-	if err := decoder.Decode(initConfig); err != nil {
-		return nil, fmt.Errorf("invalid configuration file: %w", err)
+	warnings, err := seccheck.DecodeConfig(data, initConfig)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid configuration file: %w", err)
 	}
-	return &initConfig.TraceSession, nil
+	return nil, initConfig, warnings, nil
 }
@@ -51,6 +51,26 @@ func TestConfigFile(t *testing.T) {
 			json: boot.InitConfig{TraceSession: testCfg},
 			want: testCfg,
 		},
+		{
+			name: "unknown-field",
+			json: map[string]interface{}{"name": "Default", "new_field": true},
+			err:  "unknown field",
+		},
+		{
+			name: "newer-version",
+			json: map[string]interface{}{
+				"version":   seccheck.ConfigVersion + 1,
+				"name":      "Default",
+				"points":    []interface{}{map[string]interface{}{"name": "point-1", "new_field": true}},
+				"sinks":     []interface{}{map[string]interface{}{"name": "sink-1"}},
+				"new_field": true,
+			},
+			want: func() seccheck.SessionConfig {
+				cfg := testCfg
+				cfg.Version = seccheck.ConfigVersion + 1
+				return cfg
+			}(),
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			tmp, err := os.CreateTemp(testutil.TmpDir(), "trace-create")
@@ -17,6 +17,7 @@ package trace
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/subcommands"
 	"gvisor.dev/gvisor/runsc/cmd/util"
@@ -40,7 +41,8 @@ func (*list) Synopsis() string {
 
 // Usage implements subcommands.Command.
 func (*list) Usage() string {
-	return `list - list all trace sessions
+	return `list - list all trace sessions, along with the state of their sinks, e.g.
+whether the remote process is still connected and the last error
 `
 }
 
@@ -74,7 +76,17 @@ func (l *list) Execute(_ context.Context, f *flag.FlagSet, args ...interface{})
 	for _, session := range sessions {
 		fmt.Printf("%q\n", session.Name)
 		for _, sink := range session.Sinks {
-			fmt.Printf("\tSink: %q, dropped: %d\n", sink.Name, sink.Status.DroppedCount)
+			fmt.Printf("\tSink: %q, dropped: %d", sink.Name, sink.Status.DroppedCount)
+			if sink.Status.State != "" {
+				fmt.Printf(", state: %s, queued: %d", sink.Status.State, sink.Status.QueueDepth)
+			}
+			if sink.Status.PointCount > 0 || sink.Status.ByteCount > 0 {
+				fmt.Printf(", points: %d, bytes: %d", sink.Status.PointCount, sink.Status.ByteCount)
+			}
+			if sink.Status.LastError != "" {
+				fmt.Printf(", last error: %q at %s", sink.Status.LastError, time.Unix(0, sink.Status.LastErrorTimeNs).Format(time.RFC3339))
+			}
+			fmt.Println()
 		}
 	}
 	return subcommands.ExitSuccess
@@ -35,12 +35,12 @@ func (*metadata) Name() string {
 
 // Synopsis implements subcommands.Command.
 func (*metadata) Synopsis() string {
-	return "list all trace points configuration information"
+	return "list all trace points, presets, and sinks configuration information"
 }
 
 // Usage implements subcommands.Command.
 func (*metadata) Usage() string {
-	return `metadata - list all trace points configuration information
+	return `metadata - list all trace points, presets, and sinks configuration information
 `
 }
 
@@ -64,6 +64,21 @@ func (l *metadata) Execute(context.Context, *flag.FlagSet, ...interface{}) subco
 		ctxFields := fieldNames(pt.ContextFields)
 		fmt.Printf("Name: %s, optional fields: [%s], context fields: [%s]\n", pt.Name, strings.Join(optFields, "|"), strings.Join(ctxFields, "|"))
 	}
+
+	fmt.Printf("\nPRESETS (%d)\n", len(seccheck.Presets))
+	for _, name := range seccheck.PresetNames() {
+		var names []string
+		for _, pt := range seccheck.Presets[name] {
+			names = append(names, pt.Name)
+		}
+		fmt.Printf("Name: %s, points: [%s]\n", name, strings.Join(names, "|"))
+	}
+
+	sinks := seccheck.SinkNames()
+	fmt.Printf("\nSINKS (%d)\n", len(sinks))
+	for _, name := range sinks {
+		fmt.Printf("Name: %s\n", name)
+	}
 	return subcommands.ExitSuccess
 }
 
@@ -0,0 +1,181 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/container"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// metrics implements subcommands.Command for the "metrics" command.
+type metrics struct {
+	listen string
+}
+
+// Name implements subcommands.Command.
+func (*metrics) Name() string {
+	return "metrics"
+}
+
+// Synopsis implements subcommands.Command.
+func (*metrics) Synopsis() string {
+	return "export trace session metrics in Prometheus format"
+}
+
+// Usage implements subcommands.Command.
+func (*metrics) Usage() string {
+	return `metrics [flags] <sandbox id> - export trace session metrics
+
+Prints the number of points dropped by every sink, and the metrics reported by
+"metrics" sinks, in the Prometheus text format. With --listen, metrics are
+served over HTTP at /metrics instead, and refreshed on every scrape.
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (m *metrics) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&m.listen, "listen", "", "address to serve metrics on, e.g. localhost:9090. Metrics are printed once if not set")
+}
+
+// Execute implements subcommands.Command.
+func (m *metrics) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 1 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+
+	id := f.Arg(0)
+	conf := args[0].(*config.Config)
+
+	opts := container.LoadOpts{
+		SkipCheck:     true,
+		RootContainer: true,
+	}
+	c, err := container.Load(conf.RootDir, container.FullID{ContainerID: id}, opts)
+	if err != nil {
+		util.Fatalf("loading sandbox: %v", err)
+	}
+
+	if len(m.listen) == 0 {
+		sessions, err := c.Sandbox.ListTraceSessions()
+		if err != nil {
+			util.Fatalf("listing sessions: %v", err)
+		}
+		writeMetrics(os.Stdout, sessions)
+		return subcommands.ExitSuccess
+	}
+
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		sessions, err := c.Sandbox.ListTraceSessions()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("listing sessions: %v", err), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, sessions)
+	})
+	log.Infof("Serving trace metrics for sandbox %q on %q", id, m.listen)
+	if err := http.ListenAndServe(m.listen, nil); err != nil {
+		util.Fatalf("serving metrics: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
+
+// writeMetrics writes the metrics of all sinks in sessions to w, in the
+// Prometheus text format. Every sample is labeled with the session and sink
+// that reported it.
+func writeMetrics(w io.Writer, sessions []seccheck.SessionConfig) {
+	const droppedFamily = "runsc_trace_dropped_points_total"
+	families := []string{droppedFamily}
+	typ := map[string]string{droppedFamily: "counter"}
+	samples := map[string]*bytes.Buffer{droppedFamily: &bytes.Buffer{}}
+
+	for _, session := range sessions {
+		for _, sink := range session.Sinks {
+			writeSample(samples[droppedFamily], droppedFamily, sinkLabels(session, sink), sink.Status.DroppedCount)
+			for _, s := range append(healthMetrics(sink.Status), sink.Status.Metrics...) {
+				buf, ok := samples[s.Family]
+				if !ok {
+					buf = &bytes.Buffer{}
+					samples[s.Family] = buf
+					typ[s.Family] = s.Type
+					families = append(families, s.Family)
+				}
+				labels := sinkLabels(session, sink)
+				for k, v := range s.Labels {
+					labels[k] = v
+				}
+				writeSample(buf, s.Name, labels, s.Value)
+			}
+		}
+	}
+
+	// Samples of a family must be grouped together.
+	for _, family := range families {
+		fmt.Fprintf(w, "# TYPE %s %s\n", family, typ[family])
+		_, _ = samples[family].WriteTo(w)
+	}
+}
+
+// healthMetrics returns the connection state and queue depth of sinks that
+// report them, as metrics.
+func healthMetrics(status seccheck.CheckerStatus) []seccheck.MetricSample {
+	if status.State == "" {
+		return nil
+	}
+	connected := uint64(0)
+	if status.State == seccheck.SinkConnected {
+		connected = 1
+	}
+	return []seccheck.MetricSample{
+		{Family: "runsc_trace_sink_connected", Type: "gauge", Name: "runsc_trace_sink_connected", Value: connected},
+		{Family: "runsc_trace_queued_points", Type: "gauge", Name: "runsc_trace_queued_points", Value: status.QueueDepth},
+	}
+}
+
+func sinkLabels(session seccheck.SessionConfig, sink seccheck.SinkConfig) map[string]string {
+	return map[string]string{"session": session.Name, "sink": sink.Name}
+}
+
+// labelEscaper escapes label values in the Prometheus text format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeSample writes a single sample, with labels sorted by name.
+func writeSample(w io.Writer, name string, labels map[string]string, value uint64) {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, k := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, k, labelEscaper.Replace(labels[k])))
+	}
+	fmt.Fprintf(w, "%s{%s} %d\n", name, strings.Join(pairs, ","), value)
+}
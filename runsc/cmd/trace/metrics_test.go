@@ -0,0 +1,68 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"bytes"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	sessions := []seccheck.SessionConfig{
+		{
+			Name: "Default",
+			Sinks: []seccheck.SinkConfig{
+				{
+					Name: "remote",
+					Status: seccheck.CheckerStatus{
+						DroppedCount: 3,
+						State:        seccheck.SinkDisconnected,
+						QueueDepth:   5,
+					},
+				},
+				{
+					Name: "metrics",
+					Status: seccheck.CheckerStatus{
+						Metrics: []seccheck.MetricSample{
+							{Family: "points", Type: "counter", Name: "points", Labels: map[string]string{"point": "a"}, Value: 1},
+							{Family: "size", Type: "histogram", Name: "size_bucket", Labels: map[string]string{"le": "+Inf"}, Value: 2},
+							{Family: "points", Type: "counter", Name: "points", Labels: map[string]string{"point": `"b"`}, Value: 2},
+						},
+					},
+				},
+			},
+		},
+	}
+	var got bytes.Buffer
+	writeMetrics(&got, sessions)
+	want := `# TYPE runsc_trace_dropped_points_total counter
+runsc_trace_dropped_points_total{session="Default",sink="remote"} 3
+runsc_trace_dropped_points_total{session="Default",sink="metrics"} 0
+# TYPE runsc_trace_sink_connected gauge
+runsc_trace_sink_connected{session="Default",sink="remote"} 0
+# TYPE runsc_trace_queued_points gauge
+runsc_trace_queued_points{session="Default",sink="remote"} 5
+# TYPE points counter
+points{point="a",session="Default",sink="metrics"} 1
+points{point="\"b\"",session="Default",sink="metrics"} 2
+# TYPE size histogram
+size_bucket{le="+Inf",session="Default",sink="metrics"} 2
+`
+	if got.String() != want {
+		t.Errorf("writeMetrics():\n%s\nwant:\n%s", got.String(), want)
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/google/subcommands"
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/runsc/boot"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/container"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// reload implements subcommands.Command for the "reload" command.
+type reload struct {
+	config   string
+	watch    bool
+	interval time.Duration
+}
+
+// Name implements subcommands.Command.
+func (*reload) Name() string {
+	return "reload"
+}
+
+// Synopsis implements subcommands.Command.
+func (*reload) Synopsis() string {
+	return "apply the trace configuration file to a running sandbox"
+}
+
+// Usage implements subcommands.Command.
+func (*reload) Usage() string {
+	return `reload [flags] <sandbox id> - apply the trace configuration file to a running sandbox
+
+All sessions in the file, which defaults to --pod-init-config, are created
+again, replacing existing sessions with the same name.
+
+With --watch, the command keeps running and applies the file again when it
+changes or when the command receives SIGHUP. Sessions are updated in place, so
+their sinks don't miss points, if their sinks didn't change or only changed
+configuration that the sinks can reload, e.g. the rules of the policy sink.
+Other sessions are created again, and sessions removed from the file are
+deleted. Sinks of the sessions in the file whose remote process went away are
+set up again, with exponential backoff between attempts, and their new
+connection is handed to the sandbox. Points are queued by the sinks meanwhile.
+The command exits when the sandbox stops.
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (l *reload) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&l.config, "config", "", "path to the trace configuration file, in the --pod-init-config format. Defaults to --pod-init-config")
+	f.BoolVar(&l.watch, "watch", false, "keep running and apply the file again when it changes or on SIGHUP")
+	f.DurationVar(&l.interval, "interval", 5*time.Second, "interval between checks for changes to the file with --watch")
+}
+
+// Execute implements subcommands.Command.
+func (l *reload) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 1 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	id := f.Arg(0)
+	conf := args[0].(*config.Config)
+
+	path := l.config
+	if len(path) == 0 {
+		path = conf.PodInitConfig
+	}
+	if len(path) == 0 {
+		f.Usage()
+		return util.Errorf("missing path to configuration file, please set --config=[path] or --pod-init-config=[path]")
+	}
+	if l.interval <= 0 {
+		return util.Errorf("--interval must be positive, got: %v", l.interval)
+	}
+
+	opts := container.LoadOpts{
+		SkipCheck:     true,
+		RootContainer: true,
+	}
+	c, err := container.Load(conf.RootDir, container.FullID{ContainerID: id}, opts)
+	if err != nil {
+		util.Fatalf("loading sandbox: %v", err)
+	}
+
+	r := newReloader(c.Sandbox)
+	if !l.watch {
+		if err := r.reload(path); err != nil {
+			util.Fatalf("reloading trace configuration: %v", err)
+		}
+		return subcommands.ExitSuccess
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, unix.SIGHUP)
+	defer signal.Stop(sighup)
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	var lastMod time.Time
+	for {
+		if fi, err := os.Stat(path); err != nil {
+			log.Warningf("Checking trace configuration %q: %v", path, err)
+		} else if !fi.ModTime().Equal(lastMod) {
+			lastMod = fi.ModTime()
+			if err := r.reload(path); err != nil {
+				log.Warningf("Reloading trace configuration %q: %v", path, err)
+			}
+		}
+
+		select {
+		case <-sighup:
+			log.Infof("SIGHUP received, reloading trace configuration %q", path)
+			// Force the file to be applied again, even if it didn't change.
+			lastMod = time.Time{}
+		case <-ticker.C:
+		}
+		if !c.Sandbox.IsRunning() {
+			log.Infof("Sandbox %q stopped", id)
+			return subcommands.ExitSuccess
+		}
+		if err := r.reconnect(time.Now()); err != nil {
+			log.Warningf("Reconnecting trace sinks: %v", err)
+		}
+	}
+}
+
+// traceSessionManager manages the trace sessions of a sandbox. It's
+// implemented by *sandbox.Sandbox.
+type traceSessionManager interface {
+	CreateTraceSession(config *seccheck.SessionConfig, force bool) error
+	UpdateTraceSession(config *seccheck.SessionConfig) error
+	DeleteTraceSession(name string) error
+	ListTraceSessions() ([]seccheck.SessionConfig, error)
+	ReconnectTraceSink(name string, index int, sink seccheck.SinkConfig) error
+}
+
+const (
+	// minReconnectBackoff is how long the reloader waits before reconnecting
+	// a sink again after the first failed attempt. It doubles after every
+	// failed attempt, up to maxReconnectBackoff.
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = time.Minute
+)
+
+// sinkKey identifies a sink by the name of its session and its index in the
+// configuration of the session.
+type sinkKey struct {
+	session string
+	index   int
+}
+
+// reconnectBackoff is when a disconnected sink is reconnected next, and how
+// long to wait after that if it fails.
+type reconnectBackoff struct {
+	next  time.Time
+	delay time.Duration
+}
+
+// reloader applies trace configuration files to a sandbox.
+type reloader struct {
+	mgr traceSessionManager
+
+	// sinks maps the name of each session applied by the reloader to the
+	// JSON encoding of its sinks, as they were in the configuration file.
+	sinks map[string]string
+
+	// backoffs holds the sinks that are disconnected, see reconnect.
+	backoffs map[sinkKey]*reconnectBackoff
+}
+
+func newReloader(mgr traceSessionManager) *reloader {
+	return &reloader{
+		mgr:      mgr,
+		sinks:    make(map[string]string),
+		backoffs: make(map[sinkKey]*reconnectBackoff),
+	}
+}
+
+// reload loads the configuration file at path and applies it.
+func (r *reloader) reload(path string) error {
+	initConf, err := boot.LoadInitConfig(path)
+	if err != nil {
+		return fmt.Errorf("loading config file: %w", err)
+	}
+	return r.apply(initConf.Sessions())
+}
+
+// apply makes the sessions of the sandbox match sessions. It attempts to apply
+// all sessions, even if some fail, and returns the last error.
+func (r *reloader) apply(sessions []*seccheck.SessionConfig) error {
+	var lastErr error
+	names := make(map[string]struct{}, len(sessions))
+	// applied replaces r.sinks with the sessions that exist after this call.
+	applied := make(map[string]string, len(sessions))
+	for _, session := range sessions {
+		names[session.Name] = struct{}{}
+
+		// Sinks are encoded before they are created, since setup may change
+		// their configuration.
+		encoded, err := json.Marshal(session.Sinks)
+		if err != nil {
+			return err
+		}
+		sinks := string(encoded)
+		if prev, ok := r.sinks[session.Name]; ok {
+			// Sinks that changed are reloaded in place if they support it,
+			// e.g. to swap the rules of a policy sink atomically.
+			update := *session
+			if prev == sinks {
+				update.Sinks = nil
+			}
+			err := r.mgr.UpdateTraceSession(&update)
+			if err == nil {
+				log.Infof("Trace session %q updated", session.Name)
+				applied[session.Name] = sinks
+				continue
+			}
+			log.Warningf("Updating trace session %q, creating it again instead: %v", session.Name, err)
+		}
+		if err := r.mgr.CreateTraceSession(session, true /* force */); err != nil {
+			lastErr = fmt.Errorf("creating session %q: %w", session.Name, err)
+			log.Warningf("%v", lastErr)
+			continue
+		}
+		log.Infof("Trace session %q created", session.Name)
+		applied[session.Name] = sinks
+	}
+
+	for name := range r.sinks {
+		if _, ok := names[name]; ok {
+			continue
+		}
+		if err := r.mgr.DeleteTraceSession(name); err != nil {
+			lastErr = fmt.Errorf("deleting session %q: %w", name, err)
+			log.Warningf("%v", lastErr)
+			continue
+		}
+		log.Infof("Trace session %q deleted", name)
+	}
+	r.sinks = applied
+	return lastErr
+}
+
+// reconnect sets up again the sinks of the sessions applied by the reloader
+// whose remote process went away, and hands their new connection to the
+// sandbox. A sink is reconnected as soon as it's found disconnected, and then
+// with exponential backoff between failed attempts.
+func (r *reloader) reconnect(now time.Time) error {
+	sessions, err := r.mgr.ListTraceSessions()
+	if err != nil {
+		return err
+	}
+	disconnected := make(map[sinkKey]struct{})
+	for _, session := range sessions {
+		encoded, ok := r.sinks[session.Name]
+		if !ok {
+			continue
+		}
+		for _, sink := range session.Sinks {
+			if sink.Status.State != seccheck.SinkDisconnected {
+				continue
+			}
+			key := sinkKey{session: session.Name, index: sink.Index}
+			disconnected[key] = struct{}{}
+			b, ok := r.backoffs[key]
+			if !ok {
+				b = &reconnectBackoff{next: now, delay: minReconnectBackoff}
+				r.backoffs[key] = b
+			}
+			if now.Before(b.next) {
+				continue
+			}
+			// Sinks are decoded for every attempt, since setup may change
+			// their configuration.
+			var configs []seccheck.SinkConfig
+			if err := json.Unmarshal([]byte(encoded), &configs); err != nil {
+				return err
+			}
+			if sink.Index >= len(configs) {
+				continue
+			}
+			if err := r.mgr.ReconnectTraceSink(session.Name, sink.Index, configs[sink.Index]); err != nil {
+				log.Warningf("Reconnecting sink %q of trace session %q, retrying in %v: %v", sink.Name, session.Name, b.delay, err)
+				b.next = now.Add(b.delay)
+				b.delay *= 2
+				if b.delay > maxReconnectBackoff {
+					b.delay = maxReconnectBackoff
+				}
+				continue
+			}
+			log.Infof("Sink %q of trace session %q reconnected", sink.Name, session.Name)
+		}
+	}
+	// Sinks that are connected again start over with the minimum backoff the
+	// next time they are disconnected.
+	backoffs := make(map[sinkKey]*reconnectBackoff, len(disconnected))
+	for key := range disconnected {
+		backoffs[key] = r.backoffs[key]
+	}
+	r.backoffs = backoffs
+	return nil
+}
@@ -0,0 +1,213 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+)
+
+// fakeManager records the calls made to it.
+type fakeManager struct {
+	calls    []string
+	fail     map[string]bool
+	sessions []seccheck.SessionConfig
+}
+
+func (m *fakeManager) call(name string) error {
+	m.calls = append(m.calls, name)
+	if m.fail[name] {
+		return fmt.Errorf("%s failed", name)
+	}
+	return nil
+}
+
+func (m *fakeManager) CreateTraceSession(config *seccheck.SessionConfig, force bool) error {
+	if !force {
+		return fmt.Errorf("create without force")
+	}
+	return m.call("create " + config.Name)
+}
+
+func (m *fakeManager) UpdateTraceSession(config *seccheck.SessionConfig) error {
+	if len(config.Sinks) > 0 {
+		return m.call("reload " + config.Name)
+	}
+	return m.call("update " + config.Name)
+}
+
+func (m *fakeManager) DeleteTraceSession(name string) error {
+	return m.call("delete " + name)
+}
+
+func (m *fakeManager) ListTraceSessions() ([]seccheck.SessionConfig, error) {
+	return m.sessions, nil
+}
+
+func (m *fakeManager) ReconnectTraceSink(name string, index int, sink seccheck.SinkConfig) error {
+	return m.call(fmt.Sprintf("reconnect %s/%d %s", name, index, sink.Name))
+}
+
+func TestReload(t *testing.T) {
+	session := func(name, sink string) *seccheck.SessionConfig {
+		return &seccheck.SessionConfig{
+			Name:   name,
+			Points: []seccheck.PointConfig{{Name: "syscall/openat/enter"}},
+			Sinks:  []seccheck.SinkConfig{{Name: sink}},
+		}
+	}
+	mgr := &fakeManager{fail: make(map[string]bool)}
+	r := newReloader(mgr)
+
+	for _, tc := range []struct {
+		name     string
+		sessions []*seccheck.SessionConfig
+		fail     string
+		want     []string
+		err      bool
+	}{
+		{
+			name:     "first",
+			sessions: []*seccheck.SessionConfig{session("a", "remote"), session("b", "null")},
+			want:     []string{"create a", "create b"},
+		},
+		{
+			name:     "unchanged-sinks",
+			sessions: []*seccheck.SessionConfig{session("a", "remote"), session("b", "null")},
+			want:     []string{"update a", "update b"},
+		},
+		{
+			name:     "changed-sinks",
+			sessions: []*seccheck.SessionConfig{session("a", "remote"), session("b", "remote")},
+			want:     []string{"update a", "reload b"},
+		},
+		{
+			name:     "reload-failure",
+			sessions: []*seccheck.SessionConfig{session("a", "null"), session("b", "remote")},
+			fail:     "reload a",
+			want:     []string{"reload a", "create a", "update b"},
+		},
+		{
+			name:     "removed",
+			sessions: []*seccheck.SessionConfig{session("b", "remote")},
+			want:     []string{"update b", "delete a"},
+		},
+		{
+			name:     "update-failure",
+			sessions: []*seccheck.SessionConfig{session("b", "remote")},
+			fail:     "update b",
+			want:     []string{"update b", "create b"},
+		},
+		{
+			name:     "create-failure",
+			sessions: []*seccheck.SessionConfig{session("c", "null")},
+			fail:     "create c",
+			want:     []string{"create c", "delete b"},
+			err:      true,
+		},
+		{
+			name:     "retry",
+			sessions: []*seccheck.SessionConfig{session("c", "null")},
+			want:     []string{"create c"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mgr.calls = nil
+			mgr.fail = map[string]bool{tc.fail: true}
+			err := r.apply(tc.sessions)
+			if tc.err != (err != nil) {
+				t.Errorf("apply(): want error: %t, got: %v", tc.err, err)
+			}
+			if !reflect.DeepEqual(tc.want, mgr.calls) {
+				t.Errorf("wrong calls, want: %q, got: %q", tc.want, mgr.calls)
+			}
+		})
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	mgr := &fakeManager{}
+	r := newReloader(mgr)
+	if err := r.apply([]*seccheck.SessionConfig{{
+		Name:   "a",
+		Points: []seccheck.PointConfig{{Name: "syscall/openat/enter"}},
+		Sinks:  []seccheck.SinkConfig{{Name: "null"}, {Name: "remote"}},
+	}}); err != nil {
+		t.Fatalf("apply(): %v", err)
+	}
+	// The second sink of the session is disconnected. Sessions that weren't
+	// applied by the reloader are ignored.
+	list := func(state string) {
+		mgr.sessions = []seccheck.SessionConfig{
+			{
+				Name: "a",
+				Sinks: []seccheck.SinkConfig{
+					{Name: "null"},
+					{Name: "remote", Index: 1, Status: seccheck.CheckerStatus{State: state}},
+				},
+			},
+			{
+				Name:  "other",
+				Sinks: []seccheck.SinkConfig{{Name: "remote", Status: seccheck.CheckerStatus{State: seccheck.SinkDisconnected}}},
+			},
+		}
+	}
+	list(seccheck.SinkDisconnected)
+	mgr.fail = map[string]bool{"reconnect a/1 remote": true}
+
+	start := time.Now()
+	for _, tc := range []struct {
+		name  string
+		after time.Duration
+		want  []string
+	}{
+		{name: "first", after: 0, want: []string{"reconnect a/1 remote"}},
+		{name: "backoff", after: minReconnectBackoff / 2, want: nil},
+		{name: "second", after: minReconnectBackoff, want: []string{"reconnect a/1 remote"}},
+		{name: "doubled-backoff", after: 2 * minReconnectBackoff, want: nil},
+		{name: "third", after: 3 * minReconnectBackoff, want: []string{"reconnect a/1 remote"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mgr.calls = nil
+			if err := r.reconnect(start.Add(tc.after)); err != nil {
+				t.Fatalf("reconnect(): %v", err)
+			}
+			if !reflect.DeepEqual(tc.want, mgr.calls) {
+				t.Errorf("wrong calls, want: %q, got: %q", tc.want, mgr.calls)
+			}
+		})
+	}
+
+	// Once the sink is connected again, the backoff starts over.
+	list(seccheck.SinkConnected)
+	if err := r.reconnect(start.Add(4 * minReconnectBackoff)); err != nil {
+		t.Fatalf("reconnect(): %v", err)
+	}
+	if len(r.backoffs) != 0 {
+		t.Errorf("backoff not reset: %+v", r.backoffs)
+	}
+	list(seccheck.SinkDisconnected)
+	mgr.calls = nil
+	if err := r.reconnect(start.Add(5 * minReconnectBackoff)); err != nil {
+		t.Fatalf("reconnect(): %v", err)
+	}
+	if want := []string{"reconnect a/1 remote"}; !reflect.DeepEqual(want, mgr.calls) {
+		t.Errorf("wrong calls, want: %q, got: %q", want, mgr.calls)
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/subcommands"
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/consumer"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/container"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// defaultTailPreset is the preset used when no points are given to tail.
+const defaultTailPreset = "security-essentials"
+
+// tailContextFields are the context fields requested for every point, used to
+// identify where each event comes from.
+var tailContextFields = []string{"thread_id", "container_id", "process_name"}
+
+// tail implements subcommands.Command for the "tail" command.
+type tail struct {
+	name    string
+	config  string
+	points  string
+	presets string
+}
+
+// Name implements subcommands.Command.
+func (*tail) Name() string {
+	return "tail"
+}
+
+// Synopsis implements subcommands.Command.
+func (*tail) Synopsis() string {
+	return "prints events of a sandbox as they happen"
+}
+
+// Usage implements subcommands.Command.
+func (*tail) Usage() string {
+	return `tail [flags] <sandbox id> - prints events of a sandbox as they happen
+
+Creates a temporary trace session on the sandbox and prints its events, one
+per line, until interrupted. The session is deleted on exit. Points are taken
+from --points, --presets, and --config, and default to the "` + defaultTailPreset + `"
+preset.
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (l *tail) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&l.name, "name", "", "name of the temporary session, defaults to tail-<pid>")
+	f.StringVar(&l.config, "config", "", "path to a JSON file describing the session whose points are printed. Sinks are ignored")
+	f.StringVar(&l.points, "points", "", "comma-separated list of points to enable with all their optional fields, e.g. syscall/openat/exit")
+	f.StringVar(&l.presets, "presets", "", "comma-separated list of presets to enable, e.g. "+defaultTailPreset)
+}
+
+// Execute implements subcommands.Command.
+func (l *tail) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 1 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+
+	sessionConfig, err := l.sessionConfig()
+	if err != nil {
+		return util.Errorf("%v", err)
+	}
+
+	id := f.Arg(0)
+	conf := args[0].(*config.Config)
+
+	opts := container.LoadOpts{
+		SkipCheck:     true,
+		RootContainer: true,
+	}
+	c, err := container.Load(conf.RootDir, container.FullID{ContainerID: id}, opts)
+	if err != nil {
+		util.Fatalf("loading sandbox: %v", err)
+	}
+
+	dir, err := os.MkdirTemp("", "runsc-trace-tail-")
+	if err != nil {
+		util.Fatalf("creating socket directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	endpoint := filepath.Join(dir, "tail.sock")
+	p := &eventPrinter{out: os.Stdout}
+	srv := consumer.New(endpoint)
+	srv.HandleAll(p.print)
+	if err := srv.Start(); err != nil {
+		util.Fatalf("starting server: %v", err)
+	}
+	defer srv.Close()
+
+	sessionConfig.Sinks = []seccheck.SinkConfig{
+		{
+			Name:   "remote",
+			Config: map[string]interface{}{"endpoint": endpoint},
+		},
+	}
+	if err := c.Sandbox.CreateTraceSession(sessionConfig, false); err != nil {
+		util.Fatalf("creating session: %v", err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, unix.SIGINT, unix.SIGTERM)
+	<-signals
+	signal.Stop(signals)
+
+	if err := c.Sandbox.DeleteTraceSession(sessionConfig.Name); err != nil {
+		log.Warningf("Deleting session %q: %v", sessionConfig.Name, err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// sessionConfig returns the configuration of the temporary session, without
+// sinks.
+func (l *tail) sessionConfig() (*seccheck.SessionConfig, error) {
+	sessionConfig := &seccheck.SessionConfig{}
+	if len(l.config) > 0 {
+		var err error
+		sessionConfig, err = decodeTraceConfig(l.config)
+		if err != nil {
+			return nil, fmt.Errorf("loading config file: %w", err)
+		}
+	}
+	for _, name := range splitList(l.points) {
+		desc, ok := seccheck.Points[name]
+		if !ok {
+			return nil, fmt.Errorf("point %q not found", name)
+		}
+		pt := seccheck.PointConfig{Name: name}
+		for _, field := range desc.OptionalFields {
+			pt.OptionalFields = append(pt.OptionalFields, field.Name)
+		}
+		sessionConfig.Points = append(sessionConfig.Points, pt)
+	}
+	sessionConfig.Presets = append(sessionConfig.Presets, splitList(l.presets)...)
+	if len(sessionConfig.Points) == 0 && len(sessionConfig.Presets) == 0 {
+		sessionConfig.Presets = []string{defaultTailPreset}
+	}
+	// Presets have their own context fields, which are printed if collected.
+	for i := range sessionConfig.Points {
+		pt := &sessionConfig.Points[i]
+		for _, field := range tailContextFields {
+			if !contains(pt.ContextFields, field) {
+				pt.ContextFields = append(pt.ContextFields, field)
+			}
+		}
+	}
+
+	sessionConfig.Name = l.name
+	if len(sessionConfig.Name) == 0 {
+		sessionConfig.Name = fmt.Sprintf("tail-%d", os.Getpid())
+	}
+	return sessionConfig, nil
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// eventPrinter prints events in a format similar to strace.
+type eventPrinter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// print writes e to p.out. It's called concurrently for events of different
+// connections.
+func (p *eventPrinter) print(e *consumer.Event) error {
+	line := formatEvent(e)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := fmt.Fprintln(p.out, line)
+	return err
+}
+
+// formatEvent formats e in a single line with the time, the container, the
+// thread and process that generated it, followed by the point name, its
+// fields, and the result for syscall exit points, e.g.:
+//
+//	12:00:00.000001 abc [12 cat] openat(fd=AT_FDCWD, pathname="/etc/hosts", flags=0x80000, mode=0) = 3
+func formatEvent(e *consumer.Event) string {
+	var b strings.Builder
+	if e.Header.TimeNs != 0 {
+		b.WriteString(time.Unix(0, e.Header.TimeNs).Format("15:04:05.000000"))
+	} else {
+		b.WriteString("-")
+	}
+
+	msg := e.Msg.ProtoReflect()
+	fields := msg.Descriptor().Fields()
+	if fd := fields.ByName("context_data"); fd != nil && msg.Has(fd) {
+		if ctx, ok := msg.Get(fd).Message().Interface().(*pb.ContextData); ok {
+			if len(ctx.ContainerId) > 0 {
+				fmt.Fprintf(&b, " %s", ctx.ContainerId)
+			}
+			if ctx.ThreadId != 0 || len(ctx.ProcessName) > 0 {
+				fmt.Fprintf(&b, " [%d %s]", ctx.ThreadId, ctx.ProcessName)
+			}
+		}
+	}
+
+	name := strings.ToLower(strings.TrimPrefix(e.Type.String(), "MESSAGE_"))
+	syscall := strings.HasPrefix(name, "syscall_") && e.Type != pb.MessageType_MESSAGE_SYSCALL_RAW
+	if syscall {
+		name = strings.TrimPrefix(name, "syscall_")
+	}
+	fmt.Fprintf(&b, " %s(", name)
+	first := true
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		switch fd.Name() {
+		case "context_data", "exit":
+			continue
+		case "sysno":
+			if syscall {
+				continue
+			}
+		}
+		if (fd.IsList() || fd.Kind() == protoreflect.MessageKind) && !msg.Has(fd) {
+			continue
+		}
+		if !first {
+			b.WriteString(", ")
+		}
+		first = false
+		fmt.Fprintf(&b, "%s=%s", fd.Name(), formatValue(fd, msg.Get(fd)))
+	}
+	b.WriteString(")")
+
+	if fd := fields.ByName("exit"); fd != nil && msg.Has(fd) {
+		if exit, ok := msg.Get(fd).Message().Interface().(*pb.Exit); ok {
+			if exit.Errorno != 0 {
+				errno := unix.Errno(exit.Errorno)
+				fmt.Fprintf(&b, " = -1 %s (%v)", unix.ErrnoName(errno), errno)
+			} else {
+				fmt.Fprintf(&b, " = %d", exit.Result)
+			}
+		}
+	}
+	return b.String()
+}
+
+// formatValue formats the value v of field fd.
+func formatValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	if fd.IsList() {
+		list := v.List()
+		items := make([]string, 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			items = append(items, formatScalar(fd, list.Get(i)))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	if fd.IsMap() {
+		var items []string
+		v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			items = append(items, fmt.Sprintf("%s: %s", formatScalar(fd.MapKey(), k.Value()), formatScalar(fd.MapValue(), v)))
+			return true
+		})
+		sort.Strings(items)
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return formatScalar(fd, v)
+}
+
+// formatScalar formats a single value of field fd.
+func formatScalar(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return fmt.Sprintf("%q", v.String())
+	case protoreflect.BytesKind:
+		return fmt.Sprintf("%q", v.Bytes())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return fmt.Sprint(v.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		data, err := protojson.Marshal(v.Message().Interface())
+		if err != nil {
+			return "?"
+		}
+		return string(data)
+	}
+	switch fd.Name() {
+	case "fd", "dirfd", "olddirfd", "newdirfd":
+		if (fd.Kind() == protoreflect.Int32Kind || fd.Kind() == protoreflect.Int64Kind) && v.Int() == unix.AT_FDCWD {
+			return "AT_FDCWD"
+		}
+	case "flags":
+		return fmt.Sprintf("%#x", v.Interface())
+	case "mode":
+		return fmt.Sprintf("%#o", v.Interface())
+	}
+	return fmt.Sprint(v.Interface())
+}
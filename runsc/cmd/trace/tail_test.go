@@ -0,0 +1,147 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/consumer"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestFormatEvent(t *testing.T) {
+	ctx := &pb.ContextData{
+		ThreadId:    12,
+		ContainerId: "abc",
+		ProcessName: "cat",
+	}
+	for _, tc := range []struct {
+		name  string
+		event consumer.Event
+		want  string
+	}{
+		{
+			name: "syscall",
+			event: consumer.Event{
+				Type: pb.MessageType_MESSAGE_SYSCALL_OPEN,
+				Msg: &pb.Open{
+					ContextData: ctx,
+					Exit:        &pb.Exit{Result: 3},
+					Sysno:       257,
+					Fd:          unix.AT_FDCWD,
+					Pathname:    "/etc/hosts",
+					Flags:       unix.O_CLOEXEC,
+				},
+			},
+			want: `- abc [12 cat] open(fd=AT_FDCWD, fd_path="", pathname="/etc/hosts", flags=0x80000, mode=0) = 3`,
+		},
+		{
+			name: "errno",
+			event: consumer.Event{
+				Type: pb.MessageType_MESSAGE_SYSCALL_CLOSE,
+				Msg: &pb.Close{
+					ContextData: ctx,
+					Exit:        &pb.Exit{Result: -1, Errorno: int64(unix.EBADF)},
+					Fd:          100,
+				},
+			},
+			want: `- abc [12 cat] close(fd=100, fd_path="") = -1 EBADF (bad file descriptor)`,
+		},
+		{
+			name: "enter",
+			event: consumer.Event{
+				Type: pb.MessageType_MESSAGE_SYSCALL_CLOSE,
+				Msg:  &pb.Close{Fd: 1},
+			},
+			want: `- close(fd=1, fd_path="")`,
+		},
+		{
+			name: "raw",
+			event: consumer.Event{
+				Type: pb.MessageType_MESSAGE_SYSCALL_RAW,
+				Msg:  &pb.Syscall{Sysno: 39},
+			},
+			want: `- syscall_raw(sysno=39, arg1=0, arg2=0, arg3=0, arg4=0, arg5=0, arg6=0)`,
+		},
+		{
+			name: "time",
+			event: consumer.Event{
+				Header: wire.Header{TimeNs: 1},
+				Type:   pb.MessageType_MESSAGE_CONTAINER_START,
+				Msg:    &pb.Start{Id: "abc"},
+			},
+			want: ` container_start(id="abc", cwd="")`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Time is formatted in the local time zone, only check the rest.
+			got := formatEvent(&tc.event)
+			if !strings.HasSuffix(got, tc.want) {
+				t.Errorf("formatEvent() = %q, want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTailSessionConfig(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		cfg, err := (&tail{name: "foo"}).sessionConfig()
+		if err != nil {
+			t.Fatalf("sessionConfig(): %v", err)
+		}
+		if cfg.Name != "foo" {
+			t.Errorf("wrong name, want: foo, got: %q", cfg.Name)
+		}
+		if len(cfg.Presets) != 1 || cfg.Presets[0] != defaultTailPreset {
+			t.Errorf("wrong presets, want: [%s], got: %v", defaultTailPreset, cfg.Presets)
+		}
+	})
+
+	t.Run("points", func(t *testing.T) {
+		const name = "sentry/execve"
+		cfg, err := (&tail{points: name}).sessionConfig()
+		if err != nil {
+			t.Fatalf("sessionConfig(): %v", err)
+		}
+		if !strings.HasPrefix(cfg.Name, "tail-") {
+			t.Errorf("wrong name, want: tail-<pid>, got: %q", cfg.Name)
+		}
+		if len(cfg.Presets) != 0 {
+			t.Errorf("presets must not be set with points, got: %v", cfg.Presets)
+		}
+		if len(cfg.Points) != 1 {
+			t.Fatalf("wrong number of points, want: 1, got: %+v", cfg.Points)
+		}
+		pt := cfg.Points[0]
+		if want := len(seccheck.Points[name].OptionalFields); len(pt.OptionalFields) != want {
+			t.Errorf("wrong optional fields, want: %d fields, got: %v", want, pt.OptionalFields)
+		}
+		for _, field := range tailContextFields {
+			if !contains(pt.ContextFields, field) {
+				t.Errorf("context field %q missing: %v", field, pt.ContextFields)
+			}
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		if _, err := (&tail{points: "foo/bar"}).sessionConfig(); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("sessionConfig() wrong error, want: not found, got: %v", err)
+		}
+	})
+}
@@ -65,6 +65,11 @@ func createCommander(f *flag.FlagSet) *subcommands.Commander {
 	cdr.Register(new(delete), "")
 	cdr.Register(new(list), "")
 	cdr.Register(new(metadata), "")
+	cdr.Register(new(metrics), "")
 	cdr.Register(new(procfs), "")
+	cdr.Register(new(reload), "")
+	cdr.Register(new(tail), "")
+	cdr.Register(new(update), "")
+	cdr.Register(new(validate), "")
 	return cdr
 }
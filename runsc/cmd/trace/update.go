@@ -0,0 +1,92 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/container"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// update implements subcommands.Command for the "update" command.
+type update struct {
+	config string
+}
+
+// Name implements subcommands.Command.
+func (*update) Name() string {
+	return "update"
+}
+
+// Synopsis implements subcommands.Command.
+func (*update) Synopsis() string {
+	return "update the points of a trace session"
+}
+
+// Usage implements subcommands.Command.
+func (*update) Usage() string {
+	return `update [flags] <sandbox id> - update the points of a trace session
+
+The session keeps its sinks, so they don't miss points while the points are
+changed. If the file has sinks, they must be the same sinks as the session's,
+in the same order, and their configuration is reloaded atomically, e.g. to
+tune the rules of the policy sink. Other changes to sinks require creating the
+session again with --force.
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (l *update) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&l.config, "config", "", "path to the JSON file that describes the session being updated, with sinks to reload their configuration")
+}
+
+// Execute implements subcommands.Command.
+func (l *update) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 1 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	if len(l.config) == 0 {
+		f.Usage()
+		return util.Errorf("missing path to configuration file, please set --config=[path]")
+	}
+
+	sessionConfig, err := decodeTraceConfig(l.config)
+	if err != nil {
+		return util.Errorf("loading config file: %v", err)
+	}
+
+	id := f.Arg(0)
+	conf := args[0].(*config.Config)
+
+	opts := container.LoadOpts{
+		SkipCheck:     true,
+		RootContainer: true,
+	}
+	c, err := container.Load(conf.RootDir, container.FullID{ContainerID: id}, opts)
+	if err != nil {
+		util.Fatalf("loading sandbox: %v", err)
+	}
+
+	if err := c.Sandbox.UpdateTraceSession(sessionConfig); err != nil {
+		util.Fatalf("updating session: %v", err)
+	}
+
+	return subcommands.ExitSuccess
+}
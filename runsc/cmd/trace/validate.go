@@ -0,0 +1,168 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// validateSandboxID is the sandbox ID passed to sinks when they are set up by
+// validate.
+const validateSandboxID = "runsc-trace-validate"
+
+// validate implements subcommands.Command for the "validate" command.
+type validate struct {
+	checkSinks bool
+}
+
+// Name implements subcommands.Command.
+func (*validate) Name() string {
+	return "validate"
+}
+
+// Synopsis implements subcommands.Command.
+func (*validate) Synopsis() string {
+	return "validate a trace configuration file"
+}
+
+// Usage implements subcommands.Command.
+func (*validate) Usage() string {
+	return `validate [flags] <config> - validate a trace configuration file
+
+The file is either a session, as passed to "create", or a --pod-init-config
+file. Session names, point and field names, payload settings, and sink
+configuration, e.g. filters, are checked, and the resolved configuration is
+printed.
+
+With --check-sinks, sinks are also set up, e.g. connected to remote processes,
+to check that they are reachable. The printed configuration then includes
+settings negotiated with them. No sandbox is needed.
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (l *validate) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&l.checkSinks, "check-sinks", false, "set up sinks to check that they are reachable")
+}
+
+// Execute implements subcommands.Command.
+func (l *validate) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 1 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+
+	sessions, warnings, err := decodeTraceConfigs(f.Arg(0))
+	if err != nil {
+		return util.Errorf("loading config file: %v", err)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+	errs := validateSessions(sessions, l.checkSinks)
+
+	out, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return util.Errorf("encoding configuration: %v", err)
+	}
+	fmt.Println(string(out))
+
+	if len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		return util.Errorf("%d error(s) found in %q", len(errs), f.Arg(0))
+	}
+	return subcommands.ExitSuccess
+}
+
+// decodeTraceConfigs loads all sessions from path, which is either a
+// seccheck.SessionConfig or a boot.InitConfig. Warnings are returned for
+// configurations newer than this release, see seccheck.DecodeConfig.
+func decodeTraceConfigs(path string) ([]*seccheck.SessionConfig, []string, error) {
+	sessionConfig, initConfig, warnings, err := loadConfigFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sessionConfig != nil {
+		return []*seccheck.SessionConfig{sessionConfig}, warnings, nil
+	}
+	return initConfig.Sessions(), warnings, nil
+}
+
+// validateSessions returns all errors found in sessions. If checkSinks is set,
+// sinks are set up and the files they return are closed, which may change their
+// configuration in sessions.
+func validateSessions(sessions []*seccheck.SessionConfig, checkSinks bool) []error {
+	var errs []error
+	names := make(map[string]struct{})
+	for _, session := range sessions {
+		if _, ok := names[session.Name]; ok {
+			errs = append(errs, fmt.Errorf("session %q: duplicate session name", session.Name))
+		}
+		names[session.Name] = struct{}{}
+
+		if err := seccheck.Validate(session); err != nil {
+			errs = append(errs, fmt.Errorf("session %q: %w", session.Name, err))
+			continue
+		}
+		if !checkSinks {
+			continue
+		}
+		for i := range session.Sinks {
+			// Sinks are set up one at a time, so that failures of sinks that
+			// ignore setup errors are reported too.
+			sink := session.Sinks[i : i+1]
+			ignore := sink[0].IgnoreSetupError
+			_, hasID := sink[0].Config["sandbox_id"]
+			sink[0].IgnoreSetupError = false
+			files, err := seccheck.SetupSinks(sink, validateSandboxID)
+			sink[0].IgnoreSetupError = ignore
+			if !hasID {
+				// Don't report the placeholder sandbox ID as configuration. The
+				// builtin delete is shadowed by the delete command.
+				config := make(map[string]interface{}, len(sink[0].Config))
+				for k, v := range sink[0].Config {
+					if k != "sandbox_id" {
+						config[k] = v
+					}
+				}
+				sink[0].Config = config
+			}
+			for _, f := range files {
+				if f != nil {
+					_ = f.Close()
+				}
+			}
+			if err != nil {
+				if ignore {
+					log.Warningf("Session %q: ignoring sink setup failure: %v", session.Name, err)
+					continue
+				}
+				errs = append(errs, fmt.Errorf("session %q: %w", session.Name, err))
+			}
+		}
+	}
+	return errs
+}
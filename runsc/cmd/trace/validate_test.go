@@ -0,0 +1,194 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/test/testutil"
+	"gvisor.dev/gvisor/runsc/boot"
+)
+
+func TestValidate(t *testing.T) {
+	valid := func(name string) seccheck.SessionConfig {
+		return seccheck.SessionConfig{
+			Name: name,
+			Points: []seccheck.PointConfig{
+				{
+					Name:          "sentry/clone",
+					ContextFields: []string{"container_id"},
+				},
+			},
+			Sinks: []seccheck.SinkConfig{
+				{
+					Name: "remote",
+					Config: map[string]interface{}{
+						"filters": map[string]interface{}{
+							"path_prefixes": []interface{}{"/etc"},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		sessions []seccheck.SessionConfig
+		errs     []string
+	}{
+		{
+			name:     "valid",
+			sessions: []seccheck.SessionConfig{valid("a"), valid("b")},
+		},
+		{
+			name:     "duplicate",
+			sessions: []seccheck.SessionConfig{valid("a"), valid("a")},
+			errs:     []string{"duplicate session name"},
+		},
+		{
+			name:     "bad-name",
+			sessions: []seccheck.SessionConfig{valid("a b")},
+			errs:     []string{"invalid session name"},
+		},
+		{
+			name: "bad-point",
+			sessions: []seccheck.SessionConfig{
+				func() seccheck.SessionConfig {
+					s := valid("a")
+					s.Points[0].Name = "sentry/foo"
+					return s
+				}(),
+			},
+			errs: []string{`point "sentry/foo" not found`},
+		},
+		{
+			name: "bad-field",
+			sessions: []seccheck.SessionConfig{
+				func() seccheck.SessionConfig {
+					s := valid("a")
+					s.Points[0].ContextFields = []string{"foo"}
+					return s
+				}(),
+			},
+			errs: []string{`field "foo" not found`},
+		},
+		{
+			name: "bad-sink",
+			sessions: []seccheck.SessionConfig{
+				func() seccheck.SessionConfig {
+					s := valid("a")
+					s.Sinks[0].Name = "foo"
+					return s
+				}(),
+			},
+			errs: []string{`sink "foo" not found`},
+		},
+		{
+			name: "bad-filter",
+			sessions: []seccheck.SessionConfig{
+				func() seccheck.SessionConfig {
+					s := valid("a")
+					s.Sinks[0].Config["filters"] = map[string]interface{}{"fd_types": []interface{}{"tty"}}
+					return s
+				}(),
+			},
+			errs: []string{"invalid fd type"},
+		},
+		{
+			name: "all-errors",
+			sessions: []seccheck.SessionConfig{
+				func() seccheck.SessionConfig {
+					s := valid("a")
+					s.Points[0].Name = "sentry/foo"
+					return s
+				}(),
+				func() seccheck.SessionConfig {
+					s := valid("b")
+					s.Sinks[0].Name = "foo"
+					return s
+				}(),
+			},
+			errs: []string{`session "a"`, `session "b"`},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var sessions []*seccheck.SessionConfig
+			for i := range tc.sessions {
+				sessions = append(sessions, &tc.sessions[i])
+			}
+			errs := validateSessions(sessions, false)
+			if len(errs) != len(tc.errs) {
+				t.Fatalf("validateSessions(), want errors: %q, got: %v", tc.errs, errs)
+			}
+			for i, err := range errs {
+				if !strings.Contains(err.Error(), tc.errs[i]) {
+					t.Errorf("validateSessions(), want error: %q, got: %v", tc.errs[i], err)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeTraceConfigs(t *testing.T) {
+	a := seccheck.SessionConfig{Name: "a", Sinks: []seccheck.SinkConfig{{Name: "null"}}}
+	b := seccheck.SessionConfig{Name: "b", Sinks: []seccheck.SinkConfig{{Name: "null"}}}
+	for _, tc := range []struct {
+		name string
+		json interface{}
+		want []string
+	}{
+		{
+			name: "SessionConfig",
+			json: a,
+			want: []string{"a"},
+		},
+		{
+			name: "InitConfig",
+			json: boot.InitConfig{TraceSession: a, TraceSessions: []seccheck.SessionConfig{b}},
+			want: []string{"a", "b"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tmp, err := os.CreateTemp(testutil.TmpDir(), "trace-validate")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tmp.Close()
+			if err := json.NewEncoder(tmp).Encode(tc.json); err != nil {
+				t.Fatal(err)
+			}
+
+			sessions, warnings, err := decodeTraceConfigs(tmp.Name())
+			if err != nil {
+				t.Fatalf("decodeTraceConfigs(): %v", err)
+			}
+			if len(warnings) > 0 {
+				t.Errorf("decodeTraceConfigs() unexpected warnings: %q", warnings)
+			}
+			var got []string
+			for _, s := range sessions {
+				got = append(got, s.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("wrong sessions, want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
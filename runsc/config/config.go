@@ -227,6 +227,10 @@ type Config struct {
 	// take during pod creation.
 	PodInitConfig string `flag:"pod-init-config"`
 
+	// TraceLog is a comma-separated list of trace point presets, e.g.
+	// "security-essentials", whose points are written to the debug log.
+	TraceLog string `flag:"trace-log"`
+
 	// Use pools to manage buffer memory instead of heap.
 	BufferPooling bool `flag:"buffer-pooling"`
 
@@ -73,6 +73,7 @@ func RegisterFlags(flagSet *flag.FlagSet) {
 	flagSet.Var(defaultControlConfig(), "controls", "Sentry control endpoints.")
 	flagSet.Bool("enable-core-tags", false, "enables core tagging. Requires host linux kernel >= 5.14.")
 	flagSet.String("pod-init-config", "", "path to configuration file with additional steps to take during pod creation.")
+	flagSet.String("trace-log", "", "writes the trace points of these comma-separated presets, e.g. security-essentials, to the debug log. Run 'runsc trace metadata' to list presets.")
 
 	// Flags that control sandbox runtime behavior: FS related.
 	flagSet.Var(fileAccessTypePtr(FileAccessExclusive), "file-access", "specifies which filesystem validation to use for the root mount: exclusive (default), shared.")
@@ -672,6 +672,22 @@ func (c *Container) Resume() error {
 	return c.saveLocked()
 }
 
+// ResumeQuarantined resumes the tasks of a container that a trace session
+// paused after a policy violation. Unlike Resume, the container's status
+// remains running throughout, since the rest of the sandbox isn't paused.
+func (c *Container) ResumeQuarantined() error {
+	log.Debugf("Resuming quarantined container, cid: %s", c.ID)
+	if err := c.Saver.lock(); err != nil {
+		return err
+	}
+	defer c.Saver.unlockOrDie()
+
+	if c.Status != Running {
+		return fmt.Errorf("cannot resume quarantined container %q in state %v", c.ID, c.Status)
+	}
+	return c.Sandbox.ResumeQuarantined(c.ID)
+}
+
 // Cat prints out the content of the files.
 func (c *Container) Cat(files []string, out *os.File) error {
 	log.Debugf("Cat in container, cid: %s, files: %+v", c.ID, files)
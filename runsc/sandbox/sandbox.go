@@ -23,6 +23,7 @@ import (
 	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -172,6 +173,10 @@ type Args struct {
 	// SinkFiles is the an ordered array of files to be used by seccheck sinks
 	// configured from the --pod-init-config file.
 	SinkFiles []*os.File
+
+	// PodInitConfig is the --pod-init-config file after sinks have been set up.
+	// It's nil if --pod-init-config is not set.
+	PodInitConfig *os.File
 }
 
 // New creates the sandbox process. The caller must call Destroy() on the
@@ -200,7 +205,13 @@ func New(conf *config.Config, args *Args) (*Sandbox, error) {
 		if err != nil {
 			return nil, fmt.Errorf("loading init config file: %w", err)
 		}
-		args.SinkFiles, err = initConf.Setup()
+		args.SinkFiles, err = initConf.Setup(s.ID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot init config: %w", err)
+		}
+		// Sinks may update their configuration during setup, so pass the
+		// updated configuration to the sandbox instead of the original file.
+		args.PodInitConfig, err = initConf.File()
 		if err != nil {
 			return nil, fmt.Errorf("cannot init config: %w", err)
 		}
@@ -394,7 +405,7 @@ func (s *Sandbox) Processes(cid string) ([]*control.Process, error) {
 func (s *Sandbox) CreateTraceSession(config *seccheck.SessionConfig, force bool) error {
 	log.Debugf("Creating trace session in sandbox %q", s.ID)
 
-	sinkFiles, err := seccheck.SetupSinks(config.Sinks)
+	sinkFiles, err := seccheck.SetupSinks(config.Sinks, s.ID)
 	if err != nil {
 		return err
 	}
@@ -438,6 +449,62 @@ func (s *Sandbox) DeleteTraceSession(name string) error {
 	return nil
 }
 
+// UpdateTraceSession updates the points of an existing trace session.
+func (s *Sandbox) UpdateTraceSession(config *seccheck.SessionConfig) error {
+	log.Debugf("Updating trace session %q in sandbox %q", config.Name, s.ID)
+	conn, err := s.sandboxConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.Call(boot.ContMgrUpdateTraceSession, config, nil); err != nil {
+		return fmt.Errorf("updating trace session: %w", err)
+	}
+	return nil
+}
+
+// ReconnectTraceSink sets up sink, the sink at index in the configuration of
+// trace session name, again and hands the new connection to it. It's used
+// when the remote process of the sink went away and came back.
+func (s *Sandbox) ReconnectTraceSink(name string, index int, sink seccheck.SinkConfig) error {
+	log.Debugf("Reconnecting sink %d of trace session %q in sandbox %q", index, name, s.ID)
+
+	sink.IgnoreSetupError = false
+	sinks := []seccheck.SinkConfig{sink}
+	sinkFiles, err := seccheck.SetupSinks(sinks, s.ID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, f := range sinkFiles {
+			_ = f.Close()
+		}
+	}()
+	if sinkFiles[0] == nil {
+		return fmt.Errorf("sink %q has no connection to reconnect", sink.Name)
+	}
+
+	conn, err := s.sandboxConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	arg := boot.ReconnectTraceSinkArgs{
+		Session: name,
+		Index:   index,
+		Sink:    sinks[0],
+		FilePayload: urpc.FilePayload{
+			Files: sinkFiles,
+		},
+	}
+	if err := conn.Call(boot.ContMgrReconnectTraceSink, &arg, nil); err != nil {
+		return fmt.Errorf("reconnecting trace sink: %w", err)
+	}
+	return nil
+}
+
 // ListTraceSessions lists all trace sessions.
 func (s *Sandbox) ListTraceSessions() ([]seccheck.SessionConfig, error) {
 	log.Debugf("Listing trace sessions in sandbox %q", s.ID)
@@ -491,6 +558,7 @@ func (s *Sandbox) Execute(conf *config.Config, args *control.ExecArgs) (int32, e
 	defer conn.Close()
 
 	// Send a message to the sandbox control server to start the container.
+	args.Initiator = control.CurrentInitiator()
 	var pid int32
 	if err := conn.Call(boot.ContMgrExecuteAsync, args, &pid); err != nil {
 		return 0, fmt.Errorf("executing command %q in sandbox: %v", args, err)
@@ -628,11 +696,20 @@ func (s *Sandbox) createSandboxProcess(conf *config.Config, args *Args, startSyn
 	}
 	donations.DonateAndClose("spec-fd", specFile)
 
-	if err := donations.OpenAndDonate("pod-init-config-fd", conf.PodInitConfig, os.O_RDONLY); err != nil {
-		return err
+	if args.PodInitConfig != nil {
+		donations.DonateAndClose("pod-init-config-fd", args.PodInitConfig)
 	}
 	donations.DonateAndClose("sink-fds", args.SinkFiles...)
 
+	if s.CgroupJSON.Cgroup != nil {
+		// The sandbox can't access cgroupfs, so donate cpu.stat to it to
+		// report CPU throttling. Reporting is best effort.
+		cpuStat := filepath.Join(s.CgroupJSON.Cgroup.MakePath("cpu"), "cpu.stat")
+		if err := donations.OpenAndDonate("cpu-stat-fd", cpuStat, os.O_RDONLY); err != nil {
+			log.Infof("CPU throttling won't be reported, opening %q: %v", cpuStat, err)
+		}
+	}
+
 	gPlatform, err := platform.Lookup(conf.Platform)
 	if err != nil {
 		return fmt.Errorf("cannot look up platform: %w", err)
@@ -1098,7 +1175,8 @@ func (s *Sandbox) Pause(cid string) error {
 	}
 	defer conn.Close()
 
-	if err := conn.Call(boot.LifecyclePause, nil, nil); err != nil {
+	args := control.PauseResumeArgs{Initiator: control.CurrentInitiator()}
+	if err := conn.Call(boot.LifecyclePause, &args, nil); err != nil {
 		return fmt.Errorf("pausing container %q: %v", cid, err)
 	}
 	return nil
@@ -1113,12 +1191,29 @@ func (s *Sandbox) Resume(cid string) error {
 	}
 	defer conn.Close()
 
-	if err := conn.Call(boot.LifecycleResume, nil, nil); err != nil {
+	args := control.PauseResumeArgs{Initiator: control.CurrentInitiator()}
+	if err := conn.Call(boot.LifecycleResume, &args, nil); err != nil {
 		return fmt.Errorf("resuming container %q: %v", cid, err)
 	}
 	return nil
 }
 
+// ResumeQuarantined sends the resume call for a container that was paused by
+// a policy violation, leaving the rest of the sandbox untouched.
+func (s *Sandbox) ResumeQuarantined(cid string) error {
+	log.Debugf("Resume quarantined container %q in sandbox %q", cid, s.ID)
+	conn, err := s.sandboxConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.Call(boot.ContMgrResumeQuarantined, &cid, nil); err != nil {
+		return fmt.Errorf("resuming quarantined container %q: %v", cid, err)
+	}
+	return nil
+}
+
 // Cat sends the cat call for a container in the sandbox.
 func (s *Sandbox) Cat(cid string, files []string, out *os.File) error {
 	log.Debugf("Cat sandbox %q", s.ID)
@@ -1370,7 +1465,11 @@ func (s *Sandbox) destroyContainer(cid string) error {
 		return err
 	}
 	defer conn.Close()
-	if err := conn.Call(boot.ContMgrDestroySubcontainer, &cid, nil); err != nil {
+	args := boot.DestroySubcontainerArgs{
+		CID:       cid,
+		Initiator: control.CurrentInitiator(),
+	}
+	if err := conn.Call(boot.ContMgrDestroySubcontainer, &args, nil); err != nil {
 		return fmt.Errorf("destroying container %q: %v", cid, err)
 	}
 	return nil
@@ -51,6 +51,28 @@ const (
 	// which sandbox the container should be created in when the container
 	// is not the first container in the sandbox.
 	CRIOSandboxIDAnnotation = "io.kubernetes.cri-o.SandboxID"
+
+	// ContainerdContainerNameAnnotation is the OCI annotation set by
+	// containerd to the name of the container in the pod spec.
+	ContainerdContainerNameAnnotation = "io.kubernetes.cri.container-name"
+
+	// CRIOContainerNameAnnotation is the OCI annotation set by CRI-O to the
+	// name of the container in the pod spec.
+	CRIOContainerNameAnnotation = "io.kubernetes.cri-o.ContainerName"
+
+	// ContainerdPodNameAnnotation, ContainerdPodNamespaceAnnotation and
+	// ContainerdPodUIDAnnotation are the OCI annotations set by containerd to
+	// the metadata of the pod.
+	ContainerdPodNameAnnotation      = "io.kubernetes.cri.sandbox-name"
+	ContainerdPodNamespaceAnnotation = "io.kubernetes.cri.sandbox-namespace"
+	ContainerdPodUIDAnnotation       = "io.kubernetes.cri.sandbox-uid"
+
+	// CRIOPodNameAnnotation, CRIOPodNamespaceAnnotation and
+	// CRIOPodUIDAnnotation are the OCI annotations set by CRI-O to the
+	// metadata of the pod.
+	CRIOPodNameAnnotation      = "io.kubernetes.pod.name"
+	CRIOPodNamespaceAnnotation = "io.kubernetes.pod.namespace"
+	CRIOPodUIDAnnotation       = "io.kubernetes.pod.uid"
 )
 
 // ContainerType represents the type of container requested by the calling container manager.
@@ -108,3 +130,21 @@ func SandboxID(spec *specs.Spec) (string, bool) {
 	}
 	return "", false
 }
+
+// ContainerName returns the name of the container in the pod spec, or an empty
+// string if the container manager didn't set it.
+func ContainerName(spec *specs.Spec) string {
+	if name, ok := spec.Annotations[ContainerdContainerNameAnnotation]; ok {
+		return name
+	}
+	return spec.Annotations[CRIOContainerNameAnnotation]
+}
+
+// Pod returns the name, namespace and UID of the pod that the container belongs
+// to, or empty strings if the container manager didn't set them.
+func Pod(spec *specs.Spec) (name, namespace, uid string) {
+	if n, ok := spec.Annotations[ContainerdPodNameAnnotation]; ok {
+		return n, spec.Annotations[ContainerdPodNamespaceAnnotation], spec.Annotations[ContainerdPodUIDAnnotation]
+	}
+	return spec.Annotations[CRIOPodNameAnnotation], spec.Annotations[CRIOPodNamespaceAnnotation], spec.Annotations[CRIOPodUIDAnnotation]
+}
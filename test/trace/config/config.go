@@ -104,6 +104,11 @@ func parseFields(elem, prefix string) ([]string, error) {
 	return strings.Split(clean, "|"), nil
 }
 
+// AddPoint adds the point to the configuration.
+func (b *Builder) AddPoint(point seccheck.PointConfig) {
+	b.points = append(b.points, point)
+}
+
 // AddSink adds the sink to the configuration.
 func (b *Builder) AddSink(sink seccheck.SinkConfig) {
 	b.sinks = append(b.sinks, sink)
@@ -25,6 +25,7 @@ import (
 
 	"golang.org/x/sys/unix"
 	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/test"
 	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
@@ -38,17 +39,68 @@ var cutoffTime time.Time
 // fields enabled. Then it runs a workload that will trigger those points and
 // run some basic validation over the points generated.
 func TestAll(t *testing.T) {
-	server, err := test.NewServer()
+	runsc, err := testutil.FindFile("runsc/runsc")
 	if err != nil {
 		t.Fatal(err)
 	}
+	builder := config.Builder{}
+	if err := builder.LoadAllPoints(runsc); err != nil {
+		t.Fatal(err)
+	}
+	matchPoints(t, runWorkload(t, runsc, &builder))
+}
 
+// TestGetNameExitOnly enables only the exit points of getsockname(2) and
+// getpeername(2), and checks that they still report the address.
+func TestGetNameExitOnly(t *testing.T) {
 	runsc, err := testutil.FindFile("runsc/runsc")
 	if err != nil {
 		t.Fatal(err)
 	}
 	builder := config.Builder{}
-	if err := builder.LoadAllPoints(runsc); err != nil {
+	builder.AddPoint(seccheck.PointConfig{Name: "syscall/getsockname/exit"})
+	builder.AddPoint(seccheck.PointConfig{Name: "syscall/getpeername/exit"})
+
+	count := 0
+	for _, msg := range runWorkload(t, runsc, &builder) {
+		var (
+			exit    *pb.Exit
+			address []byte
+		)
+		switch msg.MsgType {
+		case pb.MessageType_MESSAGE_SYSCALL_GETSOCKNAME:
+			p := pb.Getsockname{}
+			if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+				t.Fatalf("message type %v: %v", msg.MsgType, err)
+			}
+			exit, address = p.Exit, p.Address
+		case pb.MessageType_MESSAGE_SYSCALL_GETPEERNAME:
+			p := pb.Getpeername{}
+			if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+				t.Fatalf("message type %v: %v", msg.MsgType, err)
+			}
+			exit, address = p.Exit, p.Address
+		default:
+			t.Errorf("unexpected message type %v", msg.MsgType)
+			continue
+		}
+		count++
+		if exit == nil {
+			t.Errorf("message type %v: missing exit", msg.MsgType)
+		} else if exit.Result == 0 && len(address) == 0 {
+			t.Errorf("message type %v: empty address", msg.MsgType)
+		}
+	}
+	if count == 0 {
+		t.Errorf("no getsockname or getpeername point was generated")
+	}
+}
+
+// runWorkload runs the workload with the trace session in builder, and returns
+// the points received.
+func runWorkload(t *testing.T, runsc string, builder *config.Builder) []test.Message {
+	server, err := test.NewServer()
+	if err != nil {
 		t.Fatal(err)
 	}
 	builder.AddSink(seccheck.SinkConfig{
@@ -87,7 +139,7 @@ func TestAll(t *testing.T) {
 
 	// Wait until the sandbox disconnects to ensure all points were gathered.
 	server.WaitForNoClients()
-	matchPoints(t, server.GetPoints())
+	return server.GetPoints()
 }
 
 func matchPoints(t *testing.T, msgs []test.Message) {
@@ -101,9 +153,12 @@ func matchPoints(t *testing.T, msgs []test.Message) {
 		pb.MessageType_MESSAGE_SENTRY_EXEC:               {checker: checkSentryExec},
 		pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT: {checker: checkSentryExitNotifyParent},
 		pb.MessageType_MESSAGE_SENTRY_TASK_EXIT:          {checker: checkSentryTaskExit},
+		pb.MessageType_MESSAGE_SENTRY_SIGNAL_DELIVER:     {checker: checkSentrySignalDeliver},
 		pb.MessageType_MESSAGE_SYSCALL_CLOSE:             {checker: checkSyscallClose},
 		pb.MessageType_MESSAGE_SYSCALL_CONNECT:           {checker: checkSyscallConnect},
 		pb.MessageType_MESSAGE_SYSCALL_EXECVE:            {checker: checkSyscallExecve},
+		pb.MessageType_MESSAGE_SYSCALL_GETPEERNAME:       {checker: checkSyscallGetpeername},
+		pb.MessageType_MESSAGE_SYSCALL_GETSOCKNAME:       {checker: checkSyscallGetsockname},
 		pb.MessageType_MESSAGE_SYSCALL_OPEN:              {checker: checkSyscallOpen},
 		pb.MessageType_MESSAGE_SYSCALL_RAW:               {checker: checkSyscallRaw},
 		pb.MessageType_MESSAGE_SYSCALL_READ:              {checker: checkSyscallRead},
@@ -111,6 +166,18 @@ func matchPoints(t *testing.T, msgs []test.Message) {
 	}
 	for _, msg := range msgs {
 		t.Logf("Processing message type %v", msg.MsgType)
+		if msg.MsgType == pb.MessageType_MESSAGE_SENTRY_CAPABILITY_DENIED {
+			// The workload only performs operations that are allowed, e.g. killing
+			// a process with the same UID without CAP_KILL, so none of its
+			// capability checks should be reported as denied.
+			p := pb.CapabilityDeniedInfo{}
+			if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+				t.Errorf("message type %v: %v", msg.MsgType, err)
+			} else {
+				t.Errorf("unexpected capability denied: %s, sysno: %d", p.CapabilityName, p.Sysno)
+			}
+			continue
+		}
 		if handler := matchers[msg.MsgType]; handler == nil {
 			// All points generated should have a corresponding matcher.
 			t.Errorf("No matcher for message type %v", msg.MsgType)
@@ -231,6 +298,23 @@ func checkSentryTaskExit(msg test.Message) error {
 	return nil
 }
 
+func checkSentrySignalDeliver(msg test.Message) error {
+	p := pb.SignalDeliverInfo{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if p.Signo <= 0 || p.Signo > int32(linux.SignalMaximum) {
+		return fmt.Errorf("invalid signal number: %d", p.Signo)
+	}
+	if p.Action == pb.SignalDeliverInfo_ACTION_UNKNOWN {
+		return fmt.Errorf("missing action, signal: %d", p.Signo)
+	}
+	return nil
+}
+
 func checkSyscallRaw(msg test.Message) error {
 	p := pb.Syscall{}
 	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
@@ -433,3 +517,37 @@ func checkSyscallSocket(msg test.Message) error {
 	}
 	return nil
 }
+
+func checkSyscallGetsockname(msg test.Message) error {
+	p := pb.Getsockname{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if p.Fd < 3 {
+		return fmt.Errorf("invalid FD: %d", p.Fd)
+	}
+	if p.Exit != nil && p.Exit.Result == 0 && len(p.Address) == 0 {
+		return fmt.Errorf("empty address: %q", string(p.Address))
+	}
+	return nil
+}
+
+func checkSyscallGetpeername(msg test.Message) error {
+	p := pb.Getpeername{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if p.Fd < 3 {
+		return fmt.Errorf("invalid FD: %d", p.Fd)
+	}
+	if p.Exit != nil && p.Exit.Result == 0 && len(p.Address) == 0 {
+		return fmt.Errorf("empty address: %q", string(p.Address))
+	}
+	return nil
+}
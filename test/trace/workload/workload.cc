@@ -13,13 +13,17 @@
 // limitations under the License.
 
 #include <err.h>
+#include <signal.h>
 #include <sys/socket.h>
 #include <sys/types.h>
 #include <sys/un.h>
+#include <sys/wait.h>
+#include <unistd.h>
 
 #include "absl/cleanup/cleanup.h"
 #include "absl/strings/str_cat.h"
 #include "absl/time/clock.h"
+#include "test/util/capability_util.h"
 #include "test/util/file_descriptor.h"
 #include "test/util/multiprocess_util.h"
 #include "test/util/posix_error.h"
@@ -110,17 +114,88 @@ void runSocket() {
       err(1, "read: %d", bytes);
     }
 
+    struct sockaddr_un name;
+    socklen_t name_len = sizeof(name);
+    if (getsockname(client, reinterpret_cast<struct sockaddr*>(&name),
+                    &name_len) < 0) {
+      err(1, "getsockname");
+    }
+    name_len = sizeof(name);
+    if (getpeername(client, reinterpret_cast<struct sockaddr*>(&name),
+                    &name_len) < 0) {
+      err(1, "getpeername");
+    }
+
     // Wait to reap the child.
     RetryEINTR(waitpid)(pid, nullptr, 0);
   }
 }
 
+// Installs a handler for SIGUSR1 and raises it, so that the signal is delivered
+// to the handler.
+void runSignal() {
+  struct sigaction sa = {};
+  sa.sa_handler = +[](int) {};
+  if (sigaction(SIGUSR1, &sa, nullptr) < 0) {
+    err(1, "sigaction");
+  }
+  if (raise(SIGUSR1) < 0) {
+    err(1, "raise");
+  }
+}
+
+// Kills a child process from a sibling that has dropped CAP_KILL. The kill is
+// allowed because both run with the same UID, so it must not be reported as a
+// capability denial.
+void runKillSameUID() {
+  pid_t target = fork();
+  if (target < 0) {
+    err(1, "fork");
+  }
+  if (target == 0) {
+    // Wait to be killed.
+    while (true) {
+      pause();
+    }
+  }
+
+  pid_t killer = fork();
+  if (killer < 0) {
+    err(1, "fork");
+  }
+  if (killer == 0) {
+    if (!SetCapability(CAP_KILL, false).ok()) {
+      _exit(1);
+    }
+    if (kill(target, SIGKILL) < 0) {
+      _exit(2);
+    }
+    _exit(0);
+  }
+
+  int status;
+  if (RetryEINTR(waitpid)(killer, &status, 0) < 0) {
+    err(1, "waitpid");
+  }
+  if (!WIFEXITED(status) || WEXITSTATUS(status) != 0) {
+    errx(1, "killer failed, status: %d", status);
+  }
+  if (RetryEINTR(waitpid)(target, &status, 0) < 0) {
+    err(1, "waitpid");
+  }
+  if (!WIFSIGNALED(status) || WTERMSIG(status) != SIGKILL) {
+    errx(1, "target not killed, status: %d", status);
+  }
+}
+
 }  // namespace testing
 }  // namespace gvisor
 
 int main(int argc, char** argv) {
   ::gvisor::testing::runForkExecve();
   ::gvisor::testing::runSocket();
+  ::gvisor::testing::runSignal();
+  ::gvisor::testing::runKillSameUID();
 
   return 0;
 }
@@ -0,0 +1,247 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package falcoplugin implements a Falco plugin that provides the events of
+// gVisor trace sessions as an event source, with fields that Falco rules can
+// use, e.g. in the condition of a rule with "source: gvisor":
+//
+//	gvisor.type = "syscall/execve" and gvisor.path endswith "/sh"
+//
+// The plugin listens on a socket that sandboxes connect to through the remote
+// sink. Events are stored by Falco in the format of EncodeEvent, and fields
+// are extracted from them with Extract. The shared library that Falco loads is
+// built from the main package, which adapts this package to the plugin API.
+package falcoplugin
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/consumer"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+const (
+	// Name is the name of the plugin.
+	Name = "gvisor"
+
+	// EventSource is the name of the event source, used in the source field
+	// of rules.
+	EventSource = "gvisor"
+
+	// ID identifies the event source in capture files. 999 is reserved by the
+	// Falco plugin registry for plugins in development, and must be replaced
+	// with a registered ID before the plugin is distributed.
+	ID = 999
+
+	// Version is the version of the plugin.
+	Version = "0.1.0"
+
+	// Description describes the plugin.
+	Description = "Reads events from gVisor sandboxes through the remote trace sink"
+
+	// Contact is where to reach the authors of the plugin.
+	Contact = "github.com/google/gvisor"
+
+	// DefaultEndpoint is the path of the socket that sandboxes connect to, when
+	// not set in Config.
+	DefaultEndpoint = "/run/gvisor/falco.sock"
+
+	// DefaultBufferSize is the number of events buffered, when not set in
+	// Config.
+	DefaultBufferSize = 4096
+)
+
+// Config is the configuration of the plugin, that Falco passes as JSON when
+// the plugin is initialized.
+type Config struct {
+	// Endpoint is the path of the socket that sandboxes connect to. Sessions
+	// must use it as the endpoint of the remote sink. It can be overridden by
+	// the parameters of the event source.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// BufferSize is the number of events received from sandboxes that are
+	// buffered until Falco reads them. Sandboxes are slowed down when the
+	// buffer is full.
+	BufferSize int `json:"buffer_size,omitempty"`
+}
+
+// InitSchema is the JSON schema of Config.
+const InitSchema = `{
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "type": "object",
+  "properties": {
+    "endpoint": {
+      "type": "string",
+      "description": "Path of the socket that sandboxes connect to through the remote sink"
+    },
+    "buffer_size": {
+      "type": "integer",
+      "minimum": 1,
+      "description": "Number of events buffered until Falco reads them"
+    }
+  },
+  "additionalProperties": false
+}`
+
+// ParseConfig parses the configuration of the plugin. An empty configuration
+// uses the defaults.
+func ParseConfig(s string) (Config, error) {
+	cfg := Config{}
+	if len(s) > 0 {
+		if err := json.Unmarshal([]byte(s), &cfg); err != nil {
+			return Config{}, fmt.Errorf("invalid configuration: %w", err)
+		}
+	}
+	if len(cfg.Endpoint) == 0 {
+		cfg.Endpoint = DefaultEndpoint
+	}
+	if cfg.BufferSize == 0 {
+		cfg.BufferSize = DefaultBufferSize
+	}
+	if cfg.BufferSize < 0 {
+		return Config{}, fmt.Errorf("invalid buffer_size %d", cfg.BufferSize)
+	}
+	return cfg, nil
+}
+
+// typeSize is the size of the message type that precedes the message in
+// encoded events.
+const typeSize = 2
+
+// EncodeEvent encodes msg, of type t, as the data of a Falco event: the message
+// type, as a little-endian uint16, followed by the message in protobuf.
+func EncodeEvent(t pb.MessageType, msg proto.Message) ([]byte, error) {
+	data := make([]byte, typeSize, typeSize+proto.Size(msg))
+	binary.LittleEndian.PutUint16(data, uint16(t))
+	return proto.MarshalOptions{}.MarshalAppend(data, msg)
+}
+
+// DecodeEvent decodes the data of an event encoded with EncodeEvent.
+func DecodeEvent(data []byte) (pb.MessageType, proto.Message, error) {
+	if len(data) < typeSize {
+		return 0, nil, fmt.Errorf("event too small: %d bytes", len(data))
+	}
+	t := pb.MessageType(binary.LittleEndian.Uint16(data))
+	msg, err := consumer.Decode(t, data[typeSize:])
+	if err != nil {
+		return 0, nil, err
+	}
+	return t, msg, nil
+}
+
+// EventString returns a description of the event in data, for Falco to
+// print, e.g. in capture files.
+func EventString(data []byte) (string, error) {
+	t, msg, err := DecodeEvent(data)
+	if err != nil {
+		return "", err
+	}
+	point, err := protojson.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s", typeName(t), point), nil
+}
+
+// Event is an event read from the source.
+type Event struct {
+	// Data is the event encoded with EncodeEvent.
+	Data []byte
+
+	// TimeNs is the time when the event was generated, in nanoseconds since
+	// the Unix epoch.
+	TimeNs uint64
+}
+
+// Source is an open event source, that receives events from sandboxes.
+type Source struct {
+	consumer *consumer.Consumer
+	events   chan Event
+
+	// done is closed when the source is closed, to stop waiting for space in
+	// events.
+	done chan struct{}
+}
+
+// Open starts listening on endpoint for sandboxes, and buffers up to
+// bufferSize events until they are read with Next.
+func Open(endpoint string, bufferSize int) (*Source, error) {
+	// Remove the socket left behind by a previous instance.
+	_ = os.Remove(endpoint)
+	s := &Source{
+		consumer: consumer.New(endpoint),
+		events:   make(chan Event, bufferSize),
+		done:     make(chan struct{}),
+	}
+	s.consumer.HandleAll(s.add)
+	if err := s.consumer.Start(); err != nil {
+		return nil, fmt.Errorf("listening on %q: %w", endpoint, err)
+	}
+	return s, nil
+}
+
+func (s *Source) add(e *consumer.Event) error {
+	data, err := EncodeEvent(e.Type, e.Msg)
+	if err != nil {
+		return err
+	}
+	ts := e.Header.TimeNs
+	if captured, _ := wire.TimeOf(e.Msg); captured != 0 {
+		ts = captured
+	}
+	if ts == 0 {
+		ts = time.Now().UnixNano()
+	}
+	select {
+	case s.events <- Event{Data: data, TimeNs: uint64(ts)}:
+	case <-s.done:
+	}
+	return nil
+}
+
+// Next returns up to max events. It waits up to timeout for the first event,
+// and returns no events if none arrived by then.
+func (s *Source) Next(max int, timeout time.Duration) []Event {
+	var events []Event
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case e := <-s.events:
+		events = append(events, e)
+	case <-timer.C:
+		return nil
+	}
+	for len(events) < max {
+		select {
+		case e := <-s.events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+	return events
+}
+
+// Close stops listening for sandboxes. Buffered events are discarded.
+func (s *Source) Close() {
+	close(s.done)
+	s.consumer.Close()
+}
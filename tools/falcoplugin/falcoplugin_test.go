@@ -0,0 +1,322 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package falcoplugin
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/consumer"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func TestParseConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		config  string
+		want    Config
+		wantErr bool
+	}{
+		{
+			name:   "empty",
+			config: "",
+			want:   Config{Endpoint: DefaultEndpoint, BufferSize: DefaultBufferSize},
+		},
+		{
+			name:   "endpoint",
+			config: `{"endpoint": "/tmp/foo.sock"}`,
+			want:   Config{Endpoint: "/tmp/foo.sock", BufferSize: DefaultBufferSize},
+		},
+		{
+			name:   "buffer",
+			config: `{"buffer_size": 10}`,
+			want:   Config{Endpoint: DefaultEndpoint, BufferSize: 10},
+		},
+		{
+			name:    "negative buffer",
+			config:  `{"buffer_size": -1}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid",
+			config:  `{"endpoint": 1}`,
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseConfig(tc.config)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("ParseConfig(%q) succeeded: %+v", tc.config, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfig(%q): %v", tc.config, err)
+			}
+			if got != tc.want {
+				t.Errorf("ParseConfig(%q): got %+v, want %+v", tc.config, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInitSchema(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(InitSchema), &schema); err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+}
+
+func TestFieldsJSON(t *testing.T) {
+	var fields []fieldJSON
+	if err := json.Unmarshal([]byte(FieldsJSON()), &fields); err != nil {
+		t.Fatalf("invalid fields: %v", err)
+	}
+	if len(fields) != len(Fields) {
+		t.Fatalf("got %d fields, want %d", len(fields), len(Fields))
+	}
+	names := make(map[string]struct{})
+	for i, f := range fields {
+		if !strings.HasPrefix(f.Name, EventSource+".") {
+			t.Errorf("field %q isn't prefixed with %q", f.Name, EventSource)
+		}
+		if _, ok := names[f.Name]; ok {
+			t.Errorf("duplicate field %q", f.Name)
+		}
+		names[f.Name] = struct{}{}
+		if f.Type != FieldTypeString && f.Type != FieldTypeUint64 {
+			t.Errorf("field %q has invalid type %q", f.Name, f.Type)
+		}
+		if Fields[i].extract == nil {
+			t.Errorf("field %q can't be extracted", f.Name)
+		}
+		if (f.Arg != nil) != Fields[i].Arg {
+			t.Errorf("field %q arg: got %+v, want %t", f.Name, f.Arg, Fields[i].Arg)
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	want := &pb.Open{Pathname: "/foo", Flags: 2}
+	data, err := EncodeEvent(pb.MessageType_MESSAGE_SYSCALL_OPEN, want)
+	if err != nil {
+		t.Fatalf("EncodeEvent(): %v", err)
+	}
+	typ, got, err := DecodeEvent(data)
+	if err != nil {
+		t.Fatalf("DecodeEvent(): %v", err)
+	}
+	if typ != pb.MessageType_MESSAGE_SYSCALL_OPEN {
+		t.Errorf("DecodeEvent() type: got %v, want MESSAGE_SYSCALL_OPEN", typ)
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("DecodeEvent(): got %v, want %v", got, want)
+	}
+	if _, _, err := DecodeEvent(data[:1]); err == nil {
+		t.Errorf("DecodeEvent() of a truncated event succeeded")
+	}
+
+	str, err := EventString(data)
+	if err != nil {
+		t.Fatalf("EventString(): %v", err)
+	}
+	if !strings.HasPrefix(str, "syscall/open ") || !strings.Contains(str, "/foo") {
+		t.Errorf("EventString(): got %q", str)
+	}
+}
+
+// fieldID returns the ID of the field named name.
+func fieldID(t *testing.T, name string) int {
+	t.Helper()
+	for i, f := range Fields {
+		if f.Name == name {
+			return i
+		}
+	}
+	t.Fatalf("field %q not found", name)
+	return -1
+}
+
+func TestExtract(t *testing.T) {
+	execve := &pb.Execve{
+		ContextData: &pb.ContextData{
+			ThreadId:               11,
+			ThreadGroupId:          10,
+			NsThreadGroupId:        1,
+			ThreadGroupStartTimeNs: 5000,
+			ContainerId:            "abc",
+			ProcessName:            "bash",
+			Cwd:                    "/root",
+			Credentials:            &pb.Credentials{EffectiveUid: 1000},
+			Workload: &pb.Workload{
+				SandboxId:     "sandbox",
+				ContainerId:   "abc",
+				ContainerName: "app",
+				PodName:       "web",
+			},
+		},
+		Exit:     &pb.Exit{Result: 0},
+		Sysno:    59,
+		Pathname: "/bin/sh",
+		Argv:     []string{"sh", "-c", "id"},
+	}
+	data, err := EncodeEvent(pb.MessageType_MESSAGE_SYSCALL_EXECVE, execve)
+	if err != nil {
+		t.Fatalf("EncodeEvent(): %v", err)
+	}
+
+	for _, tc := range []struct {
+		field string
+		arg   string
+		want  interface{}
+	}{
+		{field: "gvisor.type", want: "syscall/execve"},
+		{field: "gvisor.category", want: "syscall"},
+		{field: "gvisor.syscall", want: "execve"},
+		{field: "gvisor.sysno", want: uint64(59)},
+		{field: "gvisor.res", want: uint64(0)},
+		{field: "gvisor.errno", want: uint64(0)},
+		{field: "gvisor.container_id", want: "abc"},
+		{field: "gvisor.container_name", want: "app"},
+		{field: "gvisor.sandbox_id", want: "sandbox"},
+		{field: "gvisor.pod.name", want: "web"},
+		{field: "gvisor.proc.name", want: "bash"},
+		{field: "gvisor.proc.cwd", want: "/root"},
+		{field: "gvisor.proc.pid", want: uint64(10)},
+		{field: "gvisor.thread.tid", want: uint64(11)},
+		{field: "gvisor.proc.vpid", want: uint64(1)},
+		{field: "gvisor.proc.start_ts", want: uint64(5000)},
+		{field: "gvisor.user.uid", want: uint64(1000)},
+		{field: "gvisor.path", want: "/bin/sh"},
+		{field: "gvisor.field", arg: "argv", want: `["sh","-c","id"]`},
+		{field: "gvisor.field", arg: "pathname", want: "/bin/sh"},
+	} {
+		t.Run(tc.field+tc.arg, func(t *testing.T) {
+			got, ok, err := Extract(data, fieldID(t, tc.field), tc.arg)
+			if err != nil {
+				t.Fatalf("Extract(): %v", err)
+			}
+			if !ok {
+				t.Fatalf("Extract(): field not found")
+			}
+			if got != tc.want {
+				t.Errorf("Extract(): got %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+
+	// Fields that the event doesn't have.
+	for _, tc := range []struct {
+		field string
+		arg   string
+	}{
+		{field: "gvisor.group.gid"},
+		{field: "gvisor.fd.name"},
+		{field: "gvisor.pod.uid"},
+		{field: "gvisor.thread.vtid"},
+		{field: "gvisor.field", arg: "unknown"},
+	} {
+		if got, ok, err := Extract(data, fieldID(t, tc.field), tc.arg); err != nil || ok {
+			t.Errorf("Extract(%s[%s]): got %v, %t, %v, want not found", tc.field, tc.arg, got, ok, err)
+		}
+	}
+
+	if _, _, err := Extract(data, len(Fields), ""); err == nil {
+		t.Errorf("Extract() of an invalid field succeeded")
+	}
+}
+
+func TestExtractFailedSyscall(t *testing.T) {
+	open := &pb.Open{Exit: &pb.Exit{Result: -1, Errorno: 2}, Pathname: "/foo"}
+	data, err := EncodeEvent(pb.MessageType_MESSAGE_SYSCALL_OPEN, open)
+	if err != nil {
+		t.Fatalf("EncodeEvent(): %v", err)
+	}
+	if got, ok, _ := Extract(data, fieldID(t, "gvisor.errno"), ""); !ok || got != uint64(2) {
+		t.Errorf("errno: got %v, %t, want 2", got, ok)
+	}
+	if got, ok, _ := Extract(data, fieldID(t, "gvisor.res"), ""); ok {
+		t.Errorf("res of a failed syscall: got %v", got)
+	}
+}
+
+// TestExtractWorkload checks that the workload is extracted from points that
+// don't have ContextData.
+func TestExtractWorkload(t *testing.T) {
+	stop := &pb.Stop{Id: "abc", Workload: &pb.Workload{SandboxId: "sandbox", PodNamespace: "default"}}
+	data, err := EncodeEvent(pb.MessageType_MESSAGE_CONTAINER_STOP, stop)
+	if err != nil {
+		t.Fatalf("EncodeEvent(): %v", err)
+	}
+	if got, ok, _ := Extract(data, fieldID(t, "gvisor.sandbox_id"), ""); !ok || got != "sandbox" {
+		t.Errorf("sandbox_id: got %v, %t, want sandbox", got, ok)
+	}
+	if got, ok, _ := Extract(data, fieldID(t, "gvisor.pod.namespace"), ""); !ok || got != "default" {
+		t.Errorf("pod.namespace: got %v, %t, want default", got, ok)
+	}
+}
+
+func TestSource(t *testing.T) {
+	endpoint := filepath.Join(t.TempDir(), "falco.sock")
+	s, err := Open(endpoint, 3)
+	if err != nil {
+		t.Fatalf("Open(%q): %v", endpoint, err)
+	}
+	defer s.Close()
+
+	if events := s.Next(10, time.Millisecond); len(events) != 0 {
+		t.Fatalf("Next() without events: got %d events", len(events))
+	}
+
+	for _, e := range []*consumer.Event{
+		{
+			Header: wire.Header{TimeNs: 100},
+			Type:   pb.MessageType_MESSAGE_SYSCALL_OPEN,
+			Msg:    &pb.Open{Pathname: "/foo"},
+		},
+		{
+			Header: wire.Header{TimeNs: 100},
+			Type:   pb.MessageType_MESSAGE_SYSCALL_CLOSE,
+			Msg:    &pb.Close{ContextData: &pb.ContextData{TimeNs: 200}, Fd: 3},
+		},
+		{
+			Header: wire.Header{TimeNs: 100},
+			Type:   pb.MessageType_MESSAGE_CONTAINER_PAUSE,
+			Msg:    &pb.Pause{Id: "abc", TimeNs: 300},
+		},
+	} {
+		if err := s.add(e); err != nil {
+			t.Fatalf("add(%v): %v", e.Msg, err)
+		}
+	}
+
+	events := s.Next(10, time.Second)
+	if len(events) != 3 {
+		t.Fatalf("Next(): got %d events, want 3", len(events))
+	}
+	// The time of the point takes precedence over the header's.
+	if events[0].TimeNs != 100 || events[1].TimeNs != 200 || events[2].TimeNs != 300 {
+		t.Errorf("Next() times: got %d, %d and %d, want 100, 200 and 300", events[0].TimeNs, events[1].TimeNs, events[2].TimeNs)
+	}
+	if got, ok, _ := Extract(events[0].Data, fieldID(t, "gvisor.path"), ""); !ok || got != "/foo" {
+		t.Errorf("path: got %v, %t, want /foo", got, ok)
+	}
+}
@@ -0,0 +1,434 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package falcoplugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// Types of fields, as named by the plugin API.
+const (
+	FieldTypeString = "string"
+	FieldTypeUint64 = "uint64"
+)
+
+// Field is a field that can be extracted from events.
+type Field struct {
+	// Name is the name of the field in rules, e.g. "gvisor.proc.name".
+	Name string
+
+	// Type is FieldTypeString or FieldTypeUint64.
+	Type string
+
+	// Desc describes the field.
+	Desc string
+
+	// Arg is set for fields that require an argument, e.g.
+	// gvisor.field[pathname].
+	Arg bool
+
+	// extract returns the value of the field in msg, of type t, or false if
+	// msg doesn't have the field. Values are strings or uint64, according to
+	// Type.
+	extract func(t pb.MessageType, msg proto.Message, arg string) (interface{}, bool)
+}
+
+// Fields are the fields that can be extracted from events. Their index is used
+// as the field ID by Falco.
+var Fields = []Field{
+	{
+		Name: "gvisor.type",
+		Type: FieldTypeString,
+		Desc: `Type of event, e.g. "syscall/openat", "sentry/clone" or "container/start"`,
+		extract: func(t pb.MessageType, _ proto.Message, _ string) (interface{}, bool) {
+			return typeName(t), true
+		},
+	},
+	{
+		Name: "gvisor.category",
+		Type: FieldTypeString,
+		Desc: `Category of the event: "syscall", "sentry" or "container"`,
+		extract: func(t pb.MessageType, _ proto.Message, _ string) (interface{}, bool) {
+			name := typeName(t)
+			return name[:strings.Index(name+"/", "/")], true
+		},
+	},
+	{
+		Name: "gvisor.syscall",
+		Type: FieldTypeString,
+		Desc: "Name of the syscall, for syscall events",
+		extract: func(t pb.MessageType, _ proto.Message, _ string) (interface{}, bool) {
+			name := typeName(t)
+			if !strings.HasPrefix(name, "syscall/") || t == pb.MessageType_MESSAGE_SYSCALL_RAW {
+				return nil, false
+			}
+			return strings.TrimPrefix(name, "syscall/"), true
+		},
+	},
+	{
+		Name: "gvisor.sysno",
+		Type: FieldTypeUint64,
+		Desc: "Number of the syscall, for syscall events",
+		extract: func(t pb.MessageType, msg proto.Message, _ string) (interface{}, bool) {
+			if !strings.HasPrefix(t.String(), "MESSAGE_SYSCALL_") {
+				return nil, false
+			}
+			return uintField(msg, "sysno")
+		},
+	},
+	{
+		Name: "gvisor.res",
+		Type: FieldTypeUint64,
+		Desc: "Result of the syscall, for syscall exit events that succeeded",
+		extract: func(_ pb.MessageType, msg proto.Message, _ string) (interface{}, bool) {
+			exit := exitOf(msg)
+			if exit == nil || exit.Errorno != 0 || exit.Result < 0 {
+				return nil, false
+			}
+			return uint64(exit.Result), true
+		},
+	},
+	{
+		Name: "gvisor.errno",
+		Type: FieldTypeUint64,
+		Desc: "Error number of the syscall, or 0 if it succeeded, for syscall exit events",
+		extract: func(_ pb.MessageType, msg proto.Message, _ string) (interface{}, bool) {
+			exit := exitOf(msg)
+			if exit == nil {
+				return nil, false
+			}
+			return uint64(exit.Errorno), true
+		},
+	},
+	{
+		Name:    "gvisor.container_id",
+		Type:    FieldTypeString,
+		Desc:    "ID of the container that generated the event",
+		extract: contextString(func(ctx *pb.ContextData) string { return ctx.ContainerId }),
+	},
+	{
+		Name:    "gvisor.container_name",
+		Type:    FieldTypeString,
+		Desc:    "Name of the container that generated the event, in the pod spec",
+		extract: workloadString(func(w *pb.Workload) string { return w.ContainerName }),
+	},
+	{
+		Name:    "gvisor.sandbox_id",
+		Type:    FieldTypeString,
+		Desc:    "ID of the sandbox that generated the event",
+		extract: workloadString(func(w *pb.Workload) string { return w.SandboxId }),
+	},
+	{
+		Name:    "gvisor.pod.name",
+		Type:    FieldTypeString,
+		Desc:    "Name of the Kubernetes pod that generated the event",
+		extract: workloadString(func(w *pb.Workload) string { return w.PodName }),
+	},
+	{
+		Name:    "gvisor.pod.namespace",
+		Type:    FieldTypeString,
+		Desc:    "Namespace of the Kubernetes pod that generated the event",
+		extract: workloadString(func(w *pb.Workload) string { return w.PodNamespace }),
+	},
+	{
+		Name:    "gvisor.pod.uid",
+		Type:    FieldTypeString,
+		Desc:    "UID of the Kubernetes pod that generated the event",
+		extract: workloadString(func(w *pb.Workload) string { return w.PodUid }),
+	},
+	{
+		Name:    "gvisor.proc.name",
+		Type:    FieldTypeString,
+		Desc:    "Name of the process that generated the event",
+		extract: contextString(func(ctx *pb.ContextData) string { return ctx.ProcessName }),
+	},
+	{
+		Name:    "gvisor.proc.cwd",
+		Type:    FieldTypeString,
+		Desc:    "Working directory of the process that generated the event",
+		extract: contextString(func(ctx *pb.ContextData) string { return ctx.Cwd }),
+	},
+	{
+		Name:    "gvisor.proc.pid",
+		Type:    FieldTypeUint64,
+		Desc:    "ID of the thread group that generated the event, in the sandbox's root PID namespace",
+		extract: contextUint(func(ctx *pb.ContextData) uint64 { return uint64(ctx.ThreadGroupId) }),
+	},
+	{
+		Name:    "gvisor.thread.tid",
+		Type:    FieldTypeUint64,
+		Desc:    "ID of the thread that generated the event, in the sandbox's root PID namespace",
+		extract: contextUint(func(ctx *pb.ContextData) uint64 { return uint64(ctx.ThreadId) }),
+	},
+	{
+		Name:    "gvisor.proc.vpid",
+		Type:    FieldTypeUint64,
+		Desc:    "ID of the thread group that generated the event, in its own PID namespace",
+		extract: contextUint(func(ctx *pb.ContextData) uint64 { return uint64(ctx.NsThreadGroupId) }),
+	},
+	{
+		Name:    "gvisor.thread.vtid",
+		Type:    FieldTypeUint64,
+		Desc:    "ID of the thread that generated the event, in the PID namespace of its thread group",
+		extract: contextUint(func(ctx *pb.ContextData) uint64 { return uint64(ctx.NsThreadId) }),
+	},
+	{
+		Name:    "gvisor.proc.start_ts",
+		Type:    FieldTypeUint64,
+		Desc:    "Start time of the thread group that generated the event, in nanoseconds since the Unix epoch, to tell apart processes with the same ID",
+		extract: contextUint(func(ctx *pb.ContextData) uint64 { return uint64(ctx.ThreadGroupStartTimeNs) }),
+	},
+	{
+		Name: "gvisor.user.uid",
+		Type: FieldTypeUint64,
+		Desc: "Effective user ID of the thread that generated the event",
+		extract: contextUint(func(ctx *pb.ContextData) uint64 {
+			if ctx.Credentials == nil {
+				return 0
+			}
+			return uint64(ctx.Credentials.EffectiveUid)
+		}),
+	},
+	{
+		Name: "gvisor.group.gid",
+		Type: FieldTypeUint64,
+		Desc: "Effective group ID of the thread that generated the event",
+		extract: contextUint(func(ctx *pb.ContextData) uint64 {
+			if ctx.Credentials == nil {
+				return 0
+			}
+			return uint64(ctx.Credentials.EffectiveGid)
+		}),
+	},
+	{
+		Name: "gvisor.fd.name",
+		Type: FieldTypeString,
+		Desc: "Path of the file descriptor that the syscall operates on",
+		extract: func(_ pb.MessageType, msg proto.Message, _ string) (interface{}, bool) {
+			return stringField(msg, "fd_path")
+		},
+	},
+	{
+		Name: "gvisor.path",
+		Type: FieldTypeString,
+		Desc: "Path that the syscall operates on, e.g. of open and execve",
+		extract: func(_ pb.MessageType, msg proto.Message, _ string) (interface{}, bool) {
+			return stringField(msg, "pathname")
+		},
+	},
+	{
+		Name: "gvisor.field",
+		Type: FieldTypeString,
+		Desc: "Field of the event with the given name, e.g. gvisor.field[argv]. Fields that are messages or lists are in JSON",
+		Arg:  true,
+		extract: func(_ pb.MessageType, msg proto.Message, arg string) (interface{}, bool) {
+			return formatField(msg, arg)
+		},
+	},
+	{
+		Name: "gvisor.point",
+		Type: FieldTypeString,
+		Desc: "Complete event in JSON",
+		extract: func(_ pb.MessageType, msg proto.Message, _ string) (interface{}, bool) {
+			data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+			if err != nil {
+				return nil, false
+			}
+			return string(data), true
+		},
+	},
+}
+
+// fieldJSON is a field as described to Falco.
+type fieldJSON struct {
+	Type string   `json:"type"`
+	Name string   `json:"name"`
+	Desc string   `json:"desc"`
+	Arg  *argJSON `json:"arg,omitempty"`
+}
+
+// argJSON describes the argument of a field.
+type argJSON struct {
+	IsRequired bool `json:"isRequired"`
+	IsKey      bool `json:"isKey"`
+}
+
+// FieldsJSON returns the description of Fields that Falco expects.
+func FieldsJSON() string {
+	fields := make([]fieldJSON, 0, len(Fields))
+	for _, f := range Fields {
+		field := fieldJSON{Type: f.Type, Name: f.Name, Desc: f.Desc}
+		if f.Arg {
+			field.Arg = &argJSON{IsRequired: true, IsKey: true}
+		}
+		fields = append(fields, field)
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		panic(fmt.Sprintf("json.Marshal(%+v): %v", fields, err))
+	}
+	return string(data)
+}
+
+// Extract returns the value of the field with the given ID, i.e. its index in
+// Fields, in the event in data, or false if the event doesn't have the field.
+// The value is a string or uint64, according to the type of the field.
+func Extract(data []byte, id int, arg string) (interface{}, bool, error) {
+	t, msg, err := DecodeEvent(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return ExtractDecoded(t, msg, id, arg)
+}
+
+// ExtractDecoded is like Extract, for an event already decoded with
+// DecodeEvent, to extract multiple fields from the same event.
+func ExtractDecoded(t pb.MessageType, msg proto.Message, id int, arg string) (interface{}, bool, error) {
+	if id < 0 || id >= len(Fields) {
+		return nil, false, fmt.Errorf("invalid field ID %d", id)
+	}
+	value, ok := Fields[id].extract(t, msg, arg)
+	return value, ok, nil
+}
+
+// typeName returns the name of events of type t, e.g. "syscall/openat".
+func typeName(t pb.MessageType) string {
+	name := strings.ToLower(strings.TrimPrefix(t.String(), "MESSAGE_"))
+	return strings.Replace(name, "_", "/", 1)
+}
+
+func contextString(get func(ctx *pb.ContextData) string) func(pb.MessageType, proto.Message, string) (interface{}, bool) {
+	return func(_ pb.MessageType, msg proto.Message, _ string) (interface{}, bool) {
+		ctx := wire.ContextDataOf(msg)
+		if ctx == nil {
+			return nil, false
+		}
+		if s := get(ctx); len(s) > 0 {
+			return s, true
+		}
+		return nil, false
+	}
+}
+
+func workloadString(get func(w *pb.Workload) string) func(pb.MessageType, proto.Message, string) (interface{}, bool) {
+	return func(_ pb.MessageType, msg proto.Message, _ string) (interface{}, bool) {
+		w := wire.WorkloadOf(msg)
+		if w == nil {
+			return nil, false
+		}
+		if s := get(w); len(s) > 0 {
+			return s, true
+		}
+		return nil, false
+	}
+}
+
+func contextUint(get func(ctx *pb.ContextData) uint64) func(pb.MessageType, proto.Message, string) (interface{}, bool) {
+	return func(_ pb.MessageType, msg proto.Message, _ string) (interface{}, bool) {
+		ctx := wire.ContextDataOf(msg)
+		if ctx == nil {
+			return nil, false
+		}
+		if v := get(ctx); v != 0 {
+			return v, true
+		}
+		return nil, false
+	}
+}
+
+// exitOf returns the exit of the syscall in msg, or nil if msg isn't a
+// syscall exit event.
+func exitOf(msg proto.Message) *pb.Exit {
+	m := msg.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName("exit")
+	if fd == nil || !m.Has(fd) {
+		return nil
+	}
+	exit, _ := m.Get(fd).Message().Interface().(*pb.Exit)
+	return exit
+}
+
+func stringField(msg proto.Message, name protoreflect.Name) (interface{}, bool) {
+	m := msg.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName(name)
+	if fd == nil || fd.Kind() != protoreflect.StringKind || fd.IsList() || !m.Has(fd) {
+		return nil, false
+	}
+	return m.Get(fd).String(), true
+}
+
+func uintField(msg proto.Message, name protoreflect.Name) (interface{}, bool) {
+	m := msg.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName(name)
+	if fd == nil || fd.Kind() != protoreflect.Uint64Kind || fd.IsList() {
+		return nil, false
+	}
+	return m.Get(fd).Uint(), true
+}
+
+// formatField returns the field of msg named name as a string. Messages and
+// lists are formatted as JSON.
+func formatField(msg proto.Message, name string) (interface{}, bool) {
+	m := msg.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil {
+		return nil, false
+	}
+	v := m.Get(fd)
+	switch {
+	case fd.IsList() || fd.IsMap() || fd.Kind() == protoreflect.MessageKind:
+		if !m.Has(fd) {
+			return nil, false
+		}
+		// Marshal a copy of msg with only the field, and take its value.
+		only := m.New()
+		only.Set(fd, v)
+		data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(only.Interface())
+		if err != nil {
+			return nil, false
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, false
+		}
+		// protojson doesn't guarantee stable output, compact it for rules to
+		// match the value.
+		var value bytes.Buffer
+		if err := json.Compact(&value, fields[string(fd.Name())]); err != nil {
+			return nil, false
+		}
+		return value.String(), true
+	case fd.Kind() == protoreflect.StringKind:
+		return v.String(), true
+	case fd.Kind() == protoreflect.BytesKind:
+		return string(v.Bytes()), true
+	case fd.Kind() == protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name()), true
+		}
+		return fmt.Sprint(v.Enum()), true
+	default:
+		return fmt.Sprint(v.Interface()), true
+	}
+}
@@ -0,0 +1,367 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Binary main is the shared library that Falco loads for the gVisor plugin,
+// see package falcoplugin. It implements version 2.0.0 of the Falco plugin
+// API, and must be built with -buildmode=c-shared.
+//
+// Strings and arrays returned to Falco are allocated in C memory, and are
+// owned by the plugin until the next call of the same function, or until the
+// plugin or instance is destroyed.
+package main
+
+/*
+#include <stdbool.h>
+#include <stdint.h>
+#include <stdlib.h>
+
+// Definitions from plugin_info.h, version 2.0.0 of the plugin API.
+
+typedef int32_t ss_plugin_rc;
+
+enum {
+	SS_PLUGIN_SUCCESS = 0,
+	SS_PLUGIN_FAILURE = 1,
+	SS_PLUGIN_TIMEOUT = -1,
+	SS_PLUGIN_EOF = 2,
+	SS_PLUGIN_NOT_SUPPORTED = 3,
+};
+
+typedef enum ss_plugin_schema_type {
+	SS_PLUGIN_SCHEMA_NONE = 0,
+	SS_PLUGIN_SCHEMA_JSON = 1,
+} ss_plugin_schema_type;
+
+enum {
+	FTYPE_UINT64 = 8,
+	FTYPE_STRING = 9,
+};
+
+typedef struct ss_plugin_event {
+	uint64_t evtnum;
+	const uint8_t *data;
+	uint32_t datalen;
+	uint64_t ts;
+} ss_plugin_event;
+
+typedef struct ss_plugin_extract_field {
+	union {
+		const char** str;
+		uint64_t* u64;
+	} res;
+	uint64_t res_len;
+	uint32_t field_id;
+	const char* field;
+	const char* arg_key;
+	uint64_t arg_index;
+	bool arg_present;
+	uint32_t ftype;
+	bool flist;
+} ss_plugin_extract_field;
+
+// cgo doesn't support unions, so results are set from C.
+static inline void set_str_res(ss_plugin_extract_field *f, const char **res) {
+	f->res.str = res;
+}
+
+static inline void set_u64_res(ss_plugin_extract_field *f, uint64_t *res) {
+	f->res.u64 = res;
+}
+*/
+import "C"
+
+import (
+	"runtime/cgo"
+	"time"
+	"unsafe"
+
+	"gvisor.dev/gvisor/tools/falcoplugin"
+)
+
+const (
+	// requiredAPIVersion is the version of the plugin API implemented.
+	requiredAPIVersion = "2.0.0"
+
+	// maxBatchSize is the maximum number of events returned at once.
+	maxBatchSize = 512
+
+	// batchTimeout is how long to wait for events before returning control to
+	// Falco.
+	batchTimeout = 30 * time.Millisecond
+)
+
+// Strings that are returned to Falco as is. They are never freed.
+var (
+	cRequiredAPIVersion = C.CString(requiredAPIVersion)
+	cName               = C.CString(falcoplugin.Name)
+	cDescription        = C.CString(falcoplugin.Description)
+	cContact            = C.CString(falcoplugin.Contact)
+	cVersion            = C.CString(falcoplugin.Version)
+	cEventSource        = C.CString(falcoplugin.EventSource)
+	cInitSchema         = C.CString(falcoplugin.InitSchema)
+	cFields             = C.CString(falcoplugin.FieldsJSON())
+)
+
+// plugin is the state of the plugin, i.e. ss_plugin_t.
+type plugin struct {
+	config falcoplugin.Config
+
+	// lastError is the error returned by plugin_get_last_error.
+	lastError cMemory
+
+	// extracted holds the results of the last plugin_extract_fields call.
+	extracted cMemory
+
+	// eventString holds the string returned by the last
+	// plugin_event_to_string call.
+	eventString cMemory
+}
+
+// instance is an open event source, i.e. ss_instance_t.
+type instance struct {
+	source *falcoplugin.Source
+
+	// batch holds the events returned by the last plugin_next_batch call.
+	batch cMemory
+}
+
+// cMemory tracks C memory to free it when it's no longer used by Falco.
+type cMemory []unsafe.Pointer
+
+func (m *cMemory) malloc(size uintptr) unsafe.Pointer {
+	p := C.malloc(C.size_t(size))
+	*m = append(*m, p)
+	return p
+}
+
+func (m *cMemory) cString(s string) *C.char {
+	p := C.CString(s)
+	*m = append(*m, unsafe.Pointer(p))
+	return p
+}
+
+func (m *cMemory) free() {
+	for _, p := range *m {
+		C.free(p)
+	}
+	*m = (*m)[:0]
+}
+
+// newHandle returns a pointer that refers to v, to pass it to Falco as an
+// opaque pointer, since Go pointers can't be kept by C code.
+func newHandle(v interface{}) unsafe.Pointer {
+	p := C.malloc(C.size_t(unsafe.Sizeof(C.uintptr_t(0))))
+	*(*C.uintptr_t)(p) = C.uintptr_t(cgo.NewHandle(v))
+	return p
+}
+
+func handleValue(p unsafe.Pointer) interface{} {
+	return cgo.Handle(*(*C.uintptr_t)(p)).Value()
+}
+
+func deleteHandle(p unsafe.Pointer) {
+	cgo.Handle(*(*C.uintptr_t)(p)).Delete()
+	C.free(p)
+}
+
+func (p *plugin) setError(err error) {
+	p.lastError.free()
+	p.lastError.cString(err.Error())
+}
+
+//export plugin_get_required_api_version
+func plugin_get_required_api_version() *C.char {
+	return cRequiredAPIVersion
+}
+
+//export plugin_get_name
+func plugin_get_name() *C.char {
+	return cName
+}
+
+//export plugin_get_description
+func plugin_get_description() *C.char {
+	return cDescription
+}
+
+//export plugin_get_contact
+func plugin_get_contact() *C.char {
+	return cContact
+}
+
+//export plugin_get_version
+func plugin_get_version() *C.char {
+	return cVersion
+}
+
+//export plugin_get_id
+func plugin_get_id() C.uint32_t {
+	return falcoplugin.ID
+}
+
+//export plugin_get_event_source
+func plugin_get_event_source() *C.char {
+	return cEventSource
+}
+
+//export plugin_get_fields
+func plugin_get_fields() *C.char {
+	return cFields
+}
+
+//export plugin_get_init_schema
+func plugin_get_init_schema(schemaType *C.ss_plugin_schema_type) *C.char {
+	*schemaType = C.SS_PLUGIN_SCHEMA_JSON
+	return cInitSchema
+}
+
+//export plugin_init
+func plugin_init(config *C.char, rc *C.ss_plugin_rc) unsafe.Pointer {
+	p := &plugin{}
+	cfg, err := falcoplugin.ParseConfig(C.GoString(config))
+	if err != nil {
+		// The state is still returned to report the error.
+		p.setError(err)
+		*rc = C.SS_PLUGIN_FAILURE
+		return newHandle(p)
+	}
+	p.config = cfg
+	*rc = C.SS_PLUGIN_SUCCESS
+	return newHandle(p)
+}
+
+//export plugin_destroy
+func plugin_destroy(s unsafe.Pointer) {
+	p := handleValue(s).(*plugin)
+	p.lastError.free()
+	p.extracted.free()
+	p.eventString.free()
+	deleteHandle(s)
+}
+
+//export plugin_get_last_error
+func plugin_get_last_error(s unsafe.Pointer) *C.char {
+	p := handleValue(s).(*plugin)
+	if len(p.lastError) == 0 {
+		return nil
+	}
+	return (*C.char)(p.lastError[0])
+}
+
+//export plugin_open
+func plugin_open(s unsafe.Pointer, params *C.char, rc *C.ss_plugin_rc) unsafe.Pointer {
+	p := handleValue(s).(*plugin)
+	endpoint := C.GoString(params)
+	if len(endpoint) == 0 {
+		endpoint = p.config.Endpoint
+	}
+	source, err := falcoplugin.Open(endpoint, p.config.BufferSize)
+	if err != nil {
+		p.setError(err)
+		*rc = C.SS_PLUGIN_FAILURE
+		return nil
+	}
+	*rc = C.SS_PLUGIN_SUCCESS
+	return newHandle(&instance{source: source})
+}
+
+//export plugin_close
+func plugin_close(_ unsafe.Pointer, h unsafe.Pointer) {
+	inst := handleValue(h).(*instance)
+	inst.source.Close()
+	inst.batch.free()
+	deleteHandle(h)
+}
+
+//export plugin_next_batch
+func plugin_next_batch(_ unsafe.Pointer, h unsafe.Pointer, nevts *C.uint32_t, evts ***C.ss_plugin_event) C.ss_plugin_rc {
+	inst := handleValue(h).(*instance)
+	inst.batch.free()
+
+	events := inst.source.Next(maxBatchSize, batchTimeout)
+	*nevts = C.uint32_t(len(events))
+	if len(events) == 0 {
+		return C.SS_PLUGIN_TIMEOUT
+	}
+	array := inst.batch.malloc(uintptr(len(events)) * unsafe.Sizeof((*C.ss_plugin_event)(nil)))
+	ptrs := unsafe.Slice((**C.ss_plugin_event)(array), len(events))
+	for i, e := range events {
+		evt := (*C.ss_plugin_event)(inst.batch.malloc(unsafe.Sizeof(C.ss_plugin_event{})))
+		data := C.CBytes(e.Data)
+		inst.batch = append(inst.batch, data)
+		// evtnum is assigned by Falco.
+		evt.evtnum = 0
+		evt.data = (*C.uint8_t)(data)
+		evt.datalen = C.uint32_t(len(e.Data))
+		evt.ts = C.uint64_t(e.TimeNs)
+		ptrs[i] = evt
+	}
+	*evts = (**C.ss_plugin_event)(array)
+	return C.SS_PLUGIN_SUCCESS
+}
+
+//export plugin_extract_fields
+func plugin_extract_fields(s unsafe.Pointer, evt *C.ss_plugin_event, numFields C.uint32_t, fields *C.ss_plugin_extract_field) C.ss_plugin_rc {
+	p := handleValue(s).(*plugin)
+	p.extracted.free()
+
+	data := C.GoBytes(unsafe.Pointer(evt.data), C.int(evt.datalen))
+	t, msg, err := falcoplugin.DecodeEvent(data)
+	if err != nil {
+		p.setError(err)
+		return C.SS_PLUGIN_FAILURE
+	}
+	fs := unsafe.Slice(fields, int(numFields))
+	for i := range fs {
+		f := &fs[i]
+		arg := ""
+		if bool(f.arg_present) {
+			arg = C.GoString(f.arg_key)
+		}
+		value, ok, err := falcoplugin.ExtractDecoded(t, msg, int(f.field_id), arg)
+		if err != nil {
+			p.setError(err)
+			return C.SS_PLUGIN_FAILURE
+		}
+		if !ok {
+			f.res_len = 0
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			res := (**C.char)(p.extracted.malloc(unsafe.Sizeof((*C.char)(nil))))
+			*res = p.extracted.cString(v)
+			C.set_str_res(f, res)
+		case uint64:
+			res := (*C.uint64_t)(p.extracted.malloc(unsafe.Sizeof(C.uint64_t(0))))
+			*res = C.uint64_t(v)
+			C.set_u64_res(f, res)
+		}
+		f.res_len = 1
+	}
+	return C.SS_PLUGIN_SUCCESS
+}
+
+//export plugin_event_to_string
+func plugin_event_to_string(s unsafe.Pointer, evt *C.ss_plugin_event) *C.char {
+	p := handleValue(s).(*plugin)
+	p.eventString.free()
+	str, err := falcoplugin.EventString(C.GoBytes(unsafe.Pointer(evt.data), C.int(evt.datalen)))
+	if err != nil {
+		str = err.Error()
+	}
+	return p.eventString.cString(str)
+}
+
+func main() {}
@@ -0,0 +1,112 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracereplay
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/consumer"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// Output formats of Decode.
+const (
+	// FormatJSON prints each event as indented JSON.
+	FormatJSON = "json"
+
+	// FormatCompact prints each event in a single line, with its time and
+	// type followed by the message in JSON.
+	FormatCompact = "compact"
+)
+
+// Decode implements the functionality required for the "decode" command.
+type Decode struct {
+	// Format is FormatJSON or FormatCompact.
+	Format string
+
+	// Out is where events are printed.
+	Out io.Writer
+
+	mu sync.Mutex
+}
+
+// Validate returns an error if the format is unknown.
+func (d *Decode) Validate() error {
+	if d.Format != FormatJSON && d.Format != FormatCompact {
+		return fmt.Errorf("invalid format %q, must be %q or %q", d.Format, FormatJSON, FormatCompact)
+	}
+	return nil
+}
+
+// File prints the events in the trace file at path, saved by Save.
+func (d *Decode) File(path string) error {
+	return readEvents(path, d.print)
+}
+
+// Listen returns a consumer that prints the events received on endpoint from
+// live sandboxes. It must be started by the caller.
+func (d *Decode) Listen(endpoint string) *consumer.Consumer {
+	c := consumer.New(endpoint)
+	c.HandleAll(d.print)
+	return c
+}
+
+// print prints e to d.Out in d.Format. It's called concurrently for events of
+// different clients.
+func (d *Decode) print(e *consumer.Event) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.Format == FormatCompact {
+		data, err := protojson.Marshal(e.Msg)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(d.Out, "%s %v %s\n", eventTime(e.Header), e.Type, data)
+		return err
+	}
+	data, err := protojson.MarshalOptions{Multiline: true}.Marshal(e.Msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(d.Out, "{\n  \"time\": %q,\n  \"type\": %q,\n  \"message\": %s\n}\n", eventTime(e.Header), e.Type.String(), indent(data))
+	return err
+}
+
+// eventTime returns the time that the event in hdr was sent at, in RFC3339
+// format, or "-" if it's unknown because the sender predates the field.
+func eventTime(hdr wire.Header) string {
+	if hdr.TimeNs == 0 {
+		return "-"
+	}
+	return time.Unix(0, hdr.TimeNs).UTC().Format(time.RFC3339Nano)
+}
+
+// indent indents all lines of data but the first one by two spaces, to nest
+// it in the event object.
+func indent(data []byte) []byte {
+	var out []byte
+	for _, b := range data {
+		out = append(out, b)
+		if b == '\n' {
+			out = append(out, ' ', ' ')
+		}
+	}
+	return out
+}
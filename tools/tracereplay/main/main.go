@@ -32,6 +32,8 @@ func main() {
 	subcommands.Register(subcommands.FlagsCommand(), "")
 	subcommands.Register(&saveCmd{}, "")
 	subcommands.Register(&replayCmd{}, "")
+	subcommands.Register(&decodeCmd{}, "")
+	subcommands.Register(&perfettoCmd{}, "")
 	flag.CommandLine.Parse(os.Args[1:])
 	os.Exit(int(subcommands.Execute(context.Background())))
 }
@@ -150,3 +152,151 @@ func (c *replayCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}
 	}
 	return subcommands.ExitSuccess
 }
+
+// decodeCmd implements subcommands.Command for the "decode" command.
+type decodeCmd struct {
+	endpoint string
+	in       string
+	format   string
+}
+
+// Name implements subcommands.Command.
+func (*decodeCmd) Name() string {
+	return "decode"
+}
+
+// Synopsis implements subcommands.Command.
+func (*decodeCmd) Synopsis() string {
+	return "print trace events from a file or a live session as JSON"
+}
+
+// Usage implements subcommands.Command.
+func (*decodeCmd) Usage() string {
+	return `decode [flags] - print trace events from a file or a live session as JSON
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (c *decodeCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.in, "in", "", "path to trace file containing messages to be decoded")
+	f.StringVar(&c.endpoint, "endpoint", "", "path to trace server endpoint to listen on for live sessions, instead of --in")
+	f.StringVar(&c.format, "format", tracereplay.FormatJSON, "output format: \"json\" for indented JSON, or \"compact\" for one line per event")
+}
+
+// Execute implements subcommands.Command.
+func (c *decodeCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected argument: %s\n", f.Args())
+		return subcommands.ExitUsageError
+	}
+	if (len(c.in) == 0) == (len(c.endpoint) == 0) {
+		fmt.Fprintf(os.Stderr, "exactly one of --in and --endpoint is required\n")
+		return subcommands.ExitUsageError
+	}
+	d := &tracereplay.Decode{Format: c.format, Out: os.Stdout}
+	if err := d.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return subcommands.ExitUsageError
+	}
+
+	if len(c.in) > 0 {
+		if err := d.File(c.in); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return subcommands.ExitFailure
+		}
+		return subcommands.ExitSuccess
+	}
+
+	_ = os.Remove(c.endpoint)
+	server := d.Listen(c.endpoint)
+	defer server.Close()
+	if err := server.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "starting server: %v\n", err)
+		return subcommands.ExitFailure
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt)
+	fmt.Fprintf(os.Stderr, "Listening on %q. Press ctrl-C to stop...\n", c.endpoint)
+	<-ch
+	return subcommands.ExitSuccess
+}
+
+// perfettoCmd implements subcommands.Command for the "perfetto" command.
+type perfettoCmd struct {
+	endpoint string
+	in       string
+	out      string
+}
+
+// Name implements subcommands.Command.
+func (*perfettoCmd) Name() string {
+	return "perfetto"
+}
+
+// Synopsis implements subcommands.Command.
+func (*perfettoCmd) Synopsis() string {
+	return "convert trace events from a file or a live session to a trace that Perfetto opens"
+}
+
+// Usage implements subcommands.Command.
+func (*perfettoCmd) Usage() string {
+	return `perfetto [flags] - convert trace events from a file or a live session to a trace that Perfetto opens
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (c *perfettoCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.in, "in", "", "path to trace file containing messages to be converted")
+	f.StringVar(&c.endpoint, "endpoint", "", "path to trace server endpoint to listen on for live sessions, instead of --in")
+	f.StringVar(&c.out, "out", "", "path to the JSON trace file to write, defaults to stdout")
+}
+
+// Execute implements subcommands.Command.
+func (c *perfettoCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected argument: %s\n", f.Args())
+		return subcommands.ExitUsageError
+	}
+	if (len(c.in) == 0) == (len(c.endpoint) == 0) {
+		fmt.Fprintf(os.Stderr, "exactly one of --in and --endpoint is required\n")
+		return subcommands.ExitUsageError
+	}
+	out := os.Stdout
+	if len(c.out) > 0 {
+		var err error
+		out, err = os.Create(c.out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return subcommands.ExitFailure
+		}
+		defer out.Close()
+	}
+	p := &tracereplay.Perfetto{Out: out}
+
+	if len(c.in) > 0 {
+		if err := p.File(c.in); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return subcommands.ExitFailure
+		}
+	} else {
+		_ = os.Remove(c.endpoint)
+		server := p.Listen(c.endpoint)
+		if err := server.Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "starting server: %v\n", err)
+			return subcommands.ExitFailure
+		}
+
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, os.Interrupt)
+		fmt.Fprintf(os.Stderr, "Listening on %q. Press ctrl-C to stop...\n", c.endpoint)
+		<-ch
+		server.Close()
+	}
+
+	if err := p.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,339 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracereplay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/consumer"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// clientPIDOffset separates the PIDs of different connections in live
+// sessions, since each sandbox has its own PID namespace. It's larger than the
+// maximum number of tasks in a sandbox.
+const clientPIDOffset = 1 << 22
+
+// Perfetto implements the functionality required for the "perfetto" command.
+// It converts events to the JSON trace event format, which Perfetto and
+// chrome://tracing open. Each thread group gets a process track, with one
+// track per thread. Syscalls traced at both enter and exit become slices that
+// span the syscall, and all other events become instants. Events need the
+// "thread_id", "group_id", and "time" context fields to be placed on the
+// right tracks, and "process_name" to name them.
+type Perfetto struct {
+	// Out is where the trace is written. The trace is complete after Close.
+	Out io.Writer
+
+	mu sync.Mutex
+
+	// started is set after the start of the trace is written.
+	//
+	// +checklocks:mu
+	started bool
+
+	// pending are the syscalls that entered and haven't exited yet.
+	//
+	// +checklocks:mu
+	pending map[syscallKey]traceEvent
+
+	// names are the names of the processes and threads written so far, to
+	// write them again only when they change, e.g. after execve.
+	//
+	// +checklocks:mu
+	names map[trackKey]string
+}
+
+// syscallKey identifies a syscall in progress.
+type syscallKey struct {
+	pid   int64
+	tid   int64
+	sysno uint64
+}
+
+// trackKey identifies a process track, when tid is 0, or a thread track.
+type trackKey struct {
+	pid int64
+	tid int64
+}
+
+// traceEvent is an event in the JSON trace event format.
+type traceEvent struct {
+	Name  string          `json:"name"`
+	Cat   string          `json:"cat,omitempty"`
+	Phase string          `json:"ph"`
+	TS    float64         `json:"ts"`
+	Dur   *float64        `json:"dur,omitempty"`
+	PID   int64           `json:"pid"`
+	TID   int64           `json:"tid"`
+	Scope string          `json:"s,omitempty"`
+	Args  json.RawMessage `json:"args,omitempty"`
+}
+
+// File converts the events in the trace file at path, saved by Save. Close
+// must be called afterwards to complete the trace.
+func (p *Perfetto) File(path string) error {
+	return readEvents(path, p.add)
+}
+
+// Listen returns a consumer that converts the events received on endpoint
+// from live sandboxes. It must be started by the caller, and Close must be
+// called after it's closed to complete the trace.
+func (p *Perfetto) Listen(endpoint string) *consumer.Consumer {
+	c := consumer.New(endpoint)
+	c.HandleAll(p.add)
+	return c
+}
+
+// Close writes the syscalls that didn't exit as slices that don't end, and
+// completes the trace.
+func (p *Perfetto) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pending := make([]traceEvent, 0, len(p.pending))
+	for _, enter := range p.pending {
+		pending = append(pending, enter)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].TS < pending[j].TS })
+	for _, enter := range pending {
+		enter.Phase = "B"
+		if err := p.writeLocked(enter); err != nil {
+			return err
+		}
+	}
+	p.pending = nil
+
+	if !p.started {
+		_, err := io.WriteString(p.Out, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(p.Out, "\n]\n")
+	return err
+}
+
+// add converts e to trace events. It's called concurrently for events of
+// different clients.
+func (p *Perfetto) add(e *consumer.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var (
+		tid, tgid int64
+		name      string
+		container string
+		ts        = e.Header.TimeNs
+	)
+	if ctx := wire.ContextDataOf(e.Msg); ctx != nil {
+		tid = int64(ctx.ThreadId)
+		tgid = int64(ctx.ThreadGroupId)
+		name = ctx.ProcessName
+		container = ctx.ContainerId
+		if ctx.TimeNs != 0 {
+			ts = ctx.TimeNs
+		}
+	}
+	if tgid == 0 {
+		tgid = tid
+	}
+	pid := tgid
+	if e.Client > 0 {
+		pid += int64(e.Client-1) * clientPIDOffset
+	}
+	if err := p.nameTracksLocked(pid, tid, name, container); err != nil {
+		return err
+	}
+
+	args, err := eventArgs(e.Msg)
+	if err != nil {
+		return err
+	}
+	event := traceEvent{
+		Name:  eventName(e),
+		Cat:   eventCategory(e.Type),
+		Phase: "i",
+		TS:    float64(ts) / 1000,
+		PID:   pid,
+		TID:   tid,
+		Scope: "t",
+		Args:  args,
+	}
+
+	sysno, ok := syscallNumber(e)
+	if !ok {
+		return p.writeLocked(event)
+	}
+	key := syscallKey{pid: pid, tid: tid, sysno: sysno}
+	if !hasExit(e.Msg) {
+		// A syscall that enters again before exiting lost its exit event, so
+		// it's written as a slice that doesn't end.
+		if prev, ok := p.pending[key]; ok {
+			prev.Phase = "B"
+			if err := p.writeLocked(prev); err != nil {
+				return err
+			}
+		}
+		if p.pending == nil {
+			p.pending = make(map[syscallKey]traceEvent)
+		}
+		event.Phase = "X"
+		event.Scope = ""
+		p.pending[key] = event
+		return nil
+	}
+
+	event.Phase = "X"
+	event.Scope = ""
+	dur := float64(0)
+	if enter, ok := p.pending[key]; ok {
+		delete(p.pending, key)
+		dur = event.TS - enter.TS
+		event.TS = enter.TS
+	}
+	event.Dur = &dur
+	return p.writeLocked(event)
+}
+
+// nameTracksLocked writes the names of the tracks of pid and tid, unless they
+// were already written with the same names.
+//
+// +checklocks:p.mu
+func (p *Perfetto) nameTracksLocked(pid, tid int64, name, container string) error {
+	if len(name) == 0 {
+		return nil
+	}
+	if p.names == nil {
+		p.names = make(map[trackKey]string)
+	}
+	if pid == tid || p.names[trackKey{pid: pid}] == "" {
+		processName := name
+		if len(container) > 0 {
+			processName = fmt.Sprintf("%s (%s)", name, container)
+		}
+		if err := p.nameTrackLocked(trackKey{pid: pid}, "process_name", processName); err != nil {
+			return err
+		}
+	}
+	return p.nameTrackLocked(trackKey{pid: pid, tid: tid}, "thread_name", name)
+}
+
+// +checklocks:p.mu
+func (p *Perfetto) nameTrackLocked(key trackKey, metadata, name string) error {
+	if p.names[key] == name {
+		return nil
+	}
+	p.names[key] = name
+	args, err := json.Marshal(map[string]string{"name": name})
+	if err != nil {
+		return err
+	}
+	return p.writeLocked(traceEvent{
+		Name:  metadata,
+		Phase: "M",
+		PID:   key.pid,
+		TID:   key.tid,
+		Args:  args,
+	})
+}
+
+// writeLocked writes event to p.Out, starting the trace if needed.
+//
+// +checklocks:p.mu
+func (p *Perfetto) writeLocked(event traceEvent) error {
+	data, err := json.Marshal(&event)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if !p.started {
+		sep = "[\n"
+		p.started = true
+	}
+	_, err = fmt.Fprintf(p.Out, "%s%s", sep, data)
+	return err
+}
+
+// eventName returns the name of the slice or instant for e, e.g. "openat" for
+// syscalls, and "sentry/clone" for other events.
+func eventName(e *consumer.Event) string {
+	if e.Type == pb.MessageType_MESSAGE_SYSCALL_RAW {
+		if sysno, ok := syscallNumber(e); ok {
+			return fmt.Sprintf("syscall %d", sysno)
+		}
+	}
+	name := strings.ToLower(strings.TrimPrefix(e.Type.String(), "MESSAGE_"))
+	if strings.HasPrefix(name, "syscall_") {
+		return strings.TrimPrefix(name, "syscall_")
+	}
+	return strings.Replace(name, "_", "/", 1)
+}
+
+// eventCategory returns the category of events of type t, e.g. "syscall".
+func eventCategory(t pb.MessageType) string {
+	name := strings.ToLower(strings.TrimPrefix(t.String(), "MESSAGE_"))
+	if i := strings.Index(name, "_"); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// eventArgs returns the fields of msg, without the context data that is
+// already reflected in the tracks.
+func eventArgs(msg proto.Message) (json.RawMessage, error) {
+	m := msg.ProtoReflect()
+	if fd := wire.ContextDataField(m); fd != nil && m.Has(fd) {
+		msg = proto.Clone(msg)
+		m = msg.ProtoReflect()
+		m.Clear(fd)
+	}
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	if string(data) == "{}" {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// syscallNumber returns the syscall number of e, or false if e isn't a
+// syscall.
+func syscallNumber(e *consumer.Event) (uint64, bool) {
+	if !strings.HasPrefix(e.Type.String(), "MESSAGE_SYSCALL_") {
+		return 0, false
+	}
+	m := e.Msg.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName("sysno")
+	if fd == nil || fd.Kind() != protoreflect.Uint64Kind {
+		return 0, false
+	}
+	return m.Get(fd).Uint(), true
+}
+
+// hasExit returns true if msg was generated at the exit of a syscall.
+func hasExit(msg proto.Message) bool {
+	m := msg.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName("exit")
+	return fd != nil && m.Has(fd)
+}
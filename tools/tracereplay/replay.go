@@ -15,7 +15,6 @@
 package tracereplay
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -42,28 +41,12 @@ func (r *Replay) Execute() error {
 	}
 	defer socket.Close()
 
-	f, err := os.Open(r.In)
+	f, cfg, err := openTrace(r.In)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	hdr := make([]byte, len(signature))
-	if err := readFull(f, hdr); err != nil {
-		return err
-	}
-	if string(hdr) != signature {
-		return fmt.Errorf("%q is not a replay file", r.In)
-	}
-
-	cfgJSON, err := readWithSize(f)
-	if err != nil {
-		return err
-	}
-	cfg := Config{}
-	if err := json.Unmarshal(cfgJSON, &cfg); err != nil {
-		return err
-	}
 	if err := handshake(socket, cfg.Version); err != nil {
 		return err
 	}
@@ -127,7 +110,10 @@ func handshake(socket *os.File, version uint32) error {
 		return fmt.Errorf("unmarshalling handshake message: %w", err)
 	}
 
-	// Just validate that the message can unmarshall and accept any version from
-	// the server. Will try to replay and see what happens...
+	// Messages are replayed as saved, so the server must support the version
+	// they were written with.
+	if hsIn.Version < version {
+		return fmt.Errorf("server version (%d) is older than the trace version (%d)", hsIn.Version, version)
+	}
 	return nil
 }
@@ -23,6 +23,7 @@ import (
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/server"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 )
 
 // Save implements the functionality required for the "save" command.
@@ -72,17 +73,8 @@ func (s *Save) NewClient() (server.MessageHandler, error) {
 		return nil, err
 	}
 
-	handler := &msgHandler{out: out}
-
-	cfg, err := json.Marshal(Config{Version: handler.Version()})
-	if err != nil {
-		return nil, err
-	}
-	if err := writeWithSize(out, cfg); err != nil {
-		return nil, err
-	}
-
-	return handler, nil
+	// The configuration is written once the version is negotiated.
+	return &msgHandler{out: out}, nil
 }
 
 type msgHandler struct {
@@ -91,12 +83,27 @@ type msgHandler struct {
 }
 
 var _ server.MessageHandler = (*msgHandler)(nil)
+var _ server.Negotiator = (*msgHandler)(nil)
 
 // Version implements server.MessageHandler.
 func (m *msgHandler) Version() uint32 {
 	return wire.CurrentVersion
 }
 
+// Negotiate implements server.Negotiator. It writes the configuration with
+// the version that messages are written with, since they're saved as is.
+func (m *msgHandler) Negotiate(hs *pb.Handshake) ([]pb.MessageType, error) {
+	version := m.Version()
+	if hs.Version < version {
+		version = hs.Version
+	}
+	cfg, err := json.Marshal(Config{Version: version})
+	if err != nil {
+		return nil, err
+	}
+	return nil, writeWithSize(m.out, cfg)
+}
+
 // Message saves the message to the client file.
 func (m *msgHandler) Message(raw []byte, _ wire.Header, _ []byte) error {
 	m.messageCount.Add(1)
@@ -18,9 +18,15 @@ package tracereplay
 
 import (
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/consumer"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/wire"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 )
 
 const signature = "tracereplay file"
@@ -81,3 +87,68 @@ type Config struct {
 	// Version is the wire format saved in the file.
 	Version uint32 `json:"version"`
 }
+
+// openTrace opens the trace file at path, and reads its configuration. The
+// file is left at the first message.
+func openTrace(path string) (*os.File, Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	hdr := make([]byte, len(signature))
+	if err := readFull(f, hdr); err != nil {
+		_ = f.Close()
+		return nil, Config{}, err
+	}
+	if string(hdr) != signature {
+		_ = f.Close()
+		return nil, Config{}, fmt.Errorf("%q is not a replay file", path)
+	}
+
+	cfgJSON, err := readWithSize(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, Config{}, err
+	}
+	cfg := Config{}
+	if err := json.Unmarshal(cfgJSON, &cfg); err != nil {
+		_ = f.Close()
+		return nil, Config{}, err
+	}
+	return f, cfg, nil
+}
+
+// readEvents decodes the messages in the trace file at path, saved by Save,
+// and calls fn for each one of them, in order.
+func readEvents(path string, fn consumer.HandlerFunc) error {
+	f, cfg, err := openTrace(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for {
+		raw, err := readWithSize(f)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		hdr, payload, err := wire.ParseHeader(raw)
+		if err != nil {
+			return err
+		}
+		if err := hdr.CheckVersion(cfg.Version); err != nil {
+			return err
+		}
+		t := pb.MessageType(hdr.MessageType)
+		msg, err := consumer.Decode(t, payload)
+		if err != nil {
+			return err
+		}
+		if err := fn(&consumer.Event{Header: hdr, Type: t, Msg: msg}); err != nil {
+			return err
+		}
+	}
+}
@@ -16,10 +16,14 @@ package tracereplay
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/checkers/remote/consumer"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 	"gvisor.dev/gvisor/pkg/test/testutil"
 )
 
@@ -74,3 +78,139 @@ func TestBasic(t *testing.T) {
 		t.Errorf("files don't match\nwant: %s\ngot: %s", want, got)
 	}
 }
+
+// TestDecode decodes the pre-generated file in both formats.
+func TestDecode(t *testing.T) {
+	const testdata = "tools/tracereplay/testdata/client-0001"
+	in, err := testutil.FindFile(testdata)
+	if err != nil {
+		t.Fatalf("FindFile(%q): %v", testdata, err)
+	}
+
+	var compact bytes.Buffer
+	d := Decode{Format: FormatCompact, Out: &compact}
+	if err := d.File(in); err != nil {
+		t.Fatalf("File(%q): %v", in, err)
+	}
+	lines := strings.Split(strings.TrimSuffix(compact.String(), "\n"), "\n")
+	if len(lines) == 0 || lines[0] == "" {
+		t.Fatalf("no events decoded")
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, " MESSAGE_") {
+			t.Errorf("line without message type: %q", line)
+		}
+	}
+
+	var pretty bytes.Buffer
+	d = Decode{Format: FormatJSON, Out: &pretty}
+	if err := d.File(in); err != nil {
+		t.Fatalf("File(%q): %v", in, err)
+	}
+	dec := json.NewDecoder(&pretty)
+	events := 0
+	for dec.More() {
+		var event map[string]interface{}
+		if err := dec.Decode(&event); err != nil {
+			t.Fatalf("invalid JSON event %d: %v\n%s", events, err, pretty.String())
+		}
+		if _, ok := event["message"]; !ok {
+			t.Errorf("event %d has no message: %v", events, event)
+		}
+		events++
+	}
+	if events != len(lines) {
+		t.Errorf("got %d JSON events, want %d", events, len(lines))
+	}
+}
+
+// TestPerfetto converts syscalls traced at enter and exit, and checks that
+// they are correlated into slices on the tracks of their threads.
+func TestPerfetto(t *testing.T) {
+	ctx := func(tid int32, timeNs int64) *pb.ContextData {
+		return &pb.ContextData{
+			ThreadId:      tid,
+			ThreadGroupId: 10,
+			ProcessName:   "cat",
+			ContainerId:   "abc",
+			TimeNs:        timeNs,
+		}
+	}
+	events := []*consumer.Event{
+		{Type: pb.MessageType_MESSAGE_SYSCALL_OPEN, Msg: &pb.Open{ContextData: ctx(10, 1000), Sysno: 257, Pathname: "/etc/hosts"}},
+		{Type: pb.MessageType_MESSAGE_SYSCALL_READ, Msg: &pb.Read{ContextData: ctx(11, 2000), Sysno: 0, Fd: 3}},
+		{Type: pb.MessageType_MESSAGE_SYSCALL_OPEN, Msg: &pb.Open{ContextData: ctx(10, 5000), Sysno: 257, Pathname: "/etc/hosts", Exit: &pb.Exit{Result: 3}}},
+		{Type: pb.MessageType_MESSAGE_SENTRY_CLONE, Msg: &pb.CloneInfo{ContextData: ctx(10, 6000), CreatedThreadId: 12}},
+	}
+
+	var out bytes.Buffer
+	p := Perfetto{Out: &out}
+	for _, e := range events {
+		if err := p.add(e); err != nil {
+			t.Fatalf("add(%v): %v", e.Type, err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+
+	var trace []traceEvent
+	if err := json.Unmarshal(out.Bytes(), &trace); err != nil {
+		t.Fatalf("invalid trace: %v\n%s", err, out.String())
+	}
+	byName := make(map[string]traceEvent)
+	for _, event := range trace {
+		if event.PID != 10 {
+			t.Errorf("event on the wrong process: %+v", event)
+		}
+		if event.Phase == "M" {
+			byName[event.Name+string(event.Args)] = event
+			continue
+		}
+		byName[event.Name] = event
+	}
+
+	if open, ok := byName["open"]; !ok {
+		t.Errorf("open missing: %s", out.String())
+	} else if open.Phase != "X" || open.TS != 1 || open.Dur == nil || *open.Dur != 4 || open.TID != 10 {
+		t.Errorf("open isn't a slice from 1us to 5us: %+v", open)
+	} else if !strings.Contains(string(open.Args), `"result"`) {
+		t.Errorf("open args don't include the result: %s", open.Args)
+	}
+	if read, ok := byName["read"]; !ok || read.Phase != "B" || read.TID != 11 {
+		t.Errorf("read must be a slice that doesn't end: %+v", read)
+	}
+	if clone, ok := byName["sentry/clone"]; !ok || clone.Phase != "i" || clone.Cat != "sentry" {
+		t.Errorf("clone must be an instant: %+v", clone)
+	}
+	if _, ok := byName[`process_name{"name":"cat (abc)"}`]; !ok {
+		t.Errorf("process track not named: %s", out.String())
+	}
+	if _, ok := byName[`thread_name{"name":"cat"}`]; !ok {
+		t.Errorf("thread tracks not named: %s", out.String())
+	}
+}
+
+// TestPerfettoFile converts the pre-generated file.
+func TestPerfettoFile(t *testing.T) {
+	const testdata = "tools/tracereplay/testdata/client-0001"
+	in, err := testutil.FindFile(testdata)
+	if err != nil {
+		t.Fatalf("FindFile(%q): %v", testdata, err)
+	}
+	var out bytes.Buffer
+	p := Perfetto{Out: &out}
+	if err := p.File(in); err != nil {
+		t.Fatalf("File(%q): %v", in, err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+	var trace []traceEvent
+	if err := json.Unmarshal(out.Bytes(), &trace); err != nil {
+		t.Fatalf("invalid trace: %v\n%s", err, out.String())
+	}
+	if len(trace) == 0 {
+		t.Errorf("no events converted")
+	}
+}
@@ -16,6 +16,8 @@
 package cli
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"net"
@@ -27,17 +29,49 @@ import (
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/webhook/pkg/injector"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	k8snet "k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 var (
-	address   = flag.String("address", "", "The ip address the admission webhook serves on. If unspecified, a public address is selected automatically.")
-	port      = flag.Int("port", 0, "The port the admission webhook serves on.")
-	podLabels = flag.String("pod-namespace-labels", "", "A comma-separated namespace label selector, the admission webhook will only take effect on pods in selected namespaces, e.g. `label1,label2`.")
+	address     = flag.String("address", "", "The ip address the admission webhook serves on. If unspecified, a public address is selected automatically.")
+	port        = flag.Int("port", 0, "The port the admission webhook serves on.")
+	podLabels   = flag.String("pod-namespace-labels", "", "A comma-separated namespace label selector, the admission webhook will only take effect on pods in selected namespaces, e.g. `label1,label2`.")
+	namespaces  = flag.String("namespaces", "", "A comma-separated list of namespaces. If set, only pods in these namespaces are mutated.")
+	excludeNs   = flag.String("exclude-namespaces", "", "A comma-separated list of namespaces whose pods aren't mutated.")
+	podSelector = flag.String("pod-selector", "", "A label selector, e.g. `app=web,tier!=batch`. If set, only pods with matching labels are mutated.")
+	excludePods = flag.String("exclude-pod-selector", "", "A label selector of pods that aren't mutated. Pods can also opt out with the dev.gvisor.injection/opt-out=true annotation.")
+	runtimeCls  = flag.String("runtime-class", "gvisor", "The RuntimeClass of gVisor set on mutated pods.")
+	nodeSel     = flag.String("node-selector", "", "A comma-separated list of `label=value` pairs added to the node selector of mutated pods, e.g. to schedule them on the gVisor node pool.")
+	tolerations = flag.String("tolerations", "", "A JSON list of Kubernetes tolerations added to mutated pods, e.g. `[{\"key\":\"sandbox.gke.io/runtime\",\"value\":\"gvisor\",\"effect\":\"NoSchedule\"}]`.")
+	dryRun      = flag.Bool("dry-run", false, "Don't mutate pod specs, only log the patches that would be applied and set them in the dev.gvisor.injection/dry-run-patch annotation of pods and the audit log.")
+	auditLog    = flag.String("audit-log", "", "Path of the file that the structured audit records of admission decisions are appended to, one JSON object per line, or \"-\" for stdout. They are written to the log if it's not set.")
+	validate    = flag.String("validate", "", "If set, also serve a validating webhook that checks pods running with gVisor for unsupported features, like privileged containers or host namespaces. If \"warn\", incompatible pods are admitted with their problems logged and added to the audit log. If \"reject\", they are rejected.")
+	tracePolicy = flag.String("trace-policy", "", "Path to a JSON file with the cluster-wide trace policy, which opts pods into trace sessions configured on the nodes, see injector.TracePolicy.")
+	nsTrace     = flag.Bool("namespace-trace-policy", false, "Use the dev.gvisor.trace/session and dev.gvisor.trace/points annotations of namespaces as the trace policy of their pods, overriding --trace-policy.")
+	collector   = flag.String("trace-collector-image", "", "If set, a collector sidecar with this image is added to pods that opt into trace sessions, with the socket directory of the sessions' remote sink mounted at $GVISOR_TRACE_SOCKET_DIR.")
+	socketDir   = flag.String("trace-socket-dir", "", "The directory on the nodes with the socket that the remote sink of the trace sessions connects to, mounted into the collector sidecar. An emptyDir volume is used if it's not set.")
+	caKey       = flag.String("ca-key", envOr("WEBHOOK_CA_KEY", "caKey.pem"), "Path to the PEM key of the CA that signed the server certificate. Defaults to $WEBHOOK_CA_KEY if set.")
+	caCert      = flag.String("ca-cert", envOr("WEBHOOK_CA_CERT", "caCert.pem"), "Path to the PEM certificate of the CA that signed the server certificate. Defaults to $WEBHOOK_CA_CERT if set.")
+	serverKey   = flag.String("server-key", envOr("WEBHOOK_SERVER_KEY", "serverKey.pem"), "Path to the PEM key of the server certificate. Defaults to $WEBHOOK_SERVER_KEY if set.")
+	serverCert  = flag.String("server-cert", envOr("WEBHOOK_SERVER_CERT", "serverCert.pem"), "Path to the PEM server certificate served by the admission webhook. Defaults to $WEBHOOK_SERVER_CERT if set.")
+	certReload  = flag.Duration("cert-reload-interval", 0, "If set, how often to check --server-key and --server-cert for changes, e.g. when mounted from a Secret rotated by cert-manager, and serve the new certificate without restarting. Ignored with --self-signed.")
+	selfSigned  = flag.Bool("self-signed", false, "Generate a CA and a server certificate at startup, and register the CA with the kube-apiserver, instead of loading the certificates from --ca-key, --ca-cert, --server-key and --server-cert.")
+	certSecret  = flag.String("cert-secret", "", "With --self-signed, the name of a Secret in the webhook's namespace that stores the generated certificates, created by the first replica, so that all replicas serve the same ones. The certificates are renewed before they expire by the leader, see --leader-elect, and reloaded by all replicas.")
+	leaderElect = flag.Bool("leader-elect", false, "Elect a leader among the replicas of the webhook to register it with the kube-apiserver, instead of all replicas doing it. All replicas admit pods.")
 )
 
+// envOr returns the value of the environment variable name, or def if it's
+// unset or empty.
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
 // Main runs the webhook.
 func Main() {
 	flag.Parse()
@@ -63,8 +97,84 @@ func run() error {
 		return fmt.Errorf("create kubernetes client: %w", err)
 	}
 
-	if err := injector.CreateConfiguration(clientset, parsePodLabels()); err != nil {
-		return fmt.Errorf("create webhook configuration: %w", err)
+	switch {
+	case *selfSigned && *certSecret != "":
+		if err := injector.LoadOrCreateCertificates(clientset, *certSecret); err != nil {
+			return fmt.Errorf("load or create certificates: %w", err)
+		}
+	case *selfSigned:
+		if err := injector.GenerateCertificates(); err != nil {
+			return fmt.Errorf("generate certificates: %w", err)
+		}
+	default:
+		if err := injector.LoadCertificates(certPaths()); err != nil {
+			return fmt.Errorf("load certificates: %w", err)
+		}
+	}
+
+	selector, err := parseSelector()
+	if err != nil {
+		return err
+	}
+	injector.SetSelector(selector)
+
+	podConfig, err := parsePodConfig()
+	if err != nil {
+		return err
+	}
+	injector.SetPodConfig(podConfig)
+	injector.SetDryRun(*dryRun)
+
+	switch *auditLog {
+	case "":
+	case "-":
+		injector.SetAuditLog(os.Stdout)
+	default:
+		f, err := os.OpenFile(*auditLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("open audit log: %w", err)
+		}
+		defer f.Close()
+		injector.SetAuditLog(f)
+	}
+
+	if *tracePolicy != "" {
+		if err := injector.LoadTracePolicy(*tracePolicy); err != nil {
+			return fmt.Errorf("load trace policy: %w", err)
+		}
+	}
+	if *nsTrace {
+		injector.EnableNamespaceTracePolicy(clientset)
+	}
+	if *collector != "" {
+		injector.SetCollectorConfig(injector.CollectorConfig{
+			Image:          *collector,
+			SocketHostPath: *socketDir,
+		})
+	}
+	if *validate != "" {
+		mode, err := parseValidationMode(*validate)
+		if err != nil {
+			return err
+		}
+		injector.SetValidationMode(mode)
+	}
+
+	if *leaderElect {
+		identity, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("get leader election identity: %w", err)
+		}
+		go injector.RunLeaderElection(context.Background(), clientset, identity, func(ctx context.Context) error {
+			return leaderTasks(ctx, clientset)
+		})
+	} else {
+		if err := writeConfigurations(clientset); err != nil {
+			return err
+		}
+		if *selfSigned && *certSecret != "" {
+			go injector.RenewCertificatesPeriodically(context.Background(), clientset, *certSecret, injector.CertSecretInterval)
+		}
 	}
 
 	if err := startWebhookHTTPS(clientset); err != nil {
@@ -74,6 +184,116 @@ func run() error {
 	return nil
 }
 
+// leaderTasks runs on the leader among the replicas until ctx is canceled.
+func leaderTasks(ctx context.Context, clientset kubernetes.Interface) error {
+	if err := writeConfigurations(clientset); err != nil {
+		return err
+	}
+	if *selfSigned && *certSecret != "" {
+		injector.RenewCertificatesPeriodically(ctx, clientset, *certSecret, injector.CertSecretInterval)
+	}
+	return nil
+}
+
+// writeConfigurations registers the webhook with the kube-apiserver.
+func writeConfigurations(clientset kubernetes.Interface) error {
+	if *selfSigned && *certSecret != "" {
+		// Renew the certificates if needed, or load them if they were renewed
+		// since this replica started, so that their CA is registered.
+		if err := injector.RenewCertificates(clientset, *certSecret); err != nil {
+			return fmt.Errorf("renew certificates: %w", err)
+		}
+	}
+	if err := injector.CreateConfiguration(clientset, parsePodLabels()); err != nil {
+		return fmt.Errorf("create webhook configuration: %w", err)
+	}
+	if *validate != "" {
+		if err := injector.CreateValidatingConfiguration(clientset, parsePodLabels()); err != nil {
+			return fmt.Errorf("create validating webhook configuration: %w", err)
+		}
+	}
+	if *selfSigned {
+		// The configuration may already exist with the CA of a previous run.
+		if err := injector.PatchCABundle(clientset); err != nil {
+			return fmt.Errorf("patch webhook configuration: %w", err)
+		}
+	}
+	return nil
+}
+
+func certPaths() injector.CertPaths {
+	return injector.CertPaths{
+		CAKey:      *caKey,
+		CACert:     *caCert,
+		ServerKey:  *serverKey,
+		ServerCert: *serverCert,
+	}
+}
+
+func parseSelector() (injector.Selector, error) {
+	s := injector.Selector{
+		Namespaces:        splitList(*namespaces),
+		ExcludeNamespaces: splitList(*excludeNs),
+	}
+	if *podSelector != "" {
+		sel, err := labels.Parse(*podSelector)
+		if err != nil {
+			return injector.Selector{}, fmt.Errorf("invalid --pod-selector: %w", err)
+		}
+		s.Pods = sel
+	}
+	if *excludePods != "" {
+		sel, err := labels.Parse(*excludePods)
+		if err != nil {
+			return injector.Selector{}, fmt.Errorf("invalid --exclude-pod-selector: %w", err)
+		}
+		s.ExcludePods = sel
+	}
+	return s, nil
+}
+
+func parsePodConfig() (injector.PodConfig, error) {
+	c := injector.PodConfig{RuntimeClassName: *runtimeCls}
+	for _, kv := range splitList(*nodeSel) {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return injector.PodConfig{}, fmt.Errorf("invalid --node-selector %q: want label=value", kv)
+		}
+		if c.NodeSelector == nil {
+			c.NodeSelector = make(map[string]string)
+		}
+		c.NodeSelector[parts[0]] = parts[1]
+	}
+	if *tolerations != "" {
+		if err := json.Unmarshal([]byte(*tolerations), &c.Tolerations); err != nil {
+			return injector.PodConfig{}, fmt.Errorf("invalid --tolerations: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func parseValidationMode(mode string) (injector.ValidationMode, error) {
+	switch mode {
+	case "warn":
+		return injector.ValidationWarn, nil
+	case "reject":
+		return injector.ValidationReject, nil
+	default:
+		return 0, fmt.Errorf("invalid --validate %q, must be \"warn\" or \"reject\"", mode)
+	}
+}
+
+// splitList splits a comma-separated list, ignoring empty elements.
+func splitList(list string) []string {
+	var rv []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			rv = append(rv, s)
+		}
+	}
+	return rv
+}
+
 func parsePodLabels() *metav1.LabelSelector {
 	rv := &metav1.LabelSelector{}
 	for _, s := range strings.Split(*podLabels, ",") {
@@ -97,15 +317,28 @@ func startWebhookHTTPS(clientset kubernetes.Interface) error {
 		}
 		*address = ip.String()
 	}
+	tlsConfig, err := injector.GetTLSConfig()
+	if err != nil {
+		return err
+	}
+	if *certReload > 0 && !*selfSigned {
+		injector.WatchCertificates(certPaths(), *certReload)
+	}
+	if *selfSigned && *certSecret != "" {
+		injector.WatchCertificateSecret(clientset, *certSecret, injector.CertSecretInterval)
+	}
 	mux := http.NewServeMux()
 	mux.Handle("/", http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
 			injector.Admit(w, r)
 		}))
+	mux.Handle(injector.ValidatePath, http.HandlerFunc(injector.Validate))
+	mux.Handle(injector.HealthzPath, http.HandlerFunc(injector.Healthz))
+	mux.Handle(injector.ReadyzPath, injector.Readyz(clientset))
 	server := &http.Server{
 		// Listen on all addresses.
 		Addr:      net.JoinHostPort(*address, strconv.Itoa(*port)),
-		TLSConfig: injector.GetTLSConfig(),
+		TLSConfig: tlsConfig,
 		Handler:   mux,
 	}
 	if err := server.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
@@ -0,0 +1,92 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package injector
+
+import (
+	"encoding/json"
+	"net/http"
+
+	admv1beta1 "k8s.io/api/admission/v1beta1"
+	admregv1 "k8s.io/api/admissionregistration/v1"
+	admregv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	kubeclientset "k8s.io/client-go/kubernetes"
+)
+
+// admissionReviewVersions are the versions of AdmissionReview that the
+// webhook handles, in order of preference. The v1 and v1beta1 objects have
+// the same fields, so both are decoded into admv1beta1.AdmissionReview, and
+// sendReview replies with the version of the request.
+var admissionReviewVersions = []string{"v1", "v1beta1"}
+
+// webhookSideEffects is the sideEffects of the webhooks, required by the v1
+// configurations. Admission has no side effects.
+var webhookSideEffects = admregv1beta1.SideEffectClassNone
+
+// admissionV1Supported returns true if the kube-apiserver serves the
+// admissionregistration.k8s.io/v1 API, in which case the webhook
+// configurations are created with it rather than v1beta1.
+func admissionV1Supported(clientset kubeclientset.Interface) bool {
+	_, err := clientset.Discovery().ServerResourcesForGroupVersion(admregv1.SchemeGroupVersion.String())
+	return err == nil
+}
+
+// convertConfig converts the v1beta1 webhook configuration in to the v1
+// configuration out, which have the same fields as used by the webhook.
+func convertConfig(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// sendReview sends review with its response to writer. The response must
+// echo the request's UID, which the v1 API requires.
+func sendReview(writer http.ResponseWriter, review *admv1beta1.AdmissionReview) {
+	if review.Request != nil && review.Response != nil {
+		review.Response.UID = review.Request.UID
+	}
+	sendResponse(writer, review)
+}
+
+// createMutatingConfiguration creates config with the v1 API if it's
+// supported, or v1beta1 otherwise.
+func createMutatingConfiguration(clientset kubeclientset.Interface, config *admregv1beta1.MutatingWebhookConfiguration) error {
+	if !admissionV1Supported(clientset) {
+		_, err := clientset.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Create(config)
+		return err
+	}
+	v1Config := &admregv1.MutatingWebhookConfiguration{}
+	if err := convertConfig(config, v1Config); err != nil {
+		return err
+	}
+	_, err := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Create(v1Config)
+	return err
+}
+
+// createValidatingConfiguration is like createMutatingConfiguration for
+// ValidatingWebhookConfigurations.
+func createValidatingConfiguration(clientset kubeclientset.Interface, config *admregv1beta1.ValidatingWebhookConfiguration) error {
+	if !admissionV1Supported(clientset) {
+		_, err := clientset.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Create(config)
+		return err
+	}
+	v1Config := &admregv1.ValidatingWebhookConfiguration{}
+	if err := convertConfig(config, v1Config); err != nil {
+		return err
+	}
+	_, err := clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations().Create(v1Config)
+	return err
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package injector
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sync"
+	admv1beta1 "k8s.io/api/admission/v1beta1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Decisions in audit records.
+const (
+	decisionMutated  = "mutated"
+	decisionSkipped  = "skipped"
+	decisionDryRun   = "dry-run"
+	decisionAdmitted = "admitted"
+	decisionWarned   = "warned"
+	decisionRejected = "rejected"
+	decisionError    = "error"
+)
+
+// Sources of the trace policy in audit records.
+const (
+	policySourcePod       = "pod"
+	policySourceNamespace = "namespace"
+	policySourceCluster   = "cluster"
+)
+
+// auditRecord is the structured record of an admission decision, written as
+// one JSON object per line.
+type auditRecord struct {
+	Time time.Time `json:"time"`
+
+	// Webhook is "mutate" or "validate".
+	Webhook string `json:"webhook"`
+
+	UID          string `json:"uid,omitempty"`
+	Namespace    string `json:"namespace,omitempty"`
+	Name         string `json:"name,omitempty"`
+	GenerateName string `json:"generate_name,omitempty"`
+
+	// Decision is one of the decision* constants.
+	Decision string `json:"decision"`
+
+	// Patch is the JSON patch with the fields injected into the pod, or that
+	// would have been in dry-run mode.
+	Patch json.RawMessage `json:"patch,omitempty"`
+
+	// PolicySource is where the trace sessions of the pod come from, one of
+	// the policySource* constants, or empty if it has none.
+	PolicySource string `json:"policy_source,omitempty"`
+
+	// Reason explains rejections, warnings and errors.
+	Reason string `json:"reason,omitempty"`
+}
+
+var (
+	// auditMu serializes writes to auditLog.
+	auditMu sync.Mutex
+
+	// auditLog is the writer set with SetAuditLog, or nil to write records to
+	// the log.
+	auditLog io.Writer
+)
+
+// SetAuditLog sets where the audit records of admission decisions are
+// written, as one JSON object per line. They are written to the log if it's
+// not set.
+func SetAuditLog(w io.Writer) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	auditLog = w
+}
+
+// newAuditRecord returns a record of the decision of webhook for req about
+// pod, which may be nil if it couldn't be decoded.
+func newAuditRecord(webhook string, req *admv1beta1.AdmissionRequest, pod *v1.Pod, decision string) *auditRecord {
+	r := &auditRecord{
+		Time:     time.Now(),
+		Webhook:  webhook,
+		Decision: decision,
+	}
+	if req != nil {
+		r.UID = string(req.UID)
+		r.Namespace = req.Namespace
+		r.Name = req.Name
+	}
+	if pod != nil {
+		if r.Name == "" {
+			r.Name = pod.Name
+		}
+		r.GenerateName = pod.GenerateName
+	}
+	return r
+}
+
+// audit writes r to the audit log.
+func audit(r *auditRecord) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		log.Warningf("Failed to marshal audit record %+v: %v", r, err)
+		return
+	}
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if auditLog == nil {
+		log.Infof("Audit: %s", data)
+		return
+	}
+	if _, err := auditLog.Write(append(data, '\n')); err != nil {
+		log.Warningf("Failed to write audit record %s: %v", data, err)
+	}
+}
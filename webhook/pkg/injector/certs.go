@@ -15,31 +15,149 @@
 package injector
 
 import (
+	"crypto/tls"
 	"fmt"
-	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sync"
 )
 
+// CertPaths are the paths of the PEM files with the certificates used by the
+// webhook.
+type CertPaths struct {
+	// CAKey is the key of the CA that signed ServerCert.
+	CAKey string
+
+	// CACert is the certificate of the CA that signed ServerCert. It's
+	// registered with the kube-apiserver by CreateConfiguration.
+	CACert string
+
+	// ServerKey is the key of ServerCert.
+	ServerKey string
+
+	// ServerCert is the certificate served by the webhook.
+	ServerCert string
+}
+
 var (
+	// certsMu protects the certificates below, which are replaced by
+	// WatchCertificateSecret and RenewCertificates while the webhook runs.
+	certsMu    sync.Mutex
 	caKey      []byte
 	caCert     []byte
 	serverKey  []byte
 	serverCert []byte
 )
 
-func init() {
-	var (
-		caKeyErr      error
-		caCertErr     error
-		serverKeyErr  error
-		serverCertErr error
-	)
-	caKey, caKeyErr = ioutil.ReadFile("caKey.pem")
-	caCert, caCertErr = ioutil.ReadFile("caCert.pem")
-	serverKey, serverKeyErr = ioutil.ReadFile("serverKey.pem")
-	serverCert, serverCertErr = ioutil.ReadFile("serverCert.pem")
-	for _, err := range []error{caKeyErr, caCertErr, serverKeyErr, serverCertErr} {
+// caBundle returns the CA certificate registered with the kube-apiserver.
+func caBundle() []byte {
+	certsMu.Lock()
+	defer certsMu.Unlock()
+	return caCert
+}
+
+// LoadCertificates loads the certificates at paths. It must be called before
+// CreateConfiguration and GetTLSConfig.
+func LoadCertificates(paths CertPaths) error {
+	files := []struct {
+		path string
+		data *[]byte
+	}{
+		{path: paths.CAKey, data: &caKey},
+		{path: paths.CACert, data: &caCert},
+		{path: paths.ServerKey, data: &serverKey},
+		{path: paths.ServerCert, data: &serverCert},
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f.path)
 		if err != nil {
-			panic(fmt.Errorf("unable to create certificates: %v", err))
+			return fmt.Errorf("unable to load certificates: %w", err)
 		}
+		*f.data = data
+	}
+	return nil
+}
+
+// servingCert is the *tls.Certificate served by the webhook. It's set by
+// GetTLSConfig, and replaced by WatchCertificates when the files change.
+var servingCert atomic.Value
+
+// getCertificate implements tls.Config.GetCertificate.
+func getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return servingCert.Load().(*tls.Certificate), nil
+}
+
+// certFileState is the state of a certificate file used to detect changes.
+type certFileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func statCertFile(path string) (certFileState, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return certFileState{}, err
+	}
+	return certFileState{modTime: fi.ModTime(), size: fi.Size()}, nil
+}
+
+// WatchCertificates polls paths.ServerKey and paths.ServerCert every interval,
+// and starts serving the new certificate when they change, without dropping
+// connections. This supports certificates mounted from a Secret and rotated
+// by e.g. cert-manager, which kubelet replaces atomically. If the new files
+// can't be loaded, e.g. because only one of them was updated yet, the current
+// certificate is kept until the next change.
+//
+// Preconditions: GetTLSConfig has been called.
+func WatchCertificates(paths CertPaths, interval time.Duration) {
+	files := []string{paths.ServerKey, paths.ServerCert}
+	states := make([]certFileState, len(files))
+	for i, path := range files {
+		// Errors are reported on reload.
+		states[i], _ = statCertFile(path)
+	}
+	go func() {
+		for range time.Tick(interval) {
+			changed := false
+			for i, path := range files {
+				state, err := statCertFile(path)
+				if err != nil {
+					log.Warningf("Failed to stat certificate file %q: %v", path, err)
+					continue
+				}
+				if state != states[i] {
+					states[i] = state
+					changed = true
+				}
+			}
+			if !changed {
+				continue
+			}
+			if err := reloadCertificate(paths); err != nil {
+				log.Warningf("Failed to reload certificates, keeping the current ones: %v", err)
+				continue
+			}
+			log.Infof("Reloaded certificates from %q and %q", paths.ServerKey, paths.ServerCert)
+		}
+	}()
+}
+
+func reloadCertificate(paths CertPaths) error {
+	key, err := os.ReadFile(paths.ServerKey)
+	if err != nil {
+		return err
+	}
+	cert, err := os.ReadFile(paths.ServerCert)
+	if err != nil {
+		return err
+	}
+	sc, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return fmt.Errorf("failed to generate X509 key pair: %w", err)
 	}
+	servingCert.Store(&sc)
+	return nil
 }
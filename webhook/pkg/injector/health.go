@@ -0,0 +1,63 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package injector
+
+import (
+	"fmt"
+	"net/http"
+
+	"gvisor.dev/gvisor/pkg/log"
+	kubeclientset "k8s.io/client-go/kubernetes"
+)
+
+const (
+	// HealthzPath is the path of the liveness endpoint.
+	HealthzPath = "/healthz"
+
+	// ReadyzPath is the path of the readiness endpoint.
+	ReadyzPath = "/readyz"
+)
+
+// Healthz reports that the webhook is alive as long as it serves requests.
+func Healthz(writer http.ResponseWriter, _ *http.Request) {
+	writer.WriteHeader(http.StatusOK)
+	writer.Write([]byte("ok"))
+}
+
+// Readyz returns a handler that reports that the webhook is ready to admit
+// pods once its serving certificate is loaded and the kube-apiserver is
+// reachable with clientset.
+func Readyz(clientset kubeclientset.Interface) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		if err := ready(clientset); err != nil {
+			log.Infof("Not ready: %v", err)
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			writer.Write([]byte(err.Error()))
+			return
+		}
+		writer.WriteHeader(http.StatusOK)
+		writer.Write([]byte("ok"))
+	})
+}
+
+func ready(clientset kubeclientset.Interface) error {
+	if servingCert.Load() == nil {
+		return fmt.Errorf("certificates are not loaded")
+	}
+	if _, err := clientset.Discovery().ServerVersion(); err != nil {
+		return fmt.Errorf("kube-apiserver is not reachable: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package injector
+
+import (
+	"context"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// RunLeaderElection runs fn every time this replica of the webhook, identified
+// by identity, becomes the leader among the replicas. It's meant for the
+// writes to the kube-apiserver, like CreateConfiguration and PatchCABundle, so
+// that only one replica makes them, while all replicas admit pods. The context
+// passed to fn is canceled when the leadership is lost, after which the
+// replica runs for leader again. It returns when ctx is canceled.
+func RunLeaderElection(ctx context.Context, clientset kubeclientset.Interface, identity string, fn func(ctx context.Context) error) {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      Name,
+			Namespace: serviceNamespace,
+		},
+		Client: clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+	config := leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Infof("Replica %q is the leader", identity)
+				if err := fn(ctx); err != nil {
+					log.Warningf("Leader tasks failed: %v", err)
+				}
+			},
+			OnStoppedLeading: func() {
+				log.Infof("Replica %q is no longer the leader", identity)
+			},
+		},
+	}
+	// RunOrDie returns when the leadership is lost.
+	for ctx.Err() == nil {
+		leaderelection.RunOrDie(ctx, config)
+	}
+}
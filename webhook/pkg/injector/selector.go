@@ -0,0 +1,73 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package injector
+
+import (
+	"gvisor.dev/gvisor/pkg/log"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// optOutAnnotation is the annotation that opts a pod out of being mutated by
+// the webhook when set to "true", even if it's selected by the Selector.
+const optOutAnnotation = "dev.gvisor.injection/opt-out"
+
+// Selector selects the pods that the webhook mutates, among those the
+// kube-apiserver sends to it. The zero value selects all pods.
+type Selector struct {
+	// Namespaces restricts the selected pods to those in these namespaces. Pods
+	// in all namespaces are selected if it's empty.
+	Namespaces []string
+
+	// ExcludeNamespaces excludes pods in these namespaces.
+	ExcludeNamespaces []string
+
+	// Pods restricts the selected pods to those with matching labels. Pods
+	// with any labels are selected if it's nil.
+	Pods labels.Selector
+
+	// ExcludePods excludes pods with matching labels, if not nil.
+	ExcludePods labels.Selector
+}
+
+// podSelector is the Selector set with SetSelector.
+var podSelector Selector
+
+// SetSelector sets the Selector of the pods mutated by the webhook.
+func SetSelector(s Selector) {
+	log.Infof("Pod selector: %+v", s)
+	podSelector = s
+}
+
+// selects returns true if pod in namespace must be mutated.
+func (s *Selector) selects(pod *v1.Pod, namespace string) bool {
+	if pod.Annotations[optOutAnnotation] == "true" {
+		return false
+	}
+	if len(s.Namespaces) > 0 && !containsString(s.Namespaces, namespace) {
+		return false
+	}
+	if containsString(s.ExcludeNamespaces, namespace) {
+		return false
+	}
+	podLabels := labels.Set(pod.Labels)
+	if s.Pods != nil && !s.Pods.Matches(podLabels) {
+		return false
+	}
+	if s.ExcludePods != nil && s.ExcludePods.Matches(podLabels) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,385 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package injector
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/mattbaird/jsonpatch"
+	"gvisor.dev/gvisor/pkg/log"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubeclientset "k8s.io/client-go/kubernetes"
+)
+
+// selfSignedValidity is how long generated certificates are valid for. They
+// are regenerated every time the webhook starts, unless they are shared with
+// LoadOrCreateCertificates, in which case RenewCertificates regenerates them
+// selfSignedRenewBefore their expiry.
+const (
+	selfSignedValidity    = 365 * 24 * time.Hour
+	selfSignedRenewBefore = 30 * 24 * time.Hour
+)
+
+// CertSecretInterval is how often WatchCertificateSecret and
+// RenewCertificatesPeriodically check the Secret of LoadOrCreateCertificates.
+const CertSecretInterval = 10 * time.Minute
+
+// selfSignedCerts are PEM encoded certificates generated by
+// newSelfSignedCerts.
+type selfSignedCerts struct {
+	caKey      []byte
+	caCert     []byte
+	serverKey  []byte
+	serverCert []byte
+}
+
+// GenerateCertificates generates a CA and a server certificate signed by it
+// for the admission webhook service, to be used instead of LoadCertificates.
+// The CA must then be registered with PatchCABundle.
+func GenerateCertificates() error {
+	certs, err := newSelfSignedCerts()
+	if err != nil {
+		return err
+	}
+	if err := setCertificates(certs); err != nil {
+		return err
+	}
+	log.Infof("Generated self-signed certificates for %q", fullName)
+	return nil
+}
+
+func newSelfSignedCerts() (*selfSignedCerts, error) {
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := notBefore.Add(selfSignedValidity)
+
+	caPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate CA key: %w", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: Name + "-ca"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caPriv.PublicKey, caPriv)
+	if err != nil {
+		return nil, fmt.Errorf("create CA certificate: %w", err)
+	}
+
+	serverPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate server key: %w", err)
+	}
+	serverTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: fullName},
+		DNSNames:     []string{Name, Name + "." + serviceNamespace, fullName},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	serverDER, err := x509.CreateCertificate(rand.Reader, serverTemplate, caTemplate, &serverPriv.PublicKey, caPriv)
+	if err != nil {
+		return nil, fmt.Errorf("create server certificate: %w", err)
+	}
+
+	caKeyPEM, err := encodeKey(caPriv)
+	if err != nil {
+		return nil, fmt.Errorf("encode CA key: %w", err)
+	}
+	serverKeyPEM, err := encodeKey(serverPriv)
+	if err != nil {
+		return nil, fmt.Errorf("encode server key: %w", err)
+	}
+	return &selfSignedCerts{
+		caKey:      caKeyPEM,
+		caCert:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		serverKey:  serverKeyPEM,
+		serverCert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: serverDER}),
+	}, nil
+}
+
+// setCertificates replaces the certificates used by the webhook with certs.
+// The served certificate is only replaced if GetTLSConfig has been called.
+func setCertificates(certs *selfSignedCerts) error {
+	certsMu.Lock()
+	defer certsMu.Unlock()
+	if servingCert.Load() != nil {
+		sc, err := tls.X509KeyPair(certs.serverCert, certs.serverKey)
+		if err != nil {
+			return fmt.Errorf("failed to generate X509 key pair: %w", err)
+		}
+		servingCert.Store(&sc)
+	}
+	caKey = certs.caKey
+	caCert = certs.caCert
+	serverKey = certs.serverKey
+	serverCert = certs.serverCert
+	return nil
+}
+
+// Keys of the certificates in the Secret created by LoadOrCreateCertificates.
+const (
+	secretCAKey      = "ca.key"
+	secretCACert     = "ca.crt"
+	secretServerKey  = "tls.key"
+	secretServerCert = "tls.crt"
+)
+
+// LoadOrCreateCertificates loads the certificates from the Secret secretName
+// in the webhook's namespace. If it doesn't exist, the certificates are
+// generated like GenerateCertificates and the Secret is created with them.
+// This way, all replicas of the webhook serve the same certificates, and
+// concurrent PatchCABundle calls agree on the CA. The certificates aren't
+// renewed here, see RenewCertificates.
+func LoadOrCreateCertificates(clientset kubeclientset.Interface, secretName string) error {
+	secrets := clientset.CoreV1().Secrets(serviceNamespace)
+	secret, err := secrets.Get(secretName, metav1.GetOptions{})
+	if err == nil {
+		return loadSecret(secret)
+	}
+	if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get secret %q: %w", secretName, err)
+	}
+
+	certs, err := newSelfSignedCerts()
+	if err != nil {
+		return err
+	}
+	secret = &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: serviceNamespace,
+		},
+		Data: certs.secretData(),
+	}
+	if _, err := secrets.Create(secret); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create secret %q: %w", secretName, err)
+		}
+		// Another replica created it first, use its certificates.
+		secret, err = secrets.Get(secretName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get secret %q: %w", secretName, err)
+		}
+		return loadSecret(secret)
+	}
+	log.Infof("Stored certificates in secret %q", secretName)
+	return setCertificates(certs)
+}
+
+func (c *selfSignedCerts) secretData() map[string][]byte {
+	return map[string][]byte{
+		secretCAKey:      c.caKey,
+		secretCACert:     c.caCert,
+		secretServerKey:  c.serverKey,
+		secretServerCert: c.serverCert,
+	}
+}
+
+func loadSecret(secret *v1.Secret) error {
+	for _, key := range []string{secretCAKey, secretCACert, secretServerKey, secretServerCert} {
+		if len(secret.Data[key]) == 0 {
+			return fmt.Errorf("secret %q has no %q", secret.Name, key)
+		}
+	}
+	if err := setCertificates(&selfSignedCerts{
+		caKey:      secret.Data[secretCAKey],
+		caCert:     secret.Data[secretCACert],
+		serverKey:  secret.Data[secretServerKey],
+		serverCert: secret.Data[secretServerCert],
+	}); err != nil {
+		return fmt.Errorf("secret %q: %w", secret.Name, err)
+	}
+	log.Infof("Loaded certificates from secret %q", secret.Name)
+	return nil
+}
+
+// certificateExpiry returns when the PEM encoded certificate expires.
+func certificateExpiry(certPEM []byte) (time.Time, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return time.Time{}, fmt.Errorf("no PEM certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+// WatchCertificateSecret polls the Secret secretName created by
+// LoadOrCreateCertificates every interval, and starts serving its
+// certificates when they are renewed by RenewCertificates in another replica.
+//
+// Preconditions: GetTLSConfig has been called.
+func WatchCertificateSecret(clientset kubeclientset.Interface, secretName string, interval time.Duration) {
+	secrets := clientset.CoreV1().Secrets(serviceNamespace)
+	go func() {
+		for range time.Tick(interval) {
+			secret, err := secrets.Get(secretName, metav1.GetOptions{})
+			if err != nil {
+				log.Warningf("Failed to get secret %q: %v", secretName, err)
+				continue
+			}
+			if err := reloadSecret(secret); err != nil {
+				log.Warningf("Failed to reload certificates, keeping the current ones: %v", err)
+			}
+		}
+	}()
+}
+
+// reloadSecret loads the certificates from secret if they differ from the
+// current ones.
+func reloadSecret(secret *v1.Secret) error {
+	certsMu.Lock()
+	changed := !bytes.Equal(serverCert, secret.Data[secretServerCert])
+	certsMu.Unlock()
+	if !changed {
+		return nil
+	}
+	return loadSecret(secret)
+}
+
+// RenewCertificates regenerates the certificates in the Secret secretName
+// created by LoadOrCreateCertificates if they expire within
+// selfSignedRenewBefore, or can't be parsed. Otherwise, it loads them if they
+// were renewed by a previous leader, so that PatchCABundle registers their CA.
+// The new CA is registered with the
+// kube-apiserver along with the old one, so that replicas still serving the
+// old certificate are trusted until WatchCertificateSecret reloads it. It must
+// only be called by the leader among the replicas, see RunLeaderElection.
+func RenewCertificates(clientset kubeclientset.Interface, secretName string) error {
+	secrets := clientset.CoreV1().Secrets(serviceNamespace)
+	secret, err := secrets.Get(secretName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get secret %q: %w", secretName, err)
+	}
+	expiry, err := certificateExpiry(secret.Data[secretServerCert])
+	switch {
+	case err != nil:
+		log.Warningf("Failed to parse %q of secret %q, renewing it: %v", secretServerCert, secretName, err)
+	case time.Until(expiry) > selfSignedRenewBefore:
+		return reloadSecret(secret)
+	default:
+		log.Infof("Certificates in secret %q expire at %v, renewing them", secretName, expiry)
+	}
+
+	certs, err := newSelfSignedCerts()
+	if err != nil {
+		return err
+	}
+	oldCACert := secret.Data[secretCACert]
+	secret.Data = certs.secretData()
+	// The update fails with a conflict if the Secret changed since the Get
+	// above, e.g. renewed by a previous leader.
+	if _, err := secrets.Update(secret); err != nil {
+		return fmt.Errorf("failed to update secret %q: %w", secretName, err)
+	}
+	log.Infof("Stored renewed certificates in secret %q", secretName)
+	if err := setCertificates(certs); err != nil {
+		return err
+	}
+	return patchCABundle(clientset, append(append([]byte(nil), certs.caCert...), oldCACert...))
+}
+
+// RenewCertificatesPeriodically calls RenewCertificates every interval until
+// ctx is canceled.
+func RenewCertificatesPeriodically(ctx context.Context, clientset kubeclientset.Interface, secretName string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := RenewCertificates(clientset, secretName); err != nil {
+			log.Warningf("Failed to renew certificates: %v", err)
+		}
+	}
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// PatchCABundle sets the caBundle of the MutatingWebhookConfiguration created
+// by CreateConfiguration, and of the ValidatingWebhookConfiguration created by
+// CreateValidatingConfiguration if any, to the current CA certificate. It's
+// needed when the configurations already existed, e.g. created by a previous
+// instance of the webhook with different certificates.
+func PatchCABundle(clientset kubeclientset.Interface) error {
+	return patchCABundle(clientset, caBundle())
+}
+
+func patchCABundle(clientset kubeclientset.Interface, bundle []byte) error {
+	patch, err := json.Marshal([]jsonpatch.JsonPatchOperation{
+		{
+			Operation: "replace",
+			Path:      "/webhooks/0/clientConfig/caBundle",
+			Value:     bundle,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal caBundle patch: %w", err)
+	}
+	useV1 := admissionV1Supported(clientset)
+	log.Infof("Patching caBundle of MutatingWebhookConfiguration %q", Name)
+	if useV1 {
+		_, err = clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Patch(Name, types.JSONPatchType, patch)
+	} else {
+		_, err = clientset.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Patch(Name, types.JSONPatchType, patch)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to patch MutatingWebhookConfiguration %q: %w", Name, err)
+	}
+	if validationEnabled {
+		log.Infof("Patching caBundle of ValidatingWebhookConfiguration %q", validateName)
+		if useV1 {
+			_, err = clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations().Patch(validateName, types.JSONPatchType, patch)
+		} else {
+			_, err = clientset.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Patch(validateName, types.JSONPatchType, patch)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to patch ValidatingWebhookConfiguration %q: %w", validateName, err)
+		}
+	}
+	return nil
+}
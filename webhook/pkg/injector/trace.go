@@ -0,0 +1,261 @@
+// Copyright 2022 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package injector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclientset "k8s.io/client-go/kubernetes"
+)
+
+const (
+	// traceSessionAnnotation is the annotation with the comma-separated names
+	// of the trace sessions that a pod opts into. It must be passed through to
+	// the OCI spec by the container runtime, e.g. with containerd's
+	// pod_annotations = ["dev.gvisor.*"] runtime option.
+	traceSessionAnnotation = "dev.gvisor.trace/session"
+
+	// tracePointsAnnotation is the annotation with the comma-separated names of
+	// the points sent for the pod to the sessions in traceSessionAnnotation.
+	tracePointsAnnotation = "dev.gvisor.trace/points"
+)
+
+// TracePolicy is a cluster-wide policy that opts pods into trace sessions. The
+// sessions themselves are configured on the nodes, e.g. with runsc
+// --pod-init-config, and must be opt-in sessions.
+type TracePolicy struct {
+	// Sessions are the names of the sessions that pods opt into.
+	Sessions []string `json:"sessions"`
+
+	// Points restricts the points sent for pods to these. All of the sessions'
+	// points are sent if it's empty.
+	Points []string `json:"points,omitempty"`
+
+	// Namespaces restricts the policy to pods in these namespaces. It applies
+	// to all pods admitted by the webhook if it's empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// tracePolicy is the policy set with LoadTracePolicy, or nil if none.
+var tracePolicy *TracePolicy
+
+// LoadTracePolicy loads the TracePolicy in JSON format from path. It's applied
+// to pods that don't set traceSessionAnnotation themselves.
+func LoadTracePolicy(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	policy := &TracePolicy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return fmt.Errorf("invalid trace policy %q: %w", path, err)
+	}
+	if len(policy.Sessions) == 0 {
+		return fmt.Errorf("invalid trace policy %q: no sessions", path)
+	}
+	log.Infof("Trace policy: %+v", *policy)
+	tracePolicy = policy
+	return nil
+}
+
+// updateTrace applies the trace policy of namespace, or the cluster-wide one,
+// to pod in namespace. Pods that set traceSessionAnnotation, even to an empty
+// value to opt out, are left as is. It returns the source of the trace
+// sessions of pod, one of the policySource* constants, or "" if it has none.
+func updateTrace(pod *v1.Pod, namespace string) string {
+	if sessions, ok := pod.Annotations[traceSessionAnnotation]; ok {
+		if sessions == "" {
+			return ""
+		}
+		return policySourcePod
+	}
+	source := policySourceNamespace
+	policy, ok := namespaceTracePolicy(namespace)
+	if !ok {
+		source = policySourceCluster
+		policy = tracePolicy
+		if policy == nil {
+			return ""
+		}
+		if len(policy.Namespaces) > 0 && !containsString(policy.Namespaces, namespace) {
+			return ""
+		}
+	}
+	if len(policy.Sessions) == 0 {
+		// The namespace opted out.
+		return ""
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[traceSessionAnnotation] = strings.Join(policy.Sessions, ",")
+	if len(policy.Points) > 0 {
+		pod.Annotations[tracePointsAnnotation] = strings.Join(policy.Points, ",")
+	} else {
+		delete(pod.Annotations, tracePointsAnnotation)
+	}
+	return source
+}
+
+// namespaceClient reads namespace annotations if enabled with
+// EnableNamespaceTracePolicy, or is nil.
+var namespaceClient kubeclientset.Interface
+
+// EnableNamespaceTracePolicy makes the trace annotations of namespaces,
+// traceSessionAnnotation and tracePointsAnnotation, the default trace policy
+// of their pods, overriding the cluster-wide policy set with LoadTracePolicy.
+// A namespace can opt out of the cluster-wide policy by setting
+// traceSessionAnnotation to an empty value.
+func EnableNamespaceTracePolicy(clientset kubeclientset.Interface) {
+	namespaceClient = clientset
+}
+
+// namespaceTracePolicy returns the trace policy set by the annotations of
+// namespace, and whether it sets one.
+func namespaceTracePolicy(namespace string) (*TracePolicy, bool) {
+	if namespaceClient == nil {
+		return nil, false
+	}
+	ns, err := namespaceClient.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
+	if err != nil {
+		log.Warningf("Failed to get namespace %q, ignoring its trace policy: %v", namespace, err)
+		return nil, false
+	}
+	sessions, ok := ns.Annotations[traceSessionAnnotation]
+	if !ok {
+		return nil, false
+	}
+	return &TracePolicy{
+		Sessions: splitList(sessions),
+		Points:   splitList(ns.Annotations[tracePointsAnnotation]),
+	}, true
+}
+
+// splitList splits a comma-separated list, ignoring empty elements.
+func splitList(list string) []string {
+	var rv []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			rv = append(rv, s)
+		}
+	}
+	return rv
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// collectorContainerName is the name of the collector sidecar container.
+	collectorContainerName = "gvisor-trace-collector"
+
+	// collectorVolumeName is the name of the volume with the socket of the
+	// trace sessions' remote sink.
+	collectorVolumeName = "gvisor-trace-socket"
+
+	// collectorSocketDirEnv is the environment variable with the directory of
+	// the socket in the collector container.
+	collectorSocketDirEnv = "GVISOR_TRACE_SOCKET_DIR"
+
+	// defaultCollectorMountPath is the default CollectorConfig.MountPath.
+	defaultCollectorMountPath = "/run/gvisor-trace"
+)
+
+// CollectorConfig is the configuration of the collector sidecar injected into
+// pods that opt into trace sessions. The collector serves the socket that the
+// remote sink of the sessions, configured on the nodes, connects to.
+type CollectorConfig struct {
+	// Image is the image of the collector container.
+	Image string
+
+	// SocketHostPath is the directory on the node with the socket of the
+	// sessions' remote sink, which is mounted into the collector container.
+	// An emptyDir volume is used instead if it's empty.
+	SocketHostPath string
+
+	// MountPath is where the socket directory is mounted in the collector
+	// container, passed to it in $GVISOR_TRACE_SOCKET_DIR. It's
+	// defaultCollectorMountPath if empty.
+	MountPath string
+}
+
+// collectorConfig is the configuration set with SetCollectorConfig, or nil if
+// no collector is injected.
+var collectorConfig *CollectorConfig
+
+// SetCollectorConfig sets the collector sidecar injected into pods that opt
+// into trace sessions.
+func SetCollectorConfig(c CollectorConfig) {
+	if c.MountPath == "" {
+		c.MountPath = defaultCollectorMountPath
+	}
+	log.Infof("Trace collector: %+v", c)
+	collectorConfig = &c
+}
+
+// injectCollector adds the collector sidecar and its socket volume to pod if
+// it opts into trace sessions, either itself or with the trace policy, see
+// updateTrace.
+func injectCollector(pod *v1.Pod) {
+	if collectorConfig == nil || pod.Annotations[traceSessionAnnotation] == "" {
+		return
+	}
+	// Mutations must be idempotent, the kube-apiserver may send a pod again.
+	for _, c := range pod.Spec.Containers {
+		if c.Name == collectorContainerName {
+			return
+		}
+	}
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == collectorVolumeName {
+			return
+		}
+	}
+
+	volume := v1.Volume{Name: collectorVolumeName}
+	if collectorConfig.SocketHostPath != "" {
+		hostPathType := v1.HostPathDirectoryOrCreate
+		volume.HostPath = &v1.HostPathVolumeSource{
+			Path: collectorConfig.SocketHostPath,
+			Type: &hostPathType,
+		}
+	} else {
+		volume.EmptyDir = &v1.EmptyDirVolumeSource{}
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, volume)
+
+	pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{
+		Name:  collectorContainerName,
+		Image: collectorConfig.Image,
+		Env: []v1.EnvVar{
+			{Name: collectorSocketDirEnv, Value: collectorConfig.MountPath},
+		},
+		VolumeMounts: []v1.VolumeMount{
+			{Name: collectorVolumeName, MountPath: collectorConfig.MountPath},
+		},
+	})
+}
@@ -0,0 +1,219 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package injector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/log"
+	admv1beta1 "k8s.io/api/admission/v1beta1"
+	admregv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclientset "k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ValidatePath is the path that the validating webhook is served on.
+	ValidatePath = "/validate"
+
+	// validateName is the name of the ValidatingWebhookConfiguration.
+	validateName = Name + "-validation"
+
+	// validationAuditAnnotation is the audit annotation with the problems of
+	// a pod admitted in ValidationWarn mode.
+	validationAuditAnnotation = "gvisor-incompatible"
+)
+
+// ValidationMode is how pods that are incompatible with gVisor are handled.
+type ValidationMode int
+
+const (
+	// ValidationWarn admits incompatible pods, logging their problems and
+	// adding them to the audit log.
+	ValidationWarn ValidationMode = iota
+
+	// ValidationReject rejects incompatible pods.
+	ValidationReject
+)
+
+var (
+	// validationEnabled is set by SetValidationMode.
+	validationEnabled bool
+
+	// validationMode is the mode set with SetValidationMode.
+	validationMode ValidationMode
+)
+
+// SetValidationMode enables the validating webhook, with mode for handling
+// incompatible pods.
+func SetValidationMode(mode ValidationMode) {
+	validationEnabled = true
+	validationMode = mode
+}
+
+// CreateValidatingConfiguration creates a ValidatingWebhookConfiguration that
+// checks pods running with gVisor for features it doesn't support, like
+// CreateConfiguration does for mutations. Validation runs after mutations, so
+// it checks the pods mutated by the webhook along with those that set the
+// RuntimeClass themselves. How incompatible pods are handled is set with
+// SetValidationMode.
+func CreateValidatingConfiguration(clientset kubeclientset.Interface, selector *metav1.LabelSelector) error {
+	fail := admregv1beta1.Fail
+	path := ValidatePath
+
+	config := &admregv1beta1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: validateName,
+		},
+		Webhooks: []admregv1beta1.ValidatingWebhook{
+			{
+				Name: "validation." + fullName,
+				ClientConfig: admregv1beta1.WebhookClientConfig{
+					Service: &admregv1beta1.ServiceReference{
+						Name:      Name,
+						Namespace: serviceNamespace,
+						Path:      &path,
+					},
+					CABundle: caBundle(),
+				},
+				Rules: []admregv1beta1.RuleWithOperations{
+					{
+						Operations: []admregv1beta1.OperationType{
+							admregv1beta1.Create,
+						},
+						Rule: admregv1beta1.Rule{
+							APIGroups:   []string{"*"},
+							APIVersions: []string{"*"},
+							Resources:   []string{"pods"},
+						},
+					},
+				},
+				FailurePolicy:           &fail,
+				NamespaceSelector:       selector,
+				SideEffects:             &webhookSideEffects,
+				AdmissionReviewVersions: admissionReviewVersions,
+			},
+		},
+	}
+	log.Infof("Creating ValidatingWebhookConfiguration %q", config.Name)
+	if err := createValidatingConfiguration(clientset, config); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create ValidatingWebhookConfiguration %q: %s", config.Name, err)
+		}
+		log.Infof("ValidatingWebhookConfiguration %q already exists; use the existing one", config.Name)
+	}
+	return nil
+}
+
+// Validate performs admission checks of the compatibility of Pods with gVisor.
+func Validate(writer http.ResponseWriter, req *http.Request) {
+	review := &admv1beta1.AdmissionReview{}
+	if err := json.NewDecoder(req.Body).Decode(review); err != nil {
+		log.Infof("Failed with error (%v) to decode Validate request: %+v", err, *req)
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	review.Response, err = validatePod(review.Request)
+	if err != nil {
+		r := newAuditRecord("validate", review.Request, nil, decisionError)
+		r.Reason = err.Error()
+		audit(r)
+		review.Response = &admv1beta1.AdmissionResponse{
+			Result: &metav1.Status{
+				Reason:  metav1.StatusReasonInvalid,
+				Message: err.Error(),
+			},
+		}
+	}
+	log.Debugf("Processed validation review: %+v", review)
+	sendReview(writer, review)
+}
+
+func validatePod(req *admv1beta1.AdmissionRequest) (*admv1beta1.AdmissionResponse, error) {
+	resource := metav1.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+	if req.Resource != resource {
+		return nil, fmt.Errorf("unexpected resource %+v in pod validation", req.Resource)
+	}
+	pod := &v1.Pod{}
+	if err := json.Unmarshal(req.Object.Raw, pod); err != nil {
+		return nil, fmt.Errorf("failed to decode pod object %s/%s", req.Namespace, req.Name)
+	}
+
+	if pod.Spec.RuntimeClassName == nil || *pod.Spec.RuntimeClassName != podConfig.RuntimeClassName {
+		return &admv1beta1.AdmissionResponse{Allowed: true}, nil
+	}
+	problems := incompatibilities(pod)
+	if len(problems) == 0 {
+		audit(newAuditRecord("validate", req, pod, decisionAdmitted))
+		return &admv1beta1.AdmissionResponse{Allowed: true}, nil
+	}
+	msg := fmt.Sprintf("pod is incompatible with gVisor: %s", strings.Join(problems, "; "))
+	if validationMode == ValidationReject {
+		r := newAuditRecord("validate", req, pod, decisionRejected)
+		r.Reason = msg
+		audit(r)
+		return &admv1beta1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Reason:  metav1.StatusReasonForbidden,
+				Message: msg,
+			},
+		}, nil
+	}
+	r := newAuditRecord("validate", req, pod, decisionWarned)
+	r.Reason = msg
+	audit(r)
+	return &admv1beta1.AdmissionResponse{
+		Allowed:          true,
+		AuditAnnotations: map[string]string{validationAuditAnnotation: msg},
+	}, nil
+}
+
+// incompatibilities returns the features requested by pod that gVisor
+// doesn't support.
+func incompatibilities(pod *v1.Pod) []string {
+	var problems []string
+	if pod.Spec.HostNetwork {
+		problems = append(problems, "host network is not supported")
+	}
+	if pod.Spec.HostPID {
+		problems = append(problems, "host PID namespace is not supported")
+	}
+	if pod.Spec.HostIPC {
+		problems = append(problems, "host IPC namespace is not supported")
+	}
+	check := func(c *v1.Container) {
+		if c.SecurityContext != nil && c.SecurityContext.Privileged != nil && *c.SecurityContext.Privileged {
+			problems = append(problems, fmt.Sprintf("container %q: privileged containers are not supported", c.Name))
+		}
+		for _, d := range c.VolumeDevices {
+			problems = append(problems, fmt.Sprintf("container %q: raw block volume %q is not supported", c.Name, d.Name))
+		}
+	}
+	for i := range pod.Spec.InitContainers {
+		check(&pod.Spec.InitContainers[i])
+	}
+	for i := range pod.Spec.Containers {
+		check(&pod.Spec.Containers[i])
+	}
+	return problems
+}
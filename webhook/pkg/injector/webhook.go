@@ -20,7 +20,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 
 	"github.com/mattbaird/jsonpatch"
 	"gvisor.dev/gvisor/pkg/log"
@@ -63,7 +62,7 @@ func CreateConfiguration(clientset kubeclientset.Interface, selector *metav1.Lab
 						Name:      Name,
 						Namespace: serviceNamespace,
 					},
-					CABundle: caCert,
+					CABundle: caBundle(),
 				},
 				Rules: []admregv1beta1.RuleWithOperations{
 					{
@@ -77,13 +76,15 @@ func CreateConfiguration(clientset kubeclientset.Interface, selector *metav1.Lab
 						},
 					},
 				},
-				FailurePolicy:     &fail,
-				NamespaceSelector: selector,
+				FailurePolicy:           &fail,
+				NamespaceSelector:       selector,
+				SideEffects:             &webhookSideEffects,
+				AdmissionReviewVersions: admissionReviewVersions,
 			},
 		},
 	}
 	log.Infof("Creating MutatingWebhookConfiguration %q", config.Name)
-	if _, err := clientset.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Create(config); err != nil {
+	if err := createMutatingConfiguration(clientset, config); err != nil {
 		if !apierrors.IsAlreadyExists(err) {
 			return fmt.Errorf("failed to create MutatingWebhookConfiguration %q: %s", config.Name, err)
 		}
@@ -92,16 +93,20 @@ func CreateConfiguration(clientset kubeclientset.Interface, selector *metav1.Lab
 	return nil
 }
 
-// GetTLSConfig retrieves the CA cert that signed the cert used by the webhook.
-func GetTLSConfig() *tls.Config {
+// GetTLSConfig returns the TLS configuration serving the server certificate
+// loaded by LoadCertificates, or its replacement by WatchCertificates or
+// WatchCertificateSecret.
+func GetTLSConfig() (*tls.Config, error) {
+	certsMu.Lock()
+	defer certsMu.Unlock()
 	sc, err := tls.X509KeyPair(serverCert, serverKey)
 	if err != nil {
-		log.Warningf("Failed to generate X509 key pair: %v", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("failed to generate X509 key pair: %w", err)
 	}
+	servingCert.Store(&sc)
 	return &tls.Config{
-		Certificates: []tls.Certificate{sc},
-	}
+		GetCertificate: getCertificate,
+	}, nil
 }
 
 // Admit performs admission checks and mutations on Pods.
@@ -117,19 +122,21 @@ func Admit(writer http.ResponseWriter, req *http.Request) {
 	var err error
 	review.Response, err = admitPod(review.Request)
 	if err != nil {
-		log.Warningf("admitPod failed: %v", err)
+		r := newAuditRecord("mutate", review.Request, nil, decisionError)
+		r.Reason = err.Error()
+		audit(r)
 		review.Response = &admv1beta1.AdmissionResponse{
 			Result: &metav1.Status{
 				Reason:  metav1.StatusReasonInvalid,
 				Message: err.Error(),
 			},
 		}
-		sendResponse(writer, review)
+		sendReview(writer, review)
 		return
 	}
 
 	log.Debugf("Processed admission review: %+v", review)
-	sendResponse(writer, review)
+	sendReview(writer, review)
 }
 
 func sendResponse(writer http.ResponseWriter, response interface{}) {
@@ -157,14 +164,31 @@ func admitPod(req *admv1beta1.AdmissionRequest) (*admv1beta1.AdmissionResponse,
 		return nil, fmt.Errorf("failed to decode pod object %s/%s", req.Namespace, req.Name)
 	}
 
+	if !podSelector.selects(pod, req.Namespace) {
+		audit(newAuditRecord("mutate", req, pod, decisionSkipped))
+		return &admv1beta1.AdmissionResponse{Allowed: true}, nil
+	}
+
 	// Copy first to change it.
 	podCopy := pod.DeepCopy()
 	updatePod(podCopy)
+	source := updateTrace(podCopy, req.Namespace)
+	injectCollector(podCopy)
 	patch, err := createPatch(req.Object.Raw, podCopy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create patch for pod %s/%s (generatedName: %s)", pod.Namespace, pod.Name, pod.GenerateName)
 	}
 
+	r := newAuditRecord("mutate", req, pod, decisionMutated)
+	r.Patch = patch
+	r.PolicySource = source
+	if dryRun {
+		r.Decision = decisionDryRun
+		audit(r)
+		return dryRunResponse(req, pod, patch)
+	}
+	audit(r)
+
 	log.Debugf("Patched pod %s/%s (generateName: %s): %+v", pod.Namespace, pod.Name, pod.GenerateName, podCopy)
 	patchType := admv1beta1.PatchTypeJSONPatch
 	return &admv1beta1.AdmissionResponse{
@@ -174,9 +198,85 @@ func admitPod(req *admv1beta1.AdmissionRequest) (*admv1beta1.AdmissionResponse,
 	}, nil
 }
 
+// dryRunAnnotation is the annotation set in dry-run mode to the JSON patch
+// that the webhook would have applied to the pod.
+const dryRunAnnotation = "dev.gvisor.injection/dry-run-patch"
+
+// dryRun is set with SetDryRun.
+var dryRun bool
+
+// SetDryRun sets whether the webhook runs in dry-run mode, in which it only
+// writes the patches it would apply to pods to its audit records, see
+// SetAuditLog, and sets them in dryRunAnnotation and the cluster's audit log,
+// instead of changing the pod specs.
+func SetDryRun(enabled bool) {
+	log.Infof("Dry-run mode: %t", enabled)
+	dryRun = enabled
+}
+
+// dryRunResponse returns the response to req in dry-run mode for pod, which
+// would be mutated with patch.
+func dryRunResponse(req *admv1beta1.AdmissionRequest, pod *v1.Pod, patch []byte) (*admv1beta1.AdmissionResponse, error) {
+	podCopy := pod.DeepCopy()
+	if podCopy.Annotations == nil {
+		podCopy.Annotations = make(map[string]string)
+	}
+	podCopy.Annotations[dryRunAnnotation] = string(patch)
+	annotationPatch, err := createPatch(req.Object.Raw, podCopy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dry-run patch for pod %s/%s (generatedName: %s)", pod.Namespace, pod.Name, pod.GenerateName)
+	}
+	patchType := admv1beta1.PatchTypeJSONPatch
+	return &admv1beta1.AdmissionResponse{
+		Allowed:          true,
+		Patch:            annotationPatch,
+		PatchType:        &patchType,
+		AuditAnnotations: map[string]string{"dry-run-patch": string(patch)},
+	}, nil
+}
+
+// PodConfig is the configuration applied to the pods mutated by the webhook.
+type PodConfig struct {
+	// RuntimeClassName is the RuntimeClass of gVisor that pods run with.
+	RuntimeClassName string
+
+	// NodeSelector is added to the node selector of pods, e.g. to schedule
+	// them on the node pool with the RuntimeClass. It overrides the pods'
+	// values for the same labels.
+	NodeSelector map[string]string
+
+	// Tolerations are added to the tolerations of pods, e.g. to allow them on
+	// a tainted node pool with the RuntimeClass.
+	Tolerations []v1.Toleration
+}
+
+// podConfig is the PodConfig set with SetPodConfig.
+var podConfig = PodConfig{RuntimeClassName: "gvisor"}
+
+// SetPodConfig sets the PodConfig applied to mutated pods. The RuntimeClass is
+// "gvisor" if it's unset.
+func SetPodConfig(c PodConfig) {
+	if c.RuntimeClassName == "" {
+		c.RuntimeClassName = "gvisor"
+	}
+	log.Infof("Pod config: %+v", c)
+	podConfig = c
+}
+
 func updatePod(pod *v1.Pod) {
-	gvisor := "gvisor"
-	pod.Spec.RuntimeClassName = &gvisor
+	runtimeClassName := podConfig.RuntimeClassName
+	pod.Spec.RuntimeClassName = &runtimeClassName
+	if len(podConfig.NodeSelector) > 0 {
+		if pod.Spec.NodeSelector == nil {
+			pod.Spec.NodeSelector = make(map[string]string)
+		}
+		for k, v := range podConfig.NodeSelector {
+			pod.Spec.NodeSelector[k] = v
+		}
+	}
+	for i := range podConfig.Tolerations {
+		addToleration(pod, &podConfig.Tolerations[i])
+	}
 
 	// We don't run SELinux test for gvisor.
 	// If SELinuxOptions are specified, this is usually for volume test to pass
@@ -198,6 +298,16 @@ func updatePod(pod *v1.Pod) {
 	}
 }
 
+// addToleration adds t to the tolerations of pod, unless it's already there.
+func addToleration(pod *v1.Pod, t *v1.Toleration) {
+	for i := range pod.Spec.Tolerations {
+		if pod.Spec.Tolerations[i].MatchToleration(t) {
+			return
+		}
+	}
+	pod.Spec.Tolerations = append(pod.Spec.Tolerations, *t)
+}
+
 func createPatch(old []byte, newObj interface{}) ([]byte, error) {
 	new, err := json.Marshal(newObj)
 	if err != nil {